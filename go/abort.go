@@ -0,0 +1,81 @@
+package copilot
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// abortSignal notifies waiters when [Session.Abort] is acknowledged by the CLI.
+type abortSignal struct {
+	mu      sync.Mutex
+	aborted chan struct{} // closed on the next abort; created lazily
+}
+
+// wait returns a channel closed when the next abort is acknowledged.
+func (a *abortSignal) wait() <-chan struct{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.aborted == nil {
+		a.aborted = make(chan struct{})
+	}
+	return a.aborted
+}
+
+// notify releases the current waiters.
+func (a *abortSignal) notify() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.aborted != nil {
+		close(a.aborted)
+		a.aborted = nil
+	}
+}
+
+// partialContent returns the assistant content of events: the content of each
+// completed assistant message, or the streamed deltas of one that did not
+// complete, in order and separated by blank lines.
+func partialContent(events []SessionEvent) string {
+	var order []string
+	contents := make(map[string]*strings.Builder)
+	completed := make(map[string]bool)
+	content := func(id string) *strings.Builder {
+		b, ok := contents[id]
+		if !ok {
+			b = &strings.Builder{}
+			contents[id] = b
+			order = append(order, id)
+		}
+		return b
+	}
+	for i, event := range events {
+		id := ""
+		if event.Data.MessageID != nil {
+			id = *event.Data.MessageID
+		}
+		switch event.Type {
+		case AssistantMessageDelta:
+			if event.Data.DeltaContent != nil && !completed[id] {
+				content(id).WriteString(*event.Data.DeltaContent)
+			}
+		case AssistantMessage:
+			if id == "" {
+				// Without a message ID, the message can't be matched to deltas
+				id = "#" + strconv.Itoa(i)
+			}
+			b := content(id)
+			b.Reset()
+			if event.Data.Content != nil {
+				b.WriteString(*event.Data.Content)
+			}
+			completed[id] = true
+		}
+	}
+	var parts []string
+	for _, id := range order {
+		if text := strings.TrimSpace(contents[id].String()); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
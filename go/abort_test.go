@@ -0,0 +1,148 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSession_SendAndWaitAborted(t *testing.T) {
+	delta := func(messageID, content string) SessionEvent {
+		return SessionEvent{Type: AssistantMessageDelta, Data: Data{MessageID: String(messageID), DeltaContent: String(content)}}
+	}
+
+	// newAbortSession returns a session whose fake server streams part of an
+	// answer on send and, on abort, emits the sequence given by onAbort
+	newAbortSession := func(t *testing.T, onAbort func(server *fakeServer)) (*Session, chan struct{}) {
+		streamed := make(chan struct{})
+		var server *fakeServer
+		session, server := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			switch method {
+			case "session.send":
+				go func() {
+					server.emit(SessionEvent{Type: AssistantMessage, Data: Data{MessageID: String("a1"), Content: String("Looking at the code.")}})
+					server.emit(delta("a2", "The bug is "))
+					server.emit(delta("a2", "in the parser"))
+					close(streamed)
+				}()
+				return sessionSendResponse{MessageID: "msg-1"}, nil
+			case "session.abort":
+				if onAbort != nil {
+					onAbort(server)
+				}
+				return map[string]any{}, nil
+			}
+			return nil, nil
+		})
+		return session, streamed
+	}
+
+	checkAborted := func(t *testing.T, err error, reason string) {
+		t.Helper()
+		var abortedErr *AbortedError
+		if !errors.As(err, &abortedErr) || !errors.Is(err, ErrAborted) {
+			t.Fatalf("Expected an AbortedError, got %v", err)
+		}
+		if abortedErr.MessageID != "msg-1" || abortedErr.Reason != reason {
+			t.Errorf("Unexpected error fields: %+v", abortedErr)
+		}
+		if want := "Looking at the code.\n\nThe bug is in the parser"; abortedErr.PartialContent != want {
+			t.Errorf("Expected partial content %q, got %q", want, abortedErr.PartialContent)
+		}
+		if abortedErr.Turn == nil || abortedErr.Turn.FinalMessage == nil || len(abortedErr.Turn.Events) < 3 {
+			t.Errorf("Expected the turn so far, got %+v", abortedErr.Turn)
+		}
+	}
+
+	// sendAndAbort runs SendAndWait and calls Abort once the partial answer
+	// has been streamed
+	sendAndAbort := func(t *testing.T, session *Session, streamed chan struct{}) error {
+		t.Helper()
+		result := make(chan error, 1)
+		go func() {
+			_, err := session.SendAndWaitWithOptions(t.Context(), MessageOptions{Prompt: "Find the bug"}, &SendAndWaitOptions{Timeout: 5 * time.Second})
+			result <- err
+		}()
+		<-streamed
+		if err := session.Abort(t.Context()); err != nil {
+			t.Fatalf("Abort failed: %v", err)
+		}
+		select {
+		case err := <-result:
+			return err
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected SendAndWait to return after the abort")
+			return nil
+		}
+	}
+
+	t.Run("returns on the abort acknowledgment", func(t *testing.T) {
+		session, streamed := newAbortSession(t, nil)
+		checkAborted(t, sendAndAbort(t, session, streamed), "")
+	})
+
+	t.Run("reports the abort event instead of the following error", func(t *testing.T) {
+		session, streamed := newAbortSession(t, func(server *fakeServer) {
+			server.emit(SessionEvent{Type: Abort, Data: Data{Reason: String("user requested")}})
+			server.emit(SessionEvent{Type: SessionError, Data: Data{Message: String("Operation cancelled")}})
+			server.emit(SessionEvent{Type: SessionIdle})
+		})
+		checkAborted(t, sendAndAbort(t, session, streamed), "user requested")
+	})
+
+	t.Run("recognizes an abort by another client", func(t *testing.T) {
+		var server *fakeServer
+		session, server := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.send" {
+				go func() {
+					server.emit(delta("a1", "Partial"))
+					server.emit(SessionEvent{Type: Abort, Data: Data{Reason: String("cancelled")}})
+					server.emit(SessionEvent{Type: SessionIdle})
+				}()
+				return sessionSendResponse{MessageID: "msg-1"}, nil
+			}
+			return nil, nil
+		})
+		_, err := session.SendAndCollect(t.Context(), MessageOptions{Prompt: "Hi"}, nil)
+		var abortedErr *AbortedError
+		if !errors.As(err, &abortedErr) || abortedErr.PartialContent != "Partial" || abortedErr.Reason != "cancelled" {
+			t.Errorf("Expected an AbortedError with the partial content, got %v", err)
+		}
+	})
+
+	t.Run("an abort before the send does not end the next turn", func(t *testing.T) {
+		var server *fakeServer
+		session, server := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.send" {
+				go func() {
+					server.emit(SessionEvent{Type: AssistantMessage, Data: Data{Content: String("Done")}})
+					server.emit(SessionEvent{Type: SessionIdle})
+				}()
+				return sessionSendResponse{MessageID: "msg-2"}, nil
+			}
+			return map[string]any{}, nil
+		})
+		if err := session.Abort(t.Context()); err != nil {
+			t.Fatalf("Abort failed: %v", err)
+		}
+		event, err := session.SendAndWait(t.Context(), MessageOptions{Prompt: "Hi"})
+		if err != nil || event == nil || *event.Data.Content != "Done" {
+			t.Errorf("Expected the turn to complete, got %v, %v", event, err)
+		}
+	})
+}
+
+func TestPartialContent(t *testing.T) {
+	events := []SessionEvent{
+		{Type: AssistantMessageDelta, Data: Data{MessageID: String("a1"), DeltaContent: String("Hel")}},
+		{Type: AssistantMessageDelta, Data: Data{MessageID: String("a1"), DeltaContent: String("lo")}},
+		{Type: AssistantMessage, Data: Data{MessageID: String("a1"), Content: String("Hello")}},
+		{Type: AssistantMessageDelta, Data: Data{MessageID: String("a1"), DeltaContent: String(" again")}},
+		{Type: AssistantMessage, Data: Data{Content: String("No ID")}},
+		{Type: AssistantMessageDelta, Data: Data{MessageID: String("a2"), DeltaContent: String("Unfinished")}},
+	}
+	if got, want := partialContent(events), "Hello\n\nNo ID\n\nUnfinished"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,79 @@
+package copilot
+
+import (
+	"context"
+	"fmt"
+)
+
+// AskUser asks the user a question on behalf of a running tool, and blocks
+// until it is answered. The question goes to the session's
+// [UserInputHandler], or is answered by its [UserInputFallback] if none is
+// registered, exactly like a question the agent asks with its ask_user tool.
+// The handler's [UserInputInvocation] carries the tool's Context and
+// ToolCallID.
+//
+// As with the agent's questions, waiting for the answer pauses the timeouts
+// of [Session.SendAndWait] and [Session.SendAndCollect]. It does not pause the
+// tool's own timeout: AskUser returns the cause of the error when the tool's
+// Context is done first, such as when its timeout expires or the turn is
+// aborted, leaving a handler that ignores the Context to finish on its own.
+//
+// The question is answered directly, not through the CLI, so a user input
+// handler may call back into the session, including sending messages that
+// run tools asking questions of their own. AskUser returns an error matching
+// [ErrNoUserInputHandler] on an invocation not created by the SDK.
+//
+// Example:
+//
+//	func(inv copilot.ToolInvocation) (copilot.ToolResult, error) {
+//	    answer, err := inv.AskUser(copilot.UserInputRequest{
+//	        Question: "Which environment should I deploy to?",
+//	        Choices:  []string{"staging", "production"},
+//	    })
+//	    if err != nil {
+//	        return copilot.ToolResult{}, err
+//	    }
+//	    return deploy(inv.Context, answer.Answer)
+//	}
+func (i ToolInvocation) AskUser(request UserInputRequest) (UserInputResponse, error) {
+	if i.session == nil {
+		return UserInputResponse{}, fmt.Errorf("%w: the tool invocation was not created by the SDK", ErrNoUserInputHandler)
+	}
+	ctx := i.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return UserInputResponse{}, context.Cause(ctx)
+	}
+
+	type answer struct {
+		response UserInputResponse
+		err      error
+	}
+	done := make(chan answer, 1)
+	go func() {
+		var a answer
+		defer func() {
+			if r := recover(); r != nil {
+				i.session.diagnostics.recordPanic("user input handler", i.SessionID, r)
+				a = answer{err: fmt.Errorf("user input handler panicked: %v", r)}
+			}
+			done <- a
+		}()
+		a.response, a.err = i.session.askUser(request, UserInputInvocation{
+			SessionID:  i.SessionID,
+			Context:    ctx,
+			MessageID:  i.MessageID,
+			TraceID:    i.TraceID,
+			ToolCallID: i.ToolCallID,
+		})
+	}()
+
+	select {
+	case a := <-done:
+		return a.response, a.err
+	case <-ctx.Done():
+		return UserInputResponse{}, context.Cause(ctx)
+	}
+}
@@ -0,0 +1,130 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestToolInvocation_AskUser(t *testing.T) {
+	// newAskSession registers a tool that asks its question argument and
+	// returns the answer. Errors from AskUser are also sent on askErrs.
+	askErrs := make(chan error, 1)
+	newAskSession := func(t *testing.T, timeout time.Duration, onInput UserInputHandler) (*Client, *Session) {
+		session, _ := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.getMessages" {
+				return sessionGetMessagesResponse{}, nil
+			}
+			return nil, nil
+		})
+		tool := Tool{
+			Name:    "ask",
+			Timeout: timeout,
+			Handler: func(inv ToolInvocation) (ToolResult, error) {
+				question := inv.Arguments.(map[string]any)["question"].(string)
+				answer, err := inv.AskUser(UserInputRequest{Question: question, Choices: []string{"staging", "production"}})
+				if err != nil {
+					askErrs <- err
+					return ToolResult{}, err
+				}
+				return ToolResult{TextResultForLLM: answer.Answer}, nil
+			},
+		}
+		session.registerTools([]Tool{tool}, 0)
+		if onInput != nil {
+			session.registerUserInputHandler(onInput)
+		}
+		client := NewClient(nil)
+		client.sessions.put(session)
+		return client, session
+	}
+	call := func(client *Client, toolCallID, question string) ToolResult {
+		response, rpcErr := client.handleToolCallRequest(toolCallRequest{
+			SessionID:  "test-session",
+			ToolCallID: toolCallID,
+			ToolName:   "ask",
+			Arguments:  map[string]any{"question": question},
+		})
+		if rpcErr != nil {
+			t.Fatalf("Unexpected error: %v", rpcErr)
+		}
+		return response.Result
+	}
+
+	t.Run("routes the question to the user input handler", func(t *testing.T) {
+		var got UserInputInvocation
+		client, _ := newAskSession(t, 0, func(request UserInputRequest, inv UserInputInvocation) (UserInputResponse, error) {
+			got = inv
+			return UserInputResponse{Answer: request.Choices[0]}, nil
+		})
+		if result := call(client, "call-1", "Which environment?"); result.TextResultForLLM != "staging" {
+			t.Errorf("Expected the answer as the result, got %+v", result)
+		}
+		if got.ToolCallID != "call-1" || got.SessionID != "test-session" || got.Context == nil {
+			t.Errorf("Unexpected invocation: %+v", got)
+		}
+	})
+
+	t.Run("answers with the fallback without a handler", func(t *testing.T) {
+		client, session := newAskSession(t, 0, nil)
+		session.userInputFallback = &UserInputFallback{Mode: UserInputFallbackDefaultChoice}
+		if result := call(client, "call-1", "Which environment?"); result.TextResultForLLM != "staging" {
+			t.Errorf("Expected the first choice, got %+v", result)
+		}
+	})
+
+	t.Run("lets the handler call back into the session", func(t *testing.T) {
+		var client *Client
+		var session *Session
+		client, session = newAskSession(t, 0, func(request UserInputRequest, inv UserInputInvocation) (UserInputResponse, error) {
+			if request.Question == "inner" {
+				return UserInputResponse{Answer: "inner answer"}, nil
+			}
+			if _, err := session.GetMessages(inv.Context); err != nil {
+				return UserInputResponse{}, err
+			}
+			// A tool call made while answering asks its own question
+			nested := call(client, "call-2", "inner")
+			return UserInputResponse{Answer: "outer after " + nested.TextResultForLLM}, nil
+		})
+		done := make(chan ToolResult, 1)
+		go func() { done <- call(client, "call-1", "outer") }()
+		select {
+		case result := <-done:
+			if result.TextResultForLLM != "outer after inner answer" {
+				t.Errorf("Unexpected result: %+v", result)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out: nested question deadlocked")
+		}
+	})
+
+	t.Run("gives up when the tool times out", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		client, _ := newAskSession(t, 50*time.Millisecond, func(UserInputRequest, UserInputInvocation) (UserInputResponse, error) {
+			<-release
+			return UserInputResponse{Answer: "too late"}, nil
+		})
+		if result := call(client, "call-1", "Which environment?"); result.ResultType != "failure" {
+			t.Errorf("Expected the call to fail, got %+v", result)
+		}
+		select {
+		case err := <-askErrs:
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Expected AskUser to fail with the tool's deadline, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected AskUser to give up at the tool's timeout")
+		}
+	})
+
+	t.Run("fails on invocations not created by the SDK", func(t *testing.T) {
+		_, err := ToolInvocation{}.AskUser(UserInputRequest{Question: "Hi?"})
+		if !errors.Is(err, ErrNoUserInputHandler) {
+			t.Errorf("Expected ErrNoUserInputHandler, got %v", err)
+		}
+	})
+}
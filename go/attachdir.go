@@ -0,0 +1,334 @@
+package copilot
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Default limits applied by [DirectoryAttachment].
+const (
+	defaultDirectoryMaxFiles      = 1000
+	defaultDirectoryMaxTotalBytes = 10 << 20
+)
+
+// DirectoryOption configures [DirectoryAttachment].
+type DirectoryOption func(*directoryOptions)
+
+type directoryOptions struct {
+	ignoreFiles   []string
+	maxFiles      int
+	maxTotalBytes int64
+	expand        bool
+}
+
+// WithIgnoreFile applies gitignore-style rules from files with this name,
+// such as ".gitignore", found in the directory and any subdirectory. Rules in
+// a nested file apply below its directory and take precedence over those of
+// its parents. May be given more than once.
+func WithIgnoreFile(name string) DirectoryOption {
+	return func(o *directoryOptions) {
+		o.ignoreFiles = append(o.ignoreFiles, name)
+	}
+}
+
+// WithMaxFiles limits the number of files attached. Default: 1000. A negative
+// value means no limit; zero means the default.
+func WithMaxFiles(n int) DirectoryOption {
+	return func(o *directoryOptions) {
+		o.maxFiles = n
+	}
+}
+
+// WithMaxTotalBytes limits the combined size of the files attached.
+// Default: 10 MiB. A negative value means no limit; zero means the default.
+func WithMaxTotalBytes(b int64) DirectoryOption {
+	return func(o *directoryOptions) {
+		o.maxTotalBytes = b
+	}
+}
+
+// WithExpandedFiles always produces one file attachment per file, even when
+// nothing was ignored and a single directory attachment would do.
+func WithExpandedFiles() DirectoryOption {
+	return func(o *directoryOptions) {
+		o.expand = true
+	}
+}
+
+// DirectoryAttachment walks the directory at dirPath and returns attachments
+// for the files in it, for use as [MessageOptions.Attachments].
+//
+// Files matched by the rules of [WithIgnoreFile] files are skipped, as are
+// .git directories, symbolic links and other non-regular files. If nothing
+// was skipped, the result is a single directory attachment, which the CLI
+// expands itself; otherwise it is one file attachment per remaining file, in
+// lexical walk order, with the path relative to dirPath as display name.
+//
+// Returns an error wrapping [ErrDirectoryTooLarge] if the remaining files
+// exceed [WithMaxFiles] or [WithMaxTotalBytes].
+//
+// Example:
+//
+//	attachments, err := copilot.DirectoryAttachment("./service",
+//	    copilot.WithIgnoreFile(".gitignore"),
+//	    copilot.WithMaxFiles(200),
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	_, err = session.Send(ctx, copilot.MessageOptions{Prompt: "Review this service", Attachments: attachments})
+func DirectoryAttachment(dirPath string, opts ...DirectoryOption) ([]Attachment, error) {
+	var options directoryOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.maxFiles == 0 {
+		options.maxFiles = defaultDirectoryMaxFiles
+	}
+	if options.maxTotalBytes == 0 {
+		options.maxTotalBytes = defaultDirectoryMaxTotalBytes
+	}
+
+	root, err := filepath.Abs(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach directory %s: %w", dirPath, err)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("failed to attach directory: %s is not a directory", dirPath)
+	}
+
+	w := &directoryWalker{ignoreFiles: options.ignoreFiles}
+	if err := w.walk(root, "", nil); err != nil {
+		return nil, fmt.Errorf("failed to attach directory %s: %w", dirPath, err)
+	}
+	if err := w.checkLimits(dirPath, options.maxFiles, options.maxTotalBytes); err != nil {
+		return nil, err
+	}
+
+	if !w.skipped && !options.expand {
+		name := filepath.Base(root)
+		return []Attachment{{Type: Directory, Path: &root, DisplayName: &name}}, nil
+	}
+	attachments := make([]Attachment, len(w.files))
+	for i, file := range w.files {
+		abs, rel := file.abs, file.rel
+		attachments[i] = Attachment{Type: File, Path: &abs, DisplayName: &rel}
+	}
+	return attachments, nil
+}
+
+// walkedFile is a file selected for attachment.
+type walkedFile struct {
+	abs  string
+	rel  string
+	size int64
+}
+
+// directoryWalker collects the files of a directory tree, applying ignore rules.
+type directoryWalker struct {
+	ignoreFiles []string
+	files       []walkedFile
+	totalBytes  int64
+	skipped     bool
+}
+
+func (w *directoryWalker) walk(dir, rel string, rules []ignoreRule) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range w.ignoreFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// Copy so sibling directories don't share appended rules
+		rules = append(slices.Clip(rules), parseIgnoreRules(string(data), rel)...)
+	}
+
+	for _, entry := range entries {
+		childRel := path.Join(rel, entry.Name())
+		isDir := entry.IsDir()
+		if (isDir && entry.Name() == ".git") || isIgnored(rules, childRel, isDir) {
+			w.skipped = true
+			continue
+		}
+		child := filepath.Join(dir, entry.Name())
+		switch {
+		case isDir:
+			if err := w.walk(child, childRel, rules); err != nil {
+				return err
+			}
+		case entry.Type().IsRegular():
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			w.files = append(w.files, walkedFile{abs: child, rel: childRel, size: info.Size()})
+			w.totalBytes += info.Size()
+		default:
+			w.skipped = true
+		}
+	}
+	return nil
+}
+
+// checkLimits returns a *DirectoryLimitError if the collected files exceed
+// the limits.
+func (w *directoryWalker) checkLimits(dirPath string, maxFiles int, maxTotalBytes int64) error {
+	tooMany := maxFiles >= 0 && len(w.files) > maxFiles
+	tooLarge := maxTotalBytes >= 0 && w.totalBytes > maxTotalBytes
+	if !tooMany && !tooLarge {
+		return nil
+	}
+
+	usage := make(map[string]*DirectoryUsage)
+	var entries []*DirectoryUsage
+	for _, file := range w.files {
+		top, _, nested := strings.Cut(file.rel, "/")
+		if nested {
+			top += "/"
+		}
+		u, ok := usage[top]
+		if !ok {
+			u = &DirectoryUsage{Path: top}
+			usage[top] = u
+			entries = append(entries, u)
+		}
+		u.Files++
+		u.Bytes += file.size
+	}
+	slices.SortStableFunc(entries, func(a, b *DirectoryUsage) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(b.Files, a.Files))
+	})
+	err := &DirectoryLimitError{
+		Path:          dirPath,
+		Files:         len(w.files),
+		TotalBytes:    w.totalBytes,
+		MaxFiles:      max(maxFiles, 0),
+		MaxTotalBytes: max(maxTotalBytes, 0),
+	}
+	for _, u := range entries[:min(len(entries), 5)] {
+		err.Largest = append(err.Largest, *u)
+	}
+	return err
+}
+
+// ignoreRule is one pattern from a gitignore-style file.
+type ignoreRule struct {
+	// base is the slash-separated directory of the ignore file, relative to
+	// the walk root
+	base     string
+	segments []string
+	dirOnly  bool
+	negate   bool
+}
+
+// parseIgnoreRules parses gitignore-style rules from an ignore file in the
+// directory base.
+func parseIgnoreRules(content, base string) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " \t")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rule ignoreRule
+		rule.base = base
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		// A pattern with a slash other than at the end is relative to the
+		// ignore file's directory; otherwise it matches at any depth
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+		rule.segments = strings.Split(line, "/")
+		for i, segment := range rule.segments {
+			rule.segments[i] = strings.ReplaceAll(segment, "[!", "[^")
+		}
+		if !anchored {
+			rule.segments = append([]string{"**"}, rule.segments...)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// isIgnored applies rules in order; the last matching rule decides.
+func isIgnored(rules []ignoreRule, rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.matches(rel, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+func (r ignoreRule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		var ok bool
+		if rel, ok = strings.CutPrefix(rel, r.base+"/"); !ok {
+			return false
+		}
+	}
+	return matchSegments(r.segments, strings.Split(rel, "/"))
+}
+
+// matchSegments matches a path against pattern segments, where "**" matches
+// any number of segments.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			if len(rest) == 0 {
+				// A trailing "/**" matches everything inside
+				return len(name) > 0
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
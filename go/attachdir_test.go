@@ -0,0 +1,197 @@
+package copilot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files under root; content defaults to the file's name.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if content == "" {
+			content = name
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func attachedNames(t *testing.T, attachments []Attachment) []string {
+	t.Helper()
+	names := make([]string, len(attachments))
+	for i, a := range attachments {
+		if a.Type != File || a.Path == nil || !filepath.IsAbs(*a.Path) {
+			t.Fatalf("Expected an absolute file attachment, got %+v", a)
+		}
+		names[i] = *a.DisplayName
+	}
+	return names
+}
+
+func TestDirectoryAttachment(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".gitignore":               "# build output\n/dist/\nnode_modules/\n*.log\n!keep.log\n",
+		"README.md":                "",
+		"debug.log":                "",
+		"keep.log":                 "",
+		"dist/app.js":              "",
+		"node_modules/x/index.js":  "",
+		"src/main.go":              "",
+		"src/dist/embedded.go":     "",
+		"src/app.log":              "",
+		"src/gen/.gitignore":       "*.pb.go\n!api.pb.go\n",
+		"src/gen/api.pb.go":        "",
+		"src/gen/other.pb.go":      "",
+		"src/gen/doc.go":           "",
+		"docs/.gitignore":          "/drafts\n**/tmp/**\n[!a]*.txt\n",
+		"docs/drafts/idea.md":      "",
+		"docs/guide/drafts/ok.md":  "",
+		"docs/guide/tmp/cache/x":   "",
+		"docs/guide/a.txt":         "",
+		"docs/guide/b.txt":         "",
+		".git/HEAD":                "",
+		"src/gen/nested/other.log": "",
+	})
+
+	t.Run("applies nested ignore files in walk order", func(t *testing.T) {
+		attachments, err := DirectoryAttachment(root, WithIgnoreFile(".gitignore"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := []string{
+			".gitignore",
+			"README.md",
+			"docs/.gitignore",
+			"docs/guide/a.txt",
+			"docs/guide/drafts/ok.md",
+			"keep.log",
+			"src/dist/embedded.go",
+			"src/gen/.gitignore",
+			"src/gen/api.pb.go",
+			"src/gen/doc.go",
+			"src/main.go",
+		}
+		if got := attachedNames(t, attachments); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected\n%v\ngot\n%v", want, got)
+		}
+		if got := *attachments[1].Path; got != filepath.Join(root, "README.md") {
+			t.Errorf("Expected an absolute path, got %q", got)
+		}
+	})
+
+	t.Run("is deterministic", func(t *testing.T) {
+		first, _ := DirectoryAttachment(root, WithIgnoreFile(".gitignore"))
+		for i := 0; i < 5; i++ {
+			again, _ := DirectoryAttachment(root, WithIgnoreFile(".gitignore"))
+			if !reflect.DeepEqual(attachedNames(t, first), attachedNames(t, again)) {
+				t.Fatal("Expected the same attachments on every walk")
+			}
+		}
+	})
+
+	t.Run("reports what exceeded the limits", func(t *testing.T) {
+		_, err := DirectoryAttachment(root, WithMaxFiles(3), WithMaxTotalBytes(-1))
+		var limitErr *DirectoryLimitError
+		if !errors.As(err, &limitErr) || !errors.Is(err, ErrDirectoryTooLarge) {
+			t.Fatalf("Expected a DirectoryLimitError, got %v", err)
+		}
+		// Without ignore rules, only .git is skipped
+		if limitErr.Files != 20 || limitErr.MaxFiles != 3 || limitErr.MaxTotalBytes != 0 {
+			t.Errorf("Unexpected totals: %+v", limitErr)
+		}
+		if top := limitErr.Largest[0]; top.Path != "src/" || top.Files != 8 {
+			t.Errorf("Expected src/ to be the largest entry, got %+v", limitErr.Largest)
+		}
+		if msg := err.Error(); !strings.Contains(msg, "20 files (max 3)") || strings.Contains(msg, "bytes (max") {
+			t.Errorf("Expected only the file limit in the message, got %q", msg)
+		}
+
+		_, err = DirectoryAttachment(root, WithIgnoreFile(".gitignore"), WithMaxTotalBytes(10))
+		if !errors.As(err, &limitErr) || !strings.Contains(err.Error(), "bytes (max 10)") {
+			t.Errorf("Expected the byte limit to be reported, got %v", err)
+		}
+	})
+
+	t.Run("uses a directory attachment when nothing is skipped", func(t *testing.T) {
+		plain := t.TempDir()
+		writeTree(t, plain, map[string]string{"a.go": "", "pkg/b.go": ""})
+
+		attachments, err := DirectoryAttachment(plain)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(attachments) != 1 || attachments[0].Type != Directory || *attachments[0].Path != plain {
+			t.Errorf("Expected one directory attachment, got %+v", attachments)
+		}
+
+		attachments, err = DirectoryAttachment(plain, WithExpandedFiles())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := attachedNames(t, attachments); !reflect.DeepEqual(got, []string{"a.go", "pkg/b.go"}) {
+			t.Errorf("Expected expanded files, got %v", got)
+		}
+	})
+
+	t.Run("rejects files", func(t *testing.T) {
+		if _, err := DirectoryAttachment(filepath.Join(root, "README.md")); err == nil {
+			t.Error("Expected an error for a file path")
+		}
+	})
+}
+
+func TestIgnoreRules(t *testing.T) {
+	tests := []struct {
+		pattern string
+		base    string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"*.log", "", "a/b/c.log", false, true},
+		{"*.log", "", "c.log.txt", false, false},
+		{"build/", "", "x/build", true, true},
+		{"build/", "", "x/build", false, false},
+		{"/build", "", "x/build", true, false},
+		{"/build", "", "build", false, true},
+		{"a/b", "", "a/b", false, true},
+		{"a/b", "", "x/a/b", false, false},
+		{"a/**/z", "", "a/z", false, true},
+		{"a/**/z", "", "a/b/c/z", false, true},
+		{"a/**", "", "a", true, false},
+		{"a/**", "", "a/b", false, true},
+		{"**/cache", "", "x/y/cache", true, true},
+		{"*.go", "sub", "sub/x.go", false, true},
+		{"*.go", "sub", "x.go", false, false},
+		{"/x", "sub", "sub/x", false, true},
+		{"/x", "sub", "sub/y/x", false, false},
+		{"[!a]*", "", "b.txt", false, true},
+		{"[!a]*", "", "a.txt", false, false},
+		{`\#notes`, "", "#notes", false, true},
+		{"file?.txt", "", "file1.txt", false, true},
+	}
+	for _, tt := range tests {
+		rules := parseIgnoreRules(tt.pattern, tt.base)
+		if got := isIgnored(rules, tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%q in %q against %q (dir %v): expected %v, got %v", tt.pattern, tt.base, tt.path, tt.isDir, tt.want, got)
+		}
+	}
+
+	t.Run("later rules and negations win", func(t *testing.T) {
+		rules := parseIgnoreRules("*.log\n!keep.log\n\n# comment\n", "")
+		if isIgnored(rules, "keep.log", false) || !isIgnored(rules, "drop.log", false) {
+			t.Error("Expected the negation to re-include keep.log only")
+		}
+	})
+}
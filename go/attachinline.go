@@ -0,0 +1,170 @@
+package copilot
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"unicode/utf8"
+)
+
+const (
+	// Inline attachments carry their content in the message, for content
+	// generated in memory. Set them with [MessageOptions.InlineAttachments].
+	Inline AttachmentType = "inline"
+	// URL attachments name a remote resource, in [Attachment.URL], for the
+	// CLI to fetch.
+	URL AttachmentType = "url"
+)
+
+// defaultMaxInlineAttachmentBytes is the default of
+// [ClientOptions.MaxInlineAttachmentBytes].
+const defaultMaxInlineAttachmentBytes = 10 << 20
+
+// ErrAttachmentTooLarge matches errors from [Session.Send] when an inline
+// attachment exceeds [ClientOptions.MaxInlineAttachmentBytes]. Use
+// [errors.As] with a *[AttachmentTooLargeError] for the sizes.
+var ErrAttachmentTooLarge = errors.New("attachment too large")
+
+// AttachmentTooLargeError is returned by [Session.Send] when an inline
+// attachment exceeds [ClientOptions.MaxInlineAttachmentBytes]. Nothing is
+// sent. It matches [ErrAttachmentTooLarge].
+type AttachmentTooLargeError struct {
+	// DisplayName is the attachment's display name, or a description of it
+	// if it has none
+	DisplayName string
+	// Size is the attachment's size and Limit the limit in effect, in bytes
+	Size  int
+	Limit int
+}
+
+func (e *AttachmentTooLargeError) Error() string {
+	return fmt.Sprintf("%v: inline attachment %s is %d bytes, over the limit of %d bytes; raise ClientOptions.MaxInlineAttachmentBytes or attach a file instead", ErrAttachmentTooLarge, e.DisplayName, e.Size, e.Limit)
+}
+
+func (e *AttachmentTooLargeError) Is(target error) bool { return target == ErrAttachmentTooLarge }
+
+// InlineAttachment is an attachment whose content is sent in the message,
+// such as a diff or a log fetched from storage, so nothing has to be written
+// to disk for the CLI to read. Text is sent as it is and binary content
+// base64 encoded.
+type InlineAttachment struct {
+	// Content is the attachment's content
+	Content []byte
+	// MimeType is the content's media type, e.g. "text/x-diff". Detected from
+	// Content when empty.
+	MimeType string
+	// DisplayName names the attachment in the prompt and the session history,
+	// e.g. "build.log"
+	DisplayName string
+}
+
+// label returns a human-readable name for the attachment in error messages.
+func (a InlineAttachment) label() string {
+	if a.DisplayName != "" {
+		return a.DisplayName
+	}
+	return "<inline " + a.mimeType() + ">"
+}
+
+// mimeType returns the attachment's media type, detected if unset.
+func (a InlineAttachment) mimeType() string {
+	if a.MimeType != "" {
+		return a.MimeType
+	}
+	return http.DetectContentType(a.Content)
+}
+
+// isText reports whether content can be sent as JSON text as it is.
+func isText(content []byte) bool {
+	return utf8.Valid(content) && !bytes.ContainsRune(content, 0)
+}
+
+// sendAttachment is an attachment in session.send: an [Attachment] plus the
+// content of an inline attachment, which the generated type cannot hold.
+type sendAttachment struct {
+	Attachment
+	MimeType string `json:"mimeType,omitempty"`
+	Content  string `json:"content,omitempty"`
+	// Encoding is "base64" for binary Content and empty for text
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// sendAttachments wraps attachments for session.send.
+func sendAttachments(attachments []Attachment) []sendAttachment {
+	if len(attachments) == 0 {
+		return nil
+	}
+	out := make([]sendAttachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		out = append(out, sendAttachment{Attachment: attachment})
+	}
+	return out
+}
+
+// encodeInline converts an inline attachment for session.send.
+func encodeInline(a InlineAttachment) sendAttachment {
+	out := sendAttachment{Attachment: Attachment{Type: Inline}, MimeType: a.mimeType()}
+	if a.DisplayName != "" {
+		out.DisplayName = String(a.DisplayName)
+	}
+	if isText(a.Content) {
+		out.Content = string(a.Content)
+	} else {
+		out.Content = base64.StdEncoding.EncodeToString(a.Content)
+		out.Encoding = "base64"
+	}
+	return out
+}
+
+// supportsAttachmentType reports whether the connected CLI accepts
+// attachments of type kind. The built-in types need no capability.
+func (s *Session) supportsAttachmentType(kind AttachmentType) bool {
+	if s.capabilities == nil {
+		return false
+	}
+	capabilities := s.capabilities()
+	return capabilities != nil && slices.Contains(capabilities.Attachments, string(kind))
+}
+
+// checkAttachments fails if a message has inline or URL attachments the CLI
+// does not accept, an invalid URL, or inline attachments over the size
+// limit.
+func (s *Session) checkAttachments(options MessageOptions) error {
+	for _, attachment := range options.Attachments {
+		switch attachment.Type {
+		case Inline:
+			return errors.New("inline attachments are set with MessageOptions.InlineAttachments")
+		case URL:
+			if attachment.URL == nil {
+				return errors.New("URL attachment has no URL")
+			}
+			u, err := url.Parse(*attachment.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("URL attachment %q is not an http or https URL", *attachment.URL)
+			}
+			if !s.supportsAttachmentType(URL) {
+				return fmt.Errorf("%w: URL attachments need a CLI that accepts them", ErrUnsupportedByCLI)
+			}
+		}
+	}
+	if len(options.InlineAttachments) == 0 {
+		return nil
+	}
+	if !s.supportsAttachmentType(Inline) {
+		return fmt.Errorf("%w: MessageOptions.InlineAttachments need a CLI that accepts inline attachments", ErrUnsupportedByCLI)
+	}
+	limit := s.maxInlineBytes
+	if limit == 0 {
+		limit = defaultMaxInlineAttachmentBytes
+	}
+	for _, attachment := range options.InlineAttachments {
+		if limit > 0 && len(attachment.Content) > limit {
+			return &AttachmentTooLargeError{DisplayName: attachment.label(), Size: len(attachment.Content), Limit: limit}
+		}
+	}
+	return nil
+}
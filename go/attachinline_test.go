@@ -0,0 +1,117 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSession_InlineAttachments(t *testing.T) {
+	newAttachmentSession := func(t *testing.T, accepted ...string) (*Session, func() []json.RawMessage) {
+		var mu sync.Mutex
+		var sent []json.RawMessage
+		session, _ := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method != "session.send" {
+				return nil, nil
+			}
+			var req struct {
+				Attachments []json.RawMessage `json:"attachments"`
+			}
+			json.Unmarshal(params, &req)
+			mu.Lock()
+			sent = append(sent, req.Attachments...)
+			mu.Unlock()
+			return sessionSendResponse{MessageID: "m1"}, nil
+		})
+		session.capabilities = func() *ServerCapabilities { return &ServerCapabilities{Attachments: accepted} }
+		return session, func() []json.RawMessage {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]json.RawMessage(nil), sent...)
+		}
+	}
+
+	t.Run("sends text as it is and binary content base64 encoded", func(t *testing.T) {
+		session, sent := newAttachmentSession(t, "inline", "url")
+		_, err := session.Send(t.Context(), MessageOptions{
+			Prompt:      "Why did the build fail?",
+			Attachments: []Attachment{{Type: URL, URL: String("https://ci.example/runs/42")}},
+			InlineAttachments: []InlineAttachment{
+				{Content: []byte("--- a.go\n+++ a.go\n"), MimeType: "text/x-diff", DisplayName: "change.diff"},
+				{Content: []byte{0x1f, 0x8b, 0x08, 0x00}, DisplayName: "logs.gz"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		want := []string{
+			`{"type":"url","url":"https://ci.example/runs/42"}`,
+			`{"displayName":"change.diff","type":"inline","mimeType":"text/x-diff","content":"--- a.go\n+++ a.go\n"}`,
+			`{"displayName":"logs.gz","type":"inline","mimeType":"application/x-gzip","content":"H4sIAA==","encoding":"base64"}`,
+		}
+		got := sent()
+		if len(got) != len(want) {
+			t.Fatalf("Expected %d attachments, got %d", len(want), len(got))
+		}
+		for i := range want {
+			if string(got[i]) != want[i] {
+				t.Errorf("Attachment %d: expected %s, got %s", i, want[i], got[i])
+			}
+		}
+	})
+
+	t.Run("rejects attachments over the limit before sending", func(t *testing.T) {
+		session, sent := newAttachmentSession(t, "inline")
+		session.maxInlineBytes = 1024
+		_, err := session.Send(t.Context(), MessageOptions{
+			Prompt:            "Summarize",
+			InlineAttachments: []InlineAttachment{{Content: []byte(strings.Repeat("x", 2048)), DisplayName: "huge.log"}},
+		})
+		var tooLarge *AttachmentTooLargeError
+		if !errors.As(err, &tooLarge) || !errors.Is(err, ErrAttachmentTooLarge) {
+			t.Fatalf("Expected an AttachmentTooLargeError, got %v", err)
+		}
+		if tooLarge.DisplayName != "huge.log" || tooLarge.Size != 2048 || tooLarge.Limit != 1024 || !strings.Contains(err.Error(), "huge.log is 2048 bytes") {
+			t.Errorf("Unexpected error %+v: %v", tooLarge, err)
+		}
+		if len(sent()) != 0 {
+			t.Error("Expected nothing to be sent")
+		}
+
+		session.maxInlineBytes = -1
+		if _, err := session.Send(t.Context(), MessageOptions{InlineAttachments: []InlineAttachment{{Content: make([]byte, 2048)}}}); err != nil {
+			t.Errorf("Expected a negative limit to disable the check, got %v", err)
+		}
+	})
+
+	t.Run("fails on CLIs that do not accept the type", func(t *testing.T) {
+		session, sent := newAttachmentSession(t)
+		for _, options := range []MessageOptions{
+			{InlineAttachments: []InlineAttachment{{Content: []byte("log")}}},
+			{Attachments: []Attachment{{Type: URL, URL: String("https://example.com/a")}}},
+		} {
+			if _, err := session.Send(t.Context(), options); !errors.Is(err, ErrUnsupportedByCLI) {
+				t.Errorf("Expected ErrUnsupportedByCLI, got %v", err)
+			}
+		}
+		if len(sent()) != 0 {
+			t.Error("Expected nothing to be sent")
+		}
+	})
+
+	t.Run("validates URL attachments", func(t *testing.T) {
+		session, _ := newAttachmentSession(t, "url")
+		for _, attachment := range []Attachment{
+			{Type: URL},
+			{Type: URL, URL: String("file:///etc/passwd")},
+			{Type: URL, URL: String("https://")},
+			{Type: Inline},
+		} {
+			if _, err := session.Send(t.Context(), MessageOptions{Attachments: []Attachment{attachment}}); err == nil {
+				t.Errorf("Expected %+v to be rejected", attachment)
+			}
+		}
+	})
+}
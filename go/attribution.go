@@ -0,0 +1,17 @@
+package copilot
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var attributionTagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]{0,63}$`)
+
+// validateAttributionTag checks the format of [ClientOptions.IntegrationID]
+// and [MessageOptions.Initiator] values.
+func validateAttributionTag(field, value string) error {
+	if !attributionTagPattern.MatchString(value) {
+		return fmt.Errorf("invalid %s %q: must be 1-64 characters of letters, digits, '.', '_', '-' or '/', starting with a letter or digit", field, value)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package copilot
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// AutoApprovePolicy answers permission requests in the SDK, without calling
+// [SessionConfig.OnPermissionRequest], for unattended runs inside a sandbox.
+//
+// Requests are decided in this order:
+//  1. A request whose kind is in DenyKinds is denied.
+//  2. A request whose kind is in ApproveKinds is approved, except that write
+//     requests must also target a file under one of WritePaths when WritePaths
+//     is set.
+//  3. Anything else is passed to OnPermissionRequest, or denied if no handler
+//     is set.
+//
+// Permission request kinds are "shell", "write", "read", "url", "mcp", and
+// "custom-tool".
+type AutoApprovePolicy struct {
+	// ApproveKinds lists the request kinds to approve. "*" approves every kind
+	// not listed in DenyKinds.
+	ApproveKinds []string
+	// DenyKinds lists the request kinds to deny. It takes precedence over
+	// ApproveKinds and WritePaths.
+	DenyKinds []string
+	// WritePaths restricts approved write requests to files under these
+	// directories. Relative paths are resolved against the session's
+	// WorkingDirectory, and are an error when it is not set. Paths are
+	// compared lexically; symbolic links are not resolved. Empty allows
+	// writes anywhere.
+	WritePaths []string
+	// OnDecision is called after the policy approves or denies a request, for
+	// logging. It is not called for requests passed to OnPermissionRequest.
+	OnDecision func(request PermissionRequest, result PermissionRequestResult)
+}
+
+// AutoApproveStats counts permission requests decided by an [AutoApprovePolicy].
+type AutoApproveStats struct {
+	// Approved is the number of requests approved by the policy
+	Approved int
+	// Denied is the number of requests denied by the policy
+	Denied int
+	// Deferred is the number of requests passed to OnPermissionRequest or
+	// denied because no handler was set
+	Deferred int
+}
+
+// autoApprover applies an AutoApprovePolicy to a session's permission requests.
+type autoApprover struct {
+	policy     AutoApprovePolicy
+	workDir    string
+	approveAll bool
+	approve    map[string]bool
+	deny       map[string]bool
+	writeRoots []string
+
+	mu    sync.Mutex
+	stats AutoApproveStats
+}
+
+func newAutoApprover(policy AutoApprovePolicy, workingDirectory string) *autoApprover {
+	a := &autoApprover{
+		policy:  policy,
+		workDir: workingDirectory,
+		approve: make(map[string]bool),
+		deny:    make(map[string]bool),
+	}
+	for _, kind := range policy.ApproveKinds {
+		if kind == "*" {
+			a.approveAll = true
+		}
+		a.approve[kind] = true
+	}
+	for _, kind := range policy.DenyKinds {
+		a.deny[kind] = true
+	}
+	for _, path := range policy.WritePaths {
+		a.writeRoots = append(a.writeRoots, resolvePolicyPath(path, workingDirectory))
+	}
+	return a
+}
+
+// validate checks that relative WritePaths have a working directory to be
+// resolved against. The SDK does not know the CLI's working directory, so the
+// SDK process's would otherwise be used.
+func (p *AutoApprovePolicy) validate(workingDirectory string) error {
+	if workingDirectory != "" {
+		return nil
+	}
+	for _, path := range p.WritePaths {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("AutoApprove.WritePaths entry %q is relative but WorkingDirectory is not set; use an absolute path or set WorkingDirectory", path)
+		}
+	}
+	return nil
+}
+
+// decide returns the policy's answer to a request. The second return value is
+// false if the request should be passed to the permission handler.
+func (a *autoApprover) decide(request PermissionRequest) (PermissionRequestResult, bool) {
+	var result PermissionRequestResult
+	switch {
+	case a.deny[request.Kind]:
+		result = DeniedByRules()
+	case (a.approveAll || a.approve[request.Kind]) && a.writeAllowed(request):
+		result = Approved()
+	default:
+		a.mu.Lock()
+		a.stats.Deferred++
+		a.mu.Unlock()
+		return PermissionRequestResult{}, false
+	}
+
+	a.mu.Lock()
+	if result.Approved() {
+		a.stats.Approved++
+	} else {
+		a.stats.Denied++
+	}
+	a.mu.Unlock()
+
+	if a.policy.OnDecision != nil {
+		a.policy.OnDecision(request, result)
+	}
+	return result, true
+}
+
+// writeAllowed reports whether a request passes the WritePaths allowlist.
+// Requests other than writes always pass.
+func (a *autoApprover) writeAllowed(request PermissionRequest) bool {
+	if request.Kind != "write" || len(a.writeRoots) == 0 {
+		return true
+	}
+	path := permissionRequestPath(request)
+	if path == "" {
+		return false
+	}
+	if !filepath.IsAbs(path) && a.workDir == "" {
+		// Unknown base directory
+		return false
+	}
+	path = resolvePolicyPath(path, a.workDir)
+	for _, root := range a.writeRoots {
+		if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshot returns the current counters.
+func (a *autoApprover) snapshot() AutoApproveStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.stats
+}
+
+// permissionRequestPath returns the file a write request targets, or "".
+func permissionRequestPath(request PermissionRequest) string {
+	for _, key := range []string{"fileName", "path"} {
+		if path, ok := request.Extra[key].(string); ok && path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// resolvePolicyPath makes path absolute, relative to workingDirectory.
+func resolvePolicyPath(path, workingDirectory string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDirectory, path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// AutoApproveStats returns the counters of the session's auto-approve policy.
+// All counters are zero unless [SessionConfig.AutoApprove] or
+// [ResumeSessionConfig.AutoApprove] was set.
+func (s *Session) AutoApproveStats() AutoApproveStats {
+	if s.autoApprove == nil {
+		return AutoApproveStats{}
+	}
+	return s.autoApprove.snapshot()
+}
@@ -0,0 +1,181 @@
+package copilot
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSession_AutoApprove(t *testing.T) {
+	workDir := t.TempDir()
+	writeRequest := func(path string) PermissionRequest {
+		return PermissionRequest{Kind: "write", Extra: map[string]any{"kind": "write", "fileName": path}}
+	}
+
+	newPolicySession := func(policy AutoApprovePolicy, handler PermissionHandlerFunc) *Session {
+		session := &Session{SessionID: "s1"}
+		session.registerPermissionHandler(handler)
+		session.autoApprove = newAutoApprover(policy, workDir)
+		return session
+	}
+
+	t.Run("approves listed kinds without calling the handler", func(t *testing.T) {
+		var logged []string
+		session := newPolicySession(AutoApprovePolicy{
+			ApproveKinds: []string{"shell", "read"},
+			OnDecision: func(request PermissionRequest, result PermissionRequestResult) {
+				logged = append(logged, request.Kind+":"+string(result.Kind))
+			},
+		}, func(PermissionRequest, PermissionInvocation) (PermissionRequestResult, error) {
+			t.Error("Expected handler not to be called")
+			return PermissionRequestResult{}, nil
+		})
+
+		for _, kind := range []string{"shell", "read"} {
+			result, err := session.handlePermissionRequest(PermissionRequest{Kind: kind})
+			if err != nil || result.Kind != PermissionApproved {
+				t.Errorf("Expected %s to be approved, got %v %v", kind, result, err)
+			}
+		}
+		if len(logged) != 2 || logged[0] != "shell:approved" {
+			t.Errorf("Expected decisions to be logged, got %v", logged)
+		}
+		if stats := session.AutoApproveStats(); stats != (AutoApproveStats{Approved: 2}) {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("deny list takes precedence", func(t *testing.T) {
+		session := newPolicySession(AutoApprovePolicy{
+			ApproveKinds: []string{"*"},
+			DenyKinds:    []string{"url", "write"},
+			WritePaths:   []string{"."},
+		}, nil)
+
+		for _, request := range []PermissionRequest{{Kind: "url"}, writeRequest("out.txt")} {
+			result, _ := session.handlePermissionRequest(request)
+			if result.Kind != PermissionDeniedByRules {
+				t.Errorf("Expected %s to be denied by rules, got %s", request.Kind, result.Kind)
+			}
+		}
+		if result, _ := session.handlePermissionRequest(PermissionRequest{Kind: "mcp"}); result.Kind != PermissionApproved {
+			t.Errorf("Expected wildcard to approve mcp, got %s", result.Kind)
+		}
+		if stats := session.AutoApproveStats(); stats != (AutoApproveStats{Approved: 1, Denied: 2}) {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("restricts writes to the path allowlist", func(t *testing.T) {
+		var deferred []string
+		session := newPolicySession(AutoApprovePolicy{
+			ApproveKinds: []string{"write"},
+			WritePaths:   []string{"out", filepath.Join(workDir, "tmp")},
+		}, func(request PermissionRequest, _ PermissionInvocation) (PermissionRequestResult, error) {
+			deferred = append(deferred, permissionRequestPath(request))
+			return DeniedByUser(""), nil
+		})
+
+		tests := []struct {
+			path string
+			want PermissionResultKind
+		}{
+			{"out/report.txt", PermissionApproved},
+			{filepath.Join(workDir, "out", "nested", "a.txt"), PermissionApproved},
+			{filepath.Join(workDir, "tmp", "b.txt"), PermissionApproved},
+			{"out/../secrets.txt", PermissionDeniedByUser},
+			{"outside.txt", PermissionDeniedByUser},
+			{filepath.Join(workDir, "output", "c.txt"), PermissionDeniedByUser},
+		}
+		for _, tt := range tests {
+			result, _ := session.handlePermissionRequest(writeRequest(tt.path))
+			if result.Kind != tt.want {
+				t.Errorf("Expected %s for %s, got %s", tt.want, tt.path, result.Kind)
+			}
+		}
+		if len(deferred) != 3 {
+			t.Errorf("Expected 3 requests passed to the handler, got %v", deferred)
+		}
+		if stats := session.AutoApproveStats(); stats != (AutoApproveStats{Approved: 3, Deferred: 3}) {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("denies unmatched requests without a handler", func(t *testing.T) {
+		session := newPolicySession(AutoApprovePolicy{ApproveKinds: []string{"read"}}, nil)
+		result, _ := session.handlePermissionRequest(PermissionRequest{Kind: "shell"})
+		if result.Kind != PermissionDeniedNoApprovalRule {
+			t.Errorf("Expected default denial, got %s", result.Kind)
+		}
+	})
+
+	t.Run("sessions may omit the handler when a policy is set", func(t *testing.T) {
+		cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.create" {
+				return createSessionResponse{SessionID: "s1"}, nil
+			}
+			return nil, nil
+		})
+		client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+		t.Cleanup(func() { client.ForceStop() })
+
+		session, err := client.CreateSession(t.Context(), &SessionConfig{
+			WorkingDirectory: workDir,
+			AutoApprove:      &AutoApprovePolicy{ApproveKinds: []string{"write"}, WritePaths: []string{"."}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		response, rpcErr := client.handlePermissionRequest(permissionRequestRequest{
+			SessionID: session.SessionID,
+			Request:   writeRequest("notes.md"),
+		})
+		if rpcErr != nil || response.Result.Kind != PermissionApproved {
+			t.Errorf("Expected write in the working directory to be approved, got %+v %v", response, rpcErr)
+		}
+	})
+
+	t.Run("rejects relative write paths without a working directory", func(t *testing.T) {
+		var created bool
+		cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.create" {
+				created = true
+				return createSessionResponse{SessionID: "s1"}, nil
+			}
+			return nil, nil
+		})
+		client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+		t.Cleanup(func() { client.ForceStop() })
+
+		_, err := client.CreateSession(t.Context(), &SessionConfig{
+			AutoApprove: &AutoApprovePolicy{ApproveKinds: []string{"write"}, WritePaths: []string{"out"}},
+		})
+		if err == nil || !strings.Contains(err.Error(), `"out"`) {
+			t.Errorf("Expected an error naming the relative path, got %v", err)
+		}
+		if created {
+			t.Error("Expected no session.create request")
+		}
+
+		if _, err := client.CreateSession(t.Context(), &SessionConfig{
+			AutoApprove: &AutoApprovePolicy{ApproveKinds: []string{"write"}, WritePaths: []string{workDir}},
+		}); err != nil {
+			t.Errorf("Expected absolute write paths to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("does not resolve relative request paths without a working directory", func(t *testing.T) {
+		session := &Session{SessionID: "s1"}
+		session.autoApprove = newAutoApprover(AutoApprovePolicy{ApproveKinds: []string{"write"}, WritePaths: []string{workDir}}, "")
+
+		result, _ := session.handlePermissionRequest(writeRequest(filepath.Join(workDir, "a.txt")))
+		if result.Kind != PermissionApproved {
+			t.Errorf("Expected an absolute path under the allowlist to be approved, got %s", result.Kind)
+		}
+		result, _ = session.handlePermissionRequest(writeRequest("a.txt"))
+		if result.Kind == PermissionApproved {
+			t.Error("Expected a relative path to be passed on, not resolved against the process's directory")
+		}
+	})
+}
@@ -0,0 +1,263 @@
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Defaults of [RestartBackoff].
+const (
+	defaultRestartInitialDelay = time.Second
+	defaultRestartMaxDelay     = 30 * time.Second
+	defaultRestartMaxAttempts  = 5
+)
+
+// restartStableAfter is how long a restarted CLI must stay up before its
+// restarts stop counting towards [RestartBackoff.MaxAttempts].
+const restartStableAfter = time.Minute
+
+// RestartBackoff configures how [ClientOptions.AutoRestart] restarts a CLI
+// process that exited unexpectedly.
+type RestartBackoff struct {
+	// InitialDelay is how long to wait before the second restart attempt.
+	// The first attempt is immediate, and each further wait doubles.
+	// Default: 1 second.
+	InitialDelay time.Duration
+	// MaxDelay caps the wait between attempts. Default: 30 seconds.
+	MaxDelay time.Duration
+	// MaxAttempts is how many restarts are attempted before the client gives
+	// up and marks its sessions destroyed. Restarts of a CLI that exits again
+	// within a minute count towards the same limit, so a CLI that keeps
+	// crashing is not restarted forever. Default: 5.
+	MaxAttempts int
+}
+
+// ConnectionStateChange describes a change of the client's
+// [ConnectionState], as passed to [ClientOptions.OnConnectionStateChange].
+type ConnectionStateChange struct {
+	// State is the client's new state
+	State ConnectionState
+	// Err is why the client moved to StateError, such as the connection to
+	// the CLI being lost or a restart attempt failing
+	Err error
+	// Attempt is the number of the automatic restart in progress, starting
+	// at 1, or 0 outside of automatic restarts
+	Attempt int
+}
+
+// autoRestarter tracks the automatic restarts of a client's CLI process.
+type autoRestarter struct {
+	mu       sync.Mutex
+	cancel   context.CancelFunc // stops the restart in progress, if any
+	attempts int                // restarts since the CLI last stayed up
+	last     time.Time          // when the last restart succeeded
+	current  int                // attempt reported in state changes
+}
+
+// delay returns how long to wait before the given attempt.
+func (b RestartBackoff) delay(attempt int) time.Duration {
+	return Backoff{Initial: b.InitialDelay, Max: b.MaxDelay}.Next(attempt - 1)
+}
+
+// restartBackoff returns the client's backoff with defaults applied.
+func (c *Client) restartBackoff() RestartBackoff {
+	var backoff RestartBackoff
+	if c.options.RestartBackoff != nil {
+		backoff = *c.options.RestartBackoff
+	}
+	if backoff.InitialDelay <= 0 {
+		backoff.InitialDelay = defaultRestartInitialDelay
+	}
+	if backoff.MaxDelay <= 0 {
+		backoff.MaxDelay = defaultRestartMaxDelay
+	}
+	if backoff.MaxAttempts <= 0 {
+		backoff.MaxAttempts = defaultRestartMaxAttempts
+	}
+	return backoff
+}
+
+// scheduleAutoRestart starts restarting the CLI after its connection was
+// lost with cause. Must be called with startStopMux held.
+func (c *Client) scheduleAutoRestart(cause error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.restarter.mu.Lock()
+	if c.restarter.cancel != nil {
+		c.restarter.cancel()
+	}
+	c.restarter.cancel = cancel
+	if time.Since(c.restarter.last) >= restartStableAfter {
+		c.restarter.attempts = 0
+	}
+	c.restarter.mu.Unlock()
+	go c.restartCLI(ctx, cause)
+}
+
+// cancelAutoRestart stops a restart in progress, if any.
+func (c *Client) cancelAutoRestart() {
+	c.restarter.mu.Lock()
+	defer c.restarter.mu.Unlock()
+	if c.restarter.cancel != nil {
+		c.restarter.cancel()
+		c.restarter.cancel = nil
+	}
+	c.restarter.attempts = 0
+	c.restarter.current = 0
+}
+
+// restartCLI restarts the CLI and re-attaches the client's sessions,
+// backing off between failed attempts, until it succeeds, gives up, or ctx
+// is cancelled by Stop.
+func (c *Client) restartCLI(ctx context.Context, cause error) {
+	backoff := c.restartBackoff()
+	for {
+		c.restarter.mu.Lock()
+		c.restarter.attempts++
+		attempt := c.restarter.attempts
+		c.restarter.mu.Unlock()
+
+		if attempt > backoff.MaxAttempts {
+			c.giveUpRestart(ctx, fmt.Errorf("CLI could not be restarted after %d attempts: %w", backoff.MaxAttempts, cause))
+			return
+		}
+		timer := time.NewTimer(backoff.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		err := c.restartOnce(ctx, attempt)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			return
+		}
+		c.logger.Warn("restart attempt failed", "attempt", attempt, "maxAttempts", backoff.MaxAttempts, "error", err)
+		cause = err
+	}
+}
+
+// restartOnce replaces the lost connection with a new CLI process and
+// re-attaches the client's sessions. Sessions that cannot be re-attached are
+// marked destroyed, but do not fail the attempt.
+func (c *Client) restartOnce(ctx context.Context, attempt int) error {
+	sessions := c.trackedSessions()
+
+	c.startStopMux.Lock()
+	if err := ctx.Err(); err != nil {
+		// Stopped while waiting for the lock
+		c.startStopMux.Unlock()
+		return err
+	}
+	c.restarter.mu.Lock()
+	c.restarter.current = attempt
+	c.restarter.mu.Unlock()
+	c.disconnectLocked()
+	err := c.startLocked(ctx)
+	c.startStopMux.Unlock()
+	if err != nil {
+		return err
+	}
+
+	c.restarter.mu.Lock()
+	c.restarter.last = time.Now()
+	c.restarter.current = 0
+	c.restarter.mu.Unlock()
+	if err := c.reattachSessions(sessions); err != nil {
+		c.logger.Error("failed to reattach sessions after restart", "error", err)
+	}
+	return nil
+}
+
+// giveUpRestart marks the client's sessions destroyed after the CLI could not
+// be restarted, and reports err.
+func (c *Client) giveUpRestart(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	c.logger.Error("giving up restarting the CLI", "error", err)
+	for _, session := range c.trackedSessions() {
+		c.dropSession(session, err.Error())
+	}
+
+	c.startStopMux.Lock()
+	defer c.startStopMux.Unlock()
+	c.restarter.mu.Lock()
+	c.restarter.attempts = 0
+	c.restarter.current = 0
+	c.restarter.mu.Unlock()
+	c.setStateLocked(StateError, err)
+}
+
+// setStateLocked changes the client's state and reports the change to
+// [ClientOptions.OnConnectionStateChange]. Must be called with startStopMux
+// held.
+func (c *Client) setStateLocked(state ConnectionState, err error) {
+	if c.state == state && err == nil {
+		return
+	}
+	c.state = state
+	if c.options.OnConnectionStateChange == nil {
+		return
+	}
+	c.restarter.mu.Lock()
+	attempt := c.restarter.current
+	c.restarter.mu.Unlock()
+	c.stateChanges.push(ConnectionStateChange{State: state, Err: err, Attempt: attempt})
+}
+
+// stateNotifier calls [ClientOptions.OnConnectionStateChange] with state
+// changes in order, on a goroutine of its own, so the callback may call the
+// client.
+type stateNotifier struct {
+	mu      sync.Mutex
+	pending []ConnectionStateChange
+	running bool
+	notify  func(ConnectionStateChange)
+}
+
+// push queues a change, starting a goroutine to deliver it if none is
+// running.
+func (n *stateNotifier) push(change ConnectionStateChange) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.pending = append(n.pending, change)
+	if !n.running {
+		n.running = true
+		go n.drain()
+	}
+}
+
+// drain delivers queued changes until there are none left.
+func (n *stateNotifier) drain() {
+	for {
+		n.mu.Lock()
+		if len(n.pending) == 0 {
+			n.running = false
+			n.mu.Unlock()
+			return
+		}
+		change := n.pending[0]
+		n.pending = n.pending[1:]
+		n.mu.Unlock()
+		n.notify(change)
+	}
+}
+
+// notifyStateChange calls [ClientOptions.OnConnectionStateChange], recovering
+// from panics.
+func (c *Client) notifyStateChange(change ConnectionStateChange) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.diagnostics.recordPanic("connection state callback", "", r)
+			c.logger.Error("connection state callback panicked", "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+	c.options.OnConnectionStateChange(change)
+}
@@ -0,0 +1,264 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newScriptCLI writes a CLI script that reports the fake CLI's port, as the
+// CLI does in TCP mode, and runs until killed. Once the marker file exists,
+// the script exits instead, like a CLI that crashes on startup.
+func newScriptCLI(t *testing.T, cli *fakeCLI) (script, marker string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell script")
+	}
+	dir := t.TempDir()
+	script = filepath.Join(dir, "copilot")
+	marker = filepath.Join(dir, "crash-on-start")
+	port := cli.addr()[strings.LastIndex(cli.addr(), ":")+1:]
+	body := fmt.Sprintf("#!/bin/sh\n[ -e %q ] && exit 1\necho \"CLI server listening on port %s\"\nexec sleep 60\n", marker, port)
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return script, marker
+}
+
+// stateRecorder records the changes passed to OnConnectionStateChange.
+type stateRecorder struct {
+	mu      sync.Mutex
+	changes []ConnectionStateChange
+}
+
+func (r *stateRecorder) record(change ConnectionStateChange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, change)
+}
+
+// waitFor waits for a change matching match and returns the changes so far.
+func (r *stateRecorder) waitFor(t *testing.T, match func(ConnectionStateChange) bool) []ConnectionStateChange {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.mu.Lock()
+		changes := append([]ConnectionStateChange(nil), r.changes...)
+		r.mu.Unlock()
+		for _, change := range changes {
+			if match(change) {
+				return changes
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for a state change, got %+v", changes)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// crash kills the client's CLI process and drops its connection.
+func crash(client *Client, cli *fakeCLI) {
+	if p := client.osProcess.Load(); p != nil {
+		p.Kill()
+	}
+	cli.dropConnections()
+}
+
+func TestClient_AutoRestart(t *testing.T) {
+	var mu sync.Mutex
+	var resumed []string
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		var req struct {
+			SessionID string `json:"sessionId"`
+		}
+		json.Unmarshal(params, &req)
+		switch method {
+		case "session.create":
+			return createSessionResponse{SessionID: req.SessionID}, nil
+		case "session.resume":
+			mu.Lock()
+			resumed = append(resumed, req.SessionID)
+			mu.Unlock()
+			return resumeSessionResponse{SessionID: req.SessionID}, nil
+		case "session.getMessages":
+			<-release
+		case "session.send":
+			return sessionSendResponse{MessageID: "m1"}, nil
+		}
+		return nil, nil
+	})
+	newClient := func(t *testing.T, backoff *RestartBackoff) (*Client, *stateRecorder, string) {
+		script, marker := newScriptCLI(t, cli)
+		var states stateRecorder
+		client := NewClient(&ClientOptions{
+			CLIPath:                 script,
+			UseStdio:                Bool(false),
+			RestartBackoff:          backoff,
+			OnConnectionStateChange: states.record,
+		})
+		t.Cleanup(func() { client.ForceStop() })
+		if err := client.Start(t.Context()); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		return client, &states, marker
+	}
+
+	t.Run("restarts the CLI and re-attaches sessions", func(t *testing.T) {
+		client, states, _ := newClient(t, nil)
+		session, err := client.CreateSession(t.Context(), &SessionConfig{SessionID: "kept", OnPermissionRequest: PermissionHandler.ApproveAll})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		pending := make(chan error, 1)
+		go func() {
+			_, err := session.GetMessages(t.Context())
+			pending <- err
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		crash(client, cli)
+		select {
+		case err := <-pending:
+			if !errors.Is(err, ErrConnectionLost) {
+				t.Errorf("Expected ErrConnectionLost for the pending request, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the pending request to fail")
+		}
+
+		changes := states.waitFor(t, func(c ConnectionStateChange) bool { return c.State == StateConnected && c.Attempt == 1 })
+		var lost bool
+		for _, change := range changes {
+			lost = lost || (change.State == StateError && errors.Is(change.Err, ErrConnectionLost))
+		}
+		if !lost {
+			t.Errorf("Expected the lost connection to be reported, got %+v", changes)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			mu.Lock()
+			done := len(resumed) == 1 && resumed[0] == "kept"
+			mu.Unlock()
+			if done || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "still there?"}); err != nil {
+			t.Errorf("Expected the session to work after the restart, got %v (resumed %v)", err, resumed)
+		}
+	})
+
+	t.Run("re-attaches sessions while sends are in flight", func(t *testing.T) {
+		client, states, _ := newClient(t, nil)
+		session, err := client.CreateSession(t.Context(), &SessionConfig{SessionID: "busy", OnPermissionRequest: PermissionHandler.ApproveAll})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		// Run with -race: the restart re-attaches the session under these sends
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					session.Send(t.Context(), MessageOptions{Prompt: "are you there?"})
+					session.WorkspacePath()
+				}
+			}()
+		}
+		time.Sleep(20 * time.Millisecond)
+		crash(client, cli)
+		states.waitFor(t, func(c ConnectionStateChange) bool { return c.State == StateConnected && c.Attempt == 1 })
+		// The session is re-attached once it sends on the new connection
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			client.startStopMux.RLock()
+			done := session.rpcClient() == client.client
+			client.startStopMux.RUnlock()
+			if done || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		close(stop)
+		wg.Wait()
+
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "still there?"}); err != nil {
+			t.Errorf("Expected the session to work after the restart, got %v", err)
+		}
+	})
+
+	t.Run("gives up after MaxAttempts", func(t *testing.T) {
+		client, states, marker := newClient(t, &RestartBackoff{InitialDelay: 10 * time.Millisecond, MaxAttempts: 2})
+		session, err := client.CreateSession(t.Context(), &SessionConfig{SessionID: "lost", OnPermissionRequest: PermissionHandler.ApproveAll})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if err := os.WriteFile(marker, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		crash(client, cli)
+		changes := states.waitFor(t, func(c ConnectionStateChange) bool {
+			return c.State == StateError && c.Err != nil && strings.Contains(c.Err.Error(), "after 2 attempts")
+		})
+		attempts := 0
+		for _, change := range changes {
+			if change.State == StateConnecting && change.Attempt > 0 {
+				attempts++
+			}
+		}
+		if attempts != 2 {
+			t.Errorf("Expected 2 restart attempts, got %d: %+v", attempts, changes)
+		}
+		select {
+		case <-session.Done():
+		case <-time.After(time.Second):
+			t.Fatal("Expected the session to be destroyed")
+		}
+		if reason, _ := session.DestroyReason(); !strings.Contains(reason, "could not be restarted") {
+			t.Errorf("Unexpected destroy reason %q", reason)
+		}
+	})
+
+	t.Run("does not restart after Stop", func(t *testing.T) {
+		client, states, _ := newClient(t, nil)
+		if err := client.Stop(); err != nil {
+			t.Fatalf("Failed to stop: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		states.mu.Lock()
+		defer states.mu.Unlock()
+		if last := states.changes[len(states.changes)-1]; last.State != StateDisconnected {
+			t.Errorf("Expected the client to stay disconnected, got %+v", states.changes)
+		}
+	})
+}
+
+func TestRestartBackoff_Delay(t *testing.T) {
+	backoff := RestartBackoff{InitialDelay: time.Second, MaxDelay: 5 * time.Second}
+	want := []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, delay := range want {
+		if got := backoff.delay(i + 1); got != delay {
+			t.Errorf("Attempt %d: expected %v, got %v", i+1, delay, got)
+		}
+	}
+}
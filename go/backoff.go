@@ -0,0 +1,164 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Defaults of [Backoff].
+const (
+	defaultBackoffInitial     = time.Second
+	defaultBackoffMax         = 30 * time.Second
+	defaultBackoffMultiplier  = 2
+	defaultBackoffMaxAttempts = 3
+)
+
+// Backoff is an exponential backoff policy: how long to wait between the
+// attempts of an operation that failed, and how many attempts to make. Use
+// [Backoff.Next] in a retry loop of your own, or [Retry] to run one. The SDK
+// backs off with it too, e.g. between [ClientOptions.RetryPolicy] attempts
+// and automatic restarts.
+//
+// The zero value waits 1s and then 2s between 3 attempts.
+type Backoff struct {
+	// Initial is the wait after the first attempt. Default: 1 second.
+	Initial time.Duration
+	// Max caps the wait. Default: 30 seconds.
+	Max time.Duration
+	// Multiplier is how much each wait grows over the previous one. Values
+	// below 1 use the default of 2.
+	Multiplier float64
+	// Jitter is the fraction of each wait that is randomized, from 0 to 1,
+	// so that clients failing together do not retry together: a wait of d
+	// becomes a random duration between d*(1-Jitter) and d. Default: 0.
+	Jitter float64
+	// MaxAttempts is the most times [Retry] calls its function, including
+	// the first. Default: 3.
+	MaxAttempts int
+
+	// after is the clock Retry waits on; nil uses time.After
+	after func(time.Duration) <-chan time.Time
+	// random returns a number in [0, 1) for jitter; nil uses math/rand
+	random func() float64
+}
+
+// withDefaults returns the policy with defaults applied.
+func (b Backoff) withDefaults() Backoff {
+	if b.Initial <= 0 {
+		b.Initial = defaultBackoffInitial
+	}
+	if b.Max <= 0 {
+		b.Max = defaultBackoffMax
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = defaultBackoffMultiplier
+	}
+	b.Jitter = min(max(b.Jitter, 0), 1)
+	if b.MaxAttempts <= 0 {
+		b.MaxAttempts = defaultBackoffMaxAttempts
+	}
+	return b
+}
+
+// Next returns how long to wait after attempt number attempt, counting from
+// 1, failed: Initial, then Initial*Multiplier, and so on up to Max, less
+// the jitter. It returns 0 for attempts below 1.
+//
+// Example:
+//
+//	backoff := copilot.Backoff{Initial: 500 * time.Millisecond, Max: 10 * time.Second, Jitter: 0.2}
+//	for attempt := 1; ; attempt++ {
+//	    if err = deploy(ctx); err == nil || attempt == 5 {
+//	        break
+//	    }
+//	    time.Sleep(backoff.Next(attempt))
+//	}
+func (b Backoff) Next(attempt int) time.Duration {
+	if attempt < 1 {
+		return 0
+	}
+	b = b.withDefaults()
+	delay := float64(b.Initial)
+	for i := 1; i < attempt && delay < float64(b.Max); i++ {
+		delay *= b.Multiplier
+	}
+	wait := b.Max
+	if delay < float64(b.Max) {
+		wait = time.Duration(delay)
+	}
+	if b.Jitter > 0 {
+		random := rand.Float64
+		if b.random != nil {
+			random = b.random
+		}
+		wait -= time.Duration(b.Jitter * random() * float64(wait))
+	}
+	return wait
+}
+
+// wait blocks for d, and reports false if ctx is done first.
+func (b Backoff) wait(ctx context.Context, d time.Duration) bool {
+	after := time.After
+	if b.after != nil {
+		after = b.after
+	}
+	select {
+	case <-after(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// IsRetryable reports whether a failed call may succeed if made again: err
+// is a *[RateLimitError], matches [ErrRequestTimeout], [ErrConnectionLost]
+// or [ErrTooManyPendingRequests], or has a Retryable method in its chain
+// that returns true, which lets errors of your own types opt in.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var retryable interface{ Retryable() bool }
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+	var rateLimitErr *RateLimitError
+	return errors.As(err, &rateLimitErr) ||
+		errors.Is(err, ErrRequestTimeout) ||
+		errors.Is(err, ErrConnectionLost) ||
+		errors.Is(err, ErrTooManyPendingRequests)
+}
+
+// Retry calls fn until it succeeds, fails with an error that is not
+// retryable (see [IsRetryable]), or has been called policy.MaxAttempts
+// times, waiting policy.Next between attempts. A *[RateLimitError] with a
+// RetryAfter waits that long instead. It returns fn's last error, or, if
+// ctx is done while waiting, an error matching both ctx's error and fn's
+// last error.
+//
+// Example:
+//
+//	err := copilot.Retry(ctx, copilot.Backoff{MaxAttempts: 5, Jitter: 0.2}, func(ctx context.Context) error {
+//	    _, err := session.SendAndWait(ctx, copilot.MessageOptions{Prompt: "Summarize the open issues"})
+//	    return err
+//	})
+func Retry(ctx context.Context, policy Backoff, fn func(ctx context.Context) error) error {
+	policy = policy.withDefaults()
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= policy.MaxAttempts || !IsRetryable(err) {
+			return err
+		}
+		wait := policy.Next(attempt)
+		var rateLimitErr *RateLimitError
+		if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+			wait = rateLimitErr.RetryAfter
+		}
+		if !policy.wait(ctx, wait) {
+			return fmt.Errorf("%w, after attempt %d failed: %w", context.Cause(ctx), attempt, err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBackoff_Next(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		want    []time.Duration // for attempts 0, 1, 2, ...
+	}{
+		{"defaults", Backoff{}, []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second}},
+		{"multiplier and cap", Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 3}, []time.Duration{0, 100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, time.Second}},
+		{"constant", Backoff{Initial: time.Second, Multiplier: 1}, []time.Duration{0, time.Second, time.Second, time.Second}},
+		{"jitter", Backoff{Jitter: 0.5, random: func() float64 { return 0.5 }}, []time.Duration{0, 750 * time.Millisecond, 1500 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for attempt, want := range tt.want {
+				if got := tt.backoff.Next(attempt); got != want {
+					t.Errorf("Attempt %d: expected %v, got %v", attempt, want, got)
+				}
+			}
+		})
+	}
+
+	t.Run("does not overflow", func(t *testing.T) {
+		if got := (Backoff{Max: time.Hour}).Next(1000); got != time.Hour {
+			t.Errorf("Expected the cap, got %v", got)
+		}
+	})
+
+	t.Run("random jitter stays in range", func(t *testing.T) {
+		backoff := Backoff{Initial: time.Second, Jitter: 0.3}
+		for i := 0; i < 100; i++ {
+			if got := backoff.Next(2); got < 1400*time.Millisecond || got > 2*time.Second {
+				t.Fatalf("Expected a wait between 1.4s and 2s, got %v", got)
+			}
+		}
+	})
+}
+
+type retryableTestError struct{ retryable bool }
+
+func (e *retryableTestError) Error() string   { return fmt.Sprintf("retryable: %v", e.retryable) }
+func (e *retryableTestError) Retryable() bool { return e.retryable }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("boom"), false},
+		{&RateLimitError{Message: "slow down"}, true},
+		{fmt.Errorf("ping: %w", classifyError(ErrRequestTimeout)), true},
+		{ErrConnectionLost, true},
+		{ErrTooManyPendingRequests, true},
+		{ErrClientStopped, false},
+		{context.Canceled, false},
+		{fmt.Errorf("wrapped: %w", &retryableTestError{retryable: true}), true},
+		{&retryableTestError{retryable: false}, false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v): expected %v, got %v", tt.err, tt.want, got)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	// fakeClock returns a backoff that records its waits instead of waiting.
+	fakeClock := func(backoff Backoff) (Backoff, *[]time.Duration) {
+		var waits []time.Duration
+		backoff.after = func(d time.Duration) <-chan time.Time {
+			waits = append(waits, d)
+			c := make(chan time.Time, 1)
+			c <- time.Time{}
+			return c
+		}
+		return backoff, &waits
+	}
+	// failing returns a function that fails with errs in turn, then succeeds.
+	failing := func(errs ...error) (func(context.Context) error, *int) {
+		var calls int
+		return func(context.Context) error {
+			calls++
+			if calls <= len(errs) {
+				return errs[calls-1]
+			}
+			return nil
+		}, &calls
+	}
+
+	t.Run("retries retryable errors with backoff", func(t *testing.T) {
+		backoff, waits := fakeClock(Backoff{MaxAttempts: 4})
+		fn, calls := failing(ErrConnectionLost, &RateLimitError{Message: "slow down"})
+		if err := Retry(t.Context(), backoff, fn); err != nil {
+			t.Fatalf("Expected success, got %v", err)
+		}
+		if *calls != 3 || !slices.Equal(*waits, []time.Duration{time.Second, 2 * time.Second}) {
+			t.Errorf("Expected 3 calls with waits of 1s and 2s, got %d calls and %v", *calls, *waits)
+		}
+	})
+
+	t.Run("waits the retry-after of a rate limit", func(t *testing.T) {
+		backoff, waits := fakeClock(Backoff{})
+		fn, _ := failing(&RateLimitError{RetryAfter: 42 * time.Second})
+		if err := Retry(t.Context(), backoff, fn); err != nil {
+			t.Fatalf("Expected success, got %v", err)
+		}
+		if !slices.Equal(*waits, []time.Duration{42 * time.Second}) {
+			t.Errorf("Expected the retry-after to replace the backoff, got %v", *waits)
+		}
+	})
+
+	t.Run("returns errors that are not retryable", func(t *testing.T) {
+		backoff, waits := fakeClock(Backoff{})
+		notRetryable := &retryableTestError{retryable: false}
+		fn, calls := failing(notRetryable)
+		if err := Retry(t.Context(), backoff, fn); err != notRetryable || *calls != 1 || len(*waits) != 0 {
+			t.Errorf("Expected one call returning the error, got %v after %d calls", err, *calls)
+		}
+	})
+
+	t.Run("gives up after MaxAttempts", func(t *testing.T) {
+		backoff, waits := fakeClock(Backoff{MaxAttempts: 2})
+		last := &retryableTestError{retryable: true}
+		fn, calls := failing(ErrConnectionLost, last, ErrConnectionLost)
+		if err := Retry(t.Context(), backoff, fn); err != last || *calls != 2 || len(*waits) != 1 {
+			t.Errorf("Expected the second error after 2 calls, got %v after %d calls", err, *calls)
+		}
+	})
+
+	t.Run("stops waiting when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		backoff := Backoff{after: func(time.Duration) <-chan time.Time {
+			cancel()
+			return nil
+		}}
+		fn, calls := failing(ErrConnectionLost, ErrConnectionLost)
+		err := Retry(ctx, backoff, fn)
+		if !errors.Is(err, context.Canceled) || !errors.Is(err, ErrConnectionLost) || *calls != 1 {
+			t.Errorf("Expected a cancellation error with the last failure after 1 call, got %v after %d calls", err, *calls)
+		}
+	})
+}
@@ -0,0 +1,263 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BudgetExceededWarning is the WarningType of the session.warning event
+// emitted when a message is sent over budget with [BudgetSoft].
+const BudgetExceededWarning = "budget_exceeded"
+
+// defaultCostPerPremiumRequest is the estimated price of a premium request
+// when [BudgetConfig.CostPerPremiumRequest] is not set.
+const defaultCostPerPremiumRequest = 0.04
+
+// ErrBudgetExceeded matches errors from [Session.Send] when sending a
+// message would exceed the session's [BudgetConfig].
+var ErrBudgetExceeded = errors.New("session budget exceeded")
+
+// BudgetExceededError is returned by [Session.Send] when the premium requests
+// of a message, added to those the session has used, would exceed a limit of
+// its [BudgetConfig]. The message is not sent. It matches [ErrBudgetExceeded].
+type BudgetExceededError struct {
+	// Model is the ID of the model that would answer the message, or empty if
+	// it is unknown
+	Model string
+	// NextPremiumRequests is the estimated premium requests of the message
+	NextPremiumRequests float64
+	// Status is the session's budget before the message
+	Status BudgetStatus
+}
+
+func (e *BudgetExceededError) Error() string {
+	msg := fmt.Sprintf("%v: %.2f premium requests used", ErrBudgetExceeded, e.Status.PremiumRequests)
+	if e.Status.MaxPremiumRequests > 0 {
+		msg += fmt.Sprintf(" of %.2f", e.Status.MaxPremiumRequests)
+	}
+	if e.Status.MaxCost > 0 {
+		msg += fmt.Sprintf(", estimated cost %.2f of %.2f", e.Status.EstimatedCost, e.Status.MaxCost)
+	}
+	return msg + fmt.Sprintf(", next message needs %.2f", e.NextPremiumRequests)
+}
+
+func (e *BudgetExceededError) Is(target error) bool { return target == ErrBudgetExceeded }
+
+// BudgetPolicy selects what [Session.Send] does when a message would exceed
+// the session's budget.
+type BudgetPolicy string
+
+const (
+	// BudgetHard fails the send with a *[BudgetExceededError] (default)
+	BudgetHard BudgetPolicy = "hard"
+	// BudgetSoft sends anyway, emits a session.warning event with WarningType
+	// [BudgetExceededWarning], and switches the session to
+	// [BudgetConfig.FallbackModel] if set
+	BudgetSoft BudgetPolicy = "soft"
+)
+
+// BudgetConfig caps the spending of a session. Usage is tracked by the SDK
+// across the turns sent from the [Session]; the limits are checked before
+// each message is sent.
+//
+// Premium requests are counted from the Cost of the assistant.usage events
+// the CLI reports. A model request whose usage event reports no cost counts
+// as the billing multiplier of its model, as listed by [Client.ListModels],
+// and a turn that completes without any usage event counts as one request of
+// the model it was sent to. Models without billing information count as a
+// multiplier of 1. [BudgetStatus.Estimated] reports whether any usage was
+// estimated this way.
+type BudgetConfig struct {
+	// MaxPremiumRequests is the maximum premium requests of the session.
+	// Zero means no limit.
+	MaxPremiumRequests float64
+	// MaxCost is the maximum estimated cost of the session, in the unit of
+	// CostPerPremiumRequest. Zero means no limit.
+	MaxCost float64
+	// CostPerPremiumRequest is the price of a premium request used to
+	// estimate cost. Default: 0.04 (USD).
+	CostPerPremiumRequest float64
+	// Policy selects what happens to messages over budget. Default: BudgetHard.
+	Policy BudgetPolicy
+	// FallbackModel is the model to switch the session to when a message is
+	// over budget with BudgetSoft, typically one with a lower billing
+	// multiplier. Messages that set [MessageOptions.Model] are not switched.
+	FallbackModel string
+}
+
+// BudgetStatus reports the usage of a session against its [BudgetConfig].
+type BudgetStatus struct {
+	// PremiumRequests is the premium requests used so far
+	PremiumRequests float64
+	// EstimatedCost is PremiumRequests times the cost per premium request
+	EstimatedCost float64
+	// MaxPremiumRequests and MaxCost are the limits of the budget; zero
+	// means no limit
+	MaxPremiumRequests float64
+	MaxCost            float64
+	// Requests is the number of model requests reported by usage events
+	Requests int
+	// Estimated reports whether any usage was estimated because the CLI did
+	// not report it
+	Estimated bool
+	// Exceeded reports whether usage is over a limit
+	Exceeded bool
+}
+
+// budgetTracker accumulates the premium requests of a session from its
+// usage events.
+type budgetTracker struct {
+	config BudgetConfig
+
+	mu              sync.Mutex
+	premiumRequests float64
+	requests        int
+	estimated       bool
+	// multipliers are the billing multipliers of the models messages were
+	// sent to, by model ID
+	multipliers map[string]float64
+	// turnMultiplier is the multiplier of the model answering the current
+	// turn, charged at session.idle if the turn reported no usage
+	turnMultiplier float64
+	turnActive     bool
+	turnUsage      bool
+}
+
+func newBudgetTracker(config BudgetConfig) *budgetTracker {
+	if config.CostPerPremiumRequest <= 0 {
+		config.CostPerPremiumRequest = defaultCostPerPremiumRequest
+	}
+	return &budgetTracker{config: config, multipliers: make(map[string]float64)}
+}
+
+// beginTurn records the model a message is sent to and its multiplier.
+func (b *budgetTracker) beginTurn(model string, multiplier float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if model != "" {
+		b.multipliers[model] = multiplier
+	}
+	b.turnMultiplier = multiplier
+	b.turnActive = true
+	b.turnUsage = false
+}
+
+// observeEvent charges the premium requests reported by usage events, and
+// estimates those of turns that completed without any.
+func (b *budgetTracker) observeEvent(event SessionEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch event.Type {
+	case AssistantUsage:
+		b.requests++
+		b.turnUsage = true
+		if event.Data.Cost != nil {
+			b.premiumRequests += *event.Data.Cost
+			return
+		}
+		multiplier, ok := b.multipliers[derefString(event.Data.Model)]
+		if !ok {
+			multiplier = b.turnMultiplier
+		}
+		b.premiumRequests += multiplier
+		b.estimated = true
+	case SessionIdle:
+		if b.turnActive && !b.turnUsage {
+			b.premiumRequests += b.turnMultiplier
+			b.estimated = true
+		}
+		b.turnActive = false
+	}
+}
+
+// status returns the usage so far.
+func (b *budgetTracker) status() BudgetStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := BudgetStatus{
+		PremiumRequests:    b.premiumRequests,
+		EstimatedCost:      b.premiumRequests * b.config.CostPerPremiumRequest,
+		MaxPremiumRequests: b.config.MaxPremiumRequests,
+		MaxCost:            b.config.MaxCost,
+		Requests:           b.requests,
+		Estimated:          b.estimated,
+	}
+	status.Exceeded = b.over(b.premiumRequests)
+	return status
+}
+
+// over reports whether premiumRequests are over a limit.
+func (b *budgetTracker) over(premiumRequests float64) bool {
+	if b.config.MaxPremiumRequests > 0 && premiumRequests > b.config.MaxPremiumRequests {
+		return true
+	}
+	return b.config.MaxCost > 0 && premiumRequests*b.config.CostPerPremiumRequest > b.config.MaxCost
+}
+
+// BudgetStatus returns the session's usage against its
+// [SessionConfig.Budget]. It is the zero value if the session has no budget.
+//
+// Example:
+//
+//	status := session.BudgetStatus()
+//	log.Printf("%.1f of %.0f premium requests used", status.PremiumRequests, status.MaxPremiumRequests)
+func (s *Session) BudgetStatus() BudgetStatus {
+	if s.budget == nil {
+		return BudgetStatus{}
+	}
+	return s.budget.status()
+}
+
+// checkBudget fails if a message would exceed the session's budget, or with
+// [BudgetSoft], warns and switches to the fallback model. It then starts
+// tracking the message's turn.
+func (s *Session) checkBudget(ctx context.Context, options MessageOptions) error {
+	if s.budget == nil {
+		return nil
+	}
+	model, multiplier := s.budgetModel(ctx, options.Model)
+	status := s.budget.status()
+	if s.budget.over(status.PremiumRequests + multiplier) {
+		err := &BudgetExceededError{Model: model, NextPremiumRequests: multiplier, Status: status}
+		if s.budget.config.Policy != BudgetSoft {
+			return err
+		}
+		s.dispatchEvent(SessionEvent{
+			Type:      SessionWarning,
+			Timestamp: time.Now(),
+			Ephemeral: Bool(true),
+			Data: Data{
+				WarningType: String(BudgetExceededWarning),
+				Message:     String(err.Error()),
+			},
+		})
+		if fallback := s.budget.config.FallbackModel; fallback != "" && options.Model == "" && model != fallback {
+			if err := s.switchModel(ctx, fallback); err != nil {
+				return err
+			}
+			model, multiplier = s.budgetModel(ctx, "")
+		}
+	}
+	s.budget.beginTurn(model, multiplier)
+	return nil
+}
+
+// budgetModel returns the model answering a message, override if set or
+// else the session's model, and its billing multiplier. The multiplier is 1
+// if the model or its billing is unknown.
+func (s *Session) budgetModel(ctx context.Context, override string) (string, float64) {
+	model := s.preflightModel(ctx, override)
+	if model == nil {
+		if override != "" {
+			return override, 1
+		}
+		return s.Config().Model, 1
+	}
+	if model.Billing == nil {
+		return model.ID, 1
+	}
+	return model.ID, model.Billing.Multiplier
+}
@@ -0,0 +1,169 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestBudgetTracker(t *testing.T) {
+	usage := func(model string, cost *float64) SessionEvent {
+		return SessionEvent{Type: AssistantUsage, Data: Data{Model: String(model), Cost: cost}}
+	}
+	cost := func(c float64) *float64 { return &c }
+	idle := SessionEvent{Type: SessionIdle}
+
+	t.Run("counts reported costs until the limit is crossed", func(t *testing.T) {
+		b := newBudgetTracker(BudgetConfig{MaxPremiumRequests: 2})
+		b.beginTurn("premium", 1)
+		b.observeEvent(usage("premium", cost(1)))
+		b.observeEvent(idle)
+		if status := b.status(); status.PremiumRequests != 1 || status.Requests != 1 || status.Exceeded || status.Estimated {
+			t.Fatalf("Unexpected status after one request: %+v", status)
+		}
+		if b.over(1 + 1) {
+			t.Error("Expected a second request to fit the budget")
+		}
+		b.beginTurn("premium", 1)
+		b.observeEvent(usage("premium", cost(1)))
+		b.observeEvent(usage("premium", cost(1)))
+		b.observeEvent(idle)
+		if status := b.status(); status.PremiumRequests != 3 || status.Requests != 3 || !status.Exceeded {
+			t.Errorf("Expected the budget to be exceeded, got %+v", status)
+		}
+	})
+
+	t.Run("estimates usage without a reported cost from the multiplier", func(t *testing.T) {
+		b := newBudgetTracker(BudgetConfig{})
+		b.beginTurn("premium", 3)
+		b.observeEvent(usage("premium", nil))
+		b.observeEvent(usage("unknown", nil))
+		b.observeEvent(idle)
+		if status := b.status(); status.PremiumRequests != 6 || !status.Estimated {
+			t.Errorf("Expected two requests at multiplier 3, estimated, got %+v", status)
+		}
+	})
+
+	t.Run("charges turns without usage events", func(t *testing.T) {
+		b := newBudgetTracker(BudgetConfig{MaxCost: 0.1})
+		for range 3 {
+			b.beginTurn("premium", 1)
+			b.observeEvent(idle)
+		}
+		b.observeEvent(idle) // not a turn sent from the session
+		status := b.status()
+		if status.PremiumRequests != 3 || !status.Estimated || status.Requests != 0 {
+			t.Errorf("Expected three estimated requests, got %+v", status)
+		}
+		if status.EstimatedCost < 0.119 || status.EstimatedCost > 0.121 || !status.Exceeded {
+			t.Errorf("Expected an estimated cost of 0.12 over the 0.1 limit, got %+v", status)
+		}
+	})
+}
+
+func TestSession_Budget(t *testing.T) {
+	models := []ModelInfo{
+		{ID: "premium", Billing: &ModelBilling{Multiplier: 2}},
+		{ID: "mini", Billing: &ModelBilling{Multiplier: 0}},
+	}
+	newBudgetSession := func(t *testing.T, budget BudgetConfig) (*Session, func() []string) {
+		var mu sync.Mutex
+		current := "premium"
+		var sentWith []string
+		var server *fakeServer
+		var session *Session
+		session, server = newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			switch method {
+			case "session.model.switchTo":
+				var req struct {
+					ModelID string `json:"modelId"`
+				}
+				json.Unmarshal(params, &req)
+				current = req.ModelID
+				return map[string]any{"modelId": req.ModelID}, nil
+			case "session.send":
+				sentWith = append(sentWith, current)
+				model := current
+				go func() {
+					server.emit(SessionEvent{Type: AssistantMessage, Data: Data{Content: String("answer from " + model)}})
+					server.emit(SessionEvent{Type: AssistantUsage, Data: Data{Model: String(model)}})
+					server.emit(SessionEvent{Type: SessionIdle})
+				}()
+				return sessionSendResponse{MessageID: "msg"}, nil
+			}
+			return nil, nil
+		})
+		session.listModels = func(context.Context) ([]ModelInfo, error) { return models, nil }
+		session.config.setModel("premium")
+		session.budget = newBudgetTracker(budget)
+		return session, func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), sentWith...)
+		}
+	}
+
+	t.Run("rejects a message that would exceed the budget", func(t *testing.T) {
+		session, sent := newBudgetSession(t, BudgetConfig{MaxPremiumRequests: 5})
+		for range 2 {
+			if _, err := session.SendAndWait(t.Context(), MessageOptions{Prompt: "Hi"}); err != nil {
+				t.Fatalf("Expected the message to fit the budget: %v", err)
+			}
+		}
+		_, err := session.SendAndWait(t.Context(), MessageOptions{Prompt: "Hi"})
+		var budgetErr *BudgetExceededError
+		if !errors.As(err, &budgetErr) || !errors.Is(err, ErrBudgetExceeded) {
+			t.Fatalf("Expected a BudgetExceededError, got %v", err)
+		}
+		if budgetErr.Model != "premium" || budgetErr.NextPremiumRequests != 2 || budgetErr.Status.PremiumRequests != 4 {
+			t.Errorf("Unexpected error fields: %+v", budgetErr)
+		}
+		if len(sent()) != 2 {
+			t.Errorf("Expected the third message not to be sent, got %v", sent())
+		}
+		if status := session.BudgetStatus(); status.PremiumRequests != 4 || status.MaxPremiumRequests != 5 || status.Requests != 2 || !status.Estimated {
+			t.Errorf("Unexpected budget status: %+v", status)
+		}
+	})
+
+	t.Run("warns and switches to the fallback model with a soft budget", func(t *testing.T) {
+		session, sent := newBudgetSession(t, BudgetConfig{MaxPremiumRequests: 3, Policy: BudgetSoft, FallbackModel: "mini"})
+		var mu sync.Mutex
+		var warnings []string
+		session.On(func(event SessionEvent) {
+			if data, ok := event.AsSessionWarning(); ok {
+				mu.Lock()
+				warnings = append(warnings, data.WarningType)
+				mu.Unlock()
+			}
+		})
+		for range 3 {
+			if _, err := session.SendAndWait(t.Context(), MessageOptions{Prompt: "Hi"}); err != nil {
+				t.Fatalf("Expected soft budgets not to fail: %v", err)
+			}
+		}
+		if want := []string{"premium", "mini", "mini"}; !reflect.DeepEqual(sent(), want) {
+			t.Errorf("Expected sends with %v, got %v", want, sent())
+		}
+		if session.Config().Model != "mini" {
+			t.Errorf("Expected the session to use the fallback model, got %q", session.Config().Model)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(warnings) != 1 || warnings[0] != BudgetExceededWarning {
+			t.Errorf("Expected one budget warning, got %v", warnings)
+		}
+	})
+
+	t.Run("reports no status without a budget", func(t *testing.T) {
+		session, _ := newTestSession(t, func(string, json.RawMessage) (any, error) { return nil, nil })
+		if status := session.BudgetStatus(); status != (BudgetStatus{}) {
+			t.Errorf("Expected a zero status, got %+v", status)
+		}
+	})
+}
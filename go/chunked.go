@@ -0,0 +1,248 @@
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Defaults for [ChunkOptions].
+const (
+	defaultChunkTokens = 4000
+)
+
+// ChunkOptions configures [Session.SendChunked].
+type ChunkOptions struct {
+	// ChunkTokens is the estimated size of each chunk. Default: 4000.
+	ChunkTokens int
+	// OverlapTokens repeats about this many tokens of the end of each chunk
+	// at the start of the next, so content cut at a boundary keeps its
+	// context. Must be less than ChunkTokens. Default: 0.
+	OverlapTokens int
+	// Tokenizer estimates sizes. Default: [HeuristicTokenizer].
+	Tokenizer Tokenizer
+	// FormatChunk builds the message sent for chunk index (0-based) of total.
+	// Default: the chunk with an instruction to take notes and wait for the
+	// next part, and for the last chunk, to answer prompt.
+	FormatChunk func(prompt, chunk string, index, total int) string
+	// OnChunk is called after each chunk's turn completes.
+	OnChunk func(ChunkProgress)
+	// WaitOptions configure how each chunk's turn is awaited; may be nil.
+	WaitOptions *SendAndWaitOptions
+}
+
+// ChunkProgress reports a chunk processed by [Session.SendChunked].
+type ChunkProgress struct {
+	// Index is the 0-based index of the chunk
+	Index int
+	// Total is the number of chunks
+	Total int
+	// Tokens is the estimated size of the chunk
+	Tokens int
+	// Turn is the chunk's turn
+	Turn *TurnResult
+}
+
+// SendChunked has the model process content too large for a single message.
+// It splits content into chunks with [ChunkText] and sends them one turn at a
+// time, asking the model to keep notes on each part and, with the last part,
+// to answer prompt. It returns the turn of the last chunk, whose final message
+// is the consolidated answer.
+//
+// Each turn is awaited like [Session.SendAndCollect]; canceling ctx stops
+// after the current chunk's turn.
+//
+// Example:
+//
+//	f, _ := os.Open("report.txt")
+//	defer f.Close()
+//	result, err := session.SendChunked(ctx, "List the open risks in this report", f, copilot.ChunkOptions{
+//	    ChunkTokens: 8000,
+//	    OnChunk: func(p copilot.ChunkProgress) {
+//	        log.Printf("chunk %d/%d done", p.Index+1, p.Total)
+//	    },
+//	})
+//	if err == nil && result.FinalMessage != nil {
+//	    fmt.Println(*result.FinalMessage.Data.Content)
+//	}
+func (s *Session) SendChunked(ctx context.Context, prompt string, content io.Reader, opts ChunkOptions) (*TurnResult, error) {
+	s.recordWaitTimeout(opts.WaitOptions)
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read content: %w", err)
+	}
+	tokenizer := opts.Tokenizer
+	if tokenizer == nil {
+		tokenizer = HeuristicTokenizer{}
+	}
+	chunks, err := ChunkText(string(data), opts.ChunkTokens, opts.OverlapTokens, tokenizer)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		chunks = []string{""}
+	}
+	format := opts.FormatChunk
+	if format == nil {
+		format = formatChunk
+	}
+
+	var result *TurnResult
+	for i, chunk := range chunks {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("sending chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+		message := format(prompt, chunk, i, len(chunks))
+		result, err = s.sendAndCollect(ctx, MessageOptions{Prompt: message}, opts.WaitOptions)
+		if err != nil {
+			return nil, fmt.Errorf("sending chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+		if opts.OnChunk != nil {
+			opts.OnChunk(ChunkProgress{Index: i, Total: len(chunks), Tokens: tokenizer.CountTokens(chunk), Turn: result})
+		}
+	}
+	return result, nil
+}
+
+// formatChunk is the default [ChunkOptions.FormatChunk].
+func formatChunk(prompt, chunk string, index, total int) string {
+	if total == 1 {
+		return prompt + "\n\n" + chunk
+	}
+	var b strings.Builder
+	if index == 0 {
+		fmt.Fprintf(&b, "I will send you a document in %d parts. Once you have all of them, you will be asked: %q\n\n", total, prompt)
+	}
+	fmt.Fprintf(&b, "Part %d of %d:\n\n%s\n\n", index+1, total, chunk)
+	if index < total-1 {
+		b.WriteString("Note what in this part is relevant to the question, then reply only with those notes and wait for the next part.")
+	} else {
+		fmt.Fprintf(&b, "That was the last part. Using all parts and your notes, now answer: %s", prompt)
+	}
+	return b.String()
+}
+
+// ChunkText splits text into chunks of at most about maxTokens tokens as
+// estimated by tokenizer (nil means [HeuristicTokenizer]), breaking at
+// paragraph breaks where possible, then at line breaks, then between words.
+// Each chunk after the first starts with about overlapTokens tokens from the
+// end of the previous chunk. A maxTokens of 0 means 4000.
+//
+// Example:
+//
+//	chunks, err := copilot.ChunkText(doc, 2000, 100, nil)
+func ChunkText(text string, maxTokens, overlapTokens int, tokenizer Tokenizer) ([]string, error) {
+	if maxTokens == 0 {
+		maxTokens = defaultChunkTokens
+	}
+	if maxTokens < 0 || overlapTokens < 0 || overlapTokens >= maxTokens {
+		return nil, fmt.Errorf("invalid chunk size %d with overlap %d", maxTokens, overlapTokens)
+	}
+	if tokenizer == nil {
+		tokenizer = HeuristicTokenizer{}
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	units := splitUnits(text, maxTokens-overlapTokens, tokenizer)
+	var chunks []string
+	var current []string
+	currentTokens := 0
+	for _, unit := range units {
+		n := tokenizer.CountTokens(unit)
+		if len(current) > 0 && currentTokens+n > maxTokens {
+			chunks = append(chunks, strings.Join(current, ""))
+			current = overlapTail(current, overlapTokens, tokenizer)
+			currentTokens = tokenizer.CountTokens(strings.Join(current, ""))
+		}
+		current = append(current, unit)
+		currentTokens += n
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, strings.Join(current, ""))
+	}
+	for i := range chunks {
+		chunks[i] = strings.TrimSpace(chunks[i])
+	}
+	return chunks, nil
+}
+
+// overlapTail returns the trailing units of a chunk that fit in overlapTokens.
+func overlapTail(units []string, overlapTokens int, tokenizer Tokenizer) []string {
+	if overlapTokens == 0 {
+		return nil
+	}
+	tokens := 0
+	start := len(units)
+	for start > 0 {
+		n := tokenizer.CountTokens(units[start-1])
+		if tokens+n > overlapTokens {
+			break
+		}
+		tokens += n
+		start--
+	}
+	return append([]string(nil), units[start:]...)
+}
+
+// splitUnits splits text into pieces of at most maxTokens, each keeping its
+// trailing separator: paragraphs, or lines or words of paragraphs that are
+// too large.
+func splitUnits(text string, maxTokens int, tokenizer Tokenizer) []string {
+	var units []string
+	for _, paragraph := range splitAfter(text, "\n\n") {
+		if tokenizer.CountTokens(paragraph) <= maxTokens {
+			units = append(units, paragraph)
+			continue
+		}
+		for _, line := range splitAfter(paragraph, "\n") {
+			if tokenizer.CountTokens(line) <= maxTokens {
+				units = append(units, line)
+				continue
+			}
+			for _, word := range splitAfter(line, " ") {
+				units = append(units, splitRunes(word, maxTokens, tokenizer)...)
+			}
+		}
+	}
+	return units
+}
+
+// splitAfter splits s after each separator, keeping separators.
+func splitAfter(s, sep string) []string {
+	parts := strings.SplitAfter(s, sep)
+	if len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	return parts
+}
+
+// splitRunes cuts a word too large for a chunk into pieces of at most
+// maxTokens, at rune boundaries.
+func splitRunes(word string, maxTokens int, tokenizer Tokenizer) []string {
+	if tokenizer.CountTokens(word) <= maxTokens {
+		return []string{word}
+	}
+	offsets := make([]int, 0, utf8.RuneCountInString(word)+1)
+	for i := range word {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(word))
+
+	var pieces []string
+	for len(offsets) > 1 {
+		start := offsets[0]
+		// Find the most runes that fit, taking at least one
+		n := sort.Search(len(offsets)-1, func(k int) bool {
+			return tokenizer.CountTokens(word[start:offsets[k+1]]) > maxTokens
+		})
+		n = max(n, 1)
+		pieces = append(pieces, word[start:offsets[n]])
+		offsets = offsets[n:]
+	}
+	return pieces
+}
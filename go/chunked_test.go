@@ -0,0 +1,168 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestChunkText(t *testing.T) {
+	t.Run("breaks at paragraphs", func(t *testing.T) {
+		paragraph := strings.Repeat("word ", 20) // 25 tokens
+		text := strings.Repeat(paragraph+"\n\n", 6)
+		chunks, err := ChunkText(text, 60, 0, nil)
+		if err != nil {
+			t.Fatalf("ChunkText failed: %v", err)
+		}
+		if len(chunks) != 3 {
+			t.Fatalf("Expected 3 chunks of two paragraphs, got %d", len(chunks))
+		}
+		for _, chunk := range chunks {
+			if strings.Count(chunk, "\n\n") != 1 {
+				t.Errorf("Expected two whole paragraphs, got %q", chunk)
+			}
+		}
+	})
+
+	t.Run("breaks long paragraphs at lines and words", func(t *testing.T) {
+		lines := strings.Repeat(strings.Repeat("x", 40)+"\n", 10) // 10 lines of 10 tokens
+		chunks, _ := ChunkText(lines, 25, 0, nil)
+		if len(chunks) != 5 || chunks[0] != strings.Repeat("x", 40)+"\n"+strings.Repeat("x", 40) {
+			t.Errorf("Expected chunks of two lines, got %q", chunks)
+		}
+
+		long := strings.Repeat("é", 100)
+		chunks, _ = ChunkText(long, 30, 0, nil)
+		if len(chunks) != 4 || strings.Join(chunks, "") != long {
+			t.Errorf("Expected a long word cut into 4 pieces, got %q", chunks)
+		}
+	})
+
+	t.Run("overlaps chunks", func(t *testing.T) {
+		var sentences []string
+		for i := 0; i < 12; i++ {
+			sentences = append(sentences, fmt.Sprintf("Sentence %02d.", i))
+		}
+		chunks, err := ChunkText(strings.Join(sentences, "\n"), 12, 4, nil)
+		if err != nil {
+			t.Fatalf("ChunkText failed: %v", err)
+		}
+		for i := 1; i < len(chunks); i++ {
+			prev := strings.Split(chunks[i-1], "\n")
+			if !strings.HasPrefix(chunks[i], prev[len(prev)-1]) {
+				t.Errorf("Expected chunk %d to start with the end of chunk %d, got %q after %q", i, i-1, chunks[i], chunks[i-1])
+			}
+		}
+	})
+
+	t.Run("validates sizes", func(t *testing.T) {
+		if _, err := ChunkText("text", 10, 10, nil); err == nil {
+			t.Error("Expected an error for an overlap as large as the chunk")
+		}
+		if chunks, _ := ChunkText(" \n\n ", 10, 0, nil); len(chunks) != 0 {
+			t.Errorf("Expected no chunks for blank text, got %q", chunks)
+		}
+	})
+}
+
+func TestSession_SendChunked(t *testing.T) {
+	// newScriptedSession answers each message with a note naming the part,
+	// or the final answer when the message says it was the last part
+	newScriptedSession := func(t *testing.T) (*Session, func() []string) {
+		var mu sync.Mutex
+		var prompts []string
+		var server *fakeServer
+		session, server := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method != "session.send" {
+				return nil, nil
+			}
+			var req sessionSendRequest
+			json.Unmarshal(params, &req)
+			mu.Lock()
+			prompts = append(prompts, req.Prompt)
+			n := len(prompts)
+			mu.Unlock()
+
+			reply := fmt.Sprintf("notes %d", n)
+			if strings.Contains(req.Prompt, "That was the last part") {
+				reply = "final answer"
+			}
+			go func() {
+				server.emit(SessionEvent{Type: AssistantMessage, Data: Data{Content: String(reply)}})
+				server.emit(SessionEvent{Type: SessionIdle})
+			}()
+			return sessionSendResponse{MessageID: fmt.Sprintf("m%d", n)}, nil
+		})
+		return session, func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), prompts...)
+		}
+	}
+	content := strings.Repeat(strings.Repeat("data ", 50)+"\n\n", 3) // three 63-token paragraphs
+
+	t.Run("feeds chunks and returns the final answer", func(t *testing.T) {
+		session, sent := newScriptedSession(t)
+		var progress []ChunkProgress
+		result, err := session.SendChunked(t.Context(), "What is this?", strings.NewReader(content), ChunkOptions{
+			ChunkTokens: 70,
+			OnChunk:     func(p ChunkProgress) { progress = append(progress, p) },
+		})
+		if err != nil {
+			t.Fatalf("SendChunked failed: %v", err)
+		}
+		if result.FinalMessage == nil || *result.FinalMessage.Data.Content != "final answer" {
+			t.Errorf("Expected the final answer, got %+v", result.FinalMessage)
+		}
+
+		prompts := sent()
+		if len(prompts) != 3 {
+			t.Fatalf("Expected 3 messages, got %d", len(prompts))
+		}
+		if !strings.Contains(prompts[0], `asked: "What is this?"`) || !strings.Contains(prompts[0], "Part 1 of 3") {
+			t.Errorf("Unexpected first message: %q", prompts[0])
+		}
+		if !strings.Contains(prompts[1], "Part 2 of 3") || strings.Contains(prompts[1], "asked:") {
+			t.Errorf("Unexpected middle message: %q", prompts[1])
+		}
+		if !strings.HasSuffix(prompts[2], "now answer: What is this?") {
+			t.Errorf("Unexpected last message: %q", prompts[2])
+		}
+
+		if len(progress) != 3 || progress[2].Index != 2 || progress[2].Total != 3 || progress[0].Tokens == 0 {
+			t.Errorf("Unexpected progress: %+v", progress)
+		}
+		if *progress[0].Turn.FinalMessage.Data.Content != "notes 1" {
+			t.Errorf("Expected the first chunk's turn, got %+v", progress[0].Turn)
+		}
+	})
+
+	t.Run("sends small content as one message", func(t *testing.T) {
+		session, sent := newScriptedSession(t)
+		if _, err := session.SendChunked(t.Context(), "Summarize", strings.NewReader("short"), ChunkOptions{}); err != nil {
+			t.Fatalf("SendChunked failed: %v", err)
+		}
+		if prompts := sent(); len(prompts) != 1 || prompts[0] != "Summarize\n\nshort" {
+			t.Errorf("Expected a single message, got %q", prompts)
+		}
+	})
+
+	t.Run("stops when the context is canceled", func(t *testing.T) {
+		session, sent := newScriptedSession(t)
+		ctx, cancel := context.WithCancel(t.Context())
+		_, err := session.SendChunked(ctx, "Q", strings.NewReader(content), ChunkOptions{
+			ChunkTokens: 70,
+			OnChunk:     func(ChunkProgress) { cancel() },
+		})
+		if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "chunk 2 of 3") {
+			t.Errorf("Expected cancellation before chunk 2, got %v", err)
+		}
+		if n := len(sent()); n != 1 {
+			t.Errorf("Expected one message before canceling, got %d", n)
+		}
+	})
+}
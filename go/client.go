@@ -34,10 +34,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net"
 	"os"
 	"os/exec"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -46,9 +49,14 @@ import (
 
 	"github.com/github/copilot-sdk/go/internal/embeddedcli"
 	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+	"github.com/github/copilot-sdk/go/internal/sdklog"
 	"github.com/github/copilot-sdk/go/rpc"
 )
 
+// defaultCleanupTimeout bounds the session.destroy calls made by Stop when
+// ClientOptions.CleanupTimeout is not set.
+const defaultCleanupTimeout = 10 * time.Second
+
 // Client manages the connection to the Copilot CLI server and provides session management.
 //
 // The Client can either spawn a CLI server process or connect to an existing server.
@@ -69,14 +77,19 @@ import (
 //	}
 //	defer client.Stop()
 type Client struct {
-	options                ClientOptions
-	process                *exec.Cmd
-	client                 *jsonrpc2.Client
-	actualPort             int
-	actualHost             string
-	state                  ConnectionState
-	sessions               map[string]*Session
-	sessionsMux            sync.Mutex
+	options    ClientOptions
+	process    *exec.Cmd
+	client     *jsonrpc2.Client
+	actualPort int
+	actualHost string
+	state      ConnectionState
+	sessions   sessionRegistry
+	// placements counts sessions a ClientPool is creating or resuming on
+	// this client, so concurrent placements see each other
+	placements             atomic.Int32
+	earlyEvents            earlyEventBuffer
+	capabilities           atomic.Pointer[ServerCapabilities]
+	eventAliases           atomic.Pointer[eventAliasTable]
 	isExternalServer       bool
 	conn                   net.Conn // stores net.Conn for external TCP connections
 	useStdio               bool     // resolved value from options
@@ -87,10 +100,23 @@ type Client struct {
 	lifecycleHandlers      []SessionLifecycleHandler
 	typedLifecycleHandlers map[SessionLifecycleEventType][]SessionLifecycleHandler
 	lifecycleHandlersMux   sync.Mutex
+	sessionEventTap        sessionEventTap
 	startStopMux           sync.RWMutex // protects process and state during start/[force]stop
 	processDone            chan struct{}
 	processErrorPtr        *error
 	osProcess              atomic.Pointer[os.Process]
+	pacer                  *pacer
+	serverLoad             *loadEstimator
+	tracer                 *rpcTracer // nil unless JSON-RPC messages are traced
+	diagnostics            *diagnosticsRecorder
+	logger                 *sdklog.Logger
+	startStderr            *diagnosticsRecorder // stderr of the current CLI process, for start errors
+	abandonedTools         atomic.Int32         // timed-out tool handlers still running
+	sharedContext          SharedContext
+	restarter              autoRestarter
+	stateChanges           stateNotifier
+	sharedMCP              map[string]*sharedMCPServer
+	sharedMCPMux           sync.Mutex
 
 	// RPC provides typed server-scoped RPC methods.
 	// This field is nil until the client is connected via Start().
@@ -123,12 +149,12 @@ func NewClient(options *ClientOptions) *Client {
 	client := &Client{
 		options:          opts,
 		state:            StateDisconnected,
-		sessions:         make(map[string]*Session),
 		actualHost:       "localhost",
 		isExternalServer: false,
 		useStdio:         true,
 		autoStart:        true, // default
 		autoRestart:      true, // default
+		diagnostics:      newDiagnosticsRecorder(),
 	}
 
 	if options != nil {
@@ -187,6 +213,67 @@ func NewClient(options *ClientOptions) *Client {
 		if options.UseLoggedInUser != nil {
 			opts.UseLoggedInUser = options.UseLoggedInUser
 		}
+		if options.Pacing != nil {
+			opts.Pacing = options.Pacing
+			client.pacer = newPacer(*options.Pacing)
+		}
+		if options.IntegrationID != "" {
+			if err := validateAttributionTag("IntegrationID", options.IntegrationID); err != nil {
+				panic(err.Error())
+			}
+			opts.IntegrationID = options.IntegrationID
+		}
+		if options.CleanupTimeout > 0 {
+			opts.CleanupTimeout = options.CleanupTimeout
+		}
+		opts.RequestIDGenerator = options.RequestIDGenerator
+		opts.OnRPCCall = options.OnRPCCall
+		opts.OnDeprecatedUse = options.OnDeprecatedUse
+		client.diagnostics.onDeprecatedUse = options.OnDeprecatedUse
+		opts.ServerLoad = options.ServerLoad
+		opts.MaxMessageBytes = options.MaxMessageBytes
+		opts.MaxInlineAttachmentBytes = options.MaxInlineAttachmentBytes
+		opts.RequestTimeout = options.RequestTimeout
+		opts.RetryPolicy = options.RetryPolicy
+		opts.MaxPendingRequests = options.MaxPendingRequests
+		opts.PendingRequestsWarning = options.PendingRequestsWarning
+		opts.ServerRequestOrder = options.ServerRequestOrder
+		opts.EventAliases = maps.Clone(options.EventAliases)
+		opts.CompressionThreshold = options.CompressionThreshold
+		opts.LogOutput = options.LogOutput
+		opts.MachineReadableLogs = options.MachineReadableLogs
+		opts.Logger = options.Logger
+		opts.RestartBackoff = options.RestartBackoff
+		opts.OnConnectionStateChange = options.OnConnectionStateChange
+		opts.OnRPCMessage = options.OnRPCMessage
+		opts.TraceWriter = options.TraceWriter
+		opts.TraceRedactPaths = slices.Clone(options.TraceRedactPaths)
+	}
+	client.stateChanges.notify = client.notifyStateChange
+	if opts.Logger != nil {
+		client.logger = sdklog.FromSlog(opts.Logger)
+	} else {
+		client.logger = sdklog.New(opts.LogOutput, opts.MachineReadableLogs)
+	}
+
+	var loadOptions ServerLoadOptions
+	if opts.ServerLoad != nil {
+		loadOptions = *opts.ServerLoad
+	}
+	client.serverLoad = newLoadEstimator(loadOptions)
+	if client.pacer != nil {
+		client.pacer.load = client.serverLoad
+	}
+	client.tracer = newRPCTracer(opts)
+
+	if opts.CleanupTimeout <= 0 {
+		opts.CleanupTimeout = defaultCleanupTimeout
+	}
+	if opts.RequestTimeout == 0 {
+		opts.RequestTimeout = defaultRequestTimeout
+	}
+	if opts.CompressionThreshold == 0 {
+		opts.CompressionThreshold = defaultCompressionThreshold
 	}
 
 	// Default Env to current environment if not set
@@ -256,37 +343,41 @@ func parseCliUrl(url string) (string, int) {
 func (c *Client) Start(ctx context.Context) error {
 	c.startStopMux.Lock()
 	defer c.startStopMux.Unlock()
+	return c.startLocked(ctx)
+}
 
+// startLocked implements Start. Must be called with startStopMux held.
+func (c *Client) startLocked(ctx context.Context) error {
 	if c.state == StateConnected {
 		return nil
 	}
 
-	c.state = StateConnecting
+	c.setStateLocked(StateConnecting, nil)
 
 	// Only start CLI server process if not connecting to external server
 	if !c.isExternalServer {
 		if err := c.startCLIServer(ctx); err != nil {
 			c.process = nil
-			c.state = StateError
+			c.setStateLocked(StateError, err)
 			return err
 		}
 	}
 
 	// Connect to the server
 	if err := c.connectToServer(ctx); err != nil {
-		killErr := c.killProcess()
-		c.state = StateError
-		return errors.Join(err, killErr)
+		err = errors.Join(err, c.killProcess())
+		c.setStateLocked(StateError, err)
+		return err
 	}
 
 	// Verify protocol version compatibility
 	if err := c.verifyProtocolVersion(ctx); err != nil {
-		killErr := c.killProcess()
-		c.state = StateError
-		return errors.Join(err, killErr)
+		err = errors.Join(err, c.killProcess())
+		c.setStateLocked(StateError, err)
+		return err
 	}
 
-	c.state = StateConnected
+	c.setStateLocked(StateConnected, nil)
 	return nil
 }
 
@@ -297,7 +388,11 @@ func (c *Client) Start(ctx context.Context) error {
 //  2. Closes the JSON-RPC connection
 //  3. Terminates the CLI server process (if spawned by this client)
 //
-// Returns an error that aggregates all errors encountered during cleanup.
+// Sessions are destroyed concurrently, and all session.destroy calls together
+// are bounded by [ClientOptions.CleanupTimeout], so Stop finishes in bounded
+// time however many sessions there are, even if the CLI stops answering.
+// Sessions that fail to be destroyed are still released locally. Failures are
+// reported in a *[StopError].
 //
 // Example:
 //
@@ -305,29 +400,46 @@ func (c *Client) Start(ctx context.Context) error {
 //	    log.Printf("Cleanup error: %v", err)
 //	}
 func (c *Client) Stop() error {
-	var errs []error
-
-	// Destroy all active sessions
-	c.sessionsMux.Lock()
-	sessions := make([]*Session, 0, len(c.sessions))
-	for _, session := range c.sessions {
-		sessions = append(sessions, session)
-	}
-	c.sessionsMux.Unlock()
-
-	for _, session := range sessions {
-		if err := session.Destroy(); err != nil {
-			errs = append(errs, fmt.Errorf("failed to destroy session %s: %w", session.SessionID, err))
-		}
+	c.cancelAutoRestart()
+	stopErr := &StopError{DestroyFailed: make(map[string]error)}
+
+	// Destroy all active sessions concurrently, under one deadline
+	ctx, cancel := context.WithTimeout(context.Background(), c.options.CleanupTimeout)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, session := range c.sessions.all() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := session.destroy(ctx); err != nil {
+				mu.Lock()
+				stopErr.DestroyFailed[session.SessionID] = err
+				mu.Unlock()
+				session.markDestroyed(fmt.Sprintf("client stopped: %v", err))
+			}
+		}()
 	}
+	wg.Wait()
+	cancel()
 
-	c.sessionsMux.Lock()
-	c.sessions = make(map[string]*Session)
-	c.sessionsMux.Unlock()
+	c.sessions.clear()
 
 	c.startStopMux.Lock()
 	defer c.startStopMux.Unlock()
 
+	stopErr.Errors = c.disconnectLocked()
+	if len(stopErr.DestroyFailed) > 0 || len(stopErr.Errors) > 0 {
+		return stopErr
+	}
+	return nil
+}
+
+// disconnectLocked terminates the CLI process (if spawned by this client) and
+// closes the connection, leaving the session registry untouched.
+// Must be called with startStopMux held.
+func (c *Client) disconnectLocked() []error {
+	var errs []error
+
 	// Kill CLI process FIRST (this closes stdout and unblocks readLoop) - only if we spawned it
 	if c.process != nil && !c.isExternalServer {
 		if err := c.killProcess(); err != nil {
@@ -355,13 +467,13 @@ func (c *Client) Stop() error {
 	c.modelsCache = nil
 	c.modelsCacheMux.Unlock()
 
-	c.state = StateDisconnected
+	c.setStateLocked(StateDisconnected, nil)
 	if !c.isExternalServer {
 		c.actualPort = 0
 	}
 
 	c.RPC = nil
-	return errors.Join(errs...)
+	return errs
 }
 
 // ForceStop forcefully stops the CLI server without graceful cleanup.
@@ -387,6 +499,7 @@ func (c *Client) Stop() error {
 //	    client.ForceStop()
 //	}
 func (c *Client) ForceStop() {
+	c.cancelAutoRestart()
 	// Kill the process without waiting for startStopMux, which Start may hold.
 	// This unblocks any I/O Start is doing (connect, version check).
 	if p := c.osProcess.Swap(nil); p != nil {
@@ -394,9 +507,9 @@ func (c *Client) ForceStop() {
 	}
 
 	// Clear sessions immediately without trying to destroy them
-	c.sessionsMux.Lock()
-	c.sessions = make(map[string]*Session)
-	c.sessionsMux.Unlock()
+	for _, session := range c.sessions.clear() {
+		session.detachSharedMCPServers()
+	}
 
 	c.startStopMux.Lock()
 	defer c.startStopMux.Unlock()
@@ -426,7 +539,7 @@ func (c *Client) ForceStop() {
 	c.modelsCache = nil
 	c.modelsCacheMux.Unlock()
 
-	c.state = StateDisconnected
+	c.setStateLocked(StateDisconnected, nil)
 	if !c.isExternalServer {
 		c.actualPort = 0
 	}
@@ -441,7 +554,7 @@ func (c *Client) ensureConnected() error {
 	if c.autoStart {
 		return c.Start(context.Background())
 	}
-	return fmt.Errorf("client not connected. Call Start() first")
+	return errNotConnected
 }
 
 // CreateSession creates a new conversation session with the Copilot CLI.
@@ -474,8 +587,31 @@ func (c *Client) ensureConnected() error {
 //	    },
 //	})
 func (c *Client) CreateSession(ctx context.Context, config *SessionConfig) (*Session, error) {
-	if config == nil || config.OnPermissionRequest == nil {
-		return nil, fmt.Errorf("an OnPermissionRequest handler is required when creating a session. For example, to allow all permissions, use &copilot.SessionConfig{OnPermissionRequest: copilot.PermissionHandler.ApproveAll}")
+	if config == nil || !decidesPermissions(config.OnPermissionRequest, config.PermissionPolicy, config.AutoApprove, config.PermissionBatching, config.PendingActions) {
+		return nil, fmt.Errorf("an OnPermissionRequest handler, PermissionPolicy or AutoApprove policy is required when creating a session. For example, to allow all permissions, use &copilot.SessionConfig{OnPermissionRequest: copilot.PermissionHandler.ApproveAll}")
+	}
+
+	if config.ResponseLanguage != "" {
+		if err := validateLanguageTag("ResponseLanguage", config.ResponseLanguage); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkToolSchemas(config.Tools); err != nil {
+		return nil, err
+	}
+	if config.PermissionPolicy != nil {
+		if err := config.PermissionPolicy.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if config.AutoApprove != nil {
+		if err := config.AutoApprove.validate(config.WorkingDirectory); err != nil {
+			return nil, err
+		}
+	}
+	infiniteSessions, err := c.prepareWorkspaceRoot(config.InfiniteSessions)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := c.ensureConnected(); err != nil {
@@ -489,58 +625,147 @@ func (c *Client) CreateSession(ctx context.Context, config *SessionConfig) (*Ses
 	req.ReasoningEffort = config.ReasoningEffort
 	req.ConfigDir = config.ConfigDir
 	req.Tools = config.Tools
-	req.SystemMessage = config.SystemMessage
+	var sharedContext *SharedContextInjectedData
+	req.SystemMessage, sharedContext = c.withSharedContext(withResponseLanguage(config.SystemMessage, config.ResponseLanguage), config.SharedContext)
 	req.AvailableTools = config.AvailableTools
 	req.ExcludedTools = config.ExcludedTools
 	req.Provider = config.Provider
 	req.WorkingDirectory = config.WorkingDirectory
-	req.MCPServers = config.MCPServers
+	if len(config.MCPServers) > 0 {
+		c.diagnostics.recordDeprecatedUse(DeprecatedMCPServerMap, 1)
+	}
+	mcpServers, err := mergeMCPServers(config.MCPServers, config.TypedMCPServers)
+	if err != nil {
+		return nil, err
+	}
+	mcpServers, sharedMCP, err := c.attachSharedMCPServers(config.SharedMCPServers, mcpServers)
+	if err != nil {
+		return nil, err
+	}
+	req.MCPServers = mcpServers
 	req.EnvValueMode = "direct"
 	req.CustomAgents = config.CustomAgents
 	req.SkillDirectories = config.SkillDirectories
 	req.DisabledSkills = config.DisabledSkills
-	req.InfiniteSessions = config.InfiniteSessions
+	req.InfiniteSessions = infiniteSessions
+	req.IntegrationID = c.options.IntegrationID
 
 	if config.Streaming {
 		req.Streaming = Bool(true)
 	}
-	if config.OnUserInputRequest != nil {
+	if config.OnUserInputRequest != nil || config.UserInputFallback != nil {
 		req.RequestUserInput = Bool(true)
 	}
-	if config.Hooks != nil && (config.Hooks.OnPreToolUse != nil ||
-		config.Hooks.OnPostToolUse != nil ||
-		config.Hooks.OnUserPromptSubmitted != nil ||
-		config.Hooks.OnSessionStart != nil ||
-		config.Hooks.OnSessionEnd != nil ||
-		config.Hooks.OnErrorOccurred != nil) {
+	hookMode, err := c.resolveHookMode(config.Hooks, config.HookFallback)
+	if err != nil {
+		detachSharedMCPServers(sharedMCP)
+		return nil, err
+	}
+	if err := c.checkFeatures(config.Features); err != nil {
+		detachSharedMCPServers(sharedMCP)
+		return nil, err
+	}
+	req.Features = config.Features
+	if hookMode == HookModeNative || (config.ToolResultSanitizer != nil && c.supportsHooks()) {
 		req.Hooks = Bool(true)
 	}
 	req.RequestPermission = Bool(true)
 
-	result, err := c.client.Request("session.create", req)
+	components := sessionComponents{&req.MCPServers, &req.SkillDirectories, &req.CustomAgents}
+	result, configWarnings, err := c.requestSession("session.create", &req, components, config.PartialFailurePolicy)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create session: %w", err)
+		detachSharedMCPServers(sharedMCP)
+		return nil, fmt.Errorf("failed to create session: %w", classifyError(err))
 	}
 
 	var response createSessionResponse
 	if err := json.Unmarshal(result, &response); err != nil {
+		detachSharedMCPServers(sharedMCP)
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	session := newSession(response.SessionID, c.client, response.WorkspacePath)
-
-	session.registerTools(config.Tools)
+	workspacePath := workspaceUnderRoot(req.InfiniteSessions, response.SessionID, response.WorkspacePath)
+	session := newSession(response.SessionID, c.client, workspacePath)
+	session.listModels = c.ListModels
+	session.capabilities = c.capabilities.Load
+	session.maxInlineBytes = c.options.MaxInlineAttachmentBytes
+	session.eventAliases = c.eventAliases.Load
+	session.fork = c.forkSession
+	session.pacer = c.pacer
+	session.diagnostics = c.diagnostics
+	session.logger = c.logger
+	session.sharedMCP = sharedMCP
+	session.modelFallbacks = newModelFallbackChain(config.Model, config.ModelFallbacks)
+	session.reattachRequest = resumeSessionRequest{
+		SessionID:         response.SessionID,
+		ClientName:        req.ClientName,
+		Model:             req.Model,
+		ReasoningEffort:   req.ReasoningEffort,
+		Tools:             req.Tools,
+		SystemMessage:     req.SystemMessage,
+		AvailableTools:    req.AvailableTools,
+		ExcludedTools:     req.ExcludedTools,
+		Provider:          req.Provider,
+		RequestPermission: req.RequestPermission,
+		RequestUserInput:  req.RequestUserInput,
+		Hooks:             req.Hooks,
+		WorkingDirectory:  req.WorkingDirectory,
+		ConfigDir:         req.ConfigDir,
+		DisableResume:     Bool(true),
+		Streaming:         req.Streaming,
+		MCPServers:        req.MCPServers,
+		EnvValueMode:      req.EnvValueMode,
+		CustomAgents:      req.CustomAgents,
+		SkillDirectories:  req.SkillDirectories,
+		DisabledSkills:    req.DisabledSkills,
+		InfiniteSessions:  req.InfiniteSessions,
+		IntegrationID:     req.IntegrationID,
+		Features:          req.Features,
+		ResponseLanguage:  config.ResponseLanguage,
+	}
+	session.config.resolve(session.reattachRequest, response.sessionConfigEcho)
+
+	session.registerTools(config.Tools, config.ToolTimeout)
 	session.registerPermissionHandler(config.OnPermissionRequest)
+	if config.PermissionPolicy != nil {
+		policy := *config.PermissionPolicy
+		session.permissionPolicy = &policy
+	}
+	if config.AutoApprove != nil {
+		session.autoApprove = newAutoApprover(*config.AutoApprove, session.Config().WorkingDirectory)
+	}
+	session.sanitizer.sanitizer = config.ToolResultSanitizer
+	if config.PermissionBatching != nil && config.PermissionBatching.OnBatch != nil {
+		session.permissionBatcher = newPermissionBatcher(session, *config.PermissionBatching)
+	}
+	if config.PendingActions != nil {
+		session.pendingActions = newPendingActions(*config.PendingActions)
+	}
+	if config.InfiniteSessions != nil && config.InfiniteSessions.MaxWorkspaceBytes > 0 {
+		session.workspaceLimit = &workspaceLimiter{maxBytes: config.InfiniteSessions.MaxWorkspaceBytes}
+	}
 	if config.OnUserInputRequest != nil {
 		session.registerUserInputHandler(config.OnUserInputRequest)
 	}
-	if config.Hooks != nil {
-		session.registerHooks(config.Hooks)
+	session.userInputFallback = config.UserInputFallback
+	session.promptPreflight = config.PromptPreflight
+	if config.Budget != nil {
+		session.budget = newBudgetTracker(*config.Budget)
+	}
+	session.applyHooks(config.Hooks, hookMode)
+	if config.EventOrder != nil {
+		session.eventOrder = newEventOrderGuard(*config.EventOrder, session.deliverEvent)
 	}
+	session.slowHandlers = config.SlowHandlers
 
-	c.sessionsMux.Lock()
-	c.sessions[response.SessionID] = session
-	c.sessionsMux.Unlock()
+	for _, handler := range config.OnEvent {
+		session.On(handler)
+	}
+	c.registerSession(session)
+	session.warnUnsupportedHooks(config.Hooks, hookMode)
+	session.warnWorkspaceOutsideRoot(req.InfiniteSessions)
+	session.warnConfigComponents(configWarnings)
+	session.reportSharedContext(sharedContext)
 
 	return session, nil
 }
@@ -556,7 +781,7 @@ func (c *Client) CreateSession(ctx context.Context, config *SessionConfig) (*Ses
 //	    OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
 //	})
 func (c *Client) ResumeSession(ctx context.Context, sessionID string, config *ResumeSessionConfig) (*Session, error) {
-	return c.ResumeSessionWithOptions(ctx, sessionID, config)
+	return c.resumeSession(ctx, sessionID, config)
 }
 
 // ResumeSessionWithOptions resumes an existing conversation session with additional configuration.
@@ -571,8 +796,37 @@ func (c *Client) ResumeSession(ctx context.Context, sessionID string, config *Re
 //	    Tools: []copilot.Tool{myNewTool},
 //	})
 func (c *Client) ResumeSessionWithOptions(ctx context.Context, sessionID string, config *ResumeSessionConfig) (*Session, error) {
-	if config == nil || config.OnPermissionRequest == nil {
-		return nil, fmt.Errorf("an OnPermissionRequest handler is required when resuming a session. For example, to allow all permissions, use &copilot.ResumeSessionConfig{OnPermissionRequest: copilot.PermissionHandler.ApproveAll}")
+	return c.resumeSession(ctx, sessionID, config)
+}
+
+// resumeSession resumes a session for [Client.ResumeSession] and
+// [Client.ResumeSessionWithOptions].
+func (c *Client) resumeSession(ctx context.Context, sessionID string, config *ResumeSessionConfig) (*Session, error) {
+	if config == nil || !decidesPermissions(config.OnPermissionRequest, config.PermissionPolicy, config.AutoApprove, config.PermissionBatching, config.PendingActions) {
+		return nil, fmt.Errorf("an OnPermissionRequest handler, PermissionPolicy or AutoApprove policy is required when resuming a session. For example, to allow all permissions, use &copilot.ResumeSessionConfig{OnPermissionRequest: copilot.PermissionHandler.ApproveAll}")
+	}
+
+	if config.ResponseLanguage != "" {
+		if err := validateLanguageTag("ResponseLanguage", config.ResponseLanguage); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkToolSchemas(config.Tools); err != nil {
+		return nil, err
+	}
+	if config.PermissionPolicy != nil {
+		if err := config.PermissionPolicy.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if config.AutoApprove != nil {
+		if err := config.AutoApprove.validate(config.WorkingDirectory); err != nil {
+			return nil, err
+		}
+	}
+	infiniteSessions, err := c.prepareWorkspaceRoot(config.InfiniteSessions)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := c.ensureConnected(); err != nil {
@@ -584,7 +838,7 @@ func (c *Client) ResumeSessionWithOptions(ctx context.Context, sessionID string,
 	req.ClientName = config.ClientName
 	req.Model = config.Model
 	req.ReasoningEffort = config.ReasoningEffort
-	req.SystemMessage = config.SystemMessage
+	req.ResponseLanguage = config.ResponseLanguage
 	req.Tools = config.Tools
 	req.Provider = config.Provider
 	req.AvailableTools = config.AvailableTools
@@ -592,53 +846,137 @@ func (c *Client) ResumeSessionWithOptions(ctx context.Context, sessionID string,
 	if config.Streaming {
 		req.Streaming = Bool(true)
 	}
-	if config.OnUserInputRequest != nil {
+	if config.OnUserInputRequest != nil || config.UserInputFallback != nil {
 		req.RequestUserInput = Bool(true)
 	}
-	if config.Hooks != nil && (config.Hooks.OnPreToolUse != nil ||
-		config.Hooks.OnPostToolUse != nil ||
-		config.Hooks.OnUserPromptSubmitted != nil ||
-		config.Hooks.OnSessionStart != nil ||
-		config.Hooks.OnSessionEnd != nil ||
-		config.Hooks.OnErrorOccurred != nil) {
+	hookMode, err := c.resolveHookMode(config.Hooks, config.HookFallback)
+	if err != nil {
+		return nil, err
+	}
+	if hookMode == HookModeNative || (config.ToolResultSanitizer != nil && c.supportsHooks()) {
 		req.Hooks = Bool(true)
 	}
+	if err := c.checkFeatures(config.Features); err != nil {
+		return nil, err
+	}
+	req.Features = config.Features
 	req.WorkingDirectory = config.WorkingDirectory
 	req.ConfigDir = config.ConfigDir
 	if config.DisableResume {
 		req.DisableResume = Bool(true)
 	}
-	req.MCPServers = config.MCPServers
+	if len(config.MCPServers) > 0 {
+		c.diagnostics.recordDeprecatedUse(DeprecatedMCPServerMap, 2)
+	}
+	mcpServers, err := mergeMCPServers(config.MCPServers, config.TypedMCPServers)
+	if err != nil {
+		return nil, err
+	}
+	mcpServers, sharedMCP, err := c.attachSharedMCPServers(config.SharedMCPServers, mcpServers)
+	if err != nil {
+		return nil, err
+	}
+	req.MCPServers = mcpServers
 	req.EnvValueMode = "direct"
 	req.CustomAgents = config.CustomAgents
 	req.SkillDirectories = config.SkillDirectories
 	req.DisabledSkills = config.DisabledSkills
-	req.InfiniteSessions = config.InfiniteSessions
+	req.InfiniteSessions = infiniteSessions
+	req.IntegrationID = c.options.IntegrationID
 	req.RequestPermission = Bool(true)
 
-	result, err := c.client.Request("session.resume", req)
+	// A session this client already tracks keeps its infinite session
+	// settings, workspace and response language unless the caller overrides
+	// them, so they survive resuming without repeating the config.
+	var previousWorkspace string
+	if previous := c.sessions.get(sessionID); previous != nil {
+		if req.InfiniteSessions == nil {
+			req.InfiniteSessions = previous.reattachRequest.InfiniteSessions
+		}
+		if infiniteSessionsEnabled(req.InfiniteSessions) {
+			previousWorkspace = previous.WorkspacePath()
+		}
+		if req.ResponseLanguage == "" {
+			req.ResponseLanguage = previous.reattachRequest.ResponseLanguage
+		}
+	}
+	var sharedContext *SharedContextInjectedData
+	req.SystemMessage, sharedContext = c.withSharedContext(withResponseLanguage(config.SystemMessage, req.ResponseLanguage), config.SharedContext)
+
+	components := sessionComponents{&req.MCPServers, &req.SkillDirectories, &req.CustomAgents}
+	result, configWarnings, err := c.requestSession("session.resume", &req, components, config.PartialFailurePolicy)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resume session: %w", err)
+		detachSharedMCPServers(sharedMCP)
+		return nil, fmt.Errorf("failed to resume session: %w", classifyError(err))
 	}
 
 	var response resumeSessionResponse
 	if err := json.Unmarshal(result, &response); err != nil {
+		detachSharedMCPServers(sharedMCP)
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
+	if response.WorkspacePath == "" && response.SessionID == sessionID {
+		response.WorkspacePath = previousWorkspace
+	}
+	response.WorkspacePath = workspaceUnderRoot(req.InfiniteSessions, response.SessionID, response.WorkspacePath)
+
 	session := newSession(response.SessionID, c.client, response.WorkspacePath)
-	session.registerTools(config.Tools)
+	session.listModels = c.ListModels
+	session.capabilities = c.capabilities.Load
+	session.maxInlineBytes = c.options.MaxInlineAttachmentBytes
+	session.eventAliases = c.eventAliases.Load
+	session.fork = c.forkSession
+	session.pacer = c.pacer
+	session.diagnostics = c.diagnostics
+	session.logger = c.logger
+	session.sharedMCP = sharedMCP
+	session.modelFallbacks = newModelFallbackChain(config.Model, config.ModelFallbacks)
+	session.reattachRequest = req
+	session.reattachRequest.SessionID = response.SessionID
+	session.reattachRequest.DisableResume = Bool(true)
+	session.config.resolve(req, response.sessionConfigEcho)
+	session.registerTools(config.Tools, config.ToolTimeout)
 	session.registerPermissionHandler(config.OnPermissionRequest)
+	if config.PermissionPolicy != nil {
+		policy := *config.PermissionPolicy
+		session.permissionPolicy = &policy
+	}
+	if config.AutoApprove != nil {
+		session.autoApprove = newAutoApprover(*config.AutoApprove, session.Config().WorkingDirectory)
+	}
+	session.sanitizer.sanitizer = config.ToolResultSanitizer
+	if config.PermissionBatching != nil && config.PermissionBatching.OnBatch != nil {
+		session.permissionBatcher = newPermissionBatcher(session, *config.PermissionBatching)
+	}
+	if config.PendingActions != nil {
+		session.pendingActions = newPendingActions(*config.PendingActions)
+	}
+	if config.InfiniteSessions != nil && config.InfiniteSessions.MaxWorkspaceBytes > 0 {
+		session.workspaceLimit = &workspaceLimiter{maxBytes: config.InfiniteSessions.MaxWorkspaceBytes}
+	}
 	if config.OnUserInputRequest != nil {
 		session.registerUserInputHandler(config.OnUserInputRequest)
 	}
-	if config.Hooks != nil {
-		session.registerHooks(config.Hooks)
+	session.userInputFallback = config.UserInputFallback
+	session.promptPreflight = config.PromptPreflight
+	if config.Budget != nil {
+		session.budget = newBudgetTracker(*config.Budget)
+	}
+	session.applyHooks(config.Hooks, hookMode)
+	if config.EventOrder != nil {
+		session.eventOrder = newEventOrderGuard(*config.EventOrder, session.deliverEvent)
 	}
+	session.slowHandlers = config.SlowHandlers
 
-	c.sessionsMux.Lock()
-	c.sessions[response.SessionID] = session
-	c.sessionsMux.Unlock()
+	for _, handler := range config.OnEvent {
+		session.On(handler)
+	}
+	c.registerSession(session)
+	session.warnUnsupportedHooks(config.Hooks, hookMode)
+	session.warnWorkspaceOutsideRoot(req.InfiniteSessions)
+	session.warnConfigComponents(configWarnings)
+	session.reportSharedContext(sharedContext)
 
 	return session, nil
 }
@@ -646,9 +984,13 @@ func (c *Client) ResumeSessionWithOptions(ctx context.Context, sessionID string,
 // ListSessions returns metadata about all sessions known to the server.
 //
 // Returns a list of SessionMetadata for all available sessions, including their IDs,
-// timestamps, optional summaries, and context information.
+// timestamps, optional titles and summaries, and context information. For
+// sessions this client has open, the title and summary are the latest ones
+// the session has seen, from its events or [Session.Summarize].
 //
 // An optional filter can be provided to filter sessions by cwd, git root, repository, or branch.
+// Set its Limit and ModifiedBefore to page through the sessions, most
+// recently modified first.
 //
 // Example:
 //
@@ -663,6 +1005,13 @@ func (c *Client) ResumeSessionWithOptions(ctx context.Context, sessionID string,
 // Example with filter:
 //
 //	sessions, err := client.ListSessions(context.Background(), &SessionListFilter{Repository: "owner/repo"})
+//
+// Example paging through a picker:
+//
+//	filter := &copilot.SessionListFilter{Cwd: cwd, Limit: 20}
+//	page, err := client.ListSessions(ctx, filter)
+//	// ... show page; for the next one:
+//	filter.ModifiedBefore = page[len(page)-1].ModifiedAt
 func (c *Client) ListSessions(ctx context.Context, filter *SessionListFilter) ([]SessionMetadata, error) {
 	if err := c.ensureConnected(); err != nil {
 		return nil, err
@@ -672,7 +1021,7 @@ func (c *Client) ListSessions(ctx context.Context, filter *SessionListFilter) ([
 	if filter != nil {
 		params.Filter = filter
 	}
-	result, err := c.client.Request("session.list", params)
+	result, err := c.client.RequestContext(ctx, "session.list", params)
 	if err != nil {
 		return nil, err
 	}
@@ -682,7 +1031,16 @@ func (c *Client) ListSessions(ctx context.Context, filter *SessionListFilter) ([
 		return nil, fmt.Errorf("failed to unmarshal sessions response: %w", err)
 	}
 
-	return response.Sessions, nil
+	for i := range response.Sessions {
+		latest := c.latestSummary(response.Sessions[i].SessionID)
+		if latest.Summary != "" {
+			response.Sessions[i].Summary = String(latest.Summary)
+		}
+		if latest.Title != "" {
+			response.Sessions[i].Title = String(latest.Title)
+		}
+	}
+	return paginateSessions(response.Sessions, filter), nil
 }
 
 // DeleteSession permanently deletes a session and all its conversation history.
@@ -719,9 +1077,9 @@ func (c *Client) DeleteSession(ctx context.Context, sessionID string) error {
 	}
 
 	// Remove from local sessions map if present
-	c.sessionsMux.Lock()
-	delete(c.sessions, sessionID)
-	c.sessionsMux.Unlock()
+	if session := c.sessions.remove(sessionID); session != nil {
+		session.detachSharedMCPServers()
+	}
 
 	return nil
 }
@@ -747,7 +1105,7 @@ func (c *Client) GetForegroundSessionID(ctx context.Context) (*string, error) {
 				return nil, err
 			}
 		} else {
-			return nil, fmt.Errorf("client not connected. Call Start() first")
+			return nil, errNotConnected
 		}
 	}
 
@@ -781,7 +1139,7 @@ func (c *Client) SetForegroundSessionID(ctx context.Context, sessionID string) e
 				return err
 			}
 		} else {
-			return fmt.Errorf("client not connected. Call Start() first")
+			return errNotConnected
 		}
 	}
 
@@ -880,18 +1238,14 @@ func (c *Client) handleLifecycleEvent(event SessionLifecycleEvent) {
 	copy(wildcardHandlers, c.lifecycleHandlers)
 	c.lifecycleHandlersMux.Unlock()
 
-	// Dispatch to typed handlers
-	for _, handler := range typedHandlers {
+	// Dispatch to typed handlers, then wildcard handlers
+	for _, handler := range append(typedHandlers, wildcardHandlers...) {
 		func() {
-			defer func() { recover() }() // Ignore handler panics
-			handler(event)
-		}()
-	}
-
-	// Dispatch to wildcard handlers
-	for _, handler := range wildcardHandlers {
-		func() {
-			defer func() { recover() }() // Ignore handler panics
+			defer func() {
+				if r := recover(); r != nil {
+					c.diagnostics.recordPanic("lifecycle handler", event.SessionID, r)
+				}
+			}()
 			handler(event)
 		}()
 	}
@@ -900,6 +1254,8 @@ func (c *Client) handleLifecycleEvent(event SessionLifecycleEvent) {
 // State returns the current connection state of the client.
 //
 // Possible states: StateDisconnected, StateConnecting, StateConnected, StateError.
+// A connected client moves to StateError when the connection to the CLI server
+// is lost; later calls fail with an error until [Client.Restart] reconnects.
 //
 // Example:
 //
@@ -929,10 +1285,10 @@ func (c *Client) State() ConnectionState {
 //	}
 func (c *Client) Ping(ctx context.Context, message string) (*PingResponse, error) {
 	if c.client == nil {
-		return nil, fmt.Errorf("client not connected")
+		return nil, errNotConnected
 	}
 
-	result, err := c.client.Request("ping", pingRequest{Message: message})
+	result, err := c.client.RequestContext(ctx, "ping", pingRequest{Message: message, Compression: c.acceptedEncodings()})
 	if err != nil {
 		return nil, err
 	}
@@ -947,10 +1303,10 @@ func (c *Client) Ping(ctx context.Context, message string) (*PingResponse, error
 // GetStatus returns CLI status including version and protocol information
 func (c *Client) GetStatus(ctx context.Context) (*GetStatusResponse, error) {
 	if c.client == nil {
-		return nil, fmt.Errorf("client not connected")
+		return nil, errNotConnected
 	}
 
-	result, err := c.client.Request("status.get", getStatusRequest{})
+	result, err := c.client.RequestContext(ctx, "status.get", getStatusRequest{})
 	if err != nil {
 		return nil, err
 	}
@@ -965,10 +1321,10 @@ func (c *Client) GetStatus(ctx context.Context) (*GetStatusResponse, error) {
 // GetAuthStatus returns current authentication status
 func (c *Client) GetAuthStatus(ctx context.Context) (*GetAuthStatusResponse, error) {
 	if c.client == nil {
-		return nil, fmt.Errorf("client not connected")
+		return nil, errNotConnected
 	}
 
-	result, err := c.client.Request("auth.getStatus", getAuthStatusRequest{})
+	result, err := c.client.RequestContext(ctx, "auth.getStatus", getAuthStatusRequest{})
 	if err != nil {
 		return nil, err
 	}
@@ -986,7 +1342,7 @@ func (c *Client) GetAuthStatus(ctx context.Context) (*GetAuthStatusResponse, err
 // The cache is cleared when the client disconnects.
 func (c *Client) ListModels(ctx context.Context) ([]ModelInfo, error) {
 	if c.client == nil {
-		return nil, fmt.Errorf("client not connected")
+		return nil, errNotConnected
 	}
 
 	// Use mutex for locking to prevent race condition with concurrent calls
@@ -1002,9 +1358,9 @@ func (c *Client) ListModels(ctx context.Context) ([]ModelInfo, error) {
 	}
 
 	// Cache miss - fetch from backend while holding lock
-	result, err := c.client.Request("models.list", listModelsRequest{})
+	result, err := c.client.RequestContext(ctx, "models.list", listModelsRequest{})
 	if err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 
 	var response listModelsResponse
@@ -1026,17 +1382,26 @@ func (c *Client) verifyProtocolVersion(ctx context.Context) error {
 	expectedVersion := GetSdkProtocolVersion()
 	pingResult, err := c.Ping(ctx, "")
 	if err != nil {
-		return err
+		return c.handshakeError(ctx, err)
 	}
 
 	if pingResult.ProtocolVersion == nil {
-		return fmt.Errorf("SDK protocol version mismatch: SDK expects version %d, but server does not report a protocol version. Please update your server to ensure compatibility", expectedVersion)
+		return &HandshakeError{
+			Received: "no protocol version",
+			Reason:   fmt.Sprintf("SDK protocol version mismatch: SDK expects version %d, but server does not report a protocol version. Please update your server to ensure compatibility", expectedVersion),
+		}
 	}
 
 	if *pingResult.ProtocolVersion != expectedVersion {
-		return fmt.Errorf("SDK protocol version mismatch: SDK expects version %d, but server reports version %d. Please update your SDK or server to ensure compatibility", expectedVersion, *pingResult.ProtocolVersion)
+		return &HandshakeError{
+			Received: fmt.Sprintf("protocol version %d", *pingResult.ProtocolVersion),
+			Reason:   fmt.Sprintf("SDK protocol version mismatch: SDK expects version %d, but server reports version %d. Please update your SDK or server to ensure compatibility", expectedVersion, *pingResult.ProtocolVersion),
+		}
 	}
 
+	c.capabilities.Store(pingResult.Capabilities)
+	c.eventAliases.Store(newEventAliasTable(*pingResult.ProtocolVersion, c.options.EventAliases))
+	c.negotiateCompression(pingResult.Capabilities)
 	return nil
 }
 
@@ -1084,10 +1449,15 @@ func (c *Client) startCLIServer(ctx context.Context) error {
 	// If CLIPath is a .js file, run it with node
 	// Note we can't rely on the shebang as Windows doesn't support it
 	command := cliPath
+	script := ""
 	if strings.HasSuffix(cliPath, ".js") {
 		command = "node"
+		script = cliPath
 		args = append([]string{cliPath}, args...)
 	}
+	if err := lookupCLI(command, script); err != nil {
+		return err
+	}
 
 	c.process = exec.CommandContext(ctx, command, args...)
 
@@ -1099,6 +1469,10 @@ func (c *Client) startCLIServer(ctx context.Context) error {
 		c.process.Dir = c.options.Cwd
 	}
 
+	// Keep the tail of stderr for diagnostic bundles and start errors
+	c.startStderr = newDiagnosticsRecorder()
+	c.process.Stderr = io.MultiWriter(c.diagnostics, c.startStderr)
+
 	// Add auth token if needed.
 	c.process.Env = c.options.Env
 	if c.options.GitHubToken != "" {
@@ -1118,13 +1492,28 @@ func (c *Client) startCLIServer(ctx context.Context) error {
 		}
 
 		if err := c.process.Start(); err != nil {
-			return fmt.Errorf("failed to start CLI server: %w", err)
+			return c.processStartError(command, err)
 		}
 
 		c.monitorProcess()
 
 		// Create JSON-RPC client immediately
 		c.client = jsonrpc2.NewClient(stdin, stdout)
+		c.client.SetIDGenerator(c.options.RequestIDGenerator)
+		c.client.SetMaxMessageSize(c.options.MaxMessageBytes)
+		c.client.SetRequestTimeout(c.options.RequestTimeout)
+		if c.options.RetryPolicy != nil {
+			c.client.SetRetryPolicy(c.options.RetryPolicy.jsonrpc2Policy())
+		}
+		c.client.SetLogger(c.logger)
+		c.client.SetMaxPendingRequests(c.options.MaxPendingRequests)
+		c.client.SetPendingWarning(c.options.PendingRequestsWarning)
+		c.client.SetRequestQueue(c.serverRequestQueue())
+		c.client.SetCallObserver(c.observeCall)
+		if c.tracer != nil {
+			c.client.SetTracer(c.traceMessage)
+		}
+		c.watchConnection(c.client)
 		c.client.SetProcessDone(c.processDone, c.processErrorPtr)
 		c.RPC = rpc.NewServerRpc(c.client)
 		c.setupNotificationHandler()
@@ -1139,7 +1528,7 @@ func (c *Client) startCLIServer(ctx context.Context) error {
 		}
 
 		if err := c.process.Start(); err != nil {
-			return fmt.Errorf("failed to start CLI server: %w", err)
+			return c.processStartError(command, err)
 		}
 
 		c.monitorProcess()
@@ -1151,11 +1540,13 @@ func (c *Client) startCLIServer(ctx context.Context) error {
 		for {
 			select {
 			case <-timeout:
+				startErr := c.startError("timeout waiting for CLI server to start", nil)
 				killErr := c.killProcess()
-				return errors.Join(errors.New("timeout waiting for CLI server to start"), killErr)
+				return errors.Join(startErr, killErr)
 			case <-c.processDone:
+				startErr := c.startError("CLI server process exited before reporting port", nil)
 				killErr := c.killProcess()
-				return errors.Join(errors.New("CLI server process exited before reporting port"), killErr)
+				return errors.Join(startErr, killErr)
 			default:
 				if scanner.Scan() {
 					line := scanner.Text()
@@ -1238,6 +1629,21 @@ func (c *Client) connectViaTcp(ctx context.Context) error {
 
 	// Create JSON-RPC client with the connection
 	c.client = jsonrpc2.NewClient(conn, conn)
+	c.client.SetIDGenerator(c.options.RequestIDGenerator)
+	c.client.SetMaxMessageSize(c.options.MaxMessageBytes)
+	c.client.SetRequestTimeout(c.options.RequestTimeout)
+	if c.options.RetryPolicy != nil {
+		c.client.SetRetryPolicy(c.options.RetryPolicy.jsonrpc2Policy())
+	}
+	c.client.SetLogger(c.logger)
+	c.client.SetMaxPendingRequests(c.options.MaxPendingRequests)
+	c.client.SetPendingWarning(c.options.PendingRequestsWarning)
+	c.client.SetRequestQueue(c.serverRequestQueue())
+	c.client.SetCallObserver(c.observeCall)
+	if c.tracer != nil {
+		c.client.SetTracer(c.traceMessage)
+	}
+	c.watchConnection(c.client)
 	if c.processDone != nil {
 		c.client.SetProcessDone(c.processDone, c.processErrorPtr)
 	}
@@ -1248,6 +1654,46 @@ func (c *Client) connectViaTcp(ctx context.Context) error {
 	return nil
 }
 
+// observeCall records a completed JSON-RPC call for diagnostics and the
+// server load estimate, and passes it to ClientOptions.OnRPCCall.
+func (c *Client) observeCall(call jsonrpc2.Call) {
+	c.diagnostics.observeCall(call)
+	c.serverLoad.observeCall(call)
+	if c.options.OnRPCCall == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			c.diagnostics.recordPanic("rpc call callback", "", r)
+		}
+	}()
+	c.options.OnRPCCall(RPCCall{
+		Method:             call.Method,
+		RequestID:          call.ID,
+		Incoming:           call.Incoming,
+		Duration:           call.Duration,
+		Err:                call.Err,
+		OverPendingWarning: call.OverPendingWarning,
+	})
+}
+
+// watchConnection moves the client to StateError when rpcClient loses its
+// connection, unless the client has since been stopped or reconnected, and
+// restarts the CLI if [ClientOptions.AutoRestart] applies.
+func (c *Client) watchConnection(rpcClient *jsonrpc2.Client) {
+	rpcClient.SetConnectionLostHandler(func(err error) {
+		c.startStopMux.Lock()
+		defer c.startStopMux.Unlock()
+		if c.client != rpcClient || c.state != StateConnected {
+			return
+		}
+		c.setStateLocked(StateError, err)
+		if c.autoRestart && !c.isExternalServer {
+			c.scheduleAutoRestart(err)
+		}
+	})
+}
+
 // setupNotificationHandler configures handlers for session events, tool calls, and permission requests.
 func (c *Client) setupNotificationHandler() {
 	c.client.SetRequestHandler("session.event", jsonrpc2.NotificationHandlerFor(c.handleSessionEvent))
@@ -1262,12 +1708,11 @@ func (c *Client) handleSessionEvent(req sessionEventRequest) {
 	if req.SessionID == "" {
 		return
 	}
-	// Dispatch to session
-	c.sessionsMux.Lock()
-	session, ok := c.sessions[req.SessionID]
-	c.sessionsMux.Unlock()
-
-	if ok {
+	c.resolveEventType(&req.Event)
+	c.observeSessionEvent(req.SessionID, req.Event)
+	// Dispatch to session, or hold the event until the session is registered
+	session := c.earlyEvents.sessionOrBuffer(c, req)
+	if session != nil {
 		session.dispatchEvent(req.Event)
 	}
 }
@@ -1278,37 +1723,76 @@ func (c *Client) handleToolCallRequest(req toolCallRequest) (*toolCallResponse,
 		return nil, &jsonrpc2.Error{Code: -32602, Message: "invalid tool call payload"}
 	}
 
-	c.sessionsMux.Lock()
-	session, ok := c.sessions[req.SessionID]
-	c.sessionsMux.Unlock()
-	if !ok {
+	session := c.sessions.get(req.SessionID)
+	if session == nil {
 		return nil, &jsonrpc2.Error{Code: -32602, Message: fmt.Sprintf("unknown session %s", req.SessionID)}
 	}
 
-	handler, ok := session.getToolHandler(req.ToolName)
+	tool, ok := session.getToolHandler(req.ToolName)
 	if !ok {
 		return &toolCallResponse{Result: buildUnsupportedToolResult(req.ToolName)}, nil
 	}
 
-	result := c.executeToolCall(req.SessionID, req.ToolCallID, req.ToolName, req.Arguments, handler)
+	// A retried tool.call for a non-idempotent tool gets the first call's
+	// result instead of running the handler again
+	var result ToolResult
+	if !tool.idempotent {
+		call, first := session.toolCalls.begin(req.ToolCallID)
+		if !first {
+			return &toolCallResponse{Result: call.wait()}, nil
+		}
+		defer func() { session.toolCalls.finish(call, result) }()
+	}
+	if session.sequentialTools.Load() {
+		session.toolSerial.Lock()
+		defer session.toolSerial.Unlock()
+	}
+
+	ctx, messageID, traceID := session.trace.current()
+	defer session.timeline.begin(TimelineToolRunning, req.ToolName, req.ToolCallID, traceID)()
+	defer session.state.begin(holdTool, req.ToolCallID)()
+	ctx, done := session.runningTools.begin(ctx, req.ToolCallID)
+	defer done()
+	invocation := ToolInvocation{
+		SessionID:        req.SessionID,
+		ToolCallID:       req.ToolCallID,
+		ToolName:         req.ToolName,
+		Arguments:        req.Arguments,
+		RawArguments:     req.RawArguments,
+		Timestamp:        toolCallTimestamp(req.RawTimestamp),
+		WorkingDirectory: req.Cwd,
+		Context:          ctx,
+		MessageID:        messageID,
+		TraceID:          traceID,
+		logs:             session.toolLogs.begin(req.ToolCallID),
+		session:          session,
+	}
+	if invocation.WorkingDirectory == "" {
+		invocation.WorkingDirectory = session.Config().WorkingDirectory
+	}
+	if tool.timeout > 0 {
+		result = c.executeToolCallWithTimeout(session, invocation, tool.handler, tool.timeout)
+	} else {
+		result = c.executeToolCall(invocation, tool.handler)
+	}
+	result = session.sanitizeToolResult(ctx, req.ToolName, req.ToolCallID, result)
 	return &toolCallResponse{Result: result}, nil
 }
 
-// executeToolCall executes a tool handler and returns the result.
-func (c *Client) executeToolCall(
-	sessionID, toolCallID, toolName string,
-	arguments any,
-	handler ToolHandler,
-) (result ToolResult) {
-	invocation := ToolInvocation{
-		SessionID:  sessionID,
-		ToolCallID: toolCallID,
-		ToolName:   toolName,
-		Arguments:  arguments,
+// toolCallTimestamp returns when the CLI made a tool call, or now if it
+// reported no time or one that doesn't parse.
+func toolCallTimestamp(raw json.RawMessage) time.Time {
+	if t, err := parseTimestamp(raw); err == nil && !t.IsZero() {
+		return t
 	}
+	return time.Now()
+}
 
+// executeToolCall executes a tool handler and returns the result.
+func (c *Client) executeToolCall(invocation ToolInvocation, handler ToolHandler) (result ToolResult) {
 	defer func() {
 		if r := recover(); r != nil {
+			c.diagnostics.recordPanic("tool "+invocation.ToolName, invocation.SessionID, r)
 			result = buildFailedToolResult(fmt.Sprintf("tool panic: %v", r))
 		}
 	}()
@@ -1316,6 +1800,9 @@ func (c *Client) executeToolCall(
 	if handler != nil {
 		var err error
 		result, err = handler(invocation)
+		if err == nil {
+			result, err = result.expandContent()
+		}
 		if err != nil {
 			result = buildFailedToolResult(err.Error())
 		}
@@ -1330,21 +1817,18 @@ func (c *Client) handlePermissionRequest(req permissionRequestRequest) (*permiss
 		return nil, &jsonrpc2.Error{Code: -32602, Message: "invalid permission request payload"}
 	}
 
-	c.sessionsMux.Lock()
-	session, ok := c.sessions[req.SessionID]
-	c.sessionsMux.Unlock()
-	if !ok {
+	session := c.sessions.get(req.SessionID)
+	if session == nil {
 		return nil, &jsonrpc2.Error{Code: -32602, Message: fmt.Sprintf("unknown session %s", req.SessionID)}
 	}
 
+	_, _, traceID := session.trace.current()
+	endSpan := session.timeline.begin(TimelinePermission, req.Request.Kind, req.Request.ToolCallID, traceID)
 	result, err := session.handlePermissionRequest(req.Request)
+	endSpan()
 	if err != nil {
 		// Return denial on error
-		return &permissionRequestResponse{
-			Result: PermissionRequestResult{
-				Kind: "denied-no-approval-rule-and-could-not-request-from-user",
-			},
-		}, nil
+		return &permissionRequestResponse{Result: DeniedNoApprovalRule()}, nil
 	}
 
 	return &permissionRequestResponse{Result: result}, nil
@@ -1356,10 +1840,8 @@ func (c *Client) handleUserInputRequest(req userInputRequest) (*userInputRespons
 		return nil, &jsonrpc2.Error{Code: -32602, Message: "invalid user input request payload"}
 	}
 
-	c.sessionsMux.Lock()
-	session, ok := c.sessions[req.SessionID]
-	c.sessionsMux.Unlock()
-	if !ok {
+	session := c.sessions.get(req.SessionID)
+	if session == nil {
 		return nil, &jsonrpc2.Error{Code: -32602, Message: fmt.Sprintf("unknown session %s", req.SessionID)}
 	}
 
@@ -1381,14 +1863,15 @@ func (c *Client) handleHooksInvoke(req hooksInvokeRequest) (map[string]any, *jso
 		return nil, &jsonrpc2.Error{Code: -32602, Message: "invalid hooks invoke payload"}
 	}
 
-	c.sessionsMux.Lock()
-	session, ok := c.sessions[req.SessionID]
-	c.sessionsMux.Unlock()
-	if !ok {
+	session := c.sessions.get(req.SessionID)
+	if session == nil {
 		return nil, &jsonrpc2.Error{Code: -32602, Message: fmt.Sprintf("unknown session %s", req.SessionID)}
 	}
 
+	_, _, traceID := session.trace.current()
+	endSpan := session.timeline.begin(TimelineHook, req.Type, "", traceID)
 	output, err := session.handleHooksInvoke(req.Type, req.Input)
+	endSpan()
 	if err != nil {
 		return nil, &jsonrpc2.Error{Code: -32603, Message: err.Error()}
 	}
@@ -1,13 +1,21 @@
 package copilot
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 // This file is for unit tests. Where relevant, prefer to add e2e tests in e2e/*.test.go instead
@@ -528,3 +536,247 @@ func TestClient_StartStopRace(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestClient_IntegrationID(t *testing.T) {
+	t.Run("should panic on an invalid IntegrationID", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("Expected panic for invalid IntegrationID")
+			} else if !strings.Contains(r.(string), "invalid IntegrationID") {
+				t.Errorf("Expected panic message about IntegrationID, got: %v", r)
+			}
+		}()
+
+		NewClient(&ClientOptions{IntegrationID: "has spaces"})
+	})
+
+	t.Run("should send IntegrationID on session create", func(t *testing.T) {
+		var created createSessionRequest
+		cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.create" {
+				json.Unmarshal(params, &created)
+				return createSessionResponse{SessionID: "s1"}, nil
+			}
+			return nil, nil
+		})
+
+		client := NewClient(&ClientOptions{CLIUrl: cli.addr(), IntegrationID: "acme-reviews"})
+		t.Cleanup(func() { client.ForceStop() })
+		if _, err := client.CreateSession(t.Context(), &SessionConfig{OnPermissionRequest: PermissionHandler.ApproveAll}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if created.IntegrationID != "acme-reviews" {
+			t.Errorf("Expected integrationId to be 'acme-reviews', got %q", created.IntegrationID)
+		}
+	})
+}
+
+func TestClient_StopTimesOutWedgedDestroy(t *testing.T) {
+	wedged := make(chan struct{})
+	t.Cleanup(func() { close(wedged) })
+	var mu sync.Mutex
+	var destroyed []string
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "session.create":
+			var req createSessionRequest
+			json.Unmarshal(params, &req)
+			return createSessionResponse{SessionID: req.SessionID}, nil
+		case "session.destroy":
+			var req sessionDestroyRequest
+			json.Unmarshal(params, &req)
+			if strings.HasPrefix(req.SessionID, "wedged") {
+				<-wedged // never answers
+				return nil, nil
+			}
+			mu.Lock()
+			destroyed = append(destroyed, req.SessionID)
+			mu.Unlock()
+		}
+		return nil, nil
+	})
+
+	const cleanupTimeout = 300 * time.Millisecond
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr(), CleanupTimeout: cleanupTimeout})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	var sessions []*Session
+	for _, id := range []string{"a", "wedged-1", "wedged-2", "wedged-3", "z"} {
+		session, err := client.CreateSession(t.Context(), &SessionConfig{SessionID: id, OnPermissionRequest: PermissionHandler.ApproveAll})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	start := time.Now()
+	err := client.Stop()
+	// Destroyed one after another, the wedged sessions would take three
+	// timeouts
+	if elapsed := time.Since(start); elapsed > 2*cleanupTimeout {
+		t.Fatalf("Expected Stop to finish within one cleanup timeout, took %v", elapsed)
+	}
+
+	var stopErr *StopError
+	if !errors.As(err, &stopErr) {
+		t.Fatalf("Expected StopError, got %v", err)
+	}
+	if len(stopErr.DestroyFailed) != 3 {
+		t.Errorf("Expected the wedged sessions to time out, got %v", stopErr.DestroyFailed)
+	}
+	for id, err := range stopErr.DestroyFailed {
+		if !strings.HasPrefix(id, "wedged") || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected only wedged sessions to time out, got %s: %v", id, err)
+		}
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Expected StopError to unwrap to the timeout")
+	}
+
+	mu.Lock()
+	sort.Strings(destroyed)
+	if strings.Join(destroyed, ",") != "a,z" {
+		t.Errorf("Expected the other sessions to be destroyed, got %v", destroyed)
+	}
+	mu.Unlock()
+
+	for _, session := range sessions {
+		if _, ok := session.DestroyReason(); !ok {
+			t.Errorf("Expected session %s to be released", session.SessionID)
+		}
+	}
+	if client.State() != StateDisconnected {
+		t.Errorf("Expected client to be disconnected, got %s", client.State())
+	}
+}
+
+func TestClient_ConnectionLost(t *testing.T) {
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "session.create":
+			var req createSessionRequest
+			json.Unmarshal(params, &req)
+			return createSessionResponse{SessionID: req.SessionID}, nil
+		case "session.resume":
+			var req resumeSessionRequest
+			json.Unmarshal(params, &req)
+			return resumeSessionResponse{SessionID: req.SessionID}, nil
+		}
+		return nil, nil
+	})
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	session, err := client.CreateSession(t.Context(), &SessionConfig{SessionID: "s1", OnPermissionRequest: PermissionHandler.ApproveAll})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	cli.dropConnections()
+	deadline := time.Now().Add(2 * time.Second)
+	for client.State() != StateError && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if client.State() != StateError {
+		t.Fatalf("Expected client to move to error state, got %s", client.State())
+	}
+
+	t.Run("writes fail with an error instead of panicking", func(t *testing.T) {
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "hi"}); err == nil {
+			t.Error("Expected send to fail after the connection was lost")
+		}
+		if _, err := client.Ping(t.Context(), ""); err == nil {
+			t.Error("Expected ping to fail after the connection was lost")
+		}
+	})
+
+	t.Run("restart reconnects", func(t *testing.T) {
+		if err := client.Restart(t.Context()); err != nil {
+			t.Fatalf("Failed to restart: %v", err)
+		}
+		if client.State() != StateConnected {
+			t.Errorf("Expected connected state, got %s", client.State())
+		}
+	})
+}
+
+func TestClient_StartErrors(t *testing.T) {
+	t.Run("reports ErrCLINotFound with the searched paths", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		client := NewClient(&ClientOptions{CLIPath: "copilot-missing"})
+		t.Cleanup(func() { client.ForceStop() })
+
+		err := client.Start(t.Context())
+		var notFound *CLINotFoundError
+		if !errors.Is(err, ErrCLINotFound) || !errors.As(err, &notFound) {
+			t.Fatalf("Expected ErrCLINotFound, got %v", err)
+		}
+		want := []string{filepath.Join(os.Getenv("PATH"), "copilot-missing")}
+		if !reflect.DeepEqual(notFound.Searched, want) {
+			t.Errorf("Expected searched paths %v, got %v", want, notFound.Searched)
+		}
+	})
+
+	for _, useStdio := range []bool{true, false} {
+		t.Run(fmt.Sprintf("reports ErrCLIStartFailed with exit code and stderr (stdio %v)", useStdio), func(t *testing.T) {
+			if runtime.GOOS == "windows" {
+				t.Skip("requires a shell script")
+			}
+			script := filepath.Join(t.TempDir(), "copilot")
+			body := "#!/bin/sh\necho 'starting' >&2\necho 'error: bad config' >&2\nexit 3\n"
+			if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			client := NewClient(&ClientOptions{CLIPath: script, UseStdio: Bool(useStdio)})
+			t.Cleanup(func() { client.ForceStop() })
+
+			err := client.Start(t.Context())
+			var startErr *CLIStartError
+			if !errors.Is(err, ErrCLIStartFailed) || !errors.As(err, &startErr) {
+				t.Fatalf("Expected ErrCLIStartFailed, got %v", err)
+			}
+			if startErr.ExitCode != 3 {
+				t.Errorf("Expected exit code 3, got %d", startErr.ExitCode)
+			}
+			if want := []string{"starting", "error: bad config"}; !reflect.DeepEqual(startErr.Stderr, want) {
+				t.Errorf("Expected stderr %v, got %v", want, startErr.Stderr)
+			}
+		})
+	}
+
+	t.Run("reports ErrHandshakeFailed on a protocol version mismatch", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		t.Cleanup(func() { listener.Close() })
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+			server := &fakeServer{t: t, conn: conn, handler: func(method string, params json.RawMessage) (any, error) {
+				version := GetSdkProtocolVersion() + 1
+				return PingResponse{ProtocolVersion: &version}, nil
+			}}
+			server.serve()
+		}()
+
+		client := NewClient(&ClientOptions{CLIUrl: listener.Addr().String()})
+		t.Cleanup(func() { client.ForceStop() })
+
+		err = client.Start(t.Context())
+		var handshakeErr *HandshakeError
+		if !errors.Is(err, ErrHandshakeFailed) || !errors.As(err, &handshakeErr) {
+			t.Fatalf("Expected ErrHandshakeFailed, got %v", err)
+		}
+		if want := fmt.Sprintf("protocol version %d", GetSdkProtocolVersion()+1); handshakeErr.Received != want {
+			t.Errorf("Expected received %q, got %q", want, handshakeErr.Received)
+		}
+	})
+}
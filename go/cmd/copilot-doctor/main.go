@@ -0,0 +1,68 @@
+// Copilot-doctor checks that the Copilot CLI works with the SDK in the
+// current environment: it starts the CLI, checks the connection,
+// authentication and models, and creates a throwaway session. It prints a
+// pass/fail report with hints and exits with status 1 if a check fails, so
+// it can run as a CI preflight step.
+//
+// Usage:
+//
+//	go run github.com/github/copilot-sdk/go/cmd/copilot-doctor [--cli-path PATH] [--cli-url URL] [--model MODEL] [--skip-session] [--timeout DURATION] [--json]
+//
+//	--cli-path: Path to the CLI executable. Defaults to COPILOT_CLI_PATH, the embedded CLI, or copilot on PATH.
+//	--cli-url: Check a running CLI server instead of starting one.
+//	--model: Model to check availability of and to create the session with.
+//	--skip-session: Do not create a throwaway session.
+//	--timeout: Time limit for each check. Defaults to 30s.
+//	--json: Print the report as JSON.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	copilot "github.com/github/copilot-sdk/go"
+	"github.com/github/copilot-sdk/go/doctor"
+)
+
+func main() {
+	cliPath := flag.String("cli-path", "", "path to the CLI executable")
+	cliURL := flag.String("cli-url", "", "URL of a running CLI server to check")
+	model := flag.String("model", "", "model to check and create the session with")
+	skipSession := flag.Bool("skip-session", false, "do not create a throwaway session")
+	timeout := flag.Duration("timeout", 0, "time limit for each check (default 30s)")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+	if *cliPath != "" && *cliURL != "" {
+		fmt.Fprintln(os.Stderr, "Error: --cli-path and --cli-url are mutually exclusive")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report := doctor.Run(ctx, &doctor.Options{
+		ClientOptions: &copilot.ClientOptions{CLIPath: *cliPath, CLIUrl: *cliURL},
+		Model:         *model,
+		SkipSession:   *skipSession,
+		CheckTimeout:  *timeout,
+	})
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+	} else if err := report.WriteText(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
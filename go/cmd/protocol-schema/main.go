@@ -0,0 +1,40 @@
+// Protocol-schema writes the JSON Schema of each wire type in the protocol
+// package to DIR/<Type>.json, for generating clients in other languages.
+// The output matches the golden schemas in protocol/testdata/schema.
+//
+// Usage:
+//
+//	go run github.com/github/copilot-sdk/go/cmd/protocol-schema [--out DIR]
+//
+//	--out: Directory to write the schemas to. Defaults to the current directory.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/github/copilot-sdk/go/protocol"
+)
+
+func main() {
+	out := flag.String("out", ".", "directory to write the schemas to")
+	flag.Parse()
+
+	schemas, err := protocol.Schemas()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for name, schema := range schemas {
+		if err := os.WriteFile(filepath.Join(*out, name+".json"), schema, 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
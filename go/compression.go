@@ -0,0 +1,36 @@
+package copilot
+
+import (
+	"slices"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+// defaultCompressionThreshold is the default of
+// [ClientOptions.CompressionThreshold].
+const defaultCompressionThreshold = 16 << 10
+
+// acceptedEncodings returns the message encodings the client offers the CLI
+// in its ping request: none over stdio or with compression disabled.
+func (c *Client) acceptedEncodings() []string {
+	if c.useStdio || c.options.CompressionThreshold < 0 {
+		return nil
+	}
+	return jsonrpc2.Encodings
+}
+
+// negotiateCompression compresses large messages to the CLI with the first
+// encoding the client prefers that the CLI reports it accepts. CLIs that
+// report none get uncompressed messages.
+func (c *Client) negotiateCompression(capabilities *ServerCapabilities) {
+	offered := c.acceptedEncodings()
+	if len(offered) == 0 || capabilities == nil {
+		return
+	}
+	for _, encoding := range offered {
+		if slices.Contains(capabilities.Compression, encoding) {
+			c.client.SetCompression(encoding, c.options.CompressionThreshold)
+			return
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package copilot
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+// countingListener counts the bytes read from accepted connections.
+type countingListener struct {
+	net.Listener
+	read *atomic.Int64
+}
+
+func (l countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	return countingConn{Conn: conn, read: l.read}, err
+}
+
+type countingConn struct {
+	net.Conn
+	read *atomic.Int64
+}
+
+func (c countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+// newCompressingCLI starts a fake CLI on a jsonrpc2.Client, which decodes
+// compressed messages, reporting encodings in its ping response. It returns
+// the address, the encodings offered in the last ping and the bytes received.
+func newCompressingCLI(t *testing.T, encodings []string) (string, *atomic.Pointer[[]string], *atomic.Int64) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	var offered atomic.Pointer[[]string]
+	var received atomic.Int64
+	counting := countingListener{Listener: listener, read: &received}
+	go func() {
+		for {
+			conn, err := counting.Accept()
+			if err != nil {
+				return
+			}
+			server := jsonrpc2.NewClient(conn, conn)
+			server.SetRequestHandler("ping", jsonrpc2.RequestHandlerFor(func(req pingRequest) (PingResponse, *jsonrpc2.Error) {
+				offered.Store(&req.Compression)
+				if len(encodings) > 0 {
+					server.SetCompression(req.Compression[0], 1024)
+				}
+				version := GetSdkProtocolVersion()
+				return PingResponse{Message: req.Message, ProtocolVersion: &version, Capabilities: &ServerCapabilities{Compression: encodings}}, nil
+			}))
+			server.Start()
+			t.Cleanup(func() {
+				conn.Close()
+				server.Stop()
+			})
+		}
+	}()
+	return listener.Addr().String(), &offered, &received
+}
+
+func TestClient_Compression(t *testing.T) {
+	message := strings.Repeat("the assistant read src/handler.go and proposed a change. ", 40000)
+
+	t.Run("compresses large messages when the CLI accepts it", func(t *testing.T) {
+		addr, offered, received := newCompressingCLI(t, []string{"deflate"})
+		client := NewClient(&ClientOptions{CLIUrl: addr})
+		t.Cleanup(func() { client.ForceStop() })
+		if err := client.Start(t.Context()); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		if got := *offered.Load(); !slices.Equal(got, []string{"gzip", "deflate"}) {
+			t.Errorf("Expected gzip and deflate to be offered, got %v", got)
+		}
+		before := received.Load()
+		resp, err := client.Ping(t.Context(), message)
+		if err != nil || resp.Message != message {
+			t.Fatalf("Expected the message echoed, got %v", err)
+		}
+		if sent := received.Load() - before; sent > int64(len(message)/10) {
+			t.Errorf("Expected a compressed request, sent %d bytes for %d", sent, len(message))
+		}
+	})
+
+	t.Run("falls back to plain messages", func(t *testing.T) {
+		addr, _, received := newCompressingCLI(t, nil)
+		client := NewClient(&ClientOptions{CLIUrl: addr})
+		t.Cleanup(func() { client.ForceStop() })
+		if err := client.Start(t.Context()); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		before := received.Load()
+		resp, err := client.Ping(t.Context(), message)
+		if err != nil || resp.Message != message {
+			t.Fatalf("Expected the message echoed, got %v", err)
+		}
+		if sent := received.Load() - before; sent < int64(len(message)) {
+			t.Errorf("Expected an uncompressed request, sent %d bytes for %d", sent, len(message))
+		}
+	})
+
+	t.Run("offers nothing when disabled", func(t *testing.T) {
+		addr, offered, _ := newCompressingCLI(t, nil)
+		client := NewClient(&ClientOptions{CLIUrl: addr, CompressionThreshold: -1})
+		t.Cleanup(func() { client.ForceStop() })
+		if err := client.Start(t.Context()); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		if got := *offered.Load(); len(got) != 0 {
+			t.Errorf("Expected no encodings offered, got %v", got)
+		}
+	})
+}
@@ -0,0 +1,387 @@
+package copilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// clientOptionsFile is the serializable form of [ClientOptions].
+type clientOptionsFile struct {
+	CLIPath                  string              `json:"cliPath,omitempty"`
+	CLIArgs                  []string            `json:"cliArgs,omitempty"`
+	Cwd                      string              `json:"cwd,omitempty"`
+	Port                     int                 `json:"port,omitempty"`
+	UseStdio                 *bool               `json:"useStdio,omitempty"`
+	CLIUrl                   string              `json:"cliUrl,omitempty"`
+	LogLevel                 string              `json:"logLevel,omitempty"`
+	AutoStart                *bool               `json:"autoStart,omitempty"`
+	AutoRestart              *bool               `json:"autoRestart,omitempty"`
+	Env                      []string            `json:"env,omitempty"`
+	GitHubToken              string              `json:"githubToken,omitempty"`
+	UseLoggedInUser          *bool               `json:"useLoggedInUser,omitempty"`
+	Pacing                   *pacingFile         `json:"pacing,omitempty"`
+	IntegrationID            string              `json:"integrationId,omitempty"`
+	CleanupTimeout           string              `json:"cleanupTimeout,omitempty"`
+	MaxMessageBytes          int                 `json:"maxMessageBytes,omitempty"`
+	MaxInlineAttachmentBytes int                 `json:"maxInlineAttachmentBytes,omitempty"`
+	MaxPendingRequests       int                 `json:"maxPendingRequests,omitempty"`
+	PendingRequestsWarning   int                 `json:"pendingRequestsWarning,omitempty"`
+	CompressionThreshold     int                 `json:"compressionThreshold,omitempty"`
+	RestartBackoff           *restartBackoffFile `json:"restartBackoff,omitempty"`
+	ServerRequestOrder       ServerRequestOrder  `json:"serverRequestOrder,omitempty"`
+	RequestTimeout           string              `json:"requestTimeout,omitempty"`
+	RetryPolicy              *retryPolicyFile    `json:"retryPolicy,omitempty"`
+}
+
+type restartBackoffFile struct {
+	InitialDelay string `json:"initialDelay,omitempty"`
+	MaxDelay     string `json:"maxDelay,omitempty"`
+	MaxAttempts  int    `json:"maxAttempts,omitempty"`
+}
+
+type retryPolicyFile struct {
+	MaxAttempts int      `json:"maxAttempts,omitempty"`
+	Backoff     string   `json:"backoff,omitempty"`
+	Methods     []string `json:"methods,omitempty"`
+}
+
+type pacingFile struct {
+	MaxSendsPerMinute         int  `json:"maxSendsPerMinute,omitempty"`
+	MaxConcurrentBusySessions int  `json:"maxConcurrentBusySessions,omitempty"`
+	PauseWhenOverloaded       bool `json:"pauseWhenOverloaded,omitempty"`
+}
+
+// sessionConfigFile is the serializable form of [SessionConfig].
+type sessionConfigFile struct {
+	SessionID         string                     `json:"sessionId,omitempty"`
+	ClientName        string                     `json:"clientName,omitempty"`
+	Model             string                     `json:"model,omitempty"`
+	ReasoningEffort   string                     `json:"reasoningEffort,omitempty"`
+	ResponseLanguage  string                     `json:"responseLanguage,omitempty"`
+	ConfigDir         string                     `json:"configDir,omitempty"`
+	SystemMessage     *SystemMessageConfig       `json:"systemMessage,omitempty"`
+	AvailableTools    []string                   `json:"availableTools,omitempty"`
+	ExcludedTools     []string                   `json:"excludedTools,omitempty"`
+	WorkingDirectory  string                     `json:"workingDirectory,omitempty"`
+	Streaming         bool                       `json:"streaming,omitempty"`
+	Provider          *ProviderConfig            `json:"provider,omitempty"`
+	MCPServers        map[string]MCPServerConfig `json:"mcpServers,omitempty"`
+	CustomAgents      []CustomAgentConfig        `json:"customAgents,omitempty"`
+	SkillDirectories  []string                   `json:"skillDirectories,omitempty"`
+	DisabledSkills    []string                   `json:"disabledSkills,omitempty"`
+	InfiniteSessions  *InfiniteSessionConfig     `json:"infiniteSessions,omitempty"`
+	PermissionPolicy  *permissionPolicyFile      `json:"permissionPolicy,omitempty"`
+	AutoApprove       *autoApproveFile           `json:"autoApprove,omitempty"`
+	ToolTimeout       string                     `json:"toolTimeout,omitempty"`
+	UserInputFallback *UserInputFallback         `json:"userInputFallback,omitempty"`
+}
+
+type permissionPolicyFile struct {
+	Rules []PermissionRule `json:"rules,omitempty"`
+}
+
+type autoApproveFile struct {
+	ApproveKinds []string `json:"approveKinds,omitempty"`
+	DenyKinds    []string `json:"denyKinds,omitempty"`
+	WritePaths   []string `json:"writePaths,omitempty"`
+}
+
+// LoadClientOptions reads [ClientOptions] from a JSON (.json) or YAML (.yaml,
+// .yml) file.
+//
+// Keys use the camelCase JSON names of the options, e.g. cliPath, cliArgs,
+// useStdio, autoRestart, env, githubToken and pacing. cleanupTimeout,
+// requestTimeout, the backoff of retryPolicy and the initialDelay and
+// maxDelay of restartBackoff take a Go duration string such as "5s". String values may reference environment variables as ${VAR};
+// referencing an unset variable is an error. Unknown keys are rejected so
+// that typos don't go unnoticed.
+//
+// Example config.yaml:
+//
+//	cliPath: /usr/local/bin/copilot
+//	logLevel: debug
+//	githubToken: ${GITHUB_TOKEN}
+//	env:
+//	  - HTTPS_PROXY=${HTTPS_PROXY}
+//	pacing:
+//	  maxSendsPerMinute: 30
+//
+// Example:
+//
+//	opts, err := copilot.LoadClientOptions("config.yaml")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	client := copilot.NewClient(opts)
+func LoadClientOptions(path string) (*ClientOptions, error) {
+	var file clientOptionsFile
+	if err := loadConfigFile(path, &file); err != nil {
+		return nil, err
+	}
+
+	opts := &ClientOptions{
+		CLIPath:                  file.CLIPath,
+		CLIArgs:                  file.CLIArgs,
+		Cwd:                      file.Cwd,
+		Port:                     file.Port,
+		UseStdio:                 file.UseStdio,
+		CLIUrl:                   file.CLIUrl,
+		LogLevel:                 file.LogLevel,
+		AutoStart:                file.AutoStart,
+		AutoRestart:              file.AutoRestart,
+		Env:                      file.Env,
+		GitHubToken:              file.GitHubToken,
+		UseLoggedInUser:          file.UseLoggedInUser,
+		IntegrationID:            file.IntegrationID,
+		MaxMessageBytes:          file.MaxMessageBytes,
+		MaxInlineAttachmentBytes: file.MaxInlineAttachmentBytes,
+		MaxPendingRequests:       file.MaxPendingRequests,
+		PendingRequestsWarning:   file.PendingRequestsWarning,
+		CompressionThreshold:     file.CompressionThreshold,
+		ServerRequestOrder:       file.ServerRequestOrder,
+	}
+	if file.Pacing != nil {
+		opts.Pacing = &PacingOptions{
+			MaxSendsPerMinute:         file.Pacing.MaxSendsPerMinute,
+			MaxConcurrentBusySessions: file.Pacing.MaxConcurrentBusySessions,
+			PauseWhenOverloaded:       file.Pacing.PauseWhenOverloaded,
+		}
+	}
+	switch opts.ServerRequestOrder {
+	case "", ServerRequestsOrdered, ServerRequestsConcurrent:
+	default:
+		return nil, fmt.Errorf("failed to load %s: serverRequestOrder: unknown order %q (expected %q or %q)", path, opts.ServerRequestOrder, ServerRequestsOrdered, ServerRequestsConcurrent)
+	}
+	var err error
+	if opts.CleanupTimeout, err = configDuration(path, "cleanupTimeout", file.CleanupTimeout); err != nil {
+		return nil, err
+	}
+	if opts.RequestTimeout, err = configDuration(path, "requestTimeout", file.RequestTimeout); err != nil {
+		return nil, err
+	}
+	if file.RetryPolicy != nil {
+		opts.RetryPolicy = &RetryPolicy{MaxAttempts: file.RetryPolicy.MaxAttempts, Methods: file.RetryPolicy.Methods}
+		if opts.RetryPolicy.Backoff, err = configDuration(path, "retryPolicy.backoff", file.RetryPolicy.Backoff); err != nil {
+			return nil, err
+		}
+	}
+	if file.RestartBackoff != nil {
+		opts.RestartBackoff = &RestartBackoff{MaxAttempts: file.RestartBackoff.MaxAttempts}
+		if opts.RestartBackoff.InitialDelay, err = configDuration(path, "restartBackoff.initialDelay", file.RestartBackoff.InitialDelay); err != nil {
+			return nil, err
+		}
+		if opts.RestartBackoff.MaxDelay, err = configDuration(path, "restartBackoff.maxDelay", file.RestartBackoff.MaxDelay); err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
+// LoadSessionConfig reads a [SessionConfig] from a JSON (.json) or YAML
+// (.yaml, .yml) file.
+//
+// Keys use the camelCase JSON names of the options, e.g. model, systemMessage,
+// mcpServers, customAgents, skillDirectories, infiniteSessions,
+// permissionPolicy (rules, each with name, kind, tool, command, pathGlob,
+// action and reason), autoApprove (approveKinds, denyKinds and writePaths)
+// and userInputFallback (mode and text). toolTimeout takes a Go duration
+// string such as "30s". Environment variable interpolation and unknown-key
+// handling work as in [LoadClientOptions].
+//
+// Tools, OnPermissionRequest, OnUserInputRequest, Hooks,
+// PermissionPolicy.OnDecision and AutoApprove.OnDecision are code-only: they hold Go functions and can't be
+// expressed in a file. Set them on the returned config before creating the
+// session.
+//
+// Example:
+//
+//	config, err := copilot.LoadSessionConfig("session.yaml")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	config.OnPermissionRequest = myPermissionHandler
+//	session, err := client.CreateSession(ctx, config)
+func LoadSessionConfig(path string) (*SessionConfig, error) {
+	var file sessionConfigFile
+	if err := loadConfigFile(path, &file); err != nil {
+		return nil, err
+	}
+
+	config := &SessionConfig{
+		SessionID:         file.SessionID,
+		ClientName:        file.ClientName,
+		Model:             file.Model,
+		ReasoningEffort:   file.ReasoningEffort,
+		ResponseLanguage:  file.ResponseLanguage,
+		ConfigDir:         file.ConfigDir,
+		SystemMessage:     file.SystemMessage,
+		AvailableTools:    file.AvailableTools,
+		ExcludedTools:     file.ExcludedTools,
+		WorkingDirectory:  file.WorkingDirectory,
+		Streaming:         file.Streaming,
+		Provider:          file.Provider,
+		CustomAgents:      file.CustomAgents,
+		SkillDirectories:  file.SkillDirectories,
+		DisabledSkills:    file.DisabledSkills,
+		InfiniteSessions:  file.InfiniteSessions,
+		UserInputFallback: file.UserInputFallback,
+	}
+	for name, server := range file.MCPServers {
+		typed, err := toTypedMCPServerConfig(server)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: mcpServers.%s: %w", path, name, err)
+		}
+		if config.TypedMCPServers == nil {
+			config.TypedMCPServers = make(map[string]TypedMCPServerConfig, len(file.MCPServers))
+		}
+		config.TypedMCPServers[name] = typed
+	}
+	if file.PermissionPolicy != nil {
+		config.PermissionPolicy = &PermissionPolicy{Rules: file.PermissionPolicy.Rules}
+		if err := config.PermissionPolicy.validate(); err != nil {
+			return nil, fmt.Errorf("failed to load %s: permissionPolicy: %w", path, err)
+		}
+	}
+	if file.AutoApprove != nil {
+		config.AutoApprove = &AutoApprovePolicy{
+			ApproveKinds: file.AutoApprove.ApproveKinds,
+			DenyKinds:    file.AutoApprove.DenyKinds,
+			WritePaths:   file.AutoApprove.WritePaths,
+		}
+	}
+	var err error
+	if config.ToolTimeout, err = configDuration(path, "toolTimeout", file.ToolTimeout); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// configDuration parses the Go duration string at key, or returns zero if it
+// is empty.
+func configDuration(path, key, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load %s: %s: %w", path, key, err)
+	}
+	return d, nil
+}
+
+// loadConfigFile parses a JSON or YAML file, expands environment variables in
+// string values, and strictly decodes the result into out.
+func loadConfigFile(path string, out any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		return fmt.Errorf("unsupported config file extension %q in %s (expected .json, .yaml or .yml)", ext, path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	raw, err = expandConfigValue(raw, "")
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", path, err)
+	}
+
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(normalized))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(out); err != nil {
+		return fmt.Errorf("failed to load %s: %s", path, strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
+
+var configVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandConfigValue replaces ${VAR} references in all string values. It also
+// rejects non-string map keys, which YAML allows but the options don't use.
+func expandConfigValue(value any, keyPath string) (any, error) {
+	switch v := value.(type) {
+	case string:
+		var missing string
+		expanded := configVarPattern.ReplaceAllStringFunc(v, func(ref string) string {
+			name := ref[2 : len(ref)-1]
+			val, ok := os.LookupEnv(name)
+			if !ok && missing == "" {
+				missing = name
+			}
+			return val
+		})
+		if missing != "" {
+			return nil, fmt.Errorf("%s: environment variable %s is not set", configKeyPath(keyPath), missing)
+		}
+		return expanded, nil
+	case map[string]any:
+		for key, item := range v {
+			expanded, err := expandConfigValue(item, joinConfigKey(keyPath, key))
+			if err != nil {
+				return nil, err
+			}
+			v[key] = expanded
+		}
+		return v, nil
+	case map[any]any:
+		converted := make(map[string]any, len(v))
+		for key, item := range v {
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: keys must be strings, got %v", configKeyPath(keyPath), key)
+			}
+			expanded, err := expandConfigValue(item, joinConfigKey(keyPath, name))
+			if err != nil {
+				return nil, err
+			}
+			converted[name] = expanded
+		}
+		return converted, nil
+	case []any:
+		for i, item := range v {
+			expanded, err := expandConfigValue(item, fmt.Sprintf("%s[%d]", keyPath, i))
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+func joinConfigKey(keyPath, key string) string {
+	if keyPath == "" {
+		return key
+	}
+	return keyPath + "." + key
+}
+
+func configKeyPath(keyPath string) string {
+	if keyPath == "" {
+		return "<root>"
+	}
+	return keyPath
+}
@@ -0,0 +1,219 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLoadClientOptions(t *testing.T) {
+	t.Setenv("COPILOT_TEST_TOKEN", "ghp_test")
+	t.Setenv("COPILOT_TEST_PROXY", "http://proxy:3128")
+
+	expected := &ClientOptions{
+		CLIPath:                  "/opt/copilot/bin/copilot",
+		CLIArgs:                  []string{"--verbose"},
+		Cwd:                      "/srv/app",
+		Port:                     8123,
+		UseStdio:                 Bool(false),
+		LogLevel:                 "debug",
+		AutoStart:                Bool(true),
+		AutoRestart:              Bool(false),
+		Env:                      []string{"HOME=/home/copilot", "HTTPS_PROXY=http://proxy:3128"},
+		GitHubToken:              "ghp_test",
+		UseLoggedInUser:          Bool(false),
+		Pacing:                   &PacingOptions{MaxSendsPerMinute: 30, MaxConcurrentBusySessions: 2},
+		CleanupTimeout:           5 * time.Second,
+		MaxMessageBytes:          32 << 20,
+		MaxInlineAttachmentBytes: 1 << 20,
+		MaxPendingRequests:       5000,
+		PendingRequestsWarning:   1000,
+		CompressionThreshold:     64 << 10,
+		RestartBackoff:           &RestartBackoff{InitialDelay: 500 * time.Millisecond, MaxDelay: 10 * time.Second, MaxAttempts: 3},
+		ServerRequestOrder:       ServerRequestsConcurrent,
+		RequestTimeout:           2 * time.Minute,
+		RetryPolicy:              &RetryPolicy{MaxAttempts: 4, Backoff: 250 * time.Millisecond, Methods: []string{"ping", "session.getMessages"}},
+	}
+
+	for _, name := range []string{"client.yaml", "client.json"} {
+		t.Run("loads "+name, func(t *testing.T) {
+			opts, err := LoadClientOptions(filepath.Join("testdata", "config", name))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(opts, expected) {
+				t.Errorf("Expected %+v, got %+v", expected, opts)
+			}
+		})
+	}
+
+	t.Run("rejects unknown keys", func(t *testing.T) {
+		_, err := LoadClientOptions(filepath.Join("testdata", "config", "unknown_key.yaml"))
+		if err == nil || !strings.Contains(err.Error(), `unknown field "logLevl"`) {
+			t.Errorf("Expected unknown field error, got %v", err)
+		}
+	})
+
+	t.Run("rejects unsupported extensions", func(t *testing.T) {
+		_, err := LoadClientOptions(filepath.Join("testdata", "config", "client.toml"))
+		if err == nil || !strings.Contains(err.Error(), `unsupported config file extension ".toml"`) {
+			t.Errorf("Expected unsupported extension error, got %v", err)
+		}
+	})
+
+	t.Run("reports invalid durations with their key path", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "client.yaml")
+		if err := os.WriteFile(path, []byte("restartBackoff:\n  maxDelay: soon\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadClientOptions(path)
+		if err == nil || !strings.Contains(err.Error(), `restartBackoff.maxDelay: time: invalid duration "soon"`) {
+			t.Errorf("Expected invalid duration error, got %v", err)
+		}
+	})
+
+	t.Run("rejects unknown server request orders", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "client.yaml")
+		if err := os.WriteFile(path, []byte("serverRequestOrder: parallel\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadClientOptions(path)
+		if err == nil || !strings.Contains(err.Error(), `serverRequestOrder: unknown order "parallel"`) {
+			t.Errorf("Expected unknown order error, got %v", err)
+		}
+	})
+
+	t.Run("reports missing files", func(t *testing.T) {
+		_, err := LoadClientOptions(filepath.Join("testdata", "config", "missing.yaml"))
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected not-exist error, got %v", err)
+		}
+	})
+}
+
+func TestLoadSessionConfig(t *testing.T) {
+	t.Setenv("COPILOT_TEST_TOKEN", "sk-test")
+	t.Setenv("COPILOT_TEST_LANGUAGE", "French")
+
+	t.Run("loads every serializable option", func(t *testing.T) {
+		config, err := LoadSessionConfig(filepath.Join("testdata", "config", "session.yaml"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := &SessionConfig{
+			SessionID:        "nightly-triage",
+			ClientName:       "triage-bot",
+			Model:            "gpt-5",
+			ReasoningEffort:  "high",
+			ConfigDir:        "/var/lib/copilot",
+			SystemMessage:    &SystemMessageConfig{Mode: "append", Content: "Always answer in French."},
+			AvailableTools:   []string{"view", "grep"},
+			WorkingDirectory: "/srv/app",
+			Streaming:        true,
+			Provider:         &ProviderConfig{Type: "openai", BaseURL: "https://llm.internal/v1", APIKey: "sk-test"},
+			TypedMCPServers: map[string]TypedMCPServerConfig{
+				"github": MCPRemoteServerConfig{
+					Type:    "http",
+					URL:     "https://api.githubcopilot.com/mcp/",
+					Tools:   []string{"*"},
+					Timeout: 30000,
+				},
+			},
+			CustomAgents: []CustomAgentConfig{{
+				Name:        "reviewer",
+				DisplayName: "Code Reviewer",
+				Description: "Reviews pull requests",
+				Tools:       []string{"view", "grep"},
+				Prompt:      "You review code.",
+				Infer:       Bool(true),
+			}},
+			SkillDirectories: []string{"/srv/skills"},
+			DisabledSkills:   []string{"deploy"},
+			InfiniteSessions: &InfiniteSessionConfig{
+				Enabled:                       Bool(true),
+				BackgroundCompactionThreshold: Float64(0.75),
+				BufferExhaustionThreshold:     Float64(0.9),
+			},
+			PermissionPolicy: &PermissionPolicy{Rules: []PermissionRule{
+				{Kind: "write", PathGlob: "/etc/**", Action: PermissionDeny, Reason: "system files are off limits"},
+				{Name: "git", Kind: "shell", Command: "git *", Action: PermissionAllow},
+			}},
+			AutoApprove: &AutoApprovePolicy{
+				ApproveKinds: []string{"*"},
+				DenyKinds:    []string{"url"},
+				WritePaths:   []string{"/srv/app/out"},
+			},
+			ToolTimeout:       30 * time.Second,
+			UserInputFallback: &UserInputFallback{Mode: UserInputFallbackDefaultChoice},
+		}
+		if !reflect.DeepEqual(config, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, config)
+		}
+	})
+
+	t.Run("yaml and json forms load identically", func(t *testing.T) {
+		fromYAML, err := LoadSessionConfig(filepath.Join("testdata", "config", "session.yaml"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// Transcode the YAML fixture to JSON and load it back
+		data, err := os.ReadFile(filepath.Join("testdata", "config", "session.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var raw any
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			t.Fatal(err)
+		}
+		jsonData, err := json.Marshal(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), "session.json")
+		if err := os.WriteFile(path, jsonData, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		fromJSON, err := LoadSessionConfig(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(fromJSON, fromYAML) {
+			t.Errorf("Expected %+v, got %+v", fromYAML, fromJSON)
+		}
+	})
+
+	t.Run("rejects unknown nested keys", func(t *testing.T) {
+		_, err := LoadSessionConfig(filepath.Join("testdata", "config", "unknown_nested_key.json"))
+		if err == nil || !strings.Contains(err.Error(), `unknown field "threshold"`) {
+			t.Errorf("Expected unknown field error, got %v", err)
+		}
+	})
+
+	t.Run("rejects invalid permission rules", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "session.yaml")
+		if err := os.WriteFile(path, []byte("permissionPolicy:\n  rules:\n    - kind: shell\n      action: approve\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadSessionConfig(path)
+		if !errors.Is(err, ErrInvalidPermissionPolicy) || !strings.Contains(err.Error(), `rule 0: unknown action "approve"`) {
+			t.Errorf("Expected an invalid policy error, got %v", err)
+		}
+	})
+
+	t.Run("reports unset environment variables with their key path", func(t *testing.T) {
+		_, err := LoadSessionConfig(filepath.Join("testdata", "config", "missing_var.yaml"))
+		if err == nil || !strings.Contains(err.Error(), "customAgents[0].prompt: environment variable COPILOT_TEST_UNSET_VARIABLE is not set") {
+			t.Errorf("Expected unset variable error, got %v", err)
+		}
+	})
+}
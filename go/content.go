@@ -0,0 +1,491 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrNoJSON is returned by [AssistantMessageData.FirstJSON] when the message
+// contains no JSON object.
+var ErrNoJSON = errors.New("no JSON object found in message")
+
+// AssistantMessageData is the payload of an assistant.message event.
+type AssistantMessageData struct {
+	// MessageID identifies the message
+	MessageID string
+	// Content is the Markdown text of the message
+	Content string
+	// ToolRequests lists the tool calls the assistant made with the message
+	ToolRequests []ToolRequest
+	// ReasoningText, ReasoningOpaque and EncryptedContent carry the model's
+	// reasoning, when reported
+	ReasoningText    string
+	ReasoningOpaque  string
+	EncryptedContent string
+	// Phase, InteractionID and ParentToolCallID are empty if not reported;
+	// ParentToolCallID is set for messages of a sub-agent
+	Phase            string
+	InteractionID    string
+	ParentToolCallID string
+}
+
+// CodeBlock is a fenced code block in Markdown content.
+type CodeBlock struct {
+	// Language is the first word of the info string, e.g. "go", or empty
+	Language string
+	// Info is the full info string following the opening fence
+	Info string
+	// Body is the content between the fences, without the trailing newline
+	Body string
+}
+
+// AsAssistantMessage returns the typed payload of an assistant.message event.
+// The second return value is false for any other event type.
+//
+// Example:
+//
+//	response, err := session.SendAndWait(ctx, copilot.MessageOptions{Prompt: "Write a Go hello world"})
+//	if message, ok := response.AsAssistantMessage(); ok {
+//	    for _, block := range message.CodeBlocks() {
+//	        fmt.Printf("%s:\n%s\n", block.Language, block.Body)
+//	    }
+//	}
+func (e SessionEvent) AsAssistantMessage() (*AssistantMessageData, bool) {
+	if e.Type != AssistantMessage {
+		return nil, false
+	}
+	return &AssistantMessageData{
+		MessageID:        derefString(e.Data.MessageID),
+		Content:          derefString(e.Data.Content),
+		ToolRequests:     e.Data.ToolRequests,
+		ReasoningText:    derefString(e.Data.ReasoningText),
+		ReasoningOpaque:  derefString(e.Data.ReasoningOpaque),
+		EncryptedContent: derefString(e.Data.EncryptedContent),
+		Phase:            derefString(e.Data.Phase),
+		InteractionID:    derefString(e.Data.InteractionID),
+		ParentToolCallID: derefString(e.Data.ParentToolCallID),
+	}, true
+}
+
+// CodeBlocks returns the fenced code blocks in the message, in order.
+//
+// Fences are runs of at least three backticks or tildes, and a block is
+// closed by a fence of the same character that is at least as long, so
+// longer fences can contain shorter ones. Unlike CommonMark, fences indented
+// by four or more spaces are recognized, since models often indent code
+// blocks inside list items; the fence's indentation is removed from each
+// line of the body. A block that is never closed runs to the end of the
+// message.
+func (d *AssistantMessageData) CodeBlocks() []CodeBlock {
+	var blocks []CodeBlock
+	for _, block := range parseMarkdownBlocks(d.Content) {
+		if block.code != nil {
+			blocks = append(blocks, *block.code)
+		}
+	}
+	return blocks
+}
+
+// PlainText returns the message with Markdown formatting removed: code
+// fences, heading and blockquote markers, bullet markers, horizontal rules,
+// emphasis, inline code markers, and link and image syntax (keeping the
+// link text or image alt text). Code block bodies and ordered list numbers
+// are kept.
+func (d *AssistantMessageData) PlainText() string {
+	var lines []string
+	blank := func() bool { return len(lines) == 0 || lines[len(lines)-1] == "" }
+	for _, block := range parseMarkdownBlocks(d.Content) {
+		if block.code != nil {
+			if block.code.Body != "" {
+				lines = append(lines, strings.Split(block.code.Body, "\n")...)
+			}
+			continue
+		}
+		line, keep := plainTextLine(block.line)
+		if !keep || (line == "" && blank()) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// FirstJSON unmarshals the first JSON object in the message into v. A code
+// block whose whole body is a JSON object or array also counts, so fenced
+// arrays are found too. Text that merely starts with a brace, such as
+// "{placeholder}", is skipped. It returns [ErrNoJSON] if there is no JSON.
+//
+// Example:
+//
+//	var review struct {
+//	    Verdict string `json:"verdict"`
+//	}
+//	if err := message.FirstJSON(&review); err != nil {
+//	    log.Printf("No verdict: %v", err)
+//	}
+func (d *AssistantMessageData) FirstJSON(v any) error {
+	raw, ok := firstJSON(parseMarkdownBlocks(d.Content))
+	if !ok {
+		return ErrNoJSON
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON from message: %w", err)
+	}
+	return nil
+}
+
+// markdownBlock is either a line of text outside code blocks or a code block.
+type markdownBlock struct {
+	line string
+	code *CodeBlock
+}
+
+// codeFence is an opening code fence.
+type codeFence struct {
+	char   byte
+	length int
+	indent int
+}
+
+// parseMarkdownBlocks splits content into text lines and fenced code blocks.
+func parseMarkdownBlocks(content string) []markdownBlock {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	var blocks []markdownBlock
+	for i := 0; i < len(lines); i++ {
+		fence, info, ok := parseOpeningFence(lines[i])
+		if !ok {
+			blocks = append(blocks, markdownBlock{line: lines[i]})
+			continue
+		}
+
+		var body []string
+		for i++; i < len(lines) && !isClosingFence(lines[i], fence); i++ {
+			body = append(body, trimIndent(lines[i], fence.indent))
+		}
+		language, _, _ := strings.Cut(info, " ")
+		blocks = append(blocks, markdownBlock{code: &CodeBlock{
+			Language: language,
+			Info:     info,
+			Body:     strings.Join(body, "\n"),
+		}})
+	}
+	return blocks
+}
+
+// parseOpeningFence parses line as an opening code fence, returning the
+// fence and its info string.
+func parseOpeningFence(line string) (codeFence, string, bool) {
+	rest := strings.TrimLeft(line, " \t")
+	fence := codeFence{indent: len(line) - len(rest)}
+	if rest == "" || (rest[0] != '`' && rest[0] != '~') {
+		return fence, "", false
+	}
+	fence.char = rest[0]
+	for fence.length < len(rest) && rest[fence.length] == fence.char {
+		fence.length++
+	}
+	if fence.length < 3 {
+		return fence, "", false
+	}
+	info := strings.TrimSpace(rest[fence.length:])
+	// A backtick in the info string means this is inline code, e.g. ```x```
+	if fence.char == '`' && strings.Contains(info, "`") {
+		return fence, "", false
+	}
+	return fence, info, true
+}
+
+// isClosingFence reports whether line closes a block opened by fence.
+func isClosingFence(line string, fence codeFence) bool {
+	rest := strings.TrimLeft(line, " \t")
+	n := 0
+	for n < len(rest) && rest[n] == fence.char {
+		n++
+	}
+	return n >= fence.length && strings.TrimSpace(rest[n:]) == ""
+}
+
+// trimIndent removes up to n leading spaces or tabs from line.
+func trimIndent(line string, n int) string {
+	i := 0
+	for i < n && i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+		i++
+	}
+	return line[i:]
+}
+
+// plainTextLine strips block-level Markdown from a line of text and then its
+// inline formatting. It returns false for lines that should be dropped.
+func plainTextLine(line string) (string, bool) {
+	line = strings.TrimRight(line, " \t")
+	trimmed := strings.TrimLeft(line, " \t")
+	if isThematicBreak(trimmed) || isSetextUnderline(trimmed) {
+		return "", false
+	}
+
+	// Blockquotes, possibly nested
+	for strings.HasPrefix(trimmed, ">") {
+		trimmed = strings.TrimLeft(trimmed[1:], " \t")
+		line = trimmed
+	}
+
+	// ATX headings
+	if level := strings.IndexFunc(trimmed, func(r rune) bool { return r != '#' }); level >= 1 && level <= 6 && (trimmed[level] == ' ' || trimmed[level] == '\t') {
+		heading := strings.TrimSpace(trimmed[level:])
+		heading = strings.TrimSpace(strings.TrimRight(heading, "#"))
+		return stripInlineMarkdown(heading), true
+	} else if strings.Trim(trimmed, "#") == "" && trimmed != "" && len(trimmed) <= 6 {
+		return "", true
+	}
+
+	// Bullet markers and task list checkboxes, keeping indentation
+	indent := line[:len(line)-len(trimmed)]
+	if len(trimmed) >= 2 && strings.ContainsRune("-*+", rune(trimmed[0])) && (trimmed[1] == ' ' || trimmed[1] == '\t') {
+		trimmed = strings.TrimLeft(trimmed[2:], " \t")
+		for _, box := range []string{"[ ] ", "[x] ", "[X] "} {
+			trimmed = strings.TrimPrefix(trimmed, box)
+		}
+		line = indent + trimmed
+	}
+
+	return stripInlineMarkdown(line), true
+}
+
+// isThematicBreak reports whether line is a horizontal rule such as "---".
+func isThematicBreak(line string) bool {
+	stripped := strings.NewReplacer(" ", "", "\t", "").Replace(line)
+	if len(stripped) < 3 {
+		return false
+	}
+	return strings.Count(stripped, stripped[:1]) == len(stripped) && strings.ContainsRune("-*_", rune(stripped[0]))
+}
+
+// isSetextUnderline reports whether line underlines a setext heading with "=".
+func isSetextUnderline(line string) bool {
+	return line != "" && strings.Trim(line, "=") == ""
+}
+
+// stripInlineMarkdown removes emphasis, inline code markers, link and image
+// syntax, and backslash escapes from s.
+func stripInlineMarkdown(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			b.WriteByte(s[i+1])
+			i += 2
+			continue
+
+		case c == '`':
+			n := runLength(s, i, '`')
+			if end := findCodeSpanEnd(s, i+n, n); end >= 0 {
+				code := s[i+n : end]
+				if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+					code = code[1 : len(code)-1]
+				}
+				b.WriteString(code)
+				i = end + n
+				continue
+			}
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+
+		case c == '[' || (c == '!' && i+1 < len(s) && s[i+1] == '['):
+			start := i
+			if c == '!' {
+				start++
+			}
+			if text, end, ok := parseInlineLink(s, start); ok {
+				b.WriteString(stripInlineMarkdown(text))
+				i = end
+				continue
+			}
+
+		case c == '*' || c == '_' || c == '~':
+			n := runLength(s, i, c)
+			if c != '~' || n == 2 {
+				if end := findEmphasisEnd(s, i, n); end >= 0 {
+					b.WriteString(stripInlineMarkdown(s[i+n : end]))
+					i = end + n
+					continue
+				}
+			}
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return b.String()
+}
+
+// runLength returns the number of consecutive c bytes in s starting at i.
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// findCodeSpanEnd returns the index of the backtick run of exactly n that
+// closes a code span whose content starts at from, or -1.
+func findCodeSpanEnd(s string, from, n int) int {
+	for i := from; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		run := runLength(s, i, '`')
+		if run == n {
+			return i
+		}
+		i += run
+	}
+	return -1
+}
+
+// parseInlineLink parses "[text](destination)" at s[start], returning the
+// text and the index just past the closing parenthesis.
+func parseInlineLink(s string, start int) (string, int, bool) {
+	depth := 0
+	closeBracket := -1
+	for i := start; i < len(s) && closeBracket < 0; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closeBracket = i
+			}
+		}
+	}
+	if closeBracket < 0 || closeBracket+1 >= len(s) || s[closeBracket+1] != '(' {
+		return "", 0, false
+	}
+	depth = 0
+	for i := closeBracket + 1; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s[start+1 : closeBracket], i + 1, true
+			}
+		case ' ', '\t':
+			// Allow a title, e.g. [a](url "title"), but not prose in parentheses
+			if !strings.Contains(s[i:], "\"") && !strings.Contains(s[i:], "'") {
+				return "", 0, false
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// findEmphasisEnd returns the index of the delimiter run that closes the
+// emphasis opened by the run of n delimiters at s[start], or -1. Openers must
+// be followed, and closers preceded, by non-space; underscores must also sit
+// at word boundaries, so snake_case names are left alone.
+func findEmphasisEnd(s string, start, n int) int {
+	c := s[start]
+	after, _ := utf8.DecodeRuneInString(s[start+n:])
+	if start+n >= len(s) || unicode.IsSpace(after) {
+		return -1
+	}
+	if c == '_' && start > 0 {
+		if before, _ := utf8.DecodeLastRuneInString(s[:start]); isWordRune(before) {
+			return -1
+		}
+	}
+	for i := start + n; i < len(s); {
+		if s[i] == '`' {
+			// Delimiters inside code spans don't count
+			run := runLength(s, i, '`')
+			if end := findCodeSpanEnd(s, i+run, run); end >= 0 {
+				i = end + run
+				continue
+			}
+			i += run
+			continue
+		}
+		if s[i] != c {
+			i++
+			continue
+		}
+		run := runLength(s, i, c)
+		before, _ := utf8.DecodeLastRuneInString(s[:i])
+		if run == n && i > start+n && !unicode.IsSpace(before) {
+			if c != '_' || i+run >= len(s) {
+				return i
+			}
+			if next, _ := utf8.DecodeRuneInString(s[i+run:]); !isWordRune(next) {
+				return i
+			}
+		}
+		i += run
+	}
+	return -1
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && unicode.IsPunct(rune(c)) || strings.IndexByte("$+<=>^`|~", c) >= 0
+}
+
+// firstJSON returns the first JSON object in the blocks, or a code block
+// whose whole body is JSON.
+func firstJSON(blocks []markdownBlock) (json.RawMessage, bool) {
+	var text []string
+	flush := func() (json.RawMessage, bool) {
+		raw, ok := findJSONObject(strings.Join(text, "\n"))
+		text = nil
+		return raw, ok
+	}
+	for _, block := range blocks {
+		if block.code == nil {
+			text = append(text, block.line)
+			continue
+		}
+		if raw, ok := flush(); ok {
+			return raw, true
+		}
+		body := strings.TrimSpace(block.code.Body)
+		if (strings.HasPrefix(body, "{") || strings.HasPrefix(body, "[")) && json.Valid([]byte(body)) {
+			return json.RawMessage(body), true
+		}
+		if raw, ok := findJSONObject(block.code.Body); ok {
+			return raw, true
+		}
+	}
+	return flush()
+}
+
+// findJSONObject returns the first JSON object embedded in s.
+func findJSONObject(s string) (json.RawMessage, bool) {
+	for i := strings.IndexByte(s, '{'); i >= 0; {
+		var raw json.RawMessage
+		if err := json.NewDecoder(strings.NewReader(s[i:])).Decode(&raw); err == nil {
+			return raw, true
+		}
+		next := strings.IndexByte(s[i+1:], '{')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return nil, false
+}
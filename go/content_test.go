@@ -0,0 +1,220 @@
+package copilot
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func assistantMessage(content string) *AssistantMessageData {
+	return &AssistantMessageData{Content: content}
+}
+
+func TestSessionEvent_AsAssistantMessage(t *testing.T) {
+	event := SessionEvent{Type: AssistantMessage, Data: Data{MessageID: String("m1"), Content: String("hi")}}
+	data, ok := event.AsAssistantMessage()
+	if !ok || data.MessageID != "m1" || data.Content != "hi" {
+		t.Errorf("Unexpected payload: %+v, %v", data, ok)
+	}
+	if _, ok := (SessionEvent{Type: SessionIdle}).AsAssistantMessage(); ok {
+		t.Error("Expected other event types to be rejected")
+	}
+}
+
+func TestAssistantMessageData_CodeBlocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []CodeBlock
+	}{
+		{
+			name:    "no code blocks",
+			content: "Just prose with `inline code`.",
+		},
+		{
+			name:    "single block with language",
+			content: "Here:\n```go\nfmt.Println(\"hi\")\n```\nDone.",
+			want:    []CodeBlock{{Language: "go", Info: "go", Body: "fmt.Println(\"hi\")"}},
+		},
+		{
+			name:    "multiple blocks in order",
+			content: "```sh\nls\n```\ntext\n```\nplain\n```\n",
+			want:    []CodeBlock{{Language: "sh", Info: "sh", Body: "ls"}, {Body: "plain"}},
+		},
+		{
+			name:    "tilde fence",
+			content: "~~~python\nprint(1)\n~~~",
+			want:    []CodeBlock{{Language: "python", Info: "python", Body: "print(1)"}},
+		},
+		{
+			name:    "longer fence contains shorter fences",
+			content: "````markdown\n```go\nx := 1\n```\n````",
+			want:    []CodeBlock{{Language: "markdown", Info: "markdown", Body: "```go\nx := 1\n```"}},
+		},
+		{
+			name:    "tilde fence contains backtick fences",
+			content: "~~~\n```\ninner\n```\n~~~",
+			want:    []CodeBlock{{Body: "```\ninner\n```"}},
+		},
+		{
+			name:    "closing fence may be longer",
+			content: "```\ncode\n`````",
+			want:    []CodeBlock{{Body: "code"}},
+		},
+		{
+			name:    "closing fence must use the same character",
+			content: "```\na\n~~~\nb\n```",
+			want:    []CodeBlock{{Body: "a\n~~~\nb"}},
+		},
+		{
+			name:    "fence with an info string does not close",
+			content: "```\na\n```js\nb\n```",
+			want:    []CodeBlock{{Body: "a\n```js\nb"}},
+		},
+		{
+			name:    "indented fence in a list item",
+			content: "1. Run:\n   ```bash\n   make test\n     indented\n   ```\n2. Done",
+			want:    []CodeBlock{{Language: "bash", Info: "bash", Body: "make test\n  indented"}},
+		},
+		{
+			name:    "deeply indented fence",
+			content: "- step\n      ```\n      code\n      ```",
+			want:    []CodeBlock{{Body: "code"}},
+		},
+		{
+			name:    "info string with attributes",
+			content: "```python title=\"a.py\"\npass\n```",
+			want:    []CodeBlock{{Language: "python", Info: "python title=\"a.py\"", Body: "pass"}},
+		},
+		{
+			name:    "unclosed block runs to the end",
+			content: "```json\n{\"a\": 1}\n",
+			want:    []CodeBlock{{Language: "json", Info: "json", Body: "{\"a\": 1}\n"}},
+		},
+		{
+			name:    "empty block",
+			content: "```\n```",
+			want:    []CodeBlock{{}},
+		},
+		{
+			name:    "inline triple backticks are not a fence",
+			content: "Use ```code``` inline\nnext",
+		},
+		{
+			name:    "two backticks are not a fence",
+			content: "``\nnot code\n``",
+		},
+		{
+			name:    "CRLF line endings",
+			content: "```go\r\na\r\nb\r\n```\r\n",
+			want:    []CodeBlock{{Language: "go", Info: "go", Body: "a\nb"}},
+		},
+		{
+			name:    "blank lines in the body are kept",
+			content: "```\na\n\n\nb\n```",
+			want:    []CodeBlock{{Body: "a\n\n\nb"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := assistantMessage(tt.content).CodeBlocks()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAssistantMessageData_PlainText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"plain text is unchanged", "Hello, world.", "Hello, world."},
+		{"headings", "# Title\n\n## Section ##\nBody", "Title\n\nSection\nBody"},
+		{"hash without space is not a heading", "#hashtag", "#hashtag"},
+		{"emphasis", "**bold**, *italic*, __strong__, _em_, ~~gone~~", "bold, italic, strong, em, gone"},
+		{"nested emphasis", "*a **b** c*", "a b c"},
+		{"emphasis spanning code", "**see `a*b`**", "see a*b"},
+		{"arithmetic stars are kept", "2 * 3 * 4", "2 * 3 * 4"},
+		{"snake_case is kept", "call my_func_name and __init__ here", "call my_func_name and init here"},
+		{"unmatched delimiters are kept", "a *b c", "a *b c"},
+		{"inline code", "Run `go test` or ``a ` b``", "Run go test or a ` b"},
+		{"links and images", "See [the docs](https://x.dev/a_(b)) and ![logo](l.png \"Logo\")", "See the docs and logo"},
+		{"brackets that are not links", "Use [x] or (y) and [a] (b c)", "Use [x] or (y) and [a] (b c)"},
+		{"link text formatting", "[**bold** link](u)", "bold link"},
+		{"escapes", `\*not emphasis\* and \[x\]`, "*not emphasis* and [x]"},
+		{"bullets and task lists", "- one\n* two\n  + nested\n- [ ] todo\n- [x] done", "one\ntwo\n  nested\ntodo\ndone"},
+		{"ordered lists keep numbers", "1. first\n2. second", "1. first\n2. second"},
+		{"blockquotes", "> quoted\n> > nested", "quoted\nnested"},
+		{"horizontal rules and setext underlines", "Title\n=====\ntext\n\n---\n\n* * *\nmore", "Title\ntext\n\nmore"},
+		{"code blocks keep their body", "Run:\n```sh\necho *hi*\n```\ndone", "Run:\necho *hi*\ndone"},
+		{"blank lines are collapsed", "a\n\n\n\nb\n\n", "a\n\nb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assistantMessage(tt.content).PlainText(); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAssistantMessageData_FirstJSON(t *testing.T) {
+	type result struct {
+		A int `json:"a"`
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    result
+	}{
+		{"bare object", `{"a": 1}`, result{A: 1}},
+		{"object in prose", `The answer is {"a": 2}. Hope that helps!`, result{A: 2}},
+		{"skips non-JSON braces", "Fill in {name} and {\"a\": 3}", result{A: 3}},
+		{"fenced object", "```json\n{\"a\": 4}\n```", result{A: 4}},
+		{"first in document order", "{\"a\": 5}\n```json\n{\"a\": 6}\n```", result{A: 5}},
+		{"fence before prose", "```\n{\"a\": 7}\n```\nor {\"a\": 8}", result{A: 7}},
+		{"nested object", `{"a": 9, "b": {"c": [1, 2]}}`, result{A: 9}},
+		{"braces inside strings", `{"a": 10, "s": "}{"}`, result{A: 10}},
+		{"object inside another language's block", "```js\nconst x = {\"a\": 11};\n```", result{A: 11}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got result
+			if err := assistantMessage(tt.content).FirstJSON(&got); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("fenced arrays", func(t *testing.T) {
+		var got []int
+		if err := assistantMessage("Issues:\n```json\n[1, 2, 3]\n```").FirstJSON(&got); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+			t.Errorf("Expected [1 2 3], got %v", got)
+		}
+	})
+
+	t.Run("no JSON", func(t *testing.T) {
+		var got result
+		for _, content := range []string{"", "no json here", "{broken", "[1, 2] in prose"} {
+			if err := assistantMessage(content).FirstJSON(&got); !errors.Is(err, ErrNoJSON) {
+				t.Errorf("Expected ErrNoJSON for %q, got %v", content, err)
+			}
+		}
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		var got result
+		if err := assistantMessage(`{"a": "text"}`).FirstJSON(&got); err == nil || errors.Is(err, ErrNoJSON) {
+			t.Errorf("Expected an unmarshal error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+// Package copilottest builds session events for testing event handlers.
+//
+// Each builder returns a [copilot.SessionEvent] with the fields the CLI sets
+// for its event type, equal to what [copilot.UnmarshalSessionEvent] decodes
+// from the wire. Event, message, and tool call IDs are unique within the test
+// binary. [DispatchTo] delivers events to a session's handlers through the
+// same path as events sent by the CLI, so state the session derives from
+// events, such as [copilot.Session.MessageRef], is updated as well.
+//
+// Example:
+//
+//	var replies []string
+//	session.On(func(event copilot.SessionEvent) {
+//	    if msg, ok := event.AsAssistantMessage(); ok {
+//	        replies = append(replies, msg.Content)
+//	    }
+//	})
+//	copilottest.DispatchTo(session,
+//	    copilottest.ToolStart("read_file", map[string]any{"path": "README.md"}),
+//	    copilottest.AssistantMessage("The README describes the SDK."),
+//	    copilottest.Idle(),
+//	)
+package copilottest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+	"github.com/github/copilot-sdk/go/internal/testhook"
+)
+
+// lastID numbers the IDs the builders generate.
+var lastID atomic.Int64
+
+// nextID returns a new ID with the given prefix, such as "event-1".
+func nextID(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, lastID.Add(1))
+}
+
+// newEvent returns an event of type t with data, a new ID, and the current
+// time.
+func newEvent(t copilot.SessionEventType, data copilot.Data) copilot.SessionEvent {
+	return copilot.SessionEvent{
+		ID:   nextID("event"),
+		Type: t,
+		// UTC drops the monotonic clock reading, which the wire format cannot
+		// carry
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+// AssistantMessage returns an assistant.message event with content and a new
+// message ID.
+func AssistantMessage(content string) copilot.SessionEvent {
+	return newEvent(copilot.AssistantMessage, copilot.Data{
+		MessageID: copilot.String(nextID("message")),
+		Content:   copilot.String(content),
+	})
+}
+
+// MessageDelta returns an ephemeral assistant.message_delta event that
+// streams delta as part of the message with messageID.
+func MessageDelta(messageID, delta string) copilot.SessionEvent {
+	event := newEvent(copilot.AssistantMessageDelta, copilot.Data{
+		MessageID:    copilot.String(messageID),
+		DeltaContent: copilot.String(delta),
+	})
+	event.Ephemeral = copilot.Bool(true)
+	return event
+}
+
+// UserMessage returns a user.message event with content.
+func UserMessage(content string) copilot.SessionEvent {
+	return newEvent(copilot.UserMessage, copilot.Data{Content: copilot.String(content)})
+}
+
+// ToolStart returns a tool.execution_start event for a call of the tool name
+// with a new tool call ID. args is converted to the form it has after JSON
+// decoding, so a struct becomes a map[string]any. It panics if args cannot be
+// encoded as JSON.
+func ToolStart(name string, args any) copilot.SessionEvent {
+	return newEvent(copilot.ToolExecutionStart, copilot.Data{
+		ToolCallID: copilot.String(nextID("call")),
+		ToolName:   copilot.String(name),
+		Arguments:  decoded(args),
+	})
+}
+
+// ToolComplete returns a successful tool.execution_complete event for the
+// call with toolCallID, such as the ID of an event from [ToolStart].
+func ToolComplete(toolCallID, result string) copilot.SessionEvent {
+	return newEvent(copilot.ToolExecutionComplete, copilot.Data{
+		ToolCallID: copilot.String(toolCallID),
+		Success:    copilot.Bool(true),
+		Result:     &copilot.Result{Content: result},
+	})
+}
+
+// Idle returns an ephemeral session.idle event, which ends a turn.
+func Idle() copilot.SessionEvent {
+	event := newEvent(copilot.SessionIdle, copilot.Data{})
+	event.Ephemeral = copilot.Bool(true)
+	return event
+}
+
+// Error returns a session.error event with message and errorType, such as
+// "rate_limit" or "authentication".
+func Error(message, errorType string) copilot.SessionEvent {
+	return newEvent(copilot.SessionError, copilot.Data{
+		Message:   copilot.String(message),
+		ErrorType: copilot.String(errorType),
+	})
+}
+
+// DispatchTo delivers events to session in order, as if the CLI had sent
+// them. Handlers are called before DispatchTo returns, unless the session
+// holds events back with [copilot.EventOrderOptions.ReorderWindow].
+func DispatchTo(session *copilot.Session, events ...copilot.SessionEvent) {
+	for _, event := range events {
+		testhook.DispatchEvent(session, event)
+	}
+}
+
+// decoded returns v as encoding/json decodes it into an interface value.
+func decoded(v any) any {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("copilottest: cannot encode tool arguments: %v", err))
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		panic(fmt.Sprintf("copilottest: cannot decode tool arguments: %v", err))
+	}
+	return out
+}
@@ -0,0 +1,152 @@
+package copilottest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+func TestBuilders_MatchWireEvents(t *testing.T) {
+	type args struct {
+		Path  string `json:"path"`
+		Lines int    `json:"lines"`
+	}
+	events := map[string]copilot.SessionEvent{
+		"AssistantMessage": AssistantMessage("Hello"),
+		"MessageDelta":     MessageDelta("message-1", "Hel"),
+		"UserMessage":      UserMessage("Hi"),
+		"ToolStart":        ToolStart("read_file", args{Path: "README.md", Lines: 10}),
+		"ToolStart nil":    ToolStart("list_files", nil),
+		"ToolComplete":     ToolComplete("call-1", "done"),
+		"Idle":             Idle(),
+		"Error":            Error("Rate limit exceeded", "rate_limit"),
+	}
+	for name, event := range events {
+		t.Run(name, func(t *testing.T) {
+			data, err := event.Marshal()
+			if err != nil {
+				t.Fatalf("Failed to marshal: %v", err)
+			}
+			decoded, err := copilot.UnmarshalSessionEvent(data)
+			if err != nil {
+				t.Fatalf("Failed to unmarshal: %v", err)
+			}
+			// Rebuild the decoded event to drop the JSON it keeps for its
+			// payload accessors
+			decoded = copilot.SessionEvent{Data: decoded.Data, Ephemeral: decoded.Ephemeral, ID: decoded.ID, ParentID: decoded.ParentID, Timestamp: decoded.Timestamp, Type: decoded.Type}
+			if !reflect.DeepEqual(event, decoded) {
+				t.Errorf("Builder output differs from the decoded event:\n%+v\n%+v", event, decoded)
+			}
+		})
+	}
+
+	t.Run("typed payloads", func(t *testing.T) {
+		if msg, ok := AssistantMessage("Hello").AsAssistantMessage(); !ok || msg.Content != "Hello" || msg.MessageID == "" {
+			t.Errorf("Unexpected assistant message %+v", msg)
+		}
+		if start, ok := ToolStart("read_file", nil).AsToolExecutionStart(); !ok || start.ToolName != "read_file" || start.ToolCallID == "" {
+			t.Errorf("Unexpected tool start %+v", start)
+		}
+		if a, b := AssistantMessage("a"), AssistantMessage("b"); a.ID == b.ID || *a.Data.MessageID == *b.Data.MessageID {
+			t.Error("Expected unique IDs")
+		}
+	})
+}
+
+func TestDispatchTo(t *testing.T) {
+	session := newSession(t)
+	var got []copilot.SessionEventType
+	session.On(func(event copilot.SessionEvent) {
+		got = append(got, event.Type)
+	})
+	var reported []string
+	session.OnTypes([]copilot.SessionEventType{copilot.SessionError}, func(event copilot.SessionEvent) {
+		if data, ok := event.AsSessionError(); ok {
+			reported = append(reported, data.ErrorType+": "+data.Message)
+		}
+	})
+
+	DispatchTo(session, AssistantMessage("Hello"), Error("Rate limit exceeded", "rate_limit"), Idle())
+	want := []copilot.SessionEventType{copilot.AssistantMessage, copilot.SessionError, copilot.SessionIdle}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if len(reported) != 1 || reported[0] != "rate_limit: Rate limit exceeded" {
+		t.Errorf("Unexpected errors %v", reported)
+	}
+}
+
+// newSession creates a session on a fake CLI that answers ping,
+// session.create, and session.destroy over TCP.
+func newSession(t *testing.T) *copilot.Session {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+			go serve(conn)
+		}
+	}()
+
+	client := copilot.NewClient(&copilot.ClientOptions{CLIUrl: listener.Addr().String()})
+	t.Cleanup(func() { client.ForceStop() })
+	session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{OnPermissionRequest: copilot.PermissionHandler.ApproveAll})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	return session
+}
+
+func serve(conn net.Conn) {
+	version := copilot.GetSdkProtocolVersion()
+	results := map[string]any{
+		"ping":            copilot.PingResponse{ProtocolVersion: &version},
+		"session.create":  map[string]any{"sessionId": "s1"},
+		"session.destroy": map[string]any{},
+	}
+	reader := bufio.NewReader(conn)
+	for {
+		var length int
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if line == "\r\n" {
+				break
+			}
+			fmt.Sscanf(line, "Content-Length: %d", &length)
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return
+		}
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if json.Unmarshal(body, &msg) != nil || len(msg.ID) == 0 {
+			continue
+		}
+		response := map[string]any{"jsonrpc": "2.0", "id": msg.ID, "result": results[msg.Method]}
+		if _, ok := results[msg.Method]; !ok {
+			response = map[string]any{"jsonrpc": "2.0", "id": msg.ID, "error": map[string]any{"code": -32601, "message": "Method not found"}}
+		}
+		data, _ := json.Marshal(response)
+		fmt.Fprintf(conn, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	}
+}
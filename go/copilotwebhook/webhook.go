@@ -0,0 +1,440 @@
+// Package copilotwebhook forwards session events to an HTTP endpoint.
+//
+// A [Forwarder] subscribes to a session, batches its events, and POSTs each
+// batch as a JSON [Payload] signed with HMAC-SHA256, retrying with backoff
+// when the endpoint fails. Batches are delivered one at a time, so the
+// endpoint receives a session's events in order. The forwarder stops when the
+// session is destroyed, after delivering the events queued so far.
+//
+// Example:
+//
+//	forwarder, err := copilotwebhook.NewForwarder(session, "https://hooks.example.com/copilot", &copilotwebhook.Options{
+//	    Secret: os.Getenv("WEBHOOK_SECRET"),
+//	    OnDeliveryFailure: func(failure copilotwebhook.DeliveryFailure) {
+//	        log.Printf("Dropped %d events: %v", len(failure.Events), failure.Err)
+//	    },
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer forwarder.Close(context.Background())
+package copilotwebhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+const (
+	// SignatureHeader carries the payload signature: "sha256=" followed by
+	// the hex HMAC-SHA256 of the request body keyed with [Options.Secret].
+	SignatureHeader = "X-Copilot-Signature"
+	// SequenceHeader carries the payload's [Payload.Sequence], so receivers
+	// can discard retried batches they already processed.
+	SequenceHeader = "X-Copilot-Sequence"
+
+	defaultBatchSize       = 50
+	defaultFlushInterval   = time.Second
+	defaultMaxRetries      = 5
+	defaultMinBackoff      = 500 * time.Millisecond
+	defaultMaxBackoff      = 30 * time.Second
+	defaultMaxQueuedEvents = 10000
+)
+
+// ErrQueueFull is the error of a [DeliveryFailure] for events dropped because
+// more than [Options.MaxQueuedEvents] were waiting to be delivered.
+var ErrQueueFull = errors.New("webhook queue is full")
+
+// StatusError is the error of a [DeliveryFailure] for a batch the endpoint
+// answered with a non-2xx status.
+type StatusError struct {
+	StatusCode int
+	// Body is the start of the response body
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("webhook endpoint returned %d", e.StatusCode)
+	}
+	return fmt.Sprintf("webhook endpoint returned %d: %s", e.StatusCode, e.Body)
+}
+
+// retryable reports whether the endpoint may accept the batch later.
+func (e *StatusError) retryable() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// Options configures a [Forwarder].
+type Options struct {
+	// Secret signs each payload in the [SignatureHeader]. Payloads are not
+	// signed if it is empty.
+	Secret string
+	// BatchSize is the most events sent in one request (default: 50)
+	BatchSize int
+	// FlushInterval is how long an event waits for a batch to fill before it
+	// is sent (default: 1 second)
+	FlushInterval time.Duration
+	// MaxRetries is how many times a batch is retried after a 5xx or 429
+	// status or a network error (default: 5). Other statuses are not retried.
+	MaxRetries int
+	// MinBackoff is the delay before the first retry, doubled for each
+	// further retry (default: 500ms)
+	MinBackoff time.Duration
+	// MaxBackoff caps the delay between retries (default: 30 seconds)
+	MaxBackoff time.Duration
+	// MaxQueuedEvents is how many events may wait for delivery while the
+	// endpoint is slow or failing; the oldest are dropped beyond it
+	// (default: 10000)
+	MaxQueuedEvents int
+	// HTTPClient sends the requests (default: a client with a 30 second
+	// timeout)
+	HTTPClient *http.Client
+	// OnDeliveryFailure is called with events that were given up on. It is
+	// called synchronously and must not block.
+	OnDeliveryFailure func(DeliveryFailure)
+}
+
+// Payload is the JSON body POSTed to the endpoint.
+type Payload struct {
+	SessionID string `json:"sessionId"`
+	// Sequence numbers the session's batches from 1. A retried batch keeps its
+	// number, and a gap means a batch was given up on.
+	Sequence uint64                 `json:"sequence"`
+	Events   []copilot.SessionEvent `json:"events"`
+}
+
+// DeliveryFailure describes events that were not delivered.
+type DeliveryFailure struct {
+	SessionID string
+	// Sequence is the number of the failed batch, or zero for events dropped
+	// from the queue
+	Sequence uint64
+	Events   []copilot.SessionEvent
+	// Attempts is how many requests were made for the batch
+	Attempts int
+	Err      error
+}
+
+// Stats counts a forwarder's deliveries.
+type Stats struct {
+	// Delivered is the number of events the endpoint accepted
+	Delivered int64
+	// Failed is the number of events given up on, including dropped ones
+	Failed int64
+	// Dropped is the number of events dropped because the queue was full
+	Dropped int64
+	// Batches is the number of batches the endpoint accepted
+	Batches int64
+	// Retries is the number of requests retried
+	Retries int64
+	// Queued is the number of events waiting to be delivered
+	Queued int
+}
+
+// Forwarder POSTs a session's events to a webhook endpoint.
+type Forwarder struct {
+	sessionID   string
+	endpoint    string
+	opts        Options
+	backoff     copilot.Backoff
+	unsubscribe func()
+
+	mu       sync.Mutex
+	queue    []copilot.SessionEvent
+	closed   bool
+	wake     chan struct{}
+	stopping chan struct{}
+	done     chan struct{}
+	sequence uint64
+
+	// abandon cancels delivery when Close gives up waiting
+	ctx     context.Context
+	abandon context.CancelFunc
+
+	delivered atomic.Int64
+	failed    atomic.Int64
+	dropped   atomic.Int64
+	batches   atomic.Int64
+	retries   atomic.Int64
+}
+
+// NewForwarder starts forwarding the events session emits from now on to
+// endpoint, an http or https URL. opts may be nil.
+func NewForwarder(session *copilot.Session, endpoint string, opts *Options) (*Forwarder, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook endpoint: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook endpoint %q: must be an http or https URL", endpoint)
+	}
+
+	f := &Forwarder{
+		sessionID: session.SessionID,
+		endpoint:  endpoint,
+		wake:      make(chan struct{}, 1),
+		stopping:  make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	if opts != nil {
+		f.opts = *opts
+	}
+	if f.opts.BatchSize <= 0 {
+		f.opts.BatchSize = defaultBatchSize
+	}
+	if f.opts.FlushInterval <= 0 {
+		f.opts.FlushInterval = defaultFlushInterval
+	}
+	if f.opts.MaxRetries <= 0 {
+		f.opts.MaxRetries = defaultMaxRetries
+	}
+	if f.opts.MinBackoff <= 0 {
+		f.opts.MinBackoff = defaultMinBackoff
+	}
+	if f.opts.MaxBackoff <= 0 {
+		f.opts.MaxBackoff = max(defaultMaxBackoff, f.opts.MinBackoff)
+	}
+	// Up to a quarter of jitter, so forwarders failing together do not
+	// retry together
+	f.backoff = copilot.Backoff{Initial: f.opts.MinBackoff, Max: f.opts.MaxBackoff, Jitter: 0.25}
+	if f.opts.MaxQueuedEvents <= 0 {
+		f.opts.MaxQueuedEvents = defaultMaxQueuedEvents
+	}
+	if f.opts.HTTPClient == nil {
+		f.opts.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	f.ctx, f.abandon = context.WithCancel(context.Background())
+
+	f.unsubscribe = session.On(f.enqueue)
+	go f.run(session.Done())
+	return f, nil
+}
+
+// Close stops forwarding new events and waits for the queued ones to be
+// delivered. If ctx is done first, the remaining events are given up on,
+// reported to [Options.OnDeliveryFailure], and ctx's error is returned.
+// Closing a forwarder whose session was destroyed waits for the same
+// delivery.
+func (f *Forwarder) Close(ctx context.Context) error {
+	f.stop()
+	select {
+	case <-f.done:
+		return nil
+	case <-ctx.Done():
+		f.abandon()
+		<-f.done
+		return ctx.Err()
+	}
+}
+
+// Stats returns the forwarder's delivery counts.
+func (f *Forwarder) Stats() Stats {
+	f.mu.Lock()
+	queued := len(f.queue)
+	f.mu.Unlock()
+	return Stats{
+		Delivered: f.delivered.Load(),
+		Failed:    f.failed.Load(),
+		Dropped:   f.dropped.Load(),
+		Batches:   f.batches.Load(),
+		Retries:   f.retries.Load(),
+		Queued:    queued,
+	}
+}
+
+// stop stops accepting events and tells run to deliver the queue and exit.
+func (f *Forwarder) stop() {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return
+	}
+	f.closed = true
+	f.mu.Unlock()
+	f.unsubscribe()
+	close(f.stopping)
+}
+
+// enqueue queues an event for delivery, dropping the oldest queued event if
+// the queue is full.
+func (f *Forwarder) enqueue(event copilot.SessionEvent) {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return
+	}
+	var dropped []copilot.SessionEvent
+	if len(f.queue) >= f.opts.MaxQueuedEvents {
+		dropped = []copilot.SessionEvent{f.queue[0]}
+		f.queue = f.queue[1:]
+	}
+	f.queue = append(f.queue, event)
+	f.mu.Unlock()
+
+	select {
+	case f.wake <- struct{}{}:
+	default:
+	}
+	if dropped != nil {
+		f.dropped.Add(1)
+		f.fail(DeliveryFailure{SessionID: f.sessionID, Events: dropped, Err: ErrQueueFull})
+	}
+}
+
+// run delivers queued events once a batch fills or the oldest has waited
+// FlushInterval, until the forwarder is stopped or the session destroyed.
+func (f *Forwarder) run(sessionDone <-chan struct{}) {
+	defer close(f.done)
+	defer f.abandon()
+	var flush <-chan time.Time
+	for {
+		select {
+		case <-f.wake:
+			if flush == nil {
+				flush = time.After(f.opts.FlushInterval)
+			}
+			if f.queued() < f.opts.BatchSize {
+				continue
+			}
+		case <-flush:
+		case <-sessionDone:
+			sessionDone = nil
+			f.stop()
+			continue
+		case <-f.stopping:
+			f.deliverQueued()
+			return
+		}
+		flush = nil
+		f.deliverQueued()
+	}
+}
+
+func (f *Forwarder) queued() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.queue)
+}
+
+// deliverQueued delivers the queue in batches of at most BatchSize.
+func (f *Forwarder) deliverQueued() {
+	for {
+		f.mu.Lock()
+		n := min(len(f.queue), f.opts.BatchSize)
+		batch := f.queue[:n:n]
+		f.queue = f.queue[n:]
+		f.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		f.deliver(batch)
+	}
+}
+
+// deliver sends a batch, retrying retryable failures with backoff.
+func (f *Forwarder) deliver(events []copilot.SessionEvent) {
+	f.sequence++
+	failure := DeliveryFailure{SessionID: f.sessionID, Sequence: f.sequence, Events: events}
+	body, err := json.Marshal(Payload{SessionID: f.sessionID, Sequence: f.sequence, Events: events})
+	if err != nil {
+		failure.Err = fmt.Errorf("failed to serialize events: %w", err)
+		f.fail(failure)
+		return
+	}
+
+	for {
+		failure.Attempts++
+		err := f.post(body, f.sequence)
+		if err == nil {
+			f.delivered.Add(int64(len(events)))
+			f.batches.Add(1)
+			return
+		}
+		failure.Err = err
+		var status *StatusError
+		if (errors.As(err, &status) && !status.retryable()) || f.ctx.Err() != nil || failure.Attempts > f.opts.MaxRetries {
+			f.fail(failure)
+			return
+		}
+
+		timer := time.NewTimer(f.backoff.Next(failure.Attempts))
+		select {
+		case <-timer.C:
+			f.retries.Add(1)
+		case <-f.ctx.Done():
+			timer.Stop()
+			f.fail(failure)
+			return
+		}
+	}
+}
+
+// post makes one delivery request.
+func (f *Forwarder) post(body []byte, sequence uint64) error {
+	req, err := http.NewRequestWithContext(f.ctx, http.MethodPost, f.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SequenceHeader, strconv.FormatUint(sequence, 10))
+	if f.opts.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(f.opts.Secret, body))
+	}
+
+	resp, err := f.opts.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return &StatusError{StatusCode: resp.StatusCode, Body: string(bytes.TrimSpace(snippet))}
+}
+
+func (f *Forwarder) fail(failure DeliveryFailure) {
+	f.failed.Add(int64(len(failure.Events)))
+	if f.opts.OnDeliveryFailure != nil {
+		f.opts.OnDeliveryFailure(failure)
+	}
+}
+
+// Sign returns the [SignatureHeader] value for body signed with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature, the [SignatureHeader] of a request, is
+// valid for body and secret. Receivers should verify the body before parsing
+// it.
+//
+// Example:
+//
+//	body, _ := io.ReadAll(r.Body)
+//	if !copilotwebhook.Verify(secret, body, r.Header.Get(copilotwebhook.SignatureHeader)) {
+//	    http.Error(w, "bad signature", http.StatusUnauthorized)
+//	    return
+//	}
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, body)))
+}
@@ -0,0 +1,333 @@
+package copilotwebhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// fakeCLI answers session.create and session.destroy over TCP and sends the
+// session events it is given.
+type fakeCLI struct {
+	listener net.Listener
+	mu       sync.Mutex
+	conn     net.Conn
+}
+
+func newFakeCLI(t *testing.T) *fakeCLI {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	cli := &fakeCLI{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+			cli.mu.Lock()
+			cli.conn = conn
+			cli.mu.Unlock()
+			go cli.serve(conn)
+		}
+	}()
+	return cli
+}
+
+func (f *fakeCLI) serve(conn net.Conn) {
+	version := copilot.GetSdkProtocolVersion()
+	results := map[string]any{
+		"ping":            copilot.PingResponse{ProtocolVersion: &version},
+		"session.create":  map[string]any{"sessionId": "s1"},
+		"session.destroy": map[string]any{},
+	}
+	reader := bufio.NewReader(conn)
+	for {
+		var length int
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if line == "\r\n" {
+				break
+			}
+			fmt.Sscanf(line, "Content-Length: %d", &length)
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return
+		}
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if json.Unmarshal(body, &msg) != nil || len(msg.ID) == 0 {
+			continue
+		}
+		response := map[string]any{"jsonrpc": "2.0", "id": msg.ID, "result": results[msg.Method]}
+		if _, ok := results[msg.Method]; !ok {
+			response = map[string]any{"jsonrpc": "2.0", "id": msg.ID, "error": map[string]any{"code": -32601, "message": "Method not found"}}
+		}
+		f.write(response)
+	}
+}
+
+func (f *fakeCLI) write(msg any) {
+	data, _ := json.Marshal(msg)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fmt.Fprintf(f.conn, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+// emit sends n assistant.message events numbered from first.
+func (f *fakeCLI) emit(first, n int) {
+	for i := first; i < first+n; i++ {
+		f.write(map[string]any{"jsonrpc": "2.0", "method": "session.event", "params": map[string]any{
+			"sessionId": "s1",
+			"event": copilot.SessionEvent{
+				ID:        fmt.Sprintf("e%d", i),
+				Type:      copilot.AssistantMessage,
+				Timestamp: time.Now(),
+				Data:      copilot.Data{Content: copilot.String(fmt.Sprintf("message %d", i))},
+			},
+		}})
+	}
+}
+
+func newSession(t *testing.T) (*copilot.Session, *fakeCLI) {
+	t.Helper()
+	cli := newFakeCLI(t)
+	client := copilot.NewClient(&copilot.ClientOptions{CLIUrl: cli.listener.Addr().String()})
+	t.Cleanup(func() { client.ForceStop() })
+	session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{OnPermissionRequest: copilot.PermissionHandler.ApproveAll})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	return session, cli
+}
+
+// endpoint records the payloads it accepts, answering with the statuses
+// queued in fail first.
+type endpoint struct {
+	*httptest.Server
+	mu       sync.Mutex
+	payloads []Payload
+	fail     []int
+}
+
+func newEndpoint(t *testing.T, fail ...int) *endpoint {
+	e := &endpoint{fail: fail}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if len(e.fail) > 0 {
+			status := e.fail[0]
+			e.fail = e.fail[1:]
+			http.Error(w, "unavailable", status)
+			return
+		}
+		var payload Payload
+		json.Unmarshal(body, &payload)
+		e.payloads = append(e.payloads, payload)
+		if !Verify("secret", body, r.Header.Get(SignatureHeader)) {
+			t.Errorf("Invalid signature %q", r.Header.Get(SignatureHeader))
+		}
+	}))
+	t.Cleanup(e.Close)
+	return e
+}
+
+func (e *endpoint) contents() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var contents []string
+	for _, payload := range e.payloads {
+		for _, event := range payload.Events {
+			contents = append(contents, *event.Data.Content)
+		}
+	}
+	return contents
+}
+
+// waitFor polls cond until it holds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func fastOptions() *Options {
+	return &Options{
+		Secret:        "secret",
+		BatchSize:     4,
+		FlushInterval: 10 * time.Millisecond,
+		MinBackoff:    time.Millisecond,
+		MaxBackoff:    5 * time.Millisecond,
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"sessionId":"s1"}`)
+	signature := Sign("secret", body)
+	if !strings.HasPrefix(signature, "sha256=") || len(signature) != len("sha256=")+64 {
+		t.Errorf("Unexpected signature %q", signature)
+	}
+	if !Verify("secret", body, signature) {
+		t.Error("Expected the signature to verify")
+	}
+	if Verify("other", body, signature) || Verify("secret", []byte(`{"sessionId":"s2"}`), signature) {
+		t.Error("Expected a different secret or body not to verify")
+	}
+}
+
+func TestForwarder(t *testing.T) {
+	t.Run("delivers signed batches in order", func(t *testing.T) {
+		session, cli := newSession(t)
+		endpoint := newEndpoint(t)
+		forwarder, err := NewForwarder(session, endpoint.URL, fastOptions())
+		if err != nil {
+			t.Fatalf("Failed to create forwarder: %v", err)
+		}
+		cli.emit(0, 10)
+		waitFor(t, "10 events", func() bool { return len(endpoint.contents()) == 10 })
+		if err := forwarder.Close(t.Context()); err != nil {
+			t.Fatalf("Failed to close: %v", err)
+		}
+
+		contents := endpoint.contents()
+		if len(contents) != 10 {
+			t.Fatalf("Expected 10 events, got %v", contents)
+		}
+		for i, content := range contents {
+			if content != fmt.Sprintf("message %d", i) {
+				t.Errorf("Expected events in order, got %v", contents)
+				break
+			}
+		}
+		for i, payload := range endpoint.payloads {
+			if payload.SessionID != "s1" || payload.Sequence != uint64(i+1) || len(payload.Events) > 4 {
+				t.Errorf("Unexpected payload %d: session %s, sequence %d, %d events", i, payload.SessionID, payload.Sequence, len(payload.Events))
+			}
+		}
+		if stats := forwarder.Stats(); stats.Delivered != 10 || stats.Failed != 0 || stats.Queued != 0 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("retries server errors without reordering", func(t *testing.T) {
+		session, cli := newSession(t)
+		endpoint := newEndpoint(t, http.StatusServiceUnavailable, http.StatusBadGateway)
+		forwarder, _ := NewForwarder(session, endpoint.URL, fastOptions())
+		// The second half arrives while the first batch is being retried
+		cli.emit(0, 3)
+		cli.emit(3, 3)
+		waitFor(t, "6 events", func() bool { return len(endpoint.contents()) == 6 })
+		forwarder.Close(t.Context())
+
+		contents := endpoint.contents()
+		want := []string{"message 0", "message 1", "message 2", "message 3", "message 4", "message 5"}
+		if fmt.Sprint(contents) != fmt.Sprint(want) {
+			t.Errorf("Expected %v, got %v", want, contents)
+		}
+		if endpoint.payloads[0].Sequence != 1 {
+			t.Errorf("Expected the retried batch to keep sequence 1, got %d", endpoint.payloads[0].Sequence)
+		}
+		if stats := forwarder.Stats(); stats.Retries != 2 || stats.Delivered != 6 {
+			t.Errorf("Expected 2 retries and 6 deliveries, got %+v", stats)
+		}
+	})
+
+	t.Run("reports batches that fail permanently", func(t *testing.T) {
+		session, cli := newSession(t)
+		endpoint := newEndpoint(t, http.StatusBadRequest, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+		failed := make(chan DeliveryFailure, 10)
+		opts := fastOptions()
+		opts.FlushInterval = time.Hour
+		opts.MaxRetries = 1
+		opts.OnDeliveryFailure = func(failure DeliveryFailure) { failed <- failure }
+		forwarder, _ := NewForwarder(session, endpoint.URL, opts)
+
+		// Full batches are sent at once; the last event waits for Close
+		var failures []DeliveryFailure
+		cli.emit(0, 4)
+		failures = append(failures, <-failed)
+		cli.emit(4, 4)
+		failures = append(failures, <-failed)
+		cli.emit(8, 1)
+		waitFor(t, "the last event", func() bool { return forwarder.Stats().Queued == 1 })
+		forwarder.Close(t.Context())
+
+		if len(failed) != 0 {
+			t.Errorf("Expected 2 failed batches, got another: %+v", <-failed)
+		}
+		var status *StatusError
+		if !errors.As(failures[0].Err, &status) || status.StatusCode != http.StatusBadRequest || failures[0].Attempts != 1 {
+			t.Errorf("Expected a 400 without retries, got %+v", failures[0])
+		}
+		if failures[1].Sequence != 2 || failures[1].Attempts != 2 {
+			t.Errorf("Expected batch 2 to fail after one retry, got %+v", failures[1])
+		}
+		if got := endpoint.contents(); len(got) != 1 || endpoint.payloads[0].Sequence != 3 {
+			t.Errorf("Expected only batch 3 to arrive, got %v", got)
+		}
+		if stats := forwarder.Stats(); stats.Failed != 8 || stats.Delivered != 1 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("stops when the session is destroyed", func(t *testing.T) {
+		session, cli := newSession(t)
+		endpoint := newEndpoint(t)
+		opts := fastOptions()
+		opts.FlushInterval = time.Hour
+		forwarder, _ := NewForwarder(session, endpoint.URL, opts)
+		cli.emit(0, 2)
+		waitFor(t, "2 queued events", func() bool { return forwarder.Stats().Queued == 2 })
+		if err := session.Destroy(); err != nil {
+			t.Fatalf("Failed to destroy session: %v", err)
+		}
+
+		select {
+		case <-forwarder.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the forwarder to stop")
+		}
+		if got := endpoint.contents(); len(got) != 2 {
+			t.Errorf("Expected the queued events to be delivered, got %v", got)
+		}
+		if err := forwarder.Close(t.Context()); err != nil {
+			t.Errorf("Expected Close after destroy to succeed, got %v", err)
+		}
+	})
+
+	t.Run("rejects endpoints that are not http URLs", func(t *testing.T) {
+		session, _ := newSession(t)
+		for _, endpoint := range []string{"ftp://example.com", "/relative", "://"} {
+			if _, err := NewForwarder(session, endpoint, nil); err == nil {
+				t.Errorf("Expected %q to be rejected", endpoint)
+			}
+		}
+	})
+}
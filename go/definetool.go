@@ -5,6 +5,7 @@
 package copilot
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -14,7 +15,9 @@ import (
 
 // DefineTool creates a Tool with automatic JSON schema generation from a typed handler function.
 // The handler receives typed arguments (automatically unmarshaled from JSON) and the raw ToolInvocation.
-// The handler can return any value - strings pass through directly, other types are JSON-serialized.
+// The handler can return any value - strings pass through directly, a ToolResult or *ToolResult is
+// used as is, so it can carry [ToolResult.Content] blocks such as images, and other types are
+// JSON-serialized.
 //
 // Example:
 //
@@ -28,6 +31,32 @@ import (
 //	        return fmt.Sprintf("Weather in %s: 22°%s", params.City, params.Unit), nil
 //	    })
 func DefineTool[T any, U any](name, description string, handler func(T, ToolInvocation) (U, error)) Tool {
+	return DefineToolWithContext(name, description, func(_ context.Context, params T, inv ToolInvocation) (U, error) {
+		return handler(params, inv)
+	})
+}
+
+// DefineToolWithContext is like [DefineTool], but the handler also receives
+// the invocation's context as its first argument. The context is cancelled
+// when the tool's timeout expires, the turn is aborted, the session is
+// destroyed, or the CLI withdraws the call (see [ToolInvocation.Context]).
+//
+// Example:
+//
+//	type QueryParams struct {
+//	    SQL string `json:"sql" jsonschema:"query to run"`
+//	}
+//
+//	tool := copilot.DefineToolWithContext("query_db", "Run a read-only SQL query",
+//	    func(ctx context.Context, params QueryParams, inv copilot.ToolInvocation) (any, error) {
+//	        rows, err := db.QueryContext(ctx, params.SQL)
+//	        if err != nil {
+//	            return nil, err
+//	        }
+//	        defer rows.Close()
+//	        return formatRows(rows)
+//	    })
+func DefineToolWithContext[T any, U any](name, description string, handler func(context.Context, T, ToolInvocation) (U, error)) Tool {
 	var zero T
 	schema := generateSchemaForType(reflect.TypeOf(zero))
 
@@ -40,22 +69,27 @@ func DefineTool[T any, U any](name, description string, handler func(T, ToolInvo
 }
 
 // createTypedHandler wraps a typed handler function into the standard ToolHandler signature.
-func createTypedHandler[T any, U any](handler func(T, ToolInvocation) (U, error)) ToolHandler {
+func createTypedHandler[T any, U any](handler func(context.Context, T, ToolInvocation) (U, error)) ToolHandler {
 	return func(inv ToolInvocation) (ToolResult, error) {
 		var params T
 
-		// Convert arguments to typed struct via JSON round-trip
-		// Arguments is already map[string]any from JSON-RPC parsing
-		jsonBytes, err := json.Marshal(inv.Arguments)
+		// Decode the arguments as the CLI sent them, or, for invocations
+		// built by hand, via a JSON round-trip of Arguments
+		jsonBytes, err := invocationArguments(inv)
 		if err != nil {
-			return ToolResult{}, fmt.Errorf("failed to marshal arguments: %w", err)
+			return ToolResult{}, err
 		}
 
 		if err := json.Unmarshal(jsonBytes, &params); err != nil {
 			return ToolResult{}, fmt.Errorf("failed to unmarshal arguments into %T: %w", params, err)
 		}
 
-		result, err := handler(params, inv)
+		ctx := inv.Context
+		if ctx == nil {
+			// An invocation built by hand, for example in a test
+			ctx = context.Background()
+		}
+		result, err := handler(ctx, params, inv)
 		if err != nil {
 			return ToolResult{}, err
 		}
@@ -64,8 +98,20 @@ func createTypedHandler[T any, U any](handler func(T, ToolInvocation) (U, error)
 	}
 }
 
+// invocationArguments returns an invocation's arguments as JSON.
+func invocationArguments(inv ToolInvocation) (json.RawMessage, error) {
+	if inv.RawArguments != nil {
+		return inv.RawArguments, nil
+	}
+	jsonBytes, err := json.Marshal(inv.Arguments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal arguments: %w", err)
+	}
+	return jsonBytes, nil
+}
+
 // normalizeResult converts any value to a ToolResult.
-// Strings pass through directly, ToolResult passes through, other types are JSON-serialized.
+// Strings pass through directly, ToolResult and *ToolResult pass through, other types are JSON-serialized.
 func normalizeResult(result any) (ToolResult, error) {
 	if result == nil {
 		return ToolResult{
@@ -75,8 +121,14 @@ func normalizeResult(result any) (ToolResult, error) {
 	}
 
 	// ToolResult passes through directly
-	if tr, ok := result.(ToolResult); ok {
+	switch tr := result.(type) {
+	case ToolResult:
 		return tr, nil
+	case *ToolResult:
+		if tr == nil {
+			return normalizeResult(nil)
+		}
+		return *tr, nil
 	}
 
 	// Strings pass through directly
@@ -1,9 +1,15 @@
 package copilot
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestDefineTool(t *testing.T) {
@@ -377,3 +383,109 @@ func TestGenerateSchemaForType(t *testing.T) {
 		}
 	})
 }
+
+func TestDefineTool_Invocation(t *testing.T) {
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		if method == "session.create" {
+			return createSessionResponse{SessionID: "s1"}, nil
+		}
+		return nil, nil
+	})
+	cli.mu.Lock()
+	cli.responses = make(chan json.RawMessage, 2)
+	cli.mu.Unlock()
+
+	var mu sync.Mutex
+	var calls []json.RawMessage
+	client := NewClient(&ClientOptions{
+		CLIUrl: cli.addr(),
+		OnRPCMessage: func(m RPCMessage) {
+			if m.Incoming && m.Method == "tool.call" {
+				mu.Lock()
+				defer mu.Unlock()
+				calls = append(calls, m.JSON)
+			}
+		},
+	})
+	t.Cleanup(func() { client.ForceStop() })
+
+	type Params struct {
+		ID int64 `json:"id"`
+	}
+	var ids []int64
+	var invocations []ToolInvocation
+	_, err := client.CreateSession(t.Context(), &SessionConfig{
+		OnPermissionRequest: PermissionHandler.ApproveAll,
+		WorkingDirectory:    "/work",
+		Tools: []Tool{DefineTool("lookup", "Look up an issue", func(params Params, inv ToolInvocation) (any, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			ids = append(ids, params.ID)
+			invocations = append(invocations, inv)
+			return "found", nil
+		})},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	// An ID above 2^53 survives only if the raw arguments are decoded
+	cli.request(1, "tool.call", map[string]any{
+		"sessionId":  "s1",
+		"toolCallId": "call-1",
+		"toolName":   "lookup",
+		"arguments":  json.RawMessage(`{"id":9007199254740993}`),
+		"timestamp":  "2026-03-01T12:30:45Z",
+		"cwd":        "/work/repo",
+	})
+	before := time.Now()
+	cli.request(2, "tool.call", map[string]any{"sessionId": "s1", "toolCallId": "call-2", "toolName": "lookup", "arguments": map[string]any{"id": 7}})
+	for range 2 {
+		select {
+		case <-cli.responses:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the tool results")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(invocations) != 2 || len(calls) != 2 {
+		t.Fatalf("Expected 2 invocations and 2 captured calls, got %d and %d", len(invocations), len(calls))
+	}
+	slices.SortFunc(invocations, func(a, b ToolInvocation) int { return strings.Compare(a.ToolCallID, b.ToolCallID) })
+	for _, call := range calls {
+		var request struct {
+			Params struct {
+				ToolCallID string          `json:"toolCallId"`
+				ToolName   string          `json:"toolName"`
+				Arguments  json.RawMessage `json:"arguments"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(call, &request); err != nil {
+			t.Fatalf("Failed to decode captured call %s: %v", call, err)
+		}
+		i := slices.IndexFunc(invocations, func(inv ToolInvocation) bool { return inv.ToolCallID == request.Params.ToolCallID })
+		if i < 0 {
+			t.Errorf("No invocation for captured call %s", request.Params.ToolCallID)
+			continue
+		}
+		if inv := invocations[i]; inv.ToolName != request.Params.ToolName || !bytes.Equal(inv.RawArguments, request.Params.Arguments) {
+			t.Errorf("Expected the invocation to match the captured call %s, got %q with %s", call, inv.ToolName, inv.RawArguments)
+		}
+	}
+
+	if !slices.Contains(ids, 9007199254740993) {
+		t.Errorf("Expected the exact ID to reach the handler, got %v", ids)
+	}
+	first, second := invocations[0], invocations[1]
+	if !first.Timestamp.Equal(time.Date(2026, 3, 1, 12, 30, 45, 0, time.UTC)) || first.WorkingDirectory != "/work/repo" {
+		t.Errorf("Expected the CLI's timestamp and directory, got %v and %q", first.Timestamp, first.WorkingDirectory)
+	}
+	if second.Timestamp.Before(before) || second.WorkingDirectory != "/work" {
+		t.Errorf("Expected the time received and the session's directory, got %v and %q", second.Timestamp, second.WorkingDirectory)
+	}
+	if first.Arguments.(map[string]any)["id"] == nil {
+		t.Errorf("Expected the decoded arguments too, got %v", first.Arguments)
+	}
+}
@@ -0,0 +1,59 @@
+package copilot
+
+import (
+	"fmt"
+	"maps"
+	"runtime"
+)
+
+// Legacy APIs reported to [ClientOptions.OnDeprecatedUse]. They keep working;
+// the report shows which callers to move to the replacement before the API
+// is removed.
+const (
+	// DeprecatedMCPServerMap is the map-based [SessionConfig.MCPServers] or
+	// [ResumeSessionConfig.MCPServers], reported when a session is created
+	// or resumed with it; use TypedMCPServers.
+	DeprecatedMCPServerMap = "SessionConfig.MCPServers"
+	// DeprecatedWaitTimeout is [SendAndWaitOptions.Timeout], a raw timeout
+	// for the wait, reported when a send sets it; set a deadline on the
+	// context instead.
+	DeprecatedWaitTimeout = "SendAndWaitOptions.Timeout"
+)
+
+// recordDeprecatedUse counts a use of a legacy API and reports it with the
+// caller's location to ClientOptions.OnDeprecatedUse. depth is the number of
+// SDK frames between recordDeprecatedUse and the caller's code: 1 when called
+// directly from the exported API.
+func (r *diagnosticsRecorder) recordDeprecatedUse(api string, depth int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	if r.deprecatedUses == nil {
+		r.deprecatedUses = make(map[string]int)
+	}
+	r.deprecatedUses[api]++
+	onUse := r.onDeprecatedUse
+	r.mu.Unlock()
+
+	if onUse == nil {
+		return
+	}
+	callSite := "unknown"
+	if _, file, line, ok := runtime.Caller(depth + 1); ok {
+		callSite = fmt.Sprintf("%s:%d", file, line)
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			r.recordPanic("deprecated use handler", "", v)
+		}
+	}()
+	onUse(api, callSite)
+}
+
+// deprecatedUseCounts returns how often each legacy API was called.
+func (r *diagnosticsRecorder) deprecatedUseCounts() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return maps.Clone(r.deprecatedUses)
+}
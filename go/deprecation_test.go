@@ -0,0 +1,181 @@
+package copilot
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeprecatedUse(t *testing.T) {
+	type use struct{ api, callSite string }
+	var mu sync.Mutex
+	var uses []use
+	record := func(api, callSite string) {
+		mu.Lock()
+		uses = append(uses, use{api, callSite})
+		mu.Unlock()
+	}
+	reported := func() []use {
+		mu.Lock()
+		defer mu.Unlock()
+		got := uses
+		uses = nil
+		return got
+	}
+	checkReported := func(t *testing.T, api string) {
+		t.Helper()
+		got := reported()
+		if len(got) != 1 || got[0].api != api {
+			t.Fatalf("Expected one use of %s, got %v", api, got)
+		}
+		if filepath.Base(strings.Split(got[0].callSite, ":")[0]) != "deprecation_test.go" {
+			t.Errorf("Expected the call site in this file, got %s", got[0].callSite)
+		}
+	}
+	checkNotReported := func(t *testing.T, what string) {
+		t.Helper()
+		if got := reported(); len(got) != 0 {
+			t.Errorf("Expected no report for %s, got %v", what, got)
+		}
+	}
+
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		if method == "session.create" || method == "session.resume" {
+			return createSessionResponse{SessionID: "s1"}, nil
+		}
+		return nil, nil
+	})
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr(), OnDeprecatedUse: record})
+	t.Cleanup(func() { client.ForceStop() })
+	legacyServers := map[string]MCPServerConfig{
+		"files": {"type": "local", "command": "mcp-files", "tools": []string{"*"}},
+	}
+	typedServers := map[string]TypedMCPServerConfig{
+		"files": MCPLocalServerConfig{Type: "local", Command: "mcp-files", Tools: []string{"*"}},
+	}
+
+	t.Run("reports map-based MCP server configs", func(t *testing.T) {
+		if _, err := client.CreateSession(t.Context(), &SessionConfig{
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			MCPServers:          legacyServers,
+		}); err != nil {
+			t.Fatalf("Failed to create: %v", err)
+		}
+		checkReported(t, DeprecatedMCPServerMap)
+
+		legacy := &ResumeSessionConfig{OnPermissionRequest: PermissionHandler.ApproveAll, MCPServers: legacyServers}
+		if _, err := client.ResumeSession(t.Context(), "s1", legacy); err != nil {
+			t.Fatalf("Failed to resume: %v", err)
+		}
+		checkReported(t, DeprecatedMCPServerMap)
+		if _, err := client.ResumeSessionWithOptions(t.Context(), "s1", legacy); err != nil {
+			t.Fatalf("Failed to resume: %v", err)
+		}
+		checkReported(t, DeprecatedMCPServerMap)
+	})
+
+	t.Run("does not report typed MCP server configs or plain resumes", func(t *testing.T) {
+		if _, err := client.CreateSession(t.Context(), &SessionConfig{
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			TypedMCPServers:     typedServers,
+		}); err != nil {
+			t.Fatalf("Failed to create: %v", err)
+		}
+		checkNotReported(t, "TypedMCPServers")
+
+		config := &ResumeSessionConfig{OnPermissionRequest: PermissionHandler.ApproveAll, TypedMCPServers: typedServers}
+		if _, err := client.ResumeSession(t.Context(), "s1", config); err != nil {
+			t.Fatalf("Failed to resume: %v", err)
+		}
+		if _, err := client.ResumeSessionWithOptions(t.Context(), "s1", config); err != nil {
+			t.Fatalf("Failed to resume: %v", err)
+		}
+		checkNotReported(t, "ResumeSession and ResumeSessionWithOptions")
+	})
+
+	t.Run("reports raw wait timeouts", func(t *testing.T) {
+		var server *fakeServer
+		session, server := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.send" {
+				go server.emit(SessionEvent{Type: SessionIdle})
+				return sessionSendResponse{MessageID: "msg"}, nil
+			}
+			return nil, nil
+		})
+		session.diagnostics = client.diagnostics
+		timeout := &SendAndWaitOptions{Timeout: time.Minute}
+
+		if _, err := session.SendAndWaitWithOptions(t.Context(), MessageOptions{Prompt: "hi"}, timeout); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		checkReported(t, DeprecatedWaitTimeout)
+		if _, err := session.SendAndCollect(t.Context(), MessageOptions{Prompt: "hi"}, timeout); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		checkReported(t, DeprecatedWaitTimeout)
+		if _, err := session.SendChunked(t.Context(), "Summarize", strings.NewReader("short"), ChunkOptions{WaitOptions: timeout}); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		checkReported(t, DeprecatedWaitTimeout)
+
+		if _, err := session.SendAndWait(t.Context(), MessageOptions{Prompt: "hi"}); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		if _, err := session.SendAndWaitWithOptions(t.Context(), MessageOptions{Prompt: "hi"}, &SendAndWaitOptions{IncludeHandlerTime: true}); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		if _, err := session.SendAndCollect(t.Context(), MessageOptions{Prompt: "hi"}, nil); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		checkNotReported(t, "sends without a timeout")
+	})
+
+	t.Run("counts uses in the diagnostic bundle", func(t *testing.T) {
+		bundle, err := client.DiagnosticBundle(t.Context())
+		if err != nil {
+			t.Fatalf("Failed to collect bundle: %v", err)
+		}
+		want := map[string]int{DeprecatedMCPServerMap: 3, DeprecatedWaitTimeout: 3}
+		if len(bundle.DeprecatedUses) != len(want) || bundle.DeprecatedUses[DeprecatedMCPServerMap] != 3 || bundle.DeprecatedUses[DeprecatedWaitTimeout] != 3 {
+			t.Errorf("Expected %v, got %v", want, bundle.DeprecatedUses)
+		}
+	})
+
+	t.Run("only counts without a callback", func(t *testing.T) {
+		quiet := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+		t.Cleanup(func() { quiet.ForceStop() })
+		if _, err := quiet.ResumeSession(t.Context(), "s1", &ResumeSessionConfig{
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			MCPServers:          legacyServers,
+		}); err != nil {
+			t.Fatalf("Failed to resume: %v", err)
+		}
+		if got := quiet.diagnostics.deprecatedUseCounts(); got[DeprecatedMCPServerMap] != 1 {
+			t.Errorf("Expected the use to be counted, got %v", got)
+		}
+		checkNotReported(t, "another client")
+	})
+}
+
+func TestMergeMCPServers(t *testing.T) {
+	legacy := map[string]MCPServerConfig{"files": {"type": "local", "command": "mcp-files"}}
+	typed := map[string]TypedMCPServerConfig{
+		"github": MCPRemoteServerConfig{Type: "http", URL: "https://api.githubcopilot.com/mcp/"},
+	}
+
+	merged, err := mergeMCPServers(legacy, typed)
+	if err != nil {
+		t.Fatalf("Failed to merge: %v", err)
+	}
+	if len(merged) != 2 || merged["files"]["command"] != "mcp-files" || merged["github"]["url"] != "https://api.githubcopilot.com/mcp/" {
+		t.Errorf("Unexpected merged servers: %v", merged)
+	}
+
+	typed["files"] = MCPLocalServerConfig{Command: "other"}
+	if _, err := mergeMCPServers(legacy, typed); err == nil || !strings.Contains(err.Error(), `"files"`) {
+		t.Errorf("Expected an error for a server in both fields, got %v", err)
+	}
+}
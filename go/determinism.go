@@ -0,0 +1,86 @@
+package copilot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// DeterminismConfig asks for a turn that is as reproducible as possible, for
+// regression evals. See [MessageOptions.Deterministic].
+//
+// The zero value is the temperature 0 shorthand: greedy sampling with one
+// tool call at a time.
+type DeterminismConfig struct {
+	// Seed is the sampling seed passed to the model, or nil to leave it to
+	// the model
+	Seed *int64 `json:"seed,omitempty"`
+	// Temperature is the sampling temperature (default: 0)
+	Temperature float64 `json:"temperature"`
+	// ParallelToolCalls lets the model request several tool calls at once.
+	// Off by default, since the order parallel calls complete in varies.
+	ParallelToolCalls bool `json:"parallelToolCalls"`
+}
+
+// EffectiveDeterminism records the determinism settings a turn ran with, so
+// eval reports can show how a result was produced. See
+// [TurnResult.Determinism].
+type EffectiveDeterminism struct {
+	DeterminismConfig
+	// Model is the session's model when the message was sent, or empty if it
+	// is unknown
+	Model string
+	// SequentialTools reports that the session's SDK tool handlers ran one at
+	// a time during the turn
+	SequentialTools bool
+	// RequestDigest is the hex SHA-256 of the serialized session.send
+	// parameters. Runs with the same digest sent the same request.
+	RequestDigest string
+}
+
+// validateDeterminism checks a message's determinism settings.
+func validateDeterminism(config *DeterminismConfig) error {
+	if config.Temperature < 0 {
+		return fmt.Errorf("invalid Deterministic.Temperature %v: must not be negative", config.Temperature)
+	}
+	return nil
+}
+
+// canonicalJSON re-encodes raw with object keys sorted and no insignificant
+// whitespace, so equal values serialize to equal bytes.
+func canonicalJSON(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// stabilizeSendRequest removes the SDK's own nondeterminism from req for a
+// deterministic message: the response schema is canonicalized, since callers
+// often build it from maps. The rest of req is serialized with sorted map
+// keys already.
+func stabilizeSendRequest(req *sessionSendRequest) error {
+	schema, err := canonicalJSON(req.ResponseSchema)
+	if err != nil {
+		return fmt.Errorf("invalid ResponseSchema: %w", err)
+	}
+	req.ResponseSchema = schema
+	return nil
+}
+
+// effectiveDeterminism returns what a deterministic message was sent with.
+func (s *Session) effectiveDeterminism(req sessionSendRequest) *EffectiveDeterminism {
+	data, _ := json.Marshal(req)
+	digest := sha256.Sum256(data)
+	return &EffectiveDeterminism{
+		DeterminismConfig: *req.Determinism,
+		Model:             s.Config().Model,
+		SequentialTools:   true,
+		RequestDigest:     hex.EncodeToString(digest[:]),
+	}
+}
@@ -0,0 +1,117 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSession_Deterministic(t *testing.T) {
+	// sendDeterministic sends a message in a fresh session and returns the
+	// serialized session.send parameters and the turn.
+	sendDeterministic := func(t *testing.T, schema string) (string, *TurnResult) {
+		t.Helper()
+		var params json.RawMessage
+		var server *fakeServer
+		session, server := newTestSession(t, func(method string, p json.RawMessage) (any, error) {
+			if method == "session.send" {
+				params = p
+				go server.emit(SessionEvent{Type: SessionIdle})
+				return sessionSendResponse{MessageID: "msg"}, nil
+			}
+			return nil, nil
+		})
+		seed := int64(42)
+		result, err := session.SendAndCollect(t.Context(), MessageOptions{
+			Prompt:         "Classify the ticket",
+			ResponseSchema: json.RawMessage(schema),
+			Template: &RenderedTemplate{Name: "classify", Variables: map[string]string{
+				"ticket": "T-1", "product": "sdk", "priority": "high", "area": "tools",
+			}},
+			Deterministic: &DeterminismConfig{Seed: &seed},
+		}, nil)
+		if err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		return string(params), result
+	}
+
+	t.Run("serializes byte-stable requests", func(t *testing.T) {
+		first, firstTurn := sendDeterministic(t, `{"type": "object", "properties": {"label": {"type": "string"}, "score": {"type": "number"}}}`)
+		for range 5 {
+			again, turn := sendDeterministic(t, `{"properties":{"score":{"type":"number"},"label":{"type":"string"}},"type":"object"}`)
+			if again != first {
+				t.Fatalf("Expected identical requests:\n%s\n%s", first, again)
+			}
+			if turn.Determinism.RequestDigest != firstTurn.Determinism.RequestDigest {
+				t.Errorf("Expected identical digests, got %s and %s", firstTurn.Determinism.RequestDigest, turn.Determinism.RequestDigest)
+			}
+		}
+		want := `"determinism":{"seed":42,"temperature":0,"parallelToolCalls":false}`
+		if !strings.Contains(first, want) {
+			t.Errorf("Expected %s in %s", want, first)
+		}
+	})
+
+	t.Run("records the effective settings in the turn", func(t *testing.T) {
+		_, turn := sendDeterministic(t, "")
+		got := turn.Determinism
+		if got == nil || got.Seed == nil || *got.Seed != 42 || got.Temperature != 0 || got.ParallelToolCalls || !got.SequentialTools || len(got.RequestDigest) != 64 {
+			t.Errorf("Unexpected determinism %+v", got)
+		}
+	})
+
+	t.Run("rejects a negative temperature", func(t *testing.T) {
+		session, _ := newTestSession(t, func(string, json.RawMessage) (any, error) { return nil, nil })
+		_, err := session.Send(t.Context(), MessageOptions{Prompt: "hi", Deterministic: &DeterminismConfig{Temperature: -1}})
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
+
+func TestClient_SequentialToolsInDeterministicTurns(t *testing.T) {
+	var running, peak atomic.Int32
+	tool := Tool{Name: "slow", Handler: func(ToolInvocation) (ToolResult, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		return ToolResult{TextResultForLLM: "done"}, nil
+	}}
+	session, _ := newTestSession(t, func(string, json.RawMessage) (any, error) { return nil, nil })
+	session.registerTools([]Tool{tool}, 0)
+	client := &Client{}
+	client.sessions.put(session)
+
+	callConcurrently := func(round string) int32 {
+		peak.Store(0)
+		var wg sync.WaitGroup
+		for i := range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				client.handleToolCallRequest(toolCallRequest{SessionID: session.SessionID, ToolCallID: fmt.Sprintf("%s-%d", round, i), ToolName: "slow"})
+			}()
+		}
+		wg.Wait()
+		return peak.Load()
+	}
+
+	if got := callConcurrently("concurrent"); got < 2 {
+		t.Errorf("Expected concurrent tool calls outside deterministic turns, peak %d", got)
+	}
+	session.sequentialTools.Store(true)
+	if got := callConcurrently("sequential"); got != 1 {
+		t.Errorf("Expected one tool call at a time in a deterministic turn, peak %d", got)
+	}
+}
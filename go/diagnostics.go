@@ -0,0 +1,459 @@
+package copilot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+const (
+	// diagnosticRPCCalls is how many recent JSON-RPC calls are kept for diagnostics.
+	diagnosticRPCCalls = 100
+	// diagnosticPanics is how many recent handler panics are kept for diagnostics.
+	diagnosticPanics = 20
+	// diagnosticStderrLines is how many trailing lines of CLI stderr are kept.
+	diagnosticStderrLines = 100
+	// diagnosticMaxLineLength truncates long stderr lines and panic messages.
+	diagnosticMaxLineLength = 1024
+	// redacted replaces secret values in a diagnostic bundle.
+	redacted = "[REDACTED]"
+)
+
+// DiagnosticBundle is a snapshot of the client's state for attaching to bug
+// reports. It serializes to JSON with [encoding/json].
+//
+// Secrets are redacted: environment variable values, the GitHub token, secret
+// CLI arguments, and the signed-in login are never included. JSON-RPC traffic
+// is summarized by method and latency only, so prompts and responses are not
+// included either. Free-form text such as CLI stderr and handler panic
+// messages is included as is and passed through
+// [DiagnosticBundleOptions.Redact].
+type DiagnosticBundle struct {
+	// GeneratedAt is when the bundle was collected
+	GeneratedAt time.Time `json:"generatedAt"`
+	// GoVersion is the Go runtime version
+	GoVersion string `json:"goVersion"`
+	// Platform is the operating system and architecture, e.g. "linux/amd64"
+	Platform string `json:"platform"`
+	// SDKProtocolVersion is the protocol version this SDK speaks
+	SDKProtocolVersion int `json:"sdkProtocolVersion"`
+	// CLIVersion is the version reported by the CLI, if connected
+	CLIVersion string `json:"cliVersion,omitempty"`
+	// CLIProtocolVersion is the protocol version reported by the CLI, if connected
+	CLIProtocolVersion int `json:"cliProtocolVersion,omitempty"`
+	// ConnectionState is the client's connection state
+	ConnectionState ConnectionState `json:"connectionState"`
+	// Auth is the CLI's authentication status, if connected
+	Auth *DiagnosticAuth `json:"auth,omitempty"`
+	// Options are the client options, with secrets redacted
+	Options DiagnosticClientOptions `json:"options"`
+	// Sessions describes each session tracked by the client
+	Sessions []DiagnosticSession `json:"sessions"`
+	// StderrTail is the last lines the CLI process wrote to stderr
+	StderrTail []string `json:"stderrTail"`
+	// RPCCalls summarizes recent JSON-RPC calls, oldest first
+	RPCCalls []DiagnosticRPCCall `json:"rpcCalls"`
+	// HandlerPanics lists recent panics recovered from caller-provided handlers
+	HandlerPanics []DiagnosticPanic `json:"handlerPanics"`
+	// DeprecatedUses counts calls of legacy APIs by name, such as
+	// [DeprecatedMCPServerMap]
+	DeprecatedUses map[string]int `json:"deprecatedUses,omitempty"`
+	// UnknownEventTypes counts session events by type whose type the SDK does
+	// not know, even after applying [ClientOptions.EventAliases]
+	UnknownEventTypes map[string]int `json:"unknownEventTypes,omitempty"`
+	// Errors maps each part of the bundle that could not be collected to why
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// DiagnosticAuth is the authentication part of a [DiagnosticBundle].
+type DiagnosticAuth struct {
+	IsAuthenticated bool   `json:"isAuthenticated"`
+	AuthType        string `json:"authType,omitempty"`
+	Host            string `json:"host,omitempty"`
+	StatusMessage   string `json:"statusMessage,omitempty"`
+}
+
+// DiagnosticClientOptions is the redacted form of [ClientOptions] in a
+// [DiagnosticBundle].
+type DiagnosticClientOptions struct {
+	CLIPath         string         `json:"cliPath,omitempty"`
+	CLIArgs         []string       `json:"cliArgs,omitempty"`
+	CLIUrl          string         `json:"cliUrl,omitempty"`
+	Cwd             string         `json:"cwd,omitempty"`
+	Port            int            `json:"port,omitempty"`
+	UseStdio        bool           `json:"useStdio"`
+	LogLevel        string         `json:"logLevel,omitempty"`
+	AutoStart       bool           `json:"autoStart"`
+	AutoRestart     bool           `json:"autoRestart"`
+	HasGitHubToken  bool           `json:"hasGitHubToken"`
+	UseLoggedInUser *bool          `json:"useLoggedInUser,omitempty"`
+	IntegrationID   string         `json:"integrationId,omitempty"`
+	Pacing          *PacingOptions `json:"pacing,omitempty"`
+	// Env lists the environment variable names passed to the CLI; values are redacted
+	Env []string `json:"env,omitempty"`
+}
+
+// DiagnosticSession describes a session in a [DiagnosticBundle].
+type DiagnosticSession struct {
+	SessionID     string `json:"sessionId"`
+	WorkspacePath string `json:"workspacePath,omitempty"`
+	// Busy reports whether a message was sent and the session has not yet gone idle
+	Busy bool `json:"busy"`
+	// State is what the session is doing; see [Session.State]
+	State SessionState `json:"state"`
+	// WaitingOnHandler reports whether a permission or user input handler is running
+	WaitingOnHandler bool `json:"waitingOnHandler"`
+	// LastEventType is the type of the last event received, if any
+	LastEventType SessionEventType `json:"lastEventType,omitempty"`
+	// LastEventAt is when the last event was received, if any
+	LastEventAt *time.Time `json:"lastEventAt,omitempty"`
+	// DestroyReason is why the session was destroyed, if it was
+	DestroyReason string `json:"destroyReason,omitempty"`
+}
+
+// DiagnosticRPCCall summarizes one JSON-RPC call in a [DiagnosticBundle].
+type DiagnosticRPCCall struct {
+	Method string `json:"method"`
+	// RequestID is the JSON-RPC request ID, for matching the call against
+	// CLI logs. Empty for notifications.
+	RequestID string `json:"requestId,omitempty"`
+	// Incoming is true for requests and notifications sent by the CLI
+	Incoming  bool      `json:"incoming,omitempty"`
+	At        time.Time `json:"at"`
+	LatencyMs float64   `json:"latencyMs"`
+	Failed    bool      `json:"failed,omitempty"`
+	// ErrorCode is the JSON-RPC error code of a failed call, if any
+	ErrorCode int `json:"errorCode,omitempty"`
+}
+
+// DiagnosticPanic describes a panic recovered from a caller-provided handler.
+type DiagnosticPanic struct {
+	At time.Time `json:"at"`
+	// Source identifies the handler, e.g. "event handler" or "tool my_tool"
+	Source    string `json:"source"`
+	SessionID string `json:"sessionId,omitempty"`
+	Message   string `json:"message"`
+}
+
+// DiagnosticBundleOptions configures [Client.DiagnosticBundleWithOptions].
+type DiagnosticBundleOptions struct {
+	// Redact is called with each free-form string in the bundle after the
+	// built-in redaction, along with the JSON path of its field such as
+	// "stderrTail" or "handlerPanics.message". The returned string replaces
+	// the value.
+	Redact func(field, value string) string
+}
+
+// DiagnosticBundle collects the client's state for attaching to bug reports.
+//
+// This is a convenience method that calls [Client.DiagnosticBundleWithOptions].
+//
+// Example:
+//
+//	bundle, err := client.DiagnosticBundle(ctx)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	data, _ := json.MarshalIndent(bundle, "", "  ")
+//	os.WriteFile("copilot-diagnostics.json", data, 0o600)
+func (c *Client) DiagnosticBundle(ctx context.Context) (*DiagnosticBundle, error) {
+	return c.DiagnosticBundleWithOptions(ctx, nil)
+}
+
+// DiagnosticBundleWithOptions collects the client's state for attaching to
+// bug reports, applying the given redaction hook.
+//
+// The bundle is collected on a best-effort basis: if the CLI cannot be
+// queried, the reason is recorded in [DiagnosticBundle.Errors] and the rest
+// of the bundle is still returned. The client is never started by this call.
+// An error is returned only if ctx is done before collection completes.
+//
+// Example:
+//
+//	bundle, err := client.DiagnosticBundleWithOptions(ctx, &copilot.DiagnosticBundleOptions{
+//	    Redact: func(field, value string) string {
+//	        return strings.ReplaceAll(value, homeDir, "~")
+//	    },
+//	})
+func (c *Client) DiagnosticBundleWithOptions(ctx context.Context, options *DiagnosticBundleOptions) (*DiagnosticBundle, error) {
+	bundle := &DiagnosticBundle{
+		GeneratedAt:        time.Now(),
+		GoVersion:          runtime.Version(),
+		Platform:           runtime.GOOS + "/" + runtime.GOARCH,
+		SDKProtocolVersion: GetSdkProtocolVersion(),
+		ConnectionState:    c.State(),
+		Options:            c.diagnosticOptions(),
+		Sessions:           []DiagnosticSession{},
+		Errors:             make(map[string]string),
+	}
+
+	if bundle.ConnectionState == StateConnected {
+		if status, err := c.GetStatus(ctx); err != nil {
+			bundle.Errors["status"] = err.Error()
+		} else {
+			bundle.CLIVersion = status.Version
+			bundle.CLIProtocolVersion = status.ProtocolVersion
+		}
+		if auth, err := c.GetAuthStatus(ctx); err != nil {
+			bundle.Errors["auth"] = err.Error()
+		} else {
+			bundle.Auth = diagnosticAuth(auth)
+		}
+	} else {
+		bundle.Errors["status"] = "client not connected"
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to collect diagnostic bundle: %w", err)
+	}
+
+	sessions := c.sessions.all()
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].SessionID < sessions[j].SessionID })
+	for _, session := range sessions {
+		bundle.Sessions = append(bundle.Sessions, session.diagnosticState())
+	}
+
+	bundle.StderrTail, bundle.RPCCalls, bundle.HandlerPanics = c.diagnostics.snapshot()
+	bundle.DeprecatedUses = c.diagnostics.deprecatedUseCounts()
+	bundle.UnknownEventTypes = c.diagnostics.unknownEventTypeCounts()
+	if len(bundle.Errors) == 0 {
+		bundle.Errors = nil
+	}
+	if options != nil && options.Redact != nil {
+		bundle.redact(options.Redact)
+	}
+	return bundle, nil
+}
+
+// diagnosticOptions returns the client options with secrets redacted.
+func (c *Client) diagnosticOptions() DiagnosticClientOptions {
+	opts := DiagnosticClientOptions{
+		CLIPath:         c.options.CLIPath,
+		CLIArgs:         redactArgs(c.options.CLIArgs),
+		CLIUrl:          c.options.CLIUrl,
+		Cwd:             c.options.Cwd,
+		Port:            c.options.Port,
+		UseStdio:        c.useStdio,
+		LogLevel:        c.options.LogLevel,
+		AutoStart:       c.autoStart,
+		AutoRestart:     c.autoRestart,
+		HasGitHubToken:  c.options.GitHubToken != "",
+		UseLoggedInUser: c.options.UseLoggedInUser,
+		IntegrationID:   c.options.IntegrationID,
+		Pacing:          c.options.Pacing,
+	}
+	for _, kv := range c.options.Env {
+		name, _, _ := strings.Cut(kv, "=")
+		opts.Env = append(opts.Env, name)
+	}
+	sort.Strings(opts.Env)
+	return opts
+}
+
+// diagnosticAuth converts an auth status, dropping the login.
+func diagnosticAuth(auth *GetAuthStatusResponse) *DiagnosticAuth {
+	result := &DiagnosticAuth{
+		IsAuthenticated: auth.IsAuthenticated,
+		AuthType:        derefString(auth.AuthType),
+		Host:            derefString(auth.Host),
+		StatusMessage:   derefString(auth.StatusMessage),
+	}
+	if login := derefString(auth.Login); login != "" {
+		result.StatusMessage = strings.ReplaceAll(result.StatusMessage, login, redacted)
+	}
+	return result
+}
+
+// redactArgs replaces the values of CLI arguments that look like secrets.
+func redactArgs(args []string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	result := make([]string, len(args))
+	redactNext := false
+	for i, arg := range args {
+		if redactNext {
+			result[i] = redacted
+			redactNext = false
+			continue
+		}
+		result[i] = arg
+		if !strings.HasPrefix(arg, "-") || !isSecretName(arg) {
+			continue
+		}
+		if name, _, found := strings.Cut(arg, "="); found {
+			result[i] = name + "=" + redacted
+		} else {
+			redactNext = true
+		}
+	}
+	return result
+}
+
+// isSecretName reports whether an option or variable name suggests a secret.
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range []string{"token", "secret", "password", "key", "credential"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact applies a redaction hook to every free-form string in the bundle.
+func (b *DiagnosticBundle) redact(fn func(field, value string) string) {
+	b.Options.CLIPath = fn("options.cliPath", b.Options.CLIPath)
+	for i := range b.Options.CLIArgs {
+		b.Options.CLIArgs[i] = fn("options.cliArgs", b.Options.CLIArgs[i])
+	}
+	b.Options.CLIUrl = fn("options.cliUrl", b.Options.CLIUrl)
+	b.Options.Cwd = fn("options.cwd", b.Options.Cwd)
+	if b.Auth != nil {
+		b.Auth.Host = fn("auth.host", b.Auth.Host)
+		b.Auth.StatusMessage = fn("auth.statusMessage", b.Auth.StatusMessage)
+	}
+	for i := range b.Sessions {
+		b.Sessions[i].WorkspacePath = fn("sessions.workspacePath", b.Sessions[i].WorkspacePath)
+		b.Sessions[i].DestroyReason = fn("sessions.destroyReason", b.Sessions[i].DestroyReason)
+	}
+	for i := range b.StderrTail {
+		b.StderrTail[i] = fn("stderrTail", b.StderrTail[i])
+	}
+	for i := range b.HandlerPanics {
+		b.HandlerPanics[i].Message = fn("handlerPanics.message", b.HandlerPanics[i].Message)
+	}
+	for key, value := range b.Errors {
+		b.Errors[key] = fn("errors", value)
+	}
+}
+
+// diagnosticsRecorder keeps the recent history included in diagnostic bundles.
+type diagnosticsRecorder struct {
+	mu      sync.Mutex
+	calls   []DiagnosticRPCCall
+	panics  []DiagnosticPanic
+	stderr  []string
+	partial []byte // stderr written since the last newline
+
+	onDeprecatedUse func(api, callSite string)
+	deprecatedUses  map[string]int
+
+	unknownEventTypes map[string]int
+}
+
+func newDiagnosticsRecorder() *diagnosticsRecorder {
+	return &diagnosticsRecorder{}
+}
+
+// observeCall records a completed JSON-RPC call. It is a jsonrpc2.CallObserver.
+func (r *diagnosticsRecorder) observeCall(c jsonrpc2.Call) {
+	call := DiagnosticRPCCall{
+		Method:    c.Method,
+		RequestID: c.ID,
+		Incoming:  c.Incoming,
+		At:        time.Now().Add(-c.Duration),
+		LatencyMs: float64(c.Duration) / float64(time.Millisecond),
+		Failed:    c.Err != nil,
+	}
+	var rpcErr *jsonrpc2.Error
+	if errors.As(c.Err, &rpcErr) {
+		call.ErrorCode = rpcErr.Code
+	}
+	r.mu.Lock()
+	r.calls = appendBounded(r.calls, call, diagnosticRPCCalls)
+	r.mu.Unlock()
+}
+
+// recordPanic records a panic recovered from a caller-provided handler.
+func (r *diagnosticsRecorder) recordPanic(source, sessionID string, value any) {
+	if r == nil {
+		return
+	}
+	entry := DiagnosticPanic{
+		At:        time.Now(),
+		Source:    source,
+		SessionID: sessionID,
+		Message:   truncateLine(fmt.Sprint(value)),
+	}
+	r.mu.Lock()
+	r.panics = appendBounded(r.panics, entry, diagnosticPanics)
+	r.mu.Unlock()
+}
+
+// Write records CLI stderr output, keeping the last complete lines.
+func (r *diagnosticsRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data := append(r.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimRight(string(data[:i]), "\r")
+		r.stderr = appendBounded(r.stderr, truncateLine(line), diagnosticStderrLines)
+		data = data[i+1:]
+	}
+	if len(data) > diagnosticMaxLineLength {
+		data = data[:diagnosticMaxLineLength]
+	}
+	r.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+// snapshot returns copies of the recorded history.
+func (r *diagnosticsRecorder) snapshot() ([]string, []DiagnosticRPCCall, []DiagnosticPanic) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stderr := append([]string{}, r.stderr...)
+	if len(r.partial) > 0 {
+		stderr = append(stderr, string(r.partial))
+	}
+	return stderr, append([]DiagnosticRPCCall{}, r.calls...), append([]DiagnosticPanic{}, r.panics...)
+}
+
+// appendBounded appends v to s, dropping the oldest entries beyond max.
+func appendBounded[T any](s []T, v T, max int) []T {
+	s = append(s, v)
+	if len(s) > max {
+		s = append(s[:0:0], s[len(s)-max:]...)
+	}
+	return s
+}
+
+// truncateLine shortens s to diagnosticMaxLineLength bytes.
+func truncateLine(s string) string {
+	if len(s) > diagnosticMaxLineLength {
+		return s[:diagnosticMaxLineLength] + "..."
+	}
+	return s
+}
+
+// diagnosticState describes the session for a diagnostic bundle.
+func (s *Session) diagnosticState() DiagnosticSession {
+	result := DiagnosticSession{
+		SessionID:     s.SessionID,
+		WorkspacePath: s.WorkspacePath(),
+	}
+	result.WaitingOnHandler, _ = s.state.handlers()
+	result.DestroyReason, _ = s.DestroyReason()
+
+	s.state.mu.Lock()
+	result.State = s.state.stateLocked()
+	result.Busy = result.State != SessionStateIdle
+	result.LastEventType = s.state.lastEventType
+	if !s.state.lastEventAt.IsZero() {
+		at := s.state.lastEventAt
+		result.LastEventAt = &at
+	}
+	s.state.mu.Unlock()
+	return result
+}
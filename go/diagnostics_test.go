@@ -0,0 +1,237 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+func TestClient_DiagnosticBundle(t *testing.T) {
+	secrets := []string{"sk-env-secret", "ghp_argsecret", "sk-inline-secret", "octocat-login", "my private prompt text"}
+
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "status.get":
+			return GetStatusResponse{Version: "1.2.3", ProtocolVersion: GetSdkProtocolVersion()}, nil
+		case "auth.getStatus":
+			return GetAuthStatusResponse{
+				IsAuthenticated: true,
+				AuthType:        String("user"),
+				Host:            String("github.com"),
+				Login:           String("octocat-login"),
+				StatusMessage:   String("Logged in as octocat-login"),
+			}, nil
+		case "session.create":
+			var req createSessionRequest
+			json.Unmarshal(params, &req)
+			return createSessionResponse{SessionID: req.SessionID}, nil
+		case "session.send":
+			return sessionSendResponse{MessageID: "m1"}, nil
+		}
+		return nil, nil
+	})
+
+	client := NewClient(&ClientOptions{
+		CLIUrl:  cli.addr(),
+		Env:     []string{"API_TOKEN=sk-env-secret", "HOME=/home/me"},
+		CLIArgs: []string{"--auth-token", "ghp_argsecret", "--api-key=sk-inline-secret", "--verbose"},
+	})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	session, err := client.CreateSession(t.Context(), &SessionConfig{SessionID: "s1", OnPermissionRequest: PermissionHandler.ApproveAll})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if _, err := session.Send(t.Context(), MessageOptions{Prompt: "my private prompt text"}); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+
+	bundle, err := client.DiagnosticBundle(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to collect bundle: %v", err)
+	}
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatalf("Failed to marshal bundle: %v", err)
+	}
+
+	t.Run("does not leak secrets or prompt text", func(t *testing.T) {
+		for _, secret := range secrets {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("Expected bundle not to contain %q: %s", secret, data)
+			}
+		}
+	})
+
+	t.Run("includes CLI, auth, and option details", func(t *testing.T) {
+		if bundle.CLIVersion != "1.2.3" || bundle.CLIProtocolVersion != GetSdkProtocolVersion() {
+			t.Errorf("Unexpected CLI version info: %q %d", bundle.CLIVersion, bundle.CLIProtocolVersion)
+		}
+		if bundle.Auth == nil || !bundle.Auth.IsAuthenticated || bundle.Auth.Host != "github.com" {
+			t.Errorf("Unexpected auth: %+v", bundle.Auth)
+		}
+		if strings.Join(bundle.Options.Env, ",") != "API_TOKEN,HOME" {
+			t.Errorf("Expected env names only, got %v", bundle.Options.Env)
+		}
+		want := []string{"--auth-token", redacted, "--api-key=" + redacted, "--verbose"}
+		if strings.Join(bundle.Options.CLIArgs, " ") != strings.Join(want, " ") {
+			t.Errorf("Expected %v, got %v", want, bundle.Options.CLIArgs)
+		}
+		if bundle.Errors != nil {
+			t.Errorf("Expected no collection errors, got %v", bundle.Errors)
+		}
+	})
+
+	t.Run("includes session state and RPC summary", func(t *testing.T) {
+		if len(bundle.Sessions) != 1 || bundle.Sessions[0].SessionID != "s1" || !bundle.Sessions[0].Busy {
+			t.Errorf("Expected busy session s1, got %+v", bundle.Sessions)
+		}
+		methods := map[string]bool{}
+		for _, call := range bundle.RPCCalls {
+			methods[call.Method] = true
+		}
+		for _, method := range []string{"ping", "session.create", "session.send"} {
+			if !methods[method] {
+				t.Errorf("Expected %s in RPC summary, got %+v", method, bundle.RPCCalls)
+			}
+		}
+	})
+}
+
+func TestClient_DiagnosticBundleWithOptions(t *testing.T) {
+	t.Run("reports disconnected clients without failing", func(t *testing.T) {
+		client := NewClient(&ClientOptions{GitHubToken: "ghp_tokensecret"})
+		bundle, err := client.DiagnosticBundle(t.Context())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if bundle.ConnectionState != StateDisconnected || bundle.Errors["status"] == "" {
+			t.Errorf("Expected disconnected bundle with a status error, got %+v", bundle)
+		}
+		if !bundle.Options.HasGitHubToken {
+			t.Error("Expected HasGitHubToken to be set")
+		}
+		data, _ := json.Marshal(bundle)
+		if strings.Contains(string(data), "ghp_tokensecret") {
+			t.Errorf("Expected token to be redacted: %s", data)
+		}
+	})
+
+	t.Run("records handler panics and applies the redaction hook", func(t *testing.T) {
+		client := NewClient(nil)
+		session := newSession("s1", nil, "")
+		session.diagnostics = client.diagnostics
+		client.sessions.put(session)
+		session.On(func(event SessionEvent) {
+			panic("failed to handle " + *event.Data.Content)
+		})
+		session.dispatchEvent(SessionEvent{Type: AssistantMessage, Data: Data{Content: String("secret answer")}})
+		client.On(func(SessionLifecycleEvent) { panic("lifecycle boom") })
+		client.handleLifecycleEvent(SessionLifecycleEvent{Type: SessionLifecycleCreated, SessionID: "s1"})
+		client.diagnostics.Write([]byte("warning: secret stderr\npartial"))
+
+		fields := map[string]bool{}
+		bundle, err := client.DiagnosticBundleWithOptions(t.Context(), &DiagnosticBundleOptions{
+			Redact: func(field, value string) string {
+				fields[field] = true
+				return strings.ReplaceAll(value, "secret", "***")
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if len(bundle.HandlerPanics) != 2 {
+			t.Fatalf("Expected 2 panics, got %+v", bundle.HandlerPanics)
+		}
+		if p := bundle.HandlerPanics[0]; p.Source != "event handler" || p.SessionID != "s1" || p.Message != "failed to handle *** answer" {
+			t.Errorf("Unexpected event handler panic: %+v", p)
+		}
+		if p := bundle.HandlerPanics[1]; p.Source != "lifecycle handler" || p.Message != "lifecycle boom" {
+			t.Errorf("Unexpected lifecycle handler panic: %+v", p)
+		}
+		if strings.Join(bundle.StderrTail, "|") != "warning: *** stderr|partial" {
+			t.Errorf("Unexpected stderr tail: %q", bundle.StderrTail)
+		}
+		if bundle.Sessions[0].LastEventType != AssistantMessage {
+			t.Errorf("Expected last event type, got %+v", bundle.Sessions[0])
+		}
+		for _, field := range []string{"handlerPanics.message", "stderrTail", "errors", "options.cliPath"} {
+			if !fields[field] {
+				t.Errorf("Expected redaction hook to see %s", field)
+			}
+		}
+	})
+
+	t.Run("keeps only the most recent stderr lines", func(t *testing.T) {
+		recorder := newDiagnosticsRecorder()
+		for i := 0; i < diagnosticStderrLines+10; i++ {
+			recorder.Write([]byte("line\r\n"))
+		}
+		stderr, _, _ := recorder.snapshot()
+		if len(stderr) != diagnosticStderrLines || stderr[0] != "line" {
+			t.Errorf("Expected %d lines, got %d", diagnosticStderrLines, len(stderr))
+		}
+	})
+}
+
+func TestClient_RequestIDs(t *testing.T) {
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		if method == "status.get" {
+			return nil, &jsonrpc2.Error{Code: -32000, Message: "status unavailable"}
+		}
+		return nil, nil
+	})
+
+	var mu sync.Mutex
+	var calls []RPCCall
+	next := 0
+	client := NewClient(&ClientOptions{
+		CLIUrl: cli.addr(),
+		RequestIDGenerator: func() string {
+			mu.Lock()
+			defer mu.Unlock()
+			next++
+			return fmt.Sprintf("test-%d", next)
+		},
+		OnRPCCall: func(call RPCCall) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, call)
+		},
+	})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start client: %v", err)
+	}
+
+	_, err := client.GetStatus(t.Context())
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Method != "status.get" || rpcErr.RequestID != "test-2" {
+		t.Fatalf("Expected an RPCError for status.get with request test-2, got %v", err)
+	}
+
+	mu.Lock()
+	observed := slices.Clone(calls)
+	mu.Unlock()
+	if len(observed) != 2 || observed[0].Method != "ping" || observed[0].RequestID != "test-1" ||
+		observed[1].RequestID != "test-2" || observed[1].Err == nil {
+		t.Errorf("Unexpected observed calls: %+v", observed)
+	}
+
+	bundle, err := client.DiagnosticBundle(t.Context())
+	if err != nil {
+		t.Fatalf("DiagnosticBundle failed: %v", err)
+	}
+	if call := bundle.RPCCalls[1]; call.Method != "status.get" || call.RequestID != "test-2" || !call.Failed {
+		t.Errorf("Expected the failed call with its request ID, got %+v", bundle.RPCCalls)
+	}
+}
@@ -0,0 +1,376 @@
+// Package diffutil normalizes the file changes the Copilot CLI reports into
+// unified diffs.
+//
+// Write permission requests and edit tool calls describe a change as a patch
+// in one of several dialects, or as the text before and after it. [Parse]
+// reads patches, from git's multi-file output down to bare lines prefixed
+// with + and -, and [Compute] diffs two texts. Both return a [FileDiff] with
+// numbered hunks for rendering side by side, and [FileDiff.Unified] renders
+// it as a unified diff. CRLF line endings, files without a trailing newline
+// and binary content are handled the same way on both paths.
+//
+// Example:
+//
+//	diff := diffutil.Compute("main.go", before, after)
+//	added, removed := diff.Stats()
+//	fmt.Printf("main.go: +%d -%d\n%s", added, removed, diff.Unified())
+package diffutil
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// contextLines is the number of unchanged lines [Compute] keeps around each
+// change.
+const contextLines = 3
+
+// maxEditDistance bounds the work spent finding the shortest diff. Texts
+// that differ in more lines are diffed as their changed region removed and
+// added as a whole.
+const maxEditDistance = 2000
+
+// LineKind tells whether a line of a hunk is unchanged, added or removed.
+type LineKind string
+
+const (
+	Context LineKind = "context"
+	Added   LineKind = "added"
+	Removed LineKind = "removed"
+)
+
+// Line is a line of a hunk.
+type Line struct {
+	Kind LineKind `json:"kind"`
+	// Text is the line without its line ending
+	Text string `json:"text"`
+	// OldLine is the line's number in the old file, or zero for added lines
+	OldLine int `json:"oldLine,omitempty"`
+	// NewLine is the line's number in the new file, or zero for removed lines
+	NewLine int `json:"newLine,omitempty"`
+	// NoNewline is set on the last line of a file that does not end with a
+	// newline
+	NoNewline bool `json:"noNewline,omitempty"`
+}
+
+// Hunk is a run of changed lines with the unchanged lines around them.
+// Starts are 1-based line numbers; a side with no lines starts at the line
+// before the hunk, as in a unified diff header.
+type Hunk struct {
+	OldStart int    `json:"oldStart"`
+	OldLines int    `json:"oldLines"`
+	NewStart int    `json:"newStart"`
+	NewLines int    `json:"newLines"`
+	Lines    []Line `json:"lines"`
+}
+
+// FileDiff is the change of one file.
+type FileDiff struct {
+	// OldPath and NewPath name the file before and after the change, without
+	// git's a/ and b/ prefixes. A created or deleted file is named
+	// /dev/null on its missing side in parsed patches.
+	OldPath string `json:"oldPath,omitempty"`
+	NewPath string `json:"newPath,omitempty"`
+	// Binary is set for changes of binary files, which have no hunks
+	Binary bool `json:"binary,omitempty"`
+	// LineEndingsChanged is set by [Compute] when one text uses CRLF line
+	// endings and the other does not. Hunks compare lines without their line
+	// endings, so a change of line endings alone has no hunks.
+	LineEndingsChanged bool   `json:"lineEndingsChanged,omitempty"`
+	Hunks              []Hunk `json:"hunks,omitempty"`
+}
+
+// Empty reports whether the diff shows no change.
+func (d FileDiff) Empty() bool {
+	return !d.Binary && len(d.Hunks) == 0
+}
+
+// Stats counts the added and removed lines.
+func (d FileDiff) Stats() (added, removed int) {
+	for _, hunk := range d.Hunks {
+		for _, line := range hunk.Lines {
+			switch line.Kind {
+			case Added:
+				added++
+			case Removed:
+				removed++
+			}
+		}
+	}
+	return added, removed
+}
+
+// Unified renders the diff in unified format with LF line endings, or returns
+// "" for an empty diff. The ---/+++ header is omitted when the diff names no
+// paths.
+func (d FileDiff) Unified() string {
+	if d.Empty() {
+		return ""
+	}
+	var b strings.Builder
+	if d.Binary {
+		fmt.Fprintf(&b, "Binary files %s and %s differ\n", orDevNull(d.OldPath), orDevNull(d.NewPath))
+		return b.String()
+	}
+	if d.OldPath != "" || d.NewPath != "" {
+		fmt.Fprintf(&b, "--- %s\n+++ %s\n", orDevNull(d.OldPath), orDevNull(d.NewPath))
+	}
+	for _, hunk := range d.Hunks {
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(hunk.OldStart, hunk.OldLines), hunkRange(hunk.NewStart, hunk.NewLines))
+		for _, line := range hunk.Lines {
+			switch line.Kind {
+			case Added:
+				b.WriteByte('+')
+			case Removed:
+				b.WriteByte('-')
+			default:
+				b.WriteByte(' ')
+			}
+			b.WriteString(line.Text)
+			b.WriteByte('\n')
+			if line.NoNewline {
+				b.WriteString("\\ No newline at end of file\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+func orDevNull(path string) string {
+	if path == "" {
+		return "/dev/null"
+	}
+	return path
+}
+
+// hunkRange formats one side of a hunk header, omitting a count of one.
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// Compute diffs the text of a file before and after a change. Lines are
+// compared without their line endings, and a file that gains or loses its
+// trailing newline shows its last line as changed. Texts containing NUL
+// bytes or invalid UTF-8 are treated as binary.
+func Compute(path, before, after string) FileDiff {
+	diff := FileDiff{OldPath: path, NewPath: path}
+	if isBinary(before) || isBinary(after) {
+		diff.Binary = before != after
+		return diff
+	}
+	diff.LineEndingsChanged = before != "" && after != "" &&
+		strings.Contains(before, "\r\n") != strings.Contains(after, "\r\n")
+	diff.Hunks = buildHunks(diffLines(splitLines(before), splitLines(after)))
+	return diff
+}
+
+// isBinary applies git's heuristic of a NUL byte in the first 8000 bytes,
+// and also rejects invalid UTF-8.
+func isBinary(s string) bool {
+	return bytes.IndexByte([]byte(s[:min(len(s), 8000)]), 0) >= 0 || !utf8.ValidString(s)
+}
+
+// line is a line of text and whether it ended with a newline.
+type line struct {
+	text string
+	eol  bool
+}
+
+// splitLines splits text into lines, dropping CR before LF.
+func splitLines(s string) []line {
+	if s == "" {
+		return nil
+	}
+	parts := strings.SplitAfter(s, "\n")
+	if parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	lines := make([]line, len(parts))
+	for i, part := range parts {
+		text, eol := strings.CutSuffix(part, "\n")
+		if eol {
+			text = strings.TrimSuffix(text, "\r")
+		}
+		lines[i] = line{text: text, eol: eol}
+	}
+	return lines
+}
+
+// edit is a line of the diff of two texts, with its index in each. An added
+// line's old index, and a removed line's new index, is the position in that
+// text where the line would be.
+type edit struct {
+	kind     LineKind
+	old, new int
+	line     line
+}
+
+// diffLines returns the shortest edit script from a to b, found with Myers'
+// algorithm after trimming the common prefix and suffix.
+func diffLines(a, b []line) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]edit, 0, len(a)+len(b))
+	for i := range prefix {
+		edits = append(edits, edit{kind: Context, old: i, new: i, line: a[i]})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix, prefix)...)
+	for i := range suffix {
+		oldIndex, newIndex := len(a)-suffix+i, len(b)-suffix+i
+		edits = append(edits, edit{kind: Context, old: oldIndex, new: newIndex, line: a[oldIndex]})
+	}
+	return edits
+}
+
+// myers diffs a and b, whose first lines are at oldOffset and newOffset in
+// their texts.
+func myers(a, b []line, oldOffset, newOffset int) []edit {
+	n, m := len(a), len(b)
+	// v[k+offset] is the furthest x reached on diagonal k; trace[d] keeps
+	// diagonals -d..d as they were before round d, for backtracking
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	found := false
+	for d := 0; d <= n+m && d <= maxEditDistance && !found; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return replaceAll(a, b, oldOffset, newOffset)
+	}
+
+	var reversed []edit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		prev := trace[d]
+		at := func(k int) int { return prev[k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, edit{kind: Context, old: oldOffset + x, new: newOffset + y, line: a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, edit{kind: Added, old: oldOffset + x, new: newOffset + prevY, line: b[prevY]})
+			} else {
+				reversed = append(reversed, edit{kind: Removed, old: oldOffset + prevX, new: newOffset + y, line: a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	edits := make([]edit, len(reversed))
+	for i, e := range reversed {
+		edits[len(reversed)-1-i] = e
+	}
+	return edits
+}
+
+// replaceAll diffs a and b as all of a removed and all of b added.
+func replaceAll(a, b []line, oldOffset, newOffset int) []edit {
+	edits := make([]edit, 0, len(a)+len(b))
+	for i, l := range a {
+		edits = append(edits, edit{kind: Removed, old: oldOffset + i, new: newOffset, line: l})
+	}
+	for i, l := range b {
+		edits = append(edits, edit{kind: Added, old: oldOffset + len(a), new: newOffset + i, line: l})
+	}
+	return edits
+}
+
+// buildHunks groups the changes of an edit script with up to contextLines
+// unchanged lines around them, merging changes whose context overlaps.
+func buildHunks(edits []edit) []Hunk {
+	var hunks []Hunk
+	for i := 0; i < len(edits); {
+		if edits[i].kind == Context {
+			i++
+			continue
+		}
+		start := max(0, i-contextLines)
+		// Extend past changes separated by at most 2*contextLines unchanged
+		// lines, then past the trailing context
+		end := i
+		for {
+			for end < len(edits) && edits[end].kind != Context {
+				end++
+			}
+			next := end
+			for next < len(edits) && edits[next].kind == Context {
+				next++
+			}
+			if next == len(edits) || next-end > 2*contextLines {
+				end = min(len(edits), end+contextLines)
+				break
+			}
+			end = next
+		}
+		hunks = append(hunks, newHunk(edits[start:end]))
+		i = end
+	}
+	return hunks
+}
+
+func newHunk(edits []edit) Hunk {
+	hunk := Hunk{OldStart: edits[0].old, NewStart: edits[0].new}
+	for _, e := range edits {
+		l := Line{Kind: e.kind, Text: e.line.text, NoNewline: !e.line.eol}
+		if e.kind != Added {
+			hunk.OldLines++
+			l.OldLine = e.old + 1
+		}
+		if e.kind != Removed {
+			hunk.NewLines++
+			l.NewLine = e.new + 1
+		}
+		hunk.Lines = append(hunk.Lines, l)
+	}
+	if hunk.OldLines > 0 {
+		hunk.OldStart++
+	}
+	if hunk.NewLines > 0 {
+		hunk.NewStart++
+	}
+	return hunk
+}
@@ -0,0 +1,123 @@
+package diffutil
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares the unified diff and hunks of files with want.diff
+// and want.json in dir.
+func checkGolden(t *testing.T, dir string, files []FileDiff) {
+	t.Helper()
+	var unified strings.Builder
+	for _, file := range files {
+		unified.WriteString(file.Unified())
+	}
+	hunks, err := json.MarshalIndent(files, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{"want.diff": unified.String(), "want.json": string(hunks) + "\n"}
+	for name, content := range got {
+		path := filepath.Join(dir, name)
+		if *update {
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("No golden file; run go test ./diffutil -update: %v", err)
+		}
+		if content != string(want) {
+			t.Errorf("%s changed; if intended, run go test ./diffutil -update and review the diff.\nGot:\n%s", path, content)
+		}
+	}
+}
+
+func fixtures(t *testing.T, kind string) []string {
+	dirs, err := filepath.Glob(filepath.Join("testdata", kind, "*"))
+	if err != nil || len(dirs) == 0 {
+		t.Fatalf("No %s fixtures: %v", kind, err)
+	}
+	return dirs
+}
+
+func readFixture(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCompute(t *testing.T) {
+	for _, dir := range fixtures(t, "compute") {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			diff := Compute("file.txt", readFixture(t, dir, "before"), readFixture(t, dir, "after"))
+			checkGolden(t, dir, []FileDiff{diff})
+
+			// The rendered diff reads back as the same hunks
+			if diff.Binary || diff.Empty() {
+				return
+			}
+			parsed, err := Parse(diff.Unified())
+			if err != nil {
+				t.Fatalf("Failed to parse the rendered diff: %v", err)
+			}
+			if len(parsed) != 1 || !sameHunks(parsed[0].Hunks, diff.Hunks) {
+				t.Errorf("Expected the rendered diff to parse back to its hunks, got %+v", parsed)
+			}
+		})
+	}
+}
+
+func sameHunks(a, b []Hunk) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
+
+func TestParse(t *testing.T) {
+	for _, dir := range fixtures(t, "parse") {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			files, err := Parse(readFixture(t, dir, "patch"))
+			if err != nil {
+				t.Fatalf("Failed to parse: %v", err)
+			}
+			checkGolden(t, dir, files)
+		})
+	}
+
+	t.Run("rejects malformed hunks", func(t *testing.T) {
+		for _, patch := range []string{
+			"@@ -1,x +1 @@\n-a\n",
+			"@@ -1,2 +1,2 @@\n-a\n+b\n",
+			"@@ -1 +1 @@\n-a\n-b\n",
+		} {
+			if _, err := Parse(patch); err == nil {
+				t.Errorf("Expected an error for %q", patch)
+			}
+		}
+	})
+}
+
+func TestCompute_LargeRewrite(t *testing.T) {
+	var before, after strings.Builder
+	for i := range maxEditDistance + 100 {
+		before.WriteString("old " + strings.Repeat("x", i%7) + "\n")
+		after.WriteString("new " + strings.Repeat("y", i%5) + "\n")
+	}
+	diff := Compute("big.txt", before.String(), after.String())
+	if added, removed := diff.Stats(); added != maxEditDistance+100 || removed != maxEditDistance+100 {
+		t.Errorf("Expected every line replaced, got +%d -%d", added, removed)
+	}
+}
@@ -0,0 +1,206 @@
+package diffutil
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// Parse reads the file changes of a patch. It accepts unified diffs with or
+// without git's headers, several files in one patch, and bare lines prefixed
+// with +, - and space with no hunk header, which are numbered from line 1.
+// CRLF line endings are accepted, binary changes reported by git are marked
+// [FileDiff.Binary], and lines outside of files and hunks, such as git's
+// index lines, are ignored. Text without any diff yields no files.
+//
+// Parse fails if a hunk header is malformed or a hunk has fewer lines than
+// its header counts.
+func Parse(patch string) ([]FileDiff, error) {
+	if patch == "" {
+		return nil, nil
+	}
+	p := parser{lines: strings.Split(strings.TrimSuffix(patch, "\n"), "\n")}
+	for i := range p.lines {
+		p.lines[i] = strings.TrimSuffix(p.lines[i], "\r")
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.files, nil
+}
+
+type parser struct {
+	lines []string
+	pos   int
+	files []FileDiff
+	// headed is set when the current file has its ---/+++ header
+	headed bool
+}
+
+// file returns the file being parsed, starting one if there is none.
+func (p *parser) file() *FileDiff {
+	if len(p.files) == 0 {
+		p.startFile()
+	}
+	return &p.files[len(p.files)-1]
+}
+
+func (p *parser) startFile() {
+	p.files = append(p.files, FileDiff{})
+	p.headed = false
+}
+
+func (p *parser) parse() error {
+	for p.pos < len(p.lines) {
+		text := p.lines[p.pos]
+		switch {
+		case strings.HasPrefix(text, "diff --git "):
+			p.startFile()
+			if oldPath, newPath, ok := strings.Cut(strings.TrimPrefix(text, "diff --git "), " b/"); ok {
+				p.file().OldPath, p.file().NewPath = strings.TrimPrefix(oldPath, "a/"), newPath
+			}
+			p.pos++
+		case strings.HasPrefix(text, "--- ") && p.pos+1 < len(p.lines) && strings.HasPrefix(p.lines[p.pos+1], "+++ "):
+			if len(p.files) == 0 || p.headed || len(p.file().Hunks) > 0 {
+				p.startFile()
+			}
+			file := p.file()
+			file.OldPath, file.NewPath = headerPaths(text[4:], p.lines[p.pos+1][4:])
+			p.headed = true
+			p.pos += 2
+		case strings.HasPrefix(text, "Binary files ") || text == "GIT binary patch":
+			p.file().Binary = true
+			p.pos++
+		case strings.HasPrefix(text, "@@"):
+			if err := p.parseHunk(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(text, "+") || strings.HasPrefix(text, "-"):
+			p.parseBareHunk()
+		default:
+			p.pos++
+		}
+	}
+	return nil
+}
+
+// headerPaths returns the paths of a ---/+++ header, without timestamps and
+// without git's a/ and b/ prefixes.
+func headerPaths(oldPath, newPath string) (string, string) {
+	oldPath, _, _ = strings.Cut(oldPath, "\t")
+	newPath, _, _ = strings.Cut(newPath, "\t")
+	if (strings.HasPrefix(oldPath, "a/") || oldPath == "/dev/null") && (strings.HasPrefix(newPath, "b/") || newPath == "/dev/null") {
+		oldPath, newPath = strings.TrimPrefix(oldPath, "a/"), strings.TrimPrefix(newPath, "b/")
+	}
+	return oldPath, newPath
+}
+
+// parseHunk reads a hunk with a header, numbering its lines from it.
+func (p *parser) parseHunk() error {
+	match := hunkHeader.FindStringSubmatch(p.lines[p.pos])
+	if match == nil {
+		return fmt.Errorf("line %d: malformed hunk header %q", p.pos+1, p.lines[p.pos])
+	}
+	number := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	hunk := Hunk{OldStart: number(match[1]), OldLines: number(match[2]), NewStart: number(match[3]), NewLines: number(match[4])}
+	p.pos++
+
+	oldLine, newLine := hunk.OldStart, hunk.NewStart
+	oldLeft, newLeft := hunk.OldLines, hunk.NewLines
+	for oldLeft > 0 || newLeft > 0 || p.noNewlineMarker() {
+		if p.pos >= len(p.lines) {
+			return fmt.Errorf("line %d: hunk ends %d old and %d new lines early", p.pos+1, oldLeft, newLeft)
+		}
+		text := p.lines[p.pos]
+		var l Line
+		switch {
+		case p.noNewlineMarker():
+			if len(hunk.Lines) > 0 {
+				hunk.Lines[len(hunk.Lines)-1].NoNewline = true
+			}
+			p.pos++
+			continue
+		case strings.HasPrefix(text, "+") && newLeft > 0:
+			l = Line{Kind: Added, Text: text[1:], NewLine: newLine}
+			newLine++
+			newLeft--
+		case strings.HasPrefix(text, "-") && oldLeft > 0:
+			l = Line{Kind: Removed, Text: text[1:], OldLine: oldLine}
+			oldLine++
+			oldLeft--
+		// Some tools strip the space of empty context lines
+		case (strings.HasPrefix(text, " ") || text == "") && oldLeft > 0 && newLeft > 0:
+			l = Line{Kind: Context, Text: strings.TrimPrefix(text, " "), OldLine: oldLine, NewLine: newLine}
+			oldLine++
+			newLine++
+			oldLeft--
+			newLeft--
+		default:
+			return fmt.Errorf("line %d: hunk ends %d old and %d new lines early", p.pos+1, oldLeft, newLeft)
+		}
+		hunk.Lines = append(hunk.Lines, l)
+		p.pos++
+	}
+	file := p.file()
+	file.Hunks = append(file.Hunks, hunk)
+	return nil
+}
+
+// parseBareHunk reads lines prefixed with +, - and space that have no hunk
+// header, numbering them from line 1.
+func (p *parser) parseBareHunk() {
+	var hunk Hunk
+	for p.pos < len(p.lines) {
+		text := p.lines[p.pos]
+		if strings.HasPrefix(text, "--- ") && p.pos+1 < len(p.lines) && strings.HasPrefix(p.lines[p.pos+1], "+++ ") {
+			break
+		}
+		switch {
+		case p.noNewlineMarker():
+			if len(hunk.Lines) > 0 {
+				hunk.Lines[len(hunk.Lines)-1].NoNewline = true
+			}
+		case strings.HasPrefix(text, "+"):
+			hunk.NewLines++
+			hunk.Lines = append(hunk.Lines, Line{Kind: Added, Text: text[1:], NewLine: hunk.NewLines})
+		case strings.HasPrefix(text, "-"):
+			hunk.OldLines++
+			hunk.Lines = append(hunk.Lines, Line{Kind: Removed, Text: text[1:], OldLine: hunk.OldLines})
+		case strings.HasPrefix(text, " "):
+			hunk.OldLines++
+			hunk.NewLines++
+			hunk.Lines = append(hunk.Lines, Line{Kind: Context, Text: text[1:], OldLine: hunk.OldLines, NewLine: hunk.NewLines})
+		default:
+			p.appendBareHunk(hunk)
+			return
+		}
+		p.pos++
+	}
+	p.appendBareHunk(hunk)
+}
+
+func (p *parser) appendBareHunk(hunk Hunk) {
+	if hunk.OldLines > 0 {
+		hunk.OldStart = 1
+	}
+	if hunk.NewLines > 0 {
+		hunk.NewStart = 1
+	}
+	file := p.file()
+	file.Hunks = append(file.Hunks, hunk)
+}
+
+// noNewlineMarker reports whether the current line is a "\ No newline at end
+// of file" marker.
+func (p *parser) noNewlineMarker() bool {
+	return p.pos < len(p.lines) && strings.HasPrefix(p.lines[p.pos], `\`)
+}
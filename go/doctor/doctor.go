@@ -0,0 +1,312 @@
+// Package doctor checks that a Copilot CLI environment works with the SDK.
+//
+// It discovers and starts the CLI, verifies the connection, authentication
+// and model list, and creates and destroys a throwaway session, reporting
+// each step with a remediation hint on failure. It uses only the public API
+// of the copilot package, so services can run the same checks the
+// copilot-doctor command does.
+//
+// Example:
+//
+//	report := doctor.Run(ctx, nil)
+//	report.WriteText(os.Stdout)
+//	if !report.OK() {
+//	    os.Exit(1)
+//	}
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	// StatusSkip means the check did not run because an earlier one failed
+	StatusSkip Status = "skip"
+)
+
+// Check is the result of one check.
+type Check struct {
+	// Name identifies the check, e.g. "start" or "auth"
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	// Detail describes what was found
+	Detail string `json:"detail,omitempty"`
+	// Hint suggests how to fix a warning or failure
+	Hint     string        `json:"hint,omitempty"`
+	Duration time.Duration `json:"durationNs"`
+}
+
+// Report is the result of [Run]. Its text is redacted: tokens and proxy
+// credentials never appear in it.
+type Report struct {
+	Checks []Check `json:"checks"`
+	// CLIVersion is the version the CLI reported, if it was reached
+	CLIVersion string `json:"cliVersion,omitempty"`
+}
+
+// OK reports whether no check failed. Warnings do not count as failures.
+func (r *Report) OK() bool {
+	for _, check := range r.Checks {
+		if check.Status == StatusFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Options configures [Run].
+type Options struct {
+	// ClientOptions configures the client under test (default: the same
+	// defaults as copilot.NewClient(nil))
+	ClientOptions *copilot.ClientOptions
+	// Model is used for the throwaway session (default: the CLI's default)
+	Model string
+	// SkipSession skips creating the throwaway session
+	SkipSession bool
+	// CheckTimeout bounds each check (default: 30 seconds)
+	CheckTimeout time.Duration
+}
+
+const defaultCheckTimeout = 30 * time.Second
+
+// minNodeMajor is the oldest Node.js version the CLI supports.
+const minNodeMajor = 20
+
+// Run runs every check and returns the report. Checks that depend on a
+// failed one are skipped. Run does not return an error: failures are
+// reported in the report.
+func Run(ctx context.Context, options *Options) *Report {
+	var opts Options
+	if options != nil {
+		opts = *options
+	}
+	if opts.CheckTimeout <= 0 {
+		opts.CheckTimeout = defaultCheckTimeout
+	}
+	var clientOptions copilot.ClientOptions
+	if opts.ClientOptions != nil {
+		clientOptions = *opts.ClientOptions
+	}
+
+	r := &runner{ctx: ctx, timeout: opts.CheckTimeout, report: &Report{}}
+	r.run("environment", func(context.Context) (Status, string, string, error) {
+		return checkEnvironment(clientOptions)
+	})
+
+	client := copilot.NewClient(&clientOptions)
+	defer client.ForceStop()
+
+	r.run("start", func(ctx context.Context) (Status, string, string, error) {
+		if err := client.Start(ctx); err != nil {
+			return StatusFail, "", startHint(err), err
+		}
+		if clientOptions.CLIUrl != "" {
+			return StatusPass, "connected to " + clientOptions.CLIUrl, "", nil
+		}
+		return StatusPass, "CLI started", "", nil
+	})
+	r.run("ping", func(ctx context.Context) (Status, string, string, error) {
+		resp, err := client.Ping(ctx, "doctor")
+		if err != nil {
+			return StatusFail, "", "The CLI started but does not answer; rerun with LogLevel \"debug\" and check its stderr.", err
+		}
+		return StatusPass, fmt.Sprintf("protocol version %d", derefInt(resp.ProtocolVersion)), "", nil
+	})
+	r.run("status", func(ctx context.Context) (Status, string, string, error) {
+		status, err := client.GetStatus(ctx)
+		if err != nil {
+			return StatusFail, "", "Update the Copilot CLI to a version that matches this SDK.", err
+		}
+		r.report.CLIVersion = status.Version
+		return StatusPass, "CLI version " + status.Version, "", nil
+	})
+	r.run("auth", func(ctx context.Context) (Status, string, string, error) {
+		auth, err := client.GetAuthStatus(ctx)
+		if err != nil {
+			return StatusFail, "", "", err
+		}
+		if !auth.IsAuthenticated {
+			detail := "not signed in"
+			if auth.StatusMessage != nil {
+				detail += ": " + *auth.StatusMessage
+			}
+			return StatusFail, detail, "Sign in by running the Copilot CLI and using /login, or set ClientOptions.GitHubToken (or GH_TOKEN) to a token with Copilot access.", nil
+		}
+		detail := "signed in"
+		if auth.AuthType != nil {
+			detail += " with " + *auth.AuthType
+		}
+		if auth.Host != nil {
+			detail += " on " + *auth.Host
+		}
+		return StatusPass, detail, "", nil
+	})
+	r.run("models", func(ctx context.Context) (Status, string, string, error) {
+		models, err := client.ListModels(ctx)
+		if err != nil {
+			return StatusFail, "", "Check that your Copilot subscription and organization policies allow model access.", err
+		}
+		if len(models) == 0 {
+			return StatusWarn, "no models available", "Check that your Copilot subscription and organization policies allow model access.", nil
+		}
+		if opts.Model != "" && !hasModel(models, opts.Model) {
+			return StatusFail, fmt.Sprintf("model %s is not available (%d models listed)", opts.Model, len(models)), "Pick one of the models ListModels returns.", nil
+		}
+		return StatusPass, fmt.Sprintf("%d models available", len(models)), "", nil
+	})
+	if !opts.SkipSession {
+		r.run("session", func(ctx context.Context) (Status, string, string, error) {
+			session, err := client.CreateSession(ctx, &copilot.SessionConfig{
+				Model: opts.Model,
+				OnPermissionRequest: func(copilot.PermissionRequest, copilot.PermissionInvocation) (copilot.PermissionRequestResult, error) {
+					return copilot.DeniedNoApprovalRule(), nil
+				},
+			})
+			if err != nil {
+				return StatusFail, "", "Sessions cannot be created; check the CLI's stderr and your model settings.", err
+			}
+			if err := session.Destroy(); err != nil {
+				return StatusWarn, "created a session but could not destroy it", "", err
+			}
+			if err := client.DeleteSession(ctx, session.SessionID); err != nil {
+				return StatusWarn, "created and destroyed a session but could not delete it", "", err
+			}
+			return StatusPass, "created and destroyed a session", "", nil
+		})
+	}
+	return r.report
+}
+
+// runner runs checks in order, skipping the rest once one fails.
+type runner struct {
+	ctx     context.Context
+	timeout time.Duration
+	report  *Report
+	failed  string
+}
+
+func (r *runner) run(name string, check func(ctx context.Context) (Status, string, string, error)) {
+	if r.failed != "" {
+		r.report.Checks = append(r.report.Checks, Check{Name: name, Status: StatusSkip, Detail: "skipped because " + r.failed + " failed"})
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+	defer cancel()
+	start := time.Now()
+	status, detail, hint, err := check(ctx)
+	if err != nil {
+		if detail != "" {
+			detail += ": "
+		}
+		detail += err.Error()
+	}
+	r.report.Checks = append(r.report.Checks, Check{
+		Name:     name,
+		Status:   status,
+		Detail:   Redact(detail),
+		Hint:     hint,
+		Duration: time.Since(start),
+	})
+	if status == StatusFail {
+		r.failed = name
+	}
+}
+
+// checkEnvironment reports how the CLI will be found and the proxy
+// settings, and checks the Node.js version when the CLI is a script.
+func checkEnvironment(options copilot.ClientOptions) (Status, string, string, error) {
+	var details []string
+	cliPath := options.CLIPath
+	switch {
+	case options.CLIUrl != "":
+		details = append(details, "external server "+options.CLIUrl)
+	case cliPath != "":
+		details = append(details, "CLI path "+cliPath)
+	case os.Getenv("COPILOT_CLI_PATH") != "":
+		cliPath = os.Getenv("COPILOT_CLI_PATH")
+		details = append(details, "CLI path "+cliPath+" (COPILOT_CLI_PATH)")
+	default:
+		if path, err := exec.LookPath("copilot"); err == nil {
+			details = append(details, "CLI on PATH at "+path)
+		} else {
+			details = append(details, "no CLI path set and copilot is not on PATH; the embedded CLI is used if the application bundles one")
+		}
+	}
+	for _, name := range []string{"HTTPS_PROXY", "HTTP_PROXY", "NO_PROXY"} {
+		if value := getenvAnyCase(name); value != "" {
+			details = append(details, name+"="+value)
+		}
+	}
+
+	if strings.HasSuffix(cliPath, ".js") {
+		status, detail, hint := checkNode()
+		details = append(details, detail)
+		return status, strings.Join(details, "; "), hint, nil
+	}
+	return StatusPass, strings.Join(details, "; "), "", nil
+}
+
+// checkNode checks that node is installed and recent enough to run the CLI.
+func checkNode() (Status, string, string) {
+	out, err := exec.Command("node", "--version").Output()
+	if err != nil {
+		return StatusFail, "node not found", fmt.Sprintf("Install Node.js %d or later, or use a native CLI binary.", minNodeMajor)
+	}
+	version := strings.TrimSpace(string(out))
+	var major int
+	if _, err := fmt.Sscanf(strings.TrimPrefix(version, "v"), "%d", &major); err != nil || major < minNodeMajor {
+		return StatusFail, "node " + version, fmt.Sprintf("Upgrade Node.js to %d or later.", minNodeMajor)
+	}
+	return StatusPass, "node " + version, ""
+}
+
+// startHint suggests a fix for a Start error.
+func startHint(err error) string {
+	switch {
+	case errors.Is(err, copilot.ErrCLINotFound):
+		return "Install the Copilot CLI (npm install -g @github/copilot), or set COPILOT_CLI_PATH or ClientOptions.CLIPath to its location."
+	case errors.Is(err, copilot.ErrCLIStartFailed):
+		return "The CLI exited during startup; see its stderr above. Check your Node.js version, proxy settings, and CLI arguments."
+	case errors.Is(err, copilot.ErrHandshakeFailed):
+		return "The CLI speaks a different protocol version; update the CLI or the SDK so they match."
+	}
+	return "Check that the CLI can start, and that CLIUrl points at a running server if set."
+}
+
+// getenvAnyCase returns an environment variable, upper or lower case.
+func getenvAnyCase(name string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return os.Getenv(strings.ToLower(name))
+}
+
+func hasModel(models []copilot.ModelInfo, id string) bool {
+	for _, model := range models {
+		if model.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func derefInt(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,50 @@
+package doctor
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// WriteText writes the report as an aligned pass/fail listing with hints,
+// followed by a one-line verdict.
+func (r *Report) WriteText(w io.Writer) error {
+	width := 0
+	for _, check := range r.Checks {
+		width = max(width, len(check.Name))
+	}
+	var b strings.Builder
+	for _, check := range r.Checks {
+		fmt.Fprintf(&b, "%-4s  %-*s  %s", strings.ToUpper(string(check.Status)), width, check.Name, check.Detail)
+		if check.Duration > 0 {
+			fmt.Fprintf(&b, " (%s)", check.Duration.Round(time.Millisecond))
+		}
+		b.WriteString("\n")
+		if check.Hint != "" && check.Status != StatusPass {
+			fmt.Fprintf(&b, "%s  hint: %s\n", strings.Repeat(" ", 4+2+width), check.Hint)
+		}
+	}
+	if r.OK() {
+		b.WriteString("\nAll checks passed.\n")
+	} else {
+		b.WriteString("\nSome checks failed.\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var (
+	// urlCredentials matches the user information of a URL
+	urlCredentials = regexp.MustCompile(`(://)[^/@\s]+@`)
+	// tokenPattern matches GitHub and API tokens
+	tokenPattern = regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{8,}|github_pat_[A-Za-z0-9_]{8,}|sk-[A-Za-z0-9_-]{8,})`)
+)
+
+// Redact removes credentials from text: the user information of URLs, such
+// as proxy passwords, and GitHub and API tokens.
+func Redact(text string) string {
+	text = urlCredentials.ReplaceAllString(text, "${1}[REDACTED]@")
+	return tokenPattern.ReplaceAllString(text, "[REDACTED]")
+}
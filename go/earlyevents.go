@@ -0,0 +1,92 @@
+package copilot
+
+import (
+	"sync"
+	"time"
+)
+
+// earlyEventWindow is how long the client holds events for a session it
+// does not track yet, such as those the CLI emits while session.create or
+// session.resume is still being answered.
+const earlyEventWindow = 5 * time.Second
+
+// maxEarlyEvents bounds the events held per session, and
+// maxEarlyEventSessions the number of sessions events are held for.
+const (
+	maxEarlyEvents        = 256
+	maxEarlyEventSessions = 64
+)
+
+// earlyEventBuffer holds events for sessions the client does not track yet
+// and replays them when the session is registered.
+//
+// Registration replays the held events while holding mu and only then
+// tracks the session, and the read loop takes mu when it does not find a
+// session, so events received during registration are dispatched after the
+// replayed ones.
+type earlyEventBuffer struct {
+	mu     sync.Mutex
+	events map[string][]earlyEvent
+}
+
+type earlyEvent struct {
+	event      SessionEvent
+	receivedAt time.Time
+}
+
+// sessionOrBuffer returns the session an event is for, or holds the event
+// and returns nil if the client does not track the session. Only events for
+// untracked sessions take the buffer's lock.
+func (b *earlyEventBuffer) sessionOrBuffer(c *Client, req sessionEventRequest) *Session {
+	if session := c.sessions.get(req.SessionID); session != nil {
+		return session
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if session := c.sessions.get(req.SessionID); session != nil {
+		return session
+	}
+
+	now := time.Now()
+	b.pruneLocked(now)
+	if b.events == nil {
+		b.events = make(map[string][]earlyEvent)
+	}
+	held := b.events[req.SessionID]
+	if held == nil && len(b.events) >= maxEarlyEventSessions {
+		return nil
+	}
+	if len(held) >= maxEarlyEvents {
+		held = held[1:]
+	}
+	b.events[req.SessionID] = append(held, earlyEvent{event: req.Event, receivedAt: now})
+	return nil
+}
+
+// pruneLocked drops events held for longer than earlyEventWindow.
+func (b *earlyEventBuffer) pruneLocked(now time.Time) {
+	for id, held := range b.events {
+		for len(held) > 0 && now.Sub(held[0].receivedAt) > earlyEventWindow {
+			held = held[1:]
+		}
+		if len(held) == 0 {
+			delete(b.events, id)
+		} else {
+			b.events[id] = held
+		}
+	}
+}
+
+// registerSession dispatches the events held for a session, then starts
+// tracking it.
+func (c *Client) registerSession(session *Session) {
+	c.earlyEvents.mu.Lock()
+	defer c.earlyEvents.mu.Unlock()
+	c.earlyEvents.pruneLocked(time.Now())
+	held := c.earlyEvents.events[session.SessionID]
+	delete(c.earlyEvents.events, session.SessionID)
+	for _, e := range held {
+		session.dispatchEvent(e.event)
+	}
+	c.sessions.put(session)
+}
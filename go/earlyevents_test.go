@@ -0,0 +1,89 @@
+package copilot
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+func TestClient_EarlyEvents(t *testing.T) {
+	var cli *fakeCLI
+	cli = newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		var req struct {
+			SessionID string `json:"sessionId"`
+		}
+		json.Unmarshal(params, &req)
+		switch method {
+		case "session.create", "session.resume":
+			// Emitted before the response, so the client sees the event
+			// before it knows the session
+			cli.emitTo(req.SessionID, SessionEvent{Type: SessionStart, Data: Data{SessionID: String(req.SessionID)}})
+			cli.emitTo(req.SessionID, SessionEvent{Type: SessionInfo, Data: Data{Message: String("ready")}})
+			if method == "session.create" {
+				return createSessionResponse{SessionID: req.SessionID}, nil
+			}
+			return resumeSessionResponse{SessionID: req.SessionID}, nil
+		}
+		return nil, nil
+	})
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+
+	recorder := func() (SessionEventHandler, func() []SessionEventType) {
+		var mu sync.Mutex
+		var types []SessionEventType
+		return func(event SessionEvent) {
+				mu.Lock()
+				defer mu.Unlock()
+				types = append(types, event.Type)
+			}, func() []SessionEventType {
+				mu.Lock()
+				defer mu.Unlock()
+				return append([]SessionEventType(nil), types...)
+			}
+	}
+
+	t.Run("delivers events emitted during create to OnEvent handlers", func(t *testing.T) {
+		handler, seen := recorder()
+		_, err := client.CreateSession(t.Context(), &SessionConfig{
+			SessionID:           "s1",
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			OnEvent:             []SessionEventHandler{handler},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if got := seen(); len(got) != 2 || got[0] != SessionStart || got[1] != SessionInfo {
+			t.Errorf("Expected session.start then session.info, got %v", got)
+		}
+	})
+
+	t.Run("delivers events emitted during resume to OnEvent handlers", func(t *testing.T) {
+		handler, seen := recorder()
+		_, err := client.ResumeSession(t.Context(), "s2", &ResumeSessionConfig{
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			OnEvent:             []SessionEventHandler{handler},
+		})
+		if err != nil {
+			t.Fatalf("Failed to resume session: %v", err)
+		}
+		if got := seen(); len(got) != 2 || got[0] != SessionStart {
+			t.Errorf("Expected both early events, got %v", got)
+		}
+	})
+
+	t.Run("bounds events held for unknown sessions", func(t *testing.T) {
+		for i := 0; i < maxEarlyEvents+10; i++ {
+			client.handleSessionEvent(sessionEventRequest{SessionID: "never", Event: SessionEvent{Type: SessionInfo}})
+		}
+		client.earlyEvents.mu.Lock()
+		held := len(client.earlyEvents.events["never"])
+		client.earlyEvents.mu.Unlock()
+		if held != maxEarlyEvents {
+			t.Errorf("Expected %d held events, got %d", maxEarlyEvents, held)
+		}
+	})
+}
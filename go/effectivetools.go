@@ -0,0 +1,194 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/github/copilot-sdk/go/rpc"
+)
+
+// Sources of an [EffectiveTool]. Tools provided by an MCP server have the
+// source "mcp:" followed by the server's name; see [MCPToolSource].
+const (
+	ToolSourceBuiltin = "builtin"
+	ToolSourceCustom  = "custom"
+)
+
+// ToolMissingWarning is the warning type of the session.warning event emitted
+// by [Session.EffectiveTools] when a tool registered with the session is not
+// in the list of tools the model can call.
+const ToolMissingWarning = "tool_missing"
+
+// MCPToolSource returns the [EffectiveTool.Source] of tools provided by the
+// named MCP server.
+func MCPToolSource(server string) string {
+	return "mcp:" + server
+}
+
+// EffectiveTool describes a tool the session's model can call.
+type EffectiveTool struct {
+	// Name is the name the model calls the tool by
+	Name string `json:"name"`
+	// Description is the tool's description, if known
+	Description string `json:"description,omitempty"`
+	// Source is where the tool comes from: ToolSourceBuiltin,
+	// ToolSourceCustom, or MCPToolSource(server)
+	Source string `json:"source"`
+	// Agents lists the custom agents that may call the tool. The session's
+	// default agent may call every effective tool.
+	Agents []string `json:"agents,omitempty"`
+}
+
+// sessionToolsListRequest is the request for session.tools.list
+type sessionToolsListRequest struct {
+	SessionID string `json:"sessionId"`
+}
+
+// sessionToolsListResponse is the response from session.tools.list
+type sessionToolsListResponse struct {
+	Tools []EffectiveTool `json:"tools"`
+}
+
+// missingToolWarnings remembers which registered tools a session has already
+// warned about.
+type missingToolWarnings struct {
+	mu     sync.Mutex
+	warned map[string]bool
+}
+
+// EffectiveTools returns the tools the session's model can call, after
+// built-in tools, custom tools, MCP server tools, AvailableTools,
+// ExcludedTools and custom agent tool restrictions are combined.
+//
+// The list comes from the CLI. CLIs that cannot report it get a list resolved
+// by the SDK from the built-in tools and the session's configuration; it does
+// not include MCP server tools, whose names only the CLI knows.
+//
+// For each tool registered with the session that is not in the list, a
+// session.warning event with type [ToolMissingWarning] is emitted, once per
+// tool.
+//
+// Example:
+//
+//	tools, err := session.EffectiveTools(ctx)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, tool := range tools {
+//	    fmt.Printf("%s (%s)\n", tool.Name, tool.Source)
+//	}
+func (s *Session) EffectiveTools(ctx context.Context) ([]EffectiveTool, error) {
+	if err := s.checkNotDestroyed(); err != nil {
+		return nil, err
+	}
+
+	var tools []EffectiveTool
+	result, err := s.rpcClient().RequestContext(ctx, "session.tools.list", sessionToolsListRequest{SessionID: s.SessionID})
+	if err != nil {
+		if !isMethodNotFound(err) {
+			return nil, fmt.Errorf("failed to list session tools: %w", err)
+		}
+		tools, err = s.resolveEffectiveTools(ctx)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var response sessionToolsListResponse
+		if err := json.Unmarshal(result, &response); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal session tools response: %w", err)
+		}
+		tools = response.Tools
+	}
+
+	s.warnMissingTools(tools)
+	return tools, nil
+}
+
+// resolveEffectiveTools computes the session's effective tools from the CLI's
+// built-in tools and the tools, filters and agents the session was created
+// with.
+func (s *Session) resolveEffectiveTools(ctx context.Context) ([]EffectiveTool, error) {
+	var params rpc.ToolsListParams
+	if model := s.Config().Model; model != "" {
+		params.Model = &model
+	}
+	result, err := s.rpcClient().RequestContext(ctx, "tools.list", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list built-in tools: %w", err)
+	}
+	var builtins rpc.ToolsListResult
+	if err := json.Unmarshal(result, &builtins); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tools response: %w", err)
+	}
+
+	req := s.reattachRequest
+	byName := make(map[string]EffectiveTool)
+	for _, tool := range builtins.Tools {
+		byName[tool.Name] = EffectiveTool{Name: tool.Name, Description: tool.Description, Source: ToolSourceBuiltin}
+	}
+	for _, tool := range req.Tools {
+		byName[tool.Name] = EffectiveTool{Name: tool.Name, Description: tool.Description, Source: ToolSourceCustom}
+	}
+
+	var tools []EffectiveTool
+	for name, tool := range byName {
+		if req.AvailableTools != nil {
+			if !slices.Contains(req.AvailableTools, name) {
+				continue
+			}
+		} else if slices.Contains(req.ExcludedTools, name) {
+			continue
+		}
+		for _, agent := range req.CustomAgents {
+			if agent.Tools == nil || slices.Contains(agent.Tools, name) {
+				tool.Agents = append(tool.Agents, agent.Name)
+			}
+		}
+		tools = append(tools, tool)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools, nil
+}
+
+// warnMissingTools emits a session.warning event for each registered tool
+// missing from tools that has not been warned about before.
+func (s *Session) warnMissingTools(tools []EffectiveTool) {
+	s.toolHandlersM.RLock()
+	registered := make([]string, 0, len(s.toolHandlers))
+	for name := range s.toolHandlers {
+		registered = append(registered, name)
+	}
+	s.toolHandlersM.RUnlock()
+	sort.Strings(registered)
+
+	var missing []string
+	s.missingTools.mu.Lock()
+	for _, name := range registered {
+		if s.missingTools.warned[name] || slices.ContainsFunc(tools, func(tool EffectiveTool) bool { return tool.Name == name }) {
+			continue
+		}
+		if s.missingTools.warned == nil {
+			s.missingTools.warned = make(map[string]bool)
+		}
+		s.missingTools.warned[name] = true
+		missing = append(missing, name)
+	}
+	s.missingTools.mu.Unlock()
+
+	for _, name := range missing {
+		s.dispatchEvent(SessionEvent{
+			Type:      SessionWarning,
+			Timestamp: time.Now(),
+			Ephemeral: Bool(true),
+			Data: Data{
+				WarningType: String(ToolMissingWarning),
+				Message:     String(fmt.Sprintf("tool %q is registered with the session but the model cannot call it; check AvailableTools and ExcludedTools", name)),
+			},
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package copilot
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+	"github.com/github/copilot-sdk/go/rpc"
+)
+
+func TestSession_EffectiveTools(t *testing.T) {
+	noop := func(ToolInvocation) (ToolResult, error) { return ToolResult{}, nil }
+	tools := []Tool{
+		{Name: "encrypt_string", Description: "Encrypts a string", Handler: noop},
+		{Name: "unused", Handler: noop},
+	}
+
+	newSessionWith := func(t *testing.T, handler func(method string, params json.RawMessage) (any, error)) (*Session, func() []string) {
+		session, _ := newTestSession(t, handler)
+		session.registerTools(tools, 0)
+		session.reattachRequest.Tools = tools
+		var mu sync.Mutex
+		var warnings []string
+		session.On(func(event SessionEvent) {
+			if event.Type == SessionWarning && *event.Data.WarningType == ToolMissingWarning {
+				mu.Lock()
+				warnings = append(warnings, *event.Data.Message)
+				mu.Unlock()
+			}
+		})
+		return session, func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), warnings...)
+		}
+	}
+
+	t.Run("returns the CLI's list and warns once about missing tools", func(t *testing.T) {
+		listed := []EffectiveTool{
+			{Name: "bash", Source: ToolSourceBuiltin},
+			{Name: "encrypt_string", Source: ToolSourceCustom, Agents: []string{"reviewer"}},
+			{Name: "navigate", Source: MCPToolSource("playwright")},
+		}
+		session, warnings := newSessionWith(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.tools.list" {
+				return sessionToolsListResponse{Tools: listed}, nil
+			}
+			return nil, nil
+		})
+
+		for range 2 {
+			got, err := session.EffectiveTools(t.Context())
+			if err != nil {
+				t.Fatalf("Failed to list tools: %v", err)
+			}
+			if !reflect.DeepEqual(got, listed) {
+				t.Errorf("Expected %+v, got %+v", listed, got)
+			}
+		}
+		if got := warnings(); len(got) != 1 {
+			t.Errorf("Expected one warning about the unused tool, got %v", got)
+		}
+	})
+
+	t.Run("resolves the list when the CLI cannot report it", func(t *testing.T) {
+		var toolsListParams rpc.ToolsListParams
+		session, warnings := newSessionWith(t, func(method string, params json.RawMessage) (any, error) {
+			switch method {
+			case "session.tools.list":
+				return nil, &jsonrpc2.Error{Code: -32601, Message: "Method not found: session.tools.list"}
+			case "tools.list":
+				json.Unmarshal(params, &toolsListParams)
+				return rpc.ToolsListResult{Tools: []rpc.Tool{
+					{Name: "bash", Description: "Runs commands"},
+					{Name: "view", Description: "Views files"},
+					{Name: "edit", Description: "Edits files"},
+				}}, nil
+			}
+			return nil, nil
+		})
+		session.config.setModel("gpt-5")
+		session.reattachRequest.AvailableTools = []string{"view", "bash", "encrypt_string"}
+		session.reattachRequest.CustomAgents = []CustomAgentConfig{
+			{Name: "reader", Tools: []string{"view"}},
+			{Name: "anything"},
+		}
+
+		got, err := session.EffectiveTools(t.Context())
+		if err != nil {
+			t.Fatalf("Failed to list tools: %v", err)
+		}
+		want := []EffectiveTool{
+			{Name: "bash", Description: "Runs commands", Source: ToolSourceBuiltin, Agents: []string{"anything"}},
+			{Name: "encrypt_string", Description: "Encrypts a string", Source: ToolSourceCustom, Agents: []string{"anything"}},
+			{Name: "view", Description: "Views files", Source: ToolSourceBuiltin, Agents: []string{"reader", "anything"}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %+v, got %+v", want, got)
+		}
+		if toolsListParams.Model == nil || *toolsListParams.Model != "gpt-5" {
+			t.Errorf("Expected built-in tools for the session's model, got %v", toolsListParams.Model)
+		}
+		if got := warnings(); len(got) != 1 {
+			t.Errorf("Expected a warning about the tool AvailableTools leaves out, got %v", got)
+		}
+	})
+}
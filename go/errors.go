@@ -0,0 +1,449 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+// RateLimitError is returned when the CLI reports that a request was rejected
+// because a rate limit was exceeded.
+//
+// Use [errors.As] to detect it:
+//
+//	var rateLimitErr *copilot.RateLimitError
+//	if errors.As(err, &rateLimitErr) {
+//	    time.Sleep(rateLimitErr.RetryAfter)
+//	}
+type RateLimitError struct {
+	// RetryAfter is how long the server asked the caller to wait, or zero if unknown
+	RetryAfter time.Duration
+	// Message is the error message reported by the server
+	Message string
+
+	err error
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited (retry after %s): %s", e.RetryAfter, e.Message)
+	}
+	return fmt.Sprintf("rate limited: %s", e.Message)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.err
+}
+
+// asRateLimitError converts a JSON-RPC error reporting an HTTP 429 or a rate
+// limit into a *RateLimitError. Returns nil for any other error.
+func asRateLimitError(err error) *RateLimitError {
+	var rpcErr *jsonrpc2.Error
+	if !errors.As(err, &rpcErr) {
+		return nil
+	}
+
+	statusCode, _ := rpcErr.Data["statusCode"].(float64)
+	code, _ := rpcErr.Data["code"].(string)
+	if statusCode != 429 && code != "rate_limited" && !strings.Contains(strings.ToLower(rpcErr.Message), "rate limit") {
+		return nil
+	}
+
+	rateLimitErr := &RateLimitError{Message: rpcErr.Message, err: err}
+	if seconds, ok := rpcErr.Data["retryAfter"].(float64); ok && seconds > 0 {
+		rateLimitErr.RetryAfter = time.Duration(seconds * float64(time.Second))
+	}
+	return rateLimitErr
+}
+
+// RPCError is returned when a JSON-RPC call to the CLI fails. It carries the
+// call's method and request ID, for matching the failure against CLI logs,
+// and wraps the underlying error, whose message it reports as its own. When
+// the CLI answered with an error, Code, Message and Data hold the JSON-RPC
+// error's fields; otherwise, for example when the connection was lost, Code
+// is zero.
+//
+// Use [errors.As] to detect it:
+//
+//	var rpcErr *copilot.RPCError
+//	if errors.As(err, &rpcErr) {
+//	    log.Printf("%s failed (request %s, code %d): %s", rpcErr.Method, rpcErr.RequestID, rpcErr.Code, rpcErr.Message)
+//	}
+type RPCError = jsonrpc2.CallError
+
+// ErrMessageTooLarge matches errors from requests whose JSON-RPC message
+// would exceed [ClientOptions.MaxMessageBytes], for example a session.create
+// carrying very large tool schemas. The request is not sent.
+var ErrMessageTooLarge = jsonrpc2.ErrMessageTooLarge
+
+// ErrConnectionLost matches errors from requests that could not complete
+// because the connection to the CLI was lost, for example because the CLI
+// process exited. See [ClientOptions.AutoRestart].
+var ErrConnectionLost = jsonrpc2.ErrConnectionClosed
+
+// ErrRequestTimeout matches errors from requests to the CLI that got no
+// response within [ClientOptions.RequestTimeout].
+var ErrRequestTimeout = jsonrpc2.ErrRequestTimeout
+
+// ErrTooManyPendingRequests matches errors from requests not sent because
+// [ClientOptions.MaxPendingRequests] requests were awaiting a response.
+var ErrTooManyPendingRequests = jsonrpc2.ErrTooManyPendingRequests
+
+// ErrClientStopped matches errors from client and session methods called
+// while the client is not connected, before [Client.Start] or after
+// [Client.Stop], and from requests that were waiting for a response when the
+// client was stopped.
+var ErrClientStopped = jsonrpc2.ErrClientStopped
+
+var (
+	// ErrTimeout matches errors from waits that timed out: requests that
+	// match [ErrRequestTimeout], and [Session.SendAndWait] and related methods
+	// giving up on session.idle. Timeouts of a wait also match
+	// [context.DeadlineExceeded].
+	ErrTimeout = errors.New("timed out")
+	// ErrNotAuthenticated matches errors from [Session.Send],
+	// [Client.CreateSession] and other calls the CLI rejected because it is
+	// not signed in to GitHub. See [Client.GetAuthStatus].
+	ErrNotAuthenticated = errors.New("copilot CLI not authenticated")
+)
+
+// classifiedError gives an error a sentinel to match with [errors.Is],
+// without changing its message.
+type classifiedError struct {
+	sentinel error
+	err      error
+}
+
+func (e *classifiedError) Error() string { return e.err.Error() }
+
+func (e *classifiedError) Is(target error) bool { return target == e.sentinel }
+
+func (e *classifiedError) Unwrap() error { return e.err }
+
+// errNotConnected is returned by methods that need a connection when the
+// client has none. It matches [ErrClientStopped].
+var errNotConnected error = &classifiedError{sentinel: ErrClientStopped, err: errors.New("client not connected. Call Start() first")}
+
+// classifyError makes an error from a call to the CLI match the sentinel
+// errors it stands for: [ErrTimeout] for request timeouts,
+// [ErrNotAuthenticated] when the CLI is not signed in, and
+// [ErrSessionDestroyed] when the CLI does not know the session.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, ErrRequestTimeout) {
+		return &classifiedError{sentinel: ErrTimeout, err: err}
+	}
+	var rpcErr *jsonrpc2.Error
+	if !errors.As(err, &rpcErr) {
+		return err
+	}
+	statusCode, _ := rpcErr.Data["statusCode"].(float64)
+	code, _ := rpcErr.Data["code"].(string)
+	message := strings.ToLower(rpcErr.Message)
+	switch {
+	case statusCode == 401 || code == "not_authenticated" || code == "unauthenticated" ||
+		strings.Contains(message, "not authenticated") || strings.Contains(message, "authentication required"):
+		return &classifiedError{sentinel: ErrNotAuthenticated, err: err}
+	case code == "session_not_found" || (strings.Contains(message, "session") && strings.Contains(message, "not found")):
+		return &classifiedError{sentinel: ErrSessionDestroyed, err: err}
+	}
+	return err
+}
+
+// ModelUnavailableError is returned when the CLI rejects a message because
+// the session's model is unavailable, for example because the account has
+// no quota left for it or it is temporarily disabled.
+//
+// Use [errors.As] to detect it:
+//
+//	var unavailableErr *copilot.ModelUnavailableError
+//	if errors.As(err, &unavailableErr) {
+//	    log.Printf("Model unavailable: %s", unavailableErr.Message)
+//	}
+type ModelUnavailableError struct {
+	// Message is the error message reported by the server
+	Message string
+
+	err error
+}
+
+func (e *ModelUnavailableError) Error() string {
+	return fmt.Sprintf("model unavailable: %s", e.Message)
+}
+
+func (e *ModelUnavailableError) Unwrap() error {
+	return e.err
+}
+
+// asModelUnavailableError converts a JSON-RPC error reporting an unavailable
+// model into a *ModelUnavailableError. Returns nil for any other error.
+func asModelUnavailableError(err error) *ModelUnavailableError {
+	var rpcErr *jsonrpc2.Error
+	if !errors.As(err, &rpcErr) {
+		return nil
+	}
+
+	code, _ := rpcErr.Data["code"].(string)
+	message := strings.ToLower(rpcErr.Message)
+	switch {
+	case code == "model_not_available" || code == "model_unavailable" || code == "model_not_supported":
+	case strings.Contains(message, "model") && (strings.Contains(message, "not available") ||
+		strings.Contains(message, "unavailable") || strings.Contains(message, "not supported")):
+	default:
+		return nil
+	}
+	return &ModelUnavailableError{Message: rpcErr.Message, err: err}
+}
+
+// StopError is returned by [Client.Stop] when cleanup did not complete
+// cleanly. The client is stopped regardless.
+type StopError struct {
+	// DestroyFailed maps the IDs of sessions whose session.destroy call failed
+	// or timed out to the error
+	DestroyFailed map[string]error
+	// Errors lists failures closing the connection or stopping the CLI process
+	Errors []error
+}
+
+func (e *StopError) Error() string {
+	ids := make([]string, 0, len(e.DestroyFailed))
+	for id := range e.DestroyFailed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	parts := make([]string, 0, len(ids)+len(e.Errors))
+	for _, id := range ids {
+		parts = append(parts, fmt.Sprintf("failed to destroy session %s: %v", id, e.DestroyFailed[id]))
+	}
+	for _, err := range e.Errors {
+		parts = append(parts, err.Error())
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Unwrap returns every underlying error, so [errors.Is] can match, for
+// example, [context.DeadlineExceeded] from a timed out destroy.
+func (e *StopError) Unwrap() []error {
+	errs := make([]error, 0, len(e.DestroyFailed)+len(e.Errors))
+	for _, err := range e.DestroyFailed {
+		errs = append(errs, err)
+	}
+	return append(errs, e.Errors...)
+}
+
+var (
+	// ErrCLINotFound matches errors from [Client.Start] when the CLI
+	// executable could not be found. Use [errors.As] with a
+	// *[CLINotFoundError] for the paths searched.
+	ErrCLINotFound = errors.New("copilot CLI not found")
+	// ErrCLIStartFailed matches errors from [Client.Start] when the CLI
+	// process could not be started or exited before it was ready. Use
+	// [errors.As] with a *[CLIStartError] for the exit code and stderr.
+	ErrCLIStartFailed = errors.New("copilot CLI failed to start")
+	// ErrHandshakeFailed matches errors from [Client.Start] when the CLI
+	// server did not answer the initial handshake as expected, for example
+	// because it speaks a different protocol version. Use [errors.As] with a
+	// *[HandshakeError] for what was received.
+	ErrHandshakeFailed = errors.New("copilot CLI handshake failed")
+)
+
+// CLINotFoundError is returned by [Client.Start] when the CLI executable
+// could not be found. It matches [ErrCLINotFound].
+//
+// Example:
+//
+//	var notFound *copilot.CLINotFoundError
+//	if errors.As(err, &notFound) {
+//	    log.Printf("Install the Copilot CLI; searched %v", notFound.Searched)
+//	}
+type CLINotFoundError struct {
+	// Command is the CLI path or command name that was looked up
+	Command string
+	// Searched lists the paths checked for the executable
+	Searched []string
+
+	err error
+}
+
+func (e *CLINotFoundError) Error() string {
+	return fmt.Sprintf("%v: %q (searched %s)", ErrCLINotFound, e.Command, strings.Join(e.Searched, ", "))
+}
+
+func (e *CLINotFoundError) Is(target error) bool { return target == ErrCLINotFound }
+
+func (e *CLINotFoundError) Unwrap() error { return e.err }
+
+// CLIStartError is returned by [Client.Start] when the CLI process could not
+// be started or exited before it was ready. It matches [ErrCLIStartFailed].
+type CLIStartError struct {
+	// ExitCode is the process exit code, or -1 if the process did not exit,
+	// for example because it could not be started or did not report its port
+	ExitCode int
+	// Stderr holds the last lines the process wrote to stderr
+	Stderr []string
+	// Reason describes the failure
+	Reason string
+
+	err error
+}
+
+func (e *CLIStartError) Error() string {
+	msg := fmt.Sprintf("%v: %s", ErrCLIStartFailed, e.Reason)
+	if e.ExitCode >= 0 {
+		msg += fmt.Sprintf(" (exit code %d)", e.ExitCode)
+	}
+	if len(e.Stderr) > 0 {
+		msg += "\nstderr:\n" + strings.Join(e.Stderr, "\n")
+	}
+	return msg
+}
+
+func (e *CLIStartError) Is(target error) bool { return target == ErrCLIStartFailed }
+
+func (e *CLIStartError) Unwrap() error { return e.err }
+
+// HandshakeError is returned by [Client.Start] when the CLI server did not
+// answer the initial handshake as expected. It matches [ErrHandshakeFailed].
+type HandshakeError struct {
+	// Received describes what the server sent instead of the expected
+	// response, e.g. "protocol version 1" or the error returned by ping
+	Received string
+	// Reason describes the failure
+	Reason string
+
+	err error
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("%v: %s (received %s)", ErrHandshakeFailed, e.Reason, e.Received)
+}
+
+func (e *HandshakeError) Is(target error) bool { return target == ErrHandshakeFailed }
+
+func (e *HandshakeError) Unwrap() error { return e.err }
+
+// ErrDirectoryTooLarge matches errors from [DirectoryAttachment] when the
+// files to attach exceed the configured limits. Use [errors.As] with a
+// *[DirectoryLimitError] for the totals.
+var ErrDirectoryTooLarge = errors.New("directory exceeds attachment limits")
+
+// DirectoryLimitError is returned by [DirectoryAttachment] when the files
+// left after applying ignore rules exceed [WithMaxFiles] or
+// [WithMaxTotalBytes]. It matches [ErrDirectoryTooLarge].
+//
+// Example:
+//
+//	var tooLarge *copilot.DirectoryLimitError
+//	if errors.As(err, &tooLarge) {
+//	    for _, entry := range tooLarge.Largest {
+//	        log.Printf("%s: %d files, %d bytes", entry.Path, entry.Files, entry.Bytes)
+//	    }
+//	}
+type DirectoryLimitError struct {
+	// Path is the directory that was walked
+	Path string
+	// Files and TotalBytes count the files that would have been attached
+	Files      int
+	TotalBytes int64
+	// MaxFiles and MaxTotalBytes are the limits in effect, or zero if unlimited
+	MaxFiles      int
+	MaxTotalBytes int64
+	// Largest lists the top-level entries contributing the most bytes, largest
+	// first, to show what to ignore
+	Largest []DirectoryUsage
+}
+
+// DirectoryUsage is the number of files and bytes under an entry of a
+// directory passed to [DirectoryAttachment].
+type DirectoryUsage struct {
+	// Path is relative to the attached directory, with a trailing slash for
+	// directories
+	Path  string
+	Files int
+	Bytes int64
+}
+
+func (e *DirectoryLimitError) Error() string {
+	var exceeded []string
+	if e.MaxFiles > 0 && e.Files > e.MaxFiles {
+		exceeded = append(exceeded, fmt.Sprintf("%d files (max %d)", e.Files, e.MaxFiles))
+	}
+	if e.MaxTotalBytes > 0 && e.TotalBytes > e.MaxTotalBytes {
+		exceeded = append(exceeded, fmt.Sprintf("%d bytes (max %d)", e.TotalBytes, e.MaxTotalBytes))
+	}
+	msg := fmt.Sprintf("%v: %s: %s", ErrDirectoryTooLarge, e.Path, strings.Join(exceeded, ", "))
+	if len(e.Largest) > 0 {
+		largest := make([]string, len(e.Largest))
+		for i, entry := range e.Largest {
+			largest[i] = fmt.Sprintf("%s (%d files, %d bytes)", entry.Path, entry.Files, entry.Bytes)
+		}
+		msg += "; largest: " + strings.Join(largest, ", ")
+	}
+	return msg
+}
+
+func (e *DirectoryLimitError) Is(target error) bool { return target == ErrDirectoryTooLarge }
+
+// ErrConsistencyTimeout matches errors from [Session.GetMessagesWithOptions]
+// when the history did not include [GetMessagesOptions.ConsistentWith] in
+// time.
+var ErrConsistencyTimeout = errors.New("timed out waiting for history to include message")
+
+// ConsistencyTimeoutError is returned by [Session.GetMessagesWithOptions]
+// when the history did not include the awaited message before
+// [GetMessagesOptions.ConsistencyTimeout]. It matches [ErrConsistencyTimeout].
+type ConsistencyTimeoutError struct {
+	// MessageID is the message that was waited for
+	MessageID string
+	// Waited is how long the call waited
+	Waited time.Duration
+	// Events is the last history read, which does not include the message
+	Events []SessionEvent
+}
+
+func (e *ConsistencyTimeoutError) Error() string {
+	return fmt.Sprintf("%v %s after %s", ErrConsistencyTimeout, e.MessageID, e.Waited.Round(time.Millisecond))
+}
+
+func (e *ConsistencyTimeoutError) Is(target error) bool { return target == ErrConsistencyTimeout }
+
+// ErrAborted matches errors returned by [Session.SendAndWait] and related
+// methods when the turn was interrupted by [Session.Abort].
+var ErrAborted = errors.New("turn aborted")
+
+// AbortedError is returned by [Session.SendAndWait], [Session.SendAndCollect]
+// and related methods when the awaited turn was aborted, whether by a call to
+// [Session.Abort] or by the CLI. It matches [ErrAborted].
+//
+// Use [errors.As] to get what the assistant produced before the abort:
+//
+//	var abortedErr *copilot.AbortedError
+//	if errors.As(err, &abortedErr) {
+//	    fmt.Println("Partial answer:", abortedErr.PartialContent)
+//	}
+type AbortedError struct {
+	// MessageID is the ID returned by Send for the aborted message
+	MessageID string
+	// Reason is the reason reported with the abort event, if any
+	Reason string
+	// PartialContent is the assistant content received before the abort,
+	// including streamed deltas of an unfinished message
+	PartialContent string
+	// Turn holds the events received before the abort
+	Turn *TurnResult
+}
+
+func (e *AbortedError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("%v: %s", ErrAborted, e.Reason)
+	}
+	return ErrAborted.Error()
+}
+
+func (e *AbortedError) Is(target error) bool { return target == ErrAborted }
@@ -0,0 +1,90 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+func TestTypedErrors(t *testing.T) {
+	t.Run("keeps the CLI's error code, message and data", func(t *testing.T) {
+		rejection := &jsonrpc2.Error{Code: -32001, Message: "Not authenticated", Data: map[string]any{"statusCode": float64(401)}}
+		session, _ := newTestSession(t, func(string, json.RawMessage) (any, error) { return nil, rejection })
+		_, err := session.Send(t.Context(), MessageOptions{Prompt: "Hello"})
+		if !errors.Is(err, ErrNotAuthenticated) {
+			t.Errorf("Expected ErrNotAuthenticated, got %v", err)
+		}
+		var rpcErr *RPCError
+		if !errors.As(err, &rpcErr) {
+			t.Fatalf("Expected an RPCError, got %v", err)
+		}
+		if rpcErr.Method != "session.send" || rpcErr.Code != -32001 || rpcErr.Message != "Not authenticated" || rpcErr.Data["statusCode"] != float64(401) {
+			t.Errorf("Unexpected RPCError %+v", rpcErr)
+		}
+	})
+
+	t.Run("reports sessions the CLI does not know as destroyed", func(t *testing.T) {
+		session, _ := newTestSession(t, func(string, json.RawMessage) (any, error) {
+			return nil, &jsonrpc2.Error{Code: -32603, Message: "Session not found: test-session"}
+		})
+		_, err := session.GetMessages(t.Context())
+		if !errors.Is(err, ErrSessionDestroyed) {
+			t.Errorf("Expected ErrSessionDestroyed, got %v", err)
+		}
+	})
+
+	t.Run("reports request timeouts", func(t *testing.T) {
+		release := make(chan struct{})
+		t.Cleanup(func() { close(release) })
+		session, _ := newTestSession(t, func(string, json.RawMessage) (any, error) {
+			<-release
+			return nil, nil
+		})
+		session.rpcClient().SetRequestTimeout(20 * time.Millisecond)
+		_, err := session.Send(t.Context(), MessageOptions{Prompt: "Hello"})
+		if !errors.Is(err, ErrTimeout) || !errors.Is(err, ErrRequestTimeout) {
+			t.Errorf("Expected ErrTimeout and ErrRequestTimeout, got %v", err)
+		}
+	})
+
+	t.Run("reports idle timeouts", func(t *testing.T) {
+		session, _ := newTestSession(t, func(string, json.RawMessage) (any, error) {
+			return sessionSendResponse{MessageID: "m1"}, nil
+		})
+		_, err := session.SendAndWaitWithOptions(t.Context(), MessageOptions{Prompt: "Hello"}, &SendAndWaitOptions{Timeout: 20 * time.Millisecond})
+		if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected ErrTimeout and context.DeadlineExceeded, got %v", err)
+		}
+	})
+
+	t.Run("reports authentication errors during a turn", func(t *testing.T) {
+		session, server := newTestSession(t, func(string, json.RawMessage) (any, error) {
+			return sessionSendResponse{MessageID: "m1"}, nil
+		})
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			server.emit(SessionEvent{Type: SessionError, Timestamp: time.Now(), Data: Data{
+				Message:   String("Please sign in"),
+				ErrorType: String("authentication"),
+			}})
+		}()
+		_, err := session.SendAndWait(t.Context(), MessageOptions{Prompt: "Hello"})
+		if !errors.Is(err, ErrNotAuthenticated) {
+			t.Errorf("Expected ErrNotAuthenticated, got %v", err)
+		}
+	})
+
+	t.Run("reports calls on a client that is not connected", func(t *testing.T) {
+		client := NewClient(&ClientOptions{AutoStart: Bool(false)})
+		if _, err := client.ListModels(t.Context()); !errors.Is(err, ErrClientStopped) {
+			t.Errorf("Expected ErrClientStopped from ListModels, got %v", err)
+		}
+		if _, err := client.CreateSession(t.Context(), &SessionConfig{OnPermissionRequest: PermissionHandler.ApproveAll}); !errors.Is(err, ErrClientStopped) {
+			t.Errorf("Expected ErrClientStopped from CreateSession, got %v", err)
+		}
+	})
+}
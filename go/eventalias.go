@@ -0,0 +1,118 @@
+package copilot
+
+import "maps"
+
+// maxUnknownEventTypes bounds the distinct unknown event types counted in
+// [DiagnosticBundle.UnknownEventTypes].
+const maxUnknownEventTypes = 100
+
+// eventAlias maps an event type a CLI emits under another name to the type
+// the SDK knows, adapting the payload where the shapes differ.
+type eventAlias struct {
+	to    SessionEventType
+	adapt func(*Data)
+}
+
+// eventAliases lists, by SDK protocol version, the event types CLIs speaking
+// that version may emit under names the SDK no longer uses. The table for the
+// protocol version negotiated with the CLI is applied to every session event
+// before it is delivered, so handlers written against the current constants
+// see the current names.
+var eventAliases = map[int]map[SessionEventType]eventAlias{
+	2: {
+		// Failed tool executions were reported separately before they were
+		// folded into tool.execution_complete with success false
+		"tool.execution_error": {to: ToolExecutionComplete, adapt: func(data *Data) {
+			if data.Success == nil {
+				data.Success = Bool(false)
+			}
+		}},
+	},
+}
+
+// knownEventTypes are the event types the SDK handles. Events of other types
+// are still delivered, and counted in [DiagnosticBundle.UnknownEventTypes].
+var knownEventTypes = map[SessionEventType]bool{
+	Abort: true, AssistantIntent: true, AssistantMessage: true, AssistantMessageDelta: true,
+	AssistantReasoning: true, AssistantReasoningDelta: true, AssistantStreamingDelta: true,
+	AssistantTurnEnd: true, AssistantTurnStart: true, AssistantUsage: true, HookEnd: true,
+	HookStart: true, PendingMessagesModified: true, SessionCompactionComplete: true,
+	SessionCompactionStart: true, SessionContextChanged: true, SessionError: true,
+	SessionHandoff: true, SessionIdle: true, SessionInfo: true, SessionModeChanged: true,
+	SessionModelChange: true, SessionPlanChanged: true, SessionResume: true,
+	SessionShutdown: true, SessionSnapshotRewind: true, SessionStart: true,
+	SessionTaskComplete: true, SessionTitleChanged: true, SessionTruncation: true,
+	SessionUsageInfo: true, SessionWarning: true, SessionWorkspaceFileChanged: true,
+	SkillInvoked: true, SubagentCompleted: true, SubagentDeselected: true,
+	SubagentFailed: true, SubagentSelected: true, SubagentStarted: true, SystemMessage: true,
+	ToolExecutionComplete: true, ToolExecutionPartialResult: true, ToolExecutionProgress: true,
+	ToolExecutionStart: true, ToolUserRequested: true, UserMessage: true,
+	// Emitted by the CLI but not generated, as it carries no public payload
+	"session.import_legacy": true,
+	// Emitted by the SDK itself
+	SessionCheckpointCreated: true, SessionModelFallback: true, SessionSharedContextInjected: true,
+	SessionSummaryChanged: true, SessionWorkspacePruned: true,
+}
+
+// eventAliasTable is the alias table in effect for a connection.
+type eventAliasTable struct {
+	aliases map[SessionEventType]eventAlias
+}
+
+// newEventAliasTable combines the built-in aliases of protocolVersion with
+// the caller's [ClientOptions.EventAliases], which take precedence.
+func newEventAliasTable(protocolVersion int, custom map[string]SessionEventType) *eventAliasTable {
+	aliases := maps.Clone(eventAliases[protocolVersion])
+	if aliases == nil {
+		aliases = make(map[SessionEventType]eventAlias)
+	}
+	for from, to := range custom {
+		aliases[SessionEventType(from)] = eventAlias{to: to}
+	}
+	return &eventAliasTable{aliases: aliases}
+}
+
+// resolve renames an aliased event to its current type. It reports false if
+// the resulting type is unknown to the SDK.
+func (t *eventAliasTable) resolve(event *SessionEvent) bool {
+	if t != nil {
+		if alias, ok := t.aliases[event.Type]; ok {
+			event.Type = alias.to
+			if alias.adapt != nil {
+				alias.adapt(&event.Data)
+			}
+		}
+	}
+	return knownEventTypes[event.Type]
+}
+
+// resolveEventType applies the connection's alias table to an event from the
+// CLI, and counts event types that remain unknown.
+func (c *Client) resolveEventType(event *SessionEvent) {
+	if !c.eventAliases.Load().resolve(event) {
+		c.diagnostics.recordUnknownEventType(event.Type)
+	}
+}
+
+// recordUnknownEventType counts an event of a type the SDK does not know.
+func (r *diagnosticsRecorder) recordUnknownEventType(eventType SessionEventType) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.unknownEventTypes == nil {
+		r.unknownEventTypes = make(map[string]int)
+	}
+	if _, ok := r.unknownEventTypes[string(eventType)]; !ok && len(r.unknownEventTypes) >= maxUnknownEventTypes {
+		return
+	}
+	r.unknownEventTypes[string(eventType)]++
+}
+
+// unknownEventTypeCounts returns how often each unknown event type was seen.
+func (r *diagnosticsRecorder) unknownEventTypeCounts() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return maps.Clone(r.unknownEventTypes)
+}
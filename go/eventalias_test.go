@@ -0,0 +1,105 @@
+package copilot
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEventAliasTable(t *testing.T) {
+	tests := []struct {
+		name            string
+		protocolVersion int
+		custom          map[string]SessionEventType
+		event           SessionEvent
+		wantType        SessionEventType
+		wantSuccess     *bool
+		wantKnown       bool
+	}{
+		{
+			name:            "current types are unchanged",
+			protocolVersion: SdkProtocolVersion,
+			event:           SessionEvent{Type: ToolExecutionComplete, Data: Data{Success: Bool(true)}},
+			wantType:        ToolExecutionComplete,
+			wantSuccess:     Bool(true),
+			wantKnown:       true,
+		},
+		{
+			name:            "renames tool.execution_error to a failed completion",
+			protocolVersion: 2,
+			event:           SessionEvent{Type: "tool.execution_error"},
+			wantType:        ToolExecutionComplete,
+			wantSuccess:     Bool(false),
+			wantKnown:       true,
+		},
+		{
+			name:            "leaves aliases of other protocol versions alone",
+			protocolVersion: 3,
+			event:           SessionEvent{Type: "tool.execution_error"},
+			wantType:        "tool.execution_error",
+		},
+		{
+			name:            "applies caller aliases",
+			protocolVersion: SdkProtocolVersion,
+			custom:          map[string]SessionEventType{"session.renamed": SessionTitleChanged},
+			event:           SessionEvent{Type: "session.renamed"},
+			wantType:        SessionTitleChanged,
+			wantKnown:       true,
+		},
+		{
+			name:            "prefers caller aliases over built-in ones",
+			protocolVersion: 2,
+			custom:          map[string]SessionEventType{"tool.execution_error": SessionError},
+			event:           SessionEvent{Type: "tool.execution_error"},
+			wantType:        SessionError,
+			wantKnown:       true,
+		},
+		{
+			name:            "reports unknown types",
+			protocolVersion: SdkProtocolVersion,
+			event:           SessionEvent{Type: "session.something_new"},
+			wantType:        "session.something_new",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := tt.event
+			known := newEventAliasTable(tt.protocolVersion, tt.custom).resolve(&event)
+			if event.Type != tt.wantType || known != tt.wantKnown {
+				t.Errorf("Expected %q (known %v), got %q (known %v)", tt.wantType, tt.wantKnown, event.Type, known)
+			}
+			if (tt.wantSuccess == nil) != (event.Data.Success == nil) ||
+				(tt.wantSuccess != nil && *tt.wantSuccess != *event.Data.Success) {
+				t.Errorf("Expected success %v, got %v", tt.wantSuccess, event.Data.Success)
+			}
+		})
+	}
+}
+
+func TestClient_UnknownEventTypes(t *testing.T) {
+	session, _ := newTestSession(t, nil)
+	client := NewClient(nil)
+	client.sessions.put(session)
+	client.eventAliases.Store(newEventAliasTable(2, nil))
+
+	received := make(chan SessionEventType, 3)
+	session.On(func(event SessionEvent) { received <- event.Type })
+	for _, eventType := range []SessionEventType{"tool.execution_error", "session.something_new", "session.something_new"} {
+		client.handleSessionEvent(sessionEventRequest{SessionID: session.SessionID, Event: SessionEvent{Type: eventType}})
+	}
+	for _, want := range []SessionEventType{ToolExecutionComplete, "session.something_new", "session.something_new"} {
+		if got := <-received; got != want {
+			t.Errorf("Expected %q to be delivered, got %q", want, got)
+		}
+	}
+	counts := client.diagnostics.unknownEventTypeCounts()
+	if len(counts) != 1 || counts["session.something_new"] != 2 {
+		t.Errorf("Expected two unknown events counted, got %v", counts)
+	}
+
+	for i := range maxUnknownEventTypes + 10 {
+		client.diagnostics.recordUnknownEventType(SessionEventType(fmt.Sprintf("unknown.%d", i)))
+	}
+	if counts := client.diagnostics.unknownEventTypeCounts(); len(counts) != maxUnknownEventTypes {
+		t.Errorf("Expected at most %d unknown types, got %d", maxUnknownEventTypes, len(counts))
+	}
+}
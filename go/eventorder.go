@@ -0,0 +1,188 @@
+package copilot
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// EventOrderOptions configures the event ordering guard of a session.
+//
+// Events normally reach [Session.On] handlers in the order the CLI sends them.
+// After a reconnect, backfilled events may arrive behind newer ones. With the
+// guard enabled, events that arrive with a timestamp older than an event
+// already delivered are counted in [Session.EventOrderStats] and reported to
+// OnOutOfOrder. Setting ReorderWindow additionally holds events briefly so
+// that they can be delivered in timestamp order.
+//
+// The guard only affects live delivery. [Session.GetMessages] returns the
+// session history in its authoritative order and should be preferred when
+// order matters more than latency.
+type EventOrderOptions struct {
+	// ReorderWindow is how long events are held before delivery so that late
+	// arrivals can be sorted in front of them. Zero delivers events
+	// immediately and only flags those that are out of order.
+	ReorderWindow time.Duration
+	// OnOutOfOrder is called for each event delivered with a timestamp older
+	// than the newest event already delivered, before handlers see it.
+	// latest is the timestamp of that newest event.
+	OnOutOfOrder func(event SessionEvent, latest time.Time)
+}
+
+// EventOrderStats counts events seen by a session's event ordering guard.
+type EventOrderStats struct {
+	// Delivered is the number of events delivered to handlers
+	Delivered int
+	// Reordered is the number of events that arrived out of order but were
+	// delivered in order thanks to the reorder window
+	Reordered int
+	// OutOfOrder is the number of events delivered out of order
+	OutOfOrder int
+}
+
+// bufferedEvent is an event held by the guard until its delivery time.
+type bufferedEvent struct {
+	event SessionEvent
+	key   time.Time // sort key; events without a timestamp keep their place
+	due   time.Time
+	late  bool // arrived behind a newer event
+}
+
+// eventOrderGuard tracks event timestamps on dispatch and optionally delays
+// events to deliver them in timestamp order.
+type eventOrderGuard struct {
+	opts    EventOrderOptions
+	deliver func(SessionEvent)
+
+	mu         sync.Mutex
+	deliverMu  sync.Mutex // serializes delivery from dispatch and timer flushes
+	buffer     []bufferedEvent
+	newestSeen time.Time
+	newestSent time.Time
+	stats      EventOrderStats
+	timer      *time.Timer
+	stopped    bool
+	now        func() time.Time
+}
+
+func newEventOrderGuard(opts EventOrderOptions, deliver func(SessionEvent)) *eventOrderGuard {
+	return &eventOrderGuard{
+		opts:    opts,
+		deliver: deliver,
+		now:     time.Now,
+	}
+}
+
+// add accepts an event from the dispatcher and delivers every event that is
+// due, in timestamp order.
+func (g *eventOrderGuard) add(event SessionEvent) {
+	g.mu.Lock()
+	if g.stopped {
+		g.mu.Unlock()
+		return
+	}
+	late := !event.Timestamp.IsZero() && event.Timestamp.Before(g.newestSeen)
+	if event.Timestamp.After(g.newestSeen) {
+		g.newestSeen = event.Timestamp
+	}
+	key := event.Timestamp
+	if key.IsZero() {
+		key = g.newestSeen
+	}
+	g.buffer = append(g.buffer, bufferedEvent{
+		event: event,
+		key:   key,
+		due:   g.now().Add(g.opts.ReorderWindow),
+		late:  late,
+	})
+	sort.SliceStable(g.buffer, func(i, j int) bool {
+		return g.buffer[i].key.Before(g.buffer[j].key)
+	})
+	g.mu.Unlock()
+
+	g.flush()
+}
+
+// flush delivers buffered events from the front of the buffer while they are
+// due, then arms a timer for the next one.
+func (g *eventOrderGuard) flush() {
+	g.deliverMu.Lock()
+	defer g.deliverMu.Unlock()
+
+	for {
+		g.mu.Lock()
+		if g.stopped || len(g.buffer) == 0 {
+			g.mu.Unlock()
+			return
+		}
+		next := g.buffer[0]
+		if wait := next.due.Sub(g.now()); wait > 0 {
+			g.scheduleLocked(wait)
+			g.mu.Unlock()
+			return
+		}
+		g.buffer = g.buffer[1:]
+
+		var latest time.Time
+		outOfOrder := !next.event.Timestamp.IsZero() && next.event.Timestamp.Before(g.newestSent)
+		if outOfOrder {
+			latest = g.newestSent
+			g.stats.OutOfOrder++
+		} else if next.late {
+			g.stats.Reordered++
+		}
+		if next.event.Timestamp.After(g.newestSent) {
+			g.newestSent = next.event.Timestamp
+		}
+		g.stats.Delivered++
+		g.mu.Unlock()
+
+		if outOfOrder && g.opts.OnOutOfOrder != nil {
+			g.opts.OnOutOfOrder(next.event, latest)
+		}
+		g.deliver(next.event)
+	}
+}
+
+// scheduleLocked arms the flush timer. Callers must hold g.mu.
+func (g *eventOrderGuard) scheduleLocked(wait time.Duration) {
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+	g.timer = time.AfterFunc(wait, g.flush)
+}
+
+// snapshot returns the current counters.
+func (g *eventOrderGuard) snapshot() EventOrderStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stats
+}
+
+// stop drops buffered events and cancels the flush timer.
+func (g *eventOrderGuard) stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stopped = true
+	g.buffer = nil
+	if g.timer != nil {
+		g.timer.Stop()
+	}
+}
+
+// EventOrderStats returns the counters of the session's event ordering guard.
+// All counters are zero unless [SessionConfig.EventOrder] or
+// [ResumeSessionConfig.EventOrder] was set.
+func (s *Session) EventOrderStats() EventOrderStats {
+	if s.eventOrder == nil {
+		return EventOrderStats{}
+	}
+	return s.eventOrder.snapshot()
+}
+
+// buffered returns the number of events held back.
+func (g *eventOrderGuard) buffered() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.buffer)
+}
@@ -0,0 +1,201 @@
+package copilot
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func orderedEvent(id string, second int) SessionEvent {
+	return SessionEvent{
+		ID:        id,
+		Type:      AssistantMessage,
+		Timestamp: time.Date(2026, 3, 1, 12, 0, second, 0, time.UTC),
+	}
+}
+
+func collectIDs(session *Session) func() []string {
+	var mu sync.Mutex
+	var ids []string
+	session.On(func(event SessionEvent) {
+		mu.Lock()
+		ids = append(ids, event.ID)
+		mu.Unlock()
+	})
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), ids...)
+	}
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSession_EventOrderGuard(t *testing.T) {
+	shuffled := []SessionEvent{
+		orderedEvent("a", 1),
+		orderedEvent("c", 3),
+		orderedEvent("b", 2),
+		orderedEvent("e", 5),
+		orderedEvent("d", 4),
+	}
+
+	t.Run("delivers in arrival order without a guard", func(t *testing.T) {
+		session := &Session{}
+		ids := collectIDs(session)
+		for _, event := range shuffled {
+			session.dispatchEvent(event)
+		}
+		if got := ids(); !equalIDs(got, []string{"a", "c", "b", "e", "d"}) {
+			t.Errorf("Expected arrival order, got %v", got)
+		}
+		if stats := session.EventOrderStats(); stats != (EventOrderStats{}) {
+			t.Errorf("Expected zero stats, got %+v", stats)
+		}
+	})
+
+	t.Run("flags out-of-order events without a window", func(t *testing.T) {
+		session := &Session{}
+		var flagged []string
+		session.eventOrder = newEventOrderGuard(EventOrderOptions{
+			OnOutOfOrder: func(event SessionEvent, latest time.Time) {
+				if !event.Timestamp.Before(latest) {
+					t.Errorf("Expected %s to be older than %v", event.ID, latest)
+				}
+				flagged = append(flagged, event.ID)
+			},
+		}, session.deliverEvent)
+		ids := collectIDs(session)
+
+		for _, event := range shuffled {
+			session.dispatchEvent(event)
+		}
+
+		if got := ids(); !equalIDs(got, []string{"a", "c", "b", "e", "d"}) {
+			t.Errorf("Expected immediate delivery in arrival order, got %v", got)
+		}
+		if !equalIDs(flagged, []string{"b", "d"}) {
+			t.Errorf("Expected b and d to be flagged, got %v", flagged)
+		}
+		if stats := session.EventOrderStats(); stats != (EventOrderStats{Delivered: 5, OutOfOrder: 2}) {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("reorders within the window", func(t *testing.T) {
+		session := &Session{}
+		now := time.Now()
+		var flagged int
+		session.eventOrder = newEventOrderGuard(EventOrderOptions{
+			ReorderWindow: time.Hour,
+			OnOutOfOrder:  func(SessionEvent, time.Time) { flagged++ },
+		}, session.deliverEvent)
+		session.eventOrder.now = func() time.Time { return now }
+		ids := collectIDs(session)
+
+		for _, event := range shuffled {
+			session.dispatchEvent(event)
+		}
+		if got := ids(); len(got) != 0 {
+			t.Fatalf("Expected events to be held, got %v", got)
+		}
+
+		now = now.Add(time.Hour)
+		session.eventOrder.flush()
+
+		if got := ids(); !equalIDs(got, []string{"a", "b", "c", "d", "e"}) {
+			t.Errorf("Expected timestamp order, got %v", got)
+		}
+		if flagged != 0 {
+			t.Errorf("Expected no events to be flagged, got %d", flagged)
+		}
+		if stats := session.EventOrderStats(); stats != (EventOrderStats{Delivered: 5, Reordered: 2}) {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("flags events arriving after the window", func(t *testing.T) {
+		session := &Session{}
+		now := time.Now()
+		session.eventOrder = newEventOrderGuard(EventOrderOptions{ReorderWindow: time.Second}, session.deliverEvent)
+		session.eventOrder.now = func() time.Time { return now }
+		ids := collectIDs(session)
+
+		session.dispatchEvent(orderedEvent("b", 2))
+		now = now.Add(2 * time.Second)
+		session.eventOrder.flush() // the window timer firing
+		session.dispatchEvent(orderedEvent("a", 1))
+		now = now.Add(2 * time.Second)
+		session.eventOrder.flush()
+
+		if got := ids(); !equalIDs(got, []string{"b", "a"}) {
+			t.Errorf("Expected late event after the window, got %v", got)
+		}
+		if stats := session.EventOrderStats(); stats != (EventOrderStats{Delivered: 2, OutOfOrder: 1}) {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("keeps events without timestamps in place", func(t *testing.T) {
+		session := &Session{}
+		now := time.Now()
+		session.eventOrder = newEventOrderGuard(EventOrderOptions{ReorderWindow: time.Hour}, session.deliverEvent)
+		session.eventOrder.now = func() time.Time { return now }
+		ids := collectIDs(session)
+
+		session.dispatchEvent(orderedEvent("a", 1))
+		session.dispatchEvent(SessionEvent{ID: "x"})
+		session.dispatchEvent(orderedEvent("b", 2))
+		now = now.Add(time.Hour)
+		session.eventOrder.flush()
+
+		if got := ids(); !equalIDs(got, []string{"a", "x", "b"}) {
+			t.Errorf("Expected untimestamped event to keep its place, got %v", got)
+		}
+	})
+
+	t.Run("delivers held events when the window elapses", func(t *testing.T) {
+		session := &Session{}
+		session.eventOrder = newEventOrderGuard(EventOrderOptions{ReorderWindow: 10 * time.Millisecond}, session.deliverEvent)
+		delivered := make(chan string, 2)
+		session.On(func(event SessionEvent) { delivered <- event.ID })
+
+		session.dispatchEvent(orderedEvent("b", 2))
+		session.dispatchEvent(orderedEvent("a", 1))
+
+		for _, want := range []string{"a", "b"} {
+			select {
+			case got := <-delivered:
+				if got != want {
+					t.Errorf("Expected %s, got %s", want, got)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Timed out waiting for held events")
+			}
+		}
+	})
+
+	t.Run("drops held events when the session is destroyed", func(t *testing.T) {
+		session := &Session{}
+		session.eventOrder = newEventOrderGuard(EventOrderOptions{ReorderWindow: time.Hour}, session.deliverEvent)
+		ids := collectIDs(session)
+
+		session.dispatchEvent(orderedEvent("a", 1))
+		session.markDestroyed("destroyed by caller")
+		session.eventOrder.flush()
+
+		if got := ids(); len(got) != 0 {
+			t.Errorf("Expected no events after destroy, got %v", got)
+		}
+	})
+}
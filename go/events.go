@@ -0,0 +1,120 @@
+package copilot
+
+import "time"
+
+// SessionCheckpointCreated is emitted when the session writes a workspace
+// checkpoint. CLIs that predate this event report checkpoints on
+// session.compaction_complete instead; [SessionEvent.AsCheckpointCreated]
+// accepts both.
+const SessionCheckpointCreated SessionEventType = "session.checkpoint_created"
+
+// CompactionStartData is the payload of a session.compaction_start event.
+type CompactionStartData struct {
+	// Reason is why compaction was triggered, or empty if not reported
+	Reason string `json:"reason"`
+	// CurrentTokens is the context size that triggered compaction, or zero if not reported
+	CurrentTokens float64 `json:"currentTokens"`
+	// TokenLimit is the model's context window, or zero if not reported
+	TokenLimit float64 `json:"tokenLimit"`
+}
+
+// CompactionCompleteData is the payload of a session.compaction_complete event.
+type CompactionCompleteData struct {
+	// Success reports whether compaction succeeded
+	Success bool `json:"success"`
+	// Error describes the failure when Success is false
+	Error string `json:"-"`
+	// PreCompactionTokens is the context size before compaction
+	PreCompactionTokens float64 `json:"preCompactionTokens"`
+	// PostCompactionTokens is the context size after compaction
+	PostCompactionTokens float64 `json:"postCompactionTokens"`
+	// TokensRemoved is the number of tokens removed from the context
+	TokensRemoved float64 `json:"tokensRemoved"`
+	// PreCompactionMessagesLength is the number of messages before compaction
+	PreCompactionMessagesLength float64 `json:"preCompactionMessagesLength"`
+	// MessagesRemoved is the number of messages removed from the context
+	MessagesRemoved float64 `json:"messagesRemoved"`
+	// Duration is how long compaction took, or zero if not reported
+	Duration time.Duration `json:"-"`
+	// Summary is the summary that replaced the removed messages
+	Summary string `json:"summaryContent"`
+	// Checkpoint is the checkpoint written for the compacted history, or nil if none was created
+	Checkpoint *CheckpointData `json:"-"`
+	// TokensUsed is the model usage of the summarization request, or nil if not reported
+	TokensUsed *CompactionTokensUsed `json:"compactionTokensUsed"`
+	// RequestID is the ID of the summarization request
+	RequestID string `json:"requestId"`
+}
+
+// CheckpointData identifies a workspace checkpoint.
+type CheckpointData struct {
+	// Number is the sequence number of the checkpoint within the session
+	Number int `json:"checkpointNumber"`
+	// Path is the checkpoint's location in the session workspace
+	Path string `json:"checkpointPath"`
+}
+
+// AsCompactionStart returns the typed payload of a session.compaction_start
+// event. The second return value is false for any other event type.
+func (e SessionEvent) AsCompactionStart() (*CompactionStartData, bool) {
+	if e.Type != SessionCompactionStart {
+		return nil, false
+	}
+	var data CompactionStartData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// AsCompactionComplete returns the typed payload of a
+// session.compaction_complete event. The second return value is false for any
+// other event type.
+//
+// Example:
+//
+//	session.On(func(event copilot.SessionEvent) {
+//	    if data, ok := event.AsCompactionComplete(); ok && data.Success {
+//	        fmt.Printf("Compacted %.0f -> %.0f tokens\n", data.PreCompactionTokens, data.PostCompactionTokens)
+//	    }
+//	})
+func (e SessionEvent) AsCompactionComplete() (*CompactionCompleteData, bool) {
+	if e.Type != SessionCompactionComplete {
+		return nil, false
+	}
+	var data CompactionCompleteData
+	var aux struct {
+		errorPayload
+		durationPayload
+	}
+	e.decodePayload(&data, &aux)
+	data.Error, _ = aux.message()
+	data.Duration = millis(aux.Duration)
+	data.Checkpoint, _ = e.checkpoint()
+	return &data, true
+}
+
+// AsCheckpointCreated returns the checkpoint reported by a
+// session.checkpoint_created event, or by a session.compaction_complete event
+// that created one. The second return value is false otherwise.
+func (e SessionEvent) AsCheckpointCreated() (*CheckpointData, bool) {
+	if e.Type != SessionCheckpointCreated && e.Type != SessionCompactionComplete {
+		return nil, false
+	}
+	return e.checkpoint()
+}
+
+// checkpoint returns the checkpoint fields of the event, if it has any.
+func (e SessionEvent) checkpoint() (*CheckpointData, bool) {
+	if e.Data.CheckpointNumber == nil && e.Data.CheckpointPath == nil {
+		return nil, false
+	}
+	var data CheckpointData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,114 @@
+package copilot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionEvent_AsCompactionComplete(t *testing.T) {
+	t.Run("decodes CLI output", func(t *testing.T) {
+		event, err := UnmarshalSessionEvent([]byte(`{
+			"id": "evt-1",
+			"timestamp": "2026-01-01T00:00:00Z",
+			"parentId": null,
+			"type": "session.compaction_complete",
+			"data": {
+				"success": true,
+				"preCompactionTokens": 120000,
+				"postCompactionTokens": 18000,
+				"preCompactionMessagesLength": 42,
+				"messagesRemoved": 30,
+				"tokensRemoved": 102000,
+				"summaryContent": "The user asked for a story.",
+				"checkpointNumber": 3,
+				"checkpointPath": "checkpoints/003.md",
+				"compactionTokensUsed": {"input": 1000, "output": 200, "cachedInput": 50},
+				"requestId": "req-1",
+				"duration": 1500
+			}
+		}`))
+		if err != nil {
+			t.Fatalf("Failed to unmarshal event: %v", err)
+		}
+
+		data, ok := event.AsCompactionComplete()
+		if !ok {
+			t.Fatal("Expected compaction complete payload")
+		}
+		if !data.Success || data.PreCompactionTokens != 120000 || data.PostCompactionTokens != 18000 || data.TokensRemoved != 102000 {
+			t.Errorf("Unexpected token counts: %+v", data)
+		}
+		if data.PreCompactionMessagesLength != 42 || data.MessagesRemoved != 30 {
+			t.Errorf("Unexpected message counts: %+v", data)
+		}
+		if data.Duration != 1500*time.Millisecond {
+			t.Errorf("Expected 1.5s duration, got %v", data.Duration)
+		}
+		if data.Checkpoint == nil || data.Checkpoint.Number != 3 || data.Checkpoint.Path != "checkpoints/003.md" {
+			t.Errorf("Unexpected checkpoint: %+v", data.Checkpoint)
+		}
+		if data.TokensUsed == nil || data.TokensUsed.Input != 1000 || data.RequestID != "req-1" {
+			t.Errorf("Unexpected usage: %+v", data)
+		}
+
+		checkpoint, ok := event.AsCheckpointCreated()
+		if !ok || checkpoint.Number != 3 {
+			t.Errorf("Expected checkpoint from compaction event, got %+v", checkpoint)
+		}
+	})
+
+	t.Run("reports errors", func(t *testing.T) {
+		event, err := UnmarshalSessionEvent([]byte(`{"id": "evt-1", "timestamp": "2026-01-01T00:00:00Z", "parentId": null,
+			"type": "session.compaction_complete", "data": {"success": false, "error": "summarization failed"}}`))
+		if err != nil {
+			t.Fatalf("Failed to unmarshal event: %v", err)
+		}
+		data, ok := event.AsCompactionComplete()
+		if !ok || data.Success || data.Error != "summarization failed" || data.Checkpoint != nil {
+			t.Errorf("Unexpected payload: %+v", data)
+		}
+		if _, ok := event.AsCheckpointCreated(); ok {
+			t.Error("Expected no checkpoint for a failed compaction")
+		}
+	})
+
+	t.Run("rejects other event types", func(t *testing.T) {
+		event := SessionEvent{Type: SessionIdle}
+		if _, ok := event.AsCompactionComplete(); ok {
+			t.Error("Expected AsCompactionComplete to reject session.idle")
+		}
+		if _, ok := event.AsCompactionStart(); ok {
+			t.Error("Expected AsCompactionStart to reject session.idle")
+		}
+		if _, ok := event.AsCheckpointCreated(); ok {
+			t.Error("Expected AsCheckpointCreated to reject session.idle")
+		}
+	})
+}
+
+func TestSessionEvent_AsCompactionStart(t *testing.T) {
+	event, err := UnmarshalSessionEvent([]byte(`{"id": "evt-1", "timestamp": "2026-01-01T00:00:00Z", "parentId": null,
+		"type": "session.compaction_start", "data": {}}`))
+	if err != nil {
+		t.Fatalf("Failed to unmarshal event: %v", err)
+	}
+	data, ok := event.AsCompactionStart()
+	if !ok || *data != (CompactionStartData{}) {
+		t.Errorf("Expected empty compaction start payload, got %+v", data)
+	}
+}
+
+func TestSessionEvent_AsCheckpointCreated(t *testing.T) {
+	event, err := UnmarshalSessionEvent([]byte(`{"id": "evt-1", "timestamp": "2026-01-01T00:00:00Z", "parentId": null,
+		"type": "session.checkpoint_created", "data": {"checkpointNumber": 1, "checkpointPath": "checkpoints/001.md"}}`))
+	if err != nil {
+		t.Fatalf("Failed to unmarshal event: %v", err)
+	}
+	if event.Type != SessionCheckpointCreated {
+		t.Errorf("Expected %s, got %s", SessionCheckpointCreated, event.Type)
+	}
+	checkpoint, ok := event.AsCheckpointCreated()
+	if !ok || checkpoint.Number != 1 || checkpoint.Path != "checkpoints/001.md" {
+		t.Errorf("Unexpected checkpoint: %+v", checkpoint)
+	}
+}
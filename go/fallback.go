@@ -0,0 +1,108 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SessionModelFallback is emitted by the SDK when a message was rejected
+// because the session's model is rate limited or unavailable, and the session
+// switched to the next model in [SessionConfig.ModelFallbacks]. The event's
+// Data.PreviousModel and Data.NewModel name the models, and Data.Message
+// describes the rejection.
+const SessionModelFallback SessionEventType = "session.model_fallback"
+
+// ModelFallback describes an automatic switch to a fallback model.
+type ModelFallback struct {
+	// From is the model that was rejected, or empty if the session was using
+	// the CLI's default model
+	From string
+	// To is the fallback model the message was retried with
+	To string
+	// Reason is the *[RateLimitError] or *[ModelUnavailableError] that
+	// triggered the fallback
+	Reason error
+}
+
+// modelFallbackChain tracks a session's position in its fallback chain.
+type modelFallbackChain struct {
+	mu        sync.Mutex
+	current   string
+	fallbacks []string
+	next      int
+}
+
+func newModelFallbackChain(model string, fallbacks []string) *modelFallbackChain {
+	if len(fallbacks) == 0 {
+		return nil
+	}
+	return &modelFallbackChain{current: model, fallbacks: append([]string(nil), fallbacks...)}
+}
+
+// advance moves to the next model in the chain, skipping the current one.
+// It returns false when the chain is exhausted.
+func (c *modelFallbackChain) advance() (from, to string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.next < len(c.fallbacks) {
+		to = c.fallbacks[c.next]
+		c.next++
+		if to != c.current {
+			from, c.current = c.current, to
+			return from, to, true
+		}
+	}
+	return "", "", false
+}
+
+// switchModel switches the session to a fallback model.
+func (s *Session) switchModel(ctx context.Context, to string) error {
+	if _, err := s.rpcClient().RequestContext(ctx, "session.model.switchTo", map[string]any{
+		"sessionId": s.SessionID,
+		"modelId":   to,
+	}); err != nil {
+		return fmt.Errorf("failed to switch to fallback model %s: %w", to, err)
+	}
+	s.reattachRequest.Model = to
+	s.config.setModel(to)
+	return nil
+}
+
+// isModelFallbackError reports whether err should trigger a model fallback.
+func isModelFallbackError(err error) bool {
+	var rateLimitErr *RateLimitError
+	var unavailableErr *ModelUnavailableError
+	return errors.As(err, &rateLimitErr) || errors.As(err, &unavailableErr)
+}
+
+// fallBack switches the session to the next model in its fallback chain
+// after reason rejected a message, and emits a session.model_fallback event.
+// It returns nil if the chain is exhausted.
+func (s *Session) fallBack(ctx context.Context, reason error) (*ModelFallback, error) {
+	from, to, ok := s.modelFallbacks.advance()
+	if !ok {
+		return nil, nil
+	}
+	if err := s.switchModel(ctx, to); err != nil {
+		return nil, err
+	}
+
+	fallback := &ModelFallback{From: from, To: to, Reason: reason}
+	event := SessionEvent{
+		Type:      SessionModelFallback,
+		Timestamp: time.Now(),
+		Ephemeral: Bool(true),
+		Data: Data{
+			Message:  String(fmt.Sprintf("switched to fallback model %s: %v", to, reason)),
+			NewModel: String(to),
+		},
+	}
+	if from != "" {
+		event.Data.PreviousModel = String(from)
+	}
+	s.dispatchEvent(event)
+	return fallback, nil
+}
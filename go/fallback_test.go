@@ -0,0 +1,155 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+// newFallbackTestSession returns a session whose fake server rejects sends
+// while the current model is listed in rejections.
+func newFallbackTestSession(t *testing.T, model string, fallbacks []string, rejections map[string]*jsonrpc2.Error) (*Session, *fakeServer, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	current := model
+	var sentWith []string
+	var server *fakeServer
+	var session *Session
+	session, server = newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch method {
+		case "session.model.switchTo":
+			var req struct {
+				ModelID string `json:"modelId"`
+			}
+			json.Unmarshal(params, &req)
+			current = req.ModelID
+			return map[string]any{"modelId": req.ModelID}, nil
+		case "session.send":
+			sentWith = append(sentWith, current)
+			if rpcErr := rejections[current]; rpcErr != nil {
+				return nil, rpcErr
+			}
+			go func() {
+				server.emit(SessionEvent{Type: AssistantMessage, Data: Data{Content: String("answer from " + current)}})
+				server.emit(SessionEvent{Type: SessionIdle})
+			}()
+			return sessionSendResponse{MessageID: "msg"}, nil
+		}
+		return nil, nil
+	})
+	session.reattachRequest.Model = model
+	session.modelFallbacks = newModelFallbackChain(model, fallbacks)
+	return session, server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), sentWith...)
+	}
+}
+
+func TestSession_ModelFallbacks(t *testing.T) {
+	rateLimited := &jsonrpc2.Error{Code: -32000, Message: "Too many requests", Data: map[string]any{"statusCode": 429}}
+	unavailable := &jsonrpc2.Error{Code: -32000, Message: "Model premium is not available", Data: map[string]any{"code": "model_not_available"}}
+
+	t.Run("retries a rate limited turn with the next model", func(t *testing.T) {
+		session, _, sent := newFallbackTestSession(t, "premium", []string{"standard", "mini"}, map[string]*jsonrpc2.Error{"premium": rateLimited})
+		var events []SessionEvent
+		session.On(func(event SessionEvent) {
+			if event.Type == SessionModelFallback {
+				events = append(events, event)
+			}
+		})
+
+		result, err := session.SendAndCollect(t.Context(), MessageOptions{Prompt: "hi"}, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := *result.FinalMessage.Data.Content; got != "answer from standard" {
+			t.Errorf("Expected the fallback model to answer, got %q", got)
+		}
+		fallback := result.ModelFallback
+		var rateLimitErr *RateLimitError
+		if fallback == nil || fallback.From != "premium" || fallback.To != "standard" || !errors.As(fallback.Reason, &rateLimitErr) {
+			t.Errorf("Expected a fallback from premium to standard, got %+v", fallback)
+		}
+		if want := []string{"premium", "standard"}; !reflect.DeepEqual(sent(), want) {
+			t.Errorf("Expected sends with %v, got %v", want, sent())
+		}
+		if len(events) != 1 || *events[0].Data.PreviousModel != "premium" || *events[0].Data.NewModel != "standard" {
+			t.Errorf("Expected one fallback event, got %+v", events)
+		}
+		if session.reattachRequest.Model != "standard" {
+			t.Errorf("Expected restarts to resume with the fallback model, got %q", session.reattachRequest.Model)
+		}
+
+		// The session stays on the fallback model
+		result, err = session.SendAndCollect(t.Context(), MessageOptions{Prompt: "again"}, nil)
+		if err != nil || result.ModelFallback != nil {
+			t.Errorf("Expected the next turn to run without a fallback, got %+v, %v", result, err)
+		}
+	})
+
+	t.Run("falls back when the model is unavailable", func(t *testing.T) {
+		session, _, _ := newFallbackTestSession(t, "premium", []string{"standard"}, map[string]*jsonrpc2.Error{"premium": unavailable})
+		result, err := session.SendAndCollect(t.Context(), MessageOptions{Prompt: "hi"}, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var unavailableErr *ModelUnavailableError
+		if result.ModelFallback == nil || !errors.As(result.ModelFallback.Reason, &unavailableErr) {
+			t.Errorf("Expected a fallback caused by ModelUnavailableError, got %+v", result.ModelFallback)
+		}
+	})
+
+	t.Run("falls back at most once per turn", func(t *testing.T) {
+		session, _, sent := newFallbackTestSession(t, "premium", []string{"standard", "mini"}, map[string]*jsonrpc2.Error{
+			"premium":  rateLimited,
+			"standard": rateLimited,
+		})
+		_, err := session.Send(t.Context(), MessageOptions{Prompt: "hi"})
+		var rateLimitErr *RateLimitError
+		if !errors.As(err, &rateLimitErr) {
+			t.Fatalf("Expected a RateLimitError, got %v", err)
+		}
+		if want := []string{"premium", "standard"}; !reflect.DeepEqual(sent(), want) {
+			t.Errorf("Expected sends with %v, got %v", want, sent())
+		}
+
+		// The next turn continues down the chain
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "hi"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := []string{"premium", "standard", "standard", "mini"}; !reflect.DeepEqual(sent(), want) {
+			t.Errorf("Expected sends with %v, got %v", want, sent())
+		}
+	})
+
+	t.Run("returns the error when the chain is exhausted", func(t *testing.T) {
+		session, _, sent := newFallbackTestSession(t, "premium", []string{"premium"}, map[string]*jsonrpc2.Error{"premium": rateLimited})
+		_, err := session.Send(t.Context(), MessageOptions{Prompt: "hi"})
+		var rateLimitErr *RateLimitError
+		if !errors.As(err, &rateLimitErr) {
+			t.Fatalf("Expected a RateLimitError, got %v", err)
+		}
+		if len(sent()) != 1 {
+			t.Errorf("Expected a single send, got %v", sent())
+		}
+	})
+
+	t.Run("other errors don't fall back", func(t *testing.T) {
+		session, _, sent := newFallbackTestSession(t, "premium", []string{"standard"}, map[string]*jsonrpc2.Error{
+			"premium": {Code: -32000, Message: "invalid attachment"},
+		})
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "hi"}); err == nil {
+			t.Fatal("Expected an error")
+		}
+		if len(sent()) != 1 {
+			t.Errorf("Expected a single send, got %v", sent())
+		}
+	})
+}
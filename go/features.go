@@ -0,0 +1,86 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrFeatureUnsupported matches errors for feature flags in
+// [SessionConfig.Features] that the connected CLI does not support.
+var ErrFeatureUnsupported = errors.New("feature not supported by the connected CLI")
+
+// FeatureUnsupportedError is returned when creating or resuming a session
+// with feature flags the connected CLI does not offer. It matches
+// [ErrFeatureUnsupported].
+type FeatureUnsupportedError struct {
+	// Features lists the requested flags the CLI does not support, sorted
+	Features []string
+	// Supported lists the flags the CLI supports, as returned by
+	// [Client.SupportedFeatures]
+	Supported []string
+}
+
+func (e *FeatureUnsupportedError) Error() string {
+	if len(e.Supported) == 0 {
+		return fmt.Sprintf("%s: %s (the CLI supports no per-session features)", ErrFeatureUnsupported, strings.Join(e.Features, ", "))
+	}
+	return fmt.Sprintf("%s: %s (supported: %s)", ErrFeatureUnsupported, strings.Join(e.Features, ", "), strings.Join(e.Supported, ", "))
+}
+
+func (e *FeatureUnsupportedError) Is(target error) bool { return target == ErrFeatureUnsupported }
+
+// SupportedFeatures returns the experimental feature flags the connected CLI
+// accepts in [SessionConfig.Features], sorted. It is empty for CLIs that do
+// not support per-session features; their experiments can only be set through
+// the environment of the CLI process. If the client is not connected and
+// AutoStart is enabled, this starts the connection.
+//
+// Example:
+//
+//	features, err := client.SupportedFeatures()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if slices.Contains(features, "parallel_tool_calls") {
+//	    config.Features = map[string]bool{"parallel_tool_calls": true}
+//	}
+func (c *Client) SupportedFeatures() ([]string, error) {
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+	return c.supportedFeatures(), nil
+}
+
+// supportedFeatures returns the feature flags reported in the last ping.
+func (c *Client) supportedFeatures() []string {
+	capabilities := c.capabilities.Load()
+	if capabilities == nil {
+		return nil
+	}
+	features := slices.Clone(capabilities.Features)
+	sort.Strings(features)
+	return features
+}
+
+// checkFeatures fails if features requests flags the connected CLI does not
+// support.
+func (c *Client) checkFeatures(features map[string]bool) error {
+	if len(features) == 0 {
+		return nil
+	}
+	supported := c.supportedFeatures()
+	var unsupported []string
+	for name := range features {
+		if !slices.Contains(supported, name) {
+			unsupported = append(unsupported, name)
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+	sort.Strings(unsupported)
+	return &FeatureUnsupportedError{Features: unsupported, Supported: supported}
+}
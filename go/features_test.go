@@ -0,0 +1,112 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestClient_Features(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]map[string]bool{}
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		if method != "session.create" && method != "session.resume" {
+			return nil, nil
+		}
+		var req struct {
+			SessionID string          `json:"sessionId"`
+			Features  map[string]bool `json:"features"`
+		}
+		json.Unmarshal(params, &req)
+		mu.Lock()
+		requests[method+" "+req.SessionID] = req.Features
+		mu.Unlock()
+		return createSessionResponse{SessionID: req.SessionID}, nil
+	})
+	cli.capabilities = &ServerCapabilities{Features: []string{"parallel_tool_calls", "compaction_v2"}}
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	t.Cleanup(func() { client.ForceStop() })
+
+	features, err := client.SupportedFeatures()
+	if err != nil {
+		t.Fatalf("Failed to get features: %v", err)
+	}
+	if !slices.Equal(features, []string{"compaction_v2", "parallel_tool_calls"}) {
+		t.Errorf("Expected the advertised features sorted, got %v", features)
+	}
+	sent := func(key string) (map[string]bool, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		features, ok := requests[key]
+		return features, ok
+	}
+
+	t.Run("forwards supported features", func(t *testing.T) {
+		want := map[string]bool{"parallel_tool_calls": false, "compaction_v2": true}
+		session, err := client.CreateSession(t.Context(), &SessionConfig{
+			SessionID:           "s-create",
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			Features:            want,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if got, _ := sent("session.create s-create"); !maps.Equal(got, want) {
+			t.Errorf("Expected features %v in session.create, got %v", want, got)
+		}
+		if got := session.reattachRequest.Features; !maps.Equal(got, want) {
+			t.Errorf("Expected features %v to be kept for reattaching, got %v", want, got)
+		}
+
+		_, err = client.ResumeSessionWithOptions(t.Context(), "s-resume", &ResumeSessionConfig{
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			Features:            map[string]bool{"compaction_v2": true},
+		})
+		if err != nil {
+			t.Fatalf("Failed to resume session: %v", err)
+		}
+		if got, _ := sent("session.resume s-resume"); !got["compaction_v2"] {
+			t.Errorf("Expected features in session.resume, got %v", got)
+		}
+	})
+
+	t.Run("rejects unsupported features before sending", func(t *testing.T) {
+		_, err := client.CreateSession(t.Context(), &SessionConfig{
+			SessionID:           "s-unsupported",
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			Features:            map[string]bool{"parallel_tool_calls": true, "zeta": true, "alpha": false},
+		})
+		var featureErr *FeatureUnsupportedError
+		if !errors.As(err, &featureErr) || !errors.Is(err, ErrFeatureUnsupported) {
+			t.Fatalf("Expected a FeatureUnsupportedError, got %v", err)
+		}
+		if !slices.Equal(featureErr.Features, []string{"alpha", "zeta"}) {
+			t.Errorf("Expected the unsupported features sorted, got %v", featureErr.Features)
+		}
+		if _, ok := sent("session.create s-unsupported"); ok {
+			t.Error("Expected no session.create request")
+		}
+	})
+
+	t.Run("rejects all features on a CLI without them", func(t *testing.T) {
+		cli.capabilities = nil
+		old := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+		t.Cleanup(func() { old.ForceStop() })
+		if features, err := old.SupportedFeatures(); err != nil || len(features) != 0 {
+			t.Errorf("Expected no features, got %v, %v", features, err)
+		}
+		_, err := old.ResumeSessionWithOptions(t.Context(), "s-old", &ResumeSessionConfig{
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			Features:            map[string]bool{"parallel_tool_calls": true},
+		})
+		if !errors.Is(err, ErrFeatureUnsupported) {
+			t.Errorf("Expected ErrFeatureUnsupported, got %v", err)
+		}
+		if _, err := old.CreateSession(t.Context(), &SessionConfig{OnPermissionRequest: PermissionHandler.ApproveAll}); err != nil {
+			t.Errorf("Expected sessions without features to work, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package copilot
+
+import (
+	"strings"
+
+	"github.com/github/copilot-sdk/go/diffutil"
+)
+
+// fileDiff normalizes the change of path carried by a write permission
+// request or the arguments of a file editing tool: a patch in its diff
+// field, the replaced text of the edit tool's old_str and new_str, or the
+// contents of a file created with file_text. It returns nil if fields carry
+// no change.
+func fileDiff(path string, fields map[string]any) *diffutil.FileDiff {
+	if patch := stringField(fields, "diff"); patch != "" {
+		files, err := diffutil.Parse(patch)
+		if err != nil || len(files) == 0 {
+			return nil
+		}
+		diff := files[0]
+		for _, file := range files {
+			if path != "" && (strings.HasSuffix(path, file.NewPath) || strings.HasSuffix(path, file.OldPath)) {
+				diff = file
+				break
+			}
+		}
+		if diff.OldPath == "" && diff.NewPath == "" {
+			diff.OldPath, diff.NewPath = path, path
+		}
+		return &diff
+	}
+	if path == "" {
+		return nil
+	}
+	if newText, ok := fields["new_str"].(string); ok {
+		oldText, _ := fields["old_str"].(string)
+		diff := diffutil.Compute(path, oldText, newText)
+		return &diff
+	}
+	if content, ok := fields["file_text"].(string); ok {
+		diff := diffutil.Compute(path, "", content)
+		diff.OldPath = ""
+		return &diff
+	}
+	return nil
+}
+
+// toolFileDiff returns the change a file editing tool call makes, from its
+// arguments, or nil for other tools.
+func toolFileDiff(arguments any) *diffutil.FileDiff {
+	args, _ := arguments.(map[string]any)
+	return fileDiff(toolPath(args), args)
+}
+
+// toolPath returns the file a tool call's arguments name.
+func toolPath(args map[string]any) string {
+	return stringField(args, "path", "file_path", "fileName")
+}
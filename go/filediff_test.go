@@ -0,0 +1,61 @@
+package copilot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFileDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		fields map[string]any
+		want   string
+	}{
+		{"bare patch", "/src/main.go", map[string]any{"diff": "-a\n+b\n"},
+			"--- /src/main.go\n+++ /src/main.go\n@@ -1 +1 @@\n-a\n+b\n"},
+		{"file of a multi-file patch", "/repo/b.txt", map[string]any{"diff": "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-1\n+2\n--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-x\n+y\n"},
+			"--- b.txt\n+++ b.txt\n@@ -1 +1 @@\n-x\n+y\n"},
+		{"edit tool", "/src/a.txt", map[string]any{"old_str": "Hello World", "new_str": "Hi Universe"},
+			"--- /src/a.txt\n+++ /src/a.txt\n@@ -1 +1 @@\n-Hello World\n\\ No newline at end of file\n+Hi Universe\n\\ No newline at end of file\n"},
+		{"create tool", "/src/new.txt", map[string]any{"file_text": "one\r\ntwo\r\n"},
+			"--- /dev/null\n+++ /src/new.txt\n@@ -0,0 +1,2 @@\n+one\n+two\n"},
+		{"malformed patch", "/src/main.go", map[string]any{"diff": "@@ -1,2 +1,2 @@\n-a\n"}, ""},
+		{"no change", "/src/main.go", map[string]any{"view_range": []any{1, 10}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := fileDiff(tt.path, tt.fields)
+			got := ""
+			if diff != nil {
+				got = diff.Unified()
+			}
+			if got != tt.want {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTurnResult_MarkdownDiffs(t *testing.T) {
+	result := &TurnResult{Events: []SessionEvent{
+		{Type: ToolExecutionStart, Data: Data{ToolCallID: String("c1"), ToolName: String("edit"), Arguments: map[string]any{
+			"path": "notes.md", "old_str": "```go\nold\n```\n", "new_str": "```go\nnew\n```\n",
+		}}},
+		{Type: ToolExecutionComplete, Data: Data{ToolCallID: String("c1"), Success: Bool(true)}},
+		{Type: ToolExecutionStart, Data: Data{ToolCallID: String("c2"), ToolName: String("edit"), Arguments: map[string]any{
+			"path": "main.go", "old_str": "a\n", "new_str": "b\n",
+		}}},
+		{Type: ToolExecutionComplete, Data: Data{ToolCallID: String("c2"), Success: Bool(false)}},
+	}}
+
+	want := "- Tool `edit` succeeded\n\n" +
+		"````diff\n--- notes.md\n+++ notes.md\n@@ -1,3 +1,3 @@\n ```go\n-old\n+new\n ```\n````\n\n" +
+		"- Tool `edit` failed\n"
+	if got := result.Markdown(); got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+	if strings.Contains(result.Markdown(), "main.go") {
+		t.Error("Expected no diff for the failed edit")
+	}
+}
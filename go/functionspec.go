@@ -0,0 +1,203 @@
+package copilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// functionNamePattern matches tool names that both the CLI and OpenAI-style
+// function calling accept.
+var functionNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// ErrUnsupportedFunctionSpec matches errors from [ToolFromFunctionSpec] and
+// [Tool.FunctionSpec] for definitions the CLI cannot represent.
+var ErrUnsupportedFunctionSpec = errors.New("unsupported function spec")
+
+// FunctionSpecError is returned when a function spec or tool cannot be
+// converted. It matches [ErrUnsupportedFunctionSpec].
+type FunctionSpecError struct {
+	// Name is the function's name, if known
+	Name string
+	// Path locates the offending construct, e.g.
+	// "parameters.properties.filter.$ref", or is empty for the spec as a whole
+	Path string
+	// Reason describes what is wrong
+	Reason string
+}
+
+func (e *FunctionSpecError) Error() string {
+	var b strings.Builder
+	b.WriteString(ErrUnsupportedFunctionSpec.Error())
+	if e.Name != "" {
+		fmt.Fprintf(&b, " %q", e.Name)
+	}
+	if e.Path != "" {
+		fmt.Fprintf(&b, " at %s", e.Path)
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Reason)
+	return b.String()
+}
+
+func (e *FunctionSpecError) Is(target error) bool { return target == ErrUnsupportedFunctionSpec }
+
+// functionSpec is an OpenAI-style function definition.
+type functionSpec struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+	Strict      *bool          `json:"strict,omitempty"`
+}
+
+// ToolFromFunctionSpec creates a Tool from an OpenAI function-calling
+// definition. spec may be the function object itself
+// ({"name", "description", "parameters"}), a Chat Completions tool
+// ({"type": "function", "function": {...}}) or a Responses API tool
+// ({"type": "function", "name": ...}). The description and parameter schema
+// are kept as they are, numbers included, so [Tool.FunctionSpec] returns an
+// equivalent spec.
+//
+// handler receives the call's arguments as JSON. Its result is returned to
+// the model as with [DefineTool]: strings pass through, other values are
+// serialized as JSON.
+//
+// It fails with a *[FunctionSpecError] for specs the CLI cannot represent:
+// names outside [a-zA-Z0-9_-]{1,64}, parameters that are not an object
+// schema, references to external schemas, and strict mode, which the CLI does
+// not enforce.
+//
+// Example:
+//
+//	tool, err := copilot.ToolFromFunctionSpec(spec, func(args json.RawMessage, inv copilot.ToolInvocation) (any, error) {
+//	    var params struct {
+//	        City string `json:"city"`
+//	    }
+//	    if err := json.Unmarshal(args, &params); err != nil {
+//	        return nil, err
+//	    }
+//	    return lookupWeather(params.City)
+//	})
+func ToolFromFunctionSpec(spec json.RawMessage, handler func(json.RawMessage, ToolInvocation) (any, error)) (Tool, error) {
+	var envelope struct {
+		Type     string          `json:"type"`
+		Function json.RawMessage `json:"function"`
+	}
+	if err := json.Unmarshal(spec, &envelope); err != nil {
+		return Tool{}, fmt.Errorf("invalid function spec: %w", err)
+	}
+	if envelope.Type != "" && envelope.Type != "function" {
+		return Tool{}, &FunctionSpecError{Path: "type", Reason: fmt.Sprintf("tool type %q is not a function", envelope.Type)}
+	}
+	if len(envelope.Function) > 0 {
+		spec = envelope.Function
+	}
+
+	// Decode numbers as written, so large integers and exact decimals in the
+	// schema survive the round trip
+	var fn functionSpec
+	decoder := json.NewDecoder(bytes.NewReader(spec))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fn); err != nil {
+		return Tool{}, fmt.Errorf("invalid function spec: %w", err)
+	}
+	if fn.Strict != nil && *fn.Strict {
+		return Tool{}, &FunctionSpecError{Name: fn.Name, Path: "strict", Reason: "strict mode is not supported: the CLI does not enforce argument schemas"}
+	}
+	if err := checkFunctionSpec(fn.Name, fn.Parameters); err != nil {
+		return Tool{}, err
+	}
+
+	tool := Tool{Name: fn.Name, Description: fn.Description, Parameters: fn.Parameters}
+	if handler != nil {
+		tool.Handler = func(inv ToolInvocation) (ToolResult, error) {
+			args, err := invocationArguments(inv)
+			if err != nil {
+				return ToolResult{}, err
+			}
+			result, err := handler(args, inv)
+			if err != nil {
+				return ToolResult{}, err
+			}
+			return normalizeResult(result)
+		}
+	}
+	return tool, nil
+}
+
+// FunctionSpec returns the tool's definition as an OpenAI function object,
+// {"name", "description", "parameters"}, for platforms that store tools in
+// that format. Wrap it as {"type": "function", "function": spec} for the Chat
+// Completions API. It fails with a *[FunctionSpecError] if the definition
+// cannot be expressed as a function spec.
+//
+// Example:
+//
+//	spec, err := tool.FunctionSpec()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	registry.Save(tool.Name, spec)
+func (t Tool) FunctionSpec() (json.RawMessage, error) {
+	if err := checkFunctionSpec(t.Name, t.Parameters); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(functionSpec{Name: t.Name, Description: t.Description, Parameters: t.Parameters})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize tool %q: %w", t.Name, err)
+	}
+	return data, nil
+}
+
+// checkFunctionSpec reports constructs in a function definition that the CLI
+// cannot represent.
+func checkFunctionSpec(name string, parameters map[string]any) error {
+	if !functionNamePattern.MatchString(name) {
+		return &FunctionSpecError{Name: name, Path: "name", Reason: "name must be 1-64 letters, digits, '_' or '-'"}
+	}
+	if parameters == nil {
+		return nil
+	}
+	if t, ok := parameters["type"]; ok && t != "object" {
+		return &FunctionSpecError{Name: name, Path: "parameters.type", Reason: fmt.Sprintf("parameters must be an object schema, not %v", t)}
+	}
+	for _, keyword := range []string{"oneOf", "anyOf", "allOf", "not"} {
+		if _, ok := parameters[keyword]; ok {
+			return &FunctionSpecError{Name: name, Path: "parameters." + keyword, Reason: "parameters must be a single object schema; combine alternatives inside its properties"}
+		}
+	}
+	return checkSchemaRefs(name, "parameters", parameters)
+}
+
+// checkSchemaRefs rejects $ref values that point outside the schema, which the
+// CLI cannot resolve.
+func checkSchemaRefs(name, path string, value any) error {
+	switch v := value.(type) {
+	case map[string]any:
+		// Walk keys in order so the first error is the same every time
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if ref, ok := v[key].(string); ok && key == "$ref" && !strings.HasPrefix(ref, "#") {
+				return &FunctionSpecError{Name: name, Path: path + ".$ref", Reason: fmt.Sprintf("external schema reference %q cannot be resolved; inline it or move it under $defs", ref)}
+			}
+			if err := checkSchemaRefs(name, path+"."+key, v[key]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, item := range v {
+			if err := checkSchemaRefs(name, fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package copilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decodeSpec decodes a function spec for comparison, keeping numbers as
+// written and unwrapping tool envelopes.
+func decodeSpec(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	var spec map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&spec); err != nil {
+		t.Fatalf("Invalid spec: %v", err)
+	}
+	if function, ok := spec["function"].(map[string]any); ok {
+		return function
+	}
+	delete(spec, "type")
+	return spec
+}
+
+func TestToolFromFunctionSpec(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "functionspecs", "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("No specs found: %v", err)
+	}
+	for _, file := range files {
+		t.Run("round-trips "+filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tool, err := ToolFromFunctionSpec(data, nil)
+			if err != nil {
+				t.Fatalf("Failed to convert: %v", err)
+			}
+			exported, err := tool.FunctionSpec()
+			if err != nil {
+				t.Fatalf("Failed to export: %v", err)
+			}
+			if want, got := decodeSpec(t, data), decodeSpec(t, exported); !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %v, got %v", want, got)
+			}
+
+			again, err := ToolFromFunctionSpec(exported, nil)
+			if err != nil {
+				t.Fatalf("Failed to convert the exported spec: %v", err)
+			}
+			if again.Name != tool.Name || again.Description != tool.Description || !reflect.DeepEqual(again.Parameters, tool.Parameters) {
+				t.Errorf("Expected the same tool after a second round trip, got %+v", again)
+			}
+		})
+	}
+
+	t.Run("keeps large integers exact", func(t *testing.T) {
+		data, _ := os.ReadFile(filepath.Join("testdata", "functionspecs", "lookup_order.json"))
+		tool, _ := ToolFromFunctionSpec(data, nil)
+		exported, _ := tool.FunctionSpec()
+		if !strings.Contains(string(exported), `"maximum":9007199254740993`) {
+			t.Errorf("Expected the maximum to be kept exactly, got %s", exported)
+		}
+	})
+
+	t.Run("passes arguments to the handler as JSON", func(t *testing.T) {
+		spec := json.RawMessage(`{"name": "echo", "parameters": {"type": "object", "properties": {"text": {"type": "string"}}}}`)
+		tool, err := ToolFromFunctionSpec(spec, func(args json.RawMessage, inv ToolInvocation) (any, error) {
+			var params struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal(args, &params); err != nil {
+				return nil, err
+			}
+			return map[string]string{"echo": params.Text}, nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to convert: %v", err)
+		}
+		result, err := tool.Handler(ToolInvocation{Arguments: map[string]any{"text": "hi"}})
+		if err != nil || result.TextResultForLLM != `{"echo":"hi"}` || result.ResultType != "success" {
+			t.Errorf("Unexpected result %+v, %v", result, err)
+		}
+	})
+
+	t.Run("rejects specs the CLI cannot represent", func(t *testing.T) {
+		tests := []struct {
+			name, spec, path string
+		}{
+			{"invalid name", `{"name": "get weather"}`, "name"},
+			{"non-object parameters", `{"name": "f", "parameters": {"type": "string"}}`, "parameters.type"},
+			{"top-level oneOf", `{"name": "f", "parameters": {"oneOf": [{"type": "object"}, {"type": "object"}]}}`, "parameters.oneOf"},
+			{"external reference", `{"name": "f", "parameters": {"type": "object", "properties": {"a": {"items": [{"$ref": "https://example.com/a.json"}]}}}}`, "parameters.properties.a.items[0].$ref"},
+			{"strict mode", `{"name": "f", "strict": true, "parameters": {"type": "object"}}`, "strict"},
+			{"non-function tool", `{"type": "code_interpreter"}`, "type"},
+		}
+		for _, tt := range tests {
+			_, err := ToolFromFunctionSpec(json.RawMessage(tt.spec), nil)
+			var specErr *FunctionSpecError
+			if !errors.As(err, &specErr) || !errors.Is(err, ErrUnsupportedFunctionSpec) || specErr.Path != tt.path {
+				t.Errorf("%s: expected a FunctionSpecError at %s, got %v", tt.name, tt.path, err)
+			}
+		}
+		if _, err := ToolFromFunctionSpec(json.RawMessage(`{"name": `), nil); err == nil || errors.Is(err, ErrUnsupportedFunctionSpec) {
+			t.Errorf("Expected a JSON error for a malformed spec, got %v", err)
+		}
+	})
+}
+
+func TestTool_FunctionSpec(t *testing.T) {
+	t.Run("exports a DefineTool schema", func(t *testing.T) {
+		type params struct {
+			City string `json:"city" jsonschema:"city name"`
+		}
+		tool := DefineTool("get_weather", "Get weather", func(params, ToolInvocation) (string, error) { return "", nil })
+		spec, err := tool.FunctionSpec()
+		if err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		got := decodeSpec(t, spec)
+		properties := got["parameters"].(map[string]any)["properties"].(map[string]any)
+		if got["name"] != "get_weather" || got["description"] != "Get weather" || properties["city"] == nil {
+			t.Errorf("Unexpected spec %s", spec)
+		}
+	})
+
+	t.Run("fails for names the function format rejects", func(t *testing.T) {
+		if _, err := (Tool{Name: "mcp.server/tool"}).FunctionSpec(); !errors.Is(err, ErrUnsupportedFunctionSpec) {
+			t.Errorf("Expected ErrUnsupportedFunctionSpec, got %v", err)
+		}
+	})
+}
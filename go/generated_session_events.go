@@ -1,19 +1,14 @@
 // AUTO-GENERATED FILE - DO NOT EDIT
 // Generated from: session-events.schema.json
 
-// Code generated from JSON Schema using quicktype. DO NOT EDIT.
-// To parse and unparse this JSON data, add this code to your project and do:
-//
-//    sessionEvent, err := UnmarshalSessionEvent(bytes)
-//    bytes, err = sessionEvent.Marshal()
-
 package copilot
 
-import "bytes"
-import "errors"
-import "time"
+import (
+	"encoding/json"
+	"time"
 
-import "encoding/json"
+	"github.com/github/copilot-sdk/go/protocol"
+)
 
 func UnmarshalSessionEvent(data []byte) (SessionEvent, error) {
 	var r SessionEvent
@@ -25,6 +20,7 @@ func (r *SessionEvent) Marshal() ([]byte, error) {
 	return json.Marshal(r)
 }
 
+// SessionEvent is [protocol.SessionEvent] with the SDK's accessors.
 type SessionEvent struct {
 	Data      Data             `json:"data"`
 	Ephemeral *bool            `json:"ephemeral,omitempty"`
@@ -32,589 +28,123 @@ type SessionEvent struct {
 	ParentID  *string          `json:"parentId"`
 	Timestamp time.Time        `json:"timestamp"`
 	Type      SessionEventType `json:"type"`
-}
-
-type Data struct {
-	Context        *ContextUnion `json:"context"`
-	CopilotVersion *string       `json:"copilotVersion,omitempty"`
-	Producer       *string       `json:"producer,omitempty"`
-	SelectedModel  *string       `json:"selectedModel,omitempty"`
-	SessionID      *string       `json:"sessionId,omitempty"`
-	StartTime      *time.Time    `json:"startTime,omitempty"`
-	Version        *float64      `json:"version,omitempty"`
-	EventCount     *float64      `json:"eventCount,omitempty"`
-	ResumeTime     *time.Time    `json:"resumeTime,omitempty"`
-	ErrorType      *string       `json:"errorType,omitempty"`
-	Message        *string       `json:"message,omitempty"`
-	ProviderCallID *string       `json:"providerCallId,omitempty"`
-	Stack          *string       `json:"stack,omitempty"`
-	StatusCode     *int64        `json:"statusCode,omitempty"`
-	Title          *string       `json:"title,omitempty"`
-	InfoType       *string       `json:"infoType,omitempty"`
-	WarningType    *string       `json:"warningType,omitempty"`
-	NewModel       *string       `json:"newModel,omitempty"`
-	PreviousModel  *string       `json:"previousModel,omitempty"`
-	NewMode        *string       `json:"newMode,omitempty"`
-	PreviousMode   *string       `json:"previousMode,omitempty"`
-	Operation      *Operation    `json:"operation,omitempty"`
-	// Relative path within the workspace files directory
-	Path                            *string                  `json:"path,omitempty"`
-	HandoffTime                     *time.Time               `json:"handoffTime,omitempty"`
-	RemoteSessionID                 *string                  `json:"remoteSessionId,omitempty"`
-	Repository                      *RepositoryUnion         `json:"repository"`
-	SourceType                      *SourceType              `json:"sourceType,omitempty"`
-	Summary                         *string                  `json:"summary,omitempty"`
-	MessagesRemovedDuringTruncation *float64                 `json:"messagesRemovedDuringTruncation,omitempty"`
-	PerformedBy                     *string                  `json:"performedBy,omitempty"`
-	PostTruncationMessagesLength    *float64                 `json:"postTruncationMessagesLength,omitempty"`
-	PostTruncationTokensInMessages  *float64                 `json:"postTruncationTokensInMessages,omitempty"`
-	PreTruncationMessagesLength     *float64                 `json:"preTruncationMessagesLength,omitempty"`
-	PreTruncationTokensInMessages   *float64                 `json:"preTruncationTokensInMessages,omitempty"`
-	TokenLimit                      *float64                 `json:"tokenLimit,omitempty"`
-	TokensRemovedDuringTruncation   *float64                 `json:"tokensRemovedDuringTruncation,omitempty"`
-	EventsRemoved                   *float64                 `json:"eventsRemoved,omitempty"`
-	UpToEventID                     *string                  `json:"upToEventId,omitempty"`
-	CodeChanges                     *CodeChanges             `json:"codeChanges,omitempty"`
-	CurrentModel                    *string                  `json:"currentModel,omitempty"`
-	ErrorReason                     *string                  `json:"errorReason,omitempty"`
-	ModelMetrics                    map[string]ModelMetric   `json:"modelMetrics,omitempty"`
-	SessionStartTime                *float64                 `json:"sessionStartTime,omitempty"`
-	ShutdownType                    *ShutdownType            `json:"shutdownType,omitempty"`
-	TotalAPIDurationMS              *float64                 `json:"totalApiDurationMs,omitempty"`
-	TotalPremiumRequests            *float64                 `json:"totalPremiumRequests,omitempty"`
-	Branch                          *string                  `json:"branch,omitempty"`
-	Cwd                             *string                  `json:"cwd,omitempty"`
-	GitRoot                         *string                  `json:"gitRoot,omitempty"`
-	CurrentTokens                   *float64                 `json:"currentTokens,omitempty"`
-	MessagesLength                  *float64                 `json:"messagesLength,omitempty"`
-	CheckpointNumber                *float64                 `json:"checkpointNumber,omitempty"`
-	CheckpointPath                  *string                  `json:"checkpointPath,omitempty"`
-	CompactionTokensUsed            *CompactionTokensUsed    `json:"compactionTokensUsed,omitempty"`
-	Error                           *ErrorUnion              `json:"error"`
-	MessagesRemoved                 *float64                 `json:"messagesRemoved,omitempty"`
-	PostCompactionTokens            *float64                 `json:"postCompactionTokens,omitempty"`
-	PreCompactionMessagesLength     *float64                 `json:"preCompactionMessagesLength,omitempty"`
-	PreCompactionTokens             *float64                 `json:"preCompactionTokens,omitempty"`
-	RequestID                       *string                  `json:"requestId,omitempty"`
-	Success                         *bool                    `json:"success,omitempty"`
-	SummaryContent                  *string                  `json:"summaryContent,omitempty"`
-	TokensRemoved                   *float64                 `json:"tokensRemoved,omitempty"`
-	AgentMode                       *AgentMode               `json:"agentMode,omitempty"`
-	Attachments                     []Attachment             `json:"attachments,omitempty"`
-	Content                         *string                  `json:"content,omitempty"`
-	InteractionID                   *string                  `json:"interactionId,omitempty"`
-	Source                          *string                  `json:"source,omitempty"`
-	TransformedContent              *string                  `json:"transformedContent,omitempty"`
-	TurnID                          *string                  `json:"turnId,omitempty"`
-	Intent                          *string                  `json:"intent,omitempty"`
-	ReasoningID                     *string                  `json:"reasoningId,omitempty"`
-	DeltaContent                    *string                  `json:"deltaContent,omitempty"`
-	TotalResponseSizeBytes          *float64                 `json:"totalResponseSizeBytes,omitempty"`
-	EncryptedContent                *string                  `json:"encryptedContent,omitempty"`
-	MessageID                       *string                  `json:"messageId,omitempty"`
-	ParentToolCallID                *string                  `json:"parentToolCallId,omitempty"`
-	Phase                           *string                  `json:"phase,omitempty"`
-	ReasoningOpaque                 *string                  `json:"reasoningOpaque,omitempty"`
-	ReasoningText                   *string                  `json:"reasoningText,omitempty"`
-	ToolRequests                    []ToolRequest            `json:"toolRequests,omitempty"`
-	APICallID                       *string                  `json:"apiCallId,omitempty"`
-	CacheReadTokens                 *float64                 `json:"cacheReadTokens,omitempty"`
-	CacheWriteTokens                *float64                 `json:"cacheWriteTokens,omitempty"`
-	CopilotUsage                    *CopilotUsage            `json:"copilotUsage,omitempty"`
-	Cost                            *float64                 `json:"cost,omitempty"`
-	Duration                        *float64                 `json:"duration,omitempty"`
-	Initiator                       *string                  `json:"initiator,omitempty"`
-	InputTokens                     *float64                 `json:"inputTokens,omitempty"`
-	Model                           *string                  `json:"model,omitempty"`
-	OutputTokens                    *float64                 `json:"outputTokens,omitempty"`
-	QuotaSnapshots                  map[string]QuotaSnapshot `json:"quotaSnapshots,omitempty"`
-	Reason                          *string                  `json:"reason,omitempty"`
-	Arguments                       interface{}              `json:"arguments"`
-	ToolCallID                      *string                  `json:"toolCallId,omitempty"`
-	ToolName                        *string                  `json:"toolName,omitempty"`
-	MCPServerName                   *string                  `json:"mcpServerName,omitempty"`
-	MCPToolName                     *string                  `json:"mcpToolName,omitempty"`
-	PartialOutput                   *string                  `json:"partialOutput,omitempty"`
-	ProgressMessage                 *string                  `json:"progressMessage,omitempty"`
-	IsUserRequested                 *bool                    `json:"isUserRequested,omitempty"`
-	Result                          *Result                  `json:"result,omitempty"`
-	ToolTelemetry                   map[string]interface{}   `json:"toolTelemetry,omitempty"`
-	AllowedTools                    []string                 `json:"allowedTools,omitempty"`
-	Name                            *string                  `json:"name,omitempty"`
-	PluginName                      *string                  `json:"pluginName,omitempty"`
-	PluginVersion                   *string                  `json:"pluginVersion,omitempty"`
-	AgentDescription                *string                  `json:"agentDescription,omitempty"`
-	AgentDisplayName                *string                  `json:"agentDisplayName,omitempty"`
-	AgentName                       *string                  `json:"agentName,omitempty"`
-	Tools                           []string                 `json:"tools"`
-	HookInvocationID                *string                  `json:"hookInvocationId,omitempty"`
-	HookType                        *string                  `json:"hookType,omitempty"`
-	Input                           interface{}              `json:"input"`
-	Output                          interface{}              `json:"output"`
-	Metadata                        *Metadata                `json:"metadata,omitempty"`
-	Role                            *Role                    `json:"role,omitempty"`
-}
-
-type Attachment struct {
-	DisplayName   *string         `json:"displayName,omitempty"`
-	LineRange     *LineRange      `json:"lineRange,omitempty"`
-	Path          *string         `json:"path,omitempty"`
-	Type          AttachmentType  `json:"type"`
-	FilePath      *string         `json:"filePath,omitempty"`
-	Selection     *SelectionClass `json:"selection,omitempty"`
-	Text          *string         `json:"text,omitempty"`
-	Number        *float64        `json:"number,omitempty"`
-	ReferenceType *ReferenceType  `json:"referenceType,omitempty"`
-	State         *string         `json:"state,omitempty"`
-	Title         *string         `json:"title,omitempty"`
-	URL           *string         `json:"url,omitempty"`
-}
-
-type LineRange struct {
-	End   float64 `json:"end"`
-	Start float64 `json:"start"`
-}
-
-type SelectionClass struct {
-	End   End   `json:"end"`
-	Start Start `json:"start"`
-}
-
-type End struct {
-	Character float64 `json:"character"`
-	Line      float64 `json:"line"`
-}
-
-type Start struct {
-	Character float64 `json:"character"`
-	Line      float64 `json:"line"`
-}
-
-type CodeChanges struct {
-	FilesModified []string `json:"filesModified"`
-	LinesAdded    float64  `json:"linesAdded"`
-	LinesRemoved  float64  `json:"linesRemoved"`
-}
-
-type CompactionTokensUsed struct {
-	CachedInput float64 `json:"cachedInput"`
-	Input       float64 `json:"input"`
-	Output      float64 `json:"output"`
-}
-
-type ContextClass struct {
-	Branch     *string `json:"branch,omitempty"`
-	Cwd        string  `json:"cwd"`
-	GitRoot    *string `json:"gitRoot,omitempty"`
-	Repository *string `json:"repository,omitempty"`
-}
-
-type CopilotUsage struct {
-	TokenDetails []TokenDetail `json:"tokenDetails"`
-	TotalNanoAiu float64       `json:"totalNanoAiu"`
-}
-
-type TokenDetail struct {
-	BatchSize    float64 `json:"batchSize"`
-	CostPerBatch float64 `json:"costPerBatch"`
-	TokenCount   float64 `json:"tokenCount"`
-	TokenType    string  `json:"tokenType"`
-}
-
-type ErrorClass struct {
-	Code    *string `json:"code,omitempty"`
-	Message string  `json:"message"`
-	Stack   *string `json:"stack,omitempty"`
-}
-
-type Metadata struct {
-	PromptVersion *string                `json:"promptVersion,omitempty"`
-	Variables     map[string]interface{} `json:"variables,omitempty"`
-}
-
-type ModelMetric struct {
-	Requests Requests `json:"requests"`
-	Usage    Usage    `json:"usage"`
-}
-
-type Requests struct {
-	Cost  float64 `json:"cost"`
-	Count float64 `json:"count"`
-}
-
-type Usage struct {
-	CacheReadTokens  float64 `json:"cacheReadTokens"`
-	CacheWriteTokens float64 `json:"cacheWriteTokens"`
-	InputTokens      float64 `json:"inputTokens"`
-	OutputTokens     float64 `json:"outputTokens"`
-}
-
-type QuotaSnapshot struct {
-	EntitlementRequests              float64    `json:"entitlementRequests"`
-	IsUnlimitedEntitlement           bool       `json:"isUnlimitedEntitlement"`
-	Overage                          float64    `json:"overage"`
-	OverageAllowedWithExhaustedQuota bool       `json:"overageAllowedWithExhaustedQuota"`
-	RemainingPercentage              float64    `json:"remainingPercentage"`
-	ResetDate                        *time.Time `json:"resetDate,omitempty"`
-	UsageAllowedWithExhaustedQuota   bool       `json:"usageAllowedWithExhaustedQuota"`
-	UsedRequests                     float64    `json:"usedRequests"`
-}
-
-type RepositoryClass struct {
-	Branch *string `json:"branch,omitempty"`
-	Name   string  `json:"name"`
-	Owner  string  `json:"owner"`
-}
-
-type Result struct {
-	Content         string    `json:"content"`
-	Contents        []Content `json:"contents,omitempty"`
-	DetailedContent *string   `json:"detailedContent,omitempty"`
-}
-
-type Content struct {
-	Text        *string        `json:"text,omitempty"`
-	Type        ContentType    `json:"type"`
-	Cwd         *string        `json:"cwd,omitempty"`
-	ExitCode    *float64       `json:"exitCode,omitempty"`
-	Data        *string        `json:"data,omitempty"`
-	MIMEType    *string        `json:"mimeType,omitempty"`
-	Description *string        `json:"description,omitempty"`
-	Icons       []Icon         `json:"icons,omitempty"`
-	Name        *string        `json:"name,omitempty"`
-	Size        *float64       `json:"size,omitempty"`
-	Title       *string        `json:"title,omitempty"`
-	URI         *string        `json:"uri,omitempty"`
-	Resource    *ResourceClass `json:"resource,omitempty"`
-}
-
-type Icon struct {
-	MIMEType *string  `json:"mimeType,omitempty"`
-	Sizes    []string `json:"sizes,omitempty"`
-	Src      string   `json:"src"`
-	Theme    *Theme   `json:"theme,omitempty"`
-}
-
-type ResourceClass struct {
-	MIMEType *string `json:"mimeType,omitempty"`
-	Text     *string `json:"text,omitempty"`
-	URI      string  `json:"uri"`
-	Blob     *string `json:"blob,omitempty"`
-}
-
-type ToolRequest struct {
-	Arguments  interface{}      `json:"arguments"`
-	Name       string           `json:"name"`
-	ToolCallID string           `json:"toolCallId"`
-	Type       *ToolRequestType `json:"type,omitempty"`
-}
-
-type AgentMode string
-
-const (
-	Autopilot   AgentMode = "autopilot"
-	Interactive AgentMode = "interactive"
-	Plan        AgentMode = "plan"
-	Shell       AgentMode = "shell"
-)
-
-type ReferenceType string
-
-const (
-	Discussion ReferenceType = "discussion"
-	Issue      ReferenceType = "issue"
-	PR         ReferenceType = "pr"
-)
-
-type AttachmentType string
-
-const (
-	Directory       AttachmentType = "directory"
-	File            AttachmentType = "file"
-	GithubReference AttachmentType = "github_reference"
-	Selection       AttachmentType = "selection"
-)
-
-type Operation string
-
-const (
-	Create Operation = "create"
-	Delete Operation = "delete"
-	Update Operation = "update"
-)
-
-type Theme string
-
-const (
-	Dark  Theme = "dark"
-	Light Theme = "light"
-)
-
-type ContentType string
-
-const (
-	Audio        ContentType = "audio"
-	Image        ContentType = "image"
-	Resource     ContentType = "resource"
-	ResourceLink ContentType = "resource_link"
-	Terminal     ContentType = "terminal"
-	Text         ContentType = "text"
-)
-
-type Role string
-
-const (
-	Developer Role = "developer"
-	System    Role = "system"
-)
-
-type ShutdownType string
-
-const (
-	Error   ShutdownType = "error"
-	Routine ShutdownType = "routine"
-)
-
-type SourceType string
-
-const (
-	Local  SourceType = "local"
-	Remote SourceType = "remote"
-)
-
-type ToolRequestType string
+	// raw is the event's JSON as received, or nil for events created in code
+	raw json.RawMessage
+}
+
+type Data = protocol.Data
+type Attachment = protocol.Attachment
+type LineRange = protocol.LineRange
+type SelectionClass = protocol.SelectionClass
+type End = protocol.End
+type Start = protocol.Start
+type CodeChanges = protocol.CodeChanges
+type CompactionTokensUsed = protocol.CompactionTokensUsed
+type ContextClass = protocol.ContextClass
+type CopilotUsage = protocol.CopilotUsage
+type TokenDetail = protocol.TokenDetail
+type ErrorClass = protocol.ErrorClass
+type Metadata = protocol.Metadata
+type ModelMetric = protocol.ModelMetric
+type Requests = protocol.Requests
+type Usage = protocol.Usage
+type QuotaSnapshot = protocol.QuotaSnapshot
+type RepositoryClass = protocol.RepositoryClass
+type Result = protocol.Result
+type Content = protocol.Content
+type Icon = protocol.Icon
+type ResourceClass = protocol.ResourceClass
+type ToolRequest = protocol.ToolRequest
+type AgentMode = protocol.AgentMode
+type ReferenceType = protocol.ReferenceType
+type AttachmentType = protocol.AttachmentType
+type Operation = protocol.Operation
+type Theme = protocol.Theme
+type ContentType = protocol.ContentType
+type Role = protocol.Role
+type ShutdownType = protocol.ShutdownType
+type SourceType = protocol.SourceType
+type ToolRequestType = protocol.ToolRequestType
+type SessionEventType = protocol.SessionEventType
+type ContextUnion = protocol.ContextUnion
+type ErrorUnion = protocol.ErrorUnion
+type RepositoryUnion = protocol.RepositoryUnion
 
 const (
-	Custom   ToolRequestType = "custom"
-	Function ToolRequestType = "function"
+	Autopilot                   = protocol.Autopilot
+	Interactive                 = protocol.Interactive
+	Plan                        = protocol.Plan
+	Shell                       = protocol.Shell
+	Discussion                  = protocol.Discussion
+	Issue                       = protocol.Issue
+	PR                          = protocol.PR
+	Directory                   = protocol.Directory
+	File                        = protocol.File
+	GithubReference             = protocol.GithubReference
+	Selection                   = protocol.Selection
+	Create                      = protocol.Create
+	Delete                      = protocol.Delete
+	Update                      = protocol.Update
+	Dark                        = protocol.Dark
+	Light                       = protocol.Light
+	Audio                       = protocol.Audio
+	Image                       = protocol.Image
+	Resource                    = protocol.Resource
+	ResourceLink                = protocol.ResourceLink
+	Terminal                    = protocol.Terminal
+	Text                        = protocol.Text
+	Developer                   = protocol.Developer
+	System                      = protocol.System
+	Error                       = protocol.Error
+	Routine                     = protocol.Routine
+	Local                       = protocol.Local
+	Remote                      = protocol.Remote
+	Custom                      = protocol.Custom
+	Function                    = protocol.Function
+	Abort                       = protocol.Abort
+	AssistantIntent             = protocol.AssistantIntent
+	AssistantMessage            = protocol.AssistantMessage
+	AssistantMessageDelta       = protocol.AssistantMessageDelta
+	AssistantReasoning          = protocol.AssistantReasoning
+	AssistantReasoningDelta     = protocol.AssistantReasoningDelta
+	AssistantStreamingDelta     = protocol.AssistantStreamingDelta
+	AssistantTurnEnd            = protocol.AssistantTurnEnd
+	AssistantTurnStart          = protocol.AssistantTurnStart
+	AssistantUsage              = protocol.AssistantUsage
+	HookEnd                     = protocol.HookEnd
+	HookStart                   = protocol.HookStart
+	PendingMessagesModified     = protocol.PendingMessagesModified
+	SessionCompactionComplete   = protocol.SessionCompactionComplete
+	SessionCompactionStart      = protocol.SessionCompactionStart
+	SessionContextChanged       = protocol.SessionContextChanged
+	SessionError                = protocol.SessionError
+	SessionHandoff              = protocol.SessionHandoff
+	SessionIdle                 = protocol.SessionIdle
+	SessionInfo                 = protocol.SessionInfo
+	SessionModeChanged          = protocol.SessionModeChanged
+	SessionModelChange          = protocol.SessionModelChange
+	SessionPlanChanged          = protocol.SessionPlanChanged
+	SessionResume               = protocol.SessionResume
+	SessionShutdown             = protocol.SessionShutdown
+	SessionSnapshotRewind       = protocol.SessionSnapshotRewind
+	SessionStart                = protocol.SessionStart
+	SessionTaskComplete         = protocol.SessionTaskComplete
+	SessionTitleChanged         = protocol.SessionTitleChanged
+	SessionTruncation           = protocol.SessionTruncation
+	SessionUsageInfo            = protocol.SessionUsageInfo
+	SessionWarning              = protocol.SessionWarning
+	SessionWorkspaceFileChanged = protocol.SessionWorkspaceFileChanged
+	SkillInvoked                = protocol.SkillInvoked
+	SubagentCompleted           = protocol.SubagentCompleted
+	SubagentDeselected          = protocol.SubagentDeselected
+	SubagentFailed              = protocol.SubagentFailed
+	SubagentSelected            = protocol.SubagentSelected
+	SubagentStarted             = protocol.SubagentStarted
+	SystemMessage               = protocol.SystemMessage
+	ToolExecutionComplete       = protocol.ToolExecutionComplete
+	ToolExecutionPartialResult  = protocol.ToolExecutionPartialResult
+	ToolExecutionProgress       = protocol.ToolExecutionProgress
+	ToolExecutionStart          = protocol.ToolExecutionStart
+	ToolUserRequested           = protocol.ToolUserRequested
+	UserMessage                 = protocol.UserMessage
 )
-
-type SessionEventType string
-
-const (
-	Abort                       SessionEventType = "abort"
-	AssistantIntent             SessionEventType = "assistant.intent"
-	AssistantMessage            SessionEventType = "assistant.message"
-	AssistantMessageDelta       SessionEventType = "assistant.message_delta"
-	AssistantReasoning          SessionEventType = "assistant.reasoning"
-	AssistantReasoningDelta     SessionEventType = "assistant.reasoning_delta"
-	AssistantStreamingDelta     SessionEventType = "assistant.streaming_delta"
-	AssistantTurnEnd            SessionEventType = "assistant.turn_end"
-	AssistantTurnStart          SessionEventType = "assistant.turn_start"
-	AssistantUsage              SessionEventType = "assistant.usage"
-	HookEnd                     SessionEventType = "hook.end"
-	HookStart                   SessionEventType = "hook.start"
-	PendingMessagesModified     SessionEventType = "pending_messages.modified"
-	SessionCompactionComplete   SessionEventType = "session.compaction_complete"
-	SessionCompactionStart      SessionEventType = "session.compaction_start"
-	SessionContextChanged       SessionEventType = "session.context_changed"
-	SessionError                SessionEventType = "session.error"
-	SessionHandoff              SessionEventType = "session.handoff"
-	SessionIdle                 SessionEventType = "session.idle"
-	SessionInfo                 SessionEventType = "session.info"
-	SessionModeChanged          SessionEventType = "session.mode_changed"
-	SessionModelChange          SessionEventType = "session.model_change"
-	SessionPlanChanged          SessionEventType = "session.plan_changed"
-	SessionResume               SessionEventType = "session.resume"
-	SessionShutdown             SessionEventType = "session.shutdown"
-	SessionSnapshotRewind       SessionEventType = "session.snapshot_rewind"
-	SessionStart                SessionEventType = "session.start"
-	SessionTaskComplete         SessionEventType = "session.task_complete"
-	SessionTitleChanged         SessionEventType = "session.title_changed"
-	SessionTruncation           SessionEventType = "session.truncation"
-	SessionUsageInfo            SessionEventType = "session.usage_info"
-	SessionWarning              SessionEventType = "session.warning"
-	SessionWorkspaceFileChanged SessionEventType = "session.workspace_file_changed"
-	SkillInvoked                SessionEventType = "skill.invoked"
-	SubagentCompleted           SessionEventType = "subagent.completed"
-	SubagentDeselected          SessionEventType = "subagent.deselected"
-	SubagentFailed              SessionEventType = "subagent.failed"
-	SubagentSelected            SessionEventType = "subagent.selected"
-	SubagentStarted             SessionEventType = "subagent.started"
-	SystemMessage               SessionEventType = "system.message"
-	ToolExecutionComplete       SessionEventType = "tool.execution_complete"
-	ToolExecutionPartialResult  SessionEventType = "tool.execution_partial_result"
-	ToolExecutionProgress       SessionEventType = "tool.execution_progress"
-	ToolExecutionStart          SessionEventType = "tool.execution_start"
-	ToolUserRequested           SessionEventType = "tool.user_requested"
-	UserMessage                 SessionEventType = "user.message"
-)
-
-type ContextUnion struct {
-	ContextClass *ContextClass
-	String       *string
-}
-
-func (x *ContextUnion) UnmarshalJSON(data []byte) error {
-	x.ContextClass = nil
-	var c ContextClass
-	object, err := unmarshalUnion(data, nil, nil, nil, &x.String, false, nil, true, &c, false, nil, false, nil, false)
-	if err != nil {
-		return err
-	}
-	if object {
-		x.ContextClass = &c
-	}
-	return nil
-}
-
-func (x *ContextUnion) MarshalJSON() ([]byte, error) {
-	return marshalUnion(nil, nil, nil, x.String, false, nil, x.ContextClass != nil, x.ContextClass, false, nil, false, nil, false)
-}
-
-type ErrorUnion struct {
-	ErrorClass *ErrorClass
-	String     *string
-}
-
-func (x *ErrorUnion) UnmarshalJSON(data []byte) error {
-	x.ErrorClass = nil
-	var c ErrorClass
-	object, err := unmarshalUnion(data, nil, nil, nil, &x.String, false, nil, true, &c, false, nil, false, nil, false)
-	if err != nil {
-		return err
-	}
-	if object {
-		x.ErrorClass = &c
-	}
-	return nil
-}
-
-func (x *ErrorUnion) MarshalJSON() ([]byte, error) {
-	return marshalUnion(nil, nil, nil, x.String, false, nil, x.ErrorClass != nil, x.ErrorClass, false, nil, false, nil, false)
-}
-
-type RepositoryUnion struct {
-	RepositoryClass *RepositoryClass
-	String          *string
-}
-
-func (x *RepositoryUnion) UnmarshalJSON(data []byte) error {
-	x.RepositoryClass = nil
-	var c RepositoryClass
-	object, err := unmarshalUnion(data, nil, nil, nil, &x.String, false, nil, true, &c, false, nil, false, nil, false)
-	if err != nil {
-		return err
-	}
-	if object {
-		x.RepositoryClass = &c
-	}
-	return nil
-}
-
-func (x *RepositoryUnion) MarshalJSON() ([]byte, error) {
-	return marshalUnion(nil, nil, nil, x.String, false, nil, x.RepositoryClass != nil, x.RepositoryClass, false, nil, false, nil, false)
-}
-
-func unmarshalUnion(data []byte, pi **int64, pf **float64, pb **bool, ps **string, haveArray bool, pa interface{}, haveObject bool, pc interface{}, haveMap bool, pm interface{}, haveEnum bool, pe interface{}, nullable bool) (bool, error) {
-	if pi != nil {
-		*pi = nil
-	}
-	if pf != nil {
-		*pf = nil
-	}
-	if pb != nil {
-		*pb = nil
-	}
-	if ps != nil {
-		*ps = nil
-	}
-
-	dec := json.NewDecoder(bytes.NewReader(data))
-	dec.UseNumber()
-	tok, err := dec.Token()
-	if err != nil {
-		return false, err
-	}
-
-	switch v := tok.(type) {
-	case json.Number:
-		if pi != nil {
-			i, err := v.Int64()
-			if err == nil {
-				*pi = &i
-				return false, nil
-			}
-		}
-		if pf != nil {
-			f, err := v.Float64()
-			if err == nil {
-				*pf = &f
-				return false, nil
-			}
-			return false, errors.New("Unparsable number")
-		}
-		return false, errors.New("Union does not contain number")
-	case float64:
-		return false, errors.New("Decoder should not return float64")
-	case bool:
-		if pb != nil {
-			*pb = &v
-			return false, nil
-		}
-		return false, errors.New("Union does not contain bool")
-	case string:
-		if haveEnum {
-			return false, json.Unmarshal(data, pe)
-		}
-		if ps != nil {
-			*ps = &v
-			return false, nil
-		}
-		return false, errors.New("Union does not contain string")
-	case nil:
-		if nullable {
-			return false, nil
-		}
-		return false, errors.New("Union does not contain null")
-	case json.Delim:
-		if v == '{' {
-			if haveObject {
-				return true, json.Unmarshal(data, pc)
-			}
-			if haveMap {
-				return false, json.Unmarshal(data, pm)
-			}
-			return false, errors.New("Union does not contain object")
-		}
-		if v == '[' {
-			if haveArray {
-				return false, json.Unmarshal(data, pa)
-			}
-			return false, errors.New("Union does not contain array")
-		}
-		return false, errors.New("Cannot handle delimiter")
-	}
-	return false, errors.New("Cannot unmarshal union")
-}
-
-func marshalUnion(pi *int64, pf *float64, pb *bool, ps *string, haveArray bool, pa interface{}, haveObject bool, pc interface{}, haveMap bool, pm interface{}, haveEnum bool, pe interface{}, nullable bool) ([]byte, error) {
-	if pi != nil {
-		return json.Marshal(*pi)
-	}
-	if pf != nil {
-		return json.Marshal(*pf)
-	}
-	if pb != nil {
-		return json.Marshal(*pb)
-	}
-	if ps != nil {
-		return json.Marshal(*ps)
-	}
-	if haveArray {
-		return json.Marshal(pa)
-	}
-	if haveObject {
-		return json.Marshal(pc)
-	}
-	if haveMap {
-		return json.Marshal(pm)
-	}
-	if haveEnum {
-		return json.Marshal(pe)
-	}
-	if nullable {
-		return json.Marshal(nil)
-	}
-	return nil, errors.New("Union must not be null")
-}
@@ -0,0 +1,87 @@
+package copilot
+
+import (
+	"runtime/debug"
+	"slices"
+	"sync"
+)
+
+// sessionEventTap holds the handlers registered with
+// [Client.OnSessionEvent].
+type sessionEventTap struct {
+	mu       sync.RWMutex
+	nextID   uint64
+	handlers []sessionEventTapHandler
+}
+
+type sessionEventTapHandler struct {
+	id uint64
+	fn func(sessionID string, event SessionEvent)
+}
+
+// OnSessionEvent subscribes to the events of every session, whether created
+// or resumed, including events for sessions the client has no [Session] for.
+// It is meant for cross-cutting concerns like audit logging, and can be
+// registered before any session is created, so no event of a session's first
+// turn is missed.
+//
+// Handlers receive each event as the CLI sent it, in arrival order, before
+// it is routed to its session: before the session's own handlers, and before
+// the SDK sets the interaction ID or holds the event for a session that is
+// still being created. Handlers are called synchronously in the order they
+// were registered; a panicking handler is recovered and recorded in
+// [Client.DiagnosticBundle].
+//
+// The returned function unsubscribes the handler. It is safe to call it
+// multiple times.
+//
+// Example:
+//
+//	unsubscribe := client.OnSessionEvent(func(sessionID string, event copilot.SessionEvent) {
+//	    audit.Printf("%s %s %s", sessionID, event.Timestamp.Format(time.RFC3339), event.Type)
+//	})
+//	defer unsubscribe()
+func (c *Client) OnSessionEvent(handler func(sessionID string, event SessionEvent)) func() {
+	t := &c.sessionEventTap
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id := t.nextID
+	t.nextID++
+	t.handlers = append(t.handlers, sessionEventTapHandler{id: id, fn: handler})
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		for i, h := range t.handlers {
+			if h.id == id {
+				t.handlers = slices.Delete(t.handlers, i, i+1)
+				break
+			}
+		}
+	}
+}
+
+// observeSessionEvent calls the handlers registered with
+// [Client.OnSessionEvent].
+func (c *Client) observeSessionEvent(sessionID string, event SessionEvent) {
+	t := &c.sessionEventTap
+	t.mu.RLock()
+	if len(t.handlers) == 0 {
+		t.mu.RUnlock()
+		return
+	}
+	handlers := slices.Clone(t.handlers)
+	t.mu.RUnlock()
+
+	for _, h := range handlers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					c.diagnostics.recordPanic("session event handler", sessionID, r)
+					c.logger.Error("session event handler panicked", "sessionID", sessionID, "eventType", event.Type, "eventID", event.ID, "panic", r, "stack", string(debug.Stack()))
+				}
+			}()
+			h.fn(sessionID, event)
+		}()
+	}
+}
@@ -0,0 +1,93 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/sdklog"
+)
+
+func TestClient_OnSessionEvent(t *testing.T) {
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		if method == "session.create" {
+			var req createSessionRequest
+			json.Unmarshal(params, &req)
+			return createSessionResponse{SessionID: req.SessionID}, nil
+		}
+		return nil, nil
+	})
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	client.logger = sdklog.New(io.Discard, false)
+	t.Cleanup(func() { client.ForceStop() })
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, fmt.Sprintf(format, args...))
+	}
+	// Registered before the client starts
+	unsubscribe := client.OnSessionEvent(func(sessionID string, event SessionEvent) {
+		record("global %s %s", sessionID, event.Type)
+	})
+	client.OnSessionEvent(func(sessionID string, event SessionEvent) {
+		if event.Type == SessionError {
+			panic("audit log full")
+		}
+	})
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	session, err := client.CreateSession(t.Context(), &SessionConfig{SessionID: "s1", OnPermissionRequest: PermissionHandler.ApproveAll})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	idle := make(chan struct{}, 1)
+	session.On(func(event SessionEvent) {
+		record("session s1 %s", event.Type)
+		if event.Type == SessionIdle {
+			idle <- struct{}{}
+		}
+	})
+	waitIdle := func() {
+		t.Helper()
+		select {
+		case <-idle:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the session's events")
+		}
+	}
+
+	cli.emitTo("s1", SessionEvent{Type: AssistantMessage})
+	cli.emitTo("elsewhere", SessionEvent{Type: SessionError})
+	cli.emitTo("s1", SessionEvent{Type: SessionIdle})
+	waitIdle()
+
+	unsubscribe()
+	unsubscribe()
+	cli.emitTo("s1", SessionEvent{Type: SessionIdle})
+	waitIdle()
+
+	mu.Lock()
+	got := fmt.Sprint(calls)
+	mu.Unlock()
+	want := fmt.Sprint([]string{
+		"global s1 assistant.message",
+		"session s1 assistant.message",
+		"global elsewhere session.error",
+		"global s1 session.idle",
+		"session s1 session.idle",
+		"session s1 session.idle",
+	})
+	if got != want {
+		t.Errorf("Expected calls\n%s\ngot\n%s", want, got)
+	}
+	if _, _, panics := client.diagnostics.snapshot(); len(panics) != 1 || panics[0].Source != "session event handler" || panics[0].SessionID != "elsewhere" {
+		t.Errorf("Expected the handler's panic to be recorded, got %+v", panics)
+	}
+}
@@ -3,6 +3,10 @@ module github.com/github/copilot-sdk/go
 go 1.24
 
 require (
+	github.com/fsnotify/fsnotify v1.10.1
 	github.com/google/jsonschema-go v0.4.2
 	github.com/klauspost/compress v1.18.3
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/sys v0.13.0 // indirect
@@ -0,0 +1,163 @@
+package copilot
+
+import (
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultSlowHandlerThreshold is the default of [SlowHandlerOptions.Threshold].
+const defaultSlowHandlerThreshold = 100 * time.Millisecond
+
+// SlowHandlerOptions configures how a session reports event handlers that
+// take long to return. Handlers run synchronously, so a slow handler delays
+// every event after it.
+type SlowHandlerOptions struct {
+	// Threshold is how long a handler may take for one event before it is
+	// reported. Default: 100 milliseconds. A negative value disables
+	// reporting; [Session.HandlerStats] is still recorded.
+	Threshold time.Duration
+	// OnSlowHandler is called after each handler call that exceeded
+	// Threshold, for example to record a metric. Default: the call is
+	// logged to [ClientOptions.LogOutput].
+	OnSlowHandler func(SlowHandlerCall)
+}
+
+// SlowHandlerCall describes an event handler call that exceeded
+// [SlowHandlerOptions.Threshold].
+type SlowHandlerCall struct {
+	// Order and Name identify the handler, as in [HandlerStats]
+	Order int
+	Name  string
+	// EventType is the type of the event the handler was called with
+	EventType SessionEventType
+	// Duration is how long the handler took to return
+	Duration time.Duration
+}
+
+// HandlerStats reports how long an event handler has taken. See
+// [Session.HandlerStats].
+type HandlerStats struct {
+	// Order is the position of the handler among those registered with the
+	// session, starting at 0
+	Order int
+	// Name is the name given to [Session.OnNamed], or empty
+	Name string
+	// Invocations is the number of events the handler was called with
+	Invocations int
+	// TotalDuration is the time spent in the handler across invocations
+	TotalDuration time.Duration
+	// MaxDuration is the longest single invocation
+	MaxDuration time.Duration
+	// SlowInvocations is the number of invocations that exceeded
+	// [SlowHandlerOptions.Threshold]
+	SlowInvocations int
+}
+
+// handlerStats accumulates the durations of one handler's calls.
+type handlerStats struct {
+	mu          sync.Mutex
+	invocations int
+	slow        int
+	total       time.Duration
+	max         time.Duration
+}
+
+// OnNamed subscribes to events from this session, like [Session.On], under a
+// name that identifies the handler in [Session.HandlerStats] and in slow
+// handler reports.
+//
+// Example:
+//
+//	unsubscribe := session.OnNamed("audit-log", func(event copilot.SessionEvent) {
+//	    auditLog.Write(event)
+//	})
+//	defer unsubscribe()
+func (s *Session) OnNamed(name string, handler SessionEventHandler) func() {
+	return s.subscribe(name, handler, nil)
+}
+
+// HandlerStats returns the number of invocations and the cumulative and
+// maximum duration of each event handler currently registered with the
+// session, in registration order. Handlers that take longer than
+// [SlowHandlerOptions.Threshold] are also reported as they happen; see
+// [SessionConfig.SlowHandlers].
+//
+// Example:
+//
+//	for _, stats := range session.HandlerStats() {
+//	    if stats.MaxDuration > 50*time.Millisecond {
+//	        fmt.Printf("handler %d (%s) took up to %v\n", stats.Order, stats.Name, stats.MaxDuration)
+//	    }
+//	}
+func (s *Session) HandlerStats() []HandlerStats {
+	s.handlerMutex.RLock()
+	handlers := make([]sessionHandler, len(s.handlers))
+	copy(handlers, s.handlers)
+	s.handlerMutex.RUnlock()
+
+	result := make([]HandlerStats, 0, len(handlers))
+	for _, h := range handlers {
+		h.stats.mu.Lock()
+		result = append(result, HandlerStats{
+			Order:           int(h.id),
+			Name:            h.name,
+			Invocations:     h.stats.invocations,
+			TotalDuration:   h.stats.total,
+			MaxDuration:     h.stats.max,
+			SlowInvocations: h.stats.slow,
+		})
+		h.stats.mu.Unlock()
+	}
+	return result
+}
+
+// recordHandlerCall records that h took duration to handle an event of type
+// eventType, and reports the call if it exceeded the slow handler threshold.
+func (s *Session) recordHandlerCall(h sessionHandler, eventType SessionEventType, duration time.Duration) {
+	var opts SlowHandlerOptions
+	if s.slowHandlers != nil {
+		opts = *s.slowHandlers
+	}
+	threshold := opts.Threshold
+	if threshold == 0 {
+		threshold = defaultSlowHandlerThreshold
+	}
+	slow := threshold > 0 && duration > threshold
+
+	h.stats.mu.Lock()
+	h.stats.invocations++
+	h.stats.total += duration
+	h.stats.max = max(h.stats.max, duration)
+	if slow {
+		h.stats.slow++
+	}
+	h.stats.mu.Unlock()
+
+	if !slow {
+		return
+	}
+	call := SlowHandlerCall{Order: int(h.id), Name: h.name, EventType: eventType, Duration: duration}
+	if opts.OnSlowHandler == nil {
+		s.logger.Warn("slow event handler", "sessionID", s.SessionID, "handler", call.handlerName(), "eventType", eventType, "duration", duration)
+		return
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				s.diagnostics.recordPanic("slow handler callback", s.SessionID, r)
+				s.logger.Error("slow handler callback panicked", "sessionID", s.SessionID, "panic", r, "stack", string(debug.Stack()))
+			}
+		}()
+		opts.OnSlowHandler(call)
+	}()
+}
+
+// handlerName names the handler in log messages.
+func (c SlowHandlerCall) handlerName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return "#" + strconv.Itoa(c.Order)
+}
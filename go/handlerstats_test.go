@@ -0,0 +1,89 @@
+package copilot
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/sdklog"
+)
+
+func TestSession_HandlerStats(t *testing.T) {
+	idle := SessionEvent{Type: SessionIdle, Timestamp: time.Now()}
+
+	t.Run("records invocations and durations per handler", func(t *testing.T) {
+		session, _ := newTestSession(t, nil)
+		session.On(func(SessionEvent) {})
+		session.OnNamed("slow", func(SessionEvent) { time.Sleep(5 * time.Millisecond) })
+		session.OnTypes([]SessionEventType{AssistantMessage}, func(SessionEvent) {})
+		for range 3 {
+			session.dispatchEvent(idle)
+		}
+
+		stats := session.HandlerStats()
+		if len(stats) != 3 {
+			t.Fatalf("Expected 3 handlers, got %+v", stats)
+		}
+		if stats[0].Order != 0 || stats[0].Name != "" || stats[0].Invocations != 3 {
+			t.Errorf("Unexpected stats for the first handler: %+v", stats[0])
+		}
+		if stats[1].Name != "slow" || stats[1].Invocations != 3 || stats[1].TotalDuration < 15*time.Millisecond || stats[1].MaxDuration < 5*time.Millisecond {
+			t.Errorf("Unexpected stats for the named handler: %+v", stats[1])
+		}
+		if stats[2].Invocations != 0 {
+			t.Errorf("Expected filtered events not to count, got %+v", stats[2])
+		}
+	})
+
+	t.Run("reports handlers over the threshold", func(t *testing.T) {
+		session, _ := newTestSession(t, nil)
+		var calls []SlowHandlerCall
+		session.slowHandlers = &SlowHandlerOptions{
+			Threshold:     20 * time.Millisecond,
+			OnSlowHandler: func(call SlowHandlerCall) { calls = append(calls, call) },
+		}
+		session.On(func(SessionEvent) {})
+		session.OnNamed("renderer", func(event SessionEvent) {
+			if event.Type == SessionIdle {
+				time.Sleep(30 * time.Millisecond)
+			}
+		})
+		session.dispatchEvent(SessionEvent{Type: AssistantMessage, Timestamp: time.Now()})
+		session.dispatchEvent(idle)
+
+		if len(calls) != 1 || calls[0].Name != "renderer" || calls[0].Order != 1 || calls[0].EventType != SessionIdle || calls[0].Duration < 30*time.Millisecond {
+			t.Fatalf("Expected one slow call from the renderer, got %+v", calls)
+		}
+		if stats := session.HandlerStats(); stats[1].SlowInvocations != 1 || stats[0].SlowInvocations != 0 {
+			t.Errorf("Unexpected slow invocation counts: %+v", stats)
+		}
+	})
+
+	t.Run("logs slow handlers by default", func(t *testing.T) {
+		var buf bytes.Buffer
+		session, _ := newTestSession(t, nil)
+		session.logger = sdklog.New(&buf, false)
+		session.On(func(SessionEvent) { time.Sleep(defaultSlowHandlerThreshold + 10*time.Millisecond) })
+		session.dispatchEvent(idle)
+
+		if !strings.Contains(buf.String(), "slow event handler sessionID=test-session handler=#0 eventType=session.idle duration=") {
+			t.Errorf("Expected a log message, got %q", buf.String())
+		}
+	})
+
+	t.Run("does not report when disabled", func(t *testing.T) {
+		session, _ := newTestSession(t, nil)
+		reported := false
+		session.slowHandlers = &SlowHandlerOptions{
+			Threshold:     -1,
+			OnSlowHandler: func(SlowHandlerCall) { reported = true },
+		}
+		session.On(func(SessionEvent) { time.Sleep(5 * time.Millisecond) })
+		session.dispatchEvent(idle)
+
+		if reported || session.HandlerStats()[0].Invocations != 1 {
+			t.Errorf("Expected stats without reports, got reported=%v %+v", reported, session.HandlerStats())
+		}
+	})
+}
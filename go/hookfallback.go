@@ -0,0 +1,216 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrHooksUnsupported is returned when creating or resuming a session with
+// hooks on a CLI that does not support them, unless
+// [SessionConfig.HookFallback] says otherwise.
+var ErrHooksUnsupported = errors.New("connected CLI does not support hooks")
+
+// HooksUnsupportedWarning is the warning type of the session.warning event
+// emitted when some of a session's hooks do not run because the CLI does not
+// support hooks.
+const HooksUnsupportedWarning = "hooks_unsupported"
+
+// ServerCapabilities describes optional features the CLI reports in its ping
+// response. A nil field means the CLI did not say, and the feature is assumed
+// to be supported.
+type ServerCapabilities struct {
+	// Hooks reports whether sessions can register hooks
+	Hooks *bool `json:"hooks,omitempty"`
+	// Features lists the experimental feature flags sessions can set in
+	// [SessionConfig.Features]. CLIs without per-session features omit it.
+	Features []string `json:"features,omitempty"`
+	// Compression lists the Content-Encoding values the CLI decodes in
+	// messages on TCP connections, such as "gzip"
+	Compression []string `json:"compression,omitempty"`
+	// MessageModel reports whether session.send accepts a model for a
+	// single message. Unlike the other fields, nil means it does not, since
+	// older CLIs ignore the parameter.
+	MessageModel *bool `json:"messageModel,omitempty"`
+	// Attachments lists the attachment types session.send accepts beyond
+	// file, directory, selection and github_reference, such as "inline" and
+	// "url"
+	Attachments []string `json:"attachments,omitempty"`
+	// ImportHistory reports whether session.import seeds a session with the
+	// events of a [Session.Export]. Like MessageModel, nil means it does not.
+	ImportHistory *bool `json:"importHistory,omitempty"`
+}
+
+// HookFallback selects what happens to a session's hooks when the CLI does
+// not support hooks.
+type HookFallback string
+
+const (
+	// HookFallbackError fails session creation with [ErrHooksUnsupported].
+	// This is the default.
+	HookFallbackError HookFallback = "error"
+	// HookFallbackDrop creates the session without hooks and emits a
+	// session.warning event with type [HooksUnsupportedWarning].
+	HookFallbackDrop HookFallback = "drop"
+	// HookFallbackEmulate runs OnPreToolUse from the permission request of
+	// each tool call that asks for permission: a "deny" decision denies the
+	// request and "allow" approves it, while other decisions fall through to
+	// the permission handler. Tool calls that need no permission bypass the
+	// hook, and ModifiedArgs and AdditionalContext are ignored. The other
+	// hooks are dropped as with HookFallbackDrop.
+	HookFallbackEmulate HookFallback = "emulate"
+)
+
+// HookMode reports how a session's hooks run, in
+// [ResolvedSessionConfig.Hooks].
+type HookMode string
+
+const (
+	// HookModeNone means the session has no hooks.
+	HookModeNone HookMode = ""
+	// HookModeNative means the CLI invokes the hooks.
+	HookModeNative HookMode = "native"
+	// HookModeDropped means the hooks do not run.
+	HookModeDropped HookMode = "dropped"
+	// HookModeEmulated means OnPreToolUse runs from permission requests and
+	// the other hooks do not run.
+	HookModeEmulated HookMode = "emulated"
+)
+
+// hasHooks reports whether hooks has any handler set.
+func hasHooks(hooks *SessionHooks) bool {
+	return hooks != nil && (hooks.OnPreToolUse != nil ||
+		hooks.OnPostToolUse != nil ||
+		hooks.OnUserPromptSubmitted != nil ||
+		hooks.OnSessionStart != nil ||
+		hooks.OnSessionEnd != nil ||
+		hooks.OnErrorOccurred != nil)
+}
+
+// supportsHooks reports whether the connected CLI supports hooks.
+func (c *Client) supportsHooks() bool {
+	capabilities := c.capabilities.Load()
+	return capabilities == nil || capabilities.Hooks == nil || *capabilities.Hooks
+}
+
+// resolveHookMode decides how a session's hooks run on the connected CLI.
+func (c *Client) resolveHookMode(hooks *SessionHooks, fallback HookFallback) (HookMode, error) {
+	if !hasHooks(hooks) {
+		return HookModeNone, nil
+	}
+	if c.supportsHooks() {
+		return HookModeNative, nil
+	}
+	switch fallback {
+	case HookFallbackDrop:
+		return HookModeDropped, nil
+	case HookFallbackEmulate:
+		return HookModeEmulated, nil
+	case "", HookFallbackError:
+		return HookModeNone, fmt.Errorf("%w: set HookFallback to drop or emulate them", ErrHooksUnsupported)
+	}
+	return HookModeNone, fmt.Errorf("invalid HookFallback %q", fallback)
+}
+
+// applyHooks registers hooks to run as mode says.
+func (s *Session) applyHooks(hooks *SessionHooks, mode HookMode) {
+	switch mode {
+	case HookModeNative:
+		s.registerHooks(hooks)
+	case HookModeEmulated:
+		s.registerHooks(&SessionHooks{OnPreToolUse: hooks.OnPreToolUse})
+	}
+	s.config.mu.Lock()
+	s.config.config.Hooks = mode
+	s.config.mu.Unlock()
+}
+
+// warnUnsupportedHooks emits a session.warning event naming the hooks that
+// do not run in mode.
+func (s *Session) warnUnsupportedHooks(hooks *SessionHooks, mode HookMode) {
+	if mode != HookModeDropped && mode != HookModeEmulated {
+		return
+	}
+	var dropped []string
+	for _, hook := range []struct {
+		name string
+		set  bool
+	}{
+		{"OnPreToolUse", hooks.OnPreToolUse != nil && mode == HookModeDropped},
+		{"OnPostToolUse", hooks.OnPostToolUse != nil},
+		{"OnUserPromptSubmitted", hooks.OnUserPromptSubmitted != nil},
+		{"OnSessionStart", hooks.OnSessionStart != nil},
+		{"OnSessionEnd", hooks.OnSessionEnd != nil},
+		{"OnErrorOccurred", hooks.OnErrorOccurred != nil},
+	} {
+		if hook.set {
+			dropped = append(dropped, hook.name)
+		}
+	}
+	message := "the CLI does not support hooks"
+	if len(dropped) > 0 {
+		message += "; not running " + strings.Join(dropped, ", ")
+	}
+	if mode == HookModeEmulated && hooks.OnPreToolUse != nil {
+		message += "; OnPreToolUse runs from permission requests"
+	}
+	s.dispatchEvent(SessionEvent{
+		Type:      SessionWarning,
+		Timestamp: time.Now(),
+		Ephemeral: Bool(true),
+		Data: Data{
+			WarningType: String(HooksUnsupportedWarning),
+			Message:     String(message),
+		},
+	})
+}
+
+// emulatePreToolUse runs an emulated OnPreToolUse hook for a permission
+// request. It reports whether the hook decided the request.
+func (s *Session) emulatePreToolUse(request PermissionRequest) (PermissionRequestResult, bool, error) {
+	if s.Config().Hooks != HookModeEmulated {
+		return PermissionRequestResult{}, false, nil
+	}
+	hooks := s.getHooks()
+	if hooks == nil || hooks.OnPreToolUse == nil {
+		return PermissionRequestResult{}, false, nil
+	}
+
+	toolName, _ := request.Extra["toolName"].(string)
+	if toolName == "" {
+		toolName = request.Kind
+	}
+	now := time.Now()
+	input := PreToolUseHookInput{
+		Timestamp: now.UnixMilli(),
+		Time:      now,
+		Cwd:       s.Config().WorkingDirectory,
+		ToolName:  toolName,
+		ToolArgs:  request.Extra,
+	}
+	ctx, messageID, traceID := s.trace.current()
+	invocation := HookInvocation{
+		SessionID: s.SessionID,
+		Context:   ctx,
+		MessageID: messageID,
+		TraceID:   traceID,
+	}
+
+	output, err := hooks.OnPreToolUse(input, invocation)
+	if err != nil {
+		return PermissionRequestResult{}, true, err
+	}
+	if output == nil {
+		return PermissionRequestResult{}, false, nil
+	}
+	switch output.PermissionDecision {
+	case "deny":
+		result := DeniedByRules()
+		result.Reason = output.PermissionDecisionReason
+		return result, true, nil
+	case "allow":
+		return Approved(), true, nil
+	}
+	return PermissionRequestResult{}, false, nil
+}
@@ -0,0 +1,151 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestClient_HookFallback(t *testing.T) {
+	var mu sync.Mutex
+	var created []createSessionRequest
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		if method == "session.create" {
+			var req createSessionRequest
+			json.Unmarshal(params, &req)
+			mu.Lock()
+			created = append(created, req)
+			mu.Unlock()
+			return createSessionResponse{SessionID: req.SessionID}, nil
+		}
+		return nil, nil
+	})
+	cli.capabilities = &ServerCapabilities{Hooks: Bool(false)}
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+
+	var preToolUse []PreToolUseHookInput
+	hooks := &SessionHooks{
+		OnPreToolUse: func(input PreToolUseHookInput, _ HookInvocation) (*PreToolUseHookOutput, error) {
+			preToolUse = append(preToolUse, input)
+			switch input.ToolName {
+			case "shell":
+				return &PreToolUseHookOutput{PermissionDecision: "deny", PermissionDecisionReason: "no shell"}, nil
+			case "read":
+				return &PreToolUseHookOutput{PermissionDecision: "allow"}, nil
+			}
+			return nil, nil
+		},
+		OnSessionEnd: func(SessionEndHookInput, HookInvocation) (*SessionEndHookOutput, error) { return nil, nil },
+	}
+	lastCreate := func() createSessionRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return created[len(created)-1]
+	}
+	create := func(t *testing.T, id string, fallback HookFallback, warnings *[]string) (*Session, error) {
+		return client.CreateSession(t.Context(), &SessionConfig{
+			SessionID: id,
+			OnPermissionRequest: func(PermissionRequest, PermissionInvocation) (PermissionRequestResult, error) {
+				return DeniedByUser("asked the user"), nil
+			},
+			Hooks:        hooks,
+			HookFallback: fallback,
+			OnEvent: []SessionEventHandler{func(event SessionEvent) {
+				if event.Type == SessionWarning && warnings != nil {
+					*warnings = append(*warnings, *event.Data.WarningType+": "+*event.Data.Message)
+				}
+			}},
+		})
+	}
+
+	t.Run("fails by default", func(t *testing.T) {
+		mu.Lock()
+		before := len(created)
+		mu.Unlock()
+		_, err := create(t, "s-error", "", nil)
+		if !errors.Is(err, ErrHooksUnsupported) {
+			t.Errorf("Expected ErrHooksUnsupported, got %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(created) != before {
+			t.Error("Expected no session.create request")
+		}
+	})
+
+	t.Run("drops hooks with a warning", func(t *testing.T) {
+		var warnings []string
+		session, err := create(t, "s-drop", HookFallbackDrop, &warnings)
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if req := lastCreate(); req.Hooks != nil {
+			t.Error("Expected hooks not to be requested")
+		}
+		if mode := session.Config().Hooks; mode != HookModeDropped {
+			t.Errorf("Expected dropped hooks, got %q", mode)
+		}
+		if len(warnings) != 1 || warnings[0] != "hooks_unsupported: the CLI does not support hooks; not running OnPreToolUse, OnSessionEnd" {
+			t.Errorf("Unexpected warnings: %v", warnings)
+		}
+		result, _ := session.handlePermissionRequest(PermissionRequest{Kind: "shell"})
+		if result.Kind != PermissionDeniedByUser || len(preToolUse) != 0 {
+			t.Errorf("Expected the permission handler to decide without the hook, got %+v", result)
+		}
+	})
+
+	t.Run("emulates preToolUse from permission requests", func(t *testing.T) {
+		var warnings []string
+		session, err := create(t, "s-emulate", HookFallbackEmulate, &warnings)
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if mode := session.Config().Hooks; mode != HookModeEmulated {
+			t.Errorf("Expected emulated hooks, got %q", mode)
+		}
+		if len(warnings) != 1 {
+			t.Errorf("Expected a warning about the dropped hooks, got %v", warnings)
+		}
+
+		denied, _ := session.handlePermissionRequest(PermissionRequest{Kind: "shell"})
+		if denied.Kind != PermissionDeniedByRules || denied.Reason != "no shell" {
+			t.Errorf("Expected the hook to deny shell, got %+v", denied)
+		}
+		allowed, _ := session.handlePermissionRequest(PermissionRequest{Kind: "read"})
+		if allowed.Kind != PermissionApproved {
+			t.Errorf("Expected the hook to allow read, got %+v", allowed)
+		}
+		asked, _ := session.handlePermissionRequest(PermissionRequest{Kind: "mcp", Extra: map[string]any{"toolName": "search"}})
+		if asked.Kind != PermissionDeniedByUser {
+			t.Errorf("Expected the permission handler to decide, got %+v", asked)
+		}
+		if len(preToolUse) != 3 || preToolUse[2].ToolName != "search" {
+			t.Errorf("Expected the hook to see 3 tool calls, got %+v", preToolUse)
+		}
+	})
+
+	t.Run("uses native hooks when supported", func(t *testing.T) {
+		cli.capabilities = nil
+		native := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+		t.Cleanup(func() { native.ForceStop() })
+		session, err := native.CreateSession(t.Context(), &SessionConfig{
+			SessionID:           "s-native",
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			Hooks:               hooks,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if req := lastCreate(); req.Hooks == nil || !*req.Hooks {
+			t.Error("Expected hooks to be requested")
+		}
+		if mode := session.Config().Hooks; mode != HookModeNative {
+			t.Errorf("Expected native hooks, got %q", mode)
+		}
+	})
+}
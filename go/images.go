@@ -0,0 +1,237 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrModelLacksVision is returned by [Session.Send] when a message carries images
+// but the session's current model does not support image input.
+var ErrModelLacksVision = errors.New("model does not support image input")
+
+// ImageAttachment describes an image to send alongside a prompt.
+//
+// Set either Path or Data. MimeType is inferred from the file extension or the
+// image bytes when empty.
+type ImageAttachment struct {
+	// Path is the path to an image file on disk
+	Path string
+	// Data is the raw image content, used when Path is empty
+	Data []byte
+	// MimeType is the image media type, e.g. "image/png"
+	MimeType string
+	// DisplayName is an optional label shown for the attachment
+	DisplayName string
+}
+
+// resolvedImage is an ImageAttachment with its media type and size determined.
+type resolvedImage struct {
+	ImageAttachment
+	size int64
+}
+
+// resolveImage determines the media type and size of an image attachment.
+func resolveImage(img ImageAttachment) (resolvedImage, error) {
+	resolved := resolvedImage{ImageAttachment: img}
+
+	switch {
+	case img.Path != "":
+		info, err := os.Stat(img.Path)
+		if err != nil {
+			return resolved, fmt.Errorf("failed to stat image %s: %w", img.Path, err)
+		}
+		if info.IsDir() {
+			return resolved, fmt.Errorf("image path %s is a directory", img.Path)
+		}
+		resolved.size = info.Size()
+		if resolved.MimeType == "" {
+			resolved.MimeType = mime.TypeByExtension(strings.ToLower(filepath.Ext(img.Path)))
+		}
+		if resolved.MimeType == "" {
+			header, err := readFileHeader(img.Path)
+			if err != nil {
+				return resolved, err
+			}
+			resolved.MimeType = http.DetectContentType(header)
+		}
+	case len(img.Data) > 0:
+		resolved.size = int64(len(img.Data))
+		if resolved.MimeType == "" {
+			resolved.MimeType = http.DetectContentType(img.Data)
+		}
+	default:
+		return resolved, errors.New("image attachment requires either Path or Data")
+	}
+
+	// Drop parameters such as "; charset=utf-8"
+	if mediaType, _, err := mime.ParseMediaType(resolved.MimeType); err == nil {
+		resolved.MimeType = mediaType
+	}
+	if !strings.HasPrefix(resolved.MimeType, "image/") {
+		return resolved, fmt.Errorf("unsupported image type %q", resolved.MimeType)
+	}
+	return resolved, nil
+}
+
+// readFileHeader reads the first bytes of a file for content sniffing.
+func readFileHeader(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image %s: %w", path, err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read image %s: %w", path, err)
+	}
+	return header[:n], nil
+}
+
+// validateImages checks images against the model's vision capabilities.
+// A nil model skips validation, since capabilities are unknown (e.g. BYOK models).
+func validateImages(model *ModelInfo, images []resolvedImage) error {
+	if model == nil {
+		return nil
+	}
+	if !model.Capabilities.Supports.Vision {
+		return fmt.Errorf("%w: %s", ErrModelLacksVision, model.ID)
+	}
+
+	limits := model.Capabilities.Limits.Vision
+	if limits == nil {
+		return nil
+	}
+	if limits.MaxPromptImages > 0 && len(images) > limits.MaxPromptImages {
+		return fmt.Errorf("model %s accepts at most %d images per prompt, got %d", model.ID, limits.MaxPromptImages, len(images))
+	}
+	for _, img := range images {
+		if len(limits.SupportedMediaTypes) > 0 && !slices.Contains(limits.SupportedMediaTypes, img.MimeType) {
+			return fmt.Errorf("model %s does not support image type %q (supported: %s)", model.ID, img.MimeType, strings.Join(limits.SupportedMediaTypes, ", "))
+		}
+		if limits.MaxPromptImageSize > 0 && img.size > int64(limits.MaxPromptImageSize) {
+			return fmt.Errorf("image %s is %d bytes, exceeding the %d byte limit for model %s", img.label(), img.size, limits.MaxPromptImageSize, model.ID)
+		}
+	}
+	return nil
+}
+
+// label returns a human-readable name for the image in error messages.
+func (img resolvedImage) label() string {
+	if img.DisplayName != "" {
+		return img.DisplayName
+	}
+	if img.Path != "" {
+		return img.Path
+	}
+	return "<inline " + img.MimeType + ">"
+}
+
+// currentModelInfo looks up the capabilities of the session's current model.
+// Returns nil if the model is unknown to the server's model list.
+func (s *Session) currentModelInfo(ctx context.Context) (*ModelInfo, error) {
+	if s.listModels == nil {
+		return nil, nil
+	}
+	current, err := s.RPC.Model.GetCurrent(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current model: %w", err)
+	}
+	if current.ModelID == nil {
+		return nil, nil
+	}
+	return s.modelInfo(ctx, *current.ModelID)
+}
+
+// prepareImages validates images against the model answering the message,
+// override if set or else the current model, and converts them to file
+// attachments. In-memory images are written to temporary files that are
+// removed when the session is destroyed.
+func (s *Session) prepareImages(ctx context.Context, images []ImageAttachment, override string) ([]Attachment, error) {
+	resolved := make([]resolvedImage, 0, len(images))
+	for _, img := range images {
+		r, err := resolveImage(img)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, r)
+	}
+
+	var model *ModelInfo
+	var err error
+	if override != "" {
+		model, err = s.modelInfo(ctx, override)
+	} else {
+		model, err = s.currentModelInfo(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := validateImages(model, resolved); err != nil {
+		return nil, err
+	}
+
+	attachments := make([]Attachment, 0, len(resolved))
+	for _, img := range resolved {
+		path := img.Path
+		if path == "" {
+			path, err = s.writeTempImage(img)
+			if err != nil {
+				return nil, err
+			}
+		}
+		attachment := Attachment{Type: File, Path: &path}
+		if img.DisplayName != "" {
+			attachment.DisplayName = &img.DisplayName
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// writeTempImage writes in-memory image data to a temporary file owned by the session.
+func (s *Session) writeTempImage(img resolvedImage) (string, error) {
+	ext := ""
+	if exts, _ := mime.ExtensionsByType(img.MimeType); len(exts) > 0 {
+		ext = exts[0]
+	}
+	f, err := os.CreateTemp("", "copilot-image-*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp image: %w", err)
+	}
+	if _, err := f.Write(img.Data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write temp image: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write temp image: %w", err)
+	}
+
+	s.tempFilesMux.Lock()
+	s.tempFiles = append(s.tempFiles, f.Name())
+	s.tempFilesMux.Unlock()
+	return f.Name(), nil
+}
+
+// removeTempFiles deletes temporary files created for this session.
+func (s *Session) removeTempFiles() {
+	s.tempFilesMux.Lock()
+	files := s.tempFiles
+	s.tempFiles = nil
+	s.tempFilesMux.Unlock()
+
+	for _, f := range files {
+		os.Remove(f)
+	}
+}
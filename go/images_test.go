@@ -0,0 +1,178 @@
+package copilot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A 1x1 transparent PNG.
+var testPNG = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d,
+	0x49, 0x48, 0x44, 0x52, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01,
+	0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0x15, 0xc4, 0x89, 0x00, 0x00, 0x00,
+	0x0d, 0x49, 0x44, 0x41, 0x54, 0x78, 0x9c, 0x63, 0x00, 0x01, 0x00, 0x00,
+	0x05, 0x00, 0x01, 0x0d, 0x0a, 0x2d, 0xb4, 0x00, 0x00, 0x00, 0x00, 0x49,
+	0x45, 0x4e, 0x44, 0xae, 0x42, 0x60, 0x82,
+}
+
+func visionModel(supportedTypes []string, maxImages, maxSize int) *ModelInfo {
+	return &ModelInfo{
+		ID: "vision-model",
+		Capabilities: ModelCapabilities{
+			Supports: ModelSupports{Vision: true},
+			Limits: ModelLimits{
+				Vision: &ModelVisionLimits{
+					SupportedMediaTypes: supportedTypes,
+					MaxPromptImages:     maxImages,
+					MaxPromptImageSize:  maxSize,
+				},
+			},
+		},
+	}
+}
+
+func TestResolveImage(t *testing.T) {
+	t.Run("detects mime type from data", func(t *testing.T) {
+		img, err := resolveImage(ImageAttachment{Data: testPNG})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if img.MimeType != "image/png" {
+			t.Errorf("Expected image/png, got %q", img.MimeType)
+		}
+		if img.size != int64(len(testPNG)) {
+			t.Errorf("Expected size %d, got %d", len(testPNG), img.size)
+		}
+	})
+
+	t.Run("detects mime type from file extension", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "pixel.png")
+		if err := os.WriteFile(path, testPNG, 0644); err != nil {
+			t.Fatal(err)
+		}
+		img, err := resolveImage(ImageAttachment{Path: path})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if img.MimeType != "image/png" {
+			t.Errorf("Expected image/png, got %q", img.MimeType)
+		}
+	})
+
+	t.Run("sniffs files without a known extension", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "pixel")
+		if err := os.WriteFile(path, testPNG, 0644); err != nil {
+			t.Fatal(err)
+		}
+		img, err := resolveImage(ImageAttachment{Path: path})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if img.MimeType != "image/png" {
+			t.Errorf("Expected image/png, got %q", img.MimeType)
+		}
+	})
+
+	t.Run("rejects non-image content", func(t *testing.T) {
+		_, err := resolveImage(ImageAttachment{Data: []byte("hello world")})
+		if err == nil || !strings.Contains(err.Error(), "unsupported image type") {
+			t.Errorf("Expected unsupported image type error, got %v", err)
+		}
+	})
+
+	t.Run("requires path or data", func(t *testing.T) {
+		if _, err := resolveImage(ImageAttachment{}); err == nil {
+			t.Error("Expected error for empty image attachment")
+		}
+	})
+}
+
+func TestValidateImages(t *testing.T) {
+	png, err := resolveImage(ImageAttachment{Data: testPNG})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("returns ErrModelLacksVision for non-vision models", func(t *testing.T) {
+		model := &ModelInfo{ID: "text-only"}
+		err := validateImages(model, []resolvedImage{png})
+		if !errors.Is(err, ErrModelLacksVision) {
+			t.Errorf("Expected ErrModelLacksVision, got %v", err)
+		}
+	})
+
+	t.Run("skips validation for unknown models", func(t *testing.T) {
+		if err := validateImages(nil, []resolvedImage{png}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("accepts supported images", func(t *testing.T) {
+		model := visionModel([]string{"image/png", "image/jpeg"}, 5, 1024)
+		if err := validateImages(model, []resolvedImage{png}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("rejects unsupported media types", func(t *testing.T) {
+		model := visionModel([]string{"image/jpeg"}, 5, 1024)
+		err := validateImages(model, []resolvedImage{png})
+		if err == nil || !strings.Contains(err.Error(), "does not support image type") {
+			t.Errorf("Expected media type error, got %v", err)
+		}
+	})
+
+	t.Run("rejects too many images", func(t *testing.T) {
+		model := visionModel(nil, 1, 0)
+		err := validateImages(model, []resolvedImage{png, png})
+		if err == nil || !strings.Contains(err.Error(), "at most 1 images") {
+			t.Errorf("Expected image count error, got %v", err)
+		}
+	})
+
+	t.Run("rejects oversized images", func(t *testing.T) {
+		model := visionModel(nil, 0, 10)
+		err := validateImages(model, []resolvedImage{png})
+		if err == nil || !strings.Contains(err.Error(), "exceeding") {
+			t.Errorf("Expected image size error, got %v", err)
+		}
+	})
+}
+
+func TestSession_PrepareImages(t *testing.T) {
+	t.Run("writes inline images to temp files removed on cleanup", func(t *testing.T) {
+		session := &Session{}
+
+		attachments, err := session.prepareImages(t.Context(), []ImageAttachment{
+			{Data: testPNG, DisplayName: "pixel"},
+		}, "")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(attachments) != 1 {
+			t.Fatalf("Expected 1 attachment, got %d", len(attachments))
+		}
+
+		attachment := attachments[0]
+		if attachment.Type != File {
+			t.Errorf("Expected file attachment, got %q", attachment.Type)
+		}
+		if attachment.DisplayName == nil || *attachment.DisplayName != "pixel" {
+			t.Errorf("Expected display name 'pixel', got %v", attachment.DisplayName)
+		}
+		if attachment.Path == nil || filepath.Ext(*attachment.Path) != ".png" {
+			t.Fatalf("Expected .png temp file, got %v", attachment.Path)
+		}
+		if _, err := os.Stat(*attachment.Path); err != nil {
+			t.Fatalf("Expected temp file to exist: %v", err)
+		}
+
+		session.removeTempFiles()
+		if _, err := os.Stat(*attachment.Path); !os.IsNotExist(err) {
+			t.Errorf("Expected temp file to be removed, got %v", err)
+		}
+	})
+}
@@ -173,4 +173,59 @@ func TestAskUser(t *testing.T) {
 			t.Error("Expected non-nil response")
 		}
 	})
+
+	t.Run("should answer with the default choice when no handler is registered", func(t *testing.T) {
+		ctx.ConfigureForTest(t)
+
+		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{
+			OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+			UserInputFallback:   &copilot.UserInputFallback{Mode: copilot.UserInputFallbackDefaultChoice},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		var mu sync.Mutex
+		var warnings []copilot.SessionEvent
+		session.On(func(event copilot.SessionEvent) {
+			if event.Type == copilot.SessionWarning {
+				mu.Lock()
+				warnings = append(warnings, event)
+				mu.Unlock()
+			}
+		})
+
+		response, err := session.SendAndWait(t.Context(), copilot.MessageOptions{
+			Prompt: "Use the ask_user tool to ask me to pick between exactly two options: 'Red' and 'Blue'. These should be provided as choices. Wait for my answer.",
+		})
+		if err != nil {
+			t.Fatalf("Failed to send message: %v", err)
+		}
+		ctx.AssertContains(t, response, "Red")
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(warnings) == 0 || *warnings[0].Data.WarningType != copilot.UserInputUnavailableWarning {
+			t.Errorf("Expected a user input warning, got %+v", warnings)
+		}
+	})
+
+	t.Run("should tell the model to proceed when no handler is registered", func(t *testing.T) {
+		ctx.ConfigureForTest(t)
+
+		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{
+			OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+			UserInputFallback:   &copilot.UserInputFallback{},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		response, err := session.SendAndWait(t.Context(), copilot.MessageOptions{
+			Prompt: "Ask me a question using ask_user and then include my answer in your response. The question should be 'What is your favorite color?'",
+		})
+		if err != nil {
+			t.Fatalf("Failed to send message: %v", err)
+		}
+		ctx.AssertContains(t, response)
+	})
 }
@@ -0,0 +1,56 @@
+package e2e
+
+import (
+	"strings"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+	"github.com/github/copilot-sdk/go/internal/e2e/testharness"
+)
+
+func TestAttribution(t *testing.T) {
+	ctx := testharness.NewTestContext(t)
+	client := ctx.NewClientWithOptions(func(options *copilot.ClientOptions) {
+		options.IntegrationID = "acme-reviews"
+	})
+	t.Cleanup(func() { client.ForceStop() })
+
+	t.Run("should tag API requests with the integration ID", func(t *testing.T) {
+		ctx.ReplayOnly(t, "inspects the recorded requests")
+		ctx.ConfigureForTest(t)
+
+		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{
+			OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		_, err = session.SendAndWait(t.Context(), copilot.MessageOptions{
+			Prompt:    "What is 1+1?",
+			Initiator: "nightly-eval",
+		})
+		if err != nil {
+			t.Fatalf("Failed to send message: %v", err)
+		}
+
+		traffic, err := ctx.GetExchanges()
+		if err != nil {
+			t.Fatalf("Failed to get exchanges: %v", err)
+		}
+		if len(traffic) == 0 {
+			t.Fatal("Expected at least one exchange")
+		}
+
+		tagged := false
+		for key := range traffic[0].RequestHeaders {
+			if strings.Contains(traffic[0].Header(key), "acme-reviews") {
+				tagged = true
+				break
+			}
+		}
+		if !tagged {
+			t.Errorf("Expected a request header to carry the integration ID, got %v", traffic[0].RequestHeaders)
+		}
+	})
+}
@@ -1,6 +1,8 @@
 package e2e
 
 import (
+	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -49,6 +51,10 @@ func TestClient(t *testing.T) {
 		if client.State() != copilot.StateDisconnected {
 			t.Errorf("Expected state to be 'disconnected', got %q", client.State())
 		}
+
+		if _, err := client.Ping(t.Context(), "after stop"); !errors.Is(err, copilot.ErrClientStopped) {
+			t.Errorf("Expected ErrClientStopped after stop, got %v", err)
+		}
 	})
 
 	t.Run("should start and connect to server using tcp", func(t *testing.T) {
@@ -242,6 +248,13 @@ func TestClient(t *testing.T) {
 		if err == nil {
 			t.Fatal("Expected Start to fail with invalid CLI args")
 		}
+		var startErr *copilot.CLIStartError
+		if !errors.Is(err, copilot.ErrCLIStartFailed) || !errors.As(err, &startErr) {
+			t.Fatalf("Expected ErrCLIStartFailed, got %v", err)
+		}
+		if startErr.ExitCode == 0 {
+			t.Errorf("Expected a non-zero exit code, got %d", startErr.ExitCode)
+		}
 
 		// Verify subsequent calls also fail (don't hang)
 		session, err := client.CreateSession(t.Context(), nil)
@@ -252,4 +265,20 @@ func TestClient(t *testing.T) {
 			t.Fatal("Expected CreateSession/Send to fail after CLI exit")
 		}
 	})
+
+	t.Run("should report error when CLI is not found", func(t *testing.T) {
+		client := copilot.NewClient(&copilot.ClientOptions{
+			CLIPath: filepath.Join(t.TempDir(), "copilot"),
+		})
+		t.Cleanup(func() { client.ForceStop() })
+
+		err := client.Start(t.Context())
+		var notFound *copilot.CLINotFoundError
+		if !errors.Is(err, copilot.ErrCLINotFound) || !errors.As(err, &notFound) {
+			t.Fatalf("Expected ErrCLINotFound, got %v", err)
+		}
+		if len(notFound.Searched) != 1 {
+			t.Errorf("Expected the CLI path to be searched, got %v", notFound.Searched)
+		}
+	})
 }
@@ -1,7 +1,6 @@
 package e2e
 
 import (
-	"strings"
 	"testing"
 
 	copilot "github.com/github/copilot-sdk/go"
@@ -69,13 +68,21 @@ func TestCompaction(t *testing.T) {
 		}
 
 		// Compaction should have succeeded
+		if len(compactionStartEvents) > 0 {
+			if _, ok := compactionStartEvents[0].AsCompactionStart(); !ok {
+				t.Errorf("Expected compaction_start to decode as a typed payload")
+			}
+		}
 		if len(compactionCompleteEvents) > 0 {
-			lastComplete := compactionCompleteEvents[len(compactionCompleteEvents)-1]
-			if lastComplete.Data.Success == nil || !*lastComplete.Data.Success {
-				t.Errorf("Expected compaction to succeed")
+			lastComplete, ok := compactionCompleteEvents[len(compactionCompleteEvents)-1].AsCompactionComplete()
+			if !ok {
+				t.Fatalf("Expected compaction_complete to decode as a typed payload")
 			}
-			if lastComplete.Data.TokensRemoved != nil && *lastComplete.Data.TokensRemoved <= 0 {
-				t.Errorf("Expected tokensRemoved > 0, got %v", *lastComplete.Data.TokensRemoved)
+			if !lastComplete.Success {
+				t.Errorf("Expected compaction to succeed, got error %q", lastComplete.Error)
+			}
+			if lastComplete.TokensRemoved <= 0 {
+				t.Errorf("Expected tokensRemoved > 0, got %v", lastComplete.TokensRemoved)
 			}
 		}
 
@@ -84,9 +91,7 @@ func TestCompaction(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to send verification message: %v", err)
 		}
-		if answer.Data.Content == nil || !strings.Contains(strings.ToLower(*answer.Data.Content), "dragon") {
-			t.Errorf("Expected answer to contain 'dragon', got %v", answer.Data.Content)
-		}
+		ctx.AssertContainsFold(t, answer, "dragon")
 	})
 
 	t.Run("should not emit compaction events when infinite sessions disabled", func(t *testing.T) {
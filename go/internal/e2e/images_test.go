@@ -0,0 +1,67 @@
+package e2e
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+	"github.com/github/copilot-sdk/go/internal/e2e/testharness"
+)
+
+func TestImages(t *testing.T) {
+	ctx := testharness.NewTestContext(t)
+	client := ctx.NewClient()
+	t.Cleanup(func() { client.ForceStop() })
+
+	t.Run("should describe an attached png image", func(t *testing.T) {
+		ctx.ConfigureForTest(t)
+
+		// A small solid red square
+		img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+		for x := 0; x < 16; x++ {
+			for y := 0; y < 16; y++ {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			}
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatalf("Failed to encode image: %v", err)
+		}
+		imagePath := filepath.Join(ctx.WorkDir, "square.png")
+		if err := os.WriteFile(imagePath, buf.Bytes(), 0644); err != nil {
+			t.Fatalf("Failed to write image: %v", err)
+		}
+
+		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{OnPermissionRequest: copilot.PermissionHandler.ApproveAll})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		answer, err := session.SendAndWait(t.Context(), copilot.MessageOptions{
+			Prompt: "What color is the attached image? Answer with one word.",
+			Images: []copilot.ImageAttachment{{Path: imagePath}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to send message: %v", err)
+		}
+
+		ctx.AssertContainsFold(t, answer, "red")
+
+		messages, err := session.GetMessages(t.Context())
+		if err != nil {
+			t.Fatalf("Failed to get messages: %v", err)
+		}
+		for _, message := range messages {
+			if data, ok := message.AsUserMessage(); ok {
+				if len(data.Attachments) != 1 || data.Attachments[0].Path == nil || *data.Attachments[0].Path != imagePath {
+					t.Errorf("Expected the user message to carry the image, got %+v", data.Attachments)
+				}
+			}
+		}
+	})
+}
@@ -2,7 +2,6 @@ package e2e
 
 import (
 	"path/filepath"
-	"strings"
 	"testing"
 
 	copilot "github.com/github/copilot-sdk/go"
@@ -51,9 +50,7 @@ func TestMCPServers(t *testing.T) {
 			t.Fatalf("Failed to get final message: %v", err)
 		}
 
-		if message.Data.Content == nil || !strings.Contains(*message.Data.Content, "4") {
-			t.Errorf("Expected message to contain '4', got: %v", message.Data.Content)
-		}
+		ctx.AssertContains(t, message, "4")
 
 		session.Destroy()
 	})
@@ -100,9 +97,7 @@ func TestMCPServers(t *testing.T) {
 			t.Fatalf("Failed to send message: %v", err)
 		}
 
-		if message.Data.Content == nil || !strings.Contains(*message.Data.Content, "6") {
-			t.Errorf("Expected message to contain '6', got: %v", message.Data.Content)
-		}
+		ctx.AssertContains(t, message, "6")
 
 		session2.Destroy()
 	})
@@ -146,9 +141,7 @@ func TestMCPServers(t *testing.T) {
 			t.Fatalf("Failed to send message: %v", err)
 		}
 
-		if message.Data.Content == nil || !strings.Contains(*message.Data.Content, "hunter2") {
-			t.Errorf("Expected message to contain 'hunter2', got: %v", message.Data.Content)
-		}
+		ctx.AssertContains(t, message, "hunter2")
 
 		session.Destroy()
 	})
@@ -185,6 +178,55 @@ func TestMCPServers(t *testing.T) {
 
 		session.Destroy()
 	})
+
+	t.Run("leave a broken MCP server out with warn-and-continue", func(t *testing.T) {
+		ctx.ConfigureForTest(t)
+
+		mcpServers := map[string]copilot.MCPServerConfig{
+			"server1": {
+				"type":    "local",
+				"command": "echo",
+				"args":    []string{"server1"},
+				"tools":   []string{"*"},
+			},
+			"broken": {
+				"type":    "local",
+				"command": filepath.Join(ctx.WorkDir, "no-such-mcp-server"),
+				"tools":   []string{"*"},
+			},
+			"server2": {
+				"type":    "local",
+				"command": "echo",
+				"args":    []string{"server2"},
+				"tools":   []string{"*"},
+			},
+		}
+
+		var warnings []string
+		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{
+			OnPermissionRequest:  copilot.PermissionHandler.ApproveAll,
+			MCPServers:           mcpServers,
+			PartialFailurePolicy: copilot.PartialFailureWarnAndContinue,
+			OnEvent: []copilot.SessionEventHandler{func(event copilot.SessionEvent) {
+				if event.Type == copilot.SessionWarning && *event.Data.WarningType == copilot.ConfigComponentWarning {
+					warnings = append(warnings, *event.Data.Message)
+				}
+			}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		got := session.Warnings()
+		if len(got) != 1 || got[0].Component != copilot.ConfigComponentMCPServer || got[0].Name != "broken" {
+			t.Errorf("Expected a warning for the broken server only, got %v", got)
+		}
+		if len(warnings) != 1 {
+			t.Errorf("Expected one warning event, got %v", warnings)
+		}
+
+		session.Destroy()
+	})
 }
 
 func TestCustomAgents(t *testing.T) {
@@ -231,9 +273,7 @@ func TestCustomAgents(t *testing.T) {
 			t.Fatalf("Failed to get final message: %v", err)
 		}
 
-		if message.Data.Content == nil || !strings.Contains(*message.Data.Content, "10") {
-			t.Errorf("Expected message to contain '10', got: %v", message.Data.Content)
-		}
+		ctx.AssertContains(t, message, "10")
 
 		session.Destroy()
 	})
@@ -280,9 +320,7 @@ func TestCustomAgents(t *testing.T) {
 			t.Fatalf("Failed to send message: %v", err)
 		}
 
-		if message.Data.Content == nil || !strings.Contains(*message.Data.Content, "12") {
-			t.Errorf("Expected message to contain '12', got: %v", message.Data.Content)
-		}
+		ctx.AssertContains(t, message, "12")
 
 		session2.Destroy()
 	})
@@ -441,9 +479,7 @@ func TestCombinedConfiguration(t *testing.T) {
 			t.Fatalf("Failed to get final message: %v", err)
 		}
 
-		if message.Data.Content == nil || !strings.Contains(*message.Data.Content, "14") {
-			t.Errorf("Expected message to contain '14', got: %v", message.Data.Content)
-		}
+		ctx.AssertContains(t, message, "14")
 
 		session.Destroy()
 	})
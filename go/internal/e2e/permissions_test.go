@@ -31,7 +31,7 @@ func TestPermissions(t *testing.T) {
 				t.Error("Expected non-empty session ID in invocation")
 			}
 
-			return copilot.PermissionRequestResult{Kind: "approved"}, nil
+			return copilot.Approved(), nil
 		}
 
 		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{
@@ -82,7 +82,7 @@ func TestPermissions(t *testing.T) {
 			permissionRequests = append(permissionRequests, request)
 			mu.Unlock()
 
-			return copilot.PermissionRequestResult{Kind: "approved"}, nil
+			return copilot.Approved(), nil
 		}
 
 		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{
@@ -117,7 +117,7 @@ func TestPermissions(t *testing.T) {
 		ctx.ConfigureForTest(t)
 
 		onPermissionRequest := func(request copilot.PermissionRequest, invocation copilot.PermissionInvocation) (copilot.PermissionRequestResult, error) {
-			return copilot.PermissionRequestResult{Kind: "denied-interactively-by-user"}, nil
+			return copilot.DeniedByUser(""), nil
 		}
 
 		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{
@@ -162,7 +162,7 @@ func TestPermissions(t *testing.T) {
 
 		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{
 			OnPermissionRequest: func(request copilot.PermissionRequest, invocation copilot.PermissionInvocation) (copilot.PermissionRequestResult, error) {
-				return copilot.PermissionRequestResult{Kind: "denied-no-approval-rule-and-could-not-request-from-user"}, nil
+				return copilot.DeniedNoApprovalRule(), nil
 			},
 		})
 		if err != nil {
@@ -212,7 +212,7 @@ func TestPermissions(t *testing.T) {
 
 		session2, err := client.ResumeSession(t.Context(), sessionID, &copilot.ResumeSessionConfig{
 			OnPermissionRequest: func(request copilot.PermissionRequest, invocation copilot.PermissionInvocation) (copilot.PermissionRequestResult, error) {
-				return copilot.PermissionRequestResult{Kind: "denied-no-approval-rule-and-could-not-request-from-user"}, nil
+				return copilot.DeniedNoApprovalRule(), nil
 			},
 		})
 		if err != nil {
@@ -266,8 +266,63 @@ func TestPermissions(t *testing.T) {
 			t.Fatalf("Failed to get final message: %v", err)
 		}
 
-		if message.Data.Content == nil || !strings.Contains(*message.Data.Content, "4") {
-			t.Errorf("Expected message to contain '4', got: %v", message.Data.Content)
+		ctx.AssertContains(t, message, "4")
+	})
+
+	t.Run("autonomous mode approves writes inside the sandbox", func(t *testing.T) {
+		ctx.ConfigureForTest(t)
+
+		var mu sync.Mutex
+		var decisions []string
+		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{
+			AutoApprove: &copilot.AutoApprovePolicy{
+				ApproveKinds: []string{"*"},
+				DenyKinds:    []string{"shell", "url"},
+				WritePaths:   []string{ctx.WorkDir},
+				OnDecision: func(request copilot.PermissionRequest, result copilot.PermissionRequestResult) {
+					mu.Lock()
+					decisions = append(decisions, request.Kind+":"+string(result.Kind))
+					mu.Unlock()
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		testFile := filepath.Join(ctx.WorkDir, "test.txt")
+		if err := os.WriteFile(testFile, []byte("original content"), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+
+		_, err = session.SendAndWait(t.Context(), copilot.MessageOptions{
+			Prompt: "Edit test.txt and replace 'original' with 'modified'",
+		})
+		if err != nil {
+			t.Fatalf("Failed to send message: %v", err)
+		}
+
+		content, err := os.ReadFile(testFile)
+		if err != nil {
+			t.Fatalf("Failed to read test file: %v", err)
+		}
+		if !strings.Contains(string(content), "modified") {
+			t.Errorf("Expected the write to be approved, got content %q", content)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		approvedWrite := false
+		for _, decision := range decisions {
+			if decision == "write:approved" {
+				approvedWrite = true
+			}
+		}
+		if !approvedWrite {
+			t.Errorf("Expected an approved write decision, got %v", decisions)
+		}
+		if stats := session.AutoApproveStats(); stats.Approved == 0 || stats.Deferred != 0 {
+			t.Errorf("Expected every request to be decided by the policy, got %+v", stats)
 		}
 	})
 }
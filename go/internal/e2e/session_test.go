@@ -1,6 +1,7 @@
 package e2e
 
 import (
+	"errors"
 	"regexp"
 	"strings"
 	"testing"
@@ -16,6 +17,7 @@ func TestSession(t *testing.T) {
 	t.Cleanup(func() { client.ForceStop() })
 
 	t.Run("should create and destroy sessions", func(t *testing.T) {
+		ctx.ReplayOnly(t, "the fake model exists only in the replay proxy")
 		ctx.ConfigureForTest(t)
 
 		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{OnPermissionRequest: copilot.PermissionHandler.ApproveAll, Model: "fake-test-model"})
@@ -50,8 +52,8 @@ func TestSession(t *testing.T) {
 		}
 
 		_, err = session.GetMessages(t.Context())
-		if err == nil || !strings.Contains(err.Error(), "not found") {
-			t.Errorf("Expected GetMessages to fail with 'not found' after destroy, got %v", err)
+		if !errors.Is(err, copilot.ErrSessionDestroyed) {
+			t.Errorf("Expected GetMessages to fail with ErrSessionDestroyed after destroy, got %v", err)
 		}
 	})
 
@@ -68,21 +70,18 @@ func TestSession(t *testing.T) {
 			t.Fatalf("Failed to send message: %v", err)
 		}
 
-		if assistantMessage.Data.Content == nil || !strings.Contains(*assistantMessage.Data.Content, "2") {
-			t.Errorf("Expected assistant message to contain '2', got %v", assistantMessage.Data.Content)
-		}
+		ctx.AssertContains(t, assistantMessage, "2")
 
 		secondMessage, err := session.SendAndWait(t.Context(), copilot.MessageOptions{Prompt: "Now if you double that, what do you get?"})
 		if err != nil {
 			t.Fatalf("Failed to send second message: %v", err)
 		}
 
-		if secondMessage.Data.Content == nil || !strings.Contains(*secondMessage.Data.Content, "4") {
-			t.Errorf("Expected second message to contain '4', got %v", secondMessage.Data.Content)
-		}
+		ctx.AssertContains(t, secondMessage, "4")
 	})
 
 	t.Run("should create a session with appended systemMessage config", func(t *testing.T) {
+		ctx.ReplayOnly(t, "inspects the recorded requests")
 		ctx.ConfigureForTest(t)
 
 		systemMessageSuffix := "End each response with the phrase 'Have a nice day!'"
@@ -131,7 +130,47 @@ func TestSession(t *testing.T) {
 		}
 	})
 
+	t.Run("should answer in the session response language", func(t *testing.T) {
+		ctx.ReplayOnly(t, "inspects the recorded requests")
+		ctx.ConfigureForTest(t)
+
+		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{
+			OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+			ResponseLanguage:    "de",
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		for _, prompt := range []string{"What is 1+1?", "Now if you double that, what do you get?"} {
+			if _, err := session.SendAndWait(t.Context(), copilot.MessageOptions{Prompt: prompt}); err != nil {
+				t.Fatalf("Failed to send message: %v", err)
+			}
+		}
+
+		// The instruction is part of the system context once, not repeated per turn
+		traffic, err := ctx.GetExchanges()
+		if err != nil {
+			t.Fatalf("Failed to get exchanges: %v", err)
+		}
+		if len(traffic) < 2 {
+			t.Fatalf("Expected at least two exchanges, got %d", len(traffic))
+		}
+		instruction := `BCP 47 tag "de"`
+		last := traffic[len(traffic)-1]
+		for _, msg := range last.Request.Messages {
+			count := strings.Count(msg.Content, instruction)
+			if msg.Role == "system" && count != 1 {
+				t.Errorf("Expected the instruction once in the system message, got %d in %q", count, msg.Content)
+			}
+			if msg.Role != "system" && count != 0 {
+				t.Errorf("Expected no instruction in %s messages, got %q", msg.Role, msg.Content)
+			}
+		}
+	})
+
 	t.Run("should create a session with replaced systemMessage config", func(t *testing.T) {
+		ctx.ReplayOnly(t, "inspects the recorded requests")
 		ctx.ConfigureForTest(t)
 
 		testSystemMessage := "You are an assistant called Testy McTestface. Reply succinctly."
@@ -183,6 +222,7 @@ func TestSession(t *testing.T) {
 	})
 
 	t.Run("should create a session with availableTools", func(t *testing.T) {
+		ctx.ReplayOnly(t, "inspects the recorded requests")
 		ctx.ConfigureForTest(t)
 
 		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{
@@ -222,6 +262,7 @@ func TestSession(t *testing.T) {
 	})
 
 	t.Run("should create a session with excludedTools", func(t *testing.T) {
+		ctx.ReplayOnly(t, "inspects the recorded requests")
 		ctx.ConfigureForTest(t)
 
 		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{
@@ -310,14 +351,8 @@ func TestSession(t *testing.T) {
 			t.Fatalf("Failed to get assistant message: %v", err)
 		}
 
-		content := ""
-		if assistantMessage.Data.Content != nil {
-			content = *assistantMessage.Data.Content
-		}
-
-		if !strings.Contains(content, "54321") {
-			t.Errorf("Expected response to contain '54321', got %q", content)
-		}
+		ctx.AssertToolCalled(t, session, "get_secret_number")
+		ctx.AssertContains(t, assistantMessage, "54321")
 	})
 
 	t.Run("should handle multiple concurrent sessions", func(t *testing.T) {
@@ -344,9 +379,7 @@ func TestSession(t *testing.T) {
 			t.Fatalf("Failed to get assistant message: %v", err)
 		}
 
-		if answer.Data.Content == nil || !strings.Contains(*answer.Data.Content, "2") {
-			t.Errorf("Expected answer to contain '2', got %v", answer.Data.Content)
-		}
+		ctx.AssertContains(t, answer, "2")
 
 		// Resume using the same client
 		session2, err := client.ResumeSession(t.Context(), sessionID, &copilot.ResumeSessionConfig{
@@ -365,9 +398,7 @@ func TestSession(t *testing.T) {
 			t.Fatalf("Failed to get assistant message from resumed session: %v", err)
 		}
 
-		if answer2.Data.Content == nil || !strings.Contains(*answer2.Data.Content, "2") {
-			t.Errorf("Expected resumed session answer to contain '2', got %v", answer2.Data.Content)
-		}
+		ctx.AssertContains(t, answer2, "2")
 	})
 
 	t.Run("should resume a session using a new client", func(t *testing.T) {
@@ -390,9 +421,7 @@ func TestSession(t *testing.T) {
 			t.Fatalf("Failed to get assistant message: %v", err)
 		}
 
-		if answer.Data.Content == nil || !strings.Contains(*answer.Data.Content, "2") {
-			t.Errorf("Expected answer to contain '2', got %v", answer.Data.Content)
-		}
+		ctx.AssertContains(t, answer, "2")
 
 		// Resume using a new client
 		newClient := ctx.NewClient()
@@ -440,8 +469,54 @@ func TestSession(t *testing.T) {
 		_, err := client.ResumeSession(t.Context(), "non-existent-session-id", &copilot.ResumeSessionConfig{
 			OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
 		})
-		if err == nil {
-			t.Error("Expected error when resuming non-existent session")
+		var rpcErr *copilot.RPCError
+		if !errors.As(err, &rpcErr) || rpcErr.Method != "session.resume" || rpcErr.Code == 0 {
+			t.Errorf("Expected an RPCError from session.resume when resuming non-existent session, got %v", err)
+		}
+	})
+
+	t.Run("should keep the workspace path when resuming", func(t *testing.T) {
+		ctx.ConfigureForTest(t)
+
+		session1, err := client.CreateSession(t.Context(), &copilot.SessionConfig{
+			OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+			InfiniteSessions:    &copilot.InfiniteSessionConfig{Enabled: copilot.Bool(true)},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		workspacePath := session1.WorkspacePath()
+		if workspacePath == "" {
+			t.Fatal("Expected the created session to have a workspace path")
+		}
+		if got := session1.Config().WorkspacePath; got != workspacePath {
+			t.Errorf("Expected Config to report workspace %q, got %q", workspacePath, got)
+		}
+
+		session2, err := client.ResumeSession(t.Context(), session1.SessionID, &copilot.ResumeSessionConfig{
+			OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+		})
+		if err != nil {
+			t.Fatalf("Failed to resume session: %v", err)
+		}
+		if got := session2.WorkspacePath(); got != workspacePath {
+			t.Errorf("Expected resumed workspace path %q, got %q", workspacePath, got)
+		}
+
+		newClient := ctx.NewClient()
+		defer newClient.ForceStop()
+		session3, err := newClient.ResumeSession(t.Context(), session1.SessionID, &copilot.ResumeSessionConfig{
+			OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+			InfiniteSessions:    &copilot.InfiniteSessionConfig{Enabled: copilot.Bool(true)},
+		})
+		if err != nil {
+			t.Fatalf("Failed to resume session with a new client: %v", err)
+		}
+		if got := session3.WorkspacePath(); got != workspacePath {
+			t.Errorf("Expected workspace path %q after resuming with a new client, got %q", workspacePath, got)
+		}
+		if !session3.Config().InfiniteSessions {
+			t.Error("Expected Config to report infinite sessions")
 		}
 	})
 
@@ -560,9 +635,7 @@ func TestSession(t *testing.T) {
 			t.Fatalf("Failed to send message after abort: %v", err)
 		}
 
-		if answer.Data.Content == nil || !strings.Contains(*answer.Data.Content, "4") {
-			t.Errorf("Expected answer to contain '4', got %v", answer.Data.Content)
-		}
+		ctx.AssertContains(t, answer, "4")
 	})
 
 	t.Run("should receive streaming delta events when streaming is enabled", func(t *testing.T) {
@@ -620,9 +693,7 @@ func TestSession(t *testing.T) {
 		}
 
 		// Final message should contain the answer
-		if assistantMessage.Data.Content == nil || !strings.Contains(*assistantMessage.Data.Content, "4") {
-			t.Errorf("Expected assistant message to contain '4', got %v", assistantMessage.Data.Content)
-		}
+		ctx.AssertContains(t, assistantMessage, "4")
 	})
 
 	t.Run("should pass streaming option to session creation", func(t *testing.T) {
@@ -653,9 +724,7 @@ func TestSession(t *testing.T) {
 			t.Fatalf("Failed to get assistant message: %v", err)
 		}
 
-		if assistantMessage.Data.Content == nil || !strings.Contains(*assistantMessage.Data.Content, "2") {
-			t.Errorf("Expected assistant message to contain '2', got %v", assistantMessage.Data.Content)
-		}
+		ctx.AssertContains(t, assistantMessage, "2")
 	})
 
 	t.Run("should receive session events", func(t *testing.T) {
@@ -726,9 +795,7 @@ func TestSession(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to get assistant message: %v", err)
 		}
-		if assistantMessage.Data.Content == nil || !strings.Contains(*assistantMessage.Data.Content, "300") {
-			t.Errorf("Expected assistant message to contain '300', got %v", assistantMessage.Data.Content)
-		}
+		ctx.AssertContains(t, assistantMessage, "300")
 	})
 
 	t.Run("should create session with custom config dir", func(t *testing.T) {
@@ -759,9 +826,7 @@ func TestSession(t *testing.T) {
 			t.Fatalf("Failed to get assistant message: %v", err)
 		}
 
-		if assistantMessage.Data.Content == nil || !strings.Contains(*assistantMessage.Data.Content, "2") {
-			t.Errorf("Expected assistant message to contain '2', got %v", assistantMessage.Data.Content)
-		}
+		ctx.AssertContains(t, assistantMessage, "2")
 	})
 
 	t.Run("should list sessions", func(t *testing.T) {
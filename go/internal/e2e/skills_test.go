@@ -72,9 +72,7 @@ func TestSkills(t *testing.T) {
 			t.Fatalf("Failed to send message: %v", err)
 		}
 
-		if message.Data.Content == nil || !strings.Contains(*message.Data.Content, skillMarker) {
-			t.Errorf("Expected message to contain skill marker '%s', got: %v", skillMarker, message.Data.Content)
-		}
+		ctx.AssertContains(t, message, skillMarker)
 
 		session.Destroy()
 	})
@@ -150,9 +148,7 @@ func TestSkills(t *testing.T) {
 			t.Fatalf("Failed to send message: %v", err)
 		}
 
-		if message2.Data.Content == nil || !strings.Contains(*message2.Data.Content, skillMarker) {
-			t.Errorf("Expected message to contain skill marker '%s' after resume, got: %v", skillMarker, message2.Data.Content)
-		}
+		ctx.AssertContains(t, message2, skillMarker)
 
 		session2.Destroy()
 	})
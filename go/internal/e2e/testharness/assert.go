@@ -0,0 +1,65 @@
+package testharness
+
+import (
+	"strings"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// AssertContains checks that an assistant message's content contains each
+// of want. In live mode, where the model words its answers freely, it only
+// checks that the message has content.
+func (c *TestContext) AssertContains(t *testing.T, message *copilot.SessionEvent, want ...string) {
+	t.Helper()
+	c.assertContent(t, message, want, strings.Contains)
+}
+
+// AssertContainsFold is like AssertContains, ignoring case.
+func (c *TestContext) AssertContainsFold(t *testing.T, message *copilot.SessionEvent, want ...string) {
+	t.Helper()
+	c.assertContent(t, message, want, func(content, substr string) bool {
+		return strings.Contains(strings.ToLower(content), strings.ToLower(substr))
+	})
+}
+
+func (c *TestContext) assertContent(t *testing.T, message *copilot.SessionEvent, want []string, contains func(content, substr string) bool) {
+	t.Helper()
+	if message == nil || message.Data.Content == nil || strings.TrimSpace(*message.Data.Content) == "" {
+		t.Errorf("Expected an assistant message with content, got %+v", message)
+		return
+	}
+	if c.Live {
+		return
+	}
+	for _, substr := range want {
+		if !contains(*message.Data.Content, substr) {
+			t.Errorf("Expected answer to contain %q, got %q", substr, *message.Data.Content)
+		}
+	}
+}
+
+// AssertToolCalled checks that the session's history has a call of the named
+// tool, or of any tool if name is empty. It checks the same in both modes.
+func (c *TestContext) AssertToolCalled(t *testing.T, session *copilot.Session, name string) {
+	t.Helper()
+	events, err := session.GetMessages(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to get messages: %v", err)
+	}
+	var called []string
+	for _, event := range events {
+		if event.Type != copilot.ToolExecutionStart || event.Data.ToolName == nil {
+			continue
+		}
+		if name == "" || *event.Data.ToolName == name {
+			return
+		}
+		called = append(called, *event.Data.ToolName)
+	}
+	if name == "" {
+		t.Errorf("Expected a tool call, got none")
+		return
+	}
+	t.Errorf("Expected a call of tool %q, got calls of %v", name, called)
+}
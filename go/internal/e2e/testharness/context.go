@@ -1,6 +1,7 @@
 package testharness
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -36,18 +37,62 @@ func CLIPath() string {
 	return cliPath
 }
 
+// LiveEnvVar is the environment variable that runs every test context in
+// live mode when set to "1" or "true", as the nightly live job does.
+const LiveEnvVar = "COPILOT_E2E_LIVE"
+
+// liveTokenEnvVars are the environment variables a live run takes its GitHub
+// token from, in order of preference.
+var liveTokenEnvVars = []string{"COPILOT_E2E_GITHUB_TOKEN", "GH_TOKEN", "GITHUB_TOKEN"}
+
+// ErrNoExchanges is returned by GetExchanges in live mode, where no proxy
+// captures traffic. Tests that inspect traffic are ReplayOnly.
+var ErrNoExchanges = errors.New("no exchanges are captured in live mode")
+
 // TestContext holds shared resources for E2E tests.
 type TestContext struct {
 	CLIPath  string
 	HomeDir  string
 	WorkDir  string
 	ProxyURL string
+	// Live reports whether the tests run against the live API rather than
+	// replaying snapshots. Content assertions are then structural only.
+	Live bool
 
 	proxy *CapiProxy
+	token string // GitHub token passed to clients in live mode
+}
+
+// Option configures a TestContext.
+type Option func(*TestContext)
+
+// Live runs the context's tests against the live API: no proxy is started,
+// clients authenticate with a real GitHub token from the environment, and
+// the Assert helpers check the shape of answers instead of their wording.
+// Setting LiveEnvVar has the same effect for every context.
+func Live() Option {
+	return func(c *TestContext) { c.Live = true }
+}
+
+// liveFromEnv reports whether LiveEnvVar selects live mode.
+func liveFromEnv() bool {
+	value := strings.ToLower(os.Getenv(LiveEnvVar))
+	return value == "1" || value == "true"
 }
 
-// NewTestContext creates a new test context with isolated directories and a replaying proxy.
-func NewTestContext(t *testing.T) *TestContext {
+// liveToken returns the first GitHub token set in liveTokenEnvVars.
+func liveToken() string {
+	for _, name := range liveTokenEnvVars {
+		if token := os.Getenv(name); token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// NewTestContext creates a new test context with isolated directories and,
+// unless it runs live, a replaying proxy.
+func NewTestContext(t *testing.T, options ...Option) *TestContext {
 	t.Helper()
 
 	cliPath := CLIPath()
@@ -55,6 +100,17 @@ func NewTestContext(t *testing.T) *TestContext {
 		t.Fatalf("CLI not found at %s. Run 'npm install' in the nodejs directory first.", cliPath)
 	}
 
+	ctx := &TestContext{CLIPath: cliPath, Live: liveFromEnv()}
+	for _, option := range options {
+		option(ctx)
+	}
+	if ctx.Live {
+		ctx.token = liveToken()
+		if ctx.token == "" {
+			t.Fatalf("Live mode needs a GitHub token in one of %s", strings.Join(liveTokenEnvVars, ", "))
+		}
+	}
+
 	homeDir, err := os.MkdirTemp("", "copilot-test-config-")
 	if err != nil {
 		t.Fatalf("Failed to create temp home dir: %v", err)
@@ -66,20 +122,19 @@ func NewTestContext(t *testing.T) *TestContext {
 		t.Fatalf("Failed to create temp work dir: %v", err)
 	}
 
-	proxy := NewCapiProxy()
-	proxyURL, err := proxy.Start()
-	if err != nil {
-		os.RemoveAll(homeDir)
-		os.RemoveAll(workDir)
-		t.Fatalf("Failed to start proxy: %v", err)
-	}
+	ctx.HomeDir = homeDir
+	ctx.WorkDir = workDir
 
-	ctx := &TestContext{
-		CLIPath:  cliPath,
-		HomeDir:  homeDir,
-		WorkDir:  workDir,
-		ProxyURL: proxyURL,
-		proxy:    proxy,
+	if !ctx.Live {
+		proxy := NewCapiProxy()
+		proxyURL, err := proxy.Start()
+		if err != nil {
+			os.RemoveAll(homeDir)
+			os.RemoveAll(workDir)
+			t.Fatalf("Failed to start proxy: %v", err)
+		}
+		ctx.ProxyURL = proxyURL
+		ctx.proxy = proxy
 	}
 
 	t.Cleanup(func() {
@@ -90,7 +145,8 @@ func NewTestContext(t *testing.T) *TestContext {
 }
 
 // ConfigureForTest configures the proxy for a specific subtest.
-// Call this at the start of each t.Run subtest.
+// Call this at the start of each t.Run subtest. In live mode there is no
+// proxy, and only the subtest name is checked.
 func (c *TestContext) ConfigureForTest(t *testing.T) {
 	t.Helper()
 
@@ -116,6 +172,10 @@ func (c *TestContext) ConfigureForTest(t *testing.T) {
 	sanitizedName := strings.ToLower(regexp.MustCompile(`[^a-zA-Z0-9]`).ReplaceAllString(parts[1], "_"))
 	snapshotPath := filepath.Join("..", "..", "..", "test", "snapshots", testFile, sanitizedName+".yaml")
 
+	if c.Live {
+		return
+	}
+
 	absSnapshotPath, err := filepath.Abs(snapshotPath)
 	if err != nil {
 		t.Fatalf("Failed to get absolute path: %v", err)
@@ -139,8 +199,21 @@ func (c *TestContext) Close(testFailed bool) {
 	}
 }
 
-// GetExchanges retrieves the captured HTTP exchanges from the proxy.
+// ReplayOnly skips the test in live mode. Call it first in tests that
+// inspect captured traffic or depend on the exact snapshot responses.
+func (c *TestContext) ReplayOnly(t *testing.T, reason string) {
+	t.Helper()
+	if c.Live {
+		t.Skipf("Replay only: %s", reason)
+	}
+}
+
+// GetExchanges retrieves the captured HTTP exchanges from the proxy. In live
+// mode it returns ErrNoExchanges.
 func (c *TestContext) GetExchanges() ([]ParsedHttpExchange, error) {
+	if c.proxy == nil {
+		return nil, ErrNoExchanges
+	}
 	return c.proxy.GetExchanges()
 }
 
@@ -150,26 +223,39 @@ func (c *TestContext) Env() []string {
 
 	// Add overrides (later values take precedence in most systems)
 	env = append(env,
-		"COPILOT_API_URL="+c.ProxyURL,
 		"XDG_CONFIG_HOME="+c.HomeDir,
 		"XDG_STATE_HOME="+c.HomeDir,
 	)
+	if !c.Live {
+		env = append(env, "COPILOT_API_URL="+c.ProxyURL)
+	}
 	return env
 }
 
 // NewClient creates a CopilotClient configured for this test context.
 func (c *TestContext) NewClient() *copilot.Client {
+	return c.NewClientWithOptions(nil)
+}
+
+// NewClientWithOptions creates a CopilotClient configured for this test context,
+// letting configure adjust the options before the client is created.
+func (c *TestContext) NewClientWithOptions(configure func(options *copilot.ClientOptions)) *copilot.Client {
 	options := &copilot.ClientOptions{
 		CLIPath: c.CLIPath,
 		Cwd:     c.WorkDir,
 		Env:     c.Env(),
 	}
 
-	// Use fake token in CI to allow cached responses without real auth
-	if os.Getenv("CI") == "true" {
+	if c.Live {
+		options.GitHubToken = c.token
+	} else if os.Getenv("CI") == "true" {
+		// Use fake token in CI to allow cached responses without real auth
 		options.GitHubToken = "fake-token-for-e2e-tests"
 	}
 
+	if configure != nil {
+		configure(options)
+	}
 	return copilot.NewClient(options)
 }
 
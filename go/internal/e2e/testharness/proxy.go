@@ -161,6 +161,32 @@ func (p *CapiProxy) GetExchanges() ([]ParsedHttpExchange, error) {
 type ParsedHttpExchange struct {
 	Request  ChatCompletionRequest   `json:"request"`
 	Response *ChatCompletionResponse `json:"response,omitempty"`
+	// RequestHeaders are the HTTP headers the CLI sent with the request.
+	// Values are a string or, for repeated headers, a list of strings.
+	RequestHeaders map[string]any `json:"requestHeaders,omitempty"`
+}
+
+// Header returns the value of a request header, matched case-insensitively.
+// Repeated headers are joined with ", ".
+func (e ParsedHttpExchange) Header(name string) string {
+	for key, value := range e.RequestHeaders {
+		if !strings.EqualFold(key, name) {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			return v
+		case []any:
+			parts := make([]string, 0, len(v))
+			for _, part := range v {
+				if s, ok := part.(string); ok {
+					parts = append(parts, s)
+				}
+			}
+			return strings.Join(parts, ", ")
+		}
+	}
+	return ""
 }
 
 // ChatCompletionRequest represents an OpenAI chat completion request.
@@ -168,6 +194,16 @@ type ChatCompletionRequest struct {
 	Model    string                  `json:"model"`
 	Messages []ChatCompletionMessage `json:"messages"`
 	Tools    []ChatCompletionTool    `json:"tools,omitempty"`
+	// ReasoningEffort is the reasoning effort sent to the model, if any
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+	// Thinking is the thinking configuration sent to the model, if any
+	Thinking *ChatCompletionThinking `json:"thinking,omitempty"`
+}
+
+// ChatCompletionThinking represents the thinking configuration of a request.
+type ChatCompletionThinking struct {
+	Type         string `json:"type,omitempty"`
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
 }
 
 // ChatCompletionMessage represents a message in the chat completion request.
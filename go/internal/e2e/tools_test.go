@@ -1,12 +1,14 @@
 package e2e
 
 import (
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	copilot "github.com/github/copilot-sdk/go"
 	"github.com/github/copilot-sdk/go/internal/e2e/testharness"
@@ -43,9 +45,8 @@ func TestTools(t *testing.T) {
 			t.Fatalf("Failed to get assistant message: %v", err)
 		}
 
-		if answer.Data.Content == nil || !strings.Contains(*answer.Data.Content, "ELIZA") {
-			t.Errorf("Expected answer to contain 'ELIZA', got %v", answer.Data.Content)
-		}
+		ctx.AssertContains(t, answer, "ELIZA")
+		ctx.AssertToolCalled(t, session, "")
 	})
 
 	t.Run("invokes custom tool", func(t *testing.T) {
@@ -78,8 +79,43 @@ func TestTools(t *testing.T) {
 			t.Fatalf("Failed to get assistant message: %v", err)
 		}
 
-		if answer.Data.Content == nil || !strings.Contains(*answer.Data.Content, "HELLO") {
-			t.Errorf("Expected answer to contain 'HELLO', got %v", answer.Data.Content)
+		ctx.AssertContains(t, answer, "HELLO")
+		ctx.AssertToolCalled(t, session, "encrypt_string")
+	})
+
+	t.Run("reports custom tools in the effective tool list", func(t *testing.T) {
+		type EncryptParams struct {
+			Input string `json:"input" jsonschema:"String to encrypt"`
+		}
+
+		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{
+			OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+			Tools: []copilot.Tool{
+				copilot.DefineTool("encrypt_string", "Encrypts a string",
+					func(params EncryptParams, inv copilot.ToolInvocation) (string, error) {
+						return strings.ToUpper(params.Input), nil
+					}),
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		tools, err := session.EffectiveTools(t.Context())
+		if err != nil {
+			t.Fatalf("Failed to get effective tools: %v", err)
+		}
+		var found *copilot.EffectiveTool
+		for i := range tools {
+			if tools[i].Name == "encrypt_string" {
+				found = &tools[i]
+			}
+		}
+		if found == nil {
+			t.Fatalf("Expected encrypt_string in the effective tools, got %+v", tools)
+		}
+		if found.Source != copilot.ToolSourceCustom {
+			t.Errorf("Expected encrypt_string to be sourced as %q, got %q", copilot.ToolSourceCustom, found.Source)
 		}
 	})
 
@@ -113,61 +149,16 @@ func TestTools(t *testing.T) {
 			t.Fatalf("Failed to get assistant message: %v", err)
 		}
 
-		// Check the underlying traffic
-		traffic, err := ctx.GetExchanges()
-		if err != nil {
-			t.Fatalf("Failed to get exchanges: %v", err)
-		}
-
-		lastConversation := traffic[len(traffic)-1]
-
-		// Find tool calls
-		var toolCalls []testharness.ToolCall
-		for _, msg := range lastConversation.Request.Messages {
-			if msg.Role == "assistant" && msg.ToolCalls != nil {
-				toolCalls = append(toolCalls, msg.ToolCalls...)
-			}
-		}
-
-		if len(toolCalls) != 1 {
-			t.Fatalf("Expected 1 tool call, got %d", len(toolCalls))
-		}
-		toolCall := toolCalls[0]
-		if toolCall.Type != "function" {
-			t.Errorf("Expected tool call type 'function', got '%s'", toolCall.Type)
-		}
-		if toolCall.Function.Name != "get_user_location" {
-			t.Errorf("Expected tool call name 'get_user_location', got '%s'", toolCall.Function.Name)
-		}
-
-		// Find tool results
-		var toolResults []testharness.Message
-		for _, msg := range lastConversation.Request.Messages {
-			if msg.Role == "tool" {
-				toolResults = append(toolResults, msg)
-			}
-		}
-
-		if len(toolResults) != 1 {
-			t.Fatalf("Expected 1 tool result, got %d", len(toolResults))
-		}
-		toolResult := toolResults[0]
-		if toolResult.ToolCallID != toolCall.ID {
-			t.Errorf("Expected tool result ID '%s', got '%s'", toolCall.ID, toolResult.ToolCallID)
-		}
-
-		// The error message "Melbourne" should NOT be exposed to the LLM
-		if strings.Contains(toolResult.Content, "Melbourne") {
-			t.Errorf("Tool result should not contain error details 'Melbourne', got '%s'", toolResult.Content)
+		ctx.AssertToolCalled(t, session, "get_user_location")
+		if !ctx.Live {
+			checkToolErrorTraffic(t, ctx)
 		}
 
 		// The assistant should not see the exception information
 		if answer.Data.Content != nil && strings.Contains(*answer.Data.Content, "Melbourne") {
 			t.Errorf("Assistant should not see error details 'Melbourne', got '%s'", *answer.Data.Content)
 		}
-		if answer.Data.Content == nil || !strings.Contains(strings.ToLower(*answer.Data.Content), "unknown") {
-			t.Errorf("Expected answer to contain 'unknown', got %v", answer.Data.Content)
-		}
+		ctx.AssertContainsFold(t, answer, "unknown")
 	})
 
 	t.Run("can receive and return complex types", func(t *testing.T) {
@@ -232,27 +223,17 @@ func TestTools(t *testing.T) {
 			t.Fatalf("Failed to get assistant message: %v", err)
 		}
 
-		if answer == nil || answer.Data.Content == nil {
-			t.Fatalf("Expected assistant message with content")
-		}
-
-		responseContent := *answer.Data.Content
-		if responseContent == "" {
-			t.Errorf("Expected non-empty response")
-		}
-		if !strings.Contains(responseContent, "Passos") {
-			t.Errorf("Expected response to contain 'Passos', got '%s'", responseContent)
-		}
-		if !strings.Contains(responseContent, "San Lorenzo") {
-			t.Errorf("Expected response to contain 'San Lorenzo', got '%s'", responseContent)
-		}
-		// Remove commas for number checking (e.g., "135,460" -> "135460")
-		responseWithoutCommas := strings.ReplaceAll(responseContent, ",", "")
-		if !strings.Contains(responseWithoutCommas, "135460") {
-			t.Errorf("Expected response to contain '135460', got '%s'", responseContent)
-		}
-		if !strings.Contains(responseWithoutCommas, "204356") {
-			t.Errorf("Expected response to contain '204356', got '%s'", responseContent)
+		ctx.AssertContains(t, answer, "Passos", "San Lorenzo")
+		if !ctx.Live && answer.Data.Content != nil {
+			// Remove commas for number checking (e.g., "135,460" -> "135460")
+			responseContent := *answer.Data.Content
+			responseWithoutCommas := strings.ReplaceAll(responseContent, ",", "")
+			if !strings.Contains(responseWithoutCommas, "135460") {
+				t.Errorf("Expected response to contain '135460', got '%s'", responseContent)
+			}
+			if !strings.Contains(responseWithoutCommas, "204356") {
+				t.Errorf("Expected response to contain '204356', got '%s'", responseContent)
+			}
 		}
 
 		// We can access the raw invocation if needed
@@ -285,7 +266,7 @@ func TestTools(t *testing.T) {
 				mu.Lock()
 				permissionRequests = append(permissionRequests, request)
 				mu.Unlock()
-				return copilot.PermissionRequestResult{Kind: "approved"}, nil
+				return copilot.Approved(), nil
 			},
 		})
 		if err != nil {
@@ -302,9 +283,7 @@ func TestTools(t *testing.T) {
 			t.Fatalf("Failed to get assistant message: %v", err)
 		}
 
-		if answer.Data.Content == nil || !strings.Contains(*answer.Data.Content, "HELLO") {
-			t.Errorf("Expected answer to contain 'HELLO', got %v", answer.Data.Content)
-		}
+		ctx.AssertContains(t, answer, "HELLO")
 
 		// Should have received a custom-tool permission request
 		mu.Lock()
@@ -341,7 +320,7 @@ func TestTools(t *testing.T) {
 					}),
 			},
 			OnPermissionRequest: func(request copilot.PermissionRequest, invocation copilot.PermissionInvocation) (copilot.PermissionRequestResult, error) {
-				return copilot.PermissionRequestResult{Kind: "denied-interactively-by-user"}, nil
+				return copilot.DeniedByUser(""), nil
 			},
 		})
 		if err != nil {
@@ -362,4 +341,111 @@ func TestTools(t *testing.T) {
 			t.Errorf("Tool handler should NOT have been called since permission was denied")
 		}
 	})
+
+	t.Run("cancels custom tool context on abort", func(t *testing.T) {
+		ctx.ConfigureForTest(t)
+
+		type QueryParams struct {
+			Query string `json:"query" jsonschema:"Query to run"`
+		}
+
+		started := make(chan struct{}, 1)
+		cancelled := make(chan error, 1)
+		session, err := client.CreateSession(t.Context(), &copilot.SessionConfig{
+			OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+			Tools: []copilot.Tool{
+				copilot.DefineToolWithContext("slow_query", "Runs a slow database query",
+					func(ctx context.Context, params QueryParams, inv copilot.ToolInvocation) (string, error) {
+						started <- struct{}{}
+						select {
+						case <-ctx.Done():
+							cancelled <- ctx.Err()
+							return "", ctx.Err()
+						case <-time.After(60 * time.Second):
+							cancelled <- nil
+							return "no rows", nil
+						}
+					}),
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		_, err = session.Send(t.Context(), copilot.MessageOptions{Prompt: "Use slow_query to run the query: SELECT * FROM orders"})
+		if err != nil {
+			t.Fatalf("Failed to send message: %v", err)
+		}
+
+		select {
+		case <-started:
+		case <-time.After(60 * time.Second):
+			t.Fatal("Timed out waiting for slow_query to start")
+		}
+
+		if err := session.Abort(t.Context()); err != nil {
+			t.Fatalf("Failed to abort session: %v", err)
+		}
+
+		select {
+		case err := <-cancelled:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected the handler's context to be canceled, got %v", err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("Timed out waiting for the handler's context to be canceled")
+		}
+	})
+}
+
+// checkToolErrorTraffic checks that the tool error reached the model as a
+// failed tool result without its details.
+func checkToolErrorTraffic(t *testing.T, ctx *testharness.TestContext) {
+	t.Helper()
+	traffic, err := ctx.GetExchanges()
+	if err != nil {
+		t.Fatalf("Failed to get exchanges: %v", err)
+	}
+
+	lastConversation := traffic[len(traffic)-1]
+
+	// Find tool calls
+	var toolCalls []testharness.ToolCall
+	for _, msg := range lastConversation.Request.Messages {
+		if msg.Role == "assistant" && msg.ToolCalls != nil {
+			toolCalls = append(toolCalls, msg.ToolCalls...)
+		}
+	}
+
+	if len(toolCalls) != 1 {
+		t.Fatalf("Expected 1 tool call, got %d", len(toolCalls))
+	}
+	toolCall := toolCalls[0]
+	if toolCall.Type != "function" {
+		t.Errorf("Expected tool call type 'function', got '%s'", toolCall.Type)
+	}
+	if toolCall.Function.Name != "get_user_location" {
+		t.Errorf("Expected tool call name 'get_user_location', got '%s'", toolCall.Function.Name)
+	}
+
+	// Find tool results
+	var toolResults []testharness.Message
+	for _, msg := range lastConversation.Request.Messages {
+		if msg.Role == "tool" {
+			toolResults = append(toolResults, msg)
+		}
+	}
+
+	if len(toolResults) != 1 {
+		t.Fatalf("Expected 1 tool result, got %d", len(toolResults))
+	}
+	toolResult := toolResults[0]
+	if toolResult.ToolCallID != toolCall.ID {
+		t.Errorf("Expected tool result ID '%s', got '%s'", toolCall.ID, toolResult.ToolCallID)
+	}
+
+	// The error message "Melbourne" should NOT be exposed to the LLM
+	if strings.Contains(toolResult.Content, "Melbourne") {
+		t.Errorf("Tool result should not contain error details 'Melbourne', got '%s'", toolResult.Content)
+	}
 }
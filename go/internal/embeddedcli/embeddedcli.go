@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/github/copilot-sdk/go/internal/flock"
+	"github.com/github/copilot-sdk/go/internal/sdklog"
 )
 
 // Config defines the inputs used to install and locate the embedded Copilot CLI.
@@ -66,20 +67,24 @@ var (
 	setupMu         sync.Mutex
 	setupDone       bool
 	pathInitialized bool
+
+	// logger reports the progress of verbose installs. Installation is
+	// shared by all clients of the process, so it always writes to stderr.
+	logger *sdklog.Logger
 )
 
 func install() (path string) {
 	verbose := os.Getenv("COPILOT_CLI_INSTALL_VERBOSE") == "1"
 	logError := func(msg string, err error) {
 		if verbose {
-			fmt.Printf("embedded CLI installation error: %s: %v\n", msg, err)
+			logger.Error("embedded CLI installation error: "+msg, "error", err)
 		}
 	}
 	if verbose {
 		start := time.Now()
 		defer func() {
 			duration := time.Since(start)
-			fmt.Printf("installing embedded CLI at %s installation took %s\n", path, duration)
+			logger.Info("installed embedded CLI", "path", path, "duration", duration)
 		}()
 	}
 	installDir := config.Dir
@@ -0,0 +1,91 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Encodings lists the Content-Encoding values the client decodes, in order
+// of preference. "deflate" is the zlib format, as in HTTP.
+var Encodings = []string{"gzip", "deflate"}
+
+// compression holds the settings for compressing outgoing messages.
+type compression struct {
+	encoding  string
+	threshold int
+}
+
+// SetCompression compresses outgoing message bodies of at least threshold
+// bytes with encoding, which must be one of Encodings and decoded by the
+// peer. The frame then carries a Content-Encoding header. Messages that do
+// not get smaller are sent as they are. An empty encoding turns compression
+// off. It may be called while the client is running, for example once the
+// peer has said which encodings it accepts.
+func (c *Client) SetCompression(encoding string, threshold int) error {
+	if encoding == "" {
+		c.compression.Store(nil)
+		return nil
+	}
+	if !slices.Contains(Encodings, encoding) {
+		return fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	c.compression.Store(&compression{encoding: encoding, threshold: threshold})
+	return nil
+}
+
+// compress returns data encoded for the wire and its encoding, or data
+// itself and an empty encoding if compression is off, the message is under
+// the threshold or compressing does not make it smaller.
+func (c *Client) compress(data []byte) ([]byte, string) {
+	settings := c.compression.Load()
+	if settings == nil || len(data) < settings.threshold {
+		return data, ""
+	}
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch settings.encoding {
+	case "gzip":
+		w, _ = gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+	case "deflate":
+		w, _ = zlib.NewWriterLevel(&buf, flate.BestSpeed)
+	}
+	if _, err := w.Write(data); err != nil {
+		return data, ""
+	}
+	if err := w.Close(); err != nil || buf.Len() >= len(data) {
+		return data, ""
+	}
+	return buf.Bytes(), settings.encoding
+}
+
+// decompress decodes a message body sent with encoding, failing with
+// ErrMessageTooLarge if it decodes to more than limit bytes.
+func decompress(encoding string, body []byte, limit int) ([]byte, error) {
+	var r io.ReadCloser
+	var err error
+	switch encoding {
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		r, err = zlib.NewReader(bytes.NewReader(body))
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		return nil, fmt.Errorf("%w: decoded message exceeds the limit of %d bytes", ErrMessageTooLarge, limit)
+	}
+	return data, nil
+}
@@ -0,0 +1,184 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingConn counts the bytes written to a connection.
+type countingConn struct {
+	net.Conn
+	written atomic.Int64
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(int64(n))
+	return n, err
+}
+
+// newPeers returns two started clients connected to each other, as the SDK
+// and the CLI would be, and their connections.
+func newPeers(t testing.TB) (*Client, *Client, *countingConn, *countingConn) {
+	t.Helper()
+	a, b := net.Pipe()
+	connA, connB := &countingConn{Conn: a}, &countingConn{Conn: b}
+	clientA, clientB := NewClient(connA, connA), NewClient(connB, connB)
+	clientA.Start()
+	clientB.Start()
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+		clientA.Stop()
+		clientB.Stop()
+	})
+	return clientA, clientB, connA, connB
+}
+
+// historyPayload returns a JSON-friendly payload of about size bytes that
+// resembles a conversation history.
+func historyPayload(size int) string {
+	var b strings.Builder
+	for i := 0; b.Len() < size; i++ {
+		fmt.Fprintf(&b, "turn %d: the assistant read src/module_%d.go and proposed a change to the handler. ", i, i%50)
+	}
+	return b.String()
+}
+
+func TestClient_Compression(t *testing.T) {
+	for _, encoding := range Encodings {
+		t.Run("round-trips large messages with "+encoding, func(t *testing.T) {
+			sdk, cli, sdkConn, cliConn := newPeers(t)
+			sdk.SetCompression(encoding, 1024)
+			cli.SetCompression(encoding, 1024)
+			cli.SetRequestHandler("echo", func(params json.RawMessage) (json.RawMessage, *Error) {
+				return params, nil
+			})
+
+			payload := historyPayload(2 << 20)
+			result, err := sdk.Request("echo", payload)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			var echoed string
+			if err := json.Unmarshal(result, &echoed); err != nil || echoed != payload {
+				t.Fatalf("Expected the payload back, got %d bytes, %v", len(echoed), err)
+			}
+			if sent, received := sdkConn.written.Load(), cliConn.written.Load(); sent > int64(len(payload)/4) || received > int64(len(payload)/4) {
+				t.Errorf("Expected compressed frames, wrote %d and %d bytes for a %d-byte payload", sent, received, len(payload))
+			}
+		})
+	}
+
+	t.Run("sends small messages uncompressed", func(t *testing.T) {
+		conn := newTestConn(t)
+		conn.client.SetCompression("gzip", 1024)
+		conn.client.Notify("small", "hello")
+		conn.client.Notify("large", historyPayload(4096))
+		if frame := <-conn.writer.frames; bytes.Contains(frame, []byte("Content-Encoding")) {
+			t.Errorf("Expected no encoding on a small frame, got %q", frame)
+		}
+		if frame := <-conn.writer.frames; !bytes.HasPrefix(frame, []byte("Content-Encoding: gzip\r\n")) {
+			t.Errorf("Expected a gzip frame, got %q", frame[:40])
+		}
+	})
+
+	t.Run("sends uncompressed when compression is off", func(t *testing.T) {
+		sdk, cli, sdkConn, _ := newPeers(t)
+		received := make(chan int, 1)
+		cli.SetRequestHandler("notify", func(params json.RawMessage) (json.RawMessage, *Error) {
+			received <- len(params)
+			return nil, nil
+		})
+		payload := historyPayload(1 << 20)
+		sdk.Notify("notify", payload)
+		select {
+		case n := <-received:
+			if n != len(payload)+2 || sdkConn.written.Load() < int64(len(payload)) {
+				t.Errorf("Expected an uncompressed frame, got %d bytes on the wire", sdkConn.written.Load())
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for the message")
+		}
+	})
+
+	t.Run("discards frames with unknown encodings and keeps reading", func(t *testing.T) {
+		conn := newTestConn(t)
+		received := make(chan string, 2)
+		conn.client.SetRequestHandler("notify", func(params json.RawMessage) (json.RawMessage, *Error) {
+			received <- string(params)
+			return nil, nil
+		})
+		fmt.Fprintf(conn.inbound, "Content-Encoding: br\r\nContent-Length: 4\r\n\r\nxxxx")
+		conn.deliver(t, map[string]any{"jsonrpc": "2.0", "method": "notify", "params": "after"})
+		select {
+		case params := <-received:
+			if params != `"after"` {
+				t.Errorf("Expected only the plain message, got %s", params)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the plain message")
+		}
+	})
+
+	t.Run("limits the decoded size", func(t *testing.T) {
+		sdk, cli, _, _ := newPeers(t)
+		cli.SetMaxMessageSize(1 << 20)
+		sdk.SetCompression("gzip", 0)
+		received := make(chan int, 2)
+		cli.SetRequestHandler("notify", func(params json.RawMessage) (json.RawMessage, *Error) {
+			received <- len(params)
+			return nil, nil
+		})
+		sdk.Notify("notify", strings.Repeat("a", 2<<20))
+		sdk.Notify("notify", "after")
+		select {
+		case n := <-received:
+			if n != len(`"after"`) {
+				t.Errorf("Expected the oversized message to be discarded, got %d bytes", n)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for the small message")
+		}
+	})
+
+	t.Run("rejects unknown encodings", func(t *testing.T) {
+		if err := NewClient(nil, nil).SetCompression("br", 0); err == nil {
+			t.Error("Expected an error for an unsupported encoding")
+		}
+	})
+}
+
+// BenchmarkCompression sends a 4 MiB history-like payload and reports the
+// bytes written to the wire for each encoding.
+func BenchmarkCompression(b *testing.B) {
+	payload := historyPayload(4 << 20)
+	for _, encoding := range append([]string{"none"}, Encodings...) {
+		b.Run(encoding, func(b *testing.B) {
+			sdk, cli, sdkConn, _ := newPeers(b)
+			if encoding != "none" {
+				sdk.SetCompression(encoding, 16<<10)
+			}
+			done := make(chan struct{}, 1)
+			cli.SetRequestHandler("notify", func(json.RawMessage) (json.RawMessage, *Error) {
+				done <- struct{}{}
+				return nil, nil
+			})
+			b.SetBytes(int64(len(payload)))
+			b.ResetTimer()
+			for range b.N {
+				if err := sdk.Notify("notify", payload); err != nil {
+					b.Fatal(err)
+				}
+				<-done
+			}
+			b.ReportMetric(float64(sdkConn.written.Load())/float64(b.N), "wire-bytes/op")
+		})
+	}
+}
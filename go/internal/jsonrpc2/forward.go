@@ -0,0 +1,19 @@
+package jsonrpc2
+
+// NewForwarder returns a client with no connection of its own that sends
+// requests and notifications through the client target returns at the time
+// of each call, or fails them with ErrConnectionClosed if it returns nil.
+// Holders of the forwarder, such as typed RPC wrappers, thereby follow a
+// connection that is replaced. A forwarder must not be started.
+func NewForwarder(target func() *Client) *Client {
+	return &Client{forward: target}
+}
+
+// forwardTarget returns the client a forwarder sends through.
+func (c *Client) forwardTarget() (*Client, error) {
+	target := c.forward()
+	if target == nil {
+		return nil, ErrConnectionClosed
+	}
+	return target, nil
+}
@@ -0,0 +1,139 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxHeaderLines bounds the header lines of a frame. Header lines are
+// bounded by the size of the reader's buffer.
+const maxHeaderLines = 32
+
+// maxConsecutiveFrameErrors is how many malformed frames in a row the client
+// skips before it gives up on the connection.
+const maxConsecutiveFrameErrors = 16
+
+// frame is a message read from the connection, as framed by its headers.
+type frame struct {
+	body     []byte
+	encoding string // Content-Encoding, if any
+}
+
+// FrameError reports input that is not a valid frame. The reader is left at
+// the start of the next line, so reading can resume there.
+type FrameError struct {
+	Reason string
+}
+
+func (e *FrameError) Error() string {
+	return "malformed frame: " + e.Reason
+}
+
+// FrameTooLargeError reports a frame whose body is larger than the limit.
+// The body has been skipped. It matches [ErrMessageTooLarge].
+type FrameTooLargeError struct {
+	Size  int
+	Limit int
+}
+
+func (e *FrameTooLargeError) Error() string {
+	return fmt.Sprintf("message of %d bytes, over the limit of %d bytes", e.Size, e.Limit)
+}
+
+func (e *FrameTooLargeError) Is(target error) bool { return target == ErrMessageTooLarge }
+
+// readFrame reads a frame with a Content-Length header from r. Bodies larger
+// than maxSize are skipped and reported with a *[FrameTooLargeError], and
+// malformed headers with a *[FrameError]; after either, the next call reads
+// the next frame. Any other error, such as [io.EOF], means the connection
+// cannot be read further.
+//
+// Memory use is bounded by maxSize and the size of r's buffer, whatever the
+// input.
+func readFrame(r *bufio.Reader, maxSize int) (frame, error) {
+	var f frame
+	length := -1
+	headers := 0
+	for {
+		line, err := readHeaderLine(r)
+		if err != nil {
+			return frame{}, err
+		}
+		if line == "" {
+			if headers == 0 {
+				// Blank lines between frames are harmless
+				continue
+			}
+			break
+		}
+		headers++
+		if headers > maxHeaderLines {
+			return frame{}, &FrameError{Reason: fmt.Sprintf("more than %d header lines", maxHeaderLines)}
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return frame{}, &FrameError{Reason: fmt.Sprintf("invalid header line %.40q", line)}
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "content-length":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return frame{}, &FrameError{Reason: fmt.Sprintf("invalid Content-Length %.40q", value)}
+			}
+			length = n
+		case "content-encoding":
+			f.encoding = value
+		}
+	}
+	if length < 0 {
+		return frame{}, &FrameError{Reason: "missing Content-Length header"}
+	}
+
+	if length > maxSize {
+		if _, err := io.CopyN(io.Discard, r, int64(length)); err != nil {
+			return frame{}, unexpectedEOF(err)
+		}
+		return frame{}, &FrameTooLargeError{Size: length, Limit: maxSize}
+	}
+	f.body = make([]byte, length)
+	if _, err := io.ReadFull(r, f.body); err != nil {
+		return frame{}, unexpectedEOF(err)
+	}
+	return f, nil
+}
+
+// readHeaderLine reads a line without its line ending. A line that does not
+// fit in r's buffer is skipped and reported with a *[FrameError].
+func readHeaderLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		for errors.Is(err, bufio.ErrBufferFull) {
+			_, err = r.ReadSlice('\n')
+		}
+		if err != nil {
+			return "", unexpectedEOF(err)
+		}
+		return "", &FrameError{Reason: fmt.Sprintf("header line longer than %d bytes", r.Size())}
+	}
+	if err != nil {
+		if len(line) > 0 {
+			return "", unexpectedEOF(err)
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// unexpectedEOF reports the end of input in the middle of a frame as
+// [io.ErrUnexpectedEOF].
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,162 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/sdklog"
+)
+
+func TestReadFrame(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// want lists the bodies read, or "<malformed>", "<too large>" and
+		// "<unexpected EOF>" for errors, until io.EOF
+		want []string
+	}{
+		{"valid frames", "Content-Length: 2\r\n\r\n{}Content-Length: 4\r\n\r\nnull", []string{"{}", "null"}},
+		{"bare line feeds and other headers", "Content-Type: application/json\ncontent-length:2\n\n{}", []string{"{}"}},
+		{"blank lines between frames", "\r\n\r\nContent-Length: 2\r\n\r\n{}", []string{"{}"}},
+		{"negative length", "Content-Length: -5\r\n\r\nContent-Length: 2\r\n\r\n{}", []string{"<malformed>", "{}"}},
+		{"length that is not a number", "Content-Length: 12abc\r\n\r\n", []string{"<malformed>"}},
+		{"missing length", "Content-Type: text/plain\r\n\r\nContent-Length: 2\r\n\r\n{}", []string{"<malformed>", "{}"}},
+		{"garbage after a partial header", "Content-Length: 2\r\n\x00\xff\xfe garbage\nContent-Length: 2\r\n\r\n{}", []string{"<malformed>", "{}"}},
+		{"header line too long", "X: " + strings.Repeat("a", 10000) + "\nContent-Length: 2\r\n\r\n{}", []string{"<malformed>", "{}"}},
+		{"too many headers", strings.Repeat("X: y\r\n", maxHeaderLines+1) + "\r\nContent-Length: 2\r\n\r\n{}", []string{"<malformed>", "{}"}},
+		{"body over the limit", "Content-Length: 2000\r\n\r\n" + strings.Repeat("x", 2000) + "Content-Length: 2\r\n\r\n{}", []string{"<too large>", "{}"}},
+		{"truncated body", "Content-Length: 10\r\n\r\n{}", []string{"<unexpected EOF>"}},
+		{"truncated header", "Content-Len", []string{"<unexpected EOF>"}},
+		{"huge length", "Content-Length: 99999999999999\r\n\r\n", []string{"<unexpected EOF>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.input))
+			var got []string
+			for range 10 {
+				f, err := readFrame(r, 1024)
+				var frameErr *FrameError
+				var tooLarge *FrameTooLargeError
+				switch {
+				case err == io.EOF:
+				case errors.As(err, &frameErr):
+					got = append(got, "<malformed>")
+					continue
+				case errors.As(err, &tooLarge):
+					if !errors.Is(err, ErrMessageTooLarge) || tooLarge.Size != 2000 {
+						t.Errorf("Unexpected error %v", err)
+					}
+					got = append(got, "<too large>")
+					continue
+				case errors.Is(err, io.ErrUnexpectedEOF):
+					got = append(got, "<unexpected EOF>")
+				case err != nil:
+					t.Fatalf("Unexpected error %v", err)
+				default:
+					got = append(got, string(f.body))
+					continue
+				}
+				break
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func FuzzReadFrame(f *testing.F) {
+	f.Add([]byte("Content-Length: 2\r\n\r\n{}"))
+	f.Add([]byte("Content-Length: 13\r\nContent-Encoding: gzip\r\n\r\n{\"id\":\"1\"}"))
+	f.Add([]byte("Content-Length: -1\r\n\r\n"))
+	f.Add([]byte("Content-Length: 5\r\n\x00\x01\x02\n\r\n"))
+	f.Add([]byte("\r\n\r\nX\r\n"))
+	const maxSize = 1 << 10
+	f.Fuzz(func(t *testing.T, input []byte) {
+		r := bufio.NewReaderSize(bytes.NewReader(input), 64)
+		// Each call consumes input or ends it, so this terminates
+		for range len(input) + 1 {
+			frame, err := readFrame(r, maxSize)
+			var frameErr *FrameError
+			var tooLarge *FrameTooLargeError
+			switch {
+			case err == nil:
+				if len(frame.body) > maxSize {
+					t.Fatalf("Read a body of %d bytes, over the limit", len(frame.body))
+				}
+				continue
+			case errors.As(err, &frameErr), errors.As(err, &tooLarge):
+				continue
+			case err == io.EOF, errors.Is(err, io.ErrUnexpectedEOF):
+				return
+			default:
+				t.Fatalf("Unexpected error %v", err)
+			}
+		}
+		if _, err := r.Peek(1); err != io.EOF {
+			t.Fatalf("Expected the input to be consumed, got %v", err)
+		}
+	})
+}
+
+func TestClient_MalformedFrames(t *testing.T) {
+	quiet := func(c *Client) { c.SetLogger(sdklog.New(io.Discard, false)) }
+
+	t.Run("resynchronizes after garbage", func(t *testing.T) {
+		conn := newTestConn(t, quiet)
+		go func() {
+			frame := <-conn.writer.frames
+			var req Request
+			json.Unmarshal(frame[bytes.Index(frame, []byte("{")):], &req)
+			io.WriteString(conn.inbound, "Content-Length: 40\r\n\x00\xde\xad\xbe\xef\n\r\n")
+			io.WriteString(conn.inbound, "Content-Type: text/plain\r\n\r\n")
+			io.WriteString(conn.inbound, "Content-Length: 8\r\n\r\nnot json")
+			conn.deliver(t, Response{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage(`"ok"`)})
+		}()
+		result, err := conn.client.Request("status.get", nil)
+		if err != nil || string(result) != `"ok"` {
+			t.Fatalf("Expected the response after the garbage, got %s %v", result, err)
+		}
+		if n := conn.client.ParseFailures(); n != 3 {
+			t.Errorf("Expected 3 parse failures, got %d", n)
+		}
+	})
+
+	t.Run("fails the connection on persistent garbage", func(t *testing.T) {
+		conn := newTestConn(t, quiet)
+		go io.WriteString(conn.inbound, strings.Repeat("\x00garbage\n", maxConsecutiveFrameErrors))
+		err := conn.waitLost(t)
+		var frameErr *FrameError
+		if !errors.As(err, &frameErr) {
+			t.Errorf("Expected the frame error to be reported, got %v", err)
+		}
+		if _, err := conn.client.RequestContext(t.Context(), "status.get", nil); !errors.Is(err, ErrConnectionClosed) {
+			t.Errorf("Expected requests to fail, got %v", err)
+		}
+	})
+
+	t.Run("never blocks on a line without an end", func(t *testing.T) {
+		conn := newTestConn(t, quiet)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			chunk := bytes.Repeat([]byte{0xff}, 1<<16)
+			for range 64 {
+				if _, err := conn.inbound.Write(chunk); err != nil {
+					return
+				}
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out writing garbage")
+		}
+	})
+}
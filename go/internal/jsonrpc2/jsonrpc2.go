@@ -2,15 +2,47 @@ package jsonrpc2
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/sdklog"
 )
 
+// ErrConnectionClosed is returned when writing to a connection that has been
+// lost, for example because the server process exited.
+var ErrConnectionClosed = errors.New("connection closed")
+
+// ErrMessageTooLarge is returned when sending a message whose encoding is
+// larger than the client's message size limit.
+var ErrMessageTooLarge = errors.New("message too large")
+
+// ErrRequestTimeout is returned when no response to a request arrives within
+// the client's request timeout.
+var ErrRequestTimeout = errors.New("request timed out")
+
+// ErrTooManyPendingRequests is returned when sending a request while the
+// client's limit of requests awaiting a response is reached.
+var ErrTooManyPendingRequests = errors.New("too many pending requests")
+
+// ErrClientStopped is returned for requests that were waiting for a response
+// when the client was stopped.
+var ErrClientStopped = errors.New("client stopped")
+
+// DefaultMaxMessageSize is the default limit on the size of a message body,
+// in either direction.
+const DefaultMaxMessageSize = 64 << 20
+
 // Error represents a JSON-RPC error response
 type Error struct {
 	Code    int            `json:"code"`
@@ -42,6 +74,45 @@ type Response struct {
 	Error   *Error          `json:"error,omitempty"`
 }
 
+// Call describes a completed JSON-RPC call.
+type Call struct {
+	Method string
+	// ID is the request ID, or empty for notifications
+	ID string
+	// Incoming is true for requests and notifications sent by the server
+	Incoming bool
+	Duration time.Duration
+	Err      error
+	// OverPendingWarning is true for outgoing requests sent while at least
+	// the SetPendingWarning threshold of requests awaited a response
+	OverPendingWarning bool
+}
+
+// CallObserver is notified after each outgoing request completes and after
+// each incoming request or notification has been handled. It must not block.
+type CallObserver func(call Call)
+
+// CallError wraps the error of a failed outgoing request with the request's
+// method and ID, so failures can be matched against server logs. Its message
+// is that of the wrapped error. If the server answered with an error, Code,
+// Message and Data are copied from it.
+type CallError struct {
+	Method    string
+	RequestID string
+	Code      int
+	Message   string
+	Data      map[string]any
+	Err       error
+}
+
+func (e *CallError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
 // NotificationHandler handles incoming notifications
 type NotificationHandler func(method string, params json.RawMessage)
 
@@ -61,6 +132,28 @@ type Client struct {
 	processDone     chan struct{} // closed when the underlying process exits
 	processError    error         // set before processDone is closed
 	processErrorMu  sync.RWMutex  // protects processError
+	observer        CallObserver
+	tracer          Tracer
+	writeClosed     atomic.Bool // set once a write fails; later writes fail fast
+	lostOnce        sync.Once
+	lostChan        chan struct{} // closed when the connection is lost
+	onLost          func(err error)
+	generateID      func() string
+	maxMessageSize  int
+	parseFailures   atomic.Int64 // messages discarded because they could not be parsed
+	requestTimeout  time.Duration
+	retry           RetryPolicy
+	maxPending      int
+	pendingWarning  int
+	pendingWarnings atomic.Int64 // requests sent over the pendingWarning threshold
+	compression     atomic.Pointer[compression]
+	logger          *sdklog.Logger // nil writes to stderr
+	requestQueue    RequestQueueFunc
+	queueMu         sync.Mutex
+	queues          map[string][]func() // calls waiting per busy queue
+	writes          writeQueue
+	prioritySize    int            // frames up to this size jump the write queue; 0 writes in order
+	forward         func() *Client // set on forwarders; see NewForwarder
 }
 
 // NewClient creates a new JSON-RPC client
@@ -71,9 +164,107 @@ func NewClient(stdin io.WriteCloser, stdout io.ReadCloser) *Client {
 		pendingRequests: make(map[string]chan *Response),
 		requestHandlers: make(map[string]RequestHandler),
 		stopChan:        make(chan struct{}),
+		lostChan:        make(chan struct{}),
+		generateID:      generateUUID,
+		maxMessageSize:  DefaultMaxMessageSize,
+		prioritySize:    priorityFrameSize,
+	}
+}
+
+// SetMaxMessageSize sets the largest message body, in bytes, the client sends
+// or accepts. Larger outgoing messages fail with ErrMessageTooLarge and larger
+// incoming ones are discarded. It must be called before Start.
+func (c *Client) SetMaxMessageSize(size int) {
+	if size > 0 {
+		c.maxMessageSize = size
+	}
+}
+
+// SetLogger sets where the client reports read errors, discarded messages
+// and responses it failed to send. By default they are written to stderr. It
+// must be called before Start.
+func (c *Client) SetLogger(logger *sdklog.Logger) {
+	c.logger = logger
+}
+
+// SetRequestTimeout sets how long a request whose context has no deadline
+// waits for its response before failing with ErrRequestTimeout, so requests
+// whose response never arrives do not wait forever. Zero or negative means no
+// timeout. It must be called before Start.
+func (c *Client) SetRequestTimeout(timeout time.Duration) {
+	c.requestTimeout = timeout
+}
+
+// SetMaxPendingRequests limits the requests awaiting a response. Requests
+// over the limit fail with ErrTooManyPendingRequests without being sent. Zero
+// or negative means no limit. It must be called before Start.
+func (c *Client) SetMaxPendingRequests(max int) {
+	c.maxPending = max
+}
+
+// SetPendingWarning sets the number of requests awaiting a response at which
+// further requests are still sent but reported with OverPendingWarning set and
+// counted by PendingWarnings. Zero or negative means no warning. It must be
+// called before Start.
+func (c *Client) SetPendingWarning(threshold int) {
+	c.pendingWarning = threshold
+}
+
+// PendingWarnings returns the number of requests sent while at least the
+// SetPendingWarning threshold of requests awaited a response.
+func (c *Client) PendingWarnings() int {
+	return int(c.pendingWarnings.Load())
+}
+
+// PendingRequests returns the number of requests awaiting a response.
+func (c *Client) PendingRequests() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pendingRequests)
+}
+
+// ParseFailures returns the number of messages discarded because they were
+// malformed, could not be decompressed, or were neither a request nor a
+// response.
+func (c *Client) ParseFailures() int {
+	return int(c.parseFailures.Load())
+}
+
+// RequestHandlers returns the number of registered request handlers.
+func (c *Client) RequestHandlers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.requestHandlers)
+}
+
+// SetIDGenerator sets the function that generates request IDs. IDs must be
+// unique among the requests in flight. It must be called before Start.
+func (c *Client) SetIDGenerator(generate func() string) {
+	if generate != nil {
+		c.generateID = generate
 	}
 }
 
+// SetConnectionLostHandler sets a function called once, in its own goroutine,
+// when the connection is lost: a write fails or the read loop hits an error
+// while the client is running. It must be called before Start.
+func (c *Client) SetConnectionLostHandler(handler func(err error)) {
+	c.onLost = handler
+}
+
+// connectionLost marks the connection as lost. Only the first call has any
+// effect: later writes fail fast, pending requests are released, and the
+// connection lost handler is notified.
+func (c *Client) connectionLost(err error) {
+	c.lostOnce.Do(func() {
+		c.writeClosed.Store(true)
+		close(c.lostChan)
+		if c.onLost != nil {
+			go c.onLost(err)
+		}
+	})
+}
+
 // SetProcessDone sets a channel that will be closed when the process exits,
 // and stores the error that should be returned to pending/future requests.
 func (c *Client) SetProcessDone(done chan struct{}, errPtr *error) {
@@ -96,6 +287,15 @@ func (c *Client) getProcessError() error {
 	return c.processError
 }
 
+// processExitError returns the error of requests that fail because the
+// process exited. It matches ErrConnectionClosed.
+func (c *Client) processExitError() error {
+	if err := c.getProcessError(); err != nil {
+		return fmt.Errorf("%w: %w", ErrConnectionClosed, err)
+	}
+	return fmt.Errorf("%w: process exited unexpectedly", ErrConnectionClosed)
+}
+
 // Start begins listening for messages in a background goroutine
 func (c *Client) Start() {
 	c.running.Store(true)
@@ -170,6 +370,57 @@ func RequestHandlerFor[In, Out any](handler func(params In) (Out, *Error)) Reque
 	}
 }
 
+// SetCallObserver sets a function notified of every completed call.
+// It must be called before Start.
+func (c *Client) SetCallObserver(observer CallObserver) {
+	c.observer = observer
+}
+
+// Tracer receives the body of every message the client sends or receives,
+// with incoming set for received ones. Sent messages are traced in the order
+// they are written, before compression; received ones after decompression,
+// including those that turn out to be neither a request nor a response. It
+// must not modify or retain message, and must not block.
+type Tracer func(incoming bool, message []byte)
+
+// SetTracer sets a function receiving every message the client sends or
+// receives. It must be called before Start.
+func (c *Client) SetTracer(tracer Tracer) {
+	c.tracer = tracer
+}
+
+// observeRequest reports a completed outgoing request to the observer, if any.
+func (c *Client) observeRequest(method, id string, start time.Time, overWarning bool, err error) {
+	if c.observer != nil {
+		c.observer(Call{Method: method, ID: id, Duration: time.Since(start), Err: err, OverPendingWarning: overWarning})
+	}
+}
+
+// observe reports a completed call to the observer, if any.
+func (c *Client) observe(method, id string, incoming bool, start time.Time, err error) {
+	if c.observer != nil {
+		c.observer(Call{Method: method, ID: id, Incoming: incoming, Duration: time.Since(start), Err: err})
+	}
+}
+
+// idString returns the ID of an incoming request as a string.
+func idString(id json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(id, &s); err == nil {
+		return s
+	}
+	return string(id)
+}
+
+// asError converts a handler error to an error without creating a non-nil
+// interface holding a nil pointer.
+func asError(err *Error) error {
+	if err == nil {
+		return nil
+	}
+	return err
+}
+
 // SetRequestHandler registers a handler for incoming requests from the server
 func (c *Client) SetRequestHandler(method string, handler RequestHandler) {
 	c.mu.Lock()
@@ -183,11 +434,64 @@ func (c *Client) SetRequestHandler(method string, handler RequestHandler) {
 
 // Request sends a JSON-RPC request and waits for the response
 func (c *Client) Request(method string, params any) (json.RawMessage, error) {
-	requestID := generateUUID()
+	return c.RequestContext(context.Background(), method, params)
+}
+
+// RequestContext sends a JSON-RPC request and waits for the response or for
+// ctx to be done, whichever comes first. A response arriving after ctx is
+// done is discarded. Requests to methods of the retry policy that time out
+// are sent again. Errors are returned as a *CallError.
+func (c *Client) RequestContext(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	if c.forward != nil {
+		target, err := c.forwardTarget()
+		if err != nil {
+			return nil, err
+		}
+		return target.RequestContext(ctx, method, params)
+	}
+	retries := c.retries(method)
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		requestID := c.generateID()
+		var overWarning bool
+		result, err := c.request(ctx, requestID, method, params, &overWarning)
+		if err != nil {
+			callErr := &CallError{Method: method, RequestID: requestID, Err: err}
+			var rpcErr *Error
+			if errors.As(err, &rpcErr) {
+				callErr.Code, callErr.Message, callErr.Data = rpcErr.Code, rpcErr.Message, rpcErr.Data
+			}
+			err = callErr
+		}
+		c.observeRequest(method, requestID, start, overWarning, err)
+		if attempt == retries || !retryable(ctx, err) || !c.backoff(ctx, attempt+1) {
+			return result, err
+		}
+		c.logger.Debug("retrying timed-out request", "method", method, "requestID", requestID, "attempt", attempt+2)
+	}
+}
+
+// request sends one attempt of a request. It sets *overWarning if the request
+// was sent while at least the SetPendingWarning threshold of requests awaited
+// a response.
+func (c *Client) request(ctx context.Context, requestID, method string, params any, overWarning *bool) (json.RawMessage, error) {
+	if timeout := c.timeoutFor(ctx); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, ErrRequestTimeout)
+		defer cancel()
+	}
 
 	// Create response channel
 	responseChan := make(chan *Response, 1)
 	c.mu.Lock()
+	if c.maxPending > 0 && len(c.pendingRequests) >= c.maxPending {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d requests are awaiting a response", ErrTooManyPendingRequests, c.maxPending)
+	}
+	if c.pendingWarning > 0 && len(c.pendingRequests) >= c.pendingWarning {
+		*overWarning = true
+		c.pendingWarnings.Add(1)
+	}
 	c.pendingRequests[requestID] = responseChan
 	c.mu.Unlock()
 
@@ -198,14 +502,15 @@ func (c *Client) Request(method string, params any) (json.RawMessage, error) {
 		c.mu.Unlock()
 	}()
 
+	if c.writeClosed.Load() {
+		return nil, ErrConnectionClosed
+	}
+
 	// Check if process already exited before sending
 	if c.processDone != nil {
 		select {
 		case <-c.processDone:
-			if err := c.getProcessError(); err != nil {
-				return nil, err
-			}
-			return nil, fmt.Errorf("process exited unexpectedly")
+			return nil, c.processExitError()
 		default:
 			// Process still running, continue
 		}
@@ -217,9 +522,13 @@ func (c *Client) Request(method string, params any) (json.RawMessage, error) {
 	}
 
 	// Send request
+	id, err := json.Marshal(requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request ID: %w", err)
+	}
 	request := Request{
 		JSONRPC: "2.0",
-		ID:      json.RawMessage(`"` + requestID + `"`),
+		ID:      json.RawMessage(id),
 		Method:  method,
 		Params:  json.RawMessage(paramsData),
 	}
@@ -237,12 +546,13 @@ func (c *Client) Request(method string, params any) (json.RawMessage, error) {
 			}
 			return response.Result, nil
 		case <-c.processDone:
-			if err := c.getProcessError(); err != nil {
-				return nil, err
-			}
-			return nil, fmt.Errorf("process exited unexpectedly")
+			return nil, c.processExitError()
 		case <-c.stopChan:
-			return nil, fmt.Errorf("client stopped")
+			return nil, ErrClientStopped
+		case <-c.lostChan:
+			return nil, ErrConnectionClosed
+		case <-ctx.Done():
+			return nil, contextError(ctx)
 		}
 	}
 	select {
@@ -252,12 +562,32 @@ func (c *Client) Request(method string, params any) (json.RawMessage, error) {
 		}
 		return response.Result, nil
 	case <-c.stopChan:
-		return nil, fmt.Errorf("client stopped")
+		return nil, ErrClientStopped
+	case <-c.lostChan:
+		return nil, ErrConnectionClosed
+	case <-ctx.Done():
+		return nil, contextError(ctx)
+	}
+}
+
+// contextError returns why ctx is done: ErrRequestTimeout if the request
+// timeout expired, or ctx.Err() otherwise.
+func contextError(ctx context.Context) error {
+	if cause := context.Cause(ctx); errors.Is(cause, ErrRequestTimeout) {
+		return cause
 	}
+	return ctx.Err()
 }
 
 // Notify sends a JSON-RPC notification (no response expected)
 func (c *Client) Notify(method string, params any) error {
+	if c.forward != nil {
+		target, err := c.forwardTarget()
+		if err != nil {
+			return err
+		}
+		return target.Notify(method, params)
+	}
 	paramsData, err := json.Marshal(params)
 	if err != nil {
 		return fmt.Errorf("failed to marshal params: %w", err)
@@ -271,26 +601,32 @@ func (c *Client) Notify(method string, params any) error {
 	return c.sendMessage(notification)
 }
 
-// sendMessage writes a message to stdin
+// sendMessage writes a message to stdin through the write queue. A failed
+// write marks the connection as lost.
 func (c *Client) sendMessage(message any) error {
 	data, err := json.Marshal(message)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
-
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Write Content-Length header + message
-	header := fmt.Sprintf("Content-Length: %d\r\n\r\n", len(data))
-	if _, err := c.stdin.Write([]byte(header)); err != nil {
-		return fmt.Errorf("failed to write header: %w", err)
+	if len(data) > c.maxMessageSize {
+		return fmt.Errorf("%w: %d bytes exceeds the limit of %d bytes", ErrMessageTooLarge, len(data), c.maxMessageSize)
 	}
-	if _, err := c.stdin.Write(data); err != nil {
-		return fmt.Errorf("failed to write message: %w", err)
+
+	// Write the headers and message in a single write, so a failure never
+	// leaves a partial frame followed by another message
+	plain := data
+	data, encoding := c.compress(data)
+	var frame bytes.Buffer
+	if encoding != "" {
+		fmt.Fprintf(&frame, "Content-Encoding: %s\r\n", encoding)
 	}
+	fmt.Fprintf(&frame, "Content-Length: %d\r\n\r\n", len(data))
+	frame.Write(data)
 
-	return nil
+	if c.writeClosed.Load() {
+		return ErrConnectionClosed
+	}
+	return c.write(frame.Bytes(), plain)
 }
 
 // readLoop reads messages from stdout in a background goroutine
@@ -299,40 +635,49 @@ func (c *Client) readLoop() {
 
 	reader := bufio.NewReader(c.stdout)
 
+	malformed := 0
 	for c.running.Load() {
-		// Read Content-Length header
-		var contentLength int
-		for {
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				// Only log unexpected errors (not EOF or closed pipe during shutdown)
-				if err != io.EOF && c.running.Load() {
-					fmt.Printf("Error reading header: %v\n", err)
-				}
+		f, err := readFrame(reader, c.maxMessageSize)
+		var frameErr *FrameError
+		var tooLarge *FrameTooLargeError
+		switch {
+		case errors.As(err, &tooLarge):
+			c.logger.Warn("discarding oversized message", "error", err)
+			continue
+		case errors.As(err, &frameErr):
+			c.parseFailures.Add(1)
+			malformed++
+			if malformed >= maxConsecutiveFrameErrors {
+				c.logger.Error("giving up after too many malformed frames in a row", "frames", malformed, "error", err)
+				c.connectionLost(fmt.Errorf("%w: %d malformed frames in a row: %w", ErrConnectionClosed, malformed, err))
 				return
 			}
-
-			// Check for blank line (end of headers)
-			if line == "\r\n" || line == "\n" {
-				break
+			c.logger.Warn("skipping malformed frame", "error", err)
+			continue
+		case err != nil:
+			// Only log unexpected errors (not EOF or closed pipe during shutdown)
+			if err != io.EOF && c.running.Load() {
+				c.logger.Error("error reading message", "error", err)
 			}
-
-			// Parse Content-Length
-			var length int
-			if _, err := fmt.Sscanf(line, "Content-Length: %d", &length); err == nil {
-				contentLength = length
+			if c.running.Load() {
+				c.connectionLost(fmt.Errorf("%w: %w", ErrConnectionClosed, err))
 			}
+			return
 		}
+		malformed = 0
 
-		if contentLength == 0 {
-			continue
+		body := f.body
+		if f.encoding != "" {
+			decoded, err := decompress(f.encoding, body, c.maxMessageSize)
+			if err != nil {
+				c.parseFailures.Add(1)
+				c.logger.Warn("discarding undecodable message", "error", err)
+				continue
+			}
+			body = decoded
 		}
-
-		// Read message body
-		body := make([]byte, contentLength)
-		if _, err := io.ReadFull(reader, body); err != nil {
-			fmt.Printf("Error reading body: %v\n", err)
-			return
+		if c.tracer != nil {
+			c.tracer(true, body)
 		}
 
 		// Try to parse as request first (has both ID and Method)
@@ -348,6 +693,9 @@ func (c *Client) readLoop() {
 			c.handleResponse(&response)
 			continue
 		}
+
+		c.parseFailures.Add(1)
+		c.logger.Warn("discarding message that is neither a request nor a response", "bytes", len(body))
 	}
 }
 
@@ -376,63 +724,91 @@ func (c *Client) handleRequest(request *Request) {
 
 	if handler == nil {
 		if request.IsCall() {
-			c.sendErrorResponse(request.ID, -32601, fmt.Sprintf("Method not found: %s", request.Method), nil)
+			c.sendErrorResponse(request, -32601, fmt.Sprintf("Method not found: %s", request.Method), nil)
 		}
 		return
 	}
 
-	// Notifications run synchronously, calls run in a goroutine to avoid blocking
+	// Notifications run synchronously, calls run in a goroutine, or a queue's
+	// goroutine, to avoid blocking
+	start := time.Now()
+	id := idString(request.ID)
 	if !request.IsCall() {
-		handler(request.Params)
+		defer func() {
+			if r := recover(); r != nil {
+				message := fmt.Sprintf("notification handler panic: %v", r)
+				c.logger.Error("notification handler panicked", "method", request.Method, "panic", r, "stack", string(debug.Stack()))
+				c.observe(request.Method, id, true, start, &Error{Code: -32603, Message: message})
+			}
+		}()
+		_, err := handler(request.Params)
+		c.observe(request.Method, id, true, start, asError(err))
 		return
 	}
 
-	go func() {
+	c.dispatchCall(request, func() {
 		defer func() {
 			if r := recover(); r != nil {
-				c.sendErrorResponse(request.ID, -32603, fmt.Sprintf("request handler panic: %v", r), nil)
+				message := fmt.Sprintf("request handler panic: %v", r)
+				c.logger.Error("request handler panicked", "method", request.Method, "requestID", id, "panic", r, "stack", string(debug.Stack()))
+				c.observe(request.Method, id, true, start, &Error{Code: -32603, Message: message})
+				c.sendErrorResponse(request, -32603, message, nil)
 			}
 		}()
 
 		result, err := handler(request.Params)
+		c.observe(request.Method, id, true, start, asError(err))
 		if err != nil {
-			c.sendErrorResponse(request.ID, err.Code, err.Message, err.Data)
+			c.sendErrorResponse(request, err.Code, err.Message, err.Data)
 			return
 		}
-		c.sendResponse(request.ID, result)
-	}()
+		c.sendResponse(request, result)
+	})
 }
 
-func (c *Client) sendResponse(id json.RawMessage, result json.RawMessage) {
+func (c *Client) sendResponse(request *Request, result json.RawMessage) {
 	response := Response{
 		JSONRPC: "2.0",
-		ID:      id,
+		ID:      request.ID,
 		Result:  result,
 	}
-	if err := c.sendMessage(response); err != nil {
-		fmt.Printf("Failed to send JSON-RPC response: %v\n", err)
+	if err := c.sendMessage(response); err != nil && !errors.Is(err, ErrConnectionClosed) {
+		c.logger.Error("failed to send JSON-RPC response", "method", request.Method, "requestID", idString(request.ID), "error", err)
 	}
 }
 
-func (c *Client) sendErrorResponse(id json.RawMessage, code int, message string, data map[string]any) {
+func (c *Client) sendErrorResponse(request *Request, code int, message string, data map[string]any) {
 	response := Response{
 		JSONRPC: "2.0",
-		ID:      id,
+		ID:      request.ID,
 		Error: &Error{
 			Code:    code,
 			Message: message,
 			Data:    data,
 		},
 	}
-	if err := c.sendMessage(response); err != nil {
-		fmt.Printf("Failed to send JSON-RPC error response: %v\n", err)
+	if err := c.sendMessage(response); err != nil && !errors.Is(err, ErrConnectionClosed) {
+		c.logger.Error("failed to send JSON-RPC error response", "method", request.Method, "requestID", idString(request.ID), "error", err)
 	}
 }
 
-// generateUUID generates a simple UUID v4 without external dependencies
+// readRandom fills b with random bytes. It is a variable so tests can make
+// it fail.
+var readRandom = rand.Read
+
+// fallbackIDCounter numbers the IDs generated without randomness.
+var fallbackIDCounter atomic.Uint64
+
+// generateUUID generates a simple UUID v4 without external dependencies.
+// If the system's randomness source fails, it falls back to an ID built from
+// the current time and a process-wide counter, which is unique within the
+// process, rather than using zeroed bytes.
 func generateUUID() string {
 	b := make([]byte, 16)
-	rand.Read(b)
+	if _, err := readRandom(b); err != nil {
+		binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixNano()))
+		binary.BigEndian.PutUint64(b[8:], fallbackIDCounter.Add(1))
+	}
 	b[6] = (b[6] & 0x0f) | 0x40 // Version 4
 	b[8] = (b[8] & 0x3f) | 0x80 // Variant is 10
 	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
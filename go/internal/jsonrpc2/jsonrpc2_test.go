@@ -0,0 +1,410 @@
+package jsonrpc2
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/sdklog"
+)
+
+// faultyWriter stands in for the server's stdin. Once broken, writes fail
+// with EPIPE, or panic if panics is set.
+type faultyWriter struct {
+	broken atomic.Bool
+	panics bool
+	writes atomic.Int32
+	frames chan []byte
+}
+
+func newFaultyWriter() *faultyWriter {
+	return &faultyWriter{frames: make(chan []byte, 16)}
+}
+
+func (w *faultyWriter) Write(p []byte) (int, error) {
+	if w.broken.Load() {
+		if w.panics {
+			panic("write on closed pipe")
+		}
+		return 0, syscall.EPIPE
+	}
+	w.writes.Add(1)
+	w.frames <- append([]byte(nil), p...)
+	return len(p), nil
+}
+
+func (w *faultyWriter) Close() error { return nil }
+
+type testConn struct {
+	client  *Client
+	writer  *faultyWriter
+	inbound *io.PipeWriter
+	lost    chan error
+	losses  atomic.Int32
+}
+
+// newTestConn returns a started client reading from a pipe. setup runs
+// before the client starts.
+func newTestConn(t *testing.T, setup ...func(*Client)) *testConn {
+	t.Helper()
+	stdout, inbound := io.Pipe()
+	conn := &testConn{
+		writer:  newFaultyWriter(),
+		inbound: inbound,
+		lost:    make(chan error, 4),
+	}
+	conn.client = NewClient(conn.writer, stdout)
+	conn.client.SetConnectionLostHandler(func(err error) {
+		conn.losses.Add(1)
+		conn.lost <- err
+	})
+	for _, fn := range setup {
+		fn(conn.client)
+	}
+	conn.client.Start()
+	t.Cleanup(conn.client.Stop)
+	return conn
+}
+
+// deliver writes a message to the client as if sent by the server.
+func (c *testConn) deliver(t *testing.T, message any) {
+	t.Helper()
+	data, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("Failed to marshal message: %v", err)
+	}
+	if _, err := fmt.Fprintf(c.inbound, "Content-Length: %d\r\n\r\n%s", len(data), data); err != nil {
+		t.Fatalf("Failed to deliver message: %v", err)
+	}
+}
+
+// waitLost waits for the connection lost handler and checks it ran once.
+func (c *testConn) waitLost(t *testing.T) error {
+	t.Helper()
+	var err error
+	select {
+	case err = <-c.lost:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for connection lost handler")
+	}
+	// Give a duplicate notification a chance to show up
+	time.Sleep(20 * time.Millisecond)
+	if n := c.losses.Load(); n != 1 {
+		t.Errorf("Expected connection lost handler to run once, got %d", n)
+	}
+	if !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Expected ErrConnectionClosed, got %v", err)
+	}
+	return err
+}
+
+func TestClient_WriteAfterConnectionLost(t *testing.T) {
+	writePaths := []struct {
+		name    string
+		trigger func(t *testing.T, conn *testConn) error
+	}{
+		{"request", func(t *testing.T, conn *testConn) error {
+			_, err := conn.client.Request("ping", nil)
+			return err
+		}},
+		{"notify", func(t *testing.T, conn *testConn) error {
+			return conn.client.Notify("session.log", map[string]string{"message": "hi"})
+		}},
+		{"response", func(t *testing.T, conn *testConn) error {
+			conn.deliver(t, Request{JSONRPC: "2.0", ID: json.RawMessage(`1`), Method: "tool.call"})
+			return nil
+		}},
+		{"error response", func(t *testing.T, conn *testConn) error {
+			conn.deliver(t, Request{JSONRPC: "2.0", ID: json.RawMessage(`2`), Method: "tool.fail"})
+			return nil
+		}},
+		{"method not found response", func(t *testing.T, conn *testConn) error {
+			conn.deliver(t, Request{JSONRPC: "2.0", ID: json.RawMessage(`3`), Method: "unknown"})
+			return nil
+		}},
+		{"handler panic response", func(t *testing.T, conn *testConn) error {
+			conn.deliver(t, Request{JSONRPC: "2.0", ID: json.RawMessage(`4`), Method: "tool.panic"})
+			return nil
+		}},
+	}
+
+	for _, panics := range []bool{false, true} {
+		for _, path := range writePaths {
+			name := path.name + " on broken pipe"
+			if panics {
+				name = path.name + " on panicking writer"
+			}
+			t.Run(name, func(t *testing.T) {
+				conn := newTestConn(t)
+				conn.client.SetRequestHandler("tool.call", func(json.RawMessage) (json.RawMessage, *Error) {
+					return json.RawMessage(`{}`), nil
+				})
+				conn.client.SetRequestHandler("tool.fail", func(json.RawMessage) (json.RawMessage, *Error) {
+					return nil, &Error{Code: -32000, Message: "failed"}
+				})
+				conn.client.SetRequestHandler("tool.panic", func(json.RawMessage) (json.RawMessage, *Error) {
+					panic("boom")
+				})
+				conn.writer.panics = panics
+				conn.writer.broken.Store(true)
+
+				if err := path.trigger(t, conn); err != nil && !errors.Is(err, ErrConnectionClosed) {
+					t.Errorf("Expected ErrConnectionClosed, got %v", err)
+				}
+				conn.waitLost(t)
+
+				// Later writes fail fast without touching the transport
+				conn.writer.broken.Store(false)
+				if err := conn.client.Notify("session.log", nil); !errors.Is(err, ErrConnectionClosed) {
+					t.Errorf("Expected ErrConnectionClosed after loss, got %v", err)
+				}
+				if _, err := conn.client.Request("ping", nil); !errors.Is(err, ErrConnectionClosed) {
+					t.Errorf("Expected ErrConnectionClosed after loss, got %v", err)
+				}
+				if n := conn.writer.writes.Load(); n != 0 {
+					t.Errorf("Expected no writes after loss, got %d", n)
+				}
+			})
+		}
+	}
+
+	t.Run("pending request is released when a later write fails", func(t *testing.T) {
+		conn := newTestConn(t)
+		result := make(chan error, 1)
+		go func() {
+			_, err := conn.client.Request("session.send", nil)
+			result <- err
+		}()
+		<-conn.writer.frames
+
+		conn.writer.broken.Store(true)
+		if err := conn.client.Notify("session.log", nil); !errors.Is(err, ErrConnectionClosed) {
+			t.Errorf("Expected ErrConnectionClosed, got %v", err)
+		}
+		select {
+		case err := <-result:
+			if !errors.Is(err, ErrConnectionClosed) {
+				t.Errorf("Expected ErrConnectionClosed, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected pending request to return")
+		}
+		conn.waitLost(t)
+	})
+
+	t.Run("concurrent writes report the loss once", func(t *testing.T) {
+		conn := newTestConn(t)
+		conn.writer.broken.Store(true)
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				conn.client.Notify("session.log", nil)
+			}()
+		}
+		wg.Wait()
+		conn.waitLost(t)
+	})
+
+	t.Run("server closing the stream is a connection loss", func(t *testing.T) {
+		conn := newTestConn(t)
+		conn.inbound.Close()
+		conn.waitLost(t)
+	})
+
+	t.Run("stop is not a connection loss", func(t *testing.T) {
+		conn := newTestConn(t)
+		conn.client.Stop()
+		time.Sleep(20 * time.Millisecond)
+		if n := conn.losses.Load(); n != 0 {
+			t.Errorf("Expected no connection loss on stop, got %d", n)
+		}
+	})
+}
+
+func TestClient_RequestIDs(t *testing.T) {
+	t.Run("uses the ID generator and reports IDs", func(t *testing.T) {
+		conn := newTestConn(t)
+		conn.client.SetIDGenerator(func() string { return "req-1" })
+		calls := make(chan Call, 2)
+		conn.client.SetCallObserver(func(call Call) { calls <- call })
+
+		go func() {
+			frame := <-conn.writer.frames
+			if !strings.Contains(string(frame), `"id":"req-1"`) {
+				t.Errorf("Expected the generated ID on the wire, got %s", frame)
+			}
+			conn.deliver(t, Response{JSONRPC: "2.0", ID: json.RawMessage(`"req-1"`), Error: &Error{Code: -32000, Message: "boom", Data: map[string]any{"code": "failed"}}})
+		}()
+		_, err := conn.client.Request("session.send", nil)
+
+		var callErr *CallError
+		if !errors.As(err, &callErr) || callErr.RequestID != "req-1" || callErr.Method != "session.send" {
+			t.Fatalf("Expected a CallError for req-1, got %#v", err)
+		}
+		if callErr.Code != -32000 || callErr.Message != "boom" || callErr.Data["code"] != "failed" {
+			t.Errorf("Expected the server error's fields, got %+v", callErr)
+		}
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) || rpcErr.Code != -32000 {
+			t.Errorf("Expected the server error to be wrapped, got %v", err)
+		}
+		if err.Error() != "JSON-RPC Error -32000: boom" {
+			t.Errorf("Expected the server error's message, got %q", err.Error())
+		}
+		if call := <-calls; call.ID != "req-1" || call.Incoming || call.Err == nil {
+			t.Errorf("Unexpected observed call: %+v", call)
+		}
+
+		conn.client.SetRequestHandler("tool.call", func(json.RawMessage) (json.RawMessage, *Error) {
+			return json.RawMessage(`{}`), nil
+		})
+		conn.deliver(t, Request{JSONRPC: "2.0", ID: json.RawMessage(`7`), Method: "tool.call"})
+		if call := <-calls; call.ID != "7" || !call.Incoming {
+			t.Errorf("Expected the incoming request's ID, got %+v", call)
+		}
+	})
+
+	t.Run("falls back when randomness fails", func(t *testing.T) {
+		readRandom = func([]byte) (int, error) { return 0, errors.New("no entropy") }
+		t.Cleanup(func() { readRandom = rand.Read })
+
+		seen := make(map[string]bool)
+		for i := 0; i < 100; i++ {
+			id := generateUUID()
+			if seen[id] || strings.HasPrefix(id, "00000000-0000-4000-8000") {
+				t.Fatalf("Expected unique non-zero IDs, got %q", id)
+			}
+			seen[id] = true
+		}
+	})
+}
+
+func TestMessageSizeLimit(t *testing.T) {
+	stdout, inbound := io.Pipe()
+	writer := newFaultyWriter()
+	client := NewClient(writer, stdout)
+	client.SetMaxMessageSize(200)
+	received := make(chan string, 4)
+	client.SetRequestHandler("notify", func(params json.RawMessage) (json.RawMessage, *Error) {
+		received <- string(params)
+		return nil, nil
+	})
+	client.Start()
+	t.Cleanup(client.Stop)
+	conn := &testConn{client: client, writer: writer, inbound: inbound}
+
+	t.Run("rejects oversized outgoing messages without writing", func(t *testing.T) {
+		err := client.Notify("notify", strings.Repeat("x", 300))
+		if !errors.Is(err, ErrMessageTooLarge) {
+			t.Errorf("Expected ErrMessageTooLarge, got %v", err)
+		}
+		if writer.writes.Load() != 0 {
+			t.Errorf("Expected nothing to be written, got %d writes", writer.writes.Load())
+		}
+		if err := client.Notify("notify", "small"); err != nil {
+			t.Errorf("Expected a small message to be sent, got %v", err)
+		}
+	})
+
+	t.Run("discards oversized incoming messages and keeps reading", func(t *testing.T) {
+		conn.deliver(t, map[string]any{"jsonrpc": "2.0", "method": "notify", "params": strings.Repeat("y", 300)})
+		conn.deliver(t, map[string]any{"jsonrpc": "2.0", "method": "notify", "params": "after"})
+		select {
+		case params := <-received:
+			if params != `"after"` {
+				t.Errorf("Expected only the small message to be handled, got %s", params)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the small message")
+		}
+	})
+}
+
+func TestClient_RequestTimeout(t *testing.T) {
+	conn := newTestConn(t)
+	conn.client.SetRequestTimeout(20 * time.Millisecond)
+	go func() {
+		for range conn.writer.frames {
+		}
+	}()
+
+	if _, err := conn.client.Request("status.get", nil); !errors.Is(err, ErrRequestTimeout) {
+		t.Errorf("Expected ErrRequestTimeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := conn.client.RequestContext(ctx, "status.get", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the caller's cancellation, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := conn.client.RequestContext(ctx, "status.get", nil); !errors.Is(err, context.DeadlineExceeded) || time.Since(start) < 100*time.Millisecond {
+		t.Errorf("Expected the caller's deadline to replace the timeout, got %v", err)
+	}
+	if n := conn.client.PendingRequests(); n != 0 {
+		t.Errorf("Expected no pending requests, got %d", n)
+	}
+}
+
+func TestClient_Tracer(t *testing.T) {
+	type traced struct {
+		incoming bool
+		message  string
+	}
+	messages := make(chan traced, 8)
+	conn := newTestConn(t, func(c *Client) {
+		c.SetIDGenerator(func() string { return "req-1" })
+		c.SetLogger(sdklog.New(io.Discard, false))
+		c.SetTracer(func(incoming bool, message []byte) {
+			messages <- traced{incoming, string(message)}
+		})
+	})
+	conn.client.SetRequestHandler("tool.call", func(json.RawMessage) (json.RawMessage, *Error) {
+		return json.RawMessage(`{"ok":true}`), nil
+	})
+
+	go func() {
+		<-conn.writer.frames
+		conn.deliver(t, Response{JSONRPC: "2.0", ID: json.RawMessage(`"req-1"`), Result: json.RawMessage(`{}`)})
+	}()
+	if _, err := conn.client.Request("session.send", map[string]string{"prompt": "hi"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	conn.deliver(t, map[string]any{"jsonrpc": "2.0", "id": 7, "method": "tool.call"})
+	<-conn.writer.frames
+	conn.deliver(t, map[string]any{"jsonrpc": "2.0", "method": "session.event"})
+	conn.deliver(t, map[string]any{"jsonrpc": "2.0"})
+
+	want := []traced{
+		{false, `{"jsonrpc":"2.0","id":"req-1","method":"session.send","params":{"prompt":"hi"}}`},
+		{true, `{"jsonrpc":"2.0","id":"req-1","result":{}}`},
+		{true, `{"id":7,"jsonrpc":"2.0","method":"tool.call"}`},
+		{false, `{"jsonrpc":"2.0","id":7,"result":{"ok":true}}`},
+		{true, `{"jsonrpc":"2.0","method":"session.event"}`},
+		{true, `{"jsonrpc":"2.0"}`},
+	}
+	for i, w := range want {
+		select {
+		case got := <-messages:
+			if got != w {
+				t.Errorf("Message %d: expected %+v, got %+v", i, w, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for message %d", i)
+		}
+	}
+}
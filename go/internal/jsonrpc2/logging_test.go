@@ -0,0 +1,124 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/sdklog"
+)
+
+// logBuffer collects log output and can be waited on.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// waitFor waits until the output contains substr and returns it.
+func (b *logBuffer) waitFor(t *testing.T, substr string) string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		b.mu.Lock()
+		output := b.buf.String()
+		b.mu.Unlock()
+		if strings.Contains(output, substr) {
+			return output
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %q in the log, got %q", substr, output)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestClient_Logger(t *testing.T) {
+	withLogger := func(out *logBuffer, machineReadable bool) func(*Client) {
+		return func(c *Client) {
+			c.SetLogger(sdklog.New(out, machineReadable))
+			c.SetMaxMessageSize(200)
+		}
+	}
+
+	t.Run("reports oversized incoming messages", func(t *testing.T) {
+		var out logBuffer
+		conn := newTestConn(t, withLogger(&out, false))
+		conn.deliver(t, map[string]any{"jsonrpc": "2.0", "method": "notify", "params": strings.Repeat("y", 300)})
+		output := out.waitFor(t, "discarding oversized message")
+		if !strings.Contains(output, ` copilot: discarding oversized message error="message of 3`) || !strings.HasSuffix(output, "over the limit of 200 bytes\"\n") {
+			t.Errorf("Unexpected log output %q", output)
+		}
+	})
+
+	t.Run("reports undecodable messages", func(t *testing.T) {
+		var out logBuffer
+		conn := newTestConn(t, withLogger(&out, false))
+		fmt.Fprintf(conn.inbound, "Content-Encoding: br\r\nContent-Length: 4\r\n\r\nxxxx")
+		out.waitFor(t, `discarding undecodable message error="unsupported encoding`)
+	})
+
+	t.Run("reports read errors", func(t *testing.T) {
+		var out logBuffer
+		conn := newTestConn(t, withLogger(&out, false))
+		conn.inbound.CloseWithError(errors.New("pipe reset"))
+		out.waitFor(t, `error reading message error="pipe reset"`)
+		conn.waitLost(t)
+	})
+
+	t.Run("reports responses it failed to send", func(t *testing.T) {
+		var out logBuffer
+		conn := newTestConn(t, withLogger(&out, false))
+		conn.client.SetRequestHandler("tool.call", func(json.RawMessage) (json.RawMessage, *Error) {
+			return json.RawMessage(`"` + strings.Repeat("z", 300) + `"`), nil
+		})
+		conn.deliver(t, Request{JSONRPC: "2.0", ID: json.RawMessage(`1`), Method: "tool.call"})
+		out.waitFor(t, "failed to send JSON-RPC response method=tool.call requestID=1 error=")
+	})
+
+	t.Run("reports handler panics with their stack", func(t *testing.T) {
+		var out logBuffer
+		conn := newTestConn(t, withLogger(&out, false))
+		conn.client.SetRequestHandler("tool.call", func(json.RawMessage) (json.RawMessage, *Error) {
+			panic("tool failed")
+		})
+		conn.deliver(t, Request{JSONRPC: "2.0", ID: json.RawMessage(`7`), Method: "tool.call"})
+		output := out.waitFor(t, "request handler panicked method=tool.call requestID=7 panic=\"tool failed\" stack=")
+		if !strings.Contains(output, "logging_test.go") {
+			t.Errorf("Expected the stack of the handler, got %q", output)
+		}
+	})
+
+	t.Run("writes single-line JSON when machine readable", func(t *testing.T) {
+		var out logBuffer
+		conn := newTestConn(t, withLogger(&out, true))
+		conn.deliver(t, map[string]any{"jsonrpc": "2.0", "method": "notify", "params": strings.Repeat("y", 300)})
+		output := out.waitFor(t, "discarding oversized message")
+		var entry struct {
+			Time   time.Time `json:"time"`
+			Level  string    `json:"level"`
+			Source string    `json:"source"`
+			Msg    string    `json:"msg"`
+			Error  string    `json:"error"`
+		}
+		if strings.Count(output, "\n") != 1 {
+			t.Fatalf("Expected one line, got %q", output)
+		}
+		if err := json.Unmarshal([]byte(output), &entry); err != nil {
+			t.Fatalf("Expected a JSON line, got %q: %v", output, err)
+		}
+		if entry.Source != "copilot-sdk" || entry.Time.IsZero() || entry.Level != "WARN" || entry.Msg != "discarding oversized message" || !strings.HasPrefix(entry.Error, "message of") {
+			t.Errorf("Unexpected log entry %+v", entry)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package jsonrpc2
+
+import "encoding/json"
+
+// RequestQueueFunc assigns an incoming call to a queue. Calls in the same
+// queue run one at a time, in the order they arrived; calls in different
+// queues run concurrently. An empty queue name runs the call concurrently
+// with everything else.
+type RequestQueueFunc func(method string, params json.RawMessage) string
+
+// SetRequestQueue sets how incoming calls are queued. By default every call
+// runs in its own goroutine, so handlers may finish, and their responses be
+// sent, in any order. It must be called before Start.
+func (c *Client) SetRequestQueue(queue RequestQueueFunc) {
+	c.requestQueue = queue
+}
+
+// dispatchCall runs a call's handler in its own goroutine, or after the
+// calls queued before it.
+func (c *Client) dispatchCall(request *Request, run func()) {
+	var queue string
+	if c.requestQueue != nil {
+		queue = c.requestQueue(request.Method, request.Params)
+	}
+	if queue == "" {
+		go run()
+		return
+	}
+
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+	if pending, busy := c.queues[queue]; busy {
+		c.queues[queue] = append(pending, run)
+		return
+	}
+	if c.queues == nil {
+		c.queues = make(map[string][]func())
+	}
+	c.queues[queue] = nil
+	go c.drainQueue(queue, run)
+}
+
+// drainQueue runs run and then the calls queued behind it, until the queue
+// is empty.
+func (c *Client) drainQueue(queue string, run func()) {
+	for run != nil {
+		run()
+
+		c.queueMu.Lock()
+		pending := c.queues[queue]
+		if len(pending) == 0 {
+			delete(c.queues, queue)
+			run = nil
+		} else {
+			run = pending[0]
+			pending[0] = nil
+			c.queues[queue] = pending[1:]
+		}
+		c.queueMu.Unlock()
+	}
+}
@@ -0,0 +1,88 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_RequestQueue(t *testing.T) {
+	// slow answers a call after its delay, in milliseconds.
+	slow := func(params json.RawMessage) (json.RawMessage, *Error) {
+		var p struct {
+			Delay int `json:"delay"`
+		}
+		json.Unmarshal(params, &p)
+		time.Sleep(time.Duration(p.Delay) * time.Millisecond)
+		return json.RawMessage(`null`), nil
+	}
+	byQueueParam := func(method string, params json.RawMessage) string {
+		var p struct {
+			Queue string `json:"queue"`
+		}
+		json.Unmarshal(params, &p)
+		return p.Queue
+	}
+	// responseIDs reads n responses and returns their IDs in the order sent.
+	responseIDs := func(t *testing.T, conn *testConn, n int) string {
+		t.Helper()
+		var ids []string
+		for range n {
+			select {
+			case frame := <-conn.writer.frames:
+				var response Response
+				json.Unmarshal(frame[bytes.Index(frame, []byte("{")):], &response)
+				ids = append(ids, idString(response.ID))
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Timed out waiting for responses, got %v", ids)
+			}
+		}
+		return fmt.Sprint(ids)
+	}
+	deliverCalls := func(t *testing.T, conn *testConn) {
+		for i, params := range []string{
+			`{"queue":"a","delay":100}`,
+			`{"queue":"a"}`,
+			`{"queue":"b"}`,
+			`{"queue":"a"}`,
+		} {
+			conn.deliver(t, Request{JSONRPC: "2.0", ID: json.RawMessage(fmt.Sprint(i + 1)), Method: "slow", Params: json.RawMessage(params)})
+		}
+	}
+
+	t.Run("runs calls of a queue in arrival order", func(t *testing.T) {
+		conn := newTestConn(t, func(c *Client) {
+			c.SetRequestHandler("slow", slow)
+			c.SetRequestQueue(byQueueParam)
+		})
+		deliverCalls(t, conn)
+		if got := responseIDs(t, conn, 4); got != "[3 1 2 4]" {
+			t.Errorf("Expected queue b to answer first and queue a in order, got %s", got)
+		}
+		// A queue is released after its last call has been answered
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			conn.client.queueMu.Lock()
+			queues := len(conn.client.queues)
+			conn.client.queueMu.Unlock()
+			if queues == 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected the queues to be released, %d remain", queues)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	t.Run("runs calls concurrently without a queue", func(t *testing.T) {
+		conn := newTestConn(t, func(c *Client) { c.SetRequestHandler("slow", slow) })
+		deliverCalls(t, conn)
+		if got := responseIDs(t, conn, 4); !strings.HasSuffix(got, " 1]") {
+			t.Errorf("Expected the slow call to answer last, got %s", got)
+		}
+	})
+}
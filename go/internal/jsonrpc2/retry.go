@@ -0,0 +1,88 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// RetryPolicy re-sends requests to idempotent methods whose response did not
+// arrive within the request timeout. Each attempt is sent with a new request
+// ID, so a late response to an earlier attempt is discarded.
+type RetryPolicy struct {
+	// MaxAttempts is the most times a request is sent, including the first.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// Delay returns the wait before retry number n, counting from 1. Nil
+	// retries without waiting.
+	Delay func(n int) time.Duration
+	// Methods are the methods retried. Only list methods that are safe to
+	// call twice.
+	Methods []string
+}
+
+// SetRetryPolicy sets which timed-out requests are retried. By default none
+// are. It must be called before Start.
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	policy.Methods = slices.Clone(policy.Methods)
+	c.retry = policy
+}
+
+// retries returns how many times a request to method may be retried.
+func (c *Client) retries(method string) int {
+	if c.retry.MaxAttempts < 2 || !slices.Contains(c.retry.Methods, method) {
+		return 0
+	}
+	return c.retry.MaxAttempts - 1
+}
+
+// retryable reports whether a failed attempt may be retried: its own
+// request timeout expired while the caller's ctx is still live.
+func retryable(ctx context.Context, err error) bool {
+	return errors.Is(err, ErrRequestTimeout) && ctx.Err() == nil
+}
+
+// backoff waits before retry number n (from 1), and reports false if ctx is
+// done or the client stopped first.
+func (c *Client) backoff(ctx context.Context, n int) bool {
+	if c.retry.Delay == nil {
+		return true
+	}
+	wait := c.retry.Delay(n)
+	if wait <= 0 {
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-c.stopChan:
+		return false
+	}
+}
+
+// requestTimeoutKey is the context key of WithRequestTimeout.
+type requestTimeoutKey struct{}
+
+// WithRequestTimeout returns a copy of ctx whose requests wait timeout for
+// their response, instead of the client's request timeout, before failing
+// with ErrRequestTimeout. Zero or negative means no timeout. A deadline of
+// ctx itself still applies.
+func WithRequestTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, requestTimeoutKey{}, timeout)
+}
+
+// timeoutFor returns the timeout of a request sent with ctx.
+func (c *Client) timeoutFor(ctx context.Context) time.Duration {
+	if timeout, ok := ctx.Value(requestTimeoutKey{}).(time.Duration); ok {
+		return timeout
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return 0
+	}
+	return c.requestTimeout
+}
@@ -0,0 +1,122 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/sdklog"
+)
+
+// countingIDs returns a setup generating the request IDs req-1, req-2, ...
+func countingIDs(c *Client) {
+	var n atomic.Int32
+	c.SetIDGenerator(func() string { return fmt.Sprintf("req-%d", n.Add(1)) })
+	c.SetLogger(sdklog.New(io.Discard, false))
+}
+
+func response(id, result string) Response {
+	return Response{JSONRPC: "2.0", ID: json.RawMessage(`"` + id + `"`), Result: json.RawMessage(result)}
+}
+
+func TestClient_LateResponse(t *testing.T) {
+	conn := newTestConn(t, countingIDs)
+	conn.client.SetRequestTimeout(time.Minute)
+
+	// The server answers after the per-call timeout, then answers a second
+	// request in time.
+	go func() {
+		<-conn.writer.frames
+		time.Sleep(80 * time.Millisecond)
+		conn.deliver(t, response("req-1", `"late"`))
+		<-conn.writer.frames
+		conn.deliver(t, response("req-1", `"late again"`))
+		conn.deliver(t, response("req-2", `"on time"`))
+	}()
+
+	start := time.Now()
+	_, err := conn.client.RequestContext(WithRequestTimeout(t.Context(), 20*time.Millisecond), "status.get", nil)
+	if !errors.Is(err, ErrRequestTimeout) || time.Since(start) > time.Second {
+		t.Fatalf("Expected the per-call timeout, got %v after %v", err, time.Since(start))
+	}
+	if n := conn.client.PendingRequests(); n != 0 {
+		t.Errorf("Expected the timed-out request to be removed, got %d pending", n)
+	}
+
+	time.Sleep(120 * time.Millisecond)
+	result, err := conn.client.Request("status.get", nil)
+	if err != nil || string(result) != `"on time"` {
+		t.Fatalf("Expected the late responses to be discarded, got %s, %v", result, err)
+	}
+	if n := conn.client.ParseFailures(); n != 0 {
+		t.Errorf("Expected late responses not to count as parse failures, got %d", n)
+	}
+}
+
+func TestClient_WithRequestTimeoutDisables(t *testing.T) {
+	conn := newTestConn(t, countingIDs)
+	conn.client.SetRequestTimeout(20 * time.Millisecond)
+	go func() {
+		<-conn.writer.frames
+		time.Sleep(60 * time.Millisecond)
+		conn.deliver(t, response("req-1", `{}`))
+	}()
+
+	if _, err := conn.client.RequestContext(WithRequestTimeout(t.Context(), 0), "session.send", nil); err != nil {
+		t.Errorf("Expected no timeout, got %v", err)
+	}
+}
+
+func TestClient_RetryPolicy(t *testing.T) {
+	var calls []Call
+	conn := newTestConn(t, countingIDs, func(c *Client) {
+		c.SetRequestTimeout(30 * time.Millisecond)
+		c.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, Delay: func(n int) time.Duration { return 5 * time.Millisecond << (n - 1) }, Methods: []string{"ping"}})
+		c.SetCallObserver(func(call Call) { calls = append(calls, call) })
+	})
+
+	// The server drops the first two attempts, then answers them late, right
+	// before the third.
+	go func() {
+		<-conn.writer.frames
+		<-conn.writer.frames
+		<-conn.writer.frames
+		conn.deliver(t, response("req-1", `"first"`))
+		conn.deliver(t, response("req-2", `"second"`))
+		conn.deliver(t, response("req-3", `"third"`))
+	}()
+
+	result, err := conn.client.Request("ping", nil)
+	if err != nil || string(result) != `"third"` {
+		t.Fatalf("Expected the third attempt's response, got %s, %v", result, err)
+	}
+	if len(calls) != 3 || !errors.Is(calls[0].Err, ErrRequestTimeout) || calls[1].ID != "req-2" || calls[2].Err != nil {
+		t.Errorf("Expected each attempt to be observed, got %+v", calls)
+	}
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		calls = nil
+		go func() {
+			for range 3 {
+				<-conn.writer.frames
+			}
+		}()
+		_, err := conn.client.Request("ping", nil)
+		var callErr *CallError
+		if !errors.Is(err, ErrRequestTimeout) || !errors.As(err, &callErr) || callErr.RequestID != "req-6" || len(calls) != 3 {
+			t.Errorf("Expected the last attempt's timeout, got %v after %d calls", err, len(calls))
+		}
+	})
+
+	t.Run("does not retry other methods", func(t *testing.T) {
+		calls = nil
+		go func() { <-conn.writer.frames }()
+		if _, err := conn.client.Request("session.send", nil); !errors.Is(err, ErrRequestTimeout) || len(calls) != 1 {
+			t.Errorf("Expected a single timed-out attempt, got %v after %d calls", err, len(calls))
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package jsonrpc2
+
+import (
+	"fmt"
+	"sync"
+)
+
+// priorityFrameSize is the size up to which a frame is written ahead of the
+// larger frames queued before it, so small requests and responses are not
+// held up behind multi-megabyte ones such as session.create with big tool
+// schemas.
+const priorityFrameSize = 64 << 10
+
+// maxPriorityBurst is how many small frames are written in a row while a
+// large frame waits, so large frames are not starved either.
+const maxPriorityBurst = 32
+
+// outgoing is a frame waiting to be written.
+type outgoing struct {
+	frame []byte
+	plain []byte     // the uncompressed message, for the tracer
+	done  chan error // receives the result of the write
+}
+
+// writeQueue holds the frames waiting to be written. A writer goroutine is
+// started when frames are queued and exits once the queue is empty, so
+// senders never hold a lock while a frame is written.
+type writeQueue struct {
+	mu      sync.Mutex
+	small   []*outgoing
+	large   []*outgoing
+	burst   int  // small frames written since a large frame was queued
+	writing bool // a writer goroutine is running
+}
+
+// write queues a frame and waits until it is written or fails. Frames are
+// written whole, one at a time, small ones first.
+func (c *Client) write(frame, plain []byte) error {
+	out := &outgoing{frame: frame, plain: plain, done: make(chan error, 1)}
+	q := &c.writes
+	q.mu.Lock()
+	if c.prioritySize > 0 && len(frame) <= c.prioritySize {
+		q.small = append(q.small, out)
+	} else {
+		q.large = append(q.large, out)
+	}
+	if !q.writing {
+		q.writing = true
+		go c.writeLoop()
+	}
+	q.mu.Unlock()
+	return <-out.done
+}
+
+// writeLoop writes queued frames until the queue is empty.
+func (c *Client) writeLoop() {
+	for {
+		out := c.writes.next()
+		if out == nil {
+			return
+		}
+		out.done <- c.writeFrame(out)
+	}
+}
+
+// next removes and returns the frame to write next, or returns nil and
+// marks the writer as stopped if there is none.
+func (q *writeQueue) next() *outgoing {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out *outgoing
+	switch {
+	case len(q.small) > 0 && (len(q.large) == 0 || q.burst < maxPriorityBurst):
+		out = q.small[0]
+		q.small[0] = nil
+		q.small = q.small[1:]
+		if len(q.large) > 0 {
+			q.burst++
+		}
+	case len(q.large) > 0:
+		out = q.large[0]
+		q.large[0] = nil
+		q.large = q.large[1:]
+		q.burst = 0
+	default:
+		q.writing = false
+	}
+	return out
+}
+
+// writeFrame writes a frame to stdin. A failed write marks the connection as
+// lost, and any panic raised by the writer is converted into an error so
+// that transport failures never crash the host process.
+func (c *Client) writeFrame(out *outgoing) (err error) {
+	if c.writeClosed.Load() {
+		return ErrConnectionClosed
+	}
+	if c.tracer != nil {
+		c.tracer(false, out.plain)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: write panicked: %v", ErrConnectionClosed, r)
+			c.connectionLost(err)
+		}
+	}()
+	if _, err := c.stdin.Write(out.frame); err != nil {
+		err = fmt.Errorf("%w: failed to write message: %w", ErrConnectionClosed, err)
+		c.connectionLost(err)
+		return err
+	}
+	return nil
+}
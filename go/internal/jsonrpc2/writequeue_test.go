@@ -0,0 +1,225 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedWriter records the methods of the frames written to it. Writes block
+// until release is closed.
+type gatedWriter struct {
+	started chan struct{}
+	release chan struct{}
+	mu      sync.Mutex
+	methods []string
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	select {
+	case w.started <- struct{}{}:
+	default:
+	}
+	<-w.release
+	var message struct {
+		Method string `json:"method"`
+	}
+	_, body, _ := bytes.Cut(p, []byte("\r\n\r\n"))
+	if err := json.Unmarshal(body, &message); err != nil {
+		return 0, err
+	}
+	w.mu.Lock()
+	w.methods = append(w.methods, message.Method)
+	w.mu.Unlock()
+	return len(p), nil
+}
+
+func (w *gatedWriter) Close() error { return nil }
+
+func TestClient_WriteQueuePriority(t *testing.T) {
+	writer := &gatedWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+	client := NewClient(writer, nil)
+	large := strings.Repeat("x", priorityFrameSize)
+
+	var wg sync.WaitGroup
+	send := func(method, params string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.Notify(method, params); err != nil {
+				t.Errorf("Failed to send %s: %v", method, err)
+			}
+		}()
+	}
+	queued := func() int {
+		client.writes.mu.Lock()
+		defer client.writes.mu.Unlock()
+		return len(client.writes.small) + len(client.writes.large)
+	}
+
+	// The first large frame is being written while the rest queue up behind it
+	send("large-1", large)
+	<-writer.started
+	send("large-2", large)
+	for queued() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	for range maxPriorityBurst + 8 {
+		send("small", "ping")
+	}
+	for queued() < maxPriorityBurst+9 {
+		time.Sleep(time.Millisecond)
+	}
+	close(writer.release)
+	wg.Wait()
+
+	want := []string{"large-1"}
+	want = append(want, slices.Repeat([]string{"small"}, maxPriorityBurst)...)
+	want = append(want, "large-2")
+	want = append(want, slices.Repeat([]string{"small"}, 8)...)
+	if !slices.Equal(writer.methods, want) {
+		t.Errorf("Expected small frames first, then the waiting large frame after %d of them, got %v", maxPriorityBurst, writer.methods)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		client.writes.mu.Lock()
+		writing := client.writes.writing
+		client.writes.mu.Unlock()
+		if !writing {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the writer goroutine to exit once the queue is empty")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestClient_ConcurrentWrites(t *testing.T) {
+	sdk, cli, _, _ := newPeers(t)
+	cli.SetRequestHandler("echo", func(params json.RawMessage) (json.RawMessage, *Error) {
+		return params, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 4 {
+				size := 100
+				if (i+j)%4 == 0 {
+					size = 4 * priorityFrameSize
+				}
+				payload := fmt.Sprintf("%d-%d:%s", i, j, strings.Repeat("y", size))
+				result, err := sdk.Request("echo", payload)
+				if err != nil {
+					t.Errorf("Request %d-%d failed: %v", i, j, err)
+					return
+				}
+				var echoed string
+				if err := json.Unmarshal(result, &echoed); err != nil || echoed != payload {
+					t.Errorf("Request %d-%d got a different payload back", i, j)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := sdk.ParseFailures() + cli.ParseFailures(); n != 0 {
+		t.Errorf("Expected every frame intact, got %d parse failures", n)
+	}
+}
+
+// throttledConn writes at about 64 MiB/s, like a CLI reading its stdin
+// slower than the SDK writes it.
+type throttledConn struct {
+	net.Conn
+}
+
+func (c throttledConn) Write(p []byte) (int, error) {
+	const chunk = 64 << 10
+	written := 0
+	for written < len(p) {
+		n, err := c.Conn.Write(p[written:min(written+chunk, len(p))])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return written, nil
+}
+
+// BenchmarkClient_MixedTraffic sends small requests while large ones are
+// being written, and reports the latency of the small ones, with frames
+// written in order and with small frames first.
+func BenchmarkClient_MixedTraffic(b *testing.B) {
+	large := strings.Repeat("z", 1<<20)
+	for _, bench := range []struct {
+		name         string
+		prioritySize int
+	}{
+		{"in-order", 0},
+		{"prioritized", priorityFrameSize},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			a, c := net.Pipe()
+			sdk, cli := NewClient(throttledConn{a}, a), NewClient(c, c)
+			sdk.prioritySize = bench.prioritySize
+			cli.SetRequestHandler("echo", func(json.RawMessage) (json.RawMessage, *Error) {
+				return json.RawMessage(`{}`), nil
+			})
+			sdk.Start()
+			cli.Start()
+			b.Cleanup(func() {
+				a.Close()
+				c.Close()
+				sdk.Stop()
+				cli.Stop()
+			})
+
+			var mu sync.Mutex
+			var latencies []time.Duration
+			b.ResetTimer()
+			for range b.N {
+				var wg sync.WaitGroup
+				for range 2 {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						sdk.Request("echo", large)
+					}()
+				}
+				time.Sleep(time.Millisecond)
+				for range 8 {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						start := time.Now()
+						if _, err := sdk.Request("echo", "small"); err != nil {
+							b.Error(err)
+						}
+						mu.Lock()
+						latencies = append(latencies, time.Since(start))
+						mu.Unlock()
+					}()
+				}
+				wg.Wait()
+			}
+			b.StopTimer()
+
+			slices.Sort(latencies)
+			percentile := func(p float64) float64 {
+				return float64(latencies[int(p*float64(len(latencies)-1))]) / float64(time.Millisecond)
+			}
+			b.ReportMetric(percentile(0.5), "small-p50-ms")
+			b.ReportMetric(percentile(0.99), "small-p99-ms")
+		})
+	}
+}
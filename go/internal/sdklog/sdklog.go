@@ -0,0 +1,184 @@
+// Package sdklog writes the SDK's own diagnostic messages, such as recovered
+// handler panics and discarded JSON-RPC messages, as structured log records,
+// either to a caller's *slog.Logger or to a single writer.
+package sdklog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Logger writes diagnostic messages with attributes given as alternating
+// keys and values, like [slog.Logger]. A nil *Logger writes text to
+// os.Stderr.
+type Logger struct {
+	slog *slog.Logger
+}
+
+// stderr is used by nil loggers.
+var stderr = New(nil, false)
+
+// New returns a logger writing one line per message to w, or to os.Stderr
+// if w is nil. Lines are text, "2006/01/02 15:04:05 copilot: msg key=value",
+// or with machineReadable single-line JSON objects with "time", "level",
+// "source" and "msg" fields followed by the attributes. Debug messages are
+// dropped.
+func New(w io.Writer, machineReadable bool) *Logger {
+	if w == nil {
+		w = os.Stderr
+	}
+	return &Logger{slog: slog.New(&writerHandler{out: &output{w: w}, json: machineReadable})}
+}
+
+// FromSlog returns a logger writing to l, with a "source" attribute of
+// "copilot-sdk" on every message.
+func FromSlog(l *slog.Logger) *Logger {
+	return &Logger{slog: l.With("source", "copilot-sdk")}
+}
+
+// Debug logs a message at debug level.
+func (l *Logger) Debug(msg string, args ...any) { l.log(slog.LevelDebug, msg, args) }
+
+// Info logs a message at info level.
+func (l *Logger) Info(msg string, args ...any) { l.log(slog.LevelInfo, msg, args) }
+
+// Warn logs a message at warning level.
+func (l *Logger) Warn(msg string, args ...any) { l.log(slog.LevelWarn, msg, args) }
+
+// Error logs a message at error level.
+func (l *Logger) Error(msg string, args ...any) { l.log(slog.LevelError, msg, args) }
+
+func (l *Logger) log(level slog.Level, msg string, args []any) {
+	if l == nil {
+		l = stderr
+	}
+	l.slog.Log(context.Background(), level, msg, args...)
+}
+
+// output is a writer shared by a handler and those derived from it.
+type output struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// writerHandler formats records as text or JSON lines. Write errors are
+// ignored.
+type writerHandler struct {
+	out    *output
+	json   bool
+	attrs  []slog.Attr
+	prefix string // group prefix for attribute keys
+}
+
+func (h *writerHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo
+}
+
+func (h *writerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = slices.Clone(h.attrs)
+	for _, attr := range attrs {
+		attr.Key = h.prefix + attr.Key
+		clone.attrs = append(clone.attrs, attr)
+	}
+	return &clone
+}
+
+func (h *writerHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+func (h *writerHandler) Handle(_ context.Context, record slog.Record) error {
+	attrs := slices.Clone(h.attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		attr.Key = h.prefix + attr.Key
+		attrs = append(attrs, attr)
+		return true
+	})
+	msg := strings.TrimRight(record.Message, "\n")
+
+	var line []byte
+	if h.json {
+		line, _ = json.Marshal(record.Time)
+		line = append([]byte(`{"time":`), line...)
+		line = appendJSONField(line, "level", record.Level.String())
+		line = appendJSONField(line, "source", "copilot-sdk")
+		line = appendJSONField(line, "msg", msg)
+		for _, attr := range attrs {
+			line = appendJSONField(line, attr.Key, jsonValue(attr.Value))
+		}
+		line = append(line, '}')
+	} else {
+		line = []byte(record.Time.Format("2006/01/02 15:04:05") + " copilot: " + msg)
+		for _, attr := range attrs {
+			line = append(line, ' ')
+			line = append(line, attr.Key...)
+			line = append(line, '=')
+			line = append(line, textValue(attr.Value)...)
+		}
+	}
+	line = append(line, '\n')
+
+	h.out.mu.Lock()
+	defer h.out.mu.Unlock()
+	h.out.w.Write(line)
+	return nil
+}
+
+func appendJSONField(line []byte, key string, value any) []byte {
+	k, _ := json.Marshal(key)
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	line = append(line, ',')
+	line = append(line, k...)
+	line = append(line, ':')
+	return append(line, v...)
+}
+
+// jsonValue returns the value of an attribute to encode as JSON: errors and
+// durations as strings, and other values as they are.
+func jsonValue(value slog.Value) any {
+	value = value.Resolve()
+	switch value.Kind() {
+	case slog.KindDuration:
+		return value.Duration().String()
+	case slog.KindAny:
+		switch v := value.Any().(type) {
+		case error:
+			return v.Error()
+		case fmt.Stringer:
+			return v.String()
+		}
+	}
+	return value.Any()
+}
+
+// textValue formats the value of an attribute, quoting it if it is empty or
+// contains spaces, quotes, '=' or control characters.
+func textValue(value slog.Value) string {
+	value = value.Resolve()
+	var s string
+	switch value.Kind() {
+	case slog.KindTime:
+		s = value.Time().Format(time.RFC3339Nano)
+	default:
+		s = value.String()
+	}
+	if s == "" || strings.ContainsFunc(s, func(r rune) bool { return r <= ' ' || r == '"' || r == '=' || r == 0x7f }) {
+		return strconv.Quote(s)
+	}
+	return s
+}
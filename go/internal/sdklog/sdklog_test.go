@@ -0,0 +1,60 @@
+package sdklog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	t.Run("writes text lines", func(t *testing.T) {
+		var buf bytes.Buffer
+		New(&buf, false).Warn("discarding message\n", "error", errors.New("bad frame"), "bytes", 12)
+		if out := buf.String(); !strings.HasSuffix(out, ` copilot: discarding message error="bad frame" bytes=12`+"\n") || strings.Count(out, "\n") != 1 {
+			t.Errorf("Unexpected output %q", out)
+		}
+	})
+
+	t.Run("writes single-line JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		New(&buf, true).Error("handler panicked", "panic", "line one\nline two", "duration", time.Second)
+		if strings.Count(buf.String(), "\n") != 1 {
+			t.Fatalf("Expected a single line, got %q", buf.String())
+		}
+		if !strings.HasPrefix(buf.String(), `{"time":`) {
+			t.Errorf("Expected the time first, got %q", buf.String())
+		}
+		var entry map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("Expected JSON, got %q: %v", buf.String(), err)
+		}
+		if entry["msg"] != "handler panicked" || entry["source"] != "copilot-sdk" || entry["level"] != "ERROR" || entry["time"] == nil ||
+			entry["panic"] != "line one\nline two" || entry["duration"] != "1s" {
+			t.Errorf("Unexpected entry %v", entry)
+		}
+	})
+
+	t.Run("drops debug messages", func(t *testing.T) {
+		var buf bytes.Buffer
+		New(&buf, false).Debug("noise")
+		if buf.Len() != 0 {
+			t.Errorf("Expected no output, got %q", buf.String())
+		}
+	})
+
+	t.Run("writes to a slog logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		FromSlog(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))).Debug("noise", "sessionID", "s1")
+		var entry map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("Expected JSON, got %q: %v", buf.String(), err)
+		}
+		if entry["msg"] != "noise" || entry["level"] != "DEBUG" || entry["source"] != "copilot-sdk" || entry["sessionID"] != "s1" {
+			t.Errorf("Unexpected entry %v", entry)
+		}
+	})
+}
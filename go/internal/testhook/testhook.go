@@ -0,0 +1,8 @@
+// Package testhook gives the copilottest package access to unexported parts
+// of the copilot package, which sets the hooks when it is initialized.
+package testhook
+
+// DispatchEvent delivers a copilot.SessionEvent to a *copilot.Session's
+// handlers as if the CLI had sent it. The arguments are untyped because this
+// package cannot import the copilot package.
+var DispatchEvent func(session, event any)
@@ -0,0 +1,60 @@
+// Package timestamp parses the timestamps the CLI sends, which may be RFC
+// 3339 strings or Unix times in seconds or milliseconds.
+package timestamp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// unixMillisThreshold separates Unix times in seconds from Unix times in
+// milliseconds. 1e11 seconds is in the year 5138, while 1e11 milliseconds is
+// in 1973, so any plausible timestamp at or above it is in milliseconds.
+const unixMillisThreshold = 1e11
+
+// Parse converts a timestamp sent by the CLI into a time.Time.
+//
+// RFC 3339 strings and numeric Unix times are accepted. Numbers are read as
+// milliseconds when at or above unixMillisThreshold and as seconds otherwise.
+// Missing, null, and zero values yield the zero time.
+func Parse(raw json.RawMessage) (time.Time, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return time.Time{}, nil
+	}
+
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %s: %w", raw, err)
+		}
+		if s == "" {
+			return time.Time{}, nil
+		}
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t, nil
+		}
+		// Some producers quote numeric timestamps
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q: expected RFC 3339 or Unix time", s)
+		}
+		raw = []byte(s)
+	}
+
+	value, err := strconv.ParseFloat(string(raw), 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %s: expected RFC 3339 or Unix time", raw)
+	}
+	if value == 0 {
+		return time.Time{}, nil
+	}
+	if math.Abs(value) >= unixMillisThreshold {
+		return time.UnixMilli(0).Add(time.Duration(value * float64(time.Millisecond))), nil
+	}
+	sec, frac := math.Modf(value)
+	return time.Unix(int64(sec), int64(frac*1e9)), nil
+}
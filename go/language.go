@@ -0,0 +1,60 @@
+package copilot
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// languageTagPattern matches well-formed BCP 47 language tags: a language
+// with optional extended subtags, script, region, variants, extensions and
+// private use subtags, or a private use tag on its own. Primary language
+// subtags of 4-8 letters are reserved or unused, so they are rejected to
+// catch language names such as "german".
+var languageTagPattern = regexp.MustCompile(`(?i)^(?:` +
+	`[a-z]{2,3}(?:-[a-z]{3}){0,3}` +
+	`(?:-[a-z]{4})?` +
+	`(?:-(?:[a-z]{2}|[0-9]{3}))?` +
+	`(?:-(?:[a-z0-9]{5,8}|[0-9][a-z0-9]{3}))*` +
+	`(?:-[0-9a-wyz](?:-[a-z0-9]{2,8})+)*` +
+	`(?:-x(?:-[a-z0-9]{1,8})+)?` +
+	`|x(?:-[a-z0-9]{1,8})+)$`)
+
+// validateLanguageTag checks the format of [SessionConfig.ResponseLanguage]
+// and [MessageOptions.ResponseLanguage] values.
+func validateLanguageTag(field, value string) error {
+	if !languageTagPattern.MatchString(value) {
+		return fmt.Errorf("invalid %s %q: must be a BCP 47 language tag such as \"de\" or \"pt-BR\"", field, value)
+	}
+	return nil
+}
+
+// responseLanguageInstruction is the system message section asking the model
+// to answer in a session's response language.
+func responseLanguageInstruction(tag string) string {
+	return fmt.Sprintf("Respond in the language identified by the BCP 47 tag %q, whatever language the user writes in, unless a message asks for a different language.", tag)
+}
+
+// withResponseLanguage returns systemMessage with the response language
+// instruction appended, leaving the caller's config unchanged. An empty tag
+// returns systemMessage as is.
+func withResponseLanguage(systemMessage *SystemMessageConfig, tag string) *SystemMessageConfig {
+	if tag == "" {
+		return systemMessage
+	}
+	result := SystemMessageConfig{Mode: "append"}
+	if systemMessage != nil {
+		result = *systemMessage
+	}
+	if result.Content != "" {
+		result.Content += "\n\n"
+	}
+	result.Content += responseLanguageInstruction(tag)
+	return &result
+}
+
+// promptWithResponseLanguage prefixes a prompt with a request to answer it in
+// the language identified by tag, for a [MessageOptions.ResponseLanguage]
+// that differs from the session's.
+func promptWithResponseLanguage(prompt, tag string) string {
+	return fmt.Sprintf("[Respond to this message in the language identified by the BCP 47 tag %q.]\n\n%s", tag, prompt)
+}
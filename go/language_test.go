@@ -0,0 +1,150 @@
+package copilot
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestValidateLanguageTag(t *testing.T) {
+	for _, tag := range []string{"de", "pt-BR", "zh-Hant-TW", "es-419", "sl-rozaj-biske", "en-US-x-twain", "x-whatever", "gsw", "DE-at"} {
+		if err := validateLanguageTag("ResponseLanguage", tag); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", tag, err)
+		}
+	}
+	for _, tag := range []string{"", "german", "de_DE", "de-", "e", "en-US-", "answer in German", "d3"} {
+		if err := validateLanguageTag("ResponseLanguage", tag); err == nil {
+			t.Errorf("Expected %q to be invalid", tag)
+		}
+	}
+}
+
+func TestSession_ResponseLanguage(t *testing.T) {
+	var mu sync.Mutex
+	var created []createSessionRequest
+	var resumed []resumeSessionRequest
+	var sent []sessionSendRequest
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch method {
+		case "session.create":
+			var req createSessionRequest
+			json.Unmarshal(params, &req)
+			created = append(created, req)
+			return createSessionResponse{SessionID: req.SessionID}, nil
+		case "session.resume":
+			var req resumeSessionRequest
+			json.Unmarshal(params, &req)
+			resumed = append(resumed, req)
+			return resumeSessionResponse{SessionID: req.SessionID}, nil
+		case "session.send":
+			var req sessionSendRequest
+			json.Unmarshal(params, &req)
+			sent = append(sent, req)
+			return sessionSendResponse{MessageID: "m1"}, nil
+		}
+		return nil, nil
+	})
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	lastPrompt := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return sent[len(sent)-1].Prompt
+	}
+
+	session, err := client.CreateSession(t.Context(), &SessionConfig{
+		SessionID:           "s1",
+		OnPermissionRequest: PermissionHandler.ApproveAll,
+		ResponseLanguage:    "de",
+		SystemMessage:       &SystemMessageConfig{Content: "You are terse."},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	t.Run("adds the instruction to the system message once", func(t *testing.T) {
+		mu.Lock()
+		systemMessage := created[0].SystemMessage
+		mu.Unlock()
+		want := "You are terse.\n\n" + responseLanguageInstruction("de")
+		if systemMessage == nil || systemMessage.Content != want {
+			t.Errorf("Expected system message %q, got %+v", want, systemMessage)
+		}
+		if session.Config().ResponseLanguage != "de" {
+			t.Errorf("Expected ResponseLanguage de, got %q", session.Config().ResponseLanguage)
+		}
+
+		for range 2 {
+			if _, err := session.Send(t.Context(), MessageOptions{Prompt: "Hallo"}); err != nil {
+				t.Fatalf("Failed to send: %v", err)
+			}
+			if got := lastPrompt(); got != "Hallo" {
+				t.Errorf("Expected the prompt to be sent unchanged, got %q", got)
+			}
+		}
+	})
+
+	t.Run("overrides the language for one message", func(t *testing.T) {
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "Bonjour", ResponseLanguage: "fr"}); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		if got := lastPrompt(); !strings.HasSuffix(got, "\n\nBonjour") || !strings.Contains(got, `"fr"`) {
+			t.Errorf("Expected a French request before the prompt, got %q", got)
+		}
+
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "Hallo", ResponseLanguage: "DE"}); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		if got := lastPrompt(); got != "Hallo" {
+			t.Errorf("Expected no request for the session's own language, got %q", got)
+		}
+	})
+
+	t.Run("rejects invalid tags", func(t *testing.T) {
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "Hi", ResponseLanguage: "German"}); err == nil {
+			t.Error("Expected an error for an invalid message language")
+		}
+		_, err := client.CreateSession(t.Context(), &SessionConfig{
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			ResponseLanguage:    "de_DE",
+		})
+		if err == nil || !strings.Contains(err.Error(), "BCP 47") {
+			t.Errorf("Expected an invalid ResponseLanguage error, got %v", err)
+		}
+	})
+
+	t.Run("keeps the language when resumed", func(t *testing.T) {
+		resumedSession, err := client.ResumeSession(t.Context(), "s1", &ResumeSessionConfig{
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+		})
+		if err != nil {
+			t.Fatalf("Failed to resume: %v", err)
+		}
+		mu.Lock()
+		systemMessage := resumed[0].SystemMessage
+		mu.Unlock()
+		if systemMessage == nil || systemMessage.Content != responseLanguageInstruction("de") || systemMessage.Mode != "append" {
+			t.Errorf("Expected the language instruction on resume, got %+v", systemMessage)
+		}
+		if resumedSession.Config().ResponseLanguage != "de" {
+			t.Errorf("Expected ResponseLanguage de, got %q", resumedSession.Config().ResponseLanguage)
+		}
+
+		resumedSession, err = client.ResumeSession(t.Context(), "s1", &ResumeSessionConfig{
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			ResponseLanguage:    "ja",
+		})
+		if err != nil {
+			t.Fatalf("Failed to resume: %v", err)
+		}
+		if resumedSession.Config().ResponseLanguage != "ja" {
+			t.Errorf("Expected ResponseLanguage ja, got %q", resumedSession.Config().ResponseLanguage)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package copilot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logOutput is a ClientOptions.LogOutput that tests can wait on.
+type logOutput struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (o *logOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.Write(p)
+}
+
+// waitFor waits until the output contains substr and returns it.
+func (o *logOutput) waitFor(t *testing.T, substr string) string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		o.mu.Lock()
+		output := o.buf.String()
+		o.mu.Unlock()
+		if strings.Contains(output, substr) {
+			return output
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %q in the log, got %q", substr, output)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// entries decodes the lines written with ClientOptions.MachineReadableLogs.
+func (o *logOutput) entries(t *testing.T) []map[string]any {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSuffix(o.buf.String(), "\n"), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Expected a JSON line, got %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// logEntry is a line written with ClientOptions.MachineReadableLogs.
+type logEntry struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Msg    string    `json:"msg"`
+}
+
+func TestClientOptions_LogOutput(t *testing.T) {
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		if method == "session.create" {
+			var req createSessionRequest
+			json.Unmarshal(params, &req)
+			return createSessionResponse{SessionID: req.SessionID}, nil
+		}
+		return nil, nil
+	})
+	var out logOutput
+	client := NewClient(&ClientOptions{
+		CLIUrl:              cli.addr(),
+		LogOutput:           &out,
+		MachineReadableLogs: true,
+		MaxMessageBytes:     4096,
+	})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	session, err := client.CreateSession(t.Context(), &SessionConfig{
+		SessionID:           "logged",
+		OnPermissionRequest: PermissionHandler.ApproveAll,
+		SlowHandlers: &SlowHandlerOptions{
+			Threshold:     time.Nanosecond,
+			OnSlowHandler: func(SlowHandlerCall) { panic("callback failed") },
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	t.Run("reports handler panics", func(t *testing.T) {
+		unsubscribe := session.On(func(SessionEvent) { panic("handler failed") })
+		defer unsubscribe()
+		cli.emitTo("logged", SessionEvent{Type: SessionIdle, Timestamp: time.Now()})
+		out.waitFor(t, `"msg":"event handler panicked","sessionID":"logged","eventType":"session.idle"`)
+		out.waitFor(t, `"msg":"slow handler callback panicked","sessionID":"logged","panic":"callback failed"`)
+		for _, entry := range out.entries(t) {
+			if strings.HasSuffix(entry["msg"].(string), "panicked") && !strings.Contains(fmt.Sprint(entry["stack"]), "logging_test.go") {
+				t.Errorf("Expected a stack trace through the panicking handler, got %v", entry)
+			}
+		}
+	})
+
+	t.Run("reports discarded messages", func(t *testing.T) {
+		cli.emitTo("logged", SessionEvent{Type: AssistantMessage, Timestamp: time.Now(), Data: Data{Content: String(strings.Repeat("x", 8192))}})
+		out.waitFor(t, `"msg":"discarding oversized message","error":"message of`)
+	})
+
+	t.Run("writes each message as a JSON line", func(t *testing.T) {
+		out.mu.Lock()
+		output := out.buf.String()
+		out.mu.Unlock()
+		lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+		if len(lines) < 3 {
+			t.Fatalf("Expected at least 3 lines, got %q", output)
+		}
+		for _, line := range lines {
+			var entry logEntry
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("Expected a JSON line, got %q: %v", line, err)
+			}
+			if entry.Source != "copilot-sdk" || entry.Time.IsZero() || entry.Msg == "" {
+				t.Errorf("Unexpected log entry %+v", entry)
+			}
+		}
+	})
+
+	t.Run("writes text by default", func(t *testing.T) {
+		var out logOutput
+		client := NewClient(&ClientOptions{LogOutput: &out})
+		client.abandonedTools.Store(maxAbandonedToolCalls)
+		client.executeToolCallWithTimeout(nil, ToolInvocation{ToolName: "build"}, nil, time.Second)
+		output := out.waitFor(t, `tool=build`)
+		if !strings.Contains(output, " copilot: not running tool") || strings.Contains(output, "{") {
+			t.Errorf("Expected a text line, got %q", output)
+		}
+	})
+}
+
+// recordingHandler is a slog.Handler keeping the records it handles.
+type recordingHandler struct {
+	mu      sync.Mutex
+	attrs   []slog.Attr
+	records *[]slog.Record
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordingHandler) Handle(_ context.Context, record slog.Record) error {
+	record.AddAttrs(h.attrs...)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.records = append(*h.records, record)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &recordingHandler{attrs: append(append([]slog.Attr(nil), h.attrs...), attrs...), records: h.records}
+}
+
+func (h *recordingHandler) WithGroup(string) slog.Handler { return h }
+
+func TestClientOptions_Logger(t *testing.T) {
+	var records []slog.Record
+	var out logOutput
+	client := NewClient(&ClientOptions{
+		Logger:    slog.New(&recordingHandler{records: &records}),
+		LogOutput: &out,
+	})
+	session, _ := newTestSession(t, nil)
+	session.logger = client.logger
+	session.On(func(SessionEvent) { panic("handler failed") })
+	session.dispatchEvent(SessionEvent{Type: SessionIdle, ID: "event-1"})
+
+	if len(records) != 1 {
+		t.Fatalf("Expected one record, got %d", len(records))
+	}
+	record := records[0]
+	attrs := map[string]string{}
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs[attr.Key] = attr.Value.String()
+		return true
+	})
+	if record.Level != slog.LevelError || record.Message != "event handler panicked" {
+		t.Errorf("Unexpected record %v %q", record.Level, record.Message)
+	}
+	if attrs["sessionID"] != "test-session" || attrs["eventType"] != "session.idle" || attrs["eventID"] != "event-1" ||
+		attrs["panic"] != "handler failed" || attrs["source"] != "copilot-sdk" || !strings.Contains(attrs["stack"], "logging_test.go") {
+		t.Errorf("Unexpected attributes %v", attrs)
+	}
+	if out.buf.Len() != 0 {
+		t.Errorf("Expected Logger to take precedence over LogOutput, got %q", out.buf.String())
+	}
+}
@@ -0,0 +1,84 @@
+package copilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// TypedMCPServerConfig is an MCP server config with typed fields, an
+// [MCPLocalServerConfig] or an [MCPRemoteServerConfig]. It replaces the
+// map-based [MCPServerConfig] in [SessionConfig.TypedMCPServers] and
+// [ResumeSessionConfig.TypedMCPServers].
+type TypedMCPServerConfig interface {
+	// mcpServerConfig returns the config as sent to the CLI
+	mcpServerConfig() MCPServerConfig
+}
+
+func (c MCPLocalServerConfig) mcpServerConfig() MCPServerConfig {
+	return toMCPServerConfig(c)
+}
+
+func (c MCPRemoteServerConfig) mcpServerConfig() MCPServerConfig {
+	return toMCPServerConfig(c)
+}
+
+// toMCPServerConfig converts a typed config to its map form, keyed by the
+// JSON names the CLI expects.
+func toMCPServerConfig(config any) MCPServerConfig {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil
+	}
+	var m MCPServerConfig
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+// toTypedMCPServerConfig converts a config in map form to the typed config
+// for its type: remote for http and sse, and local otherwise. Keys the typed
+// config lacks are an error.
+func toTypedMCPServerConfig(config MCPServerConfig) (TypedMCPServerConfig, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if isRemoteMCPServer(config) {
+		var remote MCPRemoteServerConfig
+		if err := decoder.Decode(&remote); err != nil {
+			return nil, err
+		}
+		return remote, nil
+	}
+	var local MCPLocalServerConfig
+	if err := decoder.Decode(&local); err != nil {
+		return nil, err
+	}
+	return local, nil
+}
+
+// mergeMCPServers combines the map-based and typed MCP servers of a session
+// config into the servers sent to the CLI.
+func mergeMCPServers(servers map[string]MCPServerConfig, typed map[string]TypedMCPServerConfig) (map[string]MCPServerConfig, error) {
+	if len(typed) == 0 {
+		return servers, nil
+	}
+	merged := make(map[string]MCPServerConfig, len(servers)+len(typed))
+	for name, config := range servers {
+		merged[name] = config
+	}
+	for name, config := range typed {
+		if _, ok := merged[name]; ok {
+			return nil, fmt.Errorf("MCP server %q is configured in both MCPServers and TypedMCPServers", name)
+		}
+		if config == nil {
+			return nil, fmt.Errorf("MCP server %q has no config", name)
+		}
+		merged[name] = config.mcpServerConfig()
+	}
+	return merged, nil
+}
@@ -0,0 +1,120 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+// ErrUnsupportedByCLI matches errors from [Session.Send] when a message uses
+// an option the connected CLI does not support, such as
+// [MessageOptions.Model] or [MessageOptions.InlineAttachments]. The message
+// is not sent.
+var ErrUnsupportedByCLI = errors.New("not supported by the connected CLI")
+
+// UnknownModelError is returned by [Session.Send] when the CLI rejects
+// [MessageOptions.Model] and the model is not among those
+// [Client.ListModels] returns.
+//
+// Use [errors.As] to detect it:
+//
+//	var unknownErr *copilot.UnknownModelError
+//	if errors.As(err, &unknownErr) {
+//	    log.Printf("Pick one of %v", unknownErr.Available)
+//	}
+type UnknownModelError struct {
+	// Model is the ID the message asked for
+	Model string
+	// Available lists the IDs of the models the client can use
+	Available []string
+
+	err error
+}
+
+func (e *UnknownModelError) Error() string {
+	return fmt.Sprintf("unknown model %q; available models: %s", e.Model, strings.Join(e.Available, ", "))
+}
+
+func (e *UnknownModelError) Unwrap() error {
+	return e.err
+}
+
+// supportsMessageModel reports whether the connected CLI accepts a model in
+// session.send. Older CLIs ignore parameters they do not know, so the CLI
+// must report the capability.
+func (s *Session) supportsMessageModel() bool {
+	if s.capabilities == nil {
+		return false
+	}
+	capabilities := s.capabilities()
+	return capabilities != nil && capabilities.MessageModel != nil && *capabilities.MessageModel
+}
+
+// checkMessageModel fails if a message overrides the model on a CLI that
+// would ignore the override.
+func (s *Session) checkMessageModel(options MessageOptions) error {
+	if options.Model == "" || s.supportsMessageModel() {
+		return nil
+	}
+	return fmt.Errorf("%w: MessageOptions.Model needs a CLI that accepts a model per message", ErrUnsupportedByCLI)
+}
+
+// isModelRejection reports whether err is the CLI rejecting a message's
+// model, as unknown or unavailable.
+func isModelRejection(err error) bool {
+	var unavailableErr *ModelUnavailableError
+	if errors.As(err, &unavailableErr) {
+		return true
+	}
+	var rpcErr *jsonrpc2.Error
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	code, _ := rpcErr.Data["code"].(string)
+	message := strings.ToLower(rpcErr.Message)
+	return code == "model_not_found" || code == "unknown_model" ||
+		strings.Contains(message, "unknown model") ||
+		(strings.Contains(message, "model") && strings.Contains(message, "not found"))
+}
+
+// explainModelRejection returns an *[UnknownModelError] wrapping err if the
+// CLI rejected model and the model is not listed by the client. Otherwise,
+// including when the models cannot be listed, it returns err.
+func (s *Session) explainModelRejection(ctx context.Context, model string, err error) error {
+	if !isModelRejection(err) || s.listModels == nil {
+		return err
+	}
+	models, listErr := s.listModels(ctx)
+	if listErr != nil {
+		return err
+	}
+	available := make([]string, 0, len(models))
+	for _, m := range models {
+		if m.ID == model {
+			return err
+		}
+		available = append(available, m.ID)
+	}
+	return &UnknownModelError{Model: model, Available: available, err: err}
+}
+
+// modelInfo looks up a model by ID. It returns nil if the model is not
+// listed.
+func (s *Session) modelInfo(ctx context.Context, id string) (*ModelInfo, error) {
+	if s.listModels == nil {
+		return nil, nil
+	}
+	models, err := s.listModels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	for i := range models {
+		if models[i].ID == id {
+			return &models[i], nil
+		}
+	}
+	return nil, nil
+}
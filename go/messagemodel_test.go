@@ -0,0 +1,112 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+func TestSession_SendModel(t *testing.T) {
+	models := []ModelInfo{
+		{ID: "gpt-5", Capabilities: ModelCapabilities{Limits: ModelLimits{MaxContextWindowTokens: 200000}}},
+		{ID: "gpt-5-mini", Capabilities: ModelCapabilities{Limits: ModelLimits{MaxContextWindowTokens: 100}}},
+	}
+	newModelSession := func(t *testing.T, supported *bool, rejected map[string]*jsonrpc2.Error) (*Session, func() []sessionSendRequest) {
+		var mu sync.Mutex
+		var sent []sessionSendRequest
+		session, _ := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method != "session.send" {
+				return nil, nil
+			}
+			var req sessionSendRequest
+			json.Unmarshal(params, &req)
+			mu.Lock()
+			sent = append(sent, req)
+			mu.Unlock()
+			if err := rejected[req.Model]; err != nil {
+				return nil, err
+			}
+			return sessionSendResponse{MessageID: "m1"}, nil
+		})
+		session.listModels = func(context.Context) ([]ModelInfo, error) { return models, nil }
+		session.capabilities = func() *ServerCapabilities { return &ServerCapabilities{MessageModel: supported} }
+		return session, func() []sessionSendRequest {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]sessionSendRequest(nil), sent...)
+		}
+	}
+
+	t.Run("forwards the model for one message", func(t *testing.T) {
+		session, sent := newModelSession(t, Bool(true), nil)
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "Classify this", Model: "gpt-5-mini"}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "Write it"}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		if got := sent(); len(got) != 2 || got[0].Model != "gpt-5-mini" || got[1].Model != "" {
+			t.Errorf("Expected the model on the first message only, got %+v", got)
+		}
+	})
+
+	t.Run("fails on CLIs that would drop the model", func(t *testing.T) {
+		for _, supported := range []*bool{nil, Bool(false)} {
+			session, sent := newModelSession(t, supported, nil)
+			_, err := session.Send(t.Context(), MessageOptions{Prompt: "Classify this", Model: "gpt-5-mini"})
+			if !errors.Is(err, ErrUnsupportedByCLI) {
+				t.Errorf("Expected ErrUnsupportedByCLI, got %v", err)
+			}
+			if len(sent()) != 0 {
+				t.Error("Expected nothing to be sent")
+			}
+		}
+	})
+
+	t.Run("lists the available models when the model is unknown", func(t *testing.T) {
+		rejection := &jsonrpc2.Error{Code: -32000, Message: "Unknown model: gpt-6", Data: map[string]any{"code": "model_not_found"}}
+		session, _ := newModelSession(t, Bool(true), map[string]*jsonrpc2.Error{"gpt-6": rejection})
+		_, err := session.Send(t.Context(), MessageOptions{Prompt: "Classify this", Model: "gpt-6"})
+		var unknownErr *UnknownModelError
+		if !errors.As(err, &unknownErr) {
+			t.Fatalf("Expected an UnknownModelError, got %v", err)
+		}
+		if unknownErr.Model != "gpt-6" || !slices.Equal(unknownErr.Available, []string{"gpt-5", "gpt-5-mini"}) {
+			t.Errorf("Unexpected error %+v", unknownErr)
+		}
+		var rpcErr *jsonrpc2.Error
+		if !errors.As(err, &rpcErr) || rpcErr.Message != rejection.Message {
+			t.Errorf("Expected the CLI's error to be wrapped, got %v", err)
+		}
+	})
+
+	t.Run("keeps the CLI's error for listed models", func(t *testing.T) {
+		rejection := &jsonrpc2.Error{Code: -32000, Message: "Model gpt-5 is not available", Data: map[string]any{"code": "model_not_available"}}
+		session, _ := newModelSession(t, Bool(true), map[string]*jsonrpc2.Error{"gpt-5": rejection})
+		_, err := session.Send(t.Context(), MessageOptions{Prompt: "Write it", Model: "gpt-5"})
+		var unavailableErr *ModelUnavailableError
+		var unknownErr *UnknownModelError
+		if !errors.As(err, &unavailableErr) || errors.As(err, &unknownErr) {
+			t.Errorf("Expected a ModelUnavailableError, got %v", err)
+		}
+	})
+
+	t.Run("checks the prompt against the message's model", func(t *testing.T) {
+		session, sent := newModelSession(t, Bool(true), nil)
+		session.config.setModel("gpt-5")
+		_, err := session.Send(t.Context(), MessageOptions{Prompt: strings.Repeat("word ", 1000), Model: "gpt-5-mini"})
+		var tooLarge *PromptTooLargeError
+		if !errors.As(err, &tooLarge) || tooLarge.Model != "gpt-5-mini" {
+			t.Errorf("Expected a PromptTooLargeError for gpt-5-mini, got %v", err)
+		}
+		if len(sent()) != 0 {
+			t.Error("Expected nothing to be sent")
+		}
+	})
+}
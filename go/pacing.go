@@ -0,0 +1,225 @@
+package copilot
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// pacingMinBackoff is the initial backoff after a rate limit without a retry-after hint.
+	pacingMinBackoff = time.Second
+	// pacingMaxBackoff caps the adaptive backoff after repeated rate limits.
+	pacingMaxBackoff = time.Minute
+)
+
+// PacingOptions configures client-side pacing of [Session.Send] calls.
+//
+// When set on [ClientOptions], sends from all sessions of the client are delayed
+// to stay under the configured limits. Rate limit errors reported by the CLI
+// pause all sends for the server's retry-after period, or an exponentially
+// increasing backoff when none is given.
+type PacingOptions struct {
+	// MaxSendsPerMinute limits sends across all sessions in any rolling minute.
+	// Zero means unlimited.
+	MaxSendsPerMinute int
+	// MaxConcurrentBusySessions limits how many sessions may be processing a
+	// message at once. A session is busy from Send until session.idle or
+	// session.error. Zero means unlimited.
+	MaxConcurrentBusySessions int
+	// PauseWhenOverloaded delays sends from sessions that are not already
+	// busy while [Client.ServerLoad] reports ServerLoadOverloaded.
+	PauseWhenOverloaded bool
+}
+
+// PacingState is a snapshot of the client's pacing state, for logging.
+type PacingState struct {
+	// SendsInWindow is the number of sends in the current rolling minute
+	SendsInWindow int
+	// BusySessions is the number of sessions currently processing a message
+	BusySessions int
+	// Waiting is the number of Send calls currently delayed by pacing
+	Waiting int
+	// BackoffUntil is when the current rate limit backoff ends, or zero if none
+	BackoffUntil time.Time
+}
+
+// pacer delays sends to stay under the configured limits.
+type pacer struct {
+	opts   PacingOptions
+	window time.Duration
+	load   *loadEstimator // set by the client; nil in tests
+
+	mu            sync.Mutex
+	sends         []time.Time
+	busy          map[string]struct{}
+	waiting       int
+	backoffUntil  time.Time
+	backoffStreak int
+	changed       chan struct{} // closed and replaced whenever capacity is released
+}
+
+func newPacer(opts PacingOptions) *pacer {
+	return &pacer{
+		opts:    opts,
+		window:  time.Minute,
+		busy:    make(map[string]struct{}),
+		changed: make(chan struct{}),
+	}
+}
+
+// acquire blocks until the session may send, then records the send. It
+// reports whether the send made the session busy; only then may the caller
+// release the session if the send fails, since a session that was already
+// busy still holds its slot for the earlier send.
+func (p *pacer) acquire(ctx context.Context, sessionID string) (bool, error) {
+	p.mu.Lock()
+	p.waiting++
+	defer func() {
+		p.waiting--
+		p.mu.Unlock()
+	}()
+
+	for {
+		now := time.Now()
+		wait, blocked := p.delayLocked(now, sessionID)
+		if wait <= 0 && !blocked {
+			p.sends = append(p.sends, now)
+			_, wasBusy := p.busy[sessionID]
+			p.busy[sessionID] = struct{}{}
+			return !wasBusy, nil
+		}
+
+		changed := p.changed
+		p.mu.Unlock()
+		var timer *time.Timer
+		var timerC <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timerC = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-timerC:
+		case <-changed:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		p.mu.Lock()
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+	}
+}
+
+// delayLocked returns how long to wait before sending, and whether the send is
+// blocked until capacity is released. Must be called with p.mu held.
+func (p *pacer) delayLocked(now time.Time, sessionID string) (time.Duration, bool) {
+	var wait time.Duration
+	if now.Before(p.backoffUntil) {
+		wait = p.backoffUntil.Sub(now)
+	}
+
+	// Drop sends that fell out of the window
+	cutoff := now.Add(-p.window)
+	i := 0
+	for i < len(p.sends) && !p.sends[i].After(cutoff) {
+		i++
+	}
+	p.sends = p.sends[i:]
+
+	if p.opts.MaxSendsPerMinute > 0 && len(p.sends) >= p.opts.MaxSendsPerMinute {
+		if w := p.sends[len(p.sends)-p.opts.MaxSendsPerMinute].Add(p.window).Sub(now); w > wait {
+			wait = w
+		}
+	}
+
+	// A session that is already busy may enqueue more messages
+	_, alreadyBusy := p.busy[sessionID]
+	blocked := !alreadyBusy && p.opts.MaxConcurrentBusySessions > 0 && len(p.busy) >= p.opts.MaxConcurrentBusySessions
+
+	// The load only drops as requests complete or age out, so check again
+	// after a while rather than waiting for capacity to be released
+	if !alreadyBusy && p.opts.PauseWhenOverloaded && p.load != nil && p.load.overloaded() {
+		wait = max(wait, overloadRecheckInterval)
+	}
+	return wait, blocked
+}
+
+// release marks a session as no longer busy.
+func (p *pacer) release(sessionID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.busy[sessionID]; !ok {
+		return
+	}
+	delete(p.busy, sessionID)
+	p.notifyLocked()
+}
+
+// succeeded resets the adaptive backoff after a successful send.
+func (p *pacer) succeeded() {
+	p.mu.Lock()
+	p.backoffStreak = 0
+	p.mu.Unlock()
+}
+
+// backoff pauses all sends after a rate limit. A zero retryAfter uses an
+// exponential backoff based on the number of consecutive rate limits.
+func (p *pacer) backoff(retryAfter time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if retryAfter <= 0 {
+		retryAfter = Backoff{Initial: pacingMinBackoff, Max: pacingMaxBackoff}.Next(p.backoffStreak + 1)
+	}
+	p.backoffStreak++
+	if until := time.Now().Add(retryAfter); until.After(p.backoffUntil) {
+		p.backoffUntil = until
+	}
+}
+
+// state returns a snapshot of the pacing state.
+func (p *pacer) state() PacingState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	state := PacingState{
+		BusySessions: len(p.busy),
+		Waiting:      p.waiting,
+	}
+	cutoff := now.Add(-p.window)
+	for _, t := range p.sends {
+		if t.After(cutoff) {
+			state.SendsInWindow++
+		}
+	}
+	if now.Before(p.backoffUntil) {
+		state.BackoffUntil = p.backoffUntil
+	}
+	return state
+}
+
+// observeEvent backs off after a session error reporting a rate limit.
+// Sessions are released when their turn ends; see [Session.initState].
+func (p *pacer) observeEvent(event SessionEvent) {
+	if event.Type == SessionError && event.Data.StatusCode != nil && *event.Data.StatusCode == 429 {
+		p.backoff(0)
+	}
+}
+
+func (p *pacer) notifyLocked() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// PacingState returns a snapshot of the client's send pacing state.
+// Returns the zero value if [ClientOptions.Pacing] is not set.
+func (c *Client) PacingState() PacingState {
+	if c.pacer == nil {
+		return PacingState{}
+	}
+	return c.pacer.state()
+}
@@ -0,0 +1,197 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+func TestPacer(t *testing.T) {
+	t.Run("delays sends beyond the per-minute limit", func(t *testing.T) {
+		p := newPacer(PacingOptions{MaxSendsPerMinute: 2})
+		p.window = 100 * time.Millisecond
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			if _, err := p.acquire(t.Context(), "s1"); err != nil {
+				t.Fatalf("acquire failed: %v", err)
+			}
+		}
+		if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+			t.Errorf("Expected third send to wait for the window, took %v", elapsed)
+		}
+	})
+
+	t.Run("limits concurrent busy sessions", func(t *testing.T) {
+		p := newPacer(PacingOptions{MaxConcurrentBusySessions: 1})
+		if took, err := p.acquire(t.Context(), "s1"); err != nil || !took {
+			t.Fatalf("Expected the send to make the session busy, got %v, %v", took, err)
+		}
+		// The busy session may enqueue more messages
+		if took, err := p.acquire(t.Context(), "s1"); err != nil || took {
+			t.Fatalf("Expected the session to be busy already, got %v, %v", took, err)
+		}
+
+		var acquired atomic.Bool
+		done := make(chan error)
+		go func() {
+			_, err := p.acquire(t.Context(), "s2")
+			acquired.Store(true)
+			done <- err
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		if acquired.Load() {
+			t.Fatal("Expected second session to wait while the first is busy")
+		}
+		if state := p.state(); state.Waiting != 1 || state.BusySessions != 1 {
+			t.Errorf("Unexpected state while waiting: %+v", state)
+		}
+
+		p.release("s1") // as when the session goes idle
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected second session to proceed after the first became idle")
+		}
+	})
+
+	t.Run("honors context cancellation while waiting", func(t *testing.T) {
+		p := newPacer(PacingOptions{MaxConcurrentBusySessions: 1})
+		p.acquire(t.Context(), "s1")
+
+		ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := p.acquire(ctx, "s2"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected deadline exceeded, got %v", err)
+		}
+		if state := p.state(); state.Waiting != 0 {
+			t.Errorf("Expected no waiters after cancellation, got %d", state.Waiting)
+		}
+	})
+
+	t.Run("backs off exponentially without a retry-after hint", func(t *testing.T) {
+		p := newPacer(PacingOptions{})
+		p.backoff(0)
+		first := p.state().BackoffUntil
+		p.backoff(0)
+		second := p.state().BackoffUntil
+		if !second.After(first) {
+			t.Errorf("Expected backoff to increase, got %v then %v", first, second)
+		}
+		p.succeeded()
+		if p.backoffStreak != 0 {
+			t.Errorf("Expected backoff streak to reset, got %d", p.backoffStreak)
+		}
+	})
+}
+
+func TestSession_SendRateLimited(t *testing.T) {
+	var calls atomic.Int32
+	session, _ := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+		if method != "session.send" {
+			return nil, nil
+		}
+		if calls.Add(1) == 1 {
+			return nil, &jsonrpc2.Error{
+				Code:    -32000,
+				Message: "Too many requests",
+				Data:    map[string]any{"statusCode": 429, "retryAfter": 0.1},
+			}
+		}
+		return sessionSendResponse{MessageID: "msg"}, nil
+	})
+	session.pacer = newPacer(PacingOptions{})
+
+	_, err := session.Send(t.Context(), MessageOptions{Prompt: "hi"})
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("Expected RateLimitError, got %v", err)
+	}
+	if rateLimitErr.RetryAfter != 100*time.Millisecond {
+		t.Errorf("Expected RetryAfter 100ms, got %v", rateLimitErr.RetryAfter)
+	}
+	if session.pacer.state().BackoffUntil.IsZero() {
+		t.Error("Expected pacing backoff after rate limit")
+	}
+
+	start := time.Now()
+	if _, err := session.Send(t.Context(), MessageOptions{Prompt: "hi"}); err != nil {
+		t.Fatalf("Expected retry to succeed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected retry to wait for the backoff, took %v", elapsed)
+	}
+}
+
+func TestSession_SendPacing(t *testing.T) {
+	t.Run("keeps the busy slot of an earlier send when a send fails", func(t *testing.T) {
+		var calls atomic.Int32
+		session, _ := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.send" && calls.Add(1) > 1 {
+				return nil, errors.New("queue full")
+			}
+			return sessionSendResponse{MessageID: "msg"}, nil
+		})
+		session.pacer = newPacer(PacingOptions{MaxConcurrentBusySessions: 1})
+
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "first"}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "second", Mode: "enqueue"}); err == nil {
+			t.Fatal("Expected the second send to fail")
+		}
+		if state := session.pacer.state(); state.BusySessions != 1 {
+			t.Errorf("Expected the session to stay busy with its first message, got %+v", state)
+		}
+	})
+
+	t.Run("frees the busy slot taken by a send that fails", func(t *testing.T) {
+		session, _ := newTestSession(t, func(string, json.RawMessage) (any, error) {
+			return nil, errors.New("boom")
+		})
+		session.pacer = newPacer(PacingOptions{MaxConcurrentBusySessions: 1})
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "hi"}); err == nil {
+			t.Fatal("Expected the send to fail")
+		}
+		if state := session.pacer.state(); state.BusySessions != 0 {
+			t.Errorf("Expected the slot to be freed, got %+v", state)
+		}
+	})
+
+	t.Run("counts a message sent again with a fallback model once", func(t *testing.T) {
+		rateLimited := &jsonrpc2.Error{Code: -32000, Message: "Too many requests", Data: map[string]any{"statusCode": 429}}
+		session, _, sent := newFallbackTestSession(t, "premium", []string{"standard"}, map[string]*jsonrpc2.Error{"premium": rateLimited})
+		session.pacer = newPacer(PacingOptions{MaxSendsPerMinute: 10})
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "hi"}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		if state := session.pacer.state(); len(sent()) != 2 || state.SendsInWindow != 1 {
+			t.Errorf("Expected 2 attempts counted as 1 send, got %d attempts and %+v", len(sent()), state)
+		}
+	})
+
+	t.Run("leaves the session idle when the response cannot be decoded", func(t *testing.T) {
+		session, _ := newTestSession(t, func(string, json.RawMessage) (any, error) {
+			return "not a send response", nil
+		})
+		session.pacer = newPacer(PacingOptions{MaxConcurrentBusySessions: 1})
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "hi"}); err == nil {
+			t.Fatal("Expected the send to fail")
+		}
+		if state := session.State(); state != SessionStateIdle {
+			t.Errorf("Expected the session to be idle, got %s", state)
+		}
+		if state := session.pacer.state(); state.BusySessions != 0 {
+			t.Errorf("Expected the slot to be freed, got %+v", state)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultMaxConcurrency is how many sessions [Client.RunSessions] runs at
+// once when ParallelOptions.MaxConcurrency is not set.
+const defaultMaxConcurrency = 4
+
+// ParallelOptions configures [Client.RunSessions].
+type ParallelOptions struct {
+	// MaxConcurrency is how many sessions exist at once. Default: 4.
+	MaxConcurrency int
+}
+
+// SessionRunError reports a session of [Client.RunSessions] that failed.
+type SessionRunError struct {
+	// Index is the position of the session's config
+	Index int
+	// SessionID is the session's ID, or empty if it was not created and its
+	// config did not set one
+	SessionID string
+	// Err is why the session failed: the error creating it, the error
+	// returned by the function, or the context's error if it never ran
+	Err error
+}
+
+func (e *SessionRunError) Error() string {
+	if e.SessionID != "" {
+		return fmt.Sprintf("session %d (%s): %v", e.Index, e.SessionID, e.Err)
+	}
+	return fmt.Sprintf("session %d: %v", e.Index, e.Err)
+}
+
+func (e *SessionRunError) Unwrap() error {
+	return e.Err
+}
+
+// RunSessions creates a session for each of configs and calls fn with it,
+// running at most MaxConcurrency sessions at once. Each session is destroyed
+// once fn returns, also if fn panics, which fails the session like an error.
+//
+// The failures of all sessions are joined into the returned error, one
+// *[SessionRunError] per failing config in config order, so a failure can be
+// traced back to its config. One failing session does not stop the others.
+// When ctx is done, in-flight sessions are aborted, so their waits return an
+// error matching [ErrAborted], and configs not started yet fail with
+// ctx's error.
+//
+// Example:
+//
+//	fileFor := make(map[string]string)
+//	var configs []copilot.SessionConfig
+//	for _, file := range files {
+//	    id := "review-" + file
+//	    fileFor[id] = file
+//	    configs = append(configs, copilot.SessionConfig{
+//	        SessionID:           id,
+//	        OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+//	    })
+//	}
+//	err := client.RunSessions(ctx, configs, func(session *copilot.Session) error {
+//	    _, err := session.SendAndWait(ctx, copilot.MessageOptions{Prompt: "Review " + fileFor[session.SessionID]})
+//	    return err
+//	}, &copilot.ParallelOptions{MaxConcurrency: 8})
+func (c *Client) RunSessions(ctx context.Context, configs []SessionConfig, fn func(*Session) error, options *ParallelOptions) error {
+	concurrency := defaultMaxConcurrency
+	if options != nil && options.MaxConcurrency > 0 {
+		concurrency = options.MaxConcurrency
+	}
+
+	failures := make([]*SessionRunError, len(configs))
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range min(concurrency, len(configs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(configs) {
+					return
+				}
+				sessionID, err := configs[i].SessionID, ctx.Err()
+				if err == nil {
+					sessionID, err = c.runSession(ctx, configs[i], fn)
+				}
+				if err != nil {
+					failures[i] = &SessionRunError{Index: i, SessionID: sessionID, Err: err}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, failure := range failures {
+		if failure != nil {
+			errs = append(errs, failure)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runSession creates a session with config, calls fn with it and destroys it.
+func (c *Client) runSession(ctx context.Context, config SessionConfig, fn func(*Session) error) (sessionID string, err error) {
+	session, err := c.CreateSession(ctx, &config)
+	if err != nil {
+		return config.SessionID, fmt.Errorf("failed to create session: %w", err)
+	}
+	sessionID = session.SessionID
+	defer func() {
+		if r := recover(); r != nil {
+			c.diagnostics.recordPanic("session function", sessionID, r)
+			err = fmt.Errorf("session function panicked: %v", r)
+		}
+		if destroyErr := session.Destroy(); destroyErr != nil {
+			err = errors.Join(err, destroyErr)
+		}
+	}()
+
+	stop := context.AfterFunc(ctx, func() {
+		session.Abort(context.Background())
+	})
+	defer stop()
+	return sessionID, fn(session)
+}
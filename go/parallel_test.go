@@ -0,0 +1,130 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClient_RunSessions(t *testing.T) {
+	// sessionCounts records the session calls of a fake CLI.
+	type sessionCounts struct {
+		mu                        sync.Mutex
+		live, maxLive             int
+		created, destroyed, abort int
+	}
+	newClient := func(t *testing.T, counts *sessionCounts) *Client {
+		cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+			counts.mu.Lock()
+			defer counts.mu.Unlock()
+			switch method {
+			case "session.create":
+				var req createSessionRequest
+				json.Unmarshal(params, &req)
+				if req.SessionID == "bad" {
+					return nil, fmt.Errorf("invalid session")
+				}
+				counts.created++
+				counts.live++
+				counts.maxLive = max(counts.maxLive, counts.live)
+				return createSessionResponse{SessionID: req.SessionID}, nil
+			case "session.destroy":
+				counts.destroyed++
+				counts.live--
+			case "session.abort":
+				counts.abort++
+			case "session.send":
+				return sessionSendResponse{MessageID: "m1"}, nil
+			}
+			return nil, nil
+		})
+		client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+		t.Cleanup(func() { client.ForceStop() })
+		if err := client.Start(t.Context()); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		return client
+	}
+	configs := func(ids ...string) []SessionConfig {
+		result := make([]SessionConfig, len(ids))
+		for i, id := range ids {
+			result[i] = SessionConfig{SessionID: id, OnPermissionRequest: PermissionHandler.ApproveAll}
+		}
+		return result
+	}
+	failedIndexes := func(err error) []int {
+		var indexes []int
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, err := range joined.Unwrap() {
+				var runErr *SessionRunError
+				if errors.As(err, &runErr) {
+					indexes = append(indexes, runErr.Index)
+				}
+			}
+		}
+		return indexes
+	}
+
+	t.Run("reports failing sessions and destroys every session", func(t *testing.T) {
+		var counts sessionCounts
+		client := newClient(t, &counts)
+		rejected := errors.New("rejected")
+		err := client.RunSessions(t.Context(), configs("s0", "s1", "s2", "bad", "s4", "s5", "s6", "s7"), func(session *Session) error {
+			switch session.SessionID {
+			case "s2":
+				return rejected
+			case "s5":
+				panic("function failed")
+			}
+			return nil
+		}, &ParallelOptions{MaxConcurrency: 3})
+
+		if got := fmt.Sprint(failedIndexes(err)); got != "[2 3 5]" {
+			t.Errorf("Expected sessions 2, 3 and 5 to fail, got %s: %v", got, err)
+		}
+		if !errors.Is(err, rejected) {
+			t.Errorf("Expected the function's error, got %v", err)
+		}
+		if counts.created != 7 || counts.destroyed != 7 {
+			t.Errorf("Expected 7 sessions created and destroyed, got %d and %d", counts.created, counts.destroyed)
+		}
+		if counts.maxLive > 3 {
+			t.Errorf("Expected at most 3 sessions at once, got %d", counts.maxLive)
+		}
+	})
+
+	t.Run("aborts in-flight sessions when the context is canceled", func(t *testing.T) {
+		var counts sessionCounts
+		client := newClient(t, &counts)
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+		var started sync.WaitGroup
+		started.Add(2)
+		go func() {
+			started.Wait()
+			cancel()
+		}()
+		var waiting atomic.Int64
+		err := client.RunSessions(ctx, configs("s0", "s1", "s2", "s3", "s4"), func(session *Session) error {
+			if waiting.Add(1) <= 2 {
+				started.Done()
+			}
+			_, err := session.SendAndWait(t.Context(), MessageOptions{Prompt: "Hello"})
+			return err
+		}, &ParallelOptions{MaxConcurrency: 2})
+
+		if got := fmt.Sprint(failedIndexes(err)); got != "[0 1 2 3 4]" {
+			t.Errorf("Expected every session to fail, got %s: %v", got, err)
+		}
+		if !errors.Is(err, ErrAborted) || !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected aborted and canceled sessions, got %v", err)
+		}
+		if counts.abort != 2 || counts.created != 2 || counts.destroyed != 2 {
+			t.Errorf("Expected 2 sessions created, aborted and destroyed, got %+v", &counts)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrNoAssistantMessage is returned by [SendAndParse] when the turn completes
+// without a final assistant message to parse.
+var ErrNoAssistantMessage = errors.New("no assistant message received")
+
+// ParseOptions configures [SendAndParse].
+type ParseOptions struct {
+	// RetryOnInvalid sends one follow-up turn asking the model to correct its
+	// output when the first response does not parse into the target type.
+	RetryOnInvalid bool
+}
+
+// SendAndParse sends a message, waits for the session to become idle, and
+// unmarshals the final assistant message into a value of type T.
+//
+// If options.ResponseSchema is empty, a JSON schema generated from T is sent
+// so the CLI can constrain the output where supported. Markdown code fences
+// around the JSON are stripped before parsing.
+//
+// Example:
+//
+//	type Summary struct {
+//	    Title string   `json:"title"`
+//	    Tags  []string `json:"tags"`
+//	}
+//
+//	summary, err := copilot.SendAndParse[Summary](ctx, session, copilot.MessageOptions{
+//	    Prompt: "Summarize README.md as JSON with a title and tags",
+//	}, &copilot.ParseOptions{RetryOnInvalid: true})
+func SendAndParse[T any](ctx context.Context, session *Session, options MessageOptions, parseOptions *ParseOptions) (T, error) {
+	var zero T
+
+	if t := reflect.TypeFor[T](); len(options.ResponseSchema) == 0 && t.Kind() != reflect.Interface {
+		schema, err := json.Marshal(generateSchemaForType(t))
+		if err != nil {
+			return zero, fmt.Errorf("failed to marshal response schema: %w", err)
+		}
+		options.ResponseSchema = schema
+	}
+
+	response, err := session.SendAndWait(ctx, options)
+	if err != nil {
+		return zero, err
+	}
+	result, parseErr := parseAssistantResponse[T](response)
+	if parseErr == nil || parseOptions == nil || !parseOptions.RetryOnInvalid {
+		return result, parseErr
+	}
+
+	retry := MessageOptions{
+		Prompt:         buildParseRetryPrompt(parseErr, options.ResponseSchema),
+		ResponseSchema: options.ResponseSchema,
+		Mode:           options.Mode,
+	}
+	response, err = session.SendAndWait(ctx, retry)
+	if err != nil {
+		return zero, err
+	}
+	return parseAssistantResponse[T](response)
+}
+
+// parseAssistantResponse unmarshals the content of an assistant message event into T.
+func parseAssistantResponse[T any](event *SessionEvent) (T, error) {
+	var result T
+	if event == nil || event.Data.Content == nil {
+		return result, ErrNoAssistantMessage
+	}
+	content := stripCodeFences(*event.Data.Content)
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return result, fmt.Errorf("failed to parse assistant response into %T: %w", result, err)
+	}
+	return result, nil
+}
+
+// stripCodeFences returns the body of the first fenced code block in content,
+// or the trimmed content if it has no code fence.
+func stripCodeFences(content string) string {
+	for _, block := range parseMarkdownBlocks(content) {
+		if block.code != nil {
+			return strings.TrimSpace(block.code.Body)
+		}
+	}
+	return strings.TrimSpace(content)
+}
+
+// buildParseRetryPrompt builds the follow-up prompt sent when a response fails to parse.
+func buildParseRetryPrompt(parseErr error, schema json.RawMessage) string {
+	return fmt.Sprintf("Your previous response could not be parsed: %v\n\n"+
+		"Reply again with only a JSON value matching this schema, without any surrounding text:\n\n%s",
+		parseErr, schema)
+}
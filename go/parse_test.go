@@ -0,0 +1,145 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type parseTestResult struct {
+	Answer int    `json:"answer"`
+	Reason string `json:"reason"`
+}
+
+func assistantMessageEvent(content string) *SessionEvent {
+	return &SessionEvent{Type: AssistantMessage, Data: Data{Content: &content}}
+}
+
+func TestStripCodeFences(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"plain JSON", `{"a":1}`, `{"a":1}`},
+		{"surrounding whitespace", "\n  {\"a\":1}  \n", `{"a":1}`},
+		{"json fence", "```json\n{\"a\":1}\n```", `{"a":1}`},
+		{"bare fence", "```\n{\"a\":1}\n```", `{"a":1}`},
+		{"prose around fence", "Here you go:\n```json\n{\"a\":1}\n```\nLet me know!", `{"a":1}`},
+		{"unterminated fence", "```json\n{\"a\":1}", `{"a":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripCodeFences(tt.content); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseAssistantResponse(t *testing.T) {
+	t.Run("parses valid JSON", func(t *testing.T) {
+		result, err := parseAssistantResponse[parseTestResult](assistantMessageEvent(`{"answer":4,"reason":"2+2"}`))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Answer != 4 || result.Reason != "2+2" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("parses fenced JSON", func(t *testing.T) {
+		result, err := parseAssistantResponse[parseTestResult](assistantMessageEvent("```json\n{\"answer\":4}\n```"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Answer != 4 {
+			t.Errorf("Expected answer 4, got %d", result.Answer)
+		}
+	})
+
+	t.Run("returns error for malformed output", func(t *testing.T) {
+		_, err := parseAssistantResponse[parseTestResult](assistantMessageEvent("The answer is 4"))
+		if err == nil || !strings.Contains(err.Error(), "failed to parse assistant response") {
+			t.Errorf("Expected parse error, got %v", err)
+		}
+	})
+
+	t.Run("returns ErrNoAssistantMessage when no message was received", func(t *testing.T) {
+		_, err := parseAssistantResponse[parseTestResult](nil)
+		if !errors.Is(err, ErrNoAssistantMessage) {
+			t.Errorf("Expected ErrNoAssistantMessage, got %v", err)
+		}
+	})
+}
+
+func TestSendAndParse(t *testing.T) {
+	// scriptedSession replies to each session.send with the next scripted response.
+	scriptedSession := func(t *testing.T, responses ...string) (*Session, *[]sessionSendRequest) {
+		var mu sync.Mutex
+		var sent []sessionSendRequest
+		var server *fakeServer
+		session, server := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method != "session.send" {
+				return nil, nil
+			}
+			var req sessionSendRequest
+			json.Unmarshal(params, &req)
+			mu.Lock()
+			sent = append(sent, req)
+			content := responses[len(sent)-1]
+			mu.Unlock()
+			server.emit(SessionEvent{Type: AssistantMessage, Data: Data{Content: &content}})
+			server.emit(SessionEvent{Type: SessionIdle})
+			return sessionSendResponse{MessageID: "msg"}, nil
+		})
+		return session, &sent
+	}
+
+	t.Run("sends a schema generated from the target type", func(t *testing.T) {
+		session, sent := scriptedSession(t, `{"answer":4}`)
+
+		result, err := SendAndParse[parseTestResult](t.Context(), session, MessageOptions{Prompt: "2+2?"}, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Answer != 4 {
+			t.Errorf("Expected answer 4, got %d", result.Answer)
+		}
+		if len(*sent) != 1 || !strings.Contains(string((*sent)[0].ResponseSchema), `"answer"`) {
+			t.Errorf("Expected generated response schema to be sent, got %+v", *sent)
+		}
+	})
+
+	t.Run("retries once with a correction prompt", func(t *testing.T) {
+		session, sent := scriptedSession(t, "four", "```json\n{\"answer\":4}\n```")
+
+		result, err := SendAndParse[parseTestResult](t.Context(), session, MessageOptions{Prompt: "2+2?"}, &ParseOptions{RetryOnInvalid: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result.Answer != 4 {
+			t.Errorf("Expected answer 4, got %d", result.Answer)
+		}
+		if len(*sent) != 2 {
+			t.Fatalf("Expected 2 sends, got %d", len(*sent))
+		}
+		if !strings.Contains((*sent)[1].Prompt, "could not be parsed") {
+			t.Errorf("Expected correction prompt, got %q", (*sent)[1].Prompt)
+		}
+	})
+
+	t.Run("does not retry unless requested", func(t *testing.T) {
+		session, sent := scriptedSession(t, "four")
+
+		_, err := SendAndParse[parseTestResult](t.Context(), session, MessageOptions{Prompt: "2+2?"}, nil)
+		if err == nil {
+			t.Fatal("Expected parse error")
+		}
+		if len(*sent) != 1 {
+			t.Errorf("Expected 1 send, got %d", len(*sent))
+		}
+	})
+}
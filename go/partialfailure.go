@@ -0,0 +1,272 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+// ConfigComponentWarning is the warning type of the session.warning events
+// emitted for each [ConfigWarning] of a session created with
+// [PartialFailureWarnAndContinue].
+const ConfigComponentWarning = "config_component_failed"
+
+// PartialFailurePolicy selects what happens when an MCP server, skill
+// directory or custom agent of a session's configuration cannot be set up.
+type PartialFailurePolicy string
+
+const (
+	// PartialFailureFailFast fails creating or resuming the session if any
+	// component fails. This is the default.
+	PartialFailureFailFast PartialFailurePolicy = "fail_fast"
+	// PartialFailureWarnAndContinue leaves failing components out of the
+	// session and reports each in [Session.Warnings] and a session.warning
+	// event with type [ConfigComponentWarning].
+	PartialFailureWarnAndContinue PartialFailurePolicy = "warn_and_continue"
+)
+
+// ConfigComponent names a kind of session configuration component that
+// [PartialFailureWarnAndContinue] can leave out.
+type ConfigComponent string
+
+const (
+	// ConfigComponentMCPServer is an entry of MCPServers or SharedMCPServers.
+	ConfigComponentMCPServer ConfigComponent = "mcp_server"
+	// ConfigComponentSkillDirectory is an entry of SkillDirectories.
+	ConfigComponentSkillDirectory ConfigComponent = "skill_directory"
+	// ConfigComponentCustomAgent is an entry of CustomAgents.
+	ConfigComponentCustomAgent ConfigComponent = "custom_agent"
+)
+
+// ConfigWarning describes a configuration component left out of a session
+// because it could not be set up.
+type ConfigWarning struct {
+	// Component is the kind of component
+	Component ConfigComponent
+	// Name is the MCP server's name, the skill directory or the custom
+	// agent's name
+	Name string
+	// Err is why the component failed
+	Err error
+}
+
+func (w ConfigWarning) String() string {
+	return fmt.Sprintf("%s %q: %v", w.Component, w.Name, w.Err)
+}
+
+// sessionComponents points at the components of a create or resume request
+// that may be left out under [PartialFailureWarnAndContinue].
+type sessionComponents struct {
+	mcpServers       *map[string]MCPServerConfig
+	skillDirectories *[]string
+	customAgents     *[]CustomAgentConfig
+}
+
+// requestSession sends a session.create or session.resume request. With
+// [PartialFailureWarnAndContinue], components that fail local checks or that
+// the CLI rejects are left out of req, which must be a pointer, and the
+// request is sent again.
+func (c *Client) requestSession(method string, req any, components sessionComponents, policy PartialFailurePolicy) (json.RawMessage, []ConfigWarning, error) {
+	if policy != PartialFailureWarnAndContinue {
+		result, err := c.client.Request(method, req)
+		return result, nil, err
+	}
+	warnings := c.dropInvalid(components)
+	for {
+		result, err := c.client.Request(method, req)
+		if err == nil {
+			return result, warnings, nil
+		}
+		// Each retry has one component fewer, so this ends
+		warning, ok := components.dropRejected(err)
+		if !ok {
+			return nil, warnings, err
+		}
+		warnings = append(warnings, warning)
+	}
+}
+
+// dropInvalid removes components that cannot work from the request, without
+// asking the CLI. Filesystem checks are skipped for an external server, whose
+// filesystem may differ from this process's.
+func (c *Client) dropInvalid(components sessionComponents) []ConfigWarning {
+	var warnings []ConfigWarning
+	names := make([]string, 0, len(*components.mcpServers))
+	for name := range *components.mcpServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := c.checkMCPServer((*components.mcpServers)[name]); err != nil {
+			warnings = append(warnings, components.drop(ConfigComponentMCPServer, name, err))
+		}
+	}
+	if !c.isExternalServer {
+		for _, dir := range slices.Clone(*components.skillDirectories) {
+			if err := checkDirectory(c.resolvePath(dir)); err != nil {
+				warnings = append(warnings, components.drop(ConfigComponentSkillDirectory, dir, err))
+			}
+		}
+	}
+	var agents []CustomAgentConfig
+	seen := make(map[string]bool)
+	for _, agent := range *components.customAgents {
+		var err error
+		switch {
+		case agent.Name == "":
+			err = errors.New("custom agent has no name")
+		case seen[agent.Name]:
+			err = errors.New("another custom agent has the same name")
+		}
+		if err != nil {
+			warnings = append(warnings, ConfigWarning{Component: ConfigComponentCustomAgent, Name: agent.Name, Err: err})
+			continue
+		}
+		seen[agent.Name] = true
+		agents = append(agents, agent)
+	}
+	if len(agents) < len(*components.customAgents) {
+		*components.customAgents = agents
+	}
+	return warnings
+}
+
+// checkMCPServer reports MCP server configs that cannot start: remote servers
+// need an http or https URL, and local servers a command that can be found.
+func (c *Client) checkMCPServer(config MCPServerConfig) error {
+	serverType, _ := config["type"].(string)
+	if serverType == "http" || serverType == "sse" {
+		rawURL, _ := config["url"].(string)
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid server URL %q", rawURL)
+		}
+		return nil
+	}
+	command, _ := config["command"].(string)
+	if command == "" {
+		return errors.New("no command to start the server")
+	}
+	if c.isExternalServer {
+		return nil
+	}
+	if strings.ContainsRune(command, filepath.Separator) && !filepath.IsAbs(command) {
+		if cwd, _ := config["cwd"].(string); cwd != "" {
+			command = filepath.Join(cwd, command)
+		} else {
+			command = c.resolvePath(command)
+		}
+	}
+	if _, err := exec.LookPath(command); err != nil {
+		return fmt.Errorf("command not found: %w", err)
+	}
+	return nil
+}
+
+// resolvePath resolves a relative path against the CLI's working directory.
+func (c *Client) resolvePath(path string) string {
+	if filepath.IsAbs(path) || c.options.Cwd == "" {
+		return path
+	}
+	return filepath.Join(c.options.Cwd, path)
+}
+
+// checkDirectory reports whether path is not an existing directory.
+func checkDirectory(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+	return nil
+}
+
+// dropRejected removes the component the CLI's error names from the request.
+// It reports false if err is not an error response from the CLI or names no
+// component, in which case retrying would not help.
+func (components sessionComponents) dropRejected(err error) (ConfigWarning, bool) {
+	var rpcErr *jsonrpc2.Error
+	if !errors.As(err, &rpcErr) {
+		return ConfigWarning{}, false
+	}
+	// Prefer the longest name so "search" does not shadow "search-code"
+	var component ConfigComponent
+	var name string
+	consider := func(kind ConfigComponent, candidate string) {
+		if len(candidate) > len(name) && strings.Contains(rpcErr.Message, candidate) {
+			component, name = kind, candidate
+		}
+	}
+	for candidate := range *components.mcpServers {
+		consider(ConfigComponentMCPServer, candidate)
+	}
+	for _, candidate := range *components.skillDirectories {
+		consider(ConfigComponentSkillDirectory, candidate)
+	}
+	for _, agent := range *components.customAgents {
+		consider(ConfigComponentCustomAgent, agent.Name)
+	}
+	if name == "" {
+		return ConfigWarning{}, false
+	}
+	return components.drop(component, name, errors.New(rpcErr.Message)), true
+}
+
+// drop removes a component from the request without modifying the caller's
+// config, and returns the warning describing it.
+func (components sessionComponents) drop(component ConfigComponent, name string, err error) ConfigWarning {
+	switch component {
+	case ConfigComponentMCPServer:
+		servers := maps.Clone(*components.mcpServers)
+		delete(servers, name)
+		*components.mcpServers = servers
+	case ConfigComponentSkillDirectory:
+		*components.skillDirectories = slices.DeleteFunc(slices.Clone(*components.skillDirectories), func(dir string) bool { return dir == name })
+	case ConfigComponentCustomAgent:
+		*components.customAgents = slices.DeleteFunc(slices.Clone(*components.customAgents), func(agent CustomAgentConfig) bool { return agent.Name == name })
+	}
+	return ConfigWarning{Component: component, Name: name, Err: err}
+}
+
+// Warnings returns the configuration components left out of the session
+// because they could not be set up, when it was created or resumed with
+// [PartialFailureWarnAndContinue].
+//
+// Example:
+//
+//	for _, warning := range session.Warnings() {
+//	    log.Printf("Not available: %s", warning)
+//	}
+func (s *Session) Warnings() []ConfigWarning {
+	return slices.Clone(s.configWarnings)
+}
+
+// warnConfigComponents records warnings and emits a session.warning event
+// for each.
+func (s *Session) warnConfigComponents(warnings []ConfigWarning) {
+	s.configWarnings = warnings
+	for _, warning := range warnings {
+		s.dispatchEvent(SessionEvent{
+			Type:      SessionWarning,
+			Timestamp: time.Now(),
+			Ephemeral: Bool(true),
+			Data: Data{
+				WarningType: String(ConfigComponentWarning),
+				Message:     String(fmt.Sprintf("%s %q was left out of the session: %v", warning.Component, warning.Name, warning.Err)),
+			},
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+func TestClient_PartialFailurePolicy(t *testing.T) {
+	var mu sync.Mutex
+	var created []createSessionRequest
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		if method != "session.create" {
+			return nil, nil
+		}
+		var req createSessionRequest
+		json.Unmarshal(params, &req)
+		mu.Lock()
+		created = append(created, req)
+		mu.Unlock()
+		switch {
+		case req.MCPServers["flaky-server"] != nil:
+			return nil, &jsonrpc2.Error{Code: -32603, Message: `MCP server "flaky-server" failed to start: connection closed`}
+		case req.SessionID == "s-unrelated":
+			return nil, &jsonrpc2.Error{Code: -32603, Message: "internal error"}
+		}
+		return createSessionResponse{SessionID: req.SessionID}, nil
+	})
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	// Check the filesystem as if the client had spawned the CLI
+	client.isExternalServer = false
+
+	skills := t.TempDir()
+	mcpServers := map[string]MCPServerConfig{
+		"echo-server":   {"type": "local", "command": "echo", "args": []string{"hello"}},
+		"flaky-server":  {"type": "local", "command": "echo"},
+		"broken-server": {"type": "local", "command": filepath.Join(skills, "missing-server")},
+		"remote-server": {"type": "http", "url": "not a url"},
+	}
+	requests := func() []createSessionRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(created)
+	}
+
+	t.Run("leaves failing components out", func(t *testing.T) {
+		var events []string
+		session, err := client.CreateSession(t.Context(), &SessionConfig{
+			SessionID:            "s-warn",
+			OnPermissionRequest:  PermissionHandler.ApproveAll,
+			MCPServers:           mcpServers,
+			SkillDirectories:     []string{skills, filepath.Join(skills, "missing")},
+			CustomAgents:         []CustomAgentConfig{{Name: "reviewer"}, {Name: "reviewer"}, {Prompt: "no name"}},
+			PartialFailurePolicy: PartialFailureWarnAndContinue,
+			OnEvent: []SessionEventHandler{func(event SessionEvent) {
+				if event.Type == SessionWarning && *event.Data.WarningType == ConfigComponentWarning {
+					events = append(events, *event.Data.Message)
+				}
+			}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		var got []string
+		for _, warning := range session.Warnings() {
+			got = append(got, string(warning.Component)+" "+warning.Name)
+		}
+		want := []string{
+			"mcp_server broken-server",
+			"mcp_server remote-server",
+			"skill_directory " + filepath.Join(skills, "missing"),
+			"custom_agent reviewer",
+			"custom_agent ",
+			"mcp_server flaky-server",
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("Expected warnings %v, got %v", want, got)
+		}
+		if len(events) != len(want) || !strings.Contains(events[len(events)-1], "connection closed") {
+			t.Errorf("Expected a warning event per component, got %v", events)
+		}
+
+		all := requests()
+		last := all[len(all)-1]
+		var servers []string
+		for name := range last.MCPServers {
+			servers = append(servers, name)
+		}
+		sort.Strings(servers)
+		if !slices.Equal(servers, []string{"echo-server"}) || !slices.Equal(last.SkillDirectories, []string{skills}) || len(last.CustomAgents) != 1 {
+			t.Errorf("Expected only working components to be sent, got %v, %v, %v", servers, last.SkillDirectories, last.CustomAgents)
+		}
+		if len(mcpServers) != 4 {
+			t.Error("Expected the caller's config to be unchanged")
+		}
+		if _, ok := session.reattachRequest.MCPServers["flaky-server"]; ok {
+			t.Error("Expected the rejected server to be left out when re-attaching")
+		}
+	})
+
+	t.Run("fails fast by default", func(t *testing.T) {
+		before := len(requests())
+		_, err := client.CreateSession(t.Context(), &SessionConfig{
+			SessionID:           "s-fail",
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			MCPServers:          mcpServers,
+		})
+		var rpcErr *jsonrpc2.Error
+		if !errors.As(err, &rpcErr) || !strings.Contains(rpcErr.Message, "flaky-server") {
+			t.Errorf("Expected the CLI's error, got %v", err)
+		}
+		if got := len(requests()) - before; got != 1 {
+			t.Errorf("Expected one request, got %d", got)
+		}
+	})
+
+	t.Run("returns errors that name no component", func(t *testing.T) {
+		_, err := client.CreateSession(t.Context(), &SessionConfig{
+			SessionID:            "s-unrelated",
+			OnPermissionRequest:  PermissionHandler.ApproveAll,
+			MCPServers:           map[string]MCPServerConfig{"echo-server": mcpServers["echo-server"]},
+			PartialFailurePolicy: PartialFailureWarnAndContinue,
+		})
+		if err == nil || !strings.Contains(err.Error(), "internal error") {
+			t.Errorf("Expected the CLI's error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,904 @@
+package copilot
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Typed payloads of the events the CLI emits. [SessionEvent.Data] holds the
+// fields of every event type; each accessor below decodes the fields its
+// event type defines into a dedicated struct, so callers don't have to know
+// which of Data's optional fields apply. Events from the CLI are decoded from
+// the JSON as received, and numbers keep the schema's types, so no
+// information is lost. Events without a payload, such as session.idle,
+// pending_messages.modified and subagent.deselected, have no accessor.
+
+// SessionStartData is the payload of a session.start event.
+type SessionStartData struct {
+	SessionID      string    `json:"sessionId"`
+	Version        float64   `json:"version"`
+	Producer       string    `json:"producer"`
+	CopilotVersion string    `json:"copilotVersion"`
+	StartTime      time.Time `json:"startTime"`
+	// SelectedModel is the session's model, or empty if not reported
+	SelectedModel string `json:"selectedModel"`
+	// Context is the working directory and repository, or nil if not reported
+	Context *ContextClass `json:"-"`
+}
+
+// AsSessionStart returns the typed payload of a session.start event. The
+// second return value is false for any other event type.
+func (e SessionEvent) AsSessionStart() (*SessionStartData, bool) {
+	if e.Type != SessionStart {
+		return nil, false
+	}
+	var data SessionStartData
+	var aux contextPayload
+	e.decodePayload(&data, &aux)
+	data.Context = aux.contextClass()
+	return &data, true
+}
+
+// SessionResumeData is the payload of a session.resume event.
+type SessionResumeData struct {
+	ResumeTime time.Time `json:"resumeTime"`
+	// EventCount is the number of events in the resumed session's history
+	EventCount float64 `json:"eventCount"`
+	// Context is the working directory and repository, or nil if not reported
+	Context *ContextClass `json:"-"`
+}
+
+// AsSessionResume returns the typed payload of a session.resume event. The
+// second return value is false for any other event type.
+func (e SessionEvent) AsSessionResume() (*SessionResumeData, bool) {
+	if e.Type != SessionResume {
+		return nil, false
+	}
+	var data SessionResumeData
+	var aux contextPayload
+	e.decodePayload(&data, &aux)
+	data.Context = aux.contextClass()
+	return &data, true
+}
+
+// SessionErrorData is the payload of a session.error event.
+type SessionErrorData struct {
+	ErrorType string `json:"errorType"`
+	Message   string `json:"message"`
+	// Stack is the CLI's stack trace, or empty if not reported
+	Stack string `json:"stack"`
+	// StatusCode is the HTTP status of a failed model request, or zero
+	StatusCode int `json:"statusCode"`
+	// ProviderCallID identifies the failed model request, or is empty
+	ProviderCallID string `json:"providerCallId"`
+}
+
+// AsSessionError returns the typed payload of a session.error event. The
+// second return value is false for any other event type.
+//
+// Example:
+//
+//	session.On(func(event copilot.SessionEvent) {
+//	    if data, ok := event.AsSessionError(); ok {
+//	        log.Printf("%s error (status %d): %s", data.ErrorType, data.StatusCode, data.Message)
+//	    }
+//	})
+func (e SessionEvent) AsSessionError() (*SessionErrorData, bool) {
+	if e.Type != SessionError {
+		return nil, false
+	}
+	var data SessionErrorData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// SessionInfoData is the payload of a session.info event.
+type SessionInfoData struct {
+	InfoType string `json:"infoType"`
+	Message  string `json:"message"`
+}
+
+// AsSessionInfo returns the typed payload of a session.info event. The
+// second return value is false for any other event type.
+func (e SessionEvent) AsSessionInfo() (*SessionInfoData, bool) {
+	if e.Type != SessionInfo {
+		return nil, false
+	}
+	var data SessionInfoData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// SessionWarningData is the payload of a session.warning event, including
+// those the SDK emits, such as [ConfigComponentWarning].
+type SessionWarningData struct {
+	WarningType string `json:"warningType"`
+	Message     string `json:"message"`
+}
+
+// AsSessionWarning returns the typed payload of a session.warning event. The
+// second return value is false for any other event type.
+func (e SessionEvent) AsSessionWarning() (*SessionWarningData, bool) {
+	if e.Type != SessionWarning {
+		return nil, false
+	}
+	var data SessionWarningData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// ModelChangeData is the payload of a session.model_change event.
+type ModelChangeData struct {
+	// PreviousModel is empty if the session was using the default model
+	PreviousModel string `json:"previousModel"`
+	NewModel      string `json:"newModel"`
+}
+
+// AsModelChange returns the typed payload of a session.model_change event.
+// The second return value is false for any other event type.
+func (e SessionEvent) AsModelChange() (*ModelChangeData, bool) {
+	if e.Type != SessionModelChange {
+		return nil, false
+	}
+	var data ModelChangeData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// ModeChangedData is the payload of a session.mode_changed event.
+type ModeChangedData struct {
+	PreviousMode string `json:"previousMode"`
+	NewMode      string `json:"newMode"`
+}
+
+// AsModeChanged returns the typed payload of a session.mode_changed event.
+// The second return value is false for any other event type.
+func (e SessionEvent) AsModeChanged() (*ModeChangedData, bool) {
+	if e.Type != SessionModeChanged {
+		return nil, false
+	}
+	var data ModeChangedData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// PlanChangedData is the payload of a session.plan_changed event.
+type PlanChangedData struct {
+	// Operation is Create, Update or Delete
+	Operation Operation `json:"operation"`
+}
+
+// AsPlanChanged returns the typed payload of a session.plan_changed event.
+// The second return value is false for any other event type.
+func (e SessionEvent) AsPlanChanged() (*PlanChangedData, bool) {
+	if e.Type != SessionPlanChanged {
+		return nil, false
+	}
+	var data PlanChangedData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// WorkspaceFileChangedData is the payload of a
+// session.workspace_file_changed event.
+type WorkspaceFileChangedData struct {
+	// Path is relative to the workspace files directory
+	Path string `json:"path"`
+	// Operation is Create or Update
+	Operation Operation `json:"operation"`
+}
+
+// AsWorkspaceFileChanged returns the typed payload of a
+// session.workspace_file_changed event. The second return value is false
+// for any other event type.
+func (e SessionEvent) AsWorkspaceFileChanged() (*WorkspaceFileChangedData, bool) {
+	if e.Type != SessionWorkspaceFileChanged {
+		return nil, false
+	}
+	var data WorkspaceFileChangedData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// HandoffData is the payload of a session.handoff event.
+type HandoffData struct {
+	HandoffTime time.Time `json:"handoffTime"`
+	// SourceType is Remote or Local
+	SourceType SourceType `json:"sourceType"`
+	// Repository is the repository handed off from, or nil if not reported
+	Repository *RepositoryClass `json:"-"`
+	// Context, Summary and RemoteSessionID are empty if not reported
+	Context         string `json:"-"`
+	Summary         string `json:"summary"`
+	RemoteSessionID string `json:"remoteSessionId"`
+}
+
+// AsHandoff returns the typed payload of a session.handoff event. The second
+// return value is false for any other event type.
+func (e SessionEvent) AsHandoff() (*HandoffData, bool) {
+	if e.Type != SessionHandoff {
+		return nil, false
+	}
+	var data HandoffData
+	var aux struct {
+		contextPayload
+		repositoryPayload
+	}
+	e.decodePayload(&data, &aux)
+	if aux.Repository != nil {
+		data.Repository = aux.Repository.RepositoryClass
+	}
+	if aux.Context != nil {
+		data.Context = derefString(aux.Context.String)
+	}
+	return &data, true
+}
+
+// TruncationData is the payload of a session.truncation event.
+type TruncationData struct {
+	TokenLimit                      float64 `json:"tokenLimit"`
+	PreTruncationTokensInMessages   float64 `json:"preTruncationTokensInMessages"`
+	PreTruncationMessagesLength     float64 `json:"preTruncationMessagesLength"`
+	PostTruncationTokensInMessages  float64 `json:"postTruncationTokensInMessages"`
+	PostTruncationMessagesLength    float64 `json:"postTruncationMessagesLength"`
+	TokensRemovedDuringTruncation   float64 `json:"tokensRemovedDuringTruncation"`
+	MessagesRemovedDuringTruncation float64 `json:"messagesRemovedDuringTruncation"`
+	PerformedBy                     string  `json:"performedBy"`
+}
+
+// AsTruncation returns the typed payload of a session.truncation event. The
+// second return value is false for any other event type.
+func (e SessionEvent) AsTruncation() (*TruncationData, bool) {
+	if e.Type != SessionTruncation {
+		return nil, false
+	}
+	var data TruncationData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// SnapshotRewindData is the payload of a session.snapshot_rewind event.
+type SnapshotRewindData struct {
+	// UpToEventID is the last event kept
+	UpToEventID   string  `json:"upToEventId"`
+	EventsRemoved float64 `json:"eventsRemoved"`
+}
+
+// AsSnapshotRewind returns the typed payload of a session.snapshot_rewind
+// event. The second return value is false for any other event type.
+func (e SessionEvent) AsSnapshotRewind() (*SnapshotRewindData, bool) {
+	if e.Type != SessionSnapshotRewind {
+		return nil, false
+	}
+	var data SnapshotRewindData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// ShutdownData is the payload of a session.shutdown event.
+type ShutdownData struct {
+	// ShutdownType is Routine or Error
+	ShutdownType ShutdownType `json:"shutdownType"`
+	// ErrorReason is empty for a routine shutdown
+	ErrorReason          string        `json:"errorReason"`
+	TotalPremiumRequests float64       `json:"totalPremiumRequests"`
+	TotalAPIDuration     time.Duration `json:"-"`
+	SessionStartTime     time.Time     `json:"-"`
+	CodeChanges          *CodeChanges  `json:"codeChanges"`
+	// ModelMetrics maps each model used to its request count and usage
+	ModelMetrics map[string]ModelMetric `json:"modelMetrics"`
+	// CurrentModel is empty if not reported
+	CurrentModel string `json:"currentModel"`
+}
+
+// AsShutdown returns the typed payload of a session.shutdown event. The
+// second return value is false for any other event type.
+//
+// Example:
+//
+//	session.On(func(event copilot.SessionEvent) {
+//	    if data, ok := event.AsShutdown(); ok {
+//	        fmt.Printf("Used %.1f premium requests\n", data.TotalPremiumRequests)
+//	    }
+//	})
+func (e SessionEvent) AsShutdown() (*ShutdownData, bool) {
+	if e.Type != SessionShutdown {
+		return nil, false
+	}
+	var data ShutdownData
+	var aux struct {
+		TotalAPIDurationMS *float64 `json:"totalApiDurationMs"`
+		SessionStartTime   *float64 `json:"sessionStartTime"`
+	}
+	e.decodePayload(&data, &aux)
+	data.TotalAPIDuration = millis(aux.TotalAPIDurationMS)
+	if aux.SessionStartTime != nil {
+		data.SessionStartTime = time.UnixMilli(int64(*aux.SessionStartTime))
+	}
+	return &data, true
+}
+
+// ContextChangedData is the payload of a session.context_changed event.
+type ContextChangedData struct {
+	Cwd string `json:"cwd"`
+	// GitRoot, Repository and Branch are empty outside a repository
+	GitRoot    string `json:"gitRoot"`
+	Repository string `json:"-"`
+	Branch     string `json:"branch"`
+}
+
+// AsContextChanged returns the typed payload of a session.context_changed
+// event. The second return value is false for any other event type.
+func (e SessionEvent) AsContextChanged() (*ContextChangedData, bool) {
+	if e.Type != SessionContextChanged {
+		return nil, false
+	}
+	var data ContextChangedData
+	var aux repositoryPayload
+	e.decodePayload(&data, &aux)
+	if aux.Repository != nil {
+		data.Repository = derefString(aux.Repository.String)
+	}
+	return &data, true
+}
+
+// UsageInfoData is the payload of a session.usage_info event.
+type UsageInfoData struct {
+	TokenLimit     float64 `json:"tokenLimit"`
+	CurrentTokens  float64 `json:"currentTokens"`
+	MessagesLength float64 `json:"messagesLength"`
+}
+
+// AsUsageInfo returns the typed payload of a session.usage_info event. The
+// second return value is false for any other event type.
+func (e SessionEvent) AsUsageInfo() (*UsageInfoData, bool) {
+	if e.Type != SessionUsageInfo {
+		return nil, false
+	}
+	var data UsageInfoData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// TaskCompleteData is the payload of a session.task_complete event.
+type TaskCompleteData struct {
+	// Summary is empty if not reported
+	Summary string `json:"summary"`
+}
+
+// AsTaskComplete returns the typed payload of a session.task_complete event.
+// The second return value is false for any other event type.
+func (e SessionEvent) AsTaskComplete() (*TaskCompleteData, bool) {
+	if e.Type != SessionTaskComplete {
+		return nil, false
+	}
+	var data TaskCompleteData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// UserMessageData is the payload of a user.message event.
+type UserMessageData struct {
+	Content string `json:"content"`
+	// TransformedContent is the prompt as sent to the model, or empty if
+	// the CLI did not change it
+	TransformedContent string       `json:"transformedContent"`
+	Attachments        []Attachment `json:"attachments"`
+	// Source, AgentMode and InteractionID are empty if not reported
+	Source        string    `json:"source"`
+	AgentMode     AgentMode `json:"agentMode"`
+	InteractionID string    `json:"interactionId"`
+}
+
+// AsUserMessage returns the typed payload of a user.message event. The
+// second return value is false for any other event type.
+func (e SessionEvent) AsUserMessage() (*UserMessageData, bool) {
+	if e.Type != UserMessage {
+		return nil, false
+	}
+	var data UserMessageData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// TurnStartData is the payload of an assistant.turn_start event.
+type TurnStartData struct {
+	TurnID string `json:"turnId"`
+	// InteractionID is empty if not reported
+	InteractionID string `json:"interactionId"`
+}
+
+// AsTurnStart returns the typed payload of an assistant.turn_start event.
+// The second return value is false for any other event type.
+func (e SessionEvent) AsTurnStart() (*TurnStartData, bool) {
+	if e.Type != AssistantTurnStart {
+		return nil, false
+	}
+	var data TurnStartData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// TurnEndData is the payload of an assistant.turn_end event.
+type TurnEndData struct {
+	TurnID string `json:"turnId"`
+}
+
+// AsTurnEnd returns the typed payload of an assistant.turn_end event. The
+// second return value is false for any other event type.
+func (e SessionEvent) AsTurnEnd() (*TurnEndData, bool) {
+	if e.Type != AssistantTurnEnd {
+		return nil, false
+	}
+	var data TurnEndData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// IntentData is the payload of an assistant.intent event.
+type IntentData struct {
+	// Intent is a short description of what the assistant is doing
+	Intent string `json:"intent"`
+}
+
+// AsIntent returns the typed payload of an assistant.intent event. The
+// second return value is false for any other event type.
+func (e SessionEvent) AsIntent() (*IntentData, bool) {
+	if e.Type != AssistantIntent {
+		return nil, false
+	}
+	var data IntentData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// ReasoningData is the payload of an assistant.reasoning event.
+type ReasoningData struct {
+	ReasoningID string `json:"reasoningId"`
+	Content     string `json:"content"`
+}
+
+// AsReasoning returns the typed payload of an assistant.reasoning event.
+// The second return value is false for any other event type.
+func (e SessionEvent) AsReasoning() (*ReasoningData, bool) {
+	if e.Type != AssistantReasoning {
+		return nil, false
+	}
+	var data ReasoningData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// ReasoningDeltaData is the payload of an assistant.reasoning_delta event.
+type ReasoningDeltaData struct {
+	ReasoningID  string `json:"reasoningId"`
+	DeltaContent string `json:"deltaContent"`
+}
+
+// AsReasoningDelta returns the typed payload of an
+// assistant.reasoning_delta event. The second return value is false for any
+// other event type.
+func (e SessionEvent) AsReasoningDelta() (*ReasoningDeltaData, bool) {
+	if e.Type != AssistantReasoningDelta {
+		return nil, false
+	}
+	var data ReasoningDeltaData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// StreamingDeltaData is the payload of an assistant.streaming_delta event.
+type StreamingDeltaData struct {
+	// TotalResponseSizeBytes is the size of the response streamed so far
+	TotalResponseSizeBytes float64 `json:"totalResponseSizeBytes"`
+}
+
+// AsStreamingDelta returns the typed payload of an
+// assistant.streaming_delta event. The second return value is false for any
+// other event type.
+func (e SessionEvent) AsStreamingDelta() (*StreamingDeltaData, bool) {
+	if e.Type != AssistantStreamingDelta {
+		return nil, false
+	}
+	var data StreamingDeltaData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// MessageDeltaData is the payload of an assistant.message_delta event.
+type MessageDeltaData struct {
+	MessageID    string `json:"messageId"`
+	DeltaContent string `json:"deltaContent"`
+	// ParentToolCallID is set for messages of a sub-agent
+	ParentToolCallID string `json:"parentToolCallId"`
+}
+
+// AsMessageDelta returns the typed payload of an assistant.message_delta
+// event. The second return value is false for any other event type.
+func (e SessionEvent) AsMessageDelta() (*MessageDeltaData, bool) {
+	if e.Type != AssistantMessageDelta {
+		return nil, false
+	}
+	var data MessageDeltaData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// AssistantUsageData is the payload of an assistant.usage event, reporting
+// one model request.
+type AssistantUsageData struct {
+	Model string `json:"model"`
+	// Token counts, cost and duration are zero if not reported
+	InputTokens      float64       `json:"inputTokens"`
+	OutputTokens     float64       `json:"outputTokens"`
+	CacheReadTokens  float64       `json:"cacheReadTokens"`
+	CacheWriteTokens float64       `json:"cacheWriteTokens"`
+	Cost             float64       `json:"cost"`
+	Duration         time.Duration `json:"-"`
+	// Initiator, APICallID, ProviderCallID and ParentToolCallID are empty
+	// if not reported
+	Initiator        string `json:"initiator"`
+	APICallID        string `json:"apiCallId"`
+	ProviderCallID   string `json:"providerCallId"`
+	ParentToolCallID string `json:"parentToolCallId"`
+	// QuotaSnapshots maps quota names to the remaining entitlement
+	QuotaSnapshots map[string]QuotaSnapshot `json:"quotaSnapshots"`
+	CopilotUsage   *CopilotUsage            `json:"copilotUsage"`
+}
+
+// AsAssistantUsage returns the typed payload of an assistant.usage event.
+// The second return value is false for any other event type.
+//
+// Example:
+//
+//	session.On(func(event copilot.SessionEvent) {
+//	    if usage, ok := event.AsAssistantUsage(); ok {
+//	        fmt.Printf("%s: %.0f in, %.0f out\n", usage.Model, usage.InputTokens, usage.OutputTokens)
+//	    }
+//	})
+func (e SessionEvent) AsAssistantUsage() (*AssistantUsageData, bool) {
+	if e.Type != AssistantUsage {
+		return nil, false
+	}
+	var data AssistantUsageData
+	var aux durationPayload
+	e.decodePayload(&data, &aux)
+	data.Duration = millis(aux.Duration)
+	return &data, true
+}
+
+// AbortData is the payload of an abort event.
+type AbortData struct {
+	Reason string `json:"reason"`
+}
+
+// AsAbort returns the typed payload of an abort event. The second return
+// value is false for any other event type.
+func (e SessionEvent) AsAbort() (*AbortData, bool) {
+	if e.Type != Abort {
+		return nil, false
+	}
+	var data AbortData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// ToolUserRequestedData is the payload of a tool.user_requested event.
+type ToolUserRequestedData struct {
+	ToolCallID string `json:"toolCallId"`
+	ToolName   string `json:"toolName"`
+	// Arguments are the decoded JSON arguments, or nil
+	Arguments any `json:"arguments"`
+}
+
+// AsToolUserRequested returns the typed payload of a tool.user_requested
+// event. The second return value is false for any other event type.
+func (e SessionEvent) AsToolUserRequested() (*ToolUserRequestedData, bool) {
+	if e.Type != ToolUserRequested {
+		return nil, false
+	}
+	var data ToolUserRequestedData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// ToolExecutionStartData is the payload of a tool.execution_start event.
+type ToolExecutionStartData struct {
+	ToolCallID string `json:"toolCallId"`
+	ToolName   string `json:"toolName"`
+	// Arguments are the decoded JSON arguments, or nil
+	Arguments any `json:"arguments"`
+	// MCPServerName and MCPToolName are set for tools of MCP servers
+	MCPServerName string `json:"mcpServerName"`
+	MCPToolName   string `json:"mcpToolName"`
+	// ParentToolCallID is set for tool calls of a sub-agent
+	ParentToolCallID string `json:"parentToolCallId"`
+}
+
+// AsToolExecutionStart returns the typed payload of a tool.execution_start
+// event. The second return value is false for any other event type.
+func (e SessionEvent) AsToolExecutionStart() (*ToolExecutionStartData, bool) {
+	if e.Type != ToolExecutionStart {
+		return nil, false
+	}
+	var data ToolExecutionStartData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// ToolPartialResultData is the payload of a tool.execution_partial_result
+// event.
+type ToolPartialResultData struct {
+	ToolCallID    string `json:"toolCallId"`
+	PartialOutput string `json:"partialOutput"`
+}
+
+// AsToolPartialResult returns the typed payload of a
+// tool.execution_partial_result event. The second return value is false for
+// any other event type.
+func (e SessionEvent) AsToolPartialResult() (*ToolPartialResultData, bool) {
+	if e.Type != ToolExecutionPartialResult {
+		return nil, false
+	}
+	var data ToolPartialResultData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// ToolProgressData is the payload of a tool.execution_progress event.
+type ToolProgressData struct {
+	ToolCallID      string `json:"toolCallId"`
+	ProgressMessage string `json:"progressMessage"`
+}
+
+// AsToolProgress returns the typed payload of a tool.execution_progress
+// event. The second return value is false for any other event type.
+func (e SessionEvent) AsToolProgress() (*ToolProgressData, bool) {
+	if e.Type != ToolExecutionProgress {
+		return nil, false
+	}
+	var data ToolProgressData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// ToolExecutionCompleteData is the payload of a tool.execution_complete
+// event.
+type ToolExecutionCompleteData struct {
+	ToolCallID string `json:"toolCallId"`
+	Success    bool   `json:"success"`
+	// Result is the tool's output, or nil if it failed without one
+	Result *Result `json:"result"`
+	// Error and ErrorCode describe a failure, such as [ToolTimeoutErrorCode]
+	Error     string `json:"-"`
+	ErrorCode string `json:"-"`
+	// Model, InteractionID and ParentToolCallID are empty if not reported
+	Model            string `json:"model"`
+	InteractionID    string `json:"interactionId"`
+	ParentToolCallID string `json:"parentToolCallId"`
+	// IsUserRequested reports a tool call the user asked for
+	IsUserRequested bool           `json:"isUserRequested"`
+	ToolTelemetry   map[string]any `json:"toolTelemetry"`
+}
+
+// AsToolExecutionComplete returns the typed payload of a
+// tool.execution_complete event. The second return value is false for any
+// other event type.
+//
+// Example:
+//
+//	session.On(func(event copilot.SessionEvent) {
+//	    if data, ok := event.AsToolExecutionComplete(); ok && !data.Success {
+//	        log.Printf("tool call %s failed: %s", data.ToolCallID, data.Error)
+//	    }
+//	})
+func (e SessionEvent) AsToolExecutionComplete() (*ToolExecutionCompleteData, bool) {
+	if e.Type != ToolExecutionComplete {
+		return nil, false
+	}
+	var data ToolExecutionCompleteData
+	var aux errorPayload
+	e.decodePayload(&data, &aux)
+	data.Error, data.ErrorCode = aux.message()
+	return &data, true
+}
+
+// SkillInvokedData is the payload of a skill.invoked event.
+type SkillInvokedData struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// AllowedTools restricts the tools the skill may use, or is nil
+	AllowedTools []string `json:"allowedTools"`
+	// PluginName and PluginVersion are set for skills of a plugin
+	PluginName    string `json:"pluginName"`
+	PluginVersion string `json:"pluginVersion"`
+}
+
+// AsSkillInvoked returns the typed payload of a skill.invoked event. The
+// second return value is false for any other event type.
+func (e SessionEvent) AsSkillInvoked() (*SkillInvokedData, bool) {
+	if e.Type != SkillInvoked {
+		return nil, false
+	}
+	var data SkillInvokedData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// SubagentData is the payload of the subagent.started, subagent.completed
+// and subagent.failed events.
+type SubagentData struct {
+	// ToolCallID identifies the tool call that runs the sub-agent
+	ToolCallID       string `json:"toolCallId"`
+	AgentName        string `json:"agentName"`
+	AgentDisplayName string `json:"agentDisplayName"`
+	// AgentDescription is set for subagent.started events
+	AgentDescription string `json:"agentDescription"`
+	// Error is set for subagent.failed events
+	Error string `json:"-"`
+}
+
+// AsSubagent returns the typed payload of a subagent.started,
+// subagent.completed or subagent.failed event. The second return value is
+// false for any other event type.
+func (e SessionEvent) AsSubagent() (*SubagentData, bool) {
+	if e.Type != SubagentStarted && e.Type != SubagentCompleted && e.Type != SubagentFailed {
+		return nil, false
+	}
+	var data SubagentData
+	var aux errorPayload
+	e.decodePayload(&data, &aux)
+	data.Error, _ = aux.message()
+	return &data, true
+}
+
+// SubagentSelectedData is the payload of a subagent.selected event.
+type SubagentSelectedData struct {
+	AgentName        string `json:"agentName"`
+	AgentDisplayName string `json:"agentDisplayName"`
+	// Tools lists the tools the agent may use, or is nil for all tools
+	Tools []string `json:"tools"`
+}
+
+// AsSubagentSelected returns the typed payload of a subagent.selected event.
+// The second return value is false for any other event type.
+func (e SessionEvent) AsSubagentSelected() (*SubagentSelectedData, bool) {
+	if e.Type != SubagentSelected {
+		return nil, false
+	}
+	var data SubagentSelectedData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// HookData is the payload of the hook.start and hook.end events.
+type HookData struct {
+	HookInvocationID string `json:"hookInvocationId"`
+	HookType         string `json:"hookType"`
+	// Input is the decoded input of a hook.start event, or nil
+	Input any `json:"input"`
+	// Output is the decoded output of a hook.end event, or nil
+	Output any `json:"output"`
+	// Success and Error report the outcome of a hook.end event
+	Success bool   `json:"success"`
+	Error   string `json:"-"`
+}
+
+// AsHook returns the typed payload of a hook.start or hook.end event. The
+// second return value is false for any other event type.
+func (e SessionEvent) AsHook() (*HookData, bool) {
+	if e.Type != HookStart && e.Type != HookEnd {
+		return nil, false
+	}
+	var data HookData
+	var aux errorPayload
+	e.decodePayload(&data, &aux)
+	data.Error, _ = aux.message()
+	return &data, true
+}
+
+// SystemMessageData is the payload of a system.message event.
+type SystemMessageData struct {
+	Content string `json:"content"`
+	// Role is System or Developer
+	Role Role `json:"role"`
+	// Name and Metadata are empty if not reported
+	Name     string    `json:"name"`
+	Metadata *Metadata `json:"metadata"`
+}
+
+// AsSystemMessage returns the typed payload of a system.message event. The
+// second return value is false for any other event type.
+func (e SessionEvent) AsSystemMessage() (*SystemMessageData, bool) {
+	if e.Type != SystemMessage {
+		return nil, false
+	}
+	var data SystemMessageData
+	e.decodePayload(&data)
+	return &data, true
+}
+
+// decodePayload decodes the event's data into each of targets, structs whose
+// fields are tagged with the names of the event schema. An event from the CLI
+// is decoded from the JSON it arrived as, so values keep the types the CLI
+// sent. Data is decoded over it, since the SDK may set fields of Data after
+// the event arrives, such as the interaction ID of a turn; events created in
+// code have only Data. Fields missing from both keep their zero value.
+func (e SessionEvent) decodePayload(targets ...any) {
+	sources := make([][]byte, 0, 2)
+	if len(e.raw) > 0 {
+		var envelope struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if json.Unmarshal(e.raw, &envelope) == nil && len(envelope.Data) > 0 {
+			sources = append(sources, envelope.Data)
+		}
+	}
+	if data, err := json.Marshal(e.Data); err == nil {
+		sources = append(sources, data)
+	}
+	for _, target := range targets {
+		for _, source := range sources {
+			// A field of the wrong type is left as it is; the others are
+			// still decoded
+			_ = json.Unmarshal(source, target)
+		}
+	}
+}
+
+// contextPayload decodes the context field, an object for session.start and
+// session.resume events and a string for session.handoff events.
+type contextPayload struct {
+	Context *ContextUnion `json:"context"`
+}
+
+func (p contextPayload) contextClass() *ContextClass {
+	if p.Context == nil {
+		return nil
+	}
+	return p.Context.ContextClass
+}
+
+// repositoryPayload decodes the repository field, an object for
+// session.handoff events and a string for session.context_changed events.
+type repositoryPayload struct {
+	Repository *RepositoryUnion `json:"repository"`
+}
+
+// durationPayload decodes a duration field in milliseconds.
+type durationPayload struct {
+	Duration *float64 `json:"duration"`
+}
+
+// errorPayload decodes an error reported as a string or as an object.
+type errorPayload struct {
+	Error *ErrorUnion `json:"error"`
+}
+
+// message returns the message and code of the error.
+func (p errorPayload) message() (string, string) {
+	return errorMessage(p.Error)
+}
+
+// millis converts a duration in milliseconds.
+func millis(ms *float64) time.Duration {
+	if ms == nil {
+		return 0
+	}
+	return time.Duration(*ms * float64(time.Millisecond))
+}
+
+// errorMessage returns the message and code of an error reported as a string
+// or as an object.
+func errorMessage(err *ErrorUnion) (string, string) {
+	switch {
+	case err == nil:
+		return "", ""
+	case err.String != nil:
+		return *err.String, ""
+	case err.ErrorClass != nil:
+		return err.ErrorClass.Message, derefString(err.ErrorClass.Code)
+	}
+	return "", ""
+}
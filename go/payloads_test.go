@@ -0,0 +1,255 @@
+package copilot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/github/copilot-sdk/go/protocol"
+)
+
+func TestSessionEvent_MatchesProtocol(t *testing.T) {
+	ours := reflect.TypeFor[SessionEvent]()
+	wire := reflect.TypeFor[protocol.SessionEvent]()
+	var exported int
+	for i := range ours.NumField() {
+		field := ours.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		exported++
+		want, ok := wire.FieldByName(field.Name)
+		if !ok || want.Type != field.Type || want.Tag != field.Tag {
+			t.Errorf("Field %s differs from protocol.SessionEvent: %v %s, want %v %s", field.Name, field.Type, field.Tag, want.Type, want.Tag)
+		}
+	}
+	if exported != wire.NumField() {
+		t.Errorf("Expected the %d fields of protocol.SessionEvent, got %d", wire.NumField(), exported)
+	}
+}
+
+func TestSessionEvent_Payloads(t *testing.T) {
+	accessors := map[string]func(SessionEvent) (any, bool){
+		"AsSessionStart":          func(e SessionEvent) (any, bool) { return e.AsSessionStart() },
+		"AsSessionResume":         func(e SessionEvent) (any, bool) { return e.AsSessionResume() },
+		"AsSessionError":          func(e SessionEvent) (any, bool) { return e.AsSessionError() },
+		"AsTitleChanged":          func(e SessionEvent) (any, bool) { return e.AsTitleChanged() },
+		"AsSessionInfo":           func(e SessionEvent) (any, bool) { return e.AsSessionInfo() },
+		"AsSessionWarning":        func(e SessionEvent) (any, bool) { return e.AsSessionWarning() },
+		"AsModelChange":           func(e SessionEvent) (any, bool) { return e.AsModelChange() },
+		"AsModeChanged":           func(e SessionEvent) (any, bool) { return e.AsModeChanged() },
+		"AsPlanChanged":           func(e SessionEvent) (any, bool) { return e.AsPlanChanged() },
+		"AsWorkspaceFileChanged":  func(e SessionEvent) (any, bool) { return e.AsWorkspaceFileChanged() },
+		"AsHandoff":               func(e SessionEvent) (any, bool) { return e.AsHandoff() },
+		"AsTruncation":            func(e SessionEvent) (any, bool) { return e.AsTruncation() },
+		"AsSnapshotRewind":        func(e SessionEvent) (any, bool) { return e.AsSnapshotRewind() },
+		"AsShutdown":              func(e SessionEvent) (any, bool) { return e.AsShutdown() },
+		"AsContextChanged":        func(e SessionEvent) (any, bool) { return e.AsContextChanged() },
+		"AsUsageInfo":             func(e SessionEvent) (any, bool) { return e.AsUsageInfo() },
+		"AsCompactionStart":       func(e SessionEvent) (any, bool) { return e.AsCompactionStart() },
+		"AsCompactionComplete":    func(e SessionEvent) (any, bool) { return e.AsCompactionComplete() },
+		"AsTaskComplete":          func(e SessionEvent) (any, bool) { return e.AsTaskComplete() },
+		"AsUserMessage":           func(e SessionEvent) (any, bool) { return e.AsUserMessage() },
+		"AsTurnStart":             func(e SessionEvent) (any, bool) { return e.AsTurnStart() },
+		"AsIntent":                func(e SessionEvent) (any, bool) { return e.AsIntent() },
+		"AsReasoning":             func(e SessionEvent) (any, bool) { return e.AsReasoning() },
+		"AsReasoningDelta":        func(e SessionEvent) (any, bool) { return e.AsReasoningDelta() },
+		"AsStreamingDelta":        func(e SessionEvent) (any, bool) { return e.AsStreamingDelta() },
+		"AsAssistantMessage":      func(e SessionEvent) (any, bool) { return e.AsAssistantMessage() },
+		"AsMessageDelta":          func(e SessionEvent) (any, bool) { return e.AsMessageDelta() },
+		"AsTurnEnd":               func(e SessionEvent) (any, bool) { return e.AsTurnEnd() },
+		"AsAssistantUsage":        func(e SessionEvent) (any, bool) { return e.AsAssistantUsage() },
+		"AsAbort":                 func(e SessionEvent) (any, bool) { return e.AsAbort() },
+		"AsToolUserRequested":     func(e SessionEvent) (any, bool) { return e.AsToolUserRequested() },
+		"AsToolExecutionStart":    func(e SessionEvent) (any, bool) { return e.AsToolExecutionStart() },
+		"AsToolPartialResult":     func(e SessionEvent) (any, bool) { return e.AsToolPartialResult() },
+		"AsToolProgress":          func(e SessionEvent) (any, bool) { return e.AsToolProgress() },
+		"AsToolExecutionComplete": func(e SessionEvent) (any, bool) { return e.AsToolExecutionComplete() },
+		"AsSkillInvoked":          func(e SessionEvent) (any, bool) { return e.AsSkillInvoked() },
+		"AsSubagent":              func(e SessionEvent) (any, bool) { return e.AsSubagent() },
+		"AsSubagentSelected":      func(e SessionEvent) (any, bool) { return e.AsSubagentSelected() },
+		"AsHook":                  func(e SessionEvent) (any, bool) { return e.AsHook() },
+		"AsSystemMessage":         func(e SessionEvent) (any, bool) { return e.AsSystemMessage() },
+	}
+
+	startTime := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		eventType SessionEventType
+		data      string
+		accessor  string // empty for events without a payload
+		want      any
+	}{
+		{SessionStart, `{"sessionId":"s1","version":1,"producer":"copilot-agent","copilotVersion":"1.0.0","startTime":"2026-03-01T12:00:00Z","selectedModel":"gpt-5","context":{"cwd":"/repo","branch":"main"}}`,
+			"AsSessionStart", &SessionStartData{SessionID: "s1", Version: 1, Producer: "copilot-agent", CopilotVersion: "1.0.0", StartTime: startTime, SelectedModel: "gpt-5", Context: &ContextClass{Cwd: "/repo", Branch: String("main")}}},
+		{SessionResume, `{"resumeTime":"2026-03-01T12:00:00Z","eventCount":42}`,
+			"AsSessionResume", &SessionResumeData{ResumeTime: startTime, EventCount: 42}},
+		{SessionError, `{"errorType":"model","message":"overloaded","statusCode":529,"providerCallId":"p1"}`,
+			"AsSessionError", &SessionErrorData{ErrorType: "model", Message: "overloaded", StatusCode: 529, ProviderCallID: "p1"}},
+		{SessionIdle, `{}`, "", nil},
+		{SessionTitleChanged, `{"title":"Fix the parser"}`,
+			"AsTitleChanged", &SessionTitleData{Title: "Fix the parser"}},
+		{SessionInfo, `{"infoType":"mcp","message":"server started"}`,
+			"AsSessionInfo", &SessionInfoData{InfoType: "mcp", Message: "server started"}},
+		{SessionWarning, `{"warningType":"quota","message":"80% used"}`,
+			"AsSessionWarning", &SessionWarningData{WarningType: "quota", Message: "80% used"}},
+		{SessionModelChange, `{"previousModel":"gpt-5","newModel":"claude-sonnet-4.5"}`,
+			"AsModelChange", &ModelChangeData{PreviousModel: "gpt-5", NewModel: "claude-sonnet-4.5"}},
+		{SessionModeChanged, `{"previousMode":"interactive","newMode":"plan"}`,
+			"AsModeChanged", &ModeChangedData{PreviousMode: "interactive", NewMode: "plan"}},
+		{SessionPlanChanged, `{"operation":"update"}`,
+			"AsPlanChanged", &PlanChangedData{Operation: Update}},
+		{SessionWorkspaceFileChanged, `{"path":"notes.md","operation":"create"}`,
+			"AsWorkspaceFileChanged", &WorkspaceFileChangedData{Path: "notes.md", Operation: Create}},
+		{SessionHandoff, `{"handoffTime":"2026-03-01T12:00:00Z","sourceType":"remote","repository":{"owner":"octo","name":"app"},"context":"PR review","summary":"Reviewed","remoteSessionId":"r1"}`,
+			"AsHandoff", &HandoffData{HandoffTime: startTime, SourceType: Remote, Repository: &RepositoryClass{Owner: "octo", Name: "app"}, Context: "PR review", Summary: "Reviewed", RemoteSessionID: "r1"}},
+		{SessionTruncation, `{"tokenLimit":1000,"preTruncationTokensInMessages":1200,"preTruncationMessagesLength":30,"postTruncationTokensInMessages":900,"postTruncationMessagesLength":20,"tokensRemovedDuringTruncation":300,"messagesRemovedDuringTruncation":10,"performedBy":"sdk"}`,
+			"AsTruncation", &TruncationData{TokenLimit: 1000, PreTruncationTokensInMessages: 1200, PreTruncationMessagesLength: 30, PostTruncationTokensInMessages: 900, PostTruncationMessagesLength: 20, TokensRemovedDuringTruncation: 300, MessagesRemovedDuringTruncation: 10, PerformedBy: "sdk"}},
+		{SessionSnapshotRewind, `{"upToEventId":"e7","eventsRemoved":3}`,
+			"AsSnapshotRewind", &SnapshotRewindData{UpToEventID: "e7", EventsRemoved: 3}},
+		{SessionShutdown, `{"shutdownType":"error","errorReason":"crash","totalPremiumRequests":1.5,"totalApiDurationMs":2500,"sessionStartTime":1772366400000,"codeChanges":{"linesAdded":3,"linesRemoved":1,"filesModified":["a.go"]},"modelMetrics":{"gpt-5":{"requests":{"count":2,"cost":1},"usage":{"inputTokens":10,"outputTokens":5,"cacheReadTokens":0,"cacheWriteTokens":0}}},"currentModel":"gpt-5"}`,
+			"AsShutdown", &ShutdownData{ShutdownType: Error, ErrorReason: "crash", TotalPremiumRequests: 1.5, TotalAPIDuration: 2500 * time.Millisecond, SessionStartTime: time.UnixMilli(1772366400000),
+				CodeChanges:  &CodeChanges{LinesAdded: 3, LinesRemoved: 1, FilesModified: []string{"a.go"}},
+				ModelMetrics: map[string]ModelMetric{"gpt-5": {Requests: Requests{Count: 2, Cost: 1}, Usage: Usage{InputTokens: 10, OutputTokens: 5}}}, CurrentModel: "gpt-5"}},
+		{SessionContextChanged, `{"cwd":"/repo/sub","gitRoot":"/repo","repository":"octo/app","branch":"dev"}`,
+			"AsContextChanged", &ContextChangedData{Cwd: "/repo/sub", GitRoot: "/repo", Repository: "octo/app", Branch: "dev"}},
+		{SessionUsageInfo, `{"tokenLimit":128000,"currentTokens":5000,"messagesLength":12}`,
+			"AsUsageInfo", &UsageInfoData{TokenLimit: 128000, CurrentTokens: 5000, MessagesLength: 12}},
+		{SessionCompactionStart, `{}`,
+			"AsCompactionStart", &CompactionStartData{}},
+		{SessionCompactionComplete, `{"success":false,"error":"too short","requestId":"r2"}`,
+			"AsCompactionComplete", &CompactionCompleteData{Error: "too short", RequestID: "r2"}},
+		{SessionTaskComplete, `{"summary":"Fixed the bug"}`,
+			"AsTaskComplete", &TaskCompleteData{Summary: "Fixed the bug"}},
+		{UserMessage, `{"content":"Fix it","transformedContent":"Fix it please","attachments":[{"type":"file","path":"a.go","displayName":"a.go"}],"source":"cli","agentMode":"plan","interactionId":"i1"}`,
+			"AsUserMessage", &UserMessageData{Content: "Fix it", TransformedContent: "Fix it please", Attachments: []Attachment{{Type: File, Path: String("a.go"), DisplayName: String("a.go")}}, Source: "cli", AgentMode: Plan, InteractionID: "i1"}},
+		{PendingMessagesModified, `{}`, "", nil},
+		{AssistantTurnStart, `{"turnId":"t1","interactionId":"i1"}`,
+			"AsTurnStart", &TurnStartData{TurnID: "t1", InteractionID: "i1"}},
+		{AssistantIntent, `{"intent":"Reading files"}`,
+			"AsIntent", &IntentData{Intent: "Reading files"}},
+		{AssistantReasoning, `{"reasoningId":"r1","content":"Think"}`,
+			"AsReasoning", &ReasoningData{ReasoningID: "r1", Content: "Think"}},
+		{AssistantReasoningDelta, `{"reasoningId":"r1","deltaContent":"Th"}`,
+			"AsReasoningDelta", &ReasoningDeltaData{ReasoningID: "r1", DeltaContent: "Th"}},
+		{AssistantStreamingDelta, `{"totalResponseSizeBytes":2048}`,
+			"AsStreamingDelta", &StreamingDeltaData{TotalResponseSizeBytes: 2048}},
+		{AssistantMessage, `{"messageId":"m1","content":"Done","toolRequests":[{"toolCallId":"c1","name":"grep","type":"function"}],"reasoningText":"because","phase":"final","interactionId":"i1","parentToolCallId":"c0"}`,
+			"AsAssistantMessage", &AssistantMessageData{MessageID: "m1", Content: "Done", ToolRequests: []ToolRequest{{ToolCallID: "c1", Name: "grep", Type: toolRequestType(Function)}}, ReasoningText: "because", Phase: "final", InteractionID: "i1", ParentToolCallID: "c0"}},
+		{AssistantMessageDelta, `{"messageId":"m1","deltaContent":"Do"}`,
+			"AsMessageDelta", &MessageDeltaData{MessageID: "m1", DeltaContent: "Do"}},
+		{AssistantTurnEnd, `{"turnId":"t1"}`,
+			"AsTurnEnd", &TurnEndData{TurnID: "t1"}},
+		{AssistantUsage, `{"model":"gpt-5","inputTokens":100,"outputTokens":20,"cacheReadTokens":50,"cost":1,"duration":1500,"initiator":"sub-agent","apiCallId":"a1"}`,
+			"AsAssistantUsage", &AssistantUsageData{Model: "gpt-5", InputTokens: 100, OutputTokens: 20, CacheReadTokens: 50, Cost: 1, Duration: 1500 * time.Millisecond, Initiator: "sub-agent", APICallID: "a1"}},
+		{Abort, `{"reason":"user"}`,
+			"AsAbort", &AbortData{Reason: "user"}},
+		{ToolUserRequested, `{"toolCallId":"c1","toolName":"bash","arguments":{"command":"ls"}}`,
+			"AsToolUserRequested", &ToolUserRequestedData{ToolCallID: "c1", ToolName: "bash", Arguments: map[string]any{"command": "ls"}}},
+		{ToolExecutionStart, `{"toolCallId":"c1","toolName":"fetch","arguments":{"url":"x"},"mcpServerName":"web","mcpToolName":"get"}`,
+			"AsToolExecutionStart", &ToolExecutionStartData{ToolCallID: "c1", ToolName: "fetch", Arguments: map[string]any{"url": "x"}, MCPServerName: "web", MCPToolName: "get"}},
+		{ToolExecutionPartialResult, `{"toolCallId":"c1","partialOutput":"line 1"}`,
+			"AsToolPartialResult", &ToolPartialResultData{ToolCallID: "c1", PartialOutput: "line 1"}},
+		{ToolExecutionProgress, `{"toolCallId":"c1","progressMessage":"50%"}`,
+			"AsToolProgress", &ToolProgressData{ToolCallID: "c1", ProgressMessage: "50%"}},
+		{ToolExecutionComplete, `{"toolCallId":"c1","success":false,"error":{"message":"timed out","code":"tool_timeout"},"isUserRequested":true,"toolTelemetry":{"retries":2}}`,
+			"AsToolExecutionComplete", &ToolExecutionCompleteData{ToolCallID: "c1", Error: "timed out", ErrorCode: ToolTimeoutErrorCode, IsUserRequested: true, ToolTelemetry: map[string]any{"retries": float64(2)}}},
+		{SkillInvoked, `{"name":"deploy","path":"/skills/deploy","content":"Run make deploy","allowedTools":["bash"],"pluginName":"ops","pluginVersion":"1.2"}`,
+			"AsSkillInvoked", &SkillInvokedData{Name: "deploy", Path: "/skills/deploy", Content: "Run make deploy", AllowedTools: []string{"bash"}, PluginName: "ops", PluginVersion: "1.2"}},
+		{SubagentStarted, `{"toolCallId":"c2","agentName":"reviewer","agentDisplayName":"Reviewer","agentDescription":"Reviews code"}`,
+			"AsSubagent", &SubagentData{ToolCallID: "c2", AgentName: "reviewer", AgentDisplayName: "Reviewer", AgentDescription: "Reviews code"}},
+		{SubagentCompleted, `{"toolCallId":"c2","agentName":"reviewer","agentDisplayName":"Reviewer"}`,
+			"AsSubagent", &SubagentData{ToolCallID: "c2", AgentName: "reviewer", AgentDisplayName: "Reviewer"}},
+		{SubagentFailed, `{"toolCallId":"c2","agentName":"reviewer","agentDisplayName":"Reviewer","error":"crashed"}`,
+			"AsSubagent", &SubagentData{ToolCallID: "c2", AgentName: "reviewer", AgentDisplayName: "Reviewer", Error: "crashed"}},
+		{SubagentSelected, `{"agentName":"reviewer","agentDisplayName":"Reviewer","tools":null}`,
+			"AsSubagentSelected", &SubagentSelectedData{AgentName: "reviewer", AgentDisplayName: "Reviewer"}},
+		{SubagentDeselected, `{}`, "", nil},
+		{HookStart, `{"hookInvocationId":"h1","hookType":"preToolUse","input":{"toolName":"bash"}}`,
+			"AsHook", &HookData{HookInvocationID: "h1", HookType: "preToolUse", Input: map[string]any{"toolName": "bash"}}},
+		{HookEnd, `{"hookInvocationId":"h1","hookType":"preToolUse","output":{"decision":"allow"},"success":false,"error":{"message":"denied"}}`,
+			"AsHook", &HookData{HookInvocationID: "h1", HookType: "preToolUse", Output: map[string]any{"decision": "allow"}, Error: "denied"}},
+		{SystemMessage, `{"content":"You are helpful","role":"developer","name":"base","metadata":{"promptVersion":"v2"}}`,
+			"AsSystemMessage", &SystemMessageData{Content: "You are helpful", Role: Developer, Name: "base", Metadata: &Metadata{PromptVersion: String("v2")}}},
+	}
+
+	covered := map[SessionEventType]bool{}
+	for _, tt := range tests {
+		covered[tt.eventType] = true
+		t.Run(string(tt.eventType), func(t *testing.T) {
+			event, err := UnmarshalSessionEvent([]byte(`{"id":"e1","timestamp":"2026-03-01T12:00:00Z","parentId":null,"type":"` + string(tt.eventType) + `","data":` + tt.data + `}`))
+			if err != nil {
+				t.Fatalf("Failed to unmarshal: %v", err)
+			}
+			for name, accessor := range accessors {
+				got, ok := accessor(event)
+				if name != tt.accessor {
+					if ok {
+						t.Errorf("Expected %s to reject %s", name, tt.eventType)
+					}
+					continue
+				}
+				if !ok || !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("%s = %+v, %v; want %+v", name, got, ok, tt.want)
+				}
+			}
+		})
+	}
+
+	// Every event type the CLI emits has a case
+	for _, eventType := range []SessionEventType{
+		Abort, AssistantIntent, AssistantMessage, AssistantMessageDelta, AssistantReasoning,
+		AssistantReasoningDelta, AssistantStreamingDelta, AssistantTurnEnd, AssistantTurnStart,
+		AssistantUsage, HookEnd, HookStart, PendingMessagesModified, SessionCompactionComplete,
+		SessionCompactionStart, SessionContextChanged, SessionError, SessionHandoff, SessionIdle,
+		SessionInfo, SessionModeChanged, SessionModelChange, SessionPlanChanged, SessionResume,
+		SessionShutdown, SessionSnapshotRewind, SessionStart, SessionTaskComplete,
+		SessionTitleChanged, SessionTruncation, SessionUsageInfo, SessionWarning,
+		SessionWorkspaceFileChanged, SkillInvoked, SubagentCompleted, SubagentDeselected,
+		SubagentFailed, SubagentSelected, SubagentStarted, SystemMessage, ToolExecutionComplete,
+		ToolExecutionPartialResult, ToolExecutionProgress, ToolExecutionStart, ToolUserRequested,
+		UserMessage,
+	} {
+		if !covered[eventType] {
+			t.Errorf("No payload test for %s", eventType)
+		}
+	}
+}
+
+func TestSessionEvent_PayloadValues(t *testing.T) {
+	t.Run("keeps fractional numbers", func(t *testing.T) {
+		event, err := UnmarshalSessionEvent([]byte(`{"id":"e1","timestamp":"2026-03-01T12:00:00Z","parentId":null,"type":"assistant.usage","data":{"model":"gpt-5","inputTokens":100.5,"cost":0.25,"duration":1.5}}`))
+		if err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+		usage, _ := event.AsAssistantUsage()
+		if usage.InputTokens != 100.5 || usage.Cost != 0.25 || usage.Duration != 1500*time.Microsecond {
+			t.Errorf("Expected the values sent, got %+v", usage)
+		}
+	})
+
+	t.Run("reflects fields the SDK set after decoding", func(t *testing.T) {
+		event, err := UnmarshalSessionEvent([]byte(`{"id":"e1","timestamp":"2026-03-01T12:00:00Z","parentId":null,"type":"tool.execution_complete","data":{"toolCallId":"c1","success":true}}`))
+		if err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+		event.Data.InteractionID = String("i1")
+		event.Data.ToolTelemetry = map[string]any{"retries": 1}
+		data, _ := event.AsToolExecutionComplete()
+		if data.ToolCallID != "c1" || !data.Success || data.InteractionID != "i1" || data.ToolTelemetry["retries"] != float64(1) {
+			t.Errorf("Expected the decoded and SDK-set fields, got %+v", data)
+		}
+	})
+
+	t.Run("reads events created in code", func(t *testing.T) {
+		statusCode := int64(529)
+		event := SessionEvent{Type: SessionError, Data: Data{ErrorType: String("model"), Message: String("overloaded"), StatusCode: &statusCode}}
+		data, _ := event.AsSessionError()
+		if want := (&SessionErrorData{ErrorType: "model", Message: "overloaded", StatusCode: 529}); !reflect.DeepEqual(data, want) {
+			t.Errorf("AsSessionError = %+v; want %+v", data, want)
+		}
+	})
+}
+
+func toolRequestType(t ToolRequestType) *ToolRequestType {
+	return &t
+}
@@ -0,0 +1,320 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/github/copilot-sdk/go/diffutil"
+)
+
+// defaultPendingActionBuffer is the capacity of the channel returned by
+// [Session.PendingActions].
+const defaultPendingActionBuffer = 16
+
+// ErrActionResolved is returned by [PendingAction.Approve] and
+// [PendingAction.Deny] for an action that was already approved or denied,
+// including one denied because nobody answered in time.
+var ErrActionResolved = errors.New("pending action already resolved")
+
+// PendingActionSource is the callback a [PendingAction] resolves.
+type PendingActionSource string
+
+const (
+	// PendingActionFromPermission is a permission request from the CLI.
+	PendingActionFromPermission PendingActionSource = "permission"
+	// PendingActionFromPreToolUse is a preToolUse hook invocation that
+	// OnPreToolUse answered with the "ask" decision.
+	PendingActionFromPreToolUse PendingActionSource = "preToolUse"
+)
+
+// PendingActionOptions surfaces actions awaiting approval on
+// [Session.PendingActions], so a UI can show them with approve and deny
+// buttons. Two callbacks feed it:
+//
+//   - Permission requests, in place of OnPermissionRequest and
+//     PermissionBatching. PermissionPolicy, AutoApprove and an emulated
+//     OnPreToolUse hook still decide first.
+//   - preToolUse hook invocations that OnPreToolUse answers with
+//     PermissionDecision "ask". Approving answers the hook with "allow",
+//     keeping the rest of OnPreToolUse's output, and denying with "deny".
+//
+// The CLI waits for each action until it is approved or denied, the turn is
+// aborted, the session is destroyed or Timeout elapses; in the last three
+// cases the action is denied. Like permission handlers, waiting pauses the
+// timeouts of [Session.SendAndWait] and [Session.SendAndCollect].
+//
+// Example:
+//
+//	session, err := client.CreateSession(ctx, &copilot.SessionConfig{
+//	    PendingActions: &copilot.PendingActionOptions{},
+//	})
+//	go func() {
+//	    for action := range session.PendingActions() {
+//	        if action.Command != "" && confirm("Copilot wants to run "+action.Command) {
+//	            action.Approve()
+//	        } else {
+//	            action.Deny("declined in the UI")
+//	        }
+//	    }
+//	}()
+type PendingActionOptions struct {
+	// Kinds lists the permission request kinds to surface, such as "shell".
+	// Requests of other kinds go to OnPermissionRequest. Empty surfaces every
+	// kind. preToolUse hook invocations are surfaced whatever their tool.
+	Kinds []string
+	// Timeout denies an action that is neither approved nor denied in time.
+	// Zero waits until the turn is aborted or the session destroyed.
+	Timeout time.Duration
+}
+
+// PendingAction is a tool call awaiting approval, with the details a UI
+// needs to present it. Fields that do not apply to the action are empty.
+type PendingAction struct {
+	// ID identifies the action: the tool call's ID if known, otherwise an
+	// ID unique within the session
+	ID     string
+	Source PendingActionSource
+	// Kind is the permission request kind, such as "shell", "write", "read",
+	// "url", "mcp" or "custom-tool". It is empty for actions from a
+	// preToolUse hook, whose tool is named by ToolName.
+	Kind string
+	// ToolName names the tool to run, if known
+	ToolName string
+	// ToolCallID is the ID of the tool call, if known
+	ToolCallID string
+	// Command is the shell command to run
+	Command string
+	// Path is the file to read or write
+	Path string
+	// URL is the URL to fetch
+	URL string
+	// Diff previews the change of a write, as sent by the CLI
+	Diff string
+	// FileDiff is the change of a write normalized into numbered hunks, from
+	// Diff or from the arguments of an edit tool, or nil if the action
+	// carries no change. The lines of an edit tool's change are numbered
+	// from the start of the replaced text, not of the file.
+	FileDiff *diffutil.FileDiff
+	// Intention describes what the assistant intends the action to do
+	Intention string
+	// Args are the tool's arguments
+	Args any
+
+	// Permission is the underlying permission request, for
+	// PendingActionFromPermission
+	Permission *PermissionRequest
+	// PreToolUse is the underlying hook input, for PendingActionFromPreToolUse
+	PreToolUse *PreToolUseHookInput
+
+	state *pendingActionState
+}
+
+// pendingActionState is the resolution of a [PendingAction], shared by its
+// copies.
+type pendingActionState struct {
+	mu       sync.Mutex
+	decision pendingActionDecision
+	done     chan struct{}
+}
+
+// pendingActionDecision resolves a [PendingAction].
+type pendingActionDecision struct {
+	approved bool
+	reason   string
+	// abandoned is set when nobody decided the action
+	abandoned bool
+}
+
+// Approve allows the action. It returns [ErrActionResolved] if the action was
+// already resolved.
+func (a *PendingAction) Approve() error {
+	return a.resolve(pendingActionDecision{approved: true})
+}
+
+// Deny refuses the action, with an optional reason for the assistant. It
+// returns [ErrActionResolved] if the action was already resolved.
+func (a *PendingAction) Deny(reason string) error {
+	return a.resolve(pendingActionDecision{reason: reason})
+}
+
+// Done returns a channel closed once the action is resolved, for removing
+// prompts that were answered elsewhere or timed out.
+func (a *PendingAction) Done() <-chan struct{} {
+	return a.state.done
+}
+
+func (a *PendingAction) resolve(decision pendingActionDecision) error {
+	s := a.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		return ErrActionResolved
+	default:
+	}
+	s.decision = decision
+	close(s.done)
+	return nil
+}
+
+// result returns the decision on a resolved action.
+func (a *PendingAction) result() pendingActionDecision {
+	a.state.mu.Lock()
+	defer a.state.mu.Unlock()
+	return a.state.decision
+}
+
+// pendingActions delivers a session's pending actions and waits for their
+// resolution.
+type pendingActions struct {
+	kinds   map[string]bool // nil surfaces every kind
+	timeout time.Duration
+	actions chan *PendingAction
+	nextID  atomic.Int64
+}
+
+func newPendingActions(options PendingActionOptions) *pendingActions {
+	p := &pendingActions{
+		timeout: options.Timeout,
+		actions: make(chan *PendingAction, defaultPendingActionBuffer),
+	}
+	if len(options.Kinds) > 0 {
+		p.kinds = make(map[string]bool, len(options.Kinds))
+		for _, kind := range options.Kinds {
+			p.kinds[kind] = true
+		}
+	}
+	return p
+}
+
+// accepts reports whether permission requests of kind are surfaced.
+func (p *pendingActions) accepts(kind string) bool {
+	return p.kinds == nil || p.kinds[kind]
+}
+
+// await delivers action and waits until it is resolved, denying it when ctx
+// is done or the timeout elapses first.
+func (p *pendingActions) await(ctx context.Context, action *PendingAction) pendingActionDecision {
+	action.state = &pendingActionState{done: make(chan struct{})}
+	if action.ID == "" {
+		action.ID = "action-" + strconv.FormatInt(p.nextID.Add(1), 10)
+	}
+	var expired <-chan time.Time
+	if p.timeout > 0 {
+		timer := time.NewTimer(p.timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	abandon := func(reason string) pendingActionDecision {
+		// Approve or Deny may have won the race
+		action.resolve(pendingActionDecision{reason: reason, abandoned: true})
+		return action.result()
+	}
+	select {
+	case p.actions <- action:
+	case <-ctx.Done():
+		return abandon("the turn ended before the action was decided")
+	case <-expired:
+		return abandon("no decision on the action in time")
+	}
+	select {
+	case <-action.Done():
+		return action.result()
+	case <-ctx.Done():
+		return abandon("the turn ended before the action was decided")
+	case <-expired:
+		return abandon("no decision on the action in time")
+	}
+}
+
+// PendingActions returns the channel on which the session delivers actions
+// awaiting approval, if [SessionConfig.PendingActions] is set, or nil
+// otherwise. Each action must be approved or denied; the CLI waits for it.
+// The channel is never closed.
+//
+// Example:
+//
+//	for action := range session.PendingActions() {
+//	    fmt.Printf("Copilot wants to %s %s%s\n", action.Kind, action.Command, action.Path)
+//	    action.Approve()
+//	}
+func (s *Session) PendingActions() <-chan *PendingAction {
+	if s.pendingActions == nil {
+		return nil
+	}
+	return s.pendingActions.actions
+}
+
+// awaitPermission surfaces a permission request as a pending action and
+// returns the decision on it.
+func (s *Session) awaitPermission(request PermissionRequest) PermissionRequestResult {
+	action := &PendingAction{
+		ID:         request.ToolCallID,
+		Source:     PendingActionFromPermission,
+		Kind:       request.Kind,
+		ToolCallID: request.ToolCallID,
+		ToolName:   stringField(request.Extra, "toolName"),
+		Command:    stringField(request.Extra, "fullCommandText", "command"),
+		Path:       permissionRequestPath(request),
+		URL:        stringField(request.Extra, "url"),
+		Diff:       stringField(request.Extra, "diff"),
+		Intention:  stringField(request.Extra, "intention"),
+		Args:       request.Extra["args"],
+		Permission: &request,
+	}
+	action.FileDiff = fileDiff(action.Path, request.Extra)
+	ctx, _, _ := s.trace.current()
+	defer s.state.begin(holdPermission, "")()
+	decision := s.pendingActions.await(ctx, action)
+	switch {
+	case decision.approved:
+		return Approved()
+	case decision.abandoned:
+		return DeniedNoApprovalRule()
+	}
+	return DeniedByUser(decision.reason)
+}
+
+// awaitPreToolUse surfaces a preToolUse hook invocation that OnPreToolUse
+// answered with "ask" as a pending action, and returns output with the
+// decision on it.
+func (s *Session) awaitPreToolUse(ctx context.Context, input PreToolUseHookInput, output PreToolUseHookOutput) *PreToolUseHookOutput {
+	args, _ := input.ToolArgs.(map[string]any)
+	action := &PendingAction{
+		Source:     PendingActionFromPreToolUse,
+		ToolName:   input.ToolName,
+		Command:    stringField(args, "command"),
+		Path:       toolPath(args),
+		URL:        stringField(args, "url"),
+		Diff:       stringField(args, "diff"),
+		FileDiff:   toolFileDiff(input.ToolArgs),
+		Intention:  stringField(args, "description"),
+		Args:       input.ToolArgs,
+		PreToolUse: &input,
+	}
+	defer s.state.begin(holdPermission, "")()
+	decision := s.pendingActions.await(ctx, action)
+	if decision.approved {
+		output.PermissionDecision = "allow"
+		output.PermissionDecisionReason = ""
+	} else {
+		output.PermissionDecision = "deny"
+		output.PermissionDecisionReason = decision.reason
+	}
+	return &output
+}
+
+// stringField returns the first non-empty string value of keys in m.
+func stringField(m map[string]any, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := m[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
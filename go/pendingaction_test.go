@@ -0,0 +1,186 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSession_PendingActions(t *testing.T) {
+	newPendingSession := func(options PendingActionOptions) *Session {
+		session := &Session{SessionID: "s1"}
+		session.pendingActions = newPendingActions(options)
+		return session
+	}
+	// next returns the next pending action of session.
+	next := func(t *testing.T, session *Session) *PendingAction {
+		t.Helper()
+		select {
+		case action := <-session.PendingActions():
+			return action
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for a pending action")
+			return nil
+		}
+	}
+	// request sends a permission request in the background and returns its result.
+	request := func(session *Session, request PermissionRequest) <-chan PermissionRequestResult {
+		results := make(chan PermissionRequestResult, 1)
+		go func() {
+			result, _ := session.handlePermissionRequest(request)
+			results <- result
+		}()
+		return results
+	}
+
+	t.Run("maps permission requests", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			request PermissionRequest
+			want    PendingAction
+		}{
+			{"shell", PermissionRequest{Kind: "shell", ToolCallID: "call-1", Extra: map[string]any{
+				"fullCommandText": "go test ./...", "intention": "Run the tests",
+			}}, PendingAction{ID: "call-1", Kind: "shell", ToolCallID: "call-1", Command: "go test ./...", Intention: "Run the tests"}},
+			{"write", PermissionRequest{Kind: "write", Extra: map[string]any{
+				"fileName": "/src/main.go", "diff": "-a\n+b\n", "intention": "Fix the bug",
+			}}, PendingAction{ID: "action-1", Kind: "write", Path: "/src/main.go", Diff: "-a\n+b\n", Intention: "Fix the bug"}},
+			{"read", PermissionRequest{Kind: "read", Extra: map[string]any{"path": "/etc/hosts"}},
+				PendingAction{ID: "action-1", Kind: "read", Path: "/etc/hosts"}},
+			{"url", PermissionRequest{Kind: "url", Extra: map[string]any{"url": "https://example.com"}},
+				PendingAction{ID: "action-1", Kind: "url", URL: "https://example.com"}},
+			{"mcp", PermissionRequest{Kind: "mcp", ToolCallID: "call-2", Extra: map[string]any{
+				"serverName": "github", "toolName": "list_issues", "args": map[string]any{"repo": "sdk"},
+			}}, PendingAction{ID: "call-2", Kind: "mcp", ToolCallID: "call-2", ToolName: "list_issues", Args: map[string]any{"repo": "sdk"}}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				session := newPendingSession(PendingActionOptions{})
+				results := request(session, tt.request)
+				action := next(t, session)
+
+				got := PendingAction{
+					ID: action.ID, Kind: action.Kind, ToolName: action.ToolName, ToolCallID: action.ToolCallID,
+					Command: action.Command, Path: action.Path, URL: action.URL, Diff: action.Diff,
+					Intention: action.Intention, Args: action.Args,
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Expected %+v, got %+v", tt.want, got)
+				}
+				if action.Source != PendingActionFromPermission || action.Permission == nil || action.Permission.Kind != tt.request.Kind {
+					t.Errorf("Expected the permission request as source, got %s %+v", action.Source, action.Permission)
+				}
+				if err := action.Approve(); err != nil {
+					t.Fatalf("Failed to approve: %v", err)
+				}
+				if result := <-results; !result.Approved() {
+					t.Errorf("Expected the request to be approved, got %v", result)
+				}
+			})
+		}
+	})
+
+	t.Run("denies with the reason given", func(t *testing.T) {
+		session := newPendingSession(PendingActionOptions{})
+		results := request(session, PermissionRequest{Kind: "shell"})
+		action := next(t, session)
+		if err := action.Deny("not on main"); err != nil {
+			t.Fatalf("Failed to deny: %v", err)
+		}
+		if result := <-results; result.Kind != PermissionDeniedByUser || result.Reason != "not on main" {
+			t.Errorf("Expected the request to be denied by the user, got %+v", result)
+		}
+		if err := action.Approve(); !errors.Is(err, ErrActionResolved) {
+			t.Errorf("Expected ErrActionResolved, got %v", err)
+		}
+	})
+
+	t.Run("passes other kinds to OnPermissionRequest", func(t *testing.T) {
+		session := newPendingSession(PendingActionOptions{Kinds: []string{"shell"}})
+		session.registerPermissionHandler(PermissionHandler.ApproveAll)
+		if result := <-request(session, PermissionRequest{Kind: "read"}); !result.Approved() {
+			t.Errorf("Expected OnPermissionRequest to approve, got %v", result)
+		}
+		select {
+		case action := <-session.PendingActions():
+			t.Errorf("Expected no pending action, got %+v", action)
+		default:
+		}
+	})
+
+	t.Run("denies actions nobody decides", func(t *testing.T) {
+		session := newPendingSession(PendingActionOptions{Timeout: 20 * time.Millisecond})
+		results := request(session, PermissionRequest{Kind: "shell"})
+		action := next(t, session)
+		if result := <-results; result.Kind != PermissionDeniedNoApprovalRule {
+			t.Errorf("Expected the request to be denied, got %v", result)
+		}
+		select {
+		case <-action.Done():
+		default:
+			t.Error("Expected the action to be done")
+		}
+		if err := action.Approve(); !errors.Is(err, ErrActionResolved) {
+			t.Errorf("Expected ErrActionResolved, got %v", err)
+		}
+
+		session = newPendingSession(PendingActionOptions{})
+		results = request(session, PermissionRequest{Kind: "shell"})
+		next(t, session)
+		session.markDestroyed("destroyed by caller")
+		if result := <-results; result.Kind != PermissionDeniedNoApprovalRule {
+			t.Errorf("Expected the request to be denied when the session is destroyed, got %v", result)
+		}
+	})
+
+	t.Run("maps preToolUse hooks answered with ask", func(t *testing.T) {
+		session := newPendingSession(PendingActionOptions{})
+		session.registerHooks(&SessionHooks{
+			OnPreToolUse: func(input PreToolUseHookInput, _ HookInvocation) (*PreToolUseHookOutput, error) {
+				if input.ToolName == "view" {
+					return &PreToolUseHookOutput{PermissionDecision: "allow"}, nil
+				}
+				return &PreToolUseHookOutput{PermissionDecision: "ask", AdditionalContext: "CI is red"}, nil
+			},
+		})
+		invoke := func(input string) <-chan *PreToolUseHookOutput {
+			outputs := make(chan *PreToolUseHookOutput, 1)
+			go func() {
+				output, err := session.handleHooksInvoke("preToolUse", json.RawMessage(input))
+				if err != nil {
+					t.Errorf("Hook failed: %v", err)
+				}
+				outputs <- output.(*PreToolUseHookOutput)
+			}()
+			return outputs
+		}
+
+		outputs := invoke(`{"toolName":"bash","toolArgs":{"command":"go test ./...","description":"Run the tests"}}`)
+		action := next(t, session)
+		want := PendingAction{ID: "action-1", Source: PendingActionFromPreToolUse, ToolName: "bash", Command: "go test ./...", Intention: "Run the tests"}
+		got := PendingAction{ID: action.ID, Source: action.Source, ToolName: action.ToolName, Command: action.Command, Intention: action.Intention}
+		if !reflect.DeepEqual(got, want) || action.PreToolUse == nil || action.Kind != "" {
+			t.Errorf("Expected %+v, got %+v", want, action)
+		}
+		action.Approve()
+		if output := <-outputs; output.PermissionDecision != "allow" || output.AdditionalContext != "CI is red" {
+			t.Errorf("Expected the hook to allow the tool, got %+v", output)
+		}
+
+		outputs = invoke(`{"toolName":"edit","toolArgs":{"path":"/src/main.go"}}`)
+		action = next(t, session)
+		if action.Path != "/src/main.go" {
+			t.Errorf("Expected the path, got %+v", action)
+		}
+		action.Deny("read-only review")
+		if output := <-outputs; output.PermissionDecision != "deny" || output.PermissionDecisionReason != "read-only review" {
+			t.Errorf("Expected the hook to deny the tool, got %+v", output)
+		}
+
+		if output := <-invoke(`{"toolName":"view","toolArgs":{}}`); output.PermissionDecision != "allow" {
+			t.Errorf("Expected decided hooks to pass through, got %+v", output)
+		}
+	})
+}
@@ -0,0 +1,264 @@
+package copilot
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Defaults of [PermissionBatching].
+const (
+	defaultPermissionBatchWindow  = 100 * time.Millisecond
+	defaultPermissionBatchMaxSize = 50
+)
+
+// PermissionBatching coalesces permission requests of the same kind, such as
+// the write requests of an agent editing many files at once, and presents
+// them to a handler as one [PermissionBatch].
+//
+// The CLI asks for each permission separately, so the SDK holds requests
+// that arrive within Window of the first request of a batch and answers each
+// of them with the batch's decision. A request waits at most Window before
+// the batch is presented, so batching adds a bounded delay. Requests the CLI
+// sends one after another, waiting for each answer, end up in batches of
+// one.
+//
+// Example:
+//
+//	session, err := client.CreateSession(ctx, &copilot.SessionConfig{
+//	    OnPermissionRequest: askUser,
+//	    PermissionBatching: &copilot.PermissionBatching{
+//	        Kinds: []string{"write"},
+//	        OnBatch: func(batch copilot.PermissionBatch, _ copilot.PermissionInvocation) (copilot.PermissionBatchResult, error) {
+//	            if confirm(fmt.Sprintf("Allow %d file writes?", len(batch.Requests))) {
+//	                return copilot.PermissionBatchResult{Result: copilot.Approved()}, nil
+//	            }
+//	            return copilot.PermissionBatchResult{Result: copilot.DeniedByUser("")}, nil
+//	        },
+//	    },
+//	})
+type PermissionBatching struct {
+	// OnBatch decides batched requests. Batching is off if it is nil.
+	OnBatch PermissionBatchHandlerFunc
+	// Kinds lists the request kinds to batch, such as "write". Requests of
+	// other kinds go to OnPermissionRequest. Empty batches every kind.
+	Kinds []string
+	// Window is how long the first request of a batch waits for more
+	// requests of its kind. Default: 100ms.
+	Window time.Duration
+	// MaxSize presents a batch as soon as it holds this many requests.
+	// Default: 50.
+	MaxSize int
+}
+
+// PermissionBatch holds permission requests of one kind, in the order the
+// CLI sent them.
+type PermissionBatch struct {
+	Kind     string
+	Requests []PermissionRequest
+}
+
+// PermissionBatchResult is the decision on a [PermissionBatch].
+type PermissionBatchResult struct {
+	// Result answers every request of the batch, unless Results is set
+	Result PermissionRequestResult
+	// Results, if set, answers each request of the batch, in order. It must
+	// have one result per request.
+	Results []PermissionRequestResult
+}
+
+// PermissionBatchHandlerFunc decides a batch of permission requests.
+// Returning an error denies every request of the batch, as does returning
+// Results of the wrong length. Each result whose Kind the CLI does not accept
+// denies its request.
+type PermissionBatchHandlerFunc func(batch PermissionBatch, invocation PermissionInvocation) (PermissionBatchResult, error)
+
+// permissionBatcher holds a session's permission requests until their batch
+// is presented to the handler.
+type permissionBatcher struct {
+	session *Session
+	handler PermissionBatchHandlerFunc
+	kinds   map[string]bool // nil batches every kind
+	window  time.Duration
+	maxSize int
+
+	mu      sync.Mutex
+	pending map[string]*pendingPermissionBatch
+	stopped bool
+}
+
+// pendingPermissionBatch is a batch that has not been presented yet.
+type pendingPermissionBatch struct {
+	kind     string
+	requests []PermissionRequest
+	replies  []chan permissionReply
+	timer    *time.Timer
+}
+
+// permissionReply answers one batched request.
+type permissionReply struct {
+	result PermissionRequestResult
+	err    error
+}
+
+func newPermissionBatcher(session *Session, options PermissionBatching) *permissionBatcher {
+	b := &permissionBatcher{
+		session: session,
+		handler: options.OnBatch,
+		window:  options.Window,
+		maxSize: options.MaxSize,
+		pending: make(map[string]*pendingPermissionBatch),
+	}
+	if b.window <= 0 {
+		b.window = defaultPermissionBatchWindow
+	}
+	if b.maxSize <= 0 {
+		b.maxSize = defaultPermissionBatchMaxSize
+	}
+	if len(options.Kinds) > 0 {
+		b.kinds = make(map[string]bool, len(options.Kinds))
+		for _, kind := range options.Kinds {
+			b.kinds[kind] = true
+		}
+	}
+	return b
+}
+
+// decidesPermissions reports whether a session config sets something to
+// decide permission requests.
+func decidesPermissions(handler PermissionHandlerFunc, policy *PermissionPolicy, autoApprove *AutoApprovePolicy, batching *PermissionBatching, pending *PendingActionOptions) bool {
+	return handler != nil || policy != nil || autoApprove != nil || (batching != nil && batching.OnBatch != nil) || pending != nil
+}
+
+// accepts reports whether requests of kind are batched.
+func (b *permissionBatcher) accepts(kind string) bool {
+	return b.kinds == nil || b.kinds[kind]
+}
+
+// submit adds request to the pending batch of its kind and waits for the
+// batch's decision on it.
+func (b *permissionBatcher) submit(request PermissionRequest) (PermissionRequestResult, error) {
+	reply := make(chan permissionReply, 1)
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return DeniedNoApprovalRule(), nil
+	}
+	batch := b.pending[request.Kind]
+	if batch == nil {
+		batch = &pendingPermissionBatch{kind: request.Kind}
+		b.pending[request.Kind] = batch
+		batch.timer = time.AfterFunc(b.window, func() { b.flush(batch) })
+	}
+	batch.requests = append(batch.requests, request)
+	batch.replies = append(batch.replies, reply)
+	full := len(batch.requests) >= b.maxSize
+	b.mu.Unlock()
+
+	if full {
+		b.flush(batch)
+	}
+	r := <-reply
+	return r.result, r.err
+}
+
+// take removes batch from the pending batches. It returns false if the batch
+// was already taken.
+func (b *permissionBatcher) take(batch *pendingPermissionBatch) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pending[batch.kind] != batch {
+		return false
+	}
+	delete(b.pending, batch.kind)
+	batch.timer.Stop()
+	return true
+}
+
+// flush presents batch to the handler and answers its requests, unless the
+// batch was already presented.
+func (b *permissionBatcher) flush(batch *pendingPermissionBatch) {
+	if !b.take(batch) {
+		return
+	}
+	replies := b.decide(PermissionBatch{Kind: batch.kind, Requests: batch.requests})
+	for i, reply := range batch.replies {
+		reply <- replies[i]
+	}
+}
+
+// decide calls the handler and returns one reply per request of batch.
+func (b *permissionBatcher) decide(batch PermissionBatch) []permissionReply {
+	s := b.session
+	replies := make([]permissionReply, len(batch.Requests))
+	fail := func(err error) []permissionReply {
+		for i := range replies {
+			replies[i] = permissionReply{err: err}
+		}
+		return replies
+	}
+
+	result, err := b.call(batch)
+	if err != nil {
+		return fail(err)
+	}
+	if result.Results != nil && len(result.Results) != len(batch.Requests) {
+		return fail(fmt.Errorf("permission batch handler returned %d results for %d requests", len(result.Results), len(batch.Requests)))
+	}
+	for i := range replies {
+		decision := result.Result
+		if result.Results != nil {
+			decision = result.Results[i]
+		}
+		if err := s.checkPermissionResult(decision); err != nil {
+			replies[i] = permissionReply{err: err}
+			continue
+		}
+		replies[i] = permissionReply{result: decision}
+	}
+	return replies
+}
+
+// call calls the handler, recovering from panics, which would otherwise
+// leave the batch's other requests unanswered.
+func (b *permissionBatcher) call(batch PermissionBatch) (result PermissionBatchResult, err error) {
+	s := b.session
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("permission batch handler panicked", "sessionID", s.SessionID, "panic", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("permission batch handler panicked: %v", r)
+		}
+	}()
+
+	ctx, messageID, traceID := s.trace.current()
+	invocation := PermissionInvocation{
+		SessionID: s.SessionID,
+		Context:   ctx,
+		MessageID: messageID,
+		TraceID:   traceID,
+	}
+	defer s.state.begin(holdPermission, "")()
+	return b.handler(batch, invocation)
+}
+
+// stop denies the pending requests and any submitted later, when the session
+// is destroyed.
+func (b *permissionBatcher) stop() {
+	b.mu.Lock()
+	b.stopped = true
+	batches := make([]*pendingPermissionBatch, 0, len(b.pending))
+	for _, batch := range b.pending {
+		batches = append(batches, batch)
+	}
+	b.mu.Unlock()
+
+	for _, batch := range batches {
+		if !b.take(batch) {
+			continue
+		}
+		for _, reply := range batch.replies {
+			reply <- permissionReply{result: DeniedNoApprovalRule()}
+		}
+	}
+}
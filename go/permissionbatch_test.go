@@ -0,0 +1,164 @@
+package copilot
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/sdklog"
+)
+
+func TestSession_PermissionBatching(t *testing.T) {
+	newBatchSession := func(options PermissionBatching, handler PermissionHandlerFunc) *Session {
+		session := &Session{SessionID: "s1"}
+		session.registerPermissionHandler(handler)
+		session.permissionBatcher = newPermissionBatcher(session, options)
+		return session
+	}
+	type outcome struct {
+		result PermissionRequestResult
+		err    error
+	}
+	// burst sends n write requests at once and returns their outcomes in order.
+	burst := func(session *Session, n int) []outcome {
+		outcomes := make([]outcome, n)
+		var wg sync.WaitGroup
+		for i := range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				request := PermissionRequest{Kind: "write", Extra: map[string]any{"fileName": fmt.Sprintf("file%d.go", i)}}
+				result, err := session.handlePermissionRequest(request)
+				outcomes[i] = outcome{result, err}
+			}()
+		}
+		wg.Wait()
+		return outcomes
+	}
+
+	t.Run("presents a burst of requests as one batch", func(t *testing.T) {
+		var mu sync.Mutex
+		var batches []PermissionBatch
+		session := newBatchSession(PermissionBatching{
+			Kinds:  []string{"write"},
+			Window: 50 * time.Millisecond,
+			OnBatch: func(batch PermissionBatch, _ PermissionInvocation) (PermissionBatchResult, error) {
+				mu.Lock()
+				batches = append(batches, batch)
+				mu.Unlock()
+				return PermissionBatchResult{Result: Approved()}, nil
+			},
+		}, func(PermissionRequest, PermissionInvocation) (PermissionRequestResult, error) {
+			return DeniedByUser("not batched"), nil
+		})
+
+		for i, o := range burst(session, 40) {
+			if o.err != nil || !o.result.Approved() {
+				t.Errorf("Expected request %d to be approved, got %v %v", i, o.result, o.err)
+			}
+		}
+		if len(batches) != 1 || len(batches[0].Requests) != 40 || batches[0].Kind != "write" {
+			t.Errorf("Expected one batch of 40 writes, got %d batches", len(batches))
+		}
+		if result, _ := session.handlePermissionRequest(PermissionRequest{Kind: "shell"}); result.Kind != PermissionDeniedByUser {
+			t.Errorf("Expected other kinds to go to OnPermissionRequest, got %v", result)
+		}
+	})
+
+	t.Run("answers each request with its own decision", func(t *testing.T) {
+		session := newBatchSession(PermissionBatching{
+			Window: 50 * time.Millisecond,
+			OnBatch: func(batch PermissionBatch, _ PermissionInvocation) (PermissionBatchResult, error) {
+				results := make([]PermissionRequestResult, len(batch.Requests))
+				for i, request := range batch.Requests {
+					results[i] = Approved()
+					if request.Extra["fileName"] == "file1.go" {
+						results[i] = DeniedByUser("keep file1.go")
+					}
+				}
+				return PermissionBatchResult{Results: results}, nil
+			},
+		}, nil)
+
+		outcomes := burst(session, 3)
+		if outcomes[0].result.Kind != PermissionApproved || outcomes[1].result.Kind != PermissionDeniedByUser || outcomes[2].result.Kind != PermissionApproved {
+			t.Errorf("Unexpected outcomes %+v", outcomes)
+		}
+	})
+
+	t.Run("presents full batches without waiting", func(t *testing.T) {
+		var mu sync.Mutex
+		var sizes []int
+		session := newBatchSession(PermissionBatching{
+			Window:  time.Hour,
+			MaxSize: 5,
+			OnBatch: func(batch PermissionBatch, _ PermissionInvocation) (PermissionBatchResult, error) {
+				mu.Lock()
+				sizes = append(sizes, len(batch.Requests))
+				mu.Unlock()
+				return PermissionBatchResult{Result: Approved()}, nil
+			},
+		}, nil)
+
+		done := make(chan struct{})
+		go func() {
+			burst(session, 10)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for full batches")
+		}
+		if len(sizes) != 2 || sizes[0] != 5 || sizes[1] != 5 {
+			t.Errorf("Expected two batches of 5, got %v", sizes)
+		}
+	})
+
+	t.Run("denies the batch when the handler fails", func(t *testing.T) {
+		for name, handler := range map[string]PermissionBatchHandlerFunc{
+			"wrong number of results": func(PermissionBatch, PermissionInvocation) (PermissionBatchResult, error) {
+				return PermissionBatchResult{Results: []PermissionRequestResult{Approved()}}, nil
+			},
+			"panic": func(PermissionBatch, PermissionInvocation) (PermissionBatchResult, error) {
+				panic("handler failed")
+			},
+		} {
+			session := newBatchSession(PermissionBatching{Window: 20 * time.Millisecond, OnBatch: handler}, nil)
+			session.logger = sdklog.New(io.Discard, false)
+			for i, o := range burst(session, 3) {
+				if o.err == nil {
+					t.Errorf("%s: expected request %d to fail, got %v", name, i, o.result)
+				}
+			}
+		}
+	})
+
+	t.Run("denies pending requests when the session is destroyed", func(t *testing.T) {
+		session := newBatchSession(PermissionBatching{
+			Window: time.Hour,
+			OnBatch: func(PermissionBatch, PermissionInvocation) (PermissionBatchResult, error) {
+				t.Error("Expected the handler not to be called")
+				return PermissionBatchResult{Result: Approved()}, nil
+			},
+		}, nil)
+
+		results := make(chan PermissionRequestResult, 1)
+		go func() {
+			result, _ := session.handlePermissionRequest(PermissionRequest{Kind: "write"})
+			results <- result
+		}()
+		time.Sleep(20 * time.Millisecond)
+		session.markDestroyed("destroyed by caller")
+		select {
+		case result := <-results:
+			if result.Kind != PermissionDeniedNoApprovalRule {
+				t.Errorf("Expected the request to be denied, got %v", result)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the pending request")
+		}
+	})
+}
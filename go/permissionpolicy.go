@@ -0,0 +1,310 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidPermissionPolicy matches errors from [Client.CreateSession],
+// [Client.ResumeSessionWithOptions] and [LoadSessionConfig] for a
+// [PermissionPolicy] with a rule that has an unknown action or a malformed
+// pattern.
+var ErrInvalidPermissionPolicy = errors.New("invalid permission policy")
+
+// PermissionAction is what a [PermissionRule] does with the requests it
+// matches.
+type PermissionAction string
+
+const (
+	// PermissionAllow approves the request
+	PermissionAllow PermissionAction = "allow"
+	// PermissionDeny denies the request
+	PermissionDeny PermissionAction = "deny"
+	// PermissionAsk passes the request on to [SessionConfig.AutoApprove] and
+	// OnPermissionRequest without evaluating later rules
+	PermissionAsk PermissionAction = "ask"
+)
+
+// PermissionRule matches permission requests and allows, denies or passes
+// them on. Empty fields match any request; a rule with no matchers set
+// matches every request, which makes it a default at the end of a policy.
+type PermissionRule struct {
+	// Name identifies the rule in a [PermissionExplanation]. Optional.
+	Name string `json:"name,omitempty"`
+	// Kind matches the request kind: "shell", "write", "read", "url", "mcp"
+	// or "custom-tool".
+	Kind string `json:"kind,omitempty"`
+	// Tool matches the name of the tool asking for permission, with * and ?
+	// wildcards as in [path.Match], e.g. "github-*". Requests that don't name
+	// their tool, such as shell requests from some CLIs, are matched by kind.
+	Tool string `json:"tool,omitempty"`
+	// Command matches a shell command. * matches any text, so "git *"
+	// matches every git command; a trailing " *" also matches the bare
+	// command, e.g. "git". A command chained with ;, &&, || or |, or with
+	// subshells, matches an Allow rule only if each part matches, and a Deny
+	// or Ask rule if any part does. A command with substitutions or
+	// redirections never matches an Allow rule. Requests without a command
+	// do not match.
+	Command string `json:"command,omitempty"`
+	// PathGlob matches the file a request targets. ** matches any number of
+	// directories, and *, ? and [...] match within a name as in
+	// [path.Match], so "/etc/**" matches /etc and everything under it.
+	// Relative patterns and paths are resolved against the session's working
+	// directory; symbolic links are not resolved. Requests without a path do
+	// not match.
+	PathGlob string `json:"pathGlob,omitempty"`
+	// Action is what the rule does with the requests it matches
+	Action PermissionAction `json:"action"`
+	// Reason is sent with requests a Deny rule denies. Optional.
+	Reason string `json:"reason,omitempty"`
+}
+
+// PermissionPolicy answers permission requests with declarative rules,
+// evaluated in the SDK before [SessionConfig.AutoApprove] and
+// OnPermissionRequest. The first rule that matches a request decides it;
+// requests no rule matches, or matched by an Ask rule, are passed on to
+// AutoApprove and OnPermissionRequest, or denied if neither is set.
+//
+// Example:
+//
+//	PermissionPolicy: &copilot.PermissionPolicy{
+//	    Rules: []copilot.PermissionRule{
+//	        {Kind: "write", PathGlob: "/etc/**", Action: copilot.PermissionDeny, Reason: "system files are off limits"},
+//	        {Kind: "shell", Command: "git push *", Action: copilot.PermissionAsk},
+//	        {Kind: "shell", Command: "git *", Action: copilot.PermissionAllow},
+//	        {Kind: "read", Action: copilot.PermissionAllow},
+//	    },
+//	    OnDecision: func(req copilot.PermissionRequest, e copilot.PermissionExplanation) {
+//	        log.Printf("permission %s: %v", req.Kind, e)
+//	    },
+//	},
+type PermissionPolicy struct {
+	// Rules are evaluated in order
+	Rules []PermissionRule
+	// OnDecision is called with the explanation of every request the policy
+	// evaluates, including those it passes on, for logging.
+	OnDecision func(request PermissionRequest, explanation PermissionExplanation)
+}
+
+// PermissionExplanation says which rule of a [PermissionPolicy] matched a
+// request, as returned by [PermissionPolicy.Explain].
+type PermissionExplanation struct {
+	// Matched reports whether a rule matched the request
+	Matched bool
+	// Index is the position of the matching rule in Rules, or -1
+	Index int
+	// Rule is the matching rule
+	Rule PermissionRule
+}
+
+// Action returns what the policy does with the request: the matching rule's
+// action, or [PermissionAsk] if no rule matched.
+func (e PermissionExplanation) Action() PermissionAction {
+	if !e.Matched {
+		return PermissionAsk
+	}
+	return e.Rule.Action
+}
+
+func (e PermissionExplanation) String() string {
+	if !e.Matched {
+		return "no rule matched"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "rule %d", e.Index)
+	if e.Rule.Name != "" {
+		fmt.Fprintf(&b, " %q", e.Rule.Name)
+	}
+	var matchers []string
+	for _, m := range [][2]string{{"kind", e.Rule.Kind}, {"tool", e.Rule.Tool}, {"command", e.Rule.Command}, {"pathGlob", e.Rule.PathGlob}} {
+		if m[1] != "" {
+			matchers = append(matchers, fmt.Sprintf("%s %q", m[0], m[1]))
+		}
+	}
+	if len(matchers) > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(matchers, ", "))
+	}
+	fmt.Fprintf(&b, " matched: %s", e.Rule.Action)
+	return b.String()
+}
+
+// Explain returns which rule decides a request, without deciding it.
+// Relative paths are resolved against workingDirectory. It needs no
+// session, so a policy can be tested on its own.
+//
+// Example:
+//
+//	e := policy.Explain(copilot.PermissionRequest{
+//	    Kind:  "shell",
+//	    Extra: map[string]any{"fullCommandText": "git status"},
+//	}, "/work")
+//	fmt.Println(e) // rule 2 (kind "shell", command "git *") matched: allow
+func (p *PermissionPolicy) Explain(request PermissionRequest, workingDirectory string) PermissionExplanation {
+	for i, rule := range p.Rules {
+		if rule.matches(request, workingDirectory) {
+			return PermissionExplanation{Matched: true, Index: i, Rule: rule}
+		}
+	}
+	return PermissionExplanation{Index: -1}
+}
+
+// validate checks the rules' actions and patterns.
+func (p *PermissionPolicy) validate() error {
+	for i, rule := range p.Rules {
+		switch rule.Action {
+		case PermissionAllow, PermissionDeny, PermissionAsk:
+		default:
+			return fmt.Errorf("%w: rule %d: unknown action %q, want allow, deny or ask", ErrInvalidPermissionPolicy, i, rule.Action)
+		}
+		if _, err := path.Match(rule.Tool, ""); err != nil {
+			return fmt.Errorf("%w: rule %d: tool %q: %v", ErrInvalidPermissionPolicy, i, rule.Tool, err)
+		}
+		for _, segment := range strings.Split(filepath.ToSlash(rule.PathGlob), "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("%w: rule %d: pathGlob %q: %v", ErrInvalidPermissionPolicy, i, rule.PathGlob, err)
+			}
+		}
+	}
+	return nil
+}
+
+// matches reports whether every matcher the rule sets matches the request.
+func (r PermissionRule) matches(request PermissionRequest, workingDirectory string) bool {
+	if r.Kind != "" && r.Kind != request.Kind {
+		return false
+	}
+	if r.Tool != "" {
+		tool := stringField(request.Extra, "toolName")
+		if tool == "" {
+			tool = request.Kind
+		}
+		if ok, _ := path.Match(r.Tool, tool); !ok {
+			return false
+		}
+	}
+	if r.Command != "" {
+		command := stringField(request.Extra, "fullCommandText", "command")
+		if command == "" || !matchCommand(r.Command, command, r.Action == PermissionAllow) {
+			return false
+		}
+	}
+	if r.PathGlob != "" {
+		target := permissionRequestPath(request)
+		if target == "" || !matchPathGlob(resolvePolicyPath(r.PathGlob, workingDirectory), resolvePolicyPath(target, workingDirectory)) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchCommand matches a shell command against a Command pattern. With
+// every set, each part of a chained command must match and commands that
+// substitute or redirect do not match; otherwise one matching part is
+// enough.
+func matchCommand(pattern, command string, every bool) bool {
+	if every && (strings.ContainsAny(command, "`<>") || strings.Contains(command, "$(")) {
+		return false
+	}
+	pattern = strings.Join(strings.Fields(pattern), " ")
+	parts := splitShellCommand(command)
+	if len(parts) == 0 {
+		return false
+	}
+	for _, part := range parts {
+		matched := matchWildcard(pattern, part) || (strings.HasSuffix(pattern, " *") && part == strings.TrimSuffix(pattern, " *"))
+		if every && !matched {
+			return false
+		}
+		if !every && matched {
+			return true
+		}
+	}
+	return every
+}
+
+// splitShellCommand splits a command into the commands it runs, at ;, &,
+// |, newlines, parentheses and backquotes, with runs of whitespace
+// collapsed. Quotes are not interpreted, so a quoted separator splits too,
+// which only makes Allow rules stricter and Deny rules broader.
+func splitShellCommand(command string) []string {
+	fields := strings.FieldsFunc(command, func(r rune) bool {
+		return strings.ContainsRune(";&|\n()`", r)
+	})
+	var parts []string
+	for _, field := range fields {
+		if part := strings.Join(strings.Fields(field), " "); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// matchWildcard matches s against a pattern in which * matches any text
+// and every other character matches itself.
+func matchWildcard(pattern, s string) bool {
+	star, retry := -1, 0
+	for i, j := 0, 0; j < len(s) || i < len(pattern); {
+		switch {
+		case i < len(pattern) && pattern[i] == '*':
+			star, retry = i, j
+			i++
+		case i < len(pattern) && j < len(s) && pattern[i] == s[j]:
+			i++
+			j++
+		case star >= 0 && retry < len(s):
+			retry++
+			i, j = star+1, retry
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// matchPathGlob matches an absolute path against an absolute glob in which
+// ** matches any number of directories.
+func matchPathGlob(pattern, name string) bool {
+	return matchGlobSegments(strings.Split(filepath.ToSlash(pattern), "/"), strings.Split(filepath.ToSlash(name), "/"))
+}
+
+func matchGlobSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlobSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// decideByPolicy applies the session's permission policy to a request. The
+// second return value is false if the request should be passed on.
+func (s *Session) decideByPolicy(request PermissionRequest) (PermissionRequestResult, bool) {
+	explanation := s.permissionPolicy.Explain(request, s.Config().WorkingDirectory)
+	if s.permissionPolicy.OnDecision != nil {
+		s.permissionPolicy.OnDecision(request, explanation)
+	}
+	switch explanation.Action() {
+	case PermissionAllow:
+		return Approved(), true
+	case PermissionDeny:
+		result := DeniedByRules()
+		result.Reason = explanation.Rule.Reason
+		return result, true
+	}
+	return PermissionRequestResult{}, false
+}
@@ -1,11 +1,106 @@
 package copilot
 
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/github/copilot-sdk/go/protocol"
+)
+
+// InvalidPermissionResultWarning is the WarningType of the session.warning
+// event emitted when a permission handler returns a kind the CLI does not
+// accept.
+const InvalidPermissionResultWarning = "invalid_permission_result"
+
+// ErrInvalidPermissionKind is returned for a [PermissionRequestResult] whose
+// Kind the CLI does not accept.
+var ErrInvalidPermissionKind = errors.New("invalid permission result kind")
+
+// PermissionResultKind is the decision on a permission request. See
+// [protocol.PermissionResultKind].
+type PermissionResultKind = protocol.PermissionResultKind
+
+const (
+	// PermissionApproved allows the operation
+	PermissionApproved = protocol.PermissionApproved
+	// PermissionDeniedByRules denies the operation because of configured rules
+	PermissionDeniedByRules = protocol.PermissionDeniedByRules
+	// PermissionDeniedNoApprovalRule denies the operation because no rule
+	// approves it and the user could not be asked
+	PermissionDeniedNoApprovalRule = protocol.PermissionDeniedNoApprovalRule
+	// PermissionDeniedByUser denies the operation because the user declined it
+	PermissionDeniedByUser = protocol.PermissionDeniedByUser
+)
+
+// Approved returns a result allowing the operation.
+func Approved() PermissionRequestResult {
+	return PermissionRequestResult{Kind: PermissionApproved}
+}
+
+// DeniedByUser returns a result denying the operation because the user
+// declined it, with an optional reason.
+//
+// Example:
+//
+//	OnPermissionRequest: func(req copilot.PermissionRequest, _ copilot.PermissionInvocation) (copilot.PermissionRequestResult, error) {
+//	    if req.Kind == "shell" {
+//	        return copilot.DeniedByUser("no shell commands in CI"), nil
+//	    }
+//	    return copilot.Approved(), nil
+//	},
+func DeniedByUser(reason string) PermissionRequestResult {
+	return PermissionRequestResult{Kind: PermissionDeniedByUser, Reason: reason}
+}
+
+// DeniedByRules returns a result denying the operation because of the given
+// rules.
+func DeniedByRules(rules ...any) PermissionRequestResult {
+	return PermissionRequestResult{Kind: PermissionDeniedByRules, Rules: rules}
+}
+
+// DeniedNoApprovalRule returns a result denying the operation because no rule
+// approves it and the user could not be asked. This is what the SDK answers
+// when a session has no permission handler.
+func DeniedNoApprovalRule() PermissionRequestResult {
+	return PermissionRequestResult{Kind: PermissionDeniedNoApprovalRule}
+}
+
+// validatePermissionResult returns an error wrapping
+// [ErrInvalidPermissionKind] if the CLI does not accept the result's kind. A
+// RawKind is not validated.
+func validatePermissionResult(r PermissionRequestResult) error {
+	if r.RawKind != "" || r.Kind.Valid() {
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidPermissionKind, r.Kind)
+}
+
 // PermissionHandler provides pre-built OnPermissionRequest implementations.
 var PermissionHandler = struct {
 	// ApproveAll approves all permission requests.
 	ApproveAll PermissionHandlerFunc
 }{
 	ApproveAll: func(_ PermissionRequest, _ PermissionInvocation) (PermissionRequestResult, error) {
-		return PermissionRequestResult{Kind: "approved"}, nil
+		return Approved(), nil
 	},
 }
+
+// checkPermissionResult validates a handler's result, emitting a
+// session.warning event if the CLI would not accept it.
+func (s *Session) checkPermissionResult(result PermissionRequestResult) error {
+	err := validatePermissionResult(result)
+	if err == nil {
+		return nil
+	}
+	s.dispatchEvent(SessionEvent{
+		Type:      SessionWarning,
+		Timestamp: time.Now(),
+		Ephemeral: Bool(true),
+		Data: Data{
+			WarningType: String(InvalidPermissionResultWarning),
+			Message:     String(fmt.Sprintf("permission handler returned %v; denying the request", err)),
+		},
+	})
+	return fmt.Errorf("permission handler returned %w", err)
+}
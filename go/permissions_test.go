@@ -0,0 +1,78 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestPermissionRequestResult_JSON(t *testing.T) {
+	tests := []struct {
+		result PermissionRequestResult
+		want   string
+	}{
+		{Approved(), `{"kind":"approved"}`},
+		{DeniedByUser("not now"), `{"kind":"denied-interactively-by-user","reason":"not now"}`},
+		{DeniedByRules("rule-1"), `{"kind":"denied-by-rules","rules":["rule-1"]}`},
+		{DeniedNoApprovalRule(), `{"kind":"denied-no-approval-rule-and-could-not-request-from-user"}`},
+		{PermissionRequestResult{Kind: PermissionApproved, RawKind: "approved-for-session"}, `{"kind":"approved-for-session"}`},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.result)
+		if err != nil || string(data) != tt.want {
+			t.Errorf("Expected %s, got %s (%v)", tt.want, data, err)
+		}
+	}
+
+	t.Run("keeps unknown kinds in RawKind", func(t *testing.T) {
+		var result PermissionRequestResult
+		if err := json.Unmarshal([]byte(`{"kind":"approved-for-session"}`), &result); err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+		if result.Kind != "" || result.RawKind != "approved-for-session" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+		if err := json.Unmarshal([]byte(`{"kind":"approved"}`), &result); err != nil || result.Kind != PermissionApproved {
+			t.Errorf("Expected a known kind, got %+v", result)
+		}
+	})
+}
+
+func TestSession_PermissionResultValidation(t *testing.T) {
+	newHandlerSession := func(result PermissionRequestResult) (*Client, *Session) {
+		client := NewClient(nil)
+		session := newSession("s1", nil, "")
+		session.registerPermissionHandler(func(PermissionRequest, PermissionInvocation) (PermissionRequestResult, error) {
+			return result, nil
+		})
+		client.sessions.put(session)
+		return client, session
+	}
+
+	t.Run("denies unknown kinds and warns", func(t *testing.T) {
+		client, session := newHandlerSession(PermissionRequestResult{Kind: "yes"})
+		var warning *SessionEvent
+		session.On(func(event SessionEvent) { warning = &event })
+
+		_, err := session.handlePermissionRequest(PermissionRequest{Kind: "shell"})
+		if !errors.Is(err, ErrInvalidPermissionKind) {
+			t.Errorf("Expected ErrInvalidPermissionKind, got %v", err)
+		}
+		if warning == nil || *warning.Data.WarningType != InvalidPermissionResultWarning {
+			t.Errorf("Expected a %s warning, got %+v", InvalidPermissionResultWarning, warning)
+		}
+
+		response, rpcErr := client.handlePermissionRequest(permissionRequestRequest{SessionID: "s1", Request: PermissionRequest{Kind: "shell"}})
+		if rpcErr != nil || response.Result.Kind != PermissionDeniedNoApprovalRule {
+			t.Errorf("Expected the request to be denied, got %+v, %v", response, rpcErr)
+		}
+	})
+
+	t.Run("passes raw kinds through", func(t *testing.T) {
+		client, _ := newHandlerSession(PermissionRequestResult{RawKind: "approved-for-session"})
+		response, rpcErr := client.handlePermissionRequest(permissionRequestRequest{SessionID: "s1", Request: PermissionRequest{Kind: "shell"}})
+		if rpcErr != nil || response.Result.RawKind != "approved-for-session" {
+			t.Errorf("Expected the raw kind to be sent, got %+v, %v", response, rpcErr)
+		}
+	})
+}
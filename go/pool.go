@@ -0,0 +1,407 @@
+package copilot
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// defaultPoolProcesses is the number of CLI processes a [ClientPool] runs
+// when ClientPoolOptions.Processes is not set.
+const defaultPoolProcesses = 2
+
+// ErrNoHealthyProcess is returned by [ClientPool] methods when none of the
+// pool's CLI processes is connected.
+var ErrNoHealthyProcess = errors.New("no healthy CLI process in pool")
+
+// ClientPoolOptions configures a [ClientPool].
+type ClientPoolOptions struct {
+	// Processes is the number of CLI processes to run (default: 2)
+	Processes int
+	// ClientOptions configures the client of every process. Each process
+	// gets its own copy.
+	ClientOptions *ClientOptions
+	// ConfigureProcess, if set, is called with each process's copy of
+	// ClientOptions before its client is created, for options that must
+	// differ between processes, such as Port or CLIUrl.
+	ConfigureProcess func(index int, options *ClientOptions)
+}
+
+// ClientPool runs several CLI processes behind one logical client, for
+// workloads with more concurrently busy sessions than one CLI process
+// handles well.
+//
+// New sessions are placed on the least-loaded connected process: the one
+// with the lowest [Client.ServerLoad] level, then the fewest busy sessions,
+// then the fewest sessions. A session stays on
+// its process for its whole life, so its RPCs and events only involve that
+// process. When a process fails, only its sessions are affected: new sessions
+// go to the remaining processes, and resuming a session of the failed
+// process moves it to a healthy one.
+//
+// Example:
+//
+//	pool := copilot.NewClientPool(&copilot.ClientPoolOptions{Processes: 4})
+//	if err := pool.Start(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer pool.Stop()
+//
+//	session, err := pool.CreateSession(ctx, &copilot.SessionConfig{
+//	    OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+//	})
+type ClientPool struct {
+	clients []*Client
+	// placeMux serializes choosing a process, so concurrent creates see
+	// each other's placements when comparing load. It is not held while
+	// the session is created.
+	placeMux sync.Mutex
+}
+
+// PoolProcessStats describes the load of one process in a [ClientPool].
+type PoolProcessStats struct {
+	// Index is the process's position in the pool
+	Index int
+	State ConnectionState
+	// Load is the process's estimated load level
+	Load ServerLoadLevel
+	// Sessions is the number of sessions on the process
+	Sessions int
+	// BusySessions is the number of sessions with a turn in progress
+	BusySessions int
+}
+
+// ClientPoolStats describes the load of a [ClientPool].
+type ClientPoolStats struct {
+	Processes    []PoolProcessStats
+	Sessions     int
+	BusySessions int
+}
+
+// NewClientPool creates a pool of CLI clients. The processes are not started
+// until [ClientPool.Start] is called.
+//
+// Example:
+//
+//	pool := copilot.NewClientPool(&copilot.ClientPoolOptions{
+//	    Processes:     3,
+//	    ClientOptions: &copilot.ClientOptions{LogLevel: "error"},
+//	})
+func NewClientPool(options *ClientPoolOptions) *ClientPool {
+	var opts ClientPoolOptions
+	if options != nil {
+		opts = *options
+	}
+	if opts.Processes <= 0 {
+		opts.Processes = defaultPoolProcesses
+	}
+
+	pool := &ClientPool{clients: make([]*Client, opts.Processes)}
+	for i := range pool.clients {
+		var clientOptions ClientOptions
+		if opts.ClientOptions != nil {
+			clientOptions = *opts.ClientOptions
+		}
+		if opts.ConfigureProcess != nil {
+			opts.ConfigureProcess(i, &clientOptions)
+		}
+		pool.clients[i] = NewClient(&clientOptions)
+	}
+	return pool
+}
+
+// Start starts every process in the pool. If any process fails to start,
+// the ones already started are stopped and the error is returned.
+func (p *ClientPool) Start(ctx context.Context) error {
+	for i, client := range p.clients {
+		if err := client.Start(ctx); err != nil {
+			for _, started := range p.clients[:i] {
+				started.ForceStop()
+			}
+			return fmt.Errorf("failed to start process %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Stop stops every process in the pool, destroying their sessions, and
+// returns the errors of all processes joined.
+func (p *ClientPool) Stop() error {
+	var errs []error
+	for i, client := range p.clients {
+		if err := client.Stop(); err != nil {
+			errs = append(errs, fmt.Errorf("process %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ForceStop forcefully stops every process in the pool.
+func (p *ClientPool) ForceStop() {
+	for _, client := range p.clients {
+		client.ForceStop()
+	}
+}
+
+// Clients returns the client of each process, in pool order, for
+// per-process operations such as [Client.Restart] or
+// [Client.DiagnosticBundle].
+func (p *ClientPool) Clients() []*Client {
+	return append([]*Client(nil), p.clients...)
+}
+
+// ClientFor returns the client of the process that owns the session, or nil
+// if no process in the pool has it.
+func (p *ClientPool) ClientFor(sessionID string) *Client {
+	for _, client := range p.clients {
+		if client.hasSession(sessionID) {
+			return client
+		}
+	}
+	return nil
+}
+
+// CreateSession creates a session on the least-loaded connected process. If
+// that process fails during creation, the next least-loaded one is tried.
+// Returns [ErrNoHealthyProcess] if no process is connected.
+func (p *ClientPool) CreateSession(ctx context.Context, config *SessionConfig) (*Session, error) {
+	return p.place(p.clients, func(client *Client) (*Session, error) {
+		return client.CreateSession(ctx, config)
+	})
+}
+
+// ResumeSession resumes a session on the process that can resume it.
+//
+// A session already on a connected process is resumed there. Otherwise,
+// the connected processes that list the session are tried, least-loaded
+// first; if none lists it, any connected process may resume it. A session
+// whose process has failed is resumed on a healthy process, and the copy on
+// the failed process is marked destroyed.
+//
+// Example:
+//
+//	session, err := pool.ResumeSession(ctx, "session-123", &copilot.ResumeSessionConfig{
+//	    OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+//	})
+func (p *ClientPool) ResumeSession(ctx context.Context, sessionID string, config *ResumeSessionConfig) (*Session, error) {
+	owner, candidates := p.locate(ctx, sessionID)
+	session, err := p.place(candidates, func(client *Client) (*Session, error) {
+		return client.ResumeSessionWithOptions(ctx, sessionID, config)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if owner != nil {
+		owner.dropStaleSession(sessionID, session, "session resumed on another CLI process")
+	}
+	return session, nil
+}
+
+// ListSessions lists the sessions of every connected process, without
+// duplicates. Processes that are not connected are skipped. The filter's
+// Limit and ModifiedBefore apply to the combined list.
+func (p *ClientPool) ListSessions(ctx context.Context, filter *SessionListFilter) ([]SessionMetadata, error) {
+	var sessions []SessionMetadata
+	var errs []error
+	seen := make(map[string]bool)
+	var processFilter *SessionListFilter
+	if filter != nil {
+		processFilter = &SessionListFilter{Cwd: filter.Cwd, GitRoot: filter.GitRoot, Repository: filter.Repository, Branch: filter.Branch}
+	}
+	for i, client := range p.clients {
+		if client.State() != StateConnected {
+			continue
+		}
+		listed, err := client.ListSessions(ctx, processFilter)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("process %d: %w", i, err))
+			continue
+		}
+		for _, session := range listed {
+			if !seen[session.SessionID] {
+				seen[session.SessionID] = true
+				sessions = append(sessions, session)
+			}
+		}
+	}
+	return paginateSessions(sessions, filter), errors.Join(errs...)
+}
+
+// DeleteSession deletes a session on the process that owns it, or else on
+// every connected process that lists it. A copy of the session on a failed
+// process is marked destroyed.
+func (p *ClientPool) DeleteSession(ctx context.Context, sessionID string) error {
+	owner, candidates := p.locate(ctx, sessionID)
+	if owner != nil && owner.State() == StateConnected {
+		return owner.DeleteSession(ctx, sessionID)
+	}
+	if len(candidates) == 0 {
+		return ErrNoHealthyProcess
+	}
+	var errs []error
+	for _, client := range candidates {
+		if err := client.DeleteSession(ctx, sessionID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 && owner != nil {
+		owner.dropStaleSession(sessionID, nil, "session deleted")
+	}
+	return errors.Join(errs...)
+}
+
+// On subscribes to the lifecycle events of every process in the pool.
+// Returns a function that unsubscribes the handler from all of them.
+func (p *ClientPool) On(handler SessionLifecycleHandler) func() {
+	unsubscribes := make([]func(), len(p.clients))
+	for i, client := range p.clients {
+		unsubscribes[i] = client.On(handler)
+	}
+	return func() {
+		for _, unsubscribe := range unsubscribes {
+			unsubscribe()
+		}
+	}
+}
+
+// Stats returns the state and load of every process, and the totals.
+func (p *ClientPool) Stats() ClientPoolStats {
+	var stats ClientPoolStats
+	for i, client := range p.clients {
+		sessions, busy := client.load()
+		stats.Processes = append(stats.Processes, PoolProcessStats{
+			Index:        i,
+			State:        client.State(),
+			Load:         client.ServerLoad().Level,
+			Sessions:     sessions,
+			BusySessions: busy,
+		})
+		stats.Sessions += sessions
+		stats.BusySessions += busy
+	}
+	return stats
+}
+
+// locate finds the process that owns a session and the connected processes
+// that could resume it: the owner if it is connected, else those that list
+// the session, else all connected processes.
+func (p *ClientPool) locate(ctx context.Context, sessionID string) (*Client, []*Client) {
+	owner := p.ClientFor(sessionID)
+	if owner != nil && owner.State() == StateConnected {
+		return owner, []*Client{owner}
+	}
+
+	healthy := p.healthy(p.clients)
+	var listing []*Client
+	for _, client := range healthy {
+		sessions, err := client.ListSessions(ctx, nil)
+		if err != nil {
+			continue
+		}
+		for _, session := range sessions {
+			if session.SessionID == sessionID {
+				listing = append(listing, client)
+				break
+			}
+		}
+	}
+	if len(listing) > 0 {
+		return owner, listing
+	}
+	return owner, healthy
+}
+
+// place runs fn on the least-loaded connected candidate, moving on to the
+// next one if the process fails while fn runs. The process is reserved
+// while fn runs, so a slow create on one process does not hold up
+// placements on the others.
+func (p *ClientPool) place(candidates []*Client, fn func(client *Client) (*Session, error)) (*Session, error) {
+	tried := make(map[*Client]bool)
+	var errs []error
+	for {
+		p.placeMux.Lock()
+		client := leastLoaded(p.healthy(candidates), tried)
+		if client != nil {
+			client.placements.Add(1)
+		}
+		p.placeMux.Unlock()
+		if client == nil {
+			if len(errs) == 0 {
+				return nil, ErrNoHealthyProcess
+			}
+			return nil, errors.Join(errs...)
+		}
+		tried[client] = true
+
+		session, err := fn(client)
+		client.placements.Add(-1)
+		if err == nil {
+			return session, nil
+		}
+		if client.State() == StateConnected {
+			// The process is fine, so another one would fail the same way
+			return nil, err
+		}
+		errs = append(errs, err)
+	}
+}
+
+// healthy returns the connected clients among clients.
+func (p *ClientPool) healthy(clients []*Client) []*Client {
+	var result []*Client
+	for _, client := range clients {
+		if client.State() == StateConnected {
+			result = append(result, client)
+		}
+	}
+	return result
+}
+
+// leastLoaded returns the client with the lowest server load level, then the
+// fewest busy sessions, then the fewest sessions, skipping those already
+// tried. Ties go to the first.
+func leastLoaded(clients []*Client, tried map[*Client]bool) *Client {
+	var best *Client
+	bestLevel, bestSessions, bestBusy := 0, 0, 0
+	for _, client := range clients {
+		if tried[client] {
+			continue
+		}
+		level := slices.Index(serverLoadLevels, client.ServerLoad().Level)
+		sessions, busy := client.load()
+		if best == nil || cmp.Or(cmp.Compare(level, bestLevel), cmp.Compare(busy, bestBusy), cmp.Compare(sessions, bestSessions)) < 0 {
+			best, bestLevel, bestSessions, bestBusy = client, level, sessions, busy
+		}
+	}
+	return best
+}
+
+// load returns the number of sessions the client tracks, including those a
+// ClientPool is placing on it, and how many of them have a turn in progress.
+func (c *Client) load() (sessions, busy int) {
+	sessions = int(c.placements.Load())
+	for _, session := range c.sessions.all() {
+		sessions++
+		if session.State() != SessionStateIdle {
+			busy++
+		}
+	}
+	return sessions, busy
+}
+
+// hasSession reports whether the client tracks the session.
+func (c *Client) hasSession(sessionID string) bool {
+	return c.sessions.get(sessionID) != nil
+}
+
+// dropStaleSession marks the client's copy of a session destroyed, unless
+// it is current.
+func (c *Client) dropStaleSession(sessionID string, current *Session, reason string) {
+	session := c.sessions.get(sessionID)
+	if session != nil && session != current {
+		c.dropSession(session, reason)
+	}
+}
@@ -0,0 +1,286 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// poolCLI is a fake CLI process for pool tests that records the sessions
+// created or resumed on it.
+type poolCLI struct {
+	*fakeCLI
+	mu     sync.Mutex
+	listed []string
+	// stall, if set, holds session.create requests until it is closed
+	stall chan struct{}
+}
+
+func newPoolCLI(t *testing.T) *poolCLI {
+	cli := &poolCLI{}
+	cli.fakeCLI = newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		var req struct {
+			SessionID string `json:"sessionId"`
+		}
+		json.Unmarshal(params, &req)
+		switch method {
+		case "session.create":
+			cli.mu.Lock()
+			stall := cli.stall
+			cli.mu.Unlock()
+			if stall != nil {
+				<-stall
+			}
+			return createSessionResponse{SessionID: req.SessionID}, nil
+		case "session.resume":
+			return resumeSessionResponse{SessionID: req.SessionID}, nil
+		case "session.send":
+			return sessionSendResponse{MessageID: "m-" + req.SessionID}, nil
+		case "session.list":
+			cli.mu.Lock()
+			defer cli.mu.Unlock()
+			var sessions []SessionMetadata
+			for _, id := range cli.listed {
+				sessions = append(sessions, SessionMetadata{SessionID: id})
+			}
+			return listSessionsResponse{Sessions: sessions}, nil
+		}
+		return nil, nil
+	})
+	return cli
+}
+
+func (c *poolCLI) list(ids ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listed = append(c.listed, ids...)
+}
+
+func newTestPool(t *testing.T, n int) (*ClientPool, []*poolCLI) {
+	t.Helper()
+	clis := make([]*poolCLI, n)
+	for i := range clis {
+		clis[i] = newPoolCLI(t)
+	}
+	pool := NewClientPool(&ClientPoolOptions{
+		Processes: n,
+		ConfigureProcess: func(index int, options *ClientOptions) {
+			options.CLIUrl = clis[index].addr()
+		},
+	})
+	t.Cleanup(pool.ForceStop)
+	if err := pool.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start pool: %v", err)
+	}
+	return pool, clis
+}
+
+func TestClientPool_Placement(t *testing.T) {
+	create := func(t *testing.T, pool *ClientPool, id string) *Session {
+		t.Helper()
+		session, err := pool.CreateSession(t.Context(), &SessionConfig{SessionID: id, OnPermissionRequest: PermissionHandler.ApproveAll})
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", id, err)
+		}
+		return session
+	}
+	processOf := func(pool *ClientPool, id string) int {
+		for i, client := range pool.Clients() {
+			if pool.ClientFor(id) == client {
+				return i
+			}
+		}
+		return -1
+	}
+
+	t.Run("spreads sessions across processes", func(t *testing.T) {
+		pool, _ := newTestPool(t, 3)
+		for i := 0; i < 6; i++ {
+			create(t, pool, fmt.Sprintf("s%d", i))
+		}
+		stats := pool.Stats()
+		if stats.Sessions != 6 {
+			t.Errorf("Expected 6 sessions, got %d", stats.Sessions)
+		}
+		for _, process := range stats.Processes {
+			if process.Sessions != 2 || process.State != StateConnected {
+				t.Errorf("Expected 2 sessions on each connected process, got %+v", stats.Processes)
+			}
+		}
+	})
+
+	t.Run("prefers processes with fewer busy sessions", func(t *testing.T) {
+		pool, _ := newTestPool(t, 2)
+		busy := create(t, pool, "busy")
+		create(t, pool, "idle")
+		if _, err := busy.Send(t.Context(), MessageOptions{Prompt: "work"}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		create(t, pool, "third")
+		create(t, pool, "fourth")
+		if processOf(pool, "third") == processOf(pool, "busy") || processOf(pool, "fourth") == processOf(pool, "busy") {
+			t.Errorf("Expected new sessions to avoid the busy process")
+		}
+		if stats := pool.Stats(); stats.BusySessions != 1 {
+			t.Errorf("Expected 1 busy session, got %d", stats.BusySessions)
+		}
+	})
+
+	t.Run("a stalled create does not hold up other processes", func(t *testing.T) {
+		pool, clis := newTestPool(t, 2)
+		stall := make(chan struct{})
+		clis[0].mu.Lock()
+		clis[0].stall = stall
+		clis[0].mu.Unlock()
+
+		stalled := make(chan error, 1)
+		go func() {
+			_, err := pool.CreateSession(t.Context(), &SessionConfig{SessionID: "stalled", OnPermissionRequest: PermissionHandler.ApproveAll})
+			stalled <- err
+		}()
+		// Wait until the stalled create has reserved process 0
+		for pool.Clients()[0].placements.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		created := make(chan error, 1)
+		go func() {
+			_, err := pool.CreateSession(t.Context(), &SessionConfig{SessionID: "healthy", OnPermissionRequest: PermissionHandler.ApproveAll})
+			created <- err
+		}()
+		select {
+		case err := <-created:
+			if err != nil {
+				t.Fatalf("Failed to create: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the create on the other process not to wait for the stalled one")
+		}
+		if processOf(pool, "healthy") != 1 {
+			t.Errorf("Expected the session on process 1, got %d", processOf(pool, "healthy"))
+		}
+
+		close(stall)
+		if err := <-stalled; err != nil {
+			t.Fatalf("Failed to create the stalled session: %v", err)
+		}
+		if processOf(pool, "stalled") != 0 {
+			t.Errorf("Expected the stalled session on process 0, got %d", processOf(pool, "stalled"))
+		}
+		if stats := pool.Stats(); stats.Sessions != 2 {
+			t.Errorf("Expected 2 sessions once placements finish, got %d", stats.Sessions)
+		}
+	})
+
+	t.Run("resumes on the process that lists the session", func(t *testing.T) {
+		pool, clis := newTestPool(t, 3)
+		create(t, pool, "s0")
+		create(t, pool, "s1")
+		clis[1].list("old")
+
+		if _, err := pool.ResumeSession(t.Context(), "old", &ResumeSessionConfig{OnPermissionRequest: PermissionHandler.ApproveAll}); err != nil {
+			t.Fatalf("Failed to resume: %v", err)
+		}
+		if p := processOf(pool, "old"); p != 1 {
+			t.Errorf("Expected old to be resumed on process 1, got %d", p)
+		}
+
+		if _, err := pool.ResumeSession(t.Context(), "unknown", &ResumeSessionConfig{OnPermissionRequest: PermissionHandler.ApproveAll}); err != nil {
+			t.Fatalf("Failed to resume: %v", err)
+		}
+		if p := processOf(pool, "unknown"); p != 2 {
+			t.Errorf("Expected an unlisted session on the least-loaded process 2, got %d", p)
+		}
+	})
+
+	t.Run("lists sessions without duplicates", func(t *testing.T) {
+		pool, clis := newTestPool(t, 2)
+		clis[0].list("a", "shared")
+		clis[1].list("shared", "b")
+		sessions, err := pool.ListSessions(t.Context(), nil)
+		if err != nil {
+			t.Fatalf("ListSessions failed: %v", err)
+		}
+		if len(sessions) != 3 {
+			t.Errorf("Expected 3 sessions, got %+v", sessions)
+		}
+	})
+}
+
+func TestClientPool_Failover(t *testing.T) {
+	pool, clis := newTestPool(t, 2)
+	sessions := make(map[string]*Session)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		session, err := pool.CreateSession(t.Context(), &SessionConfig{SessionID: id, OnPermissionRequest: PermissionHandler.ApproveAll})
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", id, err)
+		}
+		sessions[id] = session
+	}
+	failed, healthy := pool.Clients()[0], pool.Clients()[1]
+	var lostID, keptID string
+	for id := range sessions {
+		if pool.ClientFor(id) == failed {
+			lostID = id
+		} else {
+			keptID = id
+		}
+	}
+
+	clis[0].dropConnections()
+	deadline := time.Now().Add(2 * time.Second)
+	for failed.State() != StateError && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if failed.State() != StateError {
+		t.Fatalf("Expected process 0 to fail, got %s", failed.State())
+	}
+
+	t.Run("sessions on other processes keep working", func(t *testing.T) {
+		if _, err := sessions[keptID].Send(t.Context(), MessageOptions{Prompt: "still here"}); err != nil {
+			t.Errorf("Expected send on a healthy process to succeed, got %v", err)
+		}
+	})
+
+	t.Run("new sessions go to healthy processes", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			id := fmt.Sprintf("new%d", i)
+			if _, err := pool.CreateSession(t.Context(), &SessionConfig{SessionID: id, OnPermissionRequest: PermissionHandler.ApproveAll}); err != nil {
+				t.Fatalf("Failed to create %s: %v", id, err)
+			}
+			if pool.ClientFor(id) != healthy {
+				t.Errorf("Expected %s on the healthy process", id)
+			}
+		}
+	})
+
+	t.Run("resuming moves a session off the failed process", func(t *testing.T) {
+		resumed, err := pool.ResumeSession(t.Context(), lostID, &ResumeSessionConfig{OnPermissionRequest: PermissionHandler.ApproveAll})
+		if err != nil {
+			t.Fatalf("Failed to resume: %v", err)
+		}
+		if pool.ClientFor(lostID) != healthy {
+			t.Errorf("Expected %s on the healthy process", lostID)
+		}
+		if reason, destroyed := sessions[lostID].DestroyReason(); !destroyed {
+			t.Error("Expected the copy on the failed process to be destroyed")
+		} else if resumed == sessions[lostID] {
+			t.Errorf("Expected a new session object, old one destroyed with %q", reason)
+		}
+	})
+
+	t.Run("fails when no process is healthy", func(t *testing.T) {
+		clis[1].dropConnections()
+		deadline := time.Now().Add(2 * time.Second)
+		for healthy.State() != StateError && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		_, err := pool.CreateSession(t.Context(), &SessionConfig{SessionID: "none", OnPermissionRequest: PermissionHandler.ApproveAll})
+		if !errors.Is(err, ErrNoHealthyProcess) {
+			t.Errorf("Expected ErrNoHealthyProcess, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+)
+
+// PromptTooLargeWarning is the WarningType of the session.warning event
+// emitted when a prompt exceeds the model's limit in [PreflightWarn] mode.
+const PromptTooLargeWarning = "prompt_too_large"
+
+// ErrPromptTooLarge matches errors from [Session.Send] when the estimated size
+// of a prompt and its attachments exceeds the model's limit.
+var ErrPromptTooLarge = errors.New("prompt too large for model")
+
+// PromptTooLargeError is returned by [Session.Send] when the estimated size of
+// a prompt and its attachments exceeds the model's prompt limit, or its
+// context window if the model reports no prompt limit. It matches
+// [ErrPromptTooLarge].
+type PromptTooLargeError struct {
+	// Model is the ID of the session's model
+	Model string
+	// EstimatedTokens is the estimated size of the prompt and attachments
+	EstimatedTokens int
+	// LimitTokens is the model's limit
+	LimitTokens int
+}
+
+func (e *PromptTooLargeError) Error() string {
+	return fmt.Sprintf("%v: estimated %d tokens, %s accepts %d", ErrPromptTooLarge, e.EstimatedTokens, e.Model, e.LimitTokens)
+}
+
+func (e *PromptTooLargeError) Is(target error) bool { return target == ErrPromptTooLarge }
+
+// Tokenizer counts the tokens in a text. Implementations must be safe for
+// concurrent use.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// HeuristicTokenizer estimates token counts without a model vocabulary: about
+// four ASCII characters per token, and one token per other character, which
+// overestimates rather than underestimates for most text. It is the default
+// [Tokenizer] of the prompt size preflight.
+type HeuristicTokenizer struct{}
+
+// CountTokens returns the estimated number of tokens in text.
+func (HeuristicTokenizer) CountTokens(text string) int {
+	return EstimateTokens(text)
+}
+
+// EstimateTokens estimates the number of tokens in text with
+// [HeuristicTokenizer], for example to split a document into chunks that fit
+// a model's context window.
+//
+// Example:
+//
+//	if copilot.EstimateTokens(doc) > 8000 {
+//	    // split doc before sending it
+//	}
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for i := 0; i < len(text); {
+		if text[i] < utf8.RuneSelf {
+			ascii++
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		other++
+		i += size
+	}
+	return (ascii+3)/4 + other
+}
+
+// PreflightMode selects what [Session.Send] does when a prompt is estimated
+// to exceed the model's limit.
+type PreflightMode string
+
+const (
+	// PreflightEnforce fails the send with a *[PromptTooLargeError] (default)
+	PreflightEnforce PreflightMode = "enforce"
+	// PreflightWarn sends anyway and emits a session.warning event with
+	// WarningType [PromptTooLargeWarning]
+	PreflightWarn PreflightMode = "warn"
+	// PreflightOff skips the check
+	PreflightOff PreflightMode = "off"
+)
+
+// PromptPreflight configures the prompt size check [Session.Send] makes
+// before sending a message.
+type PromptPreflight struct {
+	// Mode selects what happens to prompts over the limit. Default: PreflightEnforce.
+	Mode PreflightMode
+	// Tokenizer counts the tokens of the prompt and attachments.
+	// Default: [HeuristicTokenizer].
+	Tokenizer Tokenizer
+}
+
+// preflightPrompt estimates the size of a message and compares it to the
+// limit of the model answering it. The check is skipped when the model or its
+// limits are unknown, including when they cannot be looked up.
+func (s *Session) preflightPrompt(ctx context.Context, options MessageOptions, prompt string) error {
+	preflight := PromptPreflight{}
+	if s.promptPreflight != nil {
+		preflight = *s.promptPreflight
+	}
+	if preflight.Mode == PreflightOff {
+		return nil
+	}
+	tokenizer := preflight.Tokenizer
+	if tokenizer == nil {
+		tokenizer = HeuristicTokenizer{}
+	}
+
+	model := s.preflightModel(ctx, options.Model)
+	if model == nil {
+		return nil
+	}
+	limit := model.Capabilities.Limits.MaxContextWindowTokens
+	if maxPrompt := model.Capabilities.Limits.MaxPromptTokens; maxPrompt != nil && *maxPrompt > 0 {
+		limit = *maxPrompt
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	estimate := tokenizer.CountTokens(prompt)
+	workingDirectory := s.Config().WorkingDirectory
+	for _, attachment := range options.Attachments {
+		// The remaining budget bounds how much of the attachments is read
+		n, err := countAttachmentTokens(tokenizer, attachment, workingDirectory, limit-estimate)
+		if err != nil {
+			// Leave unreadable attachments for the CLI to report
+			continue
+		}
+		estimate += n
+		if estimate > limit {
+			break
+		}
+	}
+	for _, attachment := range options.InlineAttachments {
+		if estimate > limit {
+			break
+		}
+		if isText(attachment.Content) {
+			estimate += tokenizer.CountTokens(string(attachment.Content))
+		}
+	}
+	if estimate <= limit {
+		return nil
+	}
+
+	err := &PromptTooLargeError{Model: model.ID, EstimatedTokens: estimate, LimitTokens: limit}
+	if preflight.Mode != PreflightWarn {
+		return err
+	}
+	s.dispatchEvent(SessionEvent{
+		Type:      SessionWarning,
+		Timestamp: time.Now(),
+		Ephemeral: Bool(true),
+		Data: Data{
+			WarningType: String(PromptTooLargeWarning),
+			Message:     String(err.Error()),
+		},
+	})
+	return nil
+}
+
+// preflightModel returns the model answering a message, override if set or
+// else the session's model, or nil if it is unknown.
+func (s *Session) preflightModel(ctx context.Context, override string) *ModelInfo {
+	if s.listModels == nil {
+		return nil
+	}
+	id := override
+	if id == "" {
+		id = s.Config().Model
+	}
+	if id == "" {
+		model, _ := s.currentModelInfo(ctx)
+		return model
+	}
+	model, _ := s.modelInfo(ctx, id)
+	return model
+}
+
+// countAttachmentTokens estimates the tokens of an attachment: its text, or
+// the text files at its path. Binary files are skipped, since they are not
+// sent to the model as text. A relative path is resolved against the
+// session's working directory, and skipped if it is unknown. Reading stops
+// once budget is exceeded, since the total is then over the limit
+// regardless.
+func countAttachmentTokens(tokenizer Tokenizer, attachment Attachment, workingDirectory string, budget int) (int, error) {
+	if attachment.Text != nil {
+		return tokenizer.CountTokens(*attachment.Text), nil
+	}
+	path := attachment.Path
+	if path == nil {
+		path = attachment.FilePath
+	}
+	if path == nil {
+		return 0, nil
+	}
+	root := *path
+	if !filepath.IsAbs(root) {
+		if workingDirectory == "" {
+			return 0, nil
+		}
+		root = filepath.Join(workingDirectory, root)
+	}
+
+	total := 0
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if !isText(data) {
+			return nil
+		}
+		total += tokenizer.CountTokens(string(data))
+		if total > budget {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	return total, err
+}
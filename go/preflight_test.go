@@ -0,0 +1,192 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abc", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{strings.Repeat("x", 4000), 1000},
+		{"日本語", 3},
+		{"héllo", 2},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q): expected %d, got %d", tt.text, tt.want, got)
+		}
+	}
+}
+
+type wordTokenizer struct{}
+
+func (wordTokenizer) CountTokens(text string) int { return len(strings.Fields(text)) }
+
+func TestSession_SendPreflight(t *testing.T) {
+	maxPrompt := 500
+	models := []ModelInfo{
+		{ID: "small", Capabilities: ModelCapabilities{Limits: ModelLimits{MaxContextWindowTokens: 1000}}},
+		{ID: "prompt-limited", Capabilities: ModelCapabilities{Limits: ModelLimits{MaxContextWindowTokens: 100000, MaxPromptTokens: &maxPrompt}}},
+		{ID: "unlimited"},
+	}
+
+	dir := t.TempDir()
+	large := filepath.Join(dir, "large.txt")
+	if err := os.WriteFile(large, []byte(strings.Repeat("word ", 1000)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, filepath.Join(dir, "tree"), map[string]string{
+		"a.txt":     strings.Repeat("x", 2400),
+		"sub/b.txt": strings.Repeat("x", 2400),
+	})
+
+	newPreflightSession := func(t *testing.T, model string, preflight *PromptPreflight) (*Session, *atomic.Int32) {
+		var sends atomic.Int32
+		session, _ := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.send" {
+				sends.Add(1)
+				return sessionSendResponse{MessageID: "m1"}, nil
+			}
+			return nil, nil
+		})
+		session.listModels = func(context.Context) ([]ModelInfo, error) { return models, nil }
+		session.config.setModel(model)
+		session.promptPreflight = preflight
+		return session, &sends
+	}
+	file := func(path string) Attachment { return Attachment{Type: File, Path: &path} }
+
+	t.Run("rejects large attachments before sending", func(t *testing.T) {
+		session, sends := newPreflightSession(t, "small", nil)
+		_, err := session.Send(t.Context(), MessageOptions{Prompt: "Summarize", Attachments: []Attachment{file(large)}})
+		var tooLarge *PromptTooLargeError
+		if !errors.As(err, &tooLarge) || !errors.Is(err, ErrPromptTooLarge) {
+			t.Fatalf("Expected a PromptTooLargeError, got %v", err)
+		}
+		if tooLarge.Model != "small" || tooLarge.LimitTokens != 1000 || tooLarge.EstimatedTokens <= 1000 {
+			t.Errorf("Unexpected error fields: %+v", tooLarge)
+		}
+		if sends.Load() != 0 {
+			t.Error("Expected no session.send call")
+		}
+	})
+
+	t.Run("counts files in directories", func(t *testing.T) {
+		session, _ := newPreflightSession(t, "small", nil)
+		tree := filepath.Join(dir, "tree")
+		_, err := session.Send(t.Context(), MessageOptions{Prompt: "Review", Attachments: []Attachment{{Type: Directory, Path: &tree}}})
+		if !errors.Is(err, ErrPromptTooLarge) {
+			t.Errorf("Expected ErrPromptTooLarge for 1200 estimated tokens, got %v", err)
+		}
+	})
+
+	t.Run("skips binary files in directories", func(t *testing.T) {
+		assets := filepath.Join(dir, "assets")
+		binary := make([]byte, 200*1024)
+		for i := range binary {
+			binary[i] = byte(i*7 + 0x80)
+		}
+		writeTree(t, assets, map[string]string{
+			"README.md":    "Icons for the app",
+			"icon.png":     string(binary),
+			"build/app.so": string(binary),
+		})
+		session, sends := newPreflightSession(t, "small", nil)
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "Review", Attachments: []Attachment{{Type: Directory, Path: &assets}}}); err != nil {
+			t.Fatalf("Expected binary files not to count, got %v", err)
+		}
+		if sends.Load() != 1 {
+			t.Errorf("Expected the message to be sent, got %d sends", sends.Load())
+		}
+	})
+
+	t.Run("resolves relative paths against the working directory", func(t *testing.T) {
+		session, sends := newPreflightSession(t, "small", nil)
+		relative := "large.txt"
+		_, err := session.Send(t.Context(), MessageOptions{Prompt: "Summarize", Attachments: []Attachment{file(relative)}})
+		if err != nil || sends.Load() != 1 {
+			t.Errorf("Expected a relative path to be left to the CLI without a working directory, got %v", err)
+		}
+
+		session.config.mu.Lock()
+		session.config.config.WorkingDirectory = dir
+		session.config.mu.Unlock()
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "Summarize", Attachments: []Attachment{file(relative)}}); !errors.Is(err, ErrPromptTooLarge) {
+			t.Errorf("Expected the file in the working directory to be counted, got %v", err)
+		}
+	})
+
+	t.Run("prefers the prompt limit over the context window", func(t *testing.T) {
+		session, _ := newPreflightSession(t, "prompt-limited", nil)
+		_, err := session.Send(t.Context(), MessageOptions{Prompt: strings.Repeat("y", 2400)})
+		var tooLarge *PromptTooLargeError
+		if !errors.As(err, &tooLarge) || tooLarge.LimitTokens != 500 || tooLarge.EstimatedTokens != 600 {
+			t.Errorf("Expected the 500 token prompt limit to apply, got %v", err)
+		}
+	})
+
+	t.Run("sends prompts within the limit", func(t *testing.T) {
+		session, sends := newPreflightSession(t, "small", nil)
+		text := "a short note"
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "Hi", Attachments: []Attachment{{Type: Selection, Text: &text}}}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		if sends.Load() != 1 {
+			t.Error("Expected the message to be sent")
+		}
+	})
+
+	t.Run("skips models without limits", func(t *testing.T) {
+		for _, model := range []string{"unlimited", "unknown"} {
+			session, _ := newPreflightSession(t, model, nil)
+			if _, err := session.Send(t.Context(), MessageOptions{Attachments: []Attachment{file(large)}}); err != nil {
+				t.Errorf("Expected no check for %s, got %v", model, err)
+			}
+		}
+	})
+
+	t.Run("warn mode sends and emits a warning", func(t *testing.T) {
+		session, sends := newPreflightSession(t, "small", &PromptPreflight{Mode: PreflightWarn})
+		var warning *SessionEvent
+		session.On(func(event SessionEvent) {
+			if event.Type == SessionWarning {
+				warning = &event
+			}
+		})
+		if _, err := session.Send(t.Context(), MessageOptions{Attachments: []Attachment{file(large)}}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		if sends.Load() != 1 {
+			t.Error("Expected the message to be sent")
+		}
+		if warning == nil || *warning.Data.WarningType != PromptTooLargeWarning {
+			t.Errorf("Expected a %s warning, got %+v", PromptTooLargeWarning, warning)
+		}
+	})
+
+	t.Run("off mode and custom tokenizers", func(t *testing.T) {
+		session, _ := newPreflightSession(t, "small", &PromptPreflight{Mode: PreflightOff})
+		if _, err := session.Send(t.Context(), MessageOptions{Attachments: []Attachment{file(large)}}); err != nil {
+			t.Errorf("Expected no check when off, got %v", err)
+		}
+
+		// 1000 words fit in 1000 tokens when counted as one token per word
+		session, _ = newPreflightSession(t, "small", &PromptPreflight{Tokenizer: wordTokenizer{}})
+		if _, err := session.Send(t.Context(), MessageOptions{Attachments: []Attachment{file(large)}}); err != nil {
+			t.Errorf("Expected the custom tokenizer to be used, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package copilot
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultProgressInterval is the minimum time between OnProgress calls when
+// SendAndWaitOptions.ProgressInterval is not set.
+const defaultProgressInterval = 250 * time.Millisecond
+
+// ProgressPhase describes what a session is doing during a turn.
+type ProgressPhase string
+
+const (
+	// ProgressWaiting means the message was sent and the turn has not started.
+	ProgressWaiting ProgressPhase = "waiting"
+	// ProgressThinking means the model is working on its next step.
+	ProgressThinking ProgressPhase = "thinking"
+	// ProgressRunningTool means at least one tool is executing.
+	ProgressRunningTool ProgressPhase = "running_tool"
+	// ProgressStreaming means the assistant's answer is being streamed.
+	ProgressStreaming ProgressPhase = "streaming"
+	// ProgressCompacting means the session is compacting its context.
+	ProgressCompacting ProgressPhase = "compacting"
+	// ProgressDone means the session is idle and the turn is complete.
+	ProgressDone ProgressPhase = "done"
+)
+
+// ProgressUpdate summarizes the state of a turn for progress reporting. See
+// [SendAndWaitOptions.OnProgress].
+type ProgressUpdate struct {
+	// Phase is what the session is currently doing
+	Phase ProgressPhase
+	// ToolName is the most recently started tool that is still running, or ""
+	ToolName string
+	// RunningTools is the number of tools currently running
+	RunningTools int
+	// CharsStreamed is the number of characters of assistant message content
+	// received so far in this turn
+	CharsStreamed int
+	// Elapsed is the time since the message was sent
+	Elapsed time.Duration
+}
+
+// runningTool is a tool execution that has started and not completed.
+type runningTool struct {
+	toolCallID string
+	toolName   string
+}
+
+// progressTracker derives ProgressUpdates from a turn's event stream.
+type progressTracker struct {
+	mu      sync.Mutex
+	started time.Time
+	now     func() time.Time
+	phase   ProgressPhase
+	tools   []runningTool // in start order
+	chars   int
+	// streamed records assistant messages whose content arrived as deltas, so
+	// the final assistant.message is not counted twice
+	streamed map[string]bool
+	changed  bool
+}
+
+func newProgressTracker(now func() time.Time) *progressTracker {
+	return &progressTracker{
+		started:  now(),
+		now:      now,
+		phase:    ProgressWaiting,
+		streamed: make(map[string]bool),
+		changed:  true,
+	}
+}
+
+// observe updates the tracked state from an event.
+func (p *progressTracker) observe(event SessionEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	before := p.snapshotLocked()
+	switch event.Type {
+	case AssistantTurnStart, AssistantIntent, AssistantReasoning, AssistantReasoningDelta, SessionCompactionComplete:
+		p.phase = ProgressThinking
+	case AssistantMessageDelta:
+		p.phase = ProgressStreaming
+		if event.Data.DeltaContent != nil {
+			p.chars += len([]rune(*event.Data.DeltaContent))
+		}
+		if event.Data.MessageID != nil {
+			p.streamed[*event.Data.MessageID] = true
+		}
+	case AssistantMessage:
+		// A complete message either ends the answer or precedes tool calls;
+		// the model keeps working until the session goes idle
+		p.phase = ProgressThinking
+		if event.Data.Content != nil && (event.Data.MessageID == nil || !p.streamed[*event.Data.MessageID]) {
+			p.chars += len([]rune(*event.Data.Content))
+		}
+	case ToolExecutionStart:
+		tool := runningTool{}
+		if event.Data.ToolCallID != nil {
+			tool.toolCallID = *event.Data.ToolCallID
+		}
+		if event.Data.ToolName != nil {
+			tool.toolName = *event.Data.ToolName
+		}
+		p.tools = append(p.tools, tool)
+	case ToolExecutionComplete:
+		if event.Data.ToolCallID != nil {
+			for i, tool := range p.tools {
+				if tool.toolCallID == *event.Data.ToolCallID {
+					p.tools = append(p.tools[:i], p.tools[i+1:]...)
+					break
+				}
+			}
+		}
+		p.phase = ProgressThinking
+	case SessionCompactionStart:
+		p.phase = ProgressCompacting
+	case SessionIdle:
+		p.phase = ProgressDone
+		p.tools = nil
+	}
+	after := p.snapshotLocked()
+	after.Elapsed, before.Elapsed = 0, 0
+	if after != before {
+		p.changed = true
+	}
+}
+
+// take returns the current update and whether it changed since the last
+// call to take.
+func (p *progressTracker) take() (ProgressUpdate, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	changed := p.changed
+	p.changed = false
+	return p.snapshotLocked(), changed
+}
+
+func (p *progressTracker) snapshotLocked() ProgressUpdate {
+	update := ProgressUpdate{
+		Phase:         p.phase,
+		RunningTools:  len(p.tools),
+		CharsStreamed: p.chars,
+		Elapsed:       p.now().Sub(p.started),
+	}
+	if len(p.tools) > 0 {
+		// Running tools take precedence over streaming or thinking, which can
+		// interleave with tool execution
+		if p.phase != ProgressDone && p.phase != ProgressCompacting {
+			update.Phase = ProgressRunningTool
+		}
+		update.ToolName = p.tools[len(p.tools)-1].toolName
+	}
+	return update
+}
@@ -0,0 +1,161 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestProgressTracker(t *testing.T) {
+	toolStart := func(id, name string) SessionEvent {
+		return SessionEvent{Type: ToolExecutionStart, Data: Data{ToolCallID: String(id), ToolName: String(name)}}
+	}
+	toolComplete := func(id string) SessionEvent {
+		return SessionEvent{Type: ToolExecutionComplete, Data: Data{ToolCallID: String(id), Success: Bool(true)}}
+	}
+	delta := func(messageID, text string) SessionEvent {
+		return SessionEvent{Type: AssistantMessageDelta, Data: Data{MessageID: String(messageID), DeltaContent: String(text)}}
+	}
+	message := func(messageID, text string) SessionEvent {
+		return SessionEvent{Type: AssistantMessage, Data: Data{MessageID: String(messageID), Content: String(text)}}
+	}
+
+	type step struct {
+		event SessionEvent
+		want  ProgressUpdate
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "thinking, running a tool, then streaming the answer",
+			steps: []step{
+				{SessionEvent{Type: AssistantTurnStart}, ProgressUpdate{Phase: ProgressThinking}},
+				{SessionEvent{Type: AssistantReasoningDelta}, ProgressUpdate{Phase: ProgressThinking}},
+				{message("m1", ""), ProgressUpdate{Phase: ProgressThinking}},
+				{toolStart("t1", "grep"), ProgressUpdate{Phase: ProgressRunningTool, ToolName: "grep", RunningTools: 1}},
+				{toolComplete("t1"), ProgressUpdate{Phase: ProgressThinking}},
+				{delta("m2", "Héllo"), ProgressUpdate{Phase: ProgressStreaming, CharsStreamed: 5}},
+				{delta("m2", ", world"), ProgressUpdate{Phase: ProgressStreaming, CharsStreamed: 12}},
+				{message("m2", "Héllo, world"), ProgressUpdate{Phase: ProgressThinking, CharsStreamed: 12}},
+				{SessionEvent{Type: SessionIdle}, ProgressUpdate{Phase: ProgressDone, CharsStreamed: 12}},
+			},
+		},
+		{
+			name: "parallel tools report the most recent running tool",
+			steps: []step{
+				{toolStart("t1", "view"), ProgressUpdate{Phase: ProgressRunningTool, ToolName: "view", RunningTools: 1}},
+				{toolStart("t2", "bash"), ProgressUpdate{Phase: ProgressRunningTool, ToolName: "bash", RunningTools: 2}},
+				{SessionEvent{Type: AssistantReasoning}, ProgressUpdate{Phase: ProgressRunningTool, ToolName: "bash", RunningTools: 2}},
+				{toolComplete("t2"), ProgressUpdate{Phase: ProgressRunningTool, ToolName: "view", RunningTools: 1}},
+				{toolComplete("unknown"), ProgressUpdate{Phase: ProgressRunningTool, ToolName: "view", RunningTools: 1}},
+				{toolComplete("t1"), ProgressUpdate{Phase: ProgressThinking}},
+			},
+		},
+		{
+			name: "non-streamed messages count their content",
+			steps: []step{
+				{message("m1", "abc"), ProgressUpdate{Phase: ProgressThinking, CharsStreamed: 3}},
+				{message("m2", "de"), ProgressUpdate{Phase: ProgressThinking, CharsStreamed: 5}},
+			},
+		},
+		{
+			name: "compaction",
+			steps: []step{
+				{SessionEvent{Type: SessionCompactionStart}, ProgressUpdate{Phase: ProgressCompacting}},
+				{SessionEvent{Type: SessionCompactionComplete}, ProgressUpdate{Phase: ProgressThinking}},
+			},
+		},
+		{
+			name: "idle clears tools that never completed",
+			steps: []step{
+				{toolStart("t1", "bash"), ProgressUpdate{Phase: ProgressRunningTool, ToolName: "bash", RunningTools: 1}},
+				{SessionEvent{Type: SessionIdle}, ProgressUpdate{Phase: ProgressDone}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			tracker := newProgressTracker(func() time.Time { return now })
+			if update, changed := tracker.take(); !changed || update.Phase != ProgressWaiting {
+				t.Errorf("Expected an initial waiting update, got %+v (changed %v)", update, changed)
+			}
+			for i, step := range tt.steps {
+				now = now.Add(time.Second)
+				tracker.observe(step.event)
+				update, _ := tracker.take()
+				step.want.Elapsed = time.Duration(i+1) * time.Second
+				if update != step.want {
+					t.Errorf("After step %d (%s): expected %+v, got %+v", i, step.event.Type, step.want, update)
+				}
+			}
+		})
+	}
+
+	t.Run("reports a change only when the update differs", func(t *testing.T) {
+		tracker := newProgressTracker(time.Now)
+		tracker.take()
+		tracker.observe(SessionEvent{Type: AssistantTurnStart})
+		if _, changed := tracker.take(); !changed {
+			t.Error("Expected a change after the turn started")
+		}
+		tracker.observe(SessionEvent{Type: AssistantReasoningDelta})
+		tracker.observe(SessionEvent{Type: AssistantUsage})
+		if _, changed := tracker.take(); changed {
+			t.Error("Expected no change while still thinking")
+		}
+	})
+}
+
+func TestSession_SendAndWaitProgress(t *testing.T) {
+	var session *Session
+	var server *fakeServer
+	session, server = newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+		if method == "session.send" {
+			go func() {
+				server.emit(SessionEvent{Type: AssistantTurnStart})
+				server.emit(SessionEvent{Type: ToolExecutionStart, Data: Data{ToolCallID: String("t1"), ToolName: String("grep")}})
+				time.Sleep(60 * time.Millisecond)
+				server.emit(SessionEvent{Type: ToolExecutionComplete, Data: Data{ToolCallID: String("t1")}})
+				for i := 0; i < 50; i++ {
+					server.emit(SessionEvent{Type: AssistantMessageDelta, Data: Data{MessageID: String("m1"), DeltaContent: String(fmt.Sprint(i % 10))}})
+				}
+				time.Sleep(60 * time.Millisecond)
+				server.emit(SessionEvent{Type: AssistantMessage, Data: Data{MessageID: String("m1"), Content: String("done")}})
+				server.emit(SessionEvent{Type: SessionIdle})
+			}()
+			return sessionSendResponse{MessageID: "msg"}, nil
+		}
+		return nil, nil
+	})
+
+	var updates []ProgressUpdate
+	_, err := session.SendAndWaitWithOptions(t.Context(), MessageOptions{Prompt: "find it"}, &SendAndWaitOptions{
+		Timeout:          5 * time.Second,
+		ProgressInterval: 20 * time.Millisecond,
+		OnProgress:       func(update ProgressUpdate) { updates = append(updates, update) },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(updates) > 15 {
+		t.Errorf("Expected updates to be throttled, got %d", len(updates))
+	}
+	last := updates[len(updates)-1]
+	if last.Phase != ProgressDone || last.CharsStreamed != 50 {
+		t.Errorf("Expected a final done update with 50 chars, got %+v", last)
+	}
+	sawTool, sawStreaming := false, false
+	for _, update := range updates {
+		sawTool = sawTool || (update.Phase == ProgressRunningTool && update.ToolName == "grep")
+		sawStreaming = sawStreaming || update.Phase == ProgressStreaming
+	}
+	if !sawTool || !sawStreaming {
+		t.Errorf("Expected running tool and streaming updates, got %+v", updates)
+	}
+}
@@ -0,0 +1,21 @@
+// Package protocol defines the JSON shapes exchanged with the Copilot CLI
+// for session events, permission requests and hooks. Their JSON tags are the
+// single definition of the wire format: package copilot re-exports each type
+// under the same name as an alias, and [Schemas] derives JSON Schemas from
+// them for generating clients in other languages.
+//
+// The session event types are generated from the CLI's session-events
+// schema. copilot.SessionEvent has the fields of [SessionEvent] but is its
+// own type, because it carries the SDK's payload accessors; its payload,
+// [Data], is shared.
+//
+// Changes to these types change the wire format. The schemas are checked
+// into testdata/schema, and a test fails when they drift, so every wire
+// change shows up in review as a schema diff. Regenerate them with
+//
+//	go test ./protocol -update
+//
+// or write them elsewhere with
+//
+//	go run github.com/github/copilot-sdk/go/cmd/protocol-schema -out DIR
+package protocol
@@ -0,0 +1,272 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/timestamp"
+)
+
+// PreToolUseHookInput is the input for a pre-tool-use hook
+type PreToolUseHookInput struct {
+	// Timestamp is the timestamp sent by the CLI if it is an integer, and
+	// otherwise Time in Unix milliseconds.
+	//
+	// Deprecated: Timestamp has no fixed unit, since CLIs send seconds or
+	// milliseconds. Use Time.
+	Timestamp int64 `json:"-"`
+	// Time is when the hook was invoked, or zero if the CLI did not report
+	// it or sent a value that is not a timestamp
+	Time time.Time `json:"-"`
+	// RawTimestamp is the timestamp exactly as sent by the CLI. When it is
+	// empty, as for inputs built in code, the input marshals Time in Unix
+	// milliseconds, or else Timestamp, as its timestamp.
+	RawTimestamp json.RawMessage `json:"timestamp"`
+	Cwd          string          `json:"cwd"`
+	ToolName     string          `json:"toolName"`
+	ToolArgs     any             `json:"toolArgs"`
+}
+
+// PreToolUseHookOutput is the output for a pre-tool-use hook
+type PreToolUseHookOutput struct {
+	PermissionDecision       string `json:"permissionDecision,omitempty"` // "allow", "deny", "ask"
+	PermissionDecisionReason string `json:"permissionDecisionReason,omitempty"`
+	ModifiedArgs             any    `json:"modifiedArgs,omitempty"`
+	AdditionalContext        string `json:"additionalContext,omitempty"`
+	SuppressOutput           bool   `json:"suppressOutput,omitempty"`
+}
+
+// PostToolUseHookInput is the input for a post-tool-use hook
+type PostToolUseHookInput struct {
+	// Timestamp is the timestamp sent by the CLI if it is an integer, and
+	// otherwise Time in Unix milliseconds.
+	//
+	// Deprecated: Timestamp has no fixed unit, since CLIs send seconds or
+	// milliseconds. Use Time.
+	Timestamp int64 `json:"-"`
+	// Time is when the hook was invoked, or zero if the CLI did not report
+	// it or sent a value that is not a timestamp
+	Time time.Time `json:"-"`
+	// RawTimestamp is the timestamp exactly as sent by the CLI. When it is
+	// empty, as for inputs built in code, the input marshals Time in Unix
+	// milliseconds, or else Timestamp, as its timestamp.
+	RawTimestamp json.RawMessage `json:"timestamp"`
+	Cwd          string          `json:"cwd"`
+	ToolName     string          `json:"toolName"`
+	ToolArgs     any             `json:"toolArgs"`
+	ToolResult   any             `json:"toolResult"`
+}
+
+// PostToolUseHookOutput is the output for a post-tool-use hook
+type PostToolUseHookOutput struct {
+	ModifiedResult    any    `json:"modifiedResult,omitempty"`
+	AdditionalContext string `json:"additionalContext,omitempty"`
+	SuppressOutput    bool   `json:"suppressOutput,omitempty"`
+}
+
+// UserPromptSubmittedHookInput is the input for a user-prompt-submitted hook
+type UserPromptSubmittedHookInput struct {
+	// Timestamp is the timestamp sent by the CLI if it is an integer, and
+	// otherwise Time in Unix milliseconds.
+	//
+	// Deprecated: Timestamp has no fixed unit, since CLIs send seconds or
+	// milliseconds. Use Time.
+	Timestamp int64 `json:"-"`
+	// Time is when the hook was invoked, or zero if the CLI did not report
+	// it or sent a value that is not a timestamp
+	Time time.Time `json:"-"`
+	// RawTimestamp is the timestamp exactly as sent by the CLI. When it is
+	// empty, as for inputs built in code, the input marshals Time in Unix
+	// milliseconds, or else Timestamp, as its timestamp.
+	RawTimestamp json.RawMessage `json:"timestamp"`
+	Cwd          string          `json:"cwd"`
+	Prompt       string          `json:"prompt"`
+}
+
+// UserPromptSubmittedHookOutput is the output for a user-prompt-submitted hook
+type UserPromptSubmittedHookOutput struct {
+	ModifiedPrompt    string `json:"modifiedPrompt,omitempty"`
+	AdditionalContext string `json:"additionalContext,omitempty"`
+	SuppressOutput    bool   `json:"suppressOutput,omitempty"`
+}
+
+// SessionStartHookInput is the input for a session-start hook
+type SessionStartHookInput struct {
+	// Timestamp is the timestamp sent by the CLI if it is an integer, and
+	// otherwise Time in Unix milliseconds.
+	//
+	// Deprecated: Timestamp has no fixed unit, since CLIs send seconds or
+	// milliseconds. Use Time.
+	Timestamp int64 `json:"-"`
+	// Time is when the hook was invoked, or zero if the CLI did not report
+	// it or sent a value that is not a timestamp
+	Time time.Time `json:"-"`
+	// RawTimestamp is the timestamp exactly as sent by the CLI. When it is
+	// empty, as for inputs built in code, the input marshals Time in Unix
+	// milliseconds, or else Timestamp, as its timestamp.
+	RawTimestamp  json.RawMessage `json:"timestamp"`
+	Cwd           string          `json:"cwd"`
+	Source        string          `json:"source"` // "startup", "resume", "new"
+	InitialPrompt string          `json:"initialPrompt,omitempty"`
+}
+
+// SessionStartHookOutput is the output for a session-start hook
+type SessionStartHookOutput struct {
+	AdditionalContext string         `json:"additionalContext,omitempty"`
+	ModifiedConfig    map[string]any `json:"modifiedConfig,omitempty"`
+}
+
+// SessionEndHookInput is the input for a session-end hook
+type SessionEndHookInput struct {
+	// Timestamp is the timestamp sent by the CLI if it is an integer, and
+	// otherwise Time in Unix milliseconds.
+	//
+	// Deprecated: Timestamp has no fixed unit, since CLIs send seconds or
+	// milliseconds. Use Time.
+	Timestamp int64 `json:"-"`
+	// Time is when the hook was invoked, or zero if the CLI did not report
+	// it or sent a value that is not a timestamp
+	Time time.Time `json:"-"`
+	// RawTimestamp is the timestamp exactly as sent by the CLI. When it is
+	// empty, as for inputs built in code, the input marshals Time in Unix
+	// milliseconds, or else Timestamp, as its timestamp.
+	RawTimestamp json.RawMessage `json:"timestamp"`
+	Cwd          string          `json:"cwd"`
+	Reason       string          `json:"reason"` // "complete", "error", "abort", "timeout", "user_exit"
+	FinalMessage string          `json:"finalMessage,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// SessionEndHookOutput is the output for a session-end hook
+type SessionEndHookOutput struct {
+	SuppressOutput bool     `json:"suppressOutput,omitempty"`
+	CleanupActions []string `json:"cleanupActions,omitempty"`
+	SessionSummary string   `json:"sessionSummary,omitempty"`
+}
+
+// ErrorOccurredHookInput is the input for an error-occurred hook
+type ErrorOccurredHookInput struct {
+	// Timestamp is the timestamp sent by the CLI if it is an integer, and
+	// otherwise Time in Unix milliseconds.
+	//
+	// Deprecated: Timestamp has no fixed unit, since CLIs send seconds or
+	// milliseconds. Use Time.
+	Timestamp int64 `json:"-"`
+	// Time is when the hook was invoked, or zero if the CLI did not report
+	// it or sent a value that is not a timestamp
+	Time time.Time `json:"-"`
+	// RawTimestamp is the timestamp exactly as sent by the CLI. When it is
+	// empty, as for inputs built in code, the input marshals Time in Unix
+	// milliseconds, or else Timestamp, as its timestamp.
+	RawTimestamp json.RawMessage `json:"timestamp"`
+	Cwd          string          `json:"cwd"`
+	Error        string          `json:"error"`
+	ErrorContext string          `json:"errorContext"` // "model_call", "tool_execution", "system", "user_input"
+	Recoverable  bool            `json:"recoverable"`
+}
+
+// ErrorOccurredHookOutput is the output for an error-occurred hook
+type ErrorOccurredHookOutput struct {
+	SuppressOutput   bool   `json:"suppressOutput,omitempty"`
+	ErrorHandling    string `json:"errorHandling,omitempty"` // "retry", "skip", "abort"
+	RetryCount       int    `json:"retryCount,omitempty"`
+	UserNotification string `json:"userNotification,omitempty"`
+}
+
+// unmarshalHookInput decodes a hook input and parses its raw timestamp. A
+// timestamp that cannot be parsed leaves Time zero instead of failing the
+// hook; the raw value is kept.
+func unmarshalHookInput(data []byte, input any, raw *json.RawMessage, unix *int64, t *time.Time) error {
+	if err := json.Unmarshal(data, input); err != nil {
+		return err
+	}
+	*t, _ = timestamp.Parse(*raw)
+	if n, err := strconv.ParseInt(string(bytes.TrimSpace(*raw)), 10, 64); err == nil {
+		*unix = n
+	} else if !t.IsZero() {
+		*unix = t.UnixMilli()
+	}
+	return nil
+}
+
+// hookTimestamp returns the timestamp a hook input marshals: raw if set, or
+// else t in Unix milliseconds, or else unix, or nil if all are zero.
+func hookTimestamp(raw json.RawMessage, unix int64, t time.Time) json.RawMessage {
+	switch {
+	case len(raw) > 0:
+		return raw
+	case !t.IsZero():
+		return strconv.AppendInt(nil, t.UnixMilli(), 10)
+	case unix != 0:
+		return strconv.AppendInt(nil, unix, 10)
+	}
+	return nil
+}
+
+func (i *PreToolUseHookInput) UnmarshalJSON(data []byte) error {
+	type plain PreToolUseHookInput
+	return unmarshalHookInput(data, (*plain)(i), &i.RawTimestamp, &i.Timestamp, &i.Time)
+}
+
+func (i PreToolUseHookInput) MarshalJSON() ([]byte, error) {
+	type plain PreToolUseHookInput
+	i.RawTimestamp = hookTimestamp(i.RawTimestamp, i.Timestamp, i.Time)
+	return json.Marshal(plain(i))
+}
+
+func (i *PostToolUseHookInput) UnmarshalJSON(data []byte) error {
+	type plain PostToolUseHookInput
+	return unmarshalHookInput(data, (*plain)(i), &i.RawTimestamp, &i.Timestamp, &i.Time)
+}
+
+func (i PostToolUseHookInput) MarshalJSON() ([]byte, error) {
+	type plain PostToolUseHookInput
+	i.RawTimestamp = hookTimestamp(i.RawTimestamp, i.Timestamp, i.Time)
+	return json.Marshal(plain(i))
+}
+
+func (i *UserPromptSubmittedHookInput) UnmarshalJSON(data []byte) error {
+	type plain UserPromptSubmittedHookInput
+	return unmarshalHookInput(data, (*plain)(i), &i.RawTimestamp, &i.Timestamp, &i.Time)
+}
+
+func (i UserPromptSubmittedHookInput) MarshalJSON() ([]byte, error) {
+	type plain UserPromptSubmittedHookInput
+	i.RawTimestamp = hookTimestamp(i.RawTimestamp, i.Timestamp, i.Time)
+	return json.Marshal(plain(i))
+}
+
+func (i *SessionStartHookInput) UnmarshalJSON(data []byte) error {
+	type plain SessionStartHookInput
+	return unmarshalHookInput(data, (*plain)(i), &i.RawTimestamp, &i.Timestamp, &i.Time)
+}
+
+func (i SessionStartHookInput) MarshalJSON() ([]byte, error) {
+	type plain SessionStartHookInput
+	i.RawTimestamp = hookTimestamp(i.RawTimestamp, i.Timestamp, i.Time)
+	return json.Marshal(plain(i))
+}
+
+func (i *SessionEndHookInput) UnmarshalJSON(data []byte) error {
+	type plain SessionEndHookInput
+	return unmarshalHookInput(data, (*plain)(i), &i.RawTimestamp, &i.Timestamp, &i.Time)
+}
+
+func (i SessionEndHookInput) MarshalJSON() ([]byte, error) {
+	type plain SessionEndHookInput
+	i.RawTimestamp = hookTimestamp(i.RawTimestamp, i.Timestamp, i.Time)
+	return json.Marshal(plain(i))
+}
+
+func (i *ErrorOccurredHookInput) UnmarshalJSON(data []byte) error {
+	type plain ErrorOccurredHookInput
+	return unmarshalHookInput(data, (*plain)(i), &i.RawTimestamp, &i.Timestamp, &i.Time)
+}
+
+func (i ErrorOccurredHookInput) MarshalJSON() ([]byte, error) {
+	type plain ErrorOccurredHookInput
+	i.RawTimestamp = hookTimestamp(i.RawTimestamp, i.Timestamp, i.Time)
+	return json.Marshal(plain(i))
+}
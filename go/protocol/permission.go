@@ -0,0 +1,108 @@
+package protocol
+
+import "encoding/json"
+
+// PermissionRequest represents a permission request from the server
+type PermissionRequest struct {
+	Kind       string         `json:"kind"`
+	ToolCallID string         `json:"toolCallId,omitempty"`
+	Extra      map[string]any `json:"-"` // Additional fields vary by kind
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for PermissionRequest
+// to capture additional fields (varying by kind) into the Extra map.
+func (p *PermissionRequest) UnmarshalJSON(data []byte) error {
+	// Unmarshal known fields via an alias to avoid infinite recursion
+	type Alias PermissionRequest
+	var alias Alias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	*p = PermissionRequest(alias)
+
+	// Unmarshal all fields into a generic map
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	// Remove known fields, keep the rest as Extra
+	delete(raw, "kind")
+	delete(raw, "toolCallId")
+	if len(raw) > 0 {
+		p.Extra = raw
+	}
+	return nil
+}
+
+// PermissionRequestResult represents the result of a permission request.
+type PermissionRequestResult struct {
+	// Kind is the decision
+	Kind PermissionResultKind `json:"kind"`
+	// Rules are the rules that decided the request, if any
+	Rules []any `json:"rules,omitempty"`
+	// Reason explains the decision. It is sent to the CLI, which may show it
+	// or ignore it.
+	Reason string `json:"reason,omitempty"`
+	// RawKind, if set, is sent as the kind instead of Kind, without
+	// validation, for kinds newer than this SDK
+	RawKind string `json:"-"`
+}
+
+// PermissionResultKind is the decision on a permission request.
+type PermissionResultKind string
+
+const (
+	// PermissionApproved allows the operation
+	PermissionApproved PermissionResultKind = "approved"
+	// PermissionDeniedByRules denies the operation because of configured rules
+	PermissionDeniedByRules PermissionResultKind = "denied-by-rules"
+	// PermissionDeniedNoApprovalRule denies the operation because no rule
+	// approves it and the user could not be asked
+	PermissionDeniedNoApprovalRule PermissionResultKind = "denied-no-approval-rule-and-could-not-request-from-user"
+	// PermissionDeniedByUser denies the operation because the user declined it
+	PermissionDeniedByUser PermissionResultKind = "denied-interactively-by-user"
+)
+
+// Valid reports whether the CLI accepts the kind.
+func (k PermissionResultKind) Valid() bool {
+	switch k {
+	case PermissionApproved, PermissionDeniedByRules, PermissionDeniedNoApprovalRule, PermissionDeniedByUser:
+		return true
+	}
+	return false
+}
+
+// Approved reports whether the result allows the operation.
+func (r PermissionRequestResult) Approved() bool {
+	return r.kind() == string(PermissionApproved)
+}
+
+// kind returns the kind sent to the CLI.
+func (r PermissionRequestResult) kind() string {
+	if r.RawKind != "" {
+		return r.RawKind
+	}
+	return string(r.Kind)
+}
+
+// MarshalJSON sends RawKind in place of Kind when it is set.
+func (r PermissionRequestResult) MarshalJSON() ([]byte, error) {
+	type plain PermissionRequestResult
+	return json.Marshal(struct {
+		Kind string `json:"kind"`
+		plain
+	}{r.kind(), plain(r)})
+}
+
+// UnmarshalJSON keeps kinds unknown to this SDK in RawKind.
+func (r *PermissionRequestResult) UnmarshalJSON(data []byte) error {
+	type plain PermissionRequestResult
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	if !r.Kind.Valid() {
+		r.RawKind, r.Kind = string(r.Kind), ""
+	}
+	return nil
+}
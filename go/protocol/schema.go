@@ -0,0 +1,81 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// wireTypes are the types described by [Schemas], by name.
+var wireTypes = map[string]reflect.Type{
+	"PermissionRequest":             reflect.TypeFor[PermissionRequest](),
+	"PermissionRequestResult":       reflect.TypeFor[PermissionRequestResult](),
+	"PreToolUseHookInput":           reflect.TypeFor[PreToolUseHookInput](),
+	"PreToolUseHookOutput":          reflect.TypeFor[PreToolUseHookOutput](),
+	"PostToolUseHookInput":          reflect.TypeFor[PostToolUseHookInput](),
+	"PostToolUseHookOutput":         reflect.TypeFor[PostToolUseHookOutput](),
+	"UserPromptSubmittedHookInput":  reflect.TypeFor[UserPromptSubmittedHookInput](),
+	"UserPromptSubmittedHookOutput": reflect.TypeFor[UserPromptSubmittedHookOutput](),
+	"SessionStartHookInput":         reflect.TypeFor[SessionStartHookInput](),
+	"SessionStartHookOutput":        reflect.TypeFor[SessionStartHookOutput](),
+	"SessionEndHookInput":           reflect.TypeFor[SessionEndHookInput](),
+	"SessionEndHookOutput":          reflect.TypeFor[SessionEndHookOutput](),
+	"ErrorOccurredHookInput":        reflect.TypeFor[ErrorOccurredHookInput](),
+	"ErrorOccurredHookOutput":       reflect.TypeFor[ErrorOccurredHookOutput](),
+	"SessionEvent":                  reflect.TypeFor[SessionEvent](),
+}
+
+// Schemas returns the JSON Schema of each wire type in this package, keyed
+// by type name, as indented JSON. The schemas follow the JSON tags; fields
+// tagged "-" are SDK conveniences and are left out.
+func Schemas() (map[string][]byte, error) {
+	options := &jsonschema.ForOptions{
+		TypeSchemas: map[reflect.Type]*jsonschema.Schema{
+			// Hook timestamps are RFC 3339 strings or Unix times in seconds
+			// or milliseconds
+			reflect.TypeFor[json.RawMessage](): {Types: []string{"string", "number", "null"}},
+		},
+	}
+	// Event payload unions are either a string or an object. The object's
+	// keywords only apply to objects, so the union is the object's schema
+	// with string added to its types.
+	for union, object := range map[reflect.Type]reflect.Type{
+		reflect.TypeFor[ContextUnion]():    reflect.TypeFor[ContextClass](),
+		reflect.TypeFor[ErrorUnion]():      reflect.TypeFor[ErrorClass](),
+		reflect.TypeFor[RepositoryUnion](): reflect.TypeFor[RepositoryClass](),
+	} {
+		schema, err := jsonschema.ForType(object, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate schema for %s: %w", object.Name(), err)
+		}
+		schema.Type = ""
+		schema.Types = []string{"string", "object"}
+		options.TypeSchemas[union] = schema
+	}
+	schemas := make(map[string][]byte, len(wireTypes))
+	for name, t := range wireTypes {
+		schema, err := jsonschema.ForType(t, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate schema for %s: %w", name, err)
+		}
+		schema.Schema = "https://json-schema.org/draft/2020-12/schema"
+		schema.Title = name
+		switch t {
+		case reflect.TypeFor[PermissionRequest]():
+			// The remaining fields vary by kind
+			schema.AdditionalProperties = &jsonschema.Schema{}
+		case reflect.TypeFor[PermissionRequestResult]():
+			schema.Properties["kind"].Enum = []any{
+				PermissionApproved, PermissionDeniedByRules, PermissionDeniedNoApprovalRule, PermissionDeniedByUser,
+			}
+		}
+		data, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize schema for %s: %w", name, err)
+		}
+		schemas[name] = append(data, '\n')
+	}
+	return schemas, nil
+}
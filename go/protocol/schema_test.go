@@ -0,0 +1,53 @@
+package protocol
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden schemas in testdata/schema")
+
+func TestSchemas(t *testing.T) {
+	schemas, err := Schemas()
+	if err != nil {
+		t.Fatalf("Failed to generate schemas: %v", err)
+	}
+	dir := filepath.Join("testdata", "schema")
+	if *update {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for name, schema := range schemas {
+			if err := os.WriteFile(filepath.Join(dir, name+".json"), schema, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	golden, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range golden {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		if _, ok := schemas[name]; !ok {
+			t.Errorf("%s has a golden schema but is no longer a wire type; run go test ./protocol -update if the removal is intended", name)
+		}
+	}
+	for name, schema := range schemas {
+		want, err := os.ReadFile(filepath.Join(dir, name+".json"))
+		if err != nil {
+			t.Errorf("%s has no golden schema; run go test ./protocol -update: %v", name, err)
+			continue
+		}
+		if string(schema) != string(want) {
+			t.Errorf("The wire format of %s changed; if intended, run go test ./protocol -update and review the diff.\nGot:\n%s", name, schema)
+		}
+	}
+}
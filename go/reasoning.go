@@ -0,0 +1,87 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Reasoning effort levels for [MessageOptions.ReasoningEffort] and
+// [SessionConfig.ReasoningEffort].
+const (
+	ReasoningEffortLow    = "low"
+	ReasoningEffortMedium = "medium"
+	ReasoningEffortHigh   = "high"
+	ReasoningEffortXHigh  = "xhigh"
+)
+
+// reasoningEfforts lists the valid reasoning effort levels.
+var reasoningEfforts = []string{ReasoningEffortLow, ReasoningEffortMedium, ReasoningEffortHigh, ReasoningEffortXHigh}
+
+// ErrUnsupportedReasoning matches errors from [Session.Send] when a message's
+// reasoning effort or thinking budget is invalid or not supported by the
+// session's model.
+var ErrUnsupportedReasoning = errors.New("unsupported reasoning options")
+
+// UnsupportedReasoningError is returned by [Session.Send] when a message's
+// reasoning options cannot be honored. It matches [ErrUnsupportedReasoning].
+type UnsupportedReasoningError struct {
+	// Model is the ID of the session's model, or empty if it is unknown
+	Model string
+	// ReasoningEffort is the effort the message asked for
+	ReasoningEffort string
+	// ThinkingBudgetTokens is the budget the message asked for
+	ThinkingBudgetTokens int
+	// Supported lists the efforts the model accepts, if it reports them
+	Supported []string
+	// Reason describes what is wrong
+	Reason string
+}
+
+func (e *UnsupportedReasoningError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrUnsupportedReasoning, e.Reason)
+}
+
+func (e *UnsupportedReasoningError) Is(target error) bool { return target == ErrUnsupportedReasoning }
+
+// checkReasoning validates a message's reasoning options against the valid
+// levels and, when the model answering the message is known, its
+// capabilities.
+func (s *Session) checkReasoning(ctx context.Context, options MessageOptions) error {
+	effort, budget := options.ReasoningEffort, options.ThinkingBudgetTokens
+	if effort == "" && budget == 0 {
+		return nil
+	}
+	fail := func(model *ModelInfo, reason string) error {
+		err := &UnsupportedReasoningError{ReasoningEffort: effort, ThinkingBudgetTokens: budget, Reason: reason}
+		if model != nil {
+			err.Model = model.ID
+			err.Supported = model.SupportedReasoningEfforts
+		}
+		return err
+	}
+
+	if effort != "" && !slices.Contains(reasoningEfforts, effort) {
+		return fail(nil, fmt.Sprintf("reasoning effort %q is not one of %s", effort, strings.Join(reasoningEfforts, ", ")))
+	}
+	if budget < 0 {
+		return fail(nil, fmt.Sprintf("thinking budget %d is negative", budget))
+	}
+
+	model := s.preflightModel(ctx, options.Model)
+	if model == nil {
+		return nil
+	}
+	if !model.Capabilities.Supports.ReasoningEffort {
+		return fail(model, fmt.Sprintf("model %s does not support reasoning controls", model.ID))
+	}
+	if effort != "" && len(model.SupportedReasoningEfforts) > 0 && !slices.Contains(model.SupportedReasoningEfforts, effort) {
+		return fail(model, fmt.Sprintf("model %s does not support reasoning effort %q", model.ID, effort))
+	}
+	if limit := model.Capabilities.Limits.MaxContextWindowTokens; limit > 0 && budget >= limit {
+		return fail(model, fmt.Sprintf("thinking budget %d does not fit the %d-token context window of %s", budget, limit, model.ID))
+	}
+	return nil
+}
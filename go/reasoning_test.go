@@ -0,0 +1,100 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestSession_SendReasoning(t *testing.T) {
+	models := []ModelInfo{
+		{ID: "reasoner", Capabilities: ModelCapabilities{
+			Supports: ModelSupports{ReasoningEffort: true},
+			Limits:   ModelLimits{MaxContextWindowTokens: 200000},
+		}, SupportedReasoningEfforts: []string{ReasoningEffortLow, ReasoningEffortMedium, ReasoningEffortHigh}},
+		{ID: "plain", Capabilities: ModelCapabilities{Limits: ModelLimits{MaxContextWindowTokens: 100000}}},
+	}
+	newReasoningSession := func(t *testing.T, model string) (*Session, func() []sessionSendRequest) {
+		var mu sync.Mutex
+		var sent []sessionSendRequest
+		session, _ := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.send" {
+				var req sessionSendRequest
+				json.Unmarshal(params, &req)
+				mu.Lock()
+				sent = append(sent, req)
+				mu.Unlock()
+				return sessionSendResponse{MessageID: "m1"}, nil
+			}
+			return nil, nil
+		})
+		session.listModels = func(context.Context) ([]ModelInfo, error) { return models, nil }
+		session.config.setModel(model)
+		return session, func() []sessionSendRequest {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]sessionSendRequest(nil), sent...)
+		}
+	}
+
+	t.Run("forwards the effort and budget", func(t *testing.T) {
+		session, sent := newReasoningSession(t, "reasoner")
+		_, err := session.Send(t.Context(), MessageOptions{Prompt: "Prove it", ReasoningEffort: ReasoningEffortHigh, ThinkingBudgetTokens: 8000})
+		if err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		if got := sent(); len(got) != 1 || got[0].ReasoningEffort != "high" || got[0].ThinkingBudgetTokens != 8000 {
+			t.Errorf("Expected the reasoning options in session.send, got %+v", got)
+		}
+	})
+
+	t.Run("rejects unsupported combinations before sending", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			model   string
+			options MessageOptions
+		}{
+			{"unknown level", "reasoner", MessageOptions{ReasoningEffort: "extreme"}},
+			{"level the model lacks", "reasoner", MessageOptions{ReasoningEffort: ReasoningEffortXHigh}},
+			{"budget beyond the context window", "reasoner", MessageOptions{ThinkingBudgetTokens: 300000}},
+			{"negative budget", "reasoner", MessageOptions{ThinkingBudgetTokens: -1}},
+			{"model without reasoning", "plain", MessageOptions{ReasoningEffort: ReasoningEffortLow}},
+			{"budget on a model without reasoning", "plain", MessageOptions{ThinkingBudgetTokens: 1000}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				session, sent := newReasoningSession(t, tt.model)
+				tt.options.Prompt = "Prove it"
+				_, err := session.Send(t.Context(), tt.options)
+				var reasoningErr *UnsupportedReasoningError
+				if !errors.As(err, &reasoningErr) || !errors.Is(err, ErrUnsupportedReasoning) {
+					t.Fatalf("Expected an UnsupportedReasoningError, got %v", err)
+				}
+				if len(sent()) != 0 {
+					t.Error("Expected nothing to be sent")
+				}
+			})
+		}
+	})
+
+	t.Run("reports the model's supported levels", func(t *testing.T) {
+		session, _ := newReasoningSession(t, "reasoner")
+		_, err := session.Send(t.Context(), MessageOptions{Prompt: "Prove it", ReasoningEffort: ReasoningEffortXHigh})
+		var reasoningErr *UnsupportedReasoningError
+		if !errors.As(err, &reasoningErr) || reasoningErr.Model != "reasoner" || len(reasoningErr.Supported) != 3 {
+			t.Errorf("Unexpected error: %+v", reasoningErr)
+		}
+	})
+
+	t.Run("sends when the model is unknown", func(t *testing.T) {
+		session, sent := newReasoningSession(t, "unlisted")
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "Prove it", ReasoningEffort: ReasoningEffortMedium}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		if got := sent(); len(got) != 1 || got[0].ReasoningEffort != "medium" {
+			t.Errorf("Expected the effort to be forwarded, got %+v", got)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrToolRegistration matches errors from [Session.RegisterTools].
+var ErrToolRegistration = errors.New("tool registration failed")
+
+// ToolRegistrationError is returned by [Session.RegisterTools] when a tool is
+// invalid or the CLI rejects the new tool set. It matches
+// [ErrToolRegistration].
+type ToolRegistrationError struct {
+	// Tool is the name of the failing tool, or empty if the CLI rejected the
+	// set without naming one
+	Tool string
+	// Err is why registration failed
+	Err error
+	// RollbackErr is set if restoring the previous tool set on the CLI also
+	// failed, in which case the CLI may offer tools the session cannot run
+	RollbackErr error
+}
+
+func (e *ToolRegistrationError) Error() string {
+	var b strings.Builder
+	b.WriteString("failed to register tools")
+	if e.Tool != "" {
+		fmt.Fprintf(&b, ": tool %q", e.Tool)
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	if e.RollbackErr != nil {
+		fmt.Fprintf(&b, " (rollback failed: %v)", e.RollbackErr)
+	}
+	return b.String()
+}
+
+func (e *ToolRegistrationError) Unwrap() error { return e.Err }
+
+func (e *ToolRegistrationError) Is(target error) bool { return target == ErrToolRegistration }
+
+// RegisterTools adds tools to a running session, all or none.
+//
+// Every tool is validated first: it needs a handler, a name that is not
+// registered yet, and a parameter schema the CLI can represent (see
+// [ToolFromFunctionSpec]) that, for tools made with [DefineToolWithSchema],
+// fits the handler's parameter type. The CLI has no call to add tools, so
+// the session is then re-attached with its tools plus the new ones in a
+// single session.resume request, as [Client.Restart] does. If the CLI rejects it,
+// the previous tool set is re-sent, so the session's tools are unchanged,
+// and a *[ToolRegistrationError] naming the failing tool is returned. The
+// session runs the new tools' handlers only once the CLI has accepted them.
+//
+// Example:
+//
+//	err := session.RegisterTools(ctx, []copilot.Tool{searchTool, fetchTool})
+//	var regErr *copilot.ToolRegistrationError
+//	if errors.As(err, &regErr) {
+//	    log.Printf("Tool %s was rejected: %v", regErr.Tool, regErr.Err)
+//	}
+func (s *Session) RegisterTools(ctx context.Context, tools []Tool) error {
+	if err := s.checkNotDestroyed(); err != nil {
+		return err
+	}
+	s.toolRegisterMux.Lock()
+	defer s.toolRegisterMux.Unlock()
+
+	previous := s.reattachRequest
+	if err := validateNewTools(previous.Tools, tools); err != nil {
+		return err
+	}
+
+	req := previous
+	req.Tools = append(append([]Tool{}, previous.Tools...), tools...)
+	req.DisableResume = Bool(true)
+	if err := s.reattach(ctx, req); err != nil {
+		regErr := &ToolRegistrationError{Tool: rejectedTool(err, tools), Err: err}
+		// The rollback must run even if ctx was cancelled mid-request
+		if rollbackErr := s.reattach(context.WithoutCancel(ctx), previous); rollbackErr != nil {
+			regErr.RollbackErr = rollbackErr
+		}
+		return regErr
+	}
+
+	s.reattachRequest.Tools = req.Tools
+	s.addToolHandlers(tools)
+	return nil
+}
+
+// validateNewTools checks tools before they are added to registered ones.
+func validateNewTools(registered, tools []Tool) error {
+	names := make(map[string]bool, len(registered)+len(tools))
+	for _, tool := range registered {
+		names[tool.Name] = true
+	}
+	for _, tool := range tools {
+		fail := func(err error) error { return &ToolRegistrationError{Tool: tool.Name, Err: err} }
+		if names[tool.Name] {
+			return fail(errors.New("a tool with this name is already registered"))
+		}
+		names[tool.Name] = true
+		if tool.Handler == nil {
+			return fail(errors.New("tool has no handler"))
+		}
+		if err := checkFunctionSpec(tool.Name, tool.Parameters); err != nil {
+			return fail(err)
+		}
+		if err := checkToolSchemas([]Tool{tool}); err != nil {
+			return fail(err)
+		}
+		if _, err := json.Marshal(tool); err != nil {
+			return fail(fmt.Errorf("failed to serialize tool: %w", err))
+		}
+	}
+	return nil
+}
+
+// rejectedTool returns the new tool the CLI's error names, preferring the
+// longest match so "search" does not shadow "search_code".
+func rejectedTool(err error, tools []Tool) string {
+	message := err.Error()
+	name := ""
+	for _, tool := range tools {
+		if len(tool.Name) > len(name) && strings.Contains(message, tool.Name) {
+			name = tool.Name
+		}
+	}
+	return name
+}
+
+// reattach sends req as a session.resume request for this session and
+// records the configuration the CLI reports.
+func (s *Session) reattach(ctx context.Context, req resumeSessionRequest) error {
+	result, err := s.rpcClient().RequestContext(ctx, "session.resume", req)
+	if err != nil {
+		return fmt.Errorf("failed to resume session: %w", err)
+	}
+	var response resumeSessionResponse
+	if err := json.Unmarshal(result, &response); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if response.SessionID != s.SessionID {
+		return fmt.Errorf("CLI resumed session %s instead of %s", response.SessionID, s.SessionID)
+	}
+	s.config.resolve(req, response.sessionConfigEcho)
+	return nil
+}
+
+// addToolHandlers registers handlers for tools alongside the existing ones.
+func (s *Session) addToolHandlers(tools []Tool) {
+	s.toolHandlersM.Lock()
+	defer s.toolHandlersM.Unlock()
+	if s.toolHandlers == nil {
+		s.toolHandlers = make(map[string]registeredTool)
+	}
+	for _, tool := range tools {
+		s.toolHandlers[tool.Name] = newRegisteredTool(tool, s.toolTimeout)
+	}
+}
@@ -0,0 +1,141 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+func TestSession_RegisterTools(t *testing.T) {
+	noop := func(ToolInvocation) (ToolResult, error) { return ToolResult{}, nil }
+	object := map[string]any{"type": "object", "properties": map[string]any{"q": map[string]any{"type": "string"}}}
+	existing := Tool{Name: "existing", Parameters: object, Handler: noop}
+
+	// newRegistrationSession returns a session with one tool whose fake CLI
+	// rejects any tool set containing bad_tool, and the tool names of each
+	// session.resume it received.
+	newRegistrationSession := func(t *testing.T, failRollback bool) (*Session, func() [][]string) {
+		var mu sync.Mutex
+		var resumed [][]string
+		session, _ := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method != "session.resume" {
+				return nil, nil
+			}
+			var req struct {
+				SessionID string `json:"sessionId"`
+				Tools     []Tool `json:"tools"`
+			}
+			json.Unmarshal(params, &req)
+			var names []string
+			for _, tool := range req.Tools {
+				names = append(names, tool.Name)
+			}
+			mu.Lock()
+			resumed = append(resumed, names)
+			mu.Unlock()
+			if slices.Contains(names, "bad_tool") || (failRollback && len(names) == 1) {
+				return nil, &jsonrpc2.Error{Code: -32602, Message: `Invalid parameters schema for tool "bad_tool"`}
+			}
+			return resumeSessionResponse{SessionID: req.SessionID}, nil
+		})
+		session.registerTools([]Tool{existing}, 0)
+		session.reattachRequest.SessionID = session.SessionID
+		session.reattachRequest.Tools = []Tool{existing}
+		return session, func() [][]string {
+			mu.Lock()
+			defer mu.Unlock()
+			return resumed
+		}
+	}
+	registered := func(session *Session) []string {
+		var names []string
+		for _, tool := range session.reattachRequest.Tools {
+			names = append(names, tool.Name)
+		}
+		for _, name := range []string{"search", "bad_tool", "fetch"} {
+			if _, ok := session.getToolHandler(name); ok {
+				names = append(names, "handler:"+name)
+			}
+		}
+		return names
+	}
+
+	t.Run("registers all tools in one request", func(t *testing.T) {
+		session, resumed := newRegistrationSession(t, false)
+		err := session.RegisterTools(t.Context(), []Tool{
+			{Name: "search", Parameters: object, Handler: noop},
+			{Name: "fetch", Handler: noop},
+		})
+		if err != nil {
+			t.Fatalf("Failed to register: %v", err)
+		}
+		if got := resumed(); len(got) != 1 || !slices.Equal(got[0], []string{"existing", "search", "fetch"}) {
+			t.Errorf("Expected one resume with every tool, got %v", got)
+		}
+		if got := registered(session); !slices.Equal(got, []string{"existing", "search", "fetch", "handler:search", "handler:fetch"}) {
+			t.Errorf("Unexpected registered tools %v", got)
+		}
+	})
+
+	t.Run("rolls back when the CLI rejects a tool mid-set", func(t *testing.T) {
+		session, resumed := newRegistrationSession(t, false)
+		err := session.RegisterTools(t.Context(), []Tool{
+			{Name: "search", Parameters: object, Handler: noop},
+			{Name: "bad_tool", Parameters: object, Handler: noop},
+			{Name: "fetch", Handler: noop},
+		})
+		var regErr *ToolRegistrationError
+		if !errors.As(err, &regErr) || !errors.Is(err, ErrToolRegistration) || regErr.Tool != "bad_tool" || regErr.RollbackErr != nil {
+			t.Fatalf("Expected a ToolRegistrationError naming bad_tool, got %v", err)
+		}
+		want := [][]string{{"existing", "search", "bad_tool", "fetch"}, {"existing"}}
+		if got := resumed(); len(got) != 2 || !slices.Equal(got[0], want[0]) || !slices.Equal(got[1], want[1]) {
+			t.Errorf("Expected the attempt and a rollback %v, got %v", want, got)
+		}
+		if got := registered(session); !slices.Equal(got, []string{"existing"}) {
+			t.Errorf("Expected the tool set to be unchanged, got %v", got)
+		}
+	})
+
+	t.Run("reports a failed rollback", func(t *testing.T) {
+		session, _ := newRegistrationSession(t, true)
+		err := session.RegisterTools(t.Context(), []Tool{{Name: "bad_tool", Handler: noop}})
+		var regErr *ToolRegistrationError
+		if !errors.As(err, &regErr) || regErr.RollbackErr == nil {
+			t.Errorf("Expected a rollback error, got %v", err)
+		}
+	})
+
+	t.Run("validates every tool before sending", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			tools []Tool
+			tool  string
+		}{
+			{"existing name", []Tool{{Name: "search", Handler: noop}, {Name: "existing", Handler: noop}}, "existing"},
+			{"duplicate name", []Tool{{Name: "search", Handler: noop}, {Name: "search", Handler: noop}}, "search"},
+			{"missing handler", []Tool{{Name: "search", Handler: noop}, {Name: "fetch"}}, "fetch"},
+			{"invalid name", []Tool{{Name: "web search", Handler: noop}}, "web search"},
+			{"non-object schema", []Tool{{Name: "search", Parameters: map[string]any{"type": "string"}, Handler: noop}}, "search"},
+			{"unserializable schema", []Tool{{Name: "search", Parameters: map[string]any{"default": func() {}}, Handler: noop}}, "search"},
+		}
+		for _, tt := range tests {
+			session, resumed := newRegistrationSession(t, false)
+			err := session.RegisterTools(t.Context(), tt.tools)
+			var regErr *ToolRegistrationError
+			if !errors.As(err, &regErr) || regErr.Tool != tt.tool {
+				t.Errorf("%s: expected an error naming %q, got %v", tt.name, tt.tool, err)
+			}
+			if got := resumed(); len(got) != 0 {
+				t.Errorf("%s: expected no request, got %v", tt.name, got)
+			}
+			if got := registered(session); !slices.Equal(got, []string{"existing"}) {
+				t.Errorf("%s: expected the tool set to be unchanged, got %v", tt.name, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package copilot
+
+import (
+	"encoding/json"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+// ServerRequestOrder selects how the client runs the handlers of requests
+// the CLI sends it. See [ClientOptions.ServerRequestOrder].
+type ServerRequestOrder string
+
+const (
+	// ServerRequestsOrdered answers the permission requests of a session one
+	// at a time, in the order the CLI sent them, so decisions cannot be
+	// attributed to the wrong tool call, and likewise its user input
+	// requests and its hook invocations. Each kind is queued separately, so
+	// a user input handler waiting for an answer does not hold up the
+	// session's permission decisions or hooks. Requests of different
+	// sessions, tool calls, and the permission requests of sessions with
+	// PermissionBatching still run concurrently. This is the default.
+	ServerRequestsOrdered ServerRequestOrder = "ordered"
+	// ServerRequestsConcurrent runs the handler of every request in its own
+	// goroutine, as soon as it arrives. Answers may be sent in any order.
+	ServerRequestsConcurrent ServerRequestOrder = "concurrent"
+)
+
+// orderedServerRequests are the methods whose requests are queued per
+// session and method under [ServerRequestsOrdered]. Tool calls are not: the CLI runs
+// them in parallel, and a slow tool must not hold up the decisions for others.
+var orderedServerRequests = map[string]bool{
+	"permission.request": true,
+	"userInput.request":  true,
+	"hooks.invoke":       true,
+}
+
+// serverRequestQueue returns how the JSON-RPC client queues the CLI's
+// requests, or nil to run each concurrently.
+func (c *Client) serverRequestQueue() jsonrpc2.RequestQueueFunc {
+	if c.options.ServerRequestOrder == ServerRequestsConcurrent {
+		return nil
+	}
+	return func(method string, params json.RawMessage) string {
+		if !orderedServerRequests[method] {
+			return ""
+		}
+		var req struct {
+			SessionID string `json:"sessionId"`
+		}
+		if json.Unmarshal(params, &req) != nil {
+			return ""
+		}
+		if method == "permission.request" {
+			session := c.sessions.get(req.SessionID)
+			if session != nil && session.permissionBatcher != nil {
+				// The batcher needs requests to arrive together, and
+				// decides each batch as a whole
+				return ""
+			}
+		}
+		return req.SessionID + "/" + method
+	}
+}
@@ -0,0 +1,137 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClient_ServerRequestOrder(t *testing.T) {
+	// answerOrder sends two permission requests back to back, the first
+	// decided slowly, and returns the IDs of the answers in the order sent.
+	answerOrder := func(t *testing.T, order ServerRequestOrder) string {
+		cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.create" {
+				var req createSessionRequest
+				json.Unmarshal(params, &req)
+				return createSessionResponse{SessionID: req.SessionID}, nil
+			}
+			return nil, nil
+		})
+		cli.mu.Lock()
+		cli.responses = make(chan json.RawMessage, 2)
+		cli.mu.Unlock()
+		client := NewClient(&ClientOptions{CLIUrl: cli.addr(), ServerRequestOrder: order})
+		t.Cleanup(func() { client.ForceStop() })
+		if err := client.Start(t.Context()); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		_, err := client.CreateSession(t.Context(), &SessionConfig{
+			SessionID: "s1",
+			OnPermissionRequest: func(request PermissionRequest, _ PermissionInvocation) (PermissionRequestResult, error) {
+				if request.ToolCallID == "call-1" {
+					time.Sleep(100 * time.Millisecond)
+				}
+				return Approved(), nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		for i, callID := range []string{"call-1", "call-2"} {
+			cli.request(i+1, "permission.request", map[string]any{
+				"sessionId":         "s1",
+				"permissionRequest": map[string]any{"kind": "shell", "toolCallId": callID},
+			})
+		}
+		var ids []string
+		for range 2 {
+			select {
+			case id := <-cli.responses:
+				ids = append(ids, string(id))
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Timed out waiting for answers, got %v", ids)
+			}
+		}
+		return fmt.Sprint(ids)
+	}
+
+	t.Run("queues decisions per session and method", func(t *testing.T) {
+		client := NewClient(nil)
+		client.sessions.put(&Session{SessionID: "batched", permissionBatcher: &permissionBatcher{}})
+		queue := client.serverRequestQueue()
+		tests := []struct {
+			method, params, want string
+		}{
+			{"permission.request", `{"sessionId":"s1"}`, "s1/permission.request"},
+			{"userInput.request", `{"sessionId":"s2"}`, "s2/userInput.request"},
+			{"hooks.invoke", `{"sessionId":"s1"}`, "s1/hooks.invoke"},
+			{"tool.call", `{"sessionId":"s1"}`, ""},
+			{"permission.request", `{"sessionId":"batched"}`, ""},
+			{"hooks.invoke", `{"sessionId":"batched"}`, "batched/hooks.invoke"},
+		}
+		for _, tt := range tests {
+			if got := queue(tt.method, json.RawMessage(tt.params)); got != tt.want {
+				t.Errorf("Expected %s %s in queue %q, got %q", tt.method, tt.params, tt.want, got)
+			}
+		}
+		if NewClient(&ClientOptions{ServerRequestOrder: ServerRequestsConcurrent}).serverRequestQueue() != nil {
+			t.Error("Expected no queues for concurrent requests")
+		}
+	})
+
+	t.Run("answers a session's requests in order by default", func(t *testing.T) {
+		if got := answerOrder(t, ""); got != "[1 2]" {
+			t.Errorf("Expected the answers in request order, got %s", got)
+		}
+	})
+
+	t.Run("does not hold up decisions behind a user input question", func(t *testing.T) {
+		cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.create" {
+				return createSessionResponse{SessionID: "s1"}, nil
+			}
+			return nil, nil
+		})
+		cli.mu.Lock()
+		cli.responses = make(chan json.RawMessage, 2)
+		cli.mu.Unlock()
+		client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+		t.Cleanup(func() { client.ForceStop() })
+		answer := make(chan struct{})
+		defer close(answer)
+		_, err := client.CreateSession(t.Context(), &SessionConfig{
+			SessionID:           "s1",
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			OnUserInputRequest: func(UserInputRequest, UserInputInvocation) (UserInputResponse, error) {
+				<-answer
+				return UserInputResponse{Answer: "yes"}, nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		cli.request(1, "userInput.request", map[string]any{"sessionId": "s1", "question": "Proceed?"})
+		cli.request(2, "permission.request", map[string]any{
+			"sessionId":         "s1",
+			"permissionRequest": map[string]any{"kind": "shell", "toolCallId": "call-1"},
+		})
+		select {
+		case id := <-cli.responses:
+			if string(id) != "2" {
+				t.Errorf("Expected the permission request to be answered first, got %s", id)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out: the permission request waited for the user input question")
+		}
+	})
+
+	t.Run("answers requests as they complete when concurrent", func(t *testing.T) {
+		if got := answerOrder(t, ServerRequestsConcurrent); got != "[2 1]" {
+			t.Errorf("Expected the fast request to be answered first, got %s", got)
+		}
+	})
+}
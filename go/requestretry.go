@@ -0,0 +1,66 @@
+package copilot
+
+import (
+	"context"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+// DefaultRetryMethods are the methods [RetryPolicy] retries when its Methods
+// is empty: requests that only read state, so sending them twice is safe.
+var DefaultRetryMethods = []string{
+	"ping",
+	"status.get",
+	"auth.getStatus",
+	"models.list",
+	"session.list",
+	"session.getMessages",
+}
+
+// RetryPolicy configures [ClientOptions.RetryPolicy]: requests to
+// idempotent methods that fail with [ErrRequestTimeout] are sent again,
+// each time with a new request ID, so a late response to an earlier attempt
+// is discarded.
+type RetryPolicy struct {
+	// MaxAttempts is the most times a request is sent, including the first.
+	// Default: 3.
+	MaxAttempts int
+	// Backoff is the wait before the first retry, doubled before each further
+	// retry. Default: 1 second.
+	Backoff time.Duration
+	// Methods are the JSON-RPC methods retried. Only list methods the CLI
+	// can safely handle twice. Default: [DefaultRetryMethods].
+	Methods []string
+}
+
+// jsonrpc2Policy returns the policy with defaults applied.
+func (p *RetryPolicy) jsonrpc2Policy() jsonrpc2.RetryPolicy {
+	policy := jsonrpc2.RetryPolicy{MaxAttempts: p.MaxAttempts, Methods: p.Methods}
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = 3
+	}
+	// RetryPolicy.Backoff doubles without a cap
+	policy.Delay = Backoff{Initial: p.Backoff, Max: math.MaxInt64}.Next
+	if len(policy.Methods) == 0 {
+		policy.Methods = slices.Clone(DefaultRetryMethods)
+	}
+	return policy
+}
+
+// WithRequestTimeout returns a copy of ctx that overrides
+// [ClientOptions.RequestTimeout] for the requests of calls made with it.
+// Zero or negative means no timeout. A deadline of ctx itself still applies.
+//
+// Example:
+//
+//	// Fail fast when checking whether the CLI is alive
+//	ctx := copilot.WithRequestTimeout(context.Background(), 2*time.Second)
+//	if _, err := client.Ping(ctx, "health"); errors.Is(err, copilot.ErrRequestTimeout) {
+//	    log.Println("CLI is not answering")
+//	}
+func WithRequestTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return jsonrpc2.WithRequestTimeout(ctx, timeout)
+}
@@ -0,0 +1,78 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientOptions_RetryPolicy(t *testing.T) {
+	var statusCalls atomic.Int32
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "status.get":
+			if statusCalls.Add(1) == 1 {
+				// Answer the first attempt after its deadline
+				time.Sleep(150 * time.Millisecond)
+				return GetStatusResponse{Version: "late"}, nil
+			}
+			return GetStatusResponse{Version: "1.0.0"}, nil
+		case "session.list":
+			time.Sleep(150 * time.Millisecond)
+			return listSessionsResponse{}, nil
+		}
+		return nil, nil
+	})
+
+	var mu sync.Mutex
+	var calls []RPCCall
+	client := NewClient(&ClientOptions{
+		CLIUrl:         cli.addr(),
+		RequestTimeout: 50 * time.Millisecond,
+		RetryPolicy:    &RetryPolicy{Backoff: time.Millisecond, Methods: []string{"status.get"}},
+		OnRPCCall: func(call RPCCall) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, call)
+		},
+	})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+
+	status, err := client.GetStatus(t.Context())
+	if err != nil || status.Version != "1.0.0" {
+		t.Fatalf("Expected the retried request to succeed, got %+v, %v", status, err)
+	}
+	if n := statusCalls.Load(); n != 2 {
+		t.Errorf("Expected 2 attempts, got %d", n)
+	}
+
+	if _, err := client.ListSessions(t.Context(), nil); !errors.Is(err, ErrRequestTimeout) {
+		t.Errorf("Expected methods outside the policy to time out, got %v", err)
+	}
+	if _, err := client.ListSessions(WithRequestTimeout(t.Context(), time.Second), nil); err != nil {
+		t.Errorf("Expected the per-call timeout to replace RequestTimeout, got %v", err)
+	}
+
+	// Let the late answer to the first attempt arrive
+	time.Sleep(150 * time.Millisecond)
+	if stats := client.Stats(); stats.PendingRequests != 0 {
+		t.Errorf("Expected no pending requests, got %d", stats.PendingRequests)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var timedOut int
+	for _, call := range calls {
+		if call.Method == "status.get" && errors.Is(call.Err, ErrRequestTimeout) {
+			timedOut++
+		}
+	}
+	if timedOut != 1 {
+		t.Errorf("Expected the timed-out attempt to be reported, got %d", timedOut)
+	}
+}
@@ -0,0 +1,239 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+// ErrSessionDestroyed is returned by [Session] methods once the session has
+// been destroyed. Use [Session.DestroyReason] to find out why. Errors from
+// the CLI reporting that it does not know a session match it too.
+var ErrSessionDestroyed = errors.New("session destroyed")
+
+// RestartError is returned by [Client.Restart] when the CLI was restarted but
+// some sessions could not be re-attached. Those sessions are marked destroyed.
+type RestartError struct {
+	// Reattached lists the IDs of sessions that were re-attached successfully
+	Reattached []string
+	// Failed maps the IDs of sessions that could not be re-attached to the error
+	Failed map[string]error
+}
+
+func (e *RestartError) Error() string {
+	ids := make([]string, 0, len(e.Failed))
+	for id := range e.Failed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, fmt.Sprintf("%s: %v", id, e.Failed[id]))
+	}
+	return fmt.Sprintf("failed to re-attach %d of %d sessions after restart: %s",
+		len(e.Failed), len(e.Failed)+len(e.Reattached), strings.Join(parts, "; "))
+}
+
+// Restart stops the CLI server, starts it again with the same options, and
+// re-attaches every session tracked by the client.
+//
+// Unlike [ClientOptions.AutoRestart], Restart is initiated by the caller and returns once all
+// sessions have been handled. Re-attached sessions keep their event, tool,
+// permission, user input, and hook handlers; any turn in progress when the
+// CLI stopped is lost. Sessions that cannot be re-attached are marked
+// destroyed, and a *[RestartError] reports which sessions succeeded and which
+// failed. If the CLI cannot be started again, every session is marked
+// destroyed and the start error is returned.
+//
+// Restart must not be called concurrently with other operations on the client
+// or its sessions.
+//
+// Example:
+//
+//	if err := client.Restart(ctx); err != nil {
+//	    var restartErr *copilot.RestartError
+//	    if errors.As(err, &restartErr) {
+//	        for id, err := range restartErr.Failed {
+//	            log.Printf("Session %s lost: %v", id, err)
+//	        }
+//	    } else {
+//	        log.Fatal(err)
+//	    }
+//	}
+func (c *Client) Restart(ctx context.Context) error {
+	sessions := c.trackedSessions()
+
+	c.startStopMux.Lock()
+	stopErrs := c.disconnectLocked()
+	c.startStopMux.Unlock()
+
+	if err := c.Start(ctx); err != nil {
+		for _, session := range sessions {
+			c.dropSession(session, fmt.Sprintf("CLI failed to restart: %v", err))
+		}
+		return fmt.Errorf("failed to restart CLI server: %w", errors.Join(append(stopErrs, err)...))
+	}
+	return c.reattachSessions(sessions)
+}
+
+// trackedSessions returns the sessions tracked by the client, ordered by ID.
+func (c *Client) trackedSessions() []*Session {
+	sessions := c.sessions.all()
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].SessionID < sessions[j].SessionID })
+	return sessions
+}
+
+// reattachSessions re-attaches sessions on the current connection, marking
+// destroyed those that fail, and returns a *[RestartError] if any did.
+func (c *Client) reattachSessions(sessions []*Session) error {
+	restartErr := &RestartError{Failed: make(map[string]error)}
+	for _, session := range sessions {
+		if _, destroyed := session.DestroyReason(); destroyed {
+			continue
+		}
+		if err := c.reattachSession(session); err != nil {
+			restartErr.Failed[session.SessionID] = err
+			c.dropSession(session, fmt.Sprintf("remote session could not be re-attached after restart: %v", err))
+			continue
+		}
+		restartErr.Reattached = append(restartErr.Reattached, session.SessionID)
+	}
+
+	if len(restartErr.Failed) > 0 {
+		return restartErr
+	}
+	return nil
+}
+
+// reattachSession resumes a tracked session on the current connection and
+// points the session at it.
+func (c *Client) reattachSession(session *Session) error {
+	c.startStopMux.RLock()
+	client := c.client
+	c.startStopMux.RUnlock()
+	if client == nil {
+		return errNotConnected
+	}
+
+	result, err := client.Request("session.resume", session.reattachRequest)
+	if err != nil {
+		return fmt.Errorf("failed to resume session: %w", err)
+	}
+	var response resumeSessionResponse
+	if err := json.Unmarshal(result, &response); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if response.SessionID != session.SessionID {
+		return fmt.Errorf("CLI resumed session %s instead of %s", response.SessionID, session.SessionID)
+	}
+
+	session.rebind(client, response.WorkspacePath)
+	session.config.resolve(session.reattachRequest, response.sessionConfigEcho)
+	return nil
+}
+
+// dropSession marks a session destroyed and removes it from the registry.
+func (c *Client) dropSession(session *Session, reason string) {
+	session.markDestroyed(reason)
+	c.sessions.removeIf(session)
+}
+
+// rebind points the session at a new connection after a restart. Any turn in
+// progress on the old connection is abandoned.
+func (s *Session) rebind(client *jsonrpc2.Client, workspacePath string) {
+	if workspacePath == "" {
+		workspacePath = s.WorkspacePath()
+	}
+	s.conn.Store(&sessionConn{client: client, workspacePath: workspacePath})
+	if s.pacer != nil {
+		s.pacer.release(s.SessionID)
+	}
+}
+
+// DestroyReason reports why the session was destroyed. The second return value
+// is false while the session is usable.
+//
+// Sessions destroyed with [Session.Destroy] report "destroyed by caller";
+// sessions lost during [Client.Restart] report why they could not be
+// re-attached.
+func (s *Session) DestroyReason() (string, bool) {
+	s.destroyMux.Lock()
+	defer s.destroyMux.Unlock()
+	return s.destroyReason, s.destroyReason != ""
+}
+
+// Done returns a channel that is closed when the session is destroyed, for
+// goroutines that should stop with it.
+//
+// Example:
+//
+//	go func() {
+//	    <-session.Done()
+//	    reason, _ := session.DestroyReason()
+//	    log.Printf("session %s ended: %s", session.SessionID, reason)
+//	}()
+func (s *Session) Done() <-chan struct{} {
+	s.destroyMux.Lock()
+	defer s.destroyMux.Unlock()
+	if s.destroyed == nil {
+		s.destroyed = make(chan struct{})
+		if s.destroyReason != "" {
+			close(s.destroyed)
+		}
+	}
+	return s.destroyed
+}
+
+// markDestroyed records why the session was destroyed and releases its
+// handlers and resources.
+func (s *Session) markDestroyed(reason string) {
+	s.destroyMux.Lock()
+	if s.destroyReason != "" {
+		s.destroyMux.Unlock()
+		return
+	}
+	s.destroyReason = reason
+	if s.destroyed != nil {
+		close(s.destroyed)
+	}
+	s.destroyMux.Unlock()
+
+	s.handlerMutex.Lock()
+	s.handlers = nil
+	s.handlerMutex.Unlock()
+
+	s.toolHandlersM.Lock()
+	s.toolHandlers = nil
+	s.toolHandlersM.Unlock()
+
+	s.permissionMux.Lock()
+	s.permissionHandler = nil
+	s.permissionMux.Unlock()
+	if s.permissionBatcher != nil {
+		s.permissionBatcher.stop()
+	}
+
+	if s.eventOrder != nil {
+		s.eventOrder.stop()
+	}
+	s.trace.destroy()
+	s.removeTempFiles()
+	if s.pacer != nil {
+		s.pacer.release(s.SessionID)
+	}
+	s.detachSharedMCPServers()
+}
+
+// checkNotDestroyed returns an error wrapping [ErrSessionDestroyed] if the
+// session has been destroyed.
+func (s *Session) checkNotDestroyed() error {
+	if reason, destroyed := s.DestroyReason(); destroyed {
+		return fmt.Errorf("%w: %s", ErrSessionDestroyed, reason)
+	}
+	return nil
+}
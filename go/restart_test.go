@@ -0,0 +1,276 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeCLI is a TCP server speaking the CLI protocol. Each accepted connection
+// is served by a fakeServer answering ping itself and everything else with handler.
+type fakeCLI struct {
+	listener net.Listener
+	mu       sync.Mutex
+	conns    int
+	live     []net.Conn
+	servers  []*fakeServer
+	// capabilities is reported in ping responses
+	capabilities *ServerCapabilities
+	// responses is passed to each fakeServer
+	responses chan json.RawMessage
+}
+
+func newFakeCLI(t testing.TB, handler func(method string, params json.RawMessage) (any, error)) *fakeCLI {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	cli := &fakeCLI{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			cli.mu.Lock()
+			cli.conns++
+			cli.live = append(cli.live, conn)
+			cli.mu.Unlock()
+
+			server := &fakeServer{t: t, conn: conn, handler: func(method string, params json.RawMessage) (any, error) {
+				if method == "ping" {
+					version := GetSdkProtocolVersion()
+					return PingResponse{ProtocolVersion: &version, Capabilities: cli.capabilities}, nil
+				}
+				return handler(method, params)
+			}}
+			cli.mu.Lock()
+			server.responses = cli.responses
+			cli.servers = append(cli.servers, server)
+			cli.mu.Unlock()
+			t.Cleanup(func() { conn.Close() })
+			go server.serve()
+		}
+	}()
+	return cli
+}
+
+func (f *fakeCLI) addr() string {
+	return f.listener.Addr().String()
+}
+
+// request sends a request from the CLI to the client on every accepted
+// connection.
+func (f *fakeCLI) request(id int, method string, params any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, server := range f.servers {
+		server.write(map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	}
+}
+
+// emitTo sends a session.event notification for sessionID on every
+// accepted connection.
+func (f *fakeCLI) emitTo(sessionID string, event SessionEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, server := range f.servers {
+		server.write(map[string]any{
+			"jsonrpc": "2.0",
+			"method":  "session.event",
+			"params":  map[string]any{"sessionId": sessionID, "event": event},
+		})
+	}
+}
+
+// dropConnections closes every accepted connection, as if the CLI crashed.
+func (f *fakeCLI) dropConnections() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, conn := range f.live {
+		conn.Close()
+	}
+	f.live = nil
+}
+
+func TestClient_Restart(t *testing.T) {
+	var mu sync.Mutex
+	var resumed []resumeSessionRequest
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "session.create":
+			var req createSessionRequest
+			json.Unmarshal(params, &req)
+			return createSessionResponse{SessionID: req.SessionID}, nil
+		case "session.resume":
+			var req resumeSessionRequest
+			json.Unmarshal(params, &req)
+			mu.Lock()
+			resumed = append(resumed, req)
+			mu.Unlock()
+			if req.SessionID == "lost" {
+				return nil, errors.New("session not found")
+			}
+			return resumeSessionResponse{SessionID: req.SessionID}, nil
+		case "session.getMessages":
+			return sessionGetMessagesResponse{}, nil
+		}
+		return nil, nil
+	})
+
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+
+	kept, err := client.CreateSession(t.Context(), &SessionConfig{
+		SessionID:           "kept",
+		Model:               "gpt-5",
+		OnPermissionRequest: PermissionHandler.ApproveAll,
+		OnUserInputRequest: func(UserInputRequest, UserInputInvocation) (UserInputResponse, error) {
+			return UserInputResponse{}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	lost, err := client.CreateSession(t.Context(), &SessionConfig{SessionID: "lost", OnPermissionRequest: PermissionHandler.ApproveAll})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	err = client.Restart(t.Context())
+	var restartErr *RestartError
+	if !errors.As(err, &restartErr) {
+		t.Fatalf("Expected RestartError, got %v", err)
+	}
+	if len(restartErr.Reattached) != 1 || restartErr.Reattached[0] != "kept" {
+		t.Errorf("Expected kept to be re-attached, got %v", restartErr.Reattached)
+	}
+	if _, ok := restartErr.Failed["lost"]; !ok || len(restartErr.Failed) != 1 {
+		t.Errorf("Expected lost to fail, got %v", restartErr.Failed)
+	}
+
+	t.Run("re-attaches with the original configuration", func(t *testing.T) {
+		mu.Lock()
+		defer mu.Unlock()
+		var keptReq *resumeSessionRequest
+		for i := range resumed {
+			if resumed[i].SessionID == "kept" {
+				keptReq = &resumed[i]
+			}
+		}
+		if keptReq == nil {
+			t.Fatal("Expected kept to be resumed")
+		}
+		if keptReq.Model != "gpt-5" || keptReq.RequestUserInput == nil || !*keptReq.RequestUserInput {
+			t.Errorf("Expected original config to be resent, got %+v", keptReq)
+		}
+		if keptReq.DisableResume == nil || !*keptReq.DisableResume {
+			t.Error("Expected re-attach to disable the resume event")
+		}
+	})
+
+	t.Run("re-attached sessions use the new connection", func(t *testing.T) {
+		if _, err := kept.GetMessages(t.Context()); err != nil {
+			t.Errorf("Expected kept session to work after restart, got %v", err)
+		}
+		if _, destroyed := kept.DestroyReason(); destroyed {
+			t.Error("Expected kept session not to be destroyed")
+		}
+		select {
+		case <-kept.Done():
+			t.Error("Expected kept session's Done channel to be open")
+		default:
+		}
+		cli.mu.Lock()
+		conns := cli.conns
+		cli.mu.Unlock()
+		if conns != 2 {
+			t.Errorf("Expected 2 connections, got %d", conns)
+		}
+	})
+
+	t.Run("failed sessions are destroyed with a reason", func(t *testing.T) {
+		reason, destroyed := lost.DestroyReason()
+		if !destroyed || !strings.Contains(reason, "session not found") {
+			t.Errorf("Expected lost to be destroyed with the remote reason, got %q", reason)
+		}
+		select {
+		case <-lost.Done():
+		default:
+			t.Error("Expected lost session's Done channel to be closed")
+		}
+		if _, err := lost.Send(t.Context(), MessageOptions{Prompt: "hi"}); !errors.Is(err, ErrSessionDestroyed) {
+			t.Errorf("Expected ErrSessionDestroyed, got %v", err)
+		}
+		if err := lost.Destroy(); err != nil {
+			t.Errorf("Expected Destroy on a destroyed session to be a no-op, got %v", err)
+		}
+		tracked := client.sessions.get("lost") != nil
+		if tracked {
+			t.Error("Expected lost to be removed from the session registry")
+		}
+	})
+}
+
+func TestClient_RestartWhileSessionInUse(t *testing.T) {
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "session.create":
+			return createSessionResponse{SessionID: "s1", WorkspacePath: "/ws/1"}, nil
+		case "session.resume":
+			return resumeSessionResponse{SessionID: "s1", WorkspacePath: "/ws/2"}, nil
+		case "session.getMessages":
+			return sessionGetMessagesResponse{}, nil
+		}
+		return nil, nil
+	})
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	t.Cleanup(func() { client.ForceStop() })
+	session, err := client.CreateSession(t.Context(), &SessionConfig{SessionID: "s1", OnPermissionRequest: PermissionHandler.ApproveAll})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	// Run with -race: the session's connection is swapped while these read it
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				session.RPC.Model.GetCurrent(t.Context())
+				session.GetMessages(t.Context())
+				session.WorkspacePath()
+			}
+		}()
+	}
+	for range 3 {
+		if err := client.Restart(t.Context()); err != nil {
+			t.Errorf("Failed to restart: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if _, err := session.RPC.Model.GetCurrent(t.Context()); err != nil {
+		t.Errorf("Expected the session's RPC to follow the new connection, got %v", err)
+	}
+	if got := session.WorkspacePath(); got != "/ws/2" {
+		t.Errorf("Expected the re-attached workspace path, got %q", got)
+	}
+}
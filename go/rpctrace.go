@@ -0,0 +1,215 @@
+package copilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RPCMessageKind is the kind of a JSON-RPC message.
+type RPCMessageKind string
+
+const (
+	RPCRequest      RPCMessageKind = "request"
+	RPCNotification RPCMessageKind = "notification"
+	RPCResponse     RPCMessageKind = "response"
+	// RPCInvalid is a received message that is neither a request nor a
+	// response, which the client discards
+	RPCInvalid RPCMessageKind = "invalid"
+)
+
+// redactedValue replaces the values at redacted paths.
+const redactedValue = "[REDACTED]"
+
+// alwaysRedactedPaths are redacted from traced messages whatever
+// [ClientOptions.TraceRedactPaths] holds.
+var alwaysRedactedPaths = []string{
+	"params.provider.apiKey",
+	"params.provider.bearerToken",
+}
+
+// RPCMessage is a JSON-RPC message exchanged with the CLI, as passed to
+// [ClientOptions.OnRPCMessage].
+type RPCMessage struct {
+	// Incoming is true for messages sent by the CLI
+	Incoming bool
+	// Time is when the message was written or read
+	Time time.Time
+	Kind RPCMessageKind
+	// Method is the method of a request or notification
+	Method string
+	// RequestID is the ID of a request or response
+	RequestID string
+	// JSON is the message as sent or received, uncompressed, with the values
+	// at redacted paths replaced by "[REDACTED]"
+	JSON json.RawMessage
+}
+
+// String formats the message as one line of [ClientOptions.TraceWriter].
+func (m RPCMessage) String() string {
+	arrow := "-->"
+	if m.Incoming {
+		arrow = "<--"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s", m.Time.Format("2006-01-02T15:04:05.000Z07:00"), arrow, m.Kind)
+	if m.Method != "" {
+		b.WriteString(" " + m.Method)
+	}
+	if m.RequestID != "" {
+		b.WriteString(" id=" + m.RequestID)
+	}
+	b.WriteString(" ")
+	b.Write(m.JSON)
+	return b.String()
+}
+
+// rpcTracer passes the JSON-RPC messages of a client to
+// ClientOptions.OnRPCMessage and TraceWriter.
+type rpcTracer struct {
+	redact    [][]string // paths split at dots
+	onMessage func(RPCMessage)
+	mu        sync.Mutex // serializes writes to w
+	w         io.Writer
+	// now is the clock; nil uses time.Now
+	now func() time.Time
+}
+
+// newRPCTracer returns a tracer for options, or nil if nothing traces.
+func newRPCTracer(options ClientOptions) *rpcTracer {
+	if options.OnRPCMessage == nil && options.TraceWriter == nil {
+		return nil
+	}
+	t := &rpcTracer{onMessage: options.OnRPCMessage, w: options.TraceWriter}
+	for _, path := range append(append([]string(nil), alwaysRedactedPaths...), options.TraceRedactPaths...) {
+		if path != "" {
+			t.redact = append(t.redact, strings.Split(path, "."))
+		}
+	}
+	return t
+}
+
+// message describes a traced message.
+func (t *rpcTracer) message(incoming bool, data []byte) RPCMessage {
+	now := time.Now()
+	if t.now != nil {
+		now = t.now()
+	}
+	m := RPCMessage{Incoming: incoming, Time: now, Kind: RPCInvalid, JSON: redactJSON(data, t.redact)}
+	var envelope struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return m
+	}
+	m.Method = envelope.Method
+	hasID := len(envelope.ID) > 0 && string(envelope.ID) != "null"
+	if hasID {
+		m.RequestID = string(envelope.ID)
+		if id, err := strconv.Unquote(m.RequestID); err == nil {
+			m.RequestID = id
+		}
+	}
+	switch {
+	case m.Method != "" && hasID:
+		m.Kind = RPCRequest
+	case m.Method != "":
+		m.Kind = RPCNotification
+	case hasID:
+		m.Kind = RPCResponse
+	}
+	return m
+}
+
+// redactJSON returns data with the values at paths replaced by
+// redactedValue. A "*" segment matches every key of an object and every
+// element of an array. Data without values to redact, or that is not JSON,
+// is returned as it is.
+func redactJSON(data []byte, paths [][]string) json.RawMessage {
+	raw := json.RawMessage(bytes.Clone(data))
+	if len(paths) == 0 {
+		return raw
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return raw
+	}
+	redacted := false
+	for _, path := range paths {
+		redacted = redactPath(value, path) || redacted
+	}
+	if !redacted {
+		return raw
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return raw
+	}
+	return out
+}
+
+// redactPath replaces the values at path below value, and reports whether
+// there were any.
+func redactPath(value any, path []string) bool {
+	if len(path) == 0 {
+		return false
+	}
+	last := len(path) == 1
+	redacted := false
+	visit := func(child any, set func(any)) {
+		if last {
+			set(redactedValue)
+			redacted = true
+			return
+		}
+		redacted = redactPath(child, path[1:]) || redacted
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		if path[0] == "*" {
+			for key, child := range v {
+				visit(child, func(x any) { v[key] = x })
+			}
+		} else if child, ok := v[path[0]]; ok {
+			visit(child, func(x any) { v[path[0]] = x })
+		}
+	case []any:
+		if path[0] == "*" {
+			for i, child := range v {
+				visit(child, func(x any) { v[i] = x })
+			}
+		} else if i, err := strconv.Atoi(path[0]); err == nil && i >= 0 && i < len(v) {
+			visit(v[i], func(x any) { v[i] = x })
+		}
+	}
+	return redacted
+}
+
+// traceMessage passes a message the client sent or received to
+// ClientOptions.OnRPCMessage and TraceWriter.
+func (c *Client) traceMessage(incoming bool, data []byte) {
+	t := c.tracer
+	m := t.message(incoming, data)
+	if t.w != nil {
+		t.mu.Lock()
+		fmt.Fprintln(t.w, m.String())
+		t.mu.Unlock()
+	}
+	if t.onMessage == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			c.diagnostics.recordPanic("rpc message callback", "", r)
+		}
+	}()
+	t.onMessage(m)
+}
@@ -0,0 +1,170 @@
+package copilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRedactJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		paths []string
+		want  string
+	}{
+		{
+			name:  "redacts a nested key",
+			data:  `{"params":{"provider":{"baseUrl":"https://x","apiKey":"sk-1"}}}`,
+			paths: []string{"params.provider.apiKey"},
+			want:  `{"params":{"provider":{"apiKey":"[REDACTED]","baseUrl":"https://x"}}}`,
+		},
+		{
+			name:  "matches every array element and key with a wildcard",
+			data:  `{"params":{"tools":[{"name":"a","description":"one"},{"name":"b","description":"two"}],"env":{"A":"1","B":"2"}}}`,
+			paths: []string{"params.tools.*.description", "params.env.*"},
+			want:  `{"params":{"env":{"A":"[REDACTED]","B":"[REDACTED]"},"tools":[{"description":"[REDACTED]","name":"a"},{"description":"[REDACTED]","name":"b"}]}}`,
+		},
+		{
+			name:  "redacts whole objects and indexed elements",
+			data:  `{"result":{"auth":{"token":"t"},"list":[1,2,3]}}`,
+			paths: []string{"result.auth", "result.list.1"},
+			want:  `{"result":{"auth":"[REDACTED]","list":[1,"[REDACTED]",3]}}`,
+		},
+		{
+			name:  "keeps messages without the paths as they are",
+			data:  `{"jsonrpc":"2.0","id":"1","method":"ping","params":{"n":1.50}}`,
+			paths: []string{"params.provider.apiKey", "params.n.x"},
+			want:  `{"jsonrpc":"2.0","id":"1","method":"ping","params":{"n":1.50}}`,
+		},
+		{
+			name:  "keeps invalid JSON as it is",
+			data:  `{"params":`,
+			paths: []string{"params"},
+			want:  `{"params":`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths [][]string
+			for _, path := range tt.paths {
+				paths = append(paths, strings.Split(path, "."))
+			}
+			if got := string(redactJSON([]byte(tt.data), paths)); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRPCMessage_String(t *testing.T) {
+	tracer := newRPCTracer(ClientOptions{OnRPCMessage: func(RPCMessage) {}})
+	tracer.now = func() time.Time { return time.Date(2026, 1, 2, 15, 4, 5, 6e6, time.UTC) }
+	tests := []struct {
+		incoming bool
+		data     string
+		want     string
+	}{
+		{false, `{"jsonrpc":"2.0","id":"a1","method":"session.send"}`, `2026-01-02T15:04:05.006Z --> request session.send id=a1 {"jsonrpc":"2.0","id":"a1","method":"session.send"}`},
+		{true, `{"jsonrpc":"2.0","id":"a1","result":{}}`, `2026-01-02T15:04:05.006Z <-- response id=a1 {"jsonrpc":"2.0","id":"a1","result":{}}`},
+		{true, `{"jsonrpc":"2.0","id":7,"method":"tool.call"}`, `2026-01-02T15:04:05.006Z <-- request tool.call id=7 {"jsonrpc":"2.0","id":7,"method":"tool.call"}`},
+		{true, `{"jsonrpc":"2.0","method":"session.event"}`, `2026-01-02T15:04:05.006Z <-- notification session.event {"jsonrpc":"2.0","method":"session.event"}`},
+		{true, `garbage`, `2026-01-02T15:04:05.006Z <-- invalid garbage`},
+	}
+	for _, tt := range tests {
+		if got := tracer.message(tt.incoming, []byte(tt.data)).String(); got != tt.want {
+			t.Errorf("Expected %s, got %s", tt.want, got)
+		}
+	}
+}
+
+func TestClientOptions_Trace(t *testing.T) {
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		if method == "session.create" {
+			var req createSessionRequest
+			json.Unmarshal(params, &req)
+			return createSessionResponse{SessionID: req.SessionID}, nil
+		}
+		return nil, nil
+	})
+	cli.mu.Lock()
+	cli.responses = make(chan json.RawMessage, 1)
+	cli.mu.Unlock()
+
+	var mu sync.Mutex
+	var messages []RPCMessage
+	var trace bytes.Buffer
+	client := NewClient(&ClientOptions{
+		CLIUrl: cli.addr(),
+		OnRPCMessage: func(m RPCMessage) {
+			mu.Lock()
+			defer mu.Unlock()
+			messages = append(messages, m)
+		},
+		TraceWriter:      &trace,
+		TraceRedactPaths: []string{"params.tools.*.description"},
+	})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	_, err := client.CreateSession(t.Context(), &SessionConfig{
+		SessionID:           "s1",
+		OnPermissionRequest: PermissionHandler.ApproveAll,
+		Provider:            &ProviderConfig{BaseURL: "https://models.example", APIKey: "sk-secret"},
+		Tools: []Tool{{
+			Name:        "lookup",
+			Description: "internal lookup",
+			Handler: func(ToolInvocation) (ToolResult, error) {
+				return ToolResult{TextResultForLLM: "found"}, nil
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	cli.request(42, "tool.call", map[string]any{"sessionId": "s1", "toolCallId": "call-1", "toolName": "lookup"})
+	select {
+	case <-cli.responses:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the tool result")
+	}
+	client.ForceStop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	find := func(incoming bool, kind RPCMessageKind, method, id string) *RPCMessage {
+		for i, m := range messages {
+			if m.Incoming == incoming && m.Kind == kind && (method == "" || m.Method == method) && (id == "" || m.RequestID == id) {
+				return &messages[i]
+			}
+		}
+		t.Errorf("No %s %s %s %s in %d messages", map[bool]string{true: "incoming", false: "outgoing"}[incoming], kind, method, id, len(messages))
+		return nil
+	}
+	if create := find(false, RPCRequest, "session.create", ""); create != nil {
+		if bytes.Contains(create.JSON, []byte("sk-secret")) || bytes.Contains(create.JSON, []byte("internal lookup")) {
+			t.Errorf("Expected the API key and tool description to be redacted, got %s", create.JSON)
+		}
+		if !bytes.Contains(create.JSON, []byte(`"apiKey":"[REDACTED]"`)) || !bytes.Contains(create.JSON, []byte("https://models.example")) {
+			t.Errorf("Expected only the redacted paths to change, got %s", create.JSON)
+		}
+		find(true, RPCResponse, "", create.RequestID)
+	}
+	find(true, RPCRequest, "tool.call", "42")
+	if result := find(false, RPCResponse, "", "42"); result != nil && !bytes.Contains(result.JSON, []byte("found")) {
+		t.Errorf("Expected the tool result, got %s", result.JSON)
+	}
+
+	lines := strings.Split(strings.TrimSpace(trace.String()), "\n")
+	if len(lines) != len(messages) {
+		t.Errorf("Expected a trace line per message, got %d lines for %d messages", len(lines), len(messages))
+	}
+	if !strings.Contains(trace.String(), "<-- request tool.call id=42 ") || strings.Contains(trace.String(), "sk-secret") {
+		t.Errorf("Unexpected trace:\n%s", trace.String())
+	}
+}
@@ -0,0 +1,116 @@
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ConversationScript is a fixed sequence of prompts run by [Session.RunScript].
+type ConversationScript struct {
+	Steps []ScriptStep
+}
+
+// ScriptStep is one prompt of a [ConversationScript].
+type ScriptStep struct {
+	// Prompt is the message to send
+	Prompt string
+	// Attachments are file or directory attachments sent with the prompt
+	Attachments []Attachment
+	// Timeout limits how long to wait for the session to become idle after
+	// sending the prompt. Default: 60 seconds unless ctx has a deadline.
+	Timeout time.Duration
+	// Validate checks the completed turn. Returning an error stops the script.
+	Validate func(turn ScriptTurn) error
+}
+
+// ScriptTurn records the outcome of one step of a [ConversationScript].
+type ScriptTurn struct {
+	// Step is the index of the step in the script
+	Step int
+	// Prompt is the prompt that was sent
+	Prompt string
+	// MessageID is the ID returned by Send for the prompt
+	MessageID string
+	// FinalMessage is the last assistant.message event of the turn, or nil if there was none
+	FinalMessage *SessionEvent
+	// Events are all events received between sending the prompt and session.idle
+	Events []SessionEvent
+}
+
+// ScriptResult records the turns completed by [Session.RunScript].
+type ScriptResult struct {
+	// Turns holds one entry per completed step, including a step whose
+	// validator failed
+	Turns []ScriptTurn
+}
+
+// ScriptStepError is returned by [Session.RunScript] when a step fails to
+// complete or its validator returns an error.
+type ScriptStepError struct {
+	// Step is the index of the failing step
+	Step int
+	// Err is the send, wait, or validation error
+	Err error
+}
+
+func (e *ScriptStepError) Error() string {
+	return fmt.Sprintf("script step %d: %v", e.Step, e.Err)
+}
+
+func (e *ScriptStepError) Unwrap() error {
+	return e.Err
+}
+
+// RunScript sends each step's prompt in order, waiting for the session to
+// become idle before running the step's validator and moving on.
+//
+// Execution stops at the first step that fails to complete or whose validator
+// returns an error; the returned error is then a *[ScriptStepError]. The result
+// is never nil and records every completed turn, including one whose validator
+// failed.
+//
+// Example:
+//
+//	result, err := session.RunScript(ctx, copilot.ConversationScript{
+//	    Steps: []copilot.ScriptStep{
+//	        {Prompt: "Create a file named hello.txt containing 'hi'"},
+//	        {
+//	            Prompt: "What is in hello.txt?",
+//	            Validate: func(turn copilot.ScriptTurn) error {
+//	                if turn.FinalMessage == nil || !strings.Contains(*turn.FinalMessage.Data.Content, "hi") {
+//	                    return errors.New("expected the file contents in the answer")
+//	                }
+//	                return nil
+//	            },
+//	        },
+//	    },
+//	})
+func (s *Session) RunScript(ctx context.Context, script ConversationScript) (*ScriptResult, error) {
+	result := &ScriptResult{}
+	for i, step := range script.Steps {
+		collected, err := s.sendAndCollect(ctx, MessageOptions{
+			Prompt:      step.Prompt,
+			Attachments: step.Attachments,
+		}, &SendAndWaitOptions{Timeout: step.Timeout})
+		if err != nil {
+			return result, &ScriptStepError{Step: i, Err: err}
+		}
+
+		turn := ScriptTurn{
+			Step:         i,
+			Prompt:       step.Prompt,
+			MessageID:    collected.MessageID,
+			FinalMessage: collected.FinalMessage,
+			Events:       collected.Events,
+		}
+		result.Turns = append(result.Turns, turn)
+
+		if step.Validate != nil {
+			if err := step.Validate(turn); err != nil {
+				return result, &ScriptStepError{Step: i, Err: err}
+			}
+		}
+	}
+	return result, nil
+}
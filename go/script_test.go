@@ -0,0 +1,121 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newScriptedSession returns a session whose fake server answers each prompt
+// with a tool call and an assistant message echoing the prompt. Prompts
+// containing "hang" never become idle.
+func newScriptedSession(t *testing.T) (*Session, *[]sessionSendRequest) {
+	var sent []sessionSendRequest
+	var server *fakeServer
+	session, server := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+		if method != "session.send" {
+			return nil, nil
+		}
+		var req sessionSendRequest
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, err
+		}
+		sent = append(sent, req)
+		messageID := "msg-" + req.Prompt
+		go func() {
+			toolCallID := "call-" + req.Prompt
+			toolName := "view"
+			reply := "echo: " + req.Prompt
+			server.emit(SessionEvent{Type: UserMessage, Data: Data{Content: &req.Prompt}})
+			server.emit(SessionEvent{Type: ToolExecutionStart, Data: Data{ToolCallID: &toolCallID, ToolName: &toolName}})
+			server.emit(SessionEvent{Type: AssistantMessage, Data: Data{Content: &reply}})
+			if !strings.Contains(req.Prompt, "hang") {
+				server.emit(SessionEvent{Type: SessionIdle})
+			}
+		}()
+		return sessionSendResponse{MessageID: messageID}, nil
+	})
+	return session, &sent
+}
+
+func TestSession_RunScript(t *testing.T) {
+	t.Run("runs every step and records turns", func(t *testing.T) {
+		session, sent := newScriptedSession(t)
+		path := "main.go"
+
+		result, err := session.RunScript(t.Context(), ConversationScript{Steps: []ScriptStep{
+			{Prompt: "first", Attachments: []Attachment{{Type: File, Path: &path}}},
+			{Prompt: "second"},
+		}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(result.Turns) != 2 {
+			t.Fatalf("Expected 2 turns, got %d", len(result.Turns))
+		}
+		for i, prompt := range []string{"first", "second"} {
+			turn := result.Turns[i]
+			if turn.Step != i || turn.Prompt != prompt || turn.MessageID != "msg-"+prompt {
+				t.Errorf("Unexpected turn %d: %+v", i, turn)
+			}
+			if turn.FinalMessage == nil || *turn.FinalMessage.Data.Content != "echo: "+prompt {
+				t.Errorf("Unexpected final message for turn %d: %+v", i, turn.FinalMessage)
+			}
+			if len(turn.Events) != 4 || turn.Events[3].Type != SessionIdle {
+				t.Errorf("Expected 4 events ending in session.idle for turn %d, got %+v", i, turn.Events)
+			}
+		}
+		if len(*sent) != 2 || len((*sent)[0].Attachments) != 1 || len((*sent)[1].Attachments) != 0 {
+			t.Errorf("Expected attachments only on the first send, got %+v", *sent)
+		}
+	})
+
+	t.Run("stops at the first failing validator", func(t *testing.T) {
+		session, sent := newScriptedSession(t)
+		validationErr := errors.New("wrong answer")
+
+		result, err := session.RunScript(t.Context(), ConversationScript{Steps: []ScriptStep{
+			{Prompt: "one", Validate: func(ScriptTurn) error { return nil }},
+			{Prompt: "two", Validate: func(turn ScriptTurn) error {
+				if *turn.FinalMessage.Data.Content != "echo: two" {
+					t.Errorf("Validator received unexpected turn: %+v", turn)
+				}
+				return validationErr
+			}},
+			{Prompt: "three"},
+		}})
+
+		var stepErr *ScriptStepError
+		if !errors.As(err, &stepErr) || stepErr.Step != 1 || !errors.Is(err, validationErr) {
+			t.Fatalf("Expected step 1 validation error, got %v", err)
+		}
+		if len(result.Turns) != 2 {
+			t.Errorf("Expected the failing turn to be recorded, got %d turns", len(result.Turns))
+		}
+		if len(*sent) != 2 {
+			t.Errorf("Expected the third step not to be sent, got %d sends", len(*sent))
+		}
+	})
+
+	t.Run("applies the per-step timeout", func(t *testing.T) {
+		session, _ := newScriptedSession(t)
+
+		result, err := session.RunScript(t.Context(), ConversationScript{Steps: []ScriptStep{
+			{Prompt: "ok"},
+			{Prompt: "hang", Timeout: 50 * time.Millisecond},
+		}})
+
+		var stepErr *ScriptStepError
+		if !errors.As(err, &stepErr) || stepErr.Step != 1 {
+			t.Fatalf("Expected step 1 error, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "waiting for session.idle") {
+			t.Errorf("Expected a timeout error, got %v", err)
+		}
+		if len(result.Turns) != 1 {
+			t.Errorf("Expected 1 completed turn, got %d", len(result.Turns))
+		}
+	})
+}
@@ -0,0 +1,228 @@
+package copilot
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+const (
+	// defaultElevatedLatency is the median request latency at which the
+	// server load becomes elevated.
+	defaultElevatedLatency = time.Second
+	// defaultOverloadedLatency is the median request latency at which the
+	// server load becomes overloaded.
+	defaultOverloadedLatency = 5 * time.Second
+	// serverLoadWindow is how long a request's latency counts towards the load.
+	serverLoadWindow = time.Minute
+	// serverLoadMaxSamples is how many recent latencies are kept.
+	serverLoadMaxSamples = 50
+	// serverLoadMinSamples is how many recent latencies are needed before the
+	// load is reported as anything but normal.
+	serverLoadMinSamples = 5
+	// overloadRecheckInterval is how often a send paused by server load
+	// checks whether the load has dropped.
+	overloadRecheckInterval = time.Second
+)
+
+// ServerLoadLevel is how loaded the CLI appears to be. See [Client.ServerLoad].
+type ServerLoadLevel string
+
+const (
+	// ServerLoadNormal means the CLI answers requests promptly.
+	ServerLoadNormal ServerLoadLevel = "normal"
+	// ServerLoadElevated means the CLI's answers are slowing down.
+	ServerLoadElevated ServerLoadLevel = "elevated"
+	// ServerLoadOverloaded means the CLI is answering too slowly to take on
+	// more work.
+	ServerLoadOverloaded ServerLoadLevel = "overloaded"
+)
+
+// serverLoadLevels lists the levels from least to most loaded.
+var serverLoadLevels = []ServerLoadLevel{ServerLoadNormal, ServerLoadElevated, ServerLoadOverloaded}
+
+// ServerLoadOptions configures how [Client.ServerLoad] is derived.
+//
+// The CLI protocol has no load signal, so the SDK estimates one from the
+// latency of its recent requests to the CLI: the median over the last 50
+// requests of the past minute. A level is entered when the median reaches its
+// threshold and left only when the median falls below half of it, so the
+// level does not flap around a threshold.
+type ServerLoadOptions struct {
+	// ElevatedLatency is the median latency at which the load becomes
+	// elevated (default: 1 second)
+	ElevatedLatency time.Duration
+	// OverloadedLatency is the median latency at which the load becomes
+	// overloaded (default: 5 seconds)
+	OverloadedLatency time.Duration
+	// OnChange is called when the load level changes. It is called
+	// synchronously and must not block.
+	OnChange func(ServerLoad)
+}
+
+// ServerLoad describes how loaded the CLI appears to be.
+type ServerLoad struct {
+	Level ServerLoadLevel
+	// Latency is the median latency of recent requests, or zero if there
+	// were none
+	Latency time.Duration
+	// Samples is the number of recent requests Latency is based on
+	Samples int
+	// Since is when Level was entered, or zero if the load has always been
+	// normal
+	Since time.Time
+}
+
+// loadSample is the latency of one request.
+type loadSample struct {
+	at      time.Time
+	latency time.Duration
+}
+
+// loadEstimator derives a server load level from request latencies.
+type loadEstimator struct {
+	enter    [3]time.Duration // latency at which each level is entered
+	onChange func(ServerLoad)
+	now      func() time.Time
+
+	mu      sync.Mutex
+	samples []loadSample
+	level   int
+	since   time.Time
+}
+
+func newLoadEstimator(opts ServerLoadOptions) *loadEstimator {
+	if opts.ElevatedLatency <= 0 {
+		opts.ElevatedLatency = defaultElevatedLatency
+	}
+	if opts.OverloadedLatency <= 0 {
+		opts.OverloadedLatency = max(defaultOverloadedLatency, opts.ElevatedLatency)
+	}
+	return &loadEstimator{
+		enter:    [3]time.Duration{0, opts.ElevatedLatency, opts.OverloadedLatency},
+		onChange: opts.OnChange,
+		now:      time.Now,
+	}
+}
+
+// observeCall records the latency of a completed outgoing request. Requests
+// that got no answer from the CLI are ignored.
+func (e *loadEstimator) observeCall(call jsonrpc2.Call) {
+	if call.Incoming || call.ID == "" {
+		return
+	}
+	var rpcErr *jsonrpc2.Error
+	if call.Err != nil && !errors.As(call.Err, &rpcErr) {
+		return
+	}
+	e.observe(call.Duration)
+}
+
+// observe records a request latency and re-evaluates the load.
+func (e *loadEstimator) observe(latency time.Duration) {
+	e.mu.Lock()
+	e.samples = append(e.samples, loadSample{at: e.now(), latency: latency})
+	if len(e.samples) > serverLoadMaxSamples {
+		e.samples = e.samples[len(e.samples)-serverLoadMaxSamples:]
+	}
+	load, changed := e.evaluateLocked()
+	e.mu.Unlock()
+	if changed && e.onChange != nil {
+		e.onChange(load)
+	}
+}
+
+// current re-evaluates and returns the load.
+func (e *loadEstimator) current() ServerLoad {
+	e.mu.Lock()
+	load, changed := e.evaluateLocked()
+	e.mu.Unlock()
+	if changed && e.onChange != nil {
+		e.onChange(load)
+	}
+	return load
+}
+
+// overloaded reports whether the load is overloaded, without notifying
+// OnChange, so it can be called while holding other locks.
+func (e *loadEstimator) overloaded() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pruneLocked()
+	level, _, _ := e.levelLocked()
+	return serverLoadLevels[level] == ServerLoadOverloaded
+}
+
+// pruneLocked drops samples older than the window. Must be called with e.mu
+// held.
+func (e *loadEstimator) pruneLocked() {
+	cutoff := e.now().Add(-serverLoadWindow)
+	i := 0
+	for i < len(e.samples) && e.samples[i].at.Before(cutoff) {
+		i++
+	}
+	e.samples = e.samples[i:]
+}
+
+// evaluateLocked drops stale samples, moves to the level the recent latency
+// calls for, and reports whether the level changed. Must be called with e.mu
+// held.
+func (e *loadEstimator) evaluateLocked() (ServerLoad, bool) {
+	e.pruneLocked()
+	level, latency, samples := e.levelLocked()
+	changed := level != e.level
+	if changed {
+		e.level = level
+		e.since = e.now()
+	}
+	load := ServerLoad{Level: serverLoadLevels[e.level], Latency: latency, Samples: samples}
+	if e.level != 0 || !e.since.IsZero() {
+		load.Since = e.since
+	}
+	return load, changed
+}
+
+// levelLocked returns the level the current samples call for, starting from
+// the current level: it rises to each level whose entry latency the median
+// reaches, and falls from each level whose exit latency, half the entry
+// latency, the median is below. Too few samples mean normal load. Must be
+// called with e.mu held.
+func (e *loadEstimator) levelLocked() (level int, latency time.Duration, samples int) {
+	samples = len(e.samples)
+	if samples == 0 {
+		return 0, 0, 0
+	}
+	latencies := make([]time.Duration, samples)
+	for i, sample := range e.samples {
+		latencies[i] = sample.latency
+	}
+	slices.Sort(latencies)
+	latency = latencies[samples/2]
+	if samples < serverLoadMinSamples {
+		return 0, latency, samples
+	}
+
+	level = e.level
+	for level < len(e.enter)-1 && latency >= e.enter[level+1] {
+		level++
+	}
+	for level > 0 && latency < e.enter[level]/2 {
+		level--
+	}
+	return level, latency, samples
+}
+
+// ServerLoad returns how loaded the CLI appears to be, estimated from the
+// latency of the client's recent requests. See [ServerLoadOptions].
+//
+// Example:
+//
+//	if client.ServerLoad().Level == copilot.ServerLoadOverloaded {
+//	    // defer background work
+//	}
+func (c *Client) ServerLoad() ServerLoad {
+	return c.serverLoad.current()
+}
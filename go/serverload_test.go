@@ -0,0 +1,144 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+// fakeClock is a manually advanced clock for the load estimator.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestLoadEstimator(opts ServerLoadOptions) (*loadEstimator, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	e := newLoadEstimator(opts)
+	e.now = clock.Now
+	return e, clock
+}
+
+func TestLoadEstimator(t *testing.T) {
+	ms := time.Millisecond
+
+	t.Run("follows a synthetic latency series with hysteresis", func(t *testing.T) {
+		var changes []ServerLoadLevel
+		e, clock := newTestLoadEstimator(ServerLoadOptions{
+			ElevatedLatency:   100 * ms,
+			OverloadedLatency: 500 * ms,
+			OnChange:          func(load ServerLoad) { changes = append(changes, load.Level) },
+		})
+
+		steps := []struct {
+			latency time.Duration
+			count   int
+			want    ServerLoadLevel
+		}{
+			{20 * ms, 10, ServerLoadNormal},
+			{150 * ms, 15, ServerLoadElevated},
+			// Hovering just under the elevated threshold does not flap back
+			{90 * ms, 20, ServerLoadElevated},
+			{110 * ms, 10, ServerLoadElevated},
+			{700 * ms, 30, ServerLoadOverloaded},
+			// Below the overloaded threshold but above half of it
+			{400 * ms, 30, ServerLoadOverloaded},
+			{300 * ms, 30, ServerLoadOverloaded},
+			{200 * ms, 30, ServerLoadElevated},
+			{40 * ms, 30, ServerLoadNormal},
+		}
+		for _, step := range steps {
+			for range step.count {
+				clock.now = clock.now.Add(100 * ms)
+				e.observe(step.latency)
+			}
+			if got := e.current(); got.Level != step.want {
+				t.Errorf("After %d calls at %v: expected %s, got %+v", step.count, step.latency, step.want, got)
+			}
+		}
+
+		want := []ServerLoadLevel{ServerLoadElevated, ServerLoadOverloaded, ServerLoadElevated, ServerLoadNormal}
+		if len(changes) != len(want) {
+			t.Fatalf("Expected changes %v, got %v", want, changes)
+		}
+		for i := range want {
+			if changes[i] != want[i] {
+				t.Errorf("Expected changes %v, got %v", want, changes)
+				break
+			}
+		}
+	})
+
+	t.Run("ignores isolated slow calls", func(t *testing.T) {
+		e, clock := newTestLoadEstimator(ServerLoadOptions{})
+		for i := range 20 {
+			clock.now = clock.now.Add(100 * ms)
+			latency := 30 * ms
+			if i%5 == 0 {
+				latency = 8 * time.Second
+			}
+			e.observe(latency)
+		}
+		if got := e.current(); got.Level != ServerLoadNormal || got.Latency != 30*ms || got.Samples != 20 {
+			t.Errorf("Expected normal load at 30ms over 20 samples, got %+v", got)
+		}
+	})
+
+	t.Run("needs a few samples and recovers once they age out", func(t *testing.T) {
+		e, clock := newTestLoadEstimator(ServerLoadOptions{})
+		for range serverLoadMinSamples - 1 {
+			e.observe(10 * time.Second)
+		}
+		if got := e.current(); got.Level != ServerLoadNormal {
+			t.Errorf("Expected normal load with too few samples, got %+v", got)
+		}
+		e.observe(10 * time.Second)
+		if got := e.current(); got.Level != ServerLoadOverloaded || got.Since != clock.now {
+			t.Errorf("Expected overloaded since now, got %+v", got)
+		}
+		if !e.overloaded() {
+			t.Error("Expected overloaded to report true")
+		}
+
+		clock.now = clock.now.Add(serverLoadWindow + time.Second)
+		if e.overloaded() {
+			t.Error("Expected stale samples to be dropped")
+		}
+		if got := e.current(); got.Level != ServerLoadNormal || got.Samples != 0 {
+			t.Errorf("Expected normal load with no recent samples, got %+v", got)
+		}
+	})
+
+	t.Run("counts only answered outgoing requests", func(t *testing.T) {
+		e, _ := newTestLoadEstimator(ServerLoadOptions{})
+		e.observeCall(jsonrpc2.Call{Method: "session.send", ID: "1", Duration: time.Second})
+		e.observeCall(jsonrpc2.Call{Method: "session.list", ID: "2", Duration: time.Second, Err: &jsonrpc2.Error{Code: -32603}})
+		e.observeCall(jsonrpc2.Call{Method: "ping", ID: "3", Duration: time.Second, Err: jsonrpc2.ErrConnectionClosed})
+		e.observeCall(jsonrpc2.Call{Method: "tool.call", ID: "4", Incoming: true, Duration: time.Second})
+		e.observeCall(jsonrpc2.Call{Method: "session.event", Incoming: true})
+		if got := e.current().Samples; got != 2 {
+			t.Errorf("Expected 2 samples, got %d", got)
+		}
+	})
+}
+
+func TestPacer_PauseWhenOverloaded(t *testing.T) {
+	load, _ := newTestLoadEstimator(ServerLoadOptions{})
+	for range serverLoadMinSamples {
+		load.observe(10 * time.Second)
+	}
+	p := newPacer(PacingOptions{PauseWhenOverloaded: true})
+	p.load = load
+	p.busy["busy"] = struct{}{}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.acquire(ctx, "idle"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected an idle session to wait while overloaded, got %v", err)
+	}
+	if _, err := p.acquire(t.Context(), "busy"); err != nil {
+		t.Errorf("Expected a busy session to send, got %v", err)
+	}
+}
@@ -4,17 +4,28 @@ package copilot
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"runtime/debug"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+	"github.com/github/copilot-sdk/go/internal/sdklog"
 	"github.com/github/copilot-sdk/go/rpc"
 )
 
 type sessionHandler struct {
-	id uint64
-	fn SessionEventHandler
+	id   uint64
+	name string
+	fn   SessionEventHandler
+	// accepts reports whether the handler wants events of a type; nil
+	// accepts every event
+	accepts func(SessionEventType) bool
+	stats   *handlerStats
 }
 
 // Session represents a single conversation session with the Copilot CLI.
@@ -51,41 +62,108 @@ type sessionHandler struct {
 type Session struct {
 	// SessionID is the unique identifier for this session.
 	SessionID         string
-	workspacePath     string
-	client            *jsonrpc2.Client
+	conn              atomic.Pointer[sessionConn] // replaced by rebind after a restart
 	handlers          []sessionHandler
 	nextHandlerID     uint64
 	handlerMutex      sync.RWMutex
-	toolHandlers      map[string]ToolHandler
+	slowHandlers      *SlowHandlerOptions // nil uses the defaults
+	toolHandlers      map[string]registeredTool
 	toolHandlersM     sync.RWMutex
+	toolCalls         *toolCallCache
+	missingTools      missingToolWarnings
+	toolLogs          toolLogTracker
+	runningTools      runningToolCalls
+	workspaceLimit    *workspaceLimiter
 	permissionHandler PermissionHandlerFunc
 	permissionMux     sync.RWMutex
 	userInputHandler  UserInputHandler
 	userInputMux      sync.RWMutex
+	userInputFallback *UserInputFallback
+	promptPreflight   *PromptPreflight
+	budget            *budgetTracker
+	usage             usageTracker
 	hooks             *SessionHooks
 	hooksMux          sync.RWMutex
+	listModels        func(context.Context) ([]ModelInfo, error)
+	capabilities      func() *ServerCapabilities
+	maxInlineBytes    int // ClientOptions.MaxInlineAttachmentBytes
+	eventAliases      func() *eventAliasTable
+	fork              func(ctx context.Context, model string) (*Session, func(), error)
+	tempFiles         []string
+	tempFilesMux      sync.Mutex
+	messageRefs       messageRefTracker
+	timeline          timelineRecorder
+	sanitizer         sanitizerState
+	pacer             *pacer
+	reattachRequest   resumeSessionRequest
+	destroyMux        sync.Mutex
+	destroyReason     string
+	destroyed         chan struct{} // created by Done
+	sequentialTools   atomic.Bool   // set while a deterministic turn runs
+	toolSerial        sync.Mutex    // held by tool handlers when sequentialTools is set
+	toolTimeout       time.Duration // default timeout of registered tools
+	toolRegisterMux   sync.Mutex    // serializes RegisterTools calls
+	eventOrder        *eventOrderGuard
+	diagnostics       *diagnosticsRecorder
+	logger            *sdklog.Logger // nil writes to stderr
+	state             sessionState
+	summary           summaryState
+	imported          []SessionEvent // history imported without CLI support
+	aborts            abortSignal
+	trace             turnTrace
+	config            sessionConfigState
+	permissionPolicy  *PermissionPolicy
+	autoApprove       *autoApprover
+	permissionBatcher *permissionBatcher
+	pendingActions    *pendingActions
+	sharedMCP         []*sharedMCPServer
+	modelFallbacks    *modelFallbackChain
+	configWarnings    []ConfigWarning
+	sharedMCPMux      sync.Mutex
 
-	// RPC provides typed session-scoped RPC methods.
+	// RPC provides typed session-scoped RPC methods. It follows the session
+	// to a new connection after [Client.Restart].
 	RPC *rpc.SessionRpc
 }
 
+// sessionConn is the state of a session that is bound to its connection to
+// the CLI. It is replaced as a whole when the session is re-attached, so
+// readers see either the old connection or the new one.
+type sessionConn struct {
+	client        *jsonrpc2.Client
+	workspacePath string
+}
+
+// rpcClient returns the connection the session currently sends on, or nil.
+func (s *Session) rpcClient() *jsonrpc2.Client {
+	if conn := s.conn.Load(); conn != nil {
+		return conn.client
+	}
+	return nil
+}
+
 // WorkspacePath returns the path to the session workspace directory when infinite
 // sessions are enabled. Contains checkpoints/, plan.md, and files/ subdirectories.
 // Returns empty string if infinite sessions are disabled.
 func (s *Session) WorkspacePath() string {
-	return s.workspacePath
+	if conn := s.conn.Load(); conn != nil {
+		return conn.workspacePath
+	}
+	return ""
 }
 
 // newSession creates a new session wrapper with the given session ID and client.
 func newSession(sessionID string, client *jsonrpc2.Client, workspacePath string) *Session {
-	return &Session{
-		SessionID:     sessionID,
-		workspacePath: workspacePath,
-		client:        client,
-		handlers:      make([]sessionHandler, 0),
-		toolHandlers:  make(map[string]ToolHandler),
-		RPC:           rpc.NewSessionRpc(client, sessionID),
+	s := &Session{
+		SessionID:    sessionID,
+		handlers:     make([]sessionHandler, 0),
+		toolHandlers: make(map[string]registeredTool),
+		toolCalls:    newToolCallCache(),
 	}
+	s.conn.Store(&sessionConn{client: client, workspacePath: workspacePath})
+	s.RPC = rpc.NewSessionRpc(jsonrpc2.NewForwarder(s.rpcClient), sessionID)
+	s.initState()
+	return s
 }
 
 // Send sends a message to this session and waits for the response.
@@ -98,6 +176,22 @@ func newSession(sessionID string, client *jsonrpc2.Client, workspacePath string)
 //
 // Returns the message ID of the response, which can be used to correlate events,
 // or an error if the session has been destroyed or the connection fails.
+// Use [Session.MessageRef] to map it to the IDs on the turn's events, or
+// [Session.FindTurn] to fetch the turn from history.
+//
+// When the client has [ClientOptions.Pacing] configured, Send may block until
+// the pacing limits allow the message to be sent. A rejection due to rate
+// limiting is returned as a [*RateLimitError].
+// If options.Images is set and the current model does not support vision,
+// returns an error wrapping [ErrModelLacksVision] without contacting the model.
+// Inline attachments over [ClientOptions.MaxInlineAttachmentBytes] fail with
+// an *[AttachmentTooLargeError] without sending.
+// If options.Template is set, its name and variables are recorded in the
+// message's [Session.MessageRef].
+// If the prompt and attachments are estimated to exceed the model's limit,
+// returns a *[PromptTooLargeError] without sending; see [PromptPreflight].
+// If the message would exceed the session's budget, returns a
+// *[BudgetExceededError] without sending; see [BudgetConfig].
 //
 // Example:
 //
@@ -111,23 +205,176 @@ func newSession(sessionID string, client *jsonrpc2.Client, workspacePath string)
 //	    log.Printf("Failed to send message: %v", err)
 //	}
 func (s *Session) Send(ctx context.Context, options MessageOptions) (string, error) {
+	messageID, _, err := s.send(ctx, options)
+	return messageID, err
+}
+
+// sendResult describes how [Session.send] sent a message.
+type sendResult struct {
+	// fallback is the model fallback taken, if any
+	fallback *ModelFallback
+	// determinism is set for deterministic messages
+	determinism *EffectiveDeterminism
+}
+
+// send sends a message, falling back to the next model in the session's
+// fallback chain at most once if the current model is rejected.
+func (s *Session) send(ctx context.Context, options MessageOptions) (string, sendResult, error) {
+	if err := s.checkNotDestroyed(); err != nil {
+		return "", sendResult{}, err
+	}
+
+	if options.Initiator != "" {
+		if err := validateAttributionTag("Initiator", options.Initiator); err != nil {
+			return "", sendResult{}, err
+		}
+	}
+	if options.ResponseLanguage != "" {
+		if err := validateLanguageTag("ResponseLanguage", options.ResponseLanguage); err != nil {
+			return "", sendResult{}, err
+		}
+	}
+	if options.Deterministic != nil {
+		if err := validateDeterminism(options.Deterministic); err != nil {
+			return "", sendResult{}, err
+		}
+	}
+	if err := s.checkMessageModel(options); err != nil {
+		return "", sendResult{}, err
+	}
+	if err := s.checkAttachments(options); err != nil {
+		return "", sendResult{}, err
+	}
+
 	req := sessionSendRequest{
-		SessionID:   s.SessionID,
-		Prompt:      options.Prompt,
-		Attachments: options.Attachments,
-		Mode:        options.Mode,
+		SessionID:      s.SessionID,
+		Prompt:         options.Prompt,
+		Attachments:    sendAttachments(options.Attachments),
+		Mode:           options.Mode,
+		ResponseSchema: options.ResponseSchema,
+		Initiator:      options.Initiator,
+		Model:          options.Model,
+
+		ReasoningEffort:      options.ReasoningEffort,
+		ThinkingBudgetTokens: options.ThinkingBudgetTokens,
+		Determinism:          options.Deterministic,
 	}
+	if req.Prompt == "" && options.Template != nil {
+		req.Prompt = options.Template.Prompt
+	}
+	if options.ResponseLanguage != "" && !strings.EqualFold(options.ResponseLanguage, s.Config().ResponseLanguage) {
+		req.Prompt = promptWithResponseLanguage(req.Prompt, options.ResponseLanguage)
+	}
+	if err := s.preflightPrompt(ctx, options, req.Prompt); err != nil {
+		return "", sendResult{}, err
+	}
+	if err := s.checkReasoning(ctx, options); err != nil {
+		return "", sendResult{}, err
+	}
+	if err := s.checkBudget(ctx, options); err != nil {
+		return "", sendResult{}, err
+	}
+
+	for _, attachment := range options.InlineAttachments {
+		req.Attachments = append(req.Attachments, encodeInline(attachment))
+	}
+	if len(options.Images) > 0 {
+		images, err := s.prepareImages(ctx, options.Images, options.Model)
+		if err != nil {
+			return "", sendResult{}, err
+		}
+		req.Attachments = append(req.Attachments, sendAttachments(images)...)
+	}
+	var determinism *EffectiveDeterminism
+	if req.Determinism != nil {
+		if err := stabilizeSendRequest(&req); err != nil {
+			return "", sendResult{}, err
+		}
+		determinism = s.effectiveDeterminism(req)
+	}
+	s.sequentialTools.Store(determinism != nil)
 
-	result, err := s.client.Request("session.send", req)
+	s.trace.begin()
+	// Marked before the request, as session.idle may arrive before the response
+	wasBusy := s.state.markBusy()
+	pending := s.messageRefs.begin()
+	pending.template = options.Template
+	// The message counts against the client's pacing once, however often
+	// it is sent, and frees the session's busy slot on failure only if it
+	// took it
+	var paced bool
+	var err error
+	if s.pacer != nil {
+		if paced, err = s.pacer.acquire(ctx, s.SessionID); err != nil {
+			err = fmt.Errorf("waiting for send pacing: %w", err)
+		}
+	}
+	// unsent undoes the marks for a message that was not sent
+	unsent := func() {
+		s.trace.end()
+		s.state.unmarkBusy(wasBusy)
+		s.messageRefs.abandon(pending)
+		if paced {
+			s.pacer.release(s.SessionID)
+		}
+	}
+	var result json.RawMessage
+	var fallback *ModelFallback
+	if err == nil {
+		result, err = s.sendRequest(req)
+	}
+	// The fallback chain replaces the session's model, which a message
+	// naming its own model does not use
+	if err != nil && s.modelFallbacks != nil && req.Model == "" && isModelFallbackError(err) {
+		var fallbackErr error
+		fallback, fallbackErr = s.fallBack(ctx, err)
+		if fallbackErr != nil {
+			err = errors.Join(err, fallbackErr)
+		} else if fallback != nil {
+			result, err = s.sendRequest(req)
+		}
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to send message: %w", err)
+		var rateLimitErr *RateLimitError
+		if errors.As(err, &rateLimitErr) && s.pacer != nil {
+			s.pacer.backoff(rateLimitErr.RetryAfter)
+		}
+		if req.Model != "" {
+			err = s.explainModelRejection(ctx, req.Model, err)
+		}
+		unsent()
+		return "", sendResult{fallback: fallback}, fmt.Errorf("failed to send message: %w", err)
 	}
 
 	var response sessionSendResponse
 	if err := json.Unmarshal(result, &response); err != nil {
-		return "", fmt.Errorf("failed to unmarshal send response: %w", err)
+		unsent()
+		return "", sendResult{fallback: fallback}, fmt.Errorf("failed to unmarshal send response: %w", err)
 	}
-	return response.MessageID, nil
+	s.messageRefs.recordSend(pending, response.MessageID)
+	s.trace.recordSend(response.MessageID)
+	return response.MessageID, sendResult{fallback: fallback, determinism: determinism}, nil
+}
+
+// sendRequest makes a session.send call. The caller acquires pacing.
+// Rejections due to rate limiting or an unavailable model are returned as a
+// *[RateLimitError] or *[ModelUnavailableError], and other failures are
+// classified by classifyError.
+func (s *Session) sendRequest(req sessionSendRequest) (json.RawMessage, error) {
+	result, err := s.rpcClient().Request("session.send", req)
+	if err != nil {
+		if rateLimitErr := asRateLimitError(err); rateLimitErr != nil {
+			return nil, rateLimitErr
+		}
+		if unavailableErr := asModelUnavailableError(err); unavailableErr != nil {
+			return nil, unavailableErr
+		}
+		return nil, classifyError(err)
+	}
+	if s.pacer != nil {
+		s.pacer.succeeded()
+	}
+	return result, nil
 }
 
 // SendAndWait sends a message to this session and waits until the session becomes idle.
@@ -137,11 +384,16 @@ func (s *Session) Send(ctx context.Context, options MessageOptions) (string, err
 // has finished processing the message.
 //
 // Events are still delivered to handlers registered via [Session.On] while waiting.
+// For the turn's token usage and its other events, use [Session.SendAndCollect];
+// [Session.Usage] reports the usage of the whole session.
 //
 // Parameters:
 //   - options: The message options including the prompt and optional attachments.
-//   - timeout: How long to wait for completion. Defaults to 60 seconds if zero.
-//     Controls how long to wait; does not abort in-flight agent work.
+//
+// If ctx has no deadline, waits up to 60 seconds, not counting time spent in
+// the session's permission and user input handlers. Use
+// [Session.SendAndWaitWithOptions] to configure the timeout. The timeout
+// controls how long to wait; it does not abort in-flight agent work.
 //
 // Returns the final assistant message event, or nil if none was received.
 // Returns an error if the timeout is reached or the connection fails.
@@ -158,57 +410,66 @@ func (s *Session) Send(ctx context.Context, options MessageOptions) (string, err
 //	    fmt.Println(*response.Data.Content)
 //	}
 func (s *Session) SendAndWait(ctx context.Context, options MessageOptions) (*SessionEvent, error) {
-	if _, ok := ctx.Deadline(); !ok {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, 60*time.Second)
-		defer cancel()
-	}
-
-	idleCh := make(chan struct{}, 1)
-	errCh := make(chan error, 1)
-	var lastAssistantMessage *SessionEvent
-	var mu sync.Mutex
-
-	unsubscribe := s.On(func(event SessionEvent) {
-		switch event.Type {
-		case AssistantMessage:
-			mu.Lock()
-			eventCopy := event
-			lastAssistantMessage = &eventCopy
-			mu.Unlock()
-		case SessionIdle:
-			select {
-			case idleCh <- struct{}{}:
-			default:
-			}
-		case SessionError:
-			errMsg := "session error"
-			if event.Data.Message != nil {
-				errMsg = *event.Data.Message
-			}
-			select {
-			case errCh <- fmt.Errorf("session error: %s", errMsg):
-			default:
-			}
-		}
-	})
-	defer unsubscribe()
+	return s.sendAndWait(ctx, options, nil)
+}
+
+// SendAndWaitWithOptions is like [Session.SendAndWait] with configurable waiting.
+//
+// The wait timeout is paused while the session's permission and user input
+// handlers run, so a turn waiting on a human approval is not timed out, unless
+// waitOptions.IncludeHandlerTime is set. A deadline on ctx always applies in
+// full, including handler time.
+//
+// Example:
+//
+//	response, err := session.SendAndWaitWithOptions(ctx, copilot.MessageOptions{
+//	    Prompt: "Refactor the parser",
+//	}, &copilot.SendAndWaitOptions{Timeout: 5 * time.Minute})
+func (s *Session) SendAndWaitWithOptions(ctx context.Context, options MessageOptions, waitOptions *SendAndWaitOptions) (*SessionEvent, error) {
+	s.recordWaitTimeout(waitOptions)
+	return s.sendAndWait(ctx, options, waitOptions)
+}
 
-	_, err := s.Send(ctx, options)
+// sendAndWait sends a message and returns the turn's final assistant message.
+func (s *Session) sendAndWait(ctx context.Context, options MessageOptions, waitOptions *SendAndWaitOptions) (*SessionEvent, error) {
+	turn, err := s.sendAndCollect(ctx, options, waitOptions)
 	if err != nil {
 		return nil, err
 	}
+	return turn.FinalMessage, nil
+}
 
-	select {
-	case <-idleCh:
-		mu.Lock()
-		result := lastAssistantMessage
-		mu.Unlock()
-		return result, nil
-	case err := <-errCh:
-		return nil, err
-	case <-ctx.Done(): // TODO: remove once session.Send honors the context
-		return nil, fmt.Errorf("waiting for session.idle: %w", ctx.Err())
+// SendAndCollect is like [Session.SendAndWaitWithOptions], but returns the
+// whole turn: every assistant message, tool call and reasoning event until
+// the session became idle, the session.idle event, the token usage, and
+// whether the message fell back to another model. waitOptions may be nil.
+//
+// Only the turn's own events are collected. Once the user.message event for
+// the prompt arrives, events with another interaction ID, such as those of a
+// concurrent Send on the same session, are left out.
+//
+// Example:
+//
+//	result, err := session.SendAndCollect(ctx, copilot.MessageOptions{Prompt: "Summarize the diff"}, nil)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, message := range result.AssistantMessages {
+//	    fmt.Println(*message.Data.Content)
+//	}
+//	if result.ModelFallback != nil {
+//	    log.Printf("Answered by %s instead of %s", result.ModelFallback.To, result.ModelFallback.From)
+//	}
+func (s *Session) SendAndCollect(ctx context.Context, options MessageOptions, waitOptions *SendAndWaitOptions) (*TurnResult, error) {
+	s.recordWaitTimeout(waitOptions)
+	return s.sendAndCollect(ctx, options, waitOptions)
+}
+
+// recordWaitTimeout reports a raw wait timeout as a legacy use. It must be
+// called directly from the exported send method.
+func (s *Session) recordWaitTimeout(waitOptions *SendAndWaitOptions) {
+	if waitOptions != nil && waitOptions.Timeout != 0 {
+		s.diagnostics.recordDeprecatedUse(DeprecatedWaitTimeout, 2)
 	}
 }
 
@@ -235,12 +496,62 @@ func (s *Session) SendAndWait(ctx context.Context, options MessageOptions) (*Ses
 //	// Later, to stop receiving events:
 //	unsubscribe()
 func (s *Session) On(handler SessionEventHandler) func() {
+	return s.subscribe("", handler, nil)
+}
+
+// OnTypes subscribes to events of the given types only, like [Session.On].
+//
+// Events are filtered before dispatch, so a handler interested in a few event
+// types is never called for others, such as high-frequency streaming deltas.
+// With no types, the handler receives no events.
+//
+// Example:
+//
+//	unsubscribe := session.OnTypes([]copilot.SessionEventType{
+//	    copilot.ToolExecutionStart,
+//	    copilot.ToolExecutionComplete,
+//	}, func(event copilot.SessionEvent) {
+//	    fmt.Println("Tool event:", event.Type)
+//	})
+//	defer unsubscribe()
+func (s *Session) OnTypes(types []SessionEventType, handler SessionEventHandler) func() {
+	set := make(map[SessionEventType]struct{}, len(types))
+	for _, t := range types {
+		set[t] = struct{}{}
+	}
+	return s.subscribe("", handler, func(t SessionEventType) bool {
+		_, ok := set[t]
+		return ok
+	})
+}
+
+// OnPrefix subscribes to events whose type starts with prefix, like
+// [Session.On]. For example, "tool." matches tool.execution_start and
+// tool.execution_complete. Events are filtered before dispatch, as with
+// [Session.OnTypes].
+//
+// Example:
+//
+//	unsubscribe := session.OnPrefix("tool.", func(event copilot.SessionEvent) {
+//	    fmt.Println("Tool event:", event.Type)
+//	})
+//	defer unsubscribe()
+func (s *Session) OnPrefix(prefix string, handler SessionEventHandler) func() {
+	return s.subscribe("", handler, func(t SessionEventType) bool {
+		return strings.HasPrefix(string(t), prefix)
+	})
+}
+
+// subscribe registers a handler, named name in [Session.HandlerStats], for
+// the events accepted by accepts, or for all events if accepts is nil, and
+// returns its unsubscribe function.
+func (s *Session) subscribe(name string, handler SessionEventHandler, accepts func(SessionEventType) bool) func() {
 	s.handlerMutex.Lock()
 	defer s.handlerMutex.Unlock()
 
 	id := s.nextHandlerID
 	s.nextHandlerID++
-	s.handlers = append(s.handlers, sessionHandler{id: id, fn: handler})
+	s.handlers = append(s.handlers, sessionHandler{id: id, name: name, fn: handler, accepts: accepts, stats: &handlerStats{}})
 
 	// Return unsubscribe function
 	return func() {
@@ -249,39 +560,73 @@ func (s *Session) On(handler SessionEventHandler) func() {
 
 		for i, h := range s.handlers {
 			if h.id == id {
-				s.handlers = append(s.handlers[:i], s.handlers[i+1:]...)
+				// slices.Delete zeroes the vacated slot, so the backing array
+				// doesn't keep the removed closure and what it captured alive.
+				s.handlers = slices.Delete(s.handlers, i, i+1)
 				break
 			}
 		}
 	}
 }
 
+// HandlerCount returns the number of event handlers currently registered with
+// [Session.On], [Session.OnTypes] or [Session.OnPrefix], including those registered internally while a
+// [Session.SendAndWait] is in progress. It is intended for tests and leak
+// checks.
+func (s *Session) HandlerCount() int {
+	s.handlerMutex.RLock()
+	defer s.handlerMutex.RUnlock()
+	return len(s.handlers)
+}
+
 // registerTools registers tool handlers for this session.
 //
 // Tools allow the assistant to execute custom functions. When the assistant
 // invokes a tool, the corresponding handler is called with the tool arguments.
 //
 // This method is internal and typically called when creating a session with tools.
-func (s *Session) registerTools(tools []Tool) {
+func (s *Session) registerTools(tools []Tool, defaultTimeout time.Duration) {
 	s.toolHandlersM.Lock()
 	defer s.toolHandlersM.Unlock()
 
-	s.toolHandlers = make(map[string]ToolHandler)
+	s.toolTimeout = defaultTimeout
+	s.toolHandlers = make(map[string]registeredTool)
 	for _, tool := range tools {
 		if tool.Name == "" || tool.Handler == nil {
 			continue
 		}
-		s.toolHandlers[tool.Name] = tool.Handler
+		s.toolHandlers[tool.Name] = newRegisteredTool(tool, defaultTimeout)
+	}
+}
+
+// registeredTool is a tool handler with its resolved call settings.
+type registeredTool struct {
+	handler    ToolHandler
+	timeout    time.Duration // zero if calls are not timed out
+	idempotent bool
+}
+
+// newRegisteredTool resolves tool's call settings, using defaultTimeout if
+// the tool sets none.
+func newRegisteredTool(tool Tool, defaultTimeout time.Duration) registeredTool {
+	timeout := tool.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	return registeredTool{
+		handler:    tool.Handler,
+		timeout:    max(timeout, 0),
+		idempotent: tool.Idempotent,
 	}
 }
 
-// getToolHandler retrieves a registered tool handler by name.
-// Returns the handler and true if found, or nil and false if not registered.
-func (s *Session) getToolHandler(name string) (ToolHandler, bool) {
+// getToolHandler retrieves a registered tool by name.
+// Returns the tool and true if found, or the zero value and false if not registered.
+func (s *Session) getToolHandler(name string) (registeredTool, bool) {
 	s.toolHandlersM.RLock()
-	handler, ok := s.toolHandlers[name]
+	tool, ok := s.toolHandlers[name]
 	s.toolHandlersM.RUnlock()
-	return handler, ok
+	return tool, ok
 }
 
 // registerPermissionHandler registers a permission handler for this session.
@@ -306,19 +651,49 @@ func (s *Session) getPermissionHandler() PermissionHandlerFunc {
 // handlePermissionRequest handles a permission request from the Copilot CLI.
 // This is an internal method called by the SDK when the CLI requests permission.
 func (s *Session) handlePermissionRequest(request PermissionRequest) (PermissionRequestResult, error) {
+	if result, decided, err := s.emulatePreToolUse(request); decided {
+		return result, err
+	}
+	if s.permissionPolicy != nil {
+		if result, decided := s.decideByPolicy(request); decided {
+			return result, nil
+		}
+	}
+	if s.autoApprove != nil {
+		if result, decided := s.autoApprove.decide(request); decided {
+			return result, nil
+		}
+	}
+	if s.pendingActions != nil && s.pendingActions.accepts(request.Kind) {
+		return s.awaitPermission(request), nil
+	}
+	if s.permissionBatcher != nil && s.permissionBatcher.accepts(request.Kind) {
+		return s.permissionBatcher.submit(request)
+	}
+
 	handler := s.getPermissionHandler()
 
 	if handler == nil {
-		return PermissionRequestResult{
-			Kind: "denied-no-approval-rule-and-could-not-request-from-user",
-		}, nil
+		return DeniedNoApprovalRule(), nil
 	}
 
+	ctx, messageID, traceID := s.trace.current()
 	invocation := PermissionInvocation{
 		SessionID: s.SessionID,
+		Context:   ctx,
+		MessageID: messageID,
+		TraceID:   traceID,
 	}
 
-	return handler(request, invocation)
+	defer s.state.begin(holdPermission, "")()
+	result, err := handler(request, invocation)
+	if err != nil {
+		return result, err
+	}
+	if err := s.checkPermissionResult(result); err != nil {
+		return PermissionRequestResult{}, err
+	}
+	return result, nil
 }
 
 // registerUserInputHandler registers a user input handler for this session.
@@ -343,16 +718,26 @@ func (s *Session) getUserInputHandler() UserInputHandler {
 // handleUserInputRequest handles a user input request from the Copilot CLI.
 // This is an internal method called by the SDK when the CLI requests user input.
 func (s *Session) handleUserInputRequest(request UserInputRequest) (UserInputResponse, error) {
+	ctx, messageID, traceID := s.trace.current()
+	return s.askUser(request, UserInputInvocation{
+		SessionID: s.SessionID,
+		Context:   ctx,
+		MessageID: messageID,
+		TraceID:   traceID,
+	})
+}
+
+// askUser answers a question from the CLI or from [ToolInvocation.AskUser]
+// with the user input handler, or the fallback if none is registered.
+func (s *Session) askUser(request UserInputRequest, invocation UserInputInvocation) (UserInputResponse, error) {
 	handler := s.getUserInputHandler()
 
 	if handler == nil {
-		return UserInputResponse{}, fmt.Errorf("no user input handler registered")
-	}
-
-	invocation := UserInputInvocation{
-		SessionID: s.SessionID,
+		return s.answerWithoutHandler(request)
 	}
 
+	defer s.state.begin(holdUserInput, "")()
+	defer s.timeline.begin(TimelineUserInput, request.Question, "", invocation.TraceID)()
 	return handler(request, invocation)
 }
 
@@ -381,11 +766,18 @@ func (s *Session) handleHooksInvoke(hookType string, rawInput json.RawMessage) (
 	hooks := s.getHooks()
 
 	if hooks == nil {
-		return nil, nil
+		if hookType != "postToolUse" || s.sanitizer.sanitizer == nil {
+			return nil, nil
+		}
+		hooks = &SessionHooks{}
 	}
 
+	ctx, messageID, traceID := s.trace.current()
 	invocation := HookInvocation{
 		SessionID: s.SessionID,
+		Context:   ctx,
+		MessageID: messageID,
+		TraceID:   traceID,
 	}
 
 	switch hookType {
@@ -397,17 +789,32 @@ func (s *Session) handleHooksInvoke(hookType string, rawInput json.RawMessage) (
 		if err := json.Unmarshal(rawInput, &input); err != nil {
 			return nil, fmt.Errorf("invalid hook input: %w", err)
 		}
-		return hooks.OnPreToolUse(input, invocation)
+		output, err := hooks.OnPreToolUse(input, invocation)
+		if err == nil && output != nil && output.PermissionDecision == "ask" && s.pendingActions != nil {
+			return s.awaitPreToolUse(invocation.Context, input, *output), nil
+		}
+		return output, err
 
 	case "postToolUse":
-		if hooks.OnPostToolUse == nil {
+		if hooks.OnPostToolUse == nil && s.sanitizer.sanitizer == nil {
 			return nil, nil
 		}
 		var input PostToolUseHookInput
 		if err := json.Unmarshal(rawInput, &input); err != nil {
 			return nil, fmt.Errorf("invalid hook input: %w", err)
 		}
-		return hooks.OnPostToolUse(input, invocation)
+		var output *PostToolUseHookOutput
+		if hooks.OnPostToolUse != nil {
+			var err error
+			if output, err = hooks.OnPostToolUse(input, invocation); err != nil {
+				return output, err
+			}
+		}
+		output = s.sanitizePostToolUse(invocation.Context, input, output)
+		if output == nil && hooks.OnPostToolUse == nil {
+			return nil, nil
+		}
+		return output, nil
 
 	case "userPromptSubmitted":
 		if hooks.OnUserPromptSubmitted == nil {
@@ -455,25 +862,63 @@ func (s *Session) handleHooksInvoke(hookType string, rawInput json.RawMessage) (
 
 // dispatchEvent dispatches an event to all registered handlers.
 // This is an internal method; handlers are called synchronously and any panics
-// are recovered to prevent crashing the event dispatcher.
+// are recovered to prevent crashing the event dispatcher. When an event
+// ordering guard is configured, delivery may be delayed to restore order.
 func (s *Session) dispatchEvent(event SessionEvent) {
+	s.toolLogs.observeEvent(&event)
+	s.runningTools.observeEvent(&event)
+	s.trace.observeEvent(&event)
+	s.messageRefs.recordEvent(event)
+	s.state.observeEvent(event)
+	s.summary.observeEvent(event)
+	s.config.observeEvent(event)
+	if s.pacer != nil {
+		s.pacer.observeEvent(event)
+	}
+	if s.workspaceLimit != nil {
+		s.workspaceLimit.observeEvent(s, event)
+	}
+	s.usage.observeEvent(event)
+	if s.budget != nil {
+		s.budget.observeEvent(event)
+	}
+
+	if s.eventOrder != nil {
+		s.eventOrder.add(event)
+		return
+	}
+	s.deliverEvent(event)
+}
+
+// deliverEvent calls every registered handler that accepts the event.
+func (s *Session) deliverEvent(event SessionEvent) {
 	s.handlerMutex.RLock()
-	handlers := make([]SessionEventHandler, 0, len(s.handlers))
+	// Allocated only if a handler accepts the event, so events no handler
+	// wants cost no allocation
+	var handlers []sessionHandler
 	for _, h := range s.handlers {
-		handlers = append(handlers, h.fn)
+		if h.accepts == nil || h.accepts(event.Type) {
+			if handlers == nil {
+				handlers = make([]sessionHandler, 0, len(s.handlers))
+			}
+			handlers = append(handlers, h)
+		}
 	}
 	s.handlerMutex.RUnlock()
 
-	for _, handler := range handlers {
+	for _, h := range handlers {
+		start := time.Now()
 		// Call handler - don't let panics crash the dispatcher
 		func() {
 			defer func() {
 				if r := recover(); r != nil {
-					fmt.Printf("Error in session event handler: %v\n", r)
+					s.diagnostics.recordPanic("event handler", s.SessionID, r)
+					s.logger.Error("event handler panicked", "sessionID", s.SessionID, "eventType", event.Type, "eventID", event.ID, "panic", r, "stack", string(debug.Stack()))
 				}
 			}()
-			handler(event)
+			h.fn(event)
 		}()
+		s.recordHandlerCall(h, event.Type, time.Since(start))
 	}
 }
 
@@ -483,6 +928,16 @@ func (s *Session) dispatchEvent(event SessionEvent) {
 // assistant responses, tool executions, and other session events in
 // chronological order.
 //
+// The order of the returned events is authoritative. Events delivered live to
+// [Session.On] handlers may arrive out of order, for example when events are
+// backfilled after a reconnect; see [EventOrderOptions].
+//
+// History is eventually consistent with [Session.Send]: Send returns once the
+// CLI has accepted the message, which may be before the message is written to
+// history, so a GetMessages call right after Send may not include it yet. Use
+// [Session.GetMessagesWithOptions] with [GetMessagesOptions.ConsistentWith] to
+// read your own writes.
+//
 // Returns an error if the session has been destroyed or the connection fails.
 //
 // Example:
@@ -498,17 +953,111 @@ func (s *Session) dispatchEvent(event SessionEvent) {
 //	    }
 //	}
 func (s *Session) GetMessages(ctx context.Context) ([]SessionEvent, error) {
+	return s.GetMessagesWithOptions(ctx, nil)
+}
+
+// GetMessagesWithOptions retrieves the session's history like
+// [Session.GetMessages]. With [GetMessagesOptions.ConsistentWith] set, it
+// re-reads the history until it includes the user message sent with that
+// message ID, backing off between reads, and returns a
+// *[ConsistencyTimeoutError] if [GetMessagesOptions.ConsistencyTimeout]
+// elapses first.
+//
+// Example:
+//
+//	messageID, err := session.Send(ctx, copilot.MessageOptions{Prompt: "Hello"})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	events, err := session.GetMessagesWithOptions(ctx, &copilot.GetMessagesOptions{
+//	    ConsistentWith: messageID,
+//	})
+func (s *Session) GetMessagesWithOptions(ctx context.Context, options *GetMessagesOptions) ([]SessionEvent, error) {
+	if options == nil || options.ConsistentWith == "" {
+		return s.getMessages(ctx)
+	}
+	return s.getMessagesConsistentWith(ctx, options.ConsistentWith, options.ConsistencyTimeout)
+}
 
-	result, err := s.client.Request("session.getMessages", sessionGetMessagesRequest{SessionID: s.SessionID})
+// getMessages reads the session history once.
+func (s *Session) getMessages(ctx context.Context) ([]SessionEvent, error) {
+	if err := s.checkNotDestroyed(); err != nil {
+		return nil, err
+	}
+
+	result, err := s.rpcClient().RequestContext(ctx, "session.getMessages", sessionGetMessagesRequest{SessionID: s.SessionID})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get messages: %w", err)
+		return nil, fmt.Errorf("failed to get messages: %w", classifyError(err))
 	}
 
 	var response sessionGetMessagesResponse
 	if err := json.Unmarshal(result, &response); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal get messages response: %w", err)
 	}
-	return response.Events, nil
+	if s.eventAliases != nil {
+		aliases := s.eventAliases()
+		for i := range response.Events {
+			aliases.resolve(&response.Events[i])
+		}
+	}
+	return s.withImported(response.Events), nil
+}
+
+// defaultConsistencyTimeout bounds the wait of
+// [GetMessagesOptions.ConsistentWith] when no timeout is set.
+const defaultConsistencyTimeout = 5 * time.Second
+
+// getMessagesConsistentWith re-reads the history until it includes the user
+// message sent with messageID.
+func (s *Session) getMessagesConsistentWith(ctx context.Context, messageID string, timeout time.Duration) ([]SessionEvent, error) {
+	if timeout <= 0 {
+		timeout = defaultConsistencyTimeout
+	}
+	start := time.Now()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	backoff := Backoff{Initial: 25 * time.Millisecond, Max: 200 * time.Millisecond}
+	for attempt := 1; ; attempt++ {
+		events, err := s.getMessages(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// The user.message event may only be linked to the send after an
+		// earlier read, so look the reference up again each time
+		ref, _ := s.messageRefs.lookup(messageID)
+		if historyIncludes(events, messageID, ref) {
+			return events, nil
+		}
+
+		retry := time.NewTimer(backoff.Next(attempt))
+		select {
+		case <-retry.C:
+		case <-deadline.C:
+			retry.Stop()
+			return nil, &ConsistencyTimeoutError{MessageID: messageID, Waited: time.Since(start), Events: events}
+		case <-ctx.Done():
+			retry.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// historyIncludes reports whether events include the user message sent with
+// messageID.
+func historyIncludes(events []SessionEvent, messageID string, ref MessageRef) bool {
+	for _, event := range events {
+		if event.Type != UserMessage {
+			continue
+		}
+		if event.ID == messageID || (ref.UserEventID != "" && event.ID == ref.UserEventID) {
+			return true
+		}
+		if ref.InteractionID != "" && event.Data.InteractionID != nil && *event.Data.InteractionID == ref.InteractionID {
+			return true
+		}
+	}
+	return false
 }
 
 // Destroy destroys this session and releases all associated resources.
@@ -517,7 +1066,9 @@ func (s *Session) GetMessages(ctx context.Context) ([]SessionEvent, error) {
 // handlers and tool handlers are cleared. To continue the conversation,
 // use [Client.ResumeSession] with the session ID.
 //
-// Returns an error if the connection fails.
+// Returns an error if the connection fails. Destroying a session that was
+// already destroyed, for example because it could not be re-attached by
+// [Client.Restart], is a no-op.
 //
 // Example:
 //
@@ -526,31 +1077,30 @@ func (s *Session) GetMessages(ctx context.Context) ([]SessionEvent, error) {
 //	    log.Printf("Failed to destroy session: %v", err)
 //	}
 func (s *Session) Destroy() error {
-	_, err := s.client.Request("session.destroy", sessionDestroyRequest{SessionID: s.SessionID})
+	return s.destroy(context.Background())
+}
+
+// destroy destroys the session, giving up when ctx is done.
+func (s *Session) destroy(ctx context.Context) error {
+	if _, destroyed := s.DestroyReason(); destroyed {
+		return nil
+	}
+
+	_, err := s.rpcClient().RequestContext(ctx, "session.destroy", sessionDestroyRequest{SessionID: s.SessionID})
 	if err != nil {
 		return fmt.Errorf("failed to destroy session: %w", err)
 	}
 
-	// Clear handlers
-	s.handlerMutex.Lock()
-	s.handlers = nil
-	s.handlerMutex.Unlock()
-
-	s.toolHandlersM.Lock()
-	s.toolHandlers = nil
-	s.toolHandlersM.Unlock()
-
-	s.permissionMux.Lock()
-	s.permissionHandler = nil
-	s.permissionMux.Unlock()
-
+	s.markDestroyed("destroyed by caller")
 	return nil
 }
 
 // Abort aborts the currently processing message in this session.
 //
 // Use this to cancel a long-running request. The session remains valid
-// and can continue to be used for new messages.
+// and can continue to be used for new messages. Once the CLI acknowledges
+// the abort, any [Session.SendAndWait] or [Session.SendAndCollect] waiting on
+// the session returns an error matching [ErrAborted].
 //
 // Returns an error if the session has been destroyed or the connection fails.
 //
@@ -569,10 +1119,20 @@ func (s *Session) Destroy() error {
 //	    log.Printf("Failed to abort: %v", err)
 //	}
 func (s *Session) Abort(ctx context.Context) error {
-	_, err := s.client.Request("session.abort", sessionAbortRequest{SessionID: s.SessionID})
+	if err := s.checkNotDestroyed(); err != nil {
+		return err
+	}
+
+	aborting := s.state.abort()
+	_, err := s.rpcClient().Request("session.abort", sessionAbortRequest{SessionID: s.SessionID})
 	if err != nil {
+		if aborting {
+			s.state.abortFailed()
+		}
 		return fmt.Errorf("failed to abort session: %w", err)
 	}
 
+	s.aborts.notify()
+	s.trace.abort()
 	return nil
 }
@@ -1,8 +1,21 @@
 package copilot
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
 )
 
 func TestSession_On(t *testing.T) {
@@ -110,12 +123,587 @@ func TestSession_On(t *testing.T) {
 		wg.Wait()
 
 		// Should not panic and handlers should be empty
-		session.handlerMutex.RLock()
-		count := len(session.handlers)
-		session.handlerMutex.RUnlock()
-
-		if count != 0 {
+		if count := session.HandlerCount(); count != 0 {
 			t.Errorf("Expected 0 handlers after all unsubscribes, got %d", count)
 		}
 	})
+
+	t.Run("unsubscribe releases the removed handler", func(t *testing.T) {
+		session := &Session{}
+		unsub1 := session.On(func(event SessionEvent) {})
+		session.On(func(event SessionEvent) {})
+		unsub1()
+
+		// The slot vacated by the removal must not keep the closure reachable
+		tail := session.handlers[:cap(session.handlers)]
+		for i := session.HandlerCount(); i < len(tail); i++ {
+			if tail[i].fn != nil {
+				t.Errorf("Expected vacated slot %d to be cleared", i)
+			}
+		}
+	})
+}
+
+func TestSession_OnFiltered(t *testing.T) {
+	events := []SessionEventType{AssistantMessageDelta, ToolExecutionStart, AssistantMessageDelta, ToolExecutionComplete, SessionIdle}
+
+	t.Run("handlers only receive matching events", func(t *testing.T) {
+		session := &Session{}
+		var all, byType, byPrefix, none []SessionEventType
+		session.On(func(event SessionEvent) { all = append(all, event.Type) })
+		session.OnTypes([]SessionEventType{ToolExecutionComplete, SessionIdle}, func(event SessionEvent) {
+			byType = append(byType, event.Type)
+		})
+		session.OnPrefix("tool.", func(event SessionEvent) { byPrefix = append(byPrefix, event.Type) })
+		session.OnTypes(nil, func(event SessionEvent) { none = append(none, event.Type) })
+
+		for _, eventType := range events {
+			session.dispatchEvent(SessionEvent{Type: eventType})
+		}
+
+		if !slices.Equal(all, events) {
+			t.Errorf("Expected On to receive every event, got %v", all)
+		}
+		if want := []SessionEventType{ToolExecutionComplete, SessionIdle}; !slices.Equal(byType, want) {
+			t.Errorf("Expected %v, got %v", want, byType)
+		}
+		if want := []SessionEventType{ToolExecutionStart, ToolExecutionComplete}; !slices.Equal(byPrefix, want) {
+			t.Errorf("Expected %v, got %v", want, byPrefix)
+		}
+		if len(none) != 0 {
+			t.Errorf("Expected no events without types, got %v", none)
+		}
+	})
+
+	t.Run("filtered handlers unsubscribe and keep their order", func(t *testing.T) {
+		session := &Session{}
+		var order []string
+		session.On(func(SessionEvent) { order = append(order, "on") })
+		unsub := session.OnPrefix("tool.", func(SessionEvent) { order = append(order, "prefix") })
+		session.OnTypes([]SessionEventType{ToolExecutionStart}, func(SessionEvent) { order = append(order, "types") })
+
+		session.dispatchEvent(SessionEvent{Type: ToolExecutionStart})
+		unsub()
+		unsub()
+		session.dispatchEvent(SessionEvent{Type: ToolExecutionStart})
+
+		want := []string{"on", "prefix", "types", "on", "types"}
+		if !slices.Equal(order, want) {
+			t.Errorf("Expected %v, got %v", want, order)
+		}
+		if n := session.HandlerCount(); n != 2 {
+			t.Errorf("Expected 2 handlers, got %d", n)
+		}
+	})
+}
+
+// BenchmarkSession_DispatchDeltas dispatches a delta-heavy stream to ten
+// subscribers that only care about tool events, subscribed with On and
+// filtering in the handler, or with OnTypes or OnPrefix.
+func BenchmarkSession_DispatchDeltas(b *testing.B) {
+	stream := make([]SessionEvent, 100)
+	for i := range stream {
+		stream[i] = SessionEvent{Type: AssistantMessageDelta}
+	}
+	stream[50] = SessionEvent{Type: ToolExecutionStart}
+	stream[99] = SessionEvent{Type: ToolExecutionComplete}
+
+	var calls int
+	handler := func(event SessionEvent) { calls++ }
+	subscribers := map[string]func(*Session){
+		"On": func(s *Session) {
+			s.On(func(event SessionEvent) {
+				if strings.HasPrefix(string(event.Type), "tool.") {
+					handler(event)
+				}
+			})
+		},
+		"OnTypes": func(s *Session) {
+			s.OnTypes([]SessionEventType{ToolExecutionStart, ToolExecutionComplete}, handler)
+		},
+		"OnPrefix": func(s *Session) { s.OnPrefix("tool.", handler) },
+	}
+	for _, name := range []string{"On", "OnTypes", "OnPrefix"} {
+		b.Run(name, func(b *testing.B) {
+			session := &Session{}
+			for i := 0; i < 10; i++ {
+				subscribers[name](session)
+			}
+			b.ReportAllocs()
+			for b.Loop() {
+				for _, event := range stream {
+					session.dispatchEvent(event)
+				}
+			}
+		})
+	}
+}
+
+// fakeServer is an in-memory stand-in for the CLI side of the JSON-RPC connection.
+// Requests from the SDK are answered by handler; events can be pushed with emit.
+type fakeServer struct {
+	t       testing.TB
+	conn    net.Conn
+	writeMu sync.Mutex
+	handler func(method string, params json.RawMessage) (any, error)
+	// responses, if set, receives the IDs of the client's responses to
+	// requests sent with write
+	responses chan json.RawMessage
+}
+
+// newTestSession returns a session connected to a fake server. Events emitted by
+// the server are dispatched to the session as if they came from the CLI.
+func newTestSession(t *testing.T, handler func(method string, params json.RawMessage) (any, error)) (*Session, *fakeServer) {
+	t.Helper()
+
+	clientConn, serverConn := net.Pipe()
+	server := &fakeServer{t: t, conn: serverConn, handler: handler}
+
+	rpcClient := jsonrpc2.NewClient(clientConn, clientConn)
+	session := newSession("test-session", rpcClient, "")
+	rpcClient.SetRequestHandler("session.event", jsonrpc2.NotificationHandlerFor(func(req sessionEventRequest) {
+		session.dispatchEvent(req.Event)
+	}))
+	rpcClient.Start()
+	go server.serve()
+
+	t.Cleanup(func() {
+		serverConn.Close()
+		rpcClient.Stop()
+	})
+	return session, server
+}
+
+func (f *fakeServer) serve() {
+	reader := bufio.NewReader(f.conn)
+	for {
+		var contentLength int
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if line == "\r\n" {
+				break
+			}
+			fmt.Sscanf(line, "Content-Length: %d", &contentLength)
+		}
+		body := make([]byte, contentLength)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return
+		}
+
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(body, &msg); err != nil {
+			continue
+		}
+		if msg.Method == "" {
+			if f.responses != nil {
+				f.responses <- msg.ID
+			}
+			continue
+		}
+		go f.respond(msg.ID, msg.Method, msg.Params)
+	}
+}
+
+func (f *fakeServer) respond(id json.RawMessage, method string, params json.RawMessage) {
+	var result any
+	var err error
+	if f.handler != nil {
+		result, err = f.handler(method, params)
+	}
+	if len(id) == 0 {
+		return
+	}
+	if err != nil {
+		rpcErr, ok := err.(*jsonrpc2.Error)
+		if !ok {
+			rpcErr = &jsonrpc2.Error{Code: -32603, Message: err.Error()}
+		}
+		f.write(map[string]any{"jsonrpc": "2.0", "id": id, "error": rpcErr})
+		return
+	}
+	f.write(map[string]any{"jsonrpc": "2.0", "id": id, "result": result})
+}
+
+// emit sends a session.event notification for the test session.
+func (f *fakeServer) emit(event SessionEvent) {
+	f.write(map[string]any{
+		"jsonrpc": "2.0",
+		"method":  "session.event",
+		"params":  map[string]any{"sessionId": "test-session", "event": event},
+	})
+}
+
+func (f *fakeServer) write(msg any) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		f.t.Errorf("fake server failed to marshal message: %v", err)
+		return
+	}
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	fmt.Fprintf(f.conn, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+func TestSession_SendAndWaitHandlerTime(t *testing.T) {
+	// slowPermissionSession simulates a turn that requests a permission which
+	// takes handlerDelay to approve, then completes.
+	slowPermissionSession := func(t *testing.T, handlerDelay time.Duration) *Session {
+		var session *Session
+		var server *fakeServer
+		session, server = newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.send" {
+				go func() {
+					session.handlePermissionRequest(PermissionRequest{Kind: "shell"})
+					server.emit(SessionEvent{Type: SessionIdle})
+				}()
+				return sessionSendResponse{MessageID: "msg"}, nil
+			}
+			return nil, nil
+		})
+		session.registerPermissionHandler(func(PermissionRequest, PermissionInvocation) (PermissionRequestResult, error) {
+			time.Sleep(handlerDelay)
+			return Approved(), nil
+		})
+		return session
+	}
+
+	t.Run("does not count permission handler time against the timeout", func(t *testing.T) {
+		session := slowPermissionSession(t, 300*time.Millisecond)
+
+		_, err := session.SendAndWaitWithOptions(t.Context(), MessageOptions{Prompt: "run it"}, &SendAndWaitOptions{
+			Timeout: 100 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("Expected wait to succeed despite slow handler, got %v", err)
+		}
+	})
+
+	t.Run("counts handler time when IncludeHandlerTime is set", func(t *testing.T) {
+		session := slowPermissionSession(t, 300*time.Millisecond)
+
+		_, err := session.SendAndWaitWithOptions(t.Context(), MessageOptions{Prompt: "run it"}, &SendAndWaitOptions{
+			Timeout:            100 * time.Millisecond,
+			IncludeHandlerTime: true,
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected timeout, got %v", err)
+		}
+	})
+
+	t.Run("times out when the turn never completes", func(t *testing.T) {
+		session, _ := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			return sessionSendResponse{MessageID: "msg"}, nil
+		})
+
+		start := time.Now()
+		_, err := session.SendAndWaitWithOptions(t.Context(), MessageOptions{Prompt: "hi"}, &SendAndWaitOptions{
+			Timeout: 50 * time.Millisecond,
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected timeout, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected timeout near 50ms, took %v", elapsed)
+		}
+	})
+}
+
+func TestSession_SendAndWaitHandlerCleanup(t *testing.T) {
+	const iterations = 200
+
+	// assertNoLeaks runs wait repeatedly and checks that neither event
+	// handlers nor goroutines accumulate.
+	assertNoLeaks := func(t *testing.T, session *Session, wait func() error) {
+		t.Helper()
+		baseline := session.HandlerCount()
+		goroutines := runtime.NumGoroutine()
+		for i := 0; i < iterations; i++ {
+			if err := wait(); err == nil {
+				t.Fatalf("Expected iteration %d to fail", i)
+			}
+		}
+		if count := session.HandlerCount(); count != baseline {
+			t.Errorf("Expected %d handlers after %d waits, got %d", baseline, iterations, count)
+		}
+		// Allow for goroutines still winding down in the test transport
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > goroutines+5 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if n := runtime.NumGoroutine(); n > goroutines+5 {
+			t.Errorf("Expected about %d goroutines after %d waits, got %d", goroutines, iterations, n)
+		}
+	}
+
+	t.Run("timeouts", func(t *testing.T) {
+		session, _ := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			return sessionSendResponse{MessageID: "msg"}, nil
+		})
+		session.On(func(SessionEvent) {})
+
+		assertNoLeaks(t, session, func() error {
+			_, err := session.SendAndWaitWithOptions(t.Context(), MessageOptions{Prompt: "hi"}, &SendAndWaitOptions{
+				Timeout: time.Millisecond,
+			})
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Expected timeout, got %v", err)
+			}
+			return err
+		})
+	})
+
+	t.Run("canceled contexts", func(t *testing.T) {
+		session, _ := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			return sessionSendResponse{MessageID: "msg"}, nil
+		})
+
+		assertNoLeaks(t, session, func() error {
+			ctx, cancel := context.WithTimeout(t.Context(), time.Millisecond)
+			defer cancel()
+			_, err := session.SendAndWait(ctx, MessageOptions{Prompt: "hi"})
+			return err
+		})
+	})
+
+	t.Run("send failures", func(t *testing.T) {
+		session, _ := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			return nil, &jsonrpc2.Error{Code: -32000, Message: "rejected"}
+		})
+
+		assertNoLeaks(t, session, func() error {
+			_, err := session.SendAndWait(t.Context(), MessageOptions{Prompt: "hi"})
+			return err
+		})
+	})
+
+	t.Run("session errors", func(t *testing.T) {
+		var server *fakeServer
+		var session *Session
+		session, server = newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			go server.emit(SessionEvent{Type: SessionError, Data: Data{Message: String("boom")}})
+			return sessionSendResponse{MessageID: "msg"}, nil
+		})
+
+		assertNoLeaks(t, session, func() error {
+			_, err := session.SendAndWait(t.Context(), MessageOptions{Prompt: "hi"})
+			return err
+		})
+	})
+}
+
+func TestSession_WaitForIdle(t *testing.T) {
+	t.Run("returns at once when idle", func(t *testing.T) {
+		session, _ := newTestSession(t, func(string, json.RawMessage) (any, error) { return nil, nil })
+		if err := session.WaitForIdle(t.Context()); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("waits for every sent message", func(t *testing.T) {
+		var server *fakeServer
+		var session *Session
+		var sends sync.WaitGroup
+		sends.Add(2)
+		session, server = newTestSession(t, func(string, json.RawMessage) (any, error) {
+			go func() {
+				sends.Wait()
+				time.Sleep(20 * time.Millisecond)
+				server.emit(SessionEvent{Type: SessionIdle, Timestamp: time.Now()})
+			}()
+			sends.Done()
+			return sessionSendResponse{MessageID: "msg"}, nil
+		})
+		for _, prompt := range []string{"one", "two"} {
+			if _, err := session.Send(t.Context(), MessageOptions{Prompt: prompt}); err != nil {
+				t.Fatalf("Failed to send: %v", err)
+			}
+		}
+		start := time.Now()
+		if err := session.WaitForIdle(t.Context()); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if time.Since(start) < 10*time.Millisecond {
+			t.Error("Expected WaitForIdle to wait for session.idle")
+		}
+	})
+
+	t.Run("counts an idle that arrives before the send response", func(t *testing.T) {
+		var server *fakeServer
+		var session *Session
+		session, server = newTestSession(t, func(string, json.RawMessage) (any, error) {
+			server.emit(SessionEvent{Type: SessionIdle, Timestamp: time.Now()})
+			return sessionSendResponse{MessageID: "msg"}, nil
+		})
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "quick"}); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+		defer cancel()
+		if err := session.WaitForIdle(ctx); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("reports session errors", func(t *testing.T) {
+		session, server := newTestSession(t, func(string, json.RawMessage) (any, error) { return nil, nil })
+		server.emit(SessionEvent{Type: AssistantTurnStart, Timestamp: time.Now(), Data: Data{TurnID: String("0")}})
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			server.emit(SessionEvent{Type: SessionError, Timestamp: time.Now(), Data: Data{
+				Message:   String("Please sign in"),
+				ErrorType: String("authentication"),
+			}})
+		}()
+		for deadline := time.Now().Add(time.Second); session.state.idle() == nil && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+		if err := session.WaitForIdle(t.Context()); !errors.Is(err, ErrNotAuthenticated) {
+			t.Errorf("Expected ErrNotAuthenticated, got %v", err)
+		}
+	})
+
+	t.Run("gives up when the context is done or the session is destroyed", func(t *testing.T) {
+		session, _ := newTestSession(t, func(string, json.RawMessage) (any, error) {
+			return sessionSendResponse{MessageID: "msg"}, nil
+		})
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "hi"}); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+		defer cancel()
+		if err := session.WaitForIdle(ctx); !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected ErrTimeout, got %v", err)
+		}
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			session.markDestroyed("destroyed by caller")
+		}()
+		if err := session.WaitForIdle(t.Context()); !errors.Is(err, ErrSessionDestroyed) {
+			t.Errorf("Expected ErrSessionDestroyed, got %v", err)
+		}
+	})
+
+	t.Run("is idle again after a failed send", func(t *testing.T) {
+		session, _ := newTestSession(t, func(string, json.RawMessage) (any, error) {
+			return nil, &jsonrpc2.Error{Code: -32000, Message: "rejected"}
+		})
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "hi"}); err == nil {
+			t.Fatal("Expected the send to fail")
+		}
+		if err := session.WaitForIdle(t.Context()); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+}
+
+func TestSession_SendInitiator(t *testing.T) {
+	var sent sessionSendRequest
+	session, _ := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+		json.Unmarshal(params, &sent)
+		return sessionSendResponse{MessageID: "msg"}, nil
+	})
+
+	t.Run("passes the initiator through", func(t *testing.T) {
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "hi", Initiator: "code-review/bot"}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		if sent.Initiator != "code-review/bot" {
+			t.Errorf("Expected initiator 'code-review/bot', got %q", sent.Initiator)
+		}
+	})
+
+	t.Run("rejects invalid initiators", func(t *testing.T) {
+		_, err := session.Send(t.Context(), MessageOptions{Prompt: "hi", Initiator: "-leading-dash"})
+		if err == nil || !strings.Contains(err.Error(), "invalid Initiator") {
+			t.Errorf("Expected invalid Initiator error, got %v", err)
+		}
+	})
+}
+
+func TestSession_GetMessagesConsistentWith(t *testing.T) {
+	// The fake server acknowledges sends immediately but writes the user
+	// message to history, and emits it live, only after a delay
+	newDelayedSession := func(t *testing.T, writeDelay time.Duration) *Session {
+		var mu sync.Mutex
+		var history []SessionEvent
+		var server *fakeServer
+		session, server := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			switch method {
+			case "session.send":
+				event := SessionEvent{Type: UserMessage, ID: "evt-1", Data: Data{InteractionID: String("interaction-1")}}
+				time.AfterFunc(writeDelay, func() {
+					mu.Lock()
+					history = append(history, event)
+					mu.Unlock()
+					server.emit(event)
+				})
+				return sessionSendResponse{MessageID: "msg-1"}, nil
+			case "session.getMessages":
+				mu.Lock()
+				defer mu.Unlock()
+				return sessionGetMessagesResponse{Events: slices.Clone(history)}, nil
+			}
+			return nil, nil
+		})
+		return session
+	}
+
+	t.Run("waits until history includes the sent message", func(t *testing.T) {
+		session := newDelayedSession(t, 100*time.Millisecond)
+		messageID, err := session.Send(t.Context(), MessageOptions{Prompt: "Hi"})
+		if err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+
+		events, err := session.GetMessages(t.Context())
+		if err != nil || len(events) != 0 {
+			t.Fatalf("Expected a read right after Send to miss the message, got %v, %v", events, err)
+		}
+
+		events, err = session.GetMessagesWithOptions(t.Context(), &GetMessagesOptions{ConsistentWith: messageID})
+		if err != nil {
+			t.Fatalf("GetMessagesWithOptions failed: %v", err)
+		}
+		if len(events) != 1 || events[0].ID != "evt-1" {
+			t.Errorf("Expected the history to include evt-1, got %+v", events)
+		}
+	})
+
+	t.Run("returns a typed error when the wait times out", func(t *testing.T) {
+		session := newDelayedSession(t, time.Hour)
+		messageID, err := session.Send(t.Context(), MessageOptions{Prompt: "Hi"})
+		if err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+
+		_, err = session.GetMessagesWithOptions(t.Context(), &GetMessagesOptions{
+			ConsistentWith:     messageID,
+			ConsistencyTimeout: 80 * time.Millisecond,
+		})
+		var timeoutErr *ConsistencyTimeoutError
+		if !errors.As(err, &timeoutErr) || !errors.Is(err, ErrConsistencyTimeout) {
+			t.Fatalf("Expected a ConsistencyTimeoutError, got %v", err)
+		}
+		if timeoutErr.MessageID != "msg-1" || timeoutErr.Waited < 80*time.Millisecond {
+			t.Errorf("Unexpected error fields: %+v", timeoutErr)
+		}
+	})
+
+	t.Run("stops waiting when the context is canceled", func(t *testing.T) {
+		session := newDelayedSession(t, time.Hour)
+		messageID, _ := session.Send(t.Context(), MessageOptions{Prompt: "Hi"})
+		ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+		defer cancel()
+		_, err := session.GetMessagesWithOptions(ctx, &GetMessagesOptions{ConsistentWith: messageID})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the context error, got %v", err)
+		}
+	})
 }
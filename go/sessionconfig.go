@@ -0,0 +1,104 @@
+package copilot
+
+import (
+	"cmp"
+	"sync"
+)
+
+// ResolvedSessionConfig describes a session's effective settings. Values the
+// CLI reports when the session is created or resumed take precedence over the
+// requested ones, and the model follows session.start and
+// session.model_change events.
+type ResolvedSessionConfig struct {
+	// Model is the model the session uses, or empty if neither the request
+	// nor the CLI named one
+	Model string
+	// ReasoningEffort is the session's reasoning effort, if any
+	ReasoningEffort string
+	// ResponseLanguage is the language tag the assistant is asked to answer
+	// in, if any
+	ResponseLanguage string
+	// WorkingDirectory is the directory the session's tools operate in, if
+	// known
+	WorkingDirectory string
+	// WorkspacePath is the session workspace directory, as returned by
+	// [Session.WorkspacePath]
+	WorkspacePath string
+	// InfiniteSessions reports whether the session has a workspace
+	InfiniteSessions bool
+	// Streaming reports whether delta events were requested
+	Streaming bool
+	// Hooks reports how the session's hooks run: natively, emulated, or
+	// dropped because the CLI does not support hooks
+	Hooks HookMode
+}
+
+// sessionConfigEcho holds the effective settings the CLI may include in its
+// session.create and session.resume responses.
+type sessionConfigEcho struct {
+	Model            string `json:"model,omitempty"`
+	ReasoningEffort  string `json:"reasoningEffort,omitempty"`
+	WorkingDirectory string `json:"workingDirectory,omitempty"`
+}
+
+// sessionConfigState tracks a session's effective settings.
+type sessionConfigState struct {
+	mu     sync.Mutex
+	config ResolvedSessionConfig
+}
+
+// resolve records the settings of a create or resume request, preferring
+// values reported by the CLI.
+func (c *sessionConfigState) resolve(req resumeSessionRequest, echo sessionConfigEcho) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config.Model = cmp.Or(echo.Model, req.Model, c.config.Model)
+	c.config.ReasoningEffort = cmp.Or(echo.ReasoningEffort, req.ReasoningEffort, c.config.ReasoningEffort)
+	c.config.WorkingDirectory = cmp.Or(echo.WorkingDirectory, req.WorkingDirectory, c.config.WorkingDirectory)
+	c.config.Streaming = req.Streaming != nil && *req.Streaming
+	c.config.ResponseLanguage = req.ResponseLanguage
+}
+
+// setModel records a model switch made by the SDK.
+func (c *sessionConfigState) setModel(model string) {
+	c.mu.Lock()
+	c.config.Model = model
+	c.mu.Unlock()
+}
+
+// observeEvent follows model changes reported by the CLI.
+func (c *sessionConfigState) observeEvent(event SessionEvent) {
+	var model *string
+	switch event.Type {
+	case SessionStart:
+		model = event.Data.SelectedModel
+	case SessionModelChange:
+		model = event.Data.NewModel
+	}
+	if model != nil && *model != "" {
+		c.setModel(*model)
+	}
+}
+
+// Config returns the session's effective settings as reported by the CLI,
+// falling back to the values requested when the session was created or
+// resumed.
+//
+// Example:
+//
+//	cfg := session.Config()
+//	fmt.Printf("model %s, workspace %s\n", cfg.Model, cfg.WorkspacePath)
+func (s *Session) Config() ResolvedSessionConfig {
+	s.config.mu.Lock()
+	config := s.config.config
+	s.config.mu.Unlock()
+	config.WorkspacePath = s.WorkspacePath()
+	config.InfiniteSessions = config.WorkspacePath != ""
+	return config
+}
+
+// infiniteSessionsEnabled reports whether config leaves infinite sessions on,
+// which is the CLI's default.
+func infiniteSessionsEnabled(config *InfiniteSessionConfig) bool {
+	return config == nil || config.Enabled == nil || *config.Enabled
+}
@@ -0,0 +1,151 @@
+package copilot
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+func TestSession_Config(t *testing.T) {
+	var mu sync.Mutex
+	var resumed []resumeSessionRequest
+	echo := sessionConfigEcho{}
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch method {
+		case "session.create":
+			var req createSessionRequest
+			json.Unmarshal(params, &req)
+			return createSessionResponse{SessionID: req.SessionID, WorkspacePath: "/ws/" + req.SessionID, sessionConfigEcho: echo}, nil
+		case "session.resume":
+			var req resumeSessionRequest
+			json.Unmarshal(params, &req)
+			resumed = append(resumed, req)
+			return resumeSessionResponse{SessionID: req.SessionID, sessionConfigEcho: echo}, nil
+		}
+		return nil, nil
+	})
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	setEcho := func(e sessionConfigEcho) {
+		mu.Lock()
+		echo = e
+		mu.Unlock()
+	}
+	lastResume := func() resumeSessionRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return resumed[len(resumed)-1]
+	}
+
+	t.Run("falls back to the requested settings", func(t *testing.T) {
+		setEcho(sessionConfigEcho{})
+		session, err := client.CreateSession(t.Context(), &SessionConfig{
+			SessionID:           "requested",
+			Model:               "gpt-5",
+			ReasoningEffort:     "high",
+			WorkingDirectory:    "/repo",
+			Streaming:           true,
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		want := ResolvedSessionConfig{
+			Model:            "gpt-5",
+			ReasoningEffort:  "high",
+			WorkingDirectory: "/repo",
+			WorkspacePath:    "/ws/requested",
+			InfiniteSessions: true,
+			Streaming:        true,
+		}
+		if got := session.Config(); got != want {
+			t.Errorf("Expected %+v, got %+v", want, got)
+		}
+	})
+
+	t.Run("prefers the settings reported by the CLI", func(t *testing.T) {
+		setEcho(sessionConfigEcho{Model: "claude-sonnet-4.5", WorkingDirectory: "/resolved"})
+		session, err := client.CreateSession(t.Context(), &SessionConfig{
+			SessionID:           "echoed",
+			WorkingDirectory:    "relative",
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		got := session.Config()
+		if got.Model != "claude-sonnet-4.5" || got.WorkingDirectory != "/resolved" {
+			t.Errorf("Expected the CLI's model and directory, got %+v", got)
+		}
+	})
+
+	t.Run("follows model changes", func(t *testing.T) {
+		setEcho(sessionConfigEcho{})
+		session, err := client.CreateSession(t.Context(), &SessionConfig{
+			SessionID:           "changes",
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		session.dispatchEvent(SessionEvent{Type: SessionStart, Data: Data{SelectedModel: String("default-model")}})
+		if got := session.Config().Model; got != "default-model" {
+			t.Errorf("Expected the model from session.start, got %q", got)
+		}
+		session.dispatchEvent(SessionEvent{Type: SessionModelChange, Data: Data{NewModel: String("other-model")}})
+		if got := session.Config().Model; got != "other-model" {
+			t.Errorf("Expected the model from session.model_change, got %q", got)
+		}
+	})
+
+	t.Run("resuming a tracked session keeps its workspace", func(t *testing.T) {
+		setEcho(sessionConfigEcho{})
+		created, err := client.CreateSession(t.Context(), &SessionConfig{
+			SessionID:           "tracked",
+			InfiniteSessions:    &InfiniteSessionConfig{BackgroundCompactionThreshold: Float64(0.7)},
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		resumed, err := client.ResumeSession(t.Context(), "tracked", &ResumeSessionConfig{OnPermissionRequest: PermissionHandler.ApproveAll})
+		if err != nil {
+			t.Fatalf("Failed to resume session: %v", err)
+		}
+		if got := resumed.WorkspacePath(); got != created.WorkspacePath() {
+			t.Errorf("Expected workspace %q, got %q", created.WorkspacePath(), got)
+		}
+		if !resumed.Config().InfiniteSessions {
+			t.Error("Expected Config to report infinite sessions")
+		}
+		if req := lastResume(); req.InfiniteSessions == nil || *req.InfiniteSessions.BackgroundCompactionThreshold != 0.7 {
+			t.Errorf("Expected the resume to repeat the infinite session config, got %+v", req.InfiniteSessions)
+		}
+	})
+
+	t.Run("disabling infinite sessions on resume drops the workspace", func(t *testing.T) {
+		setEcho(sessionConfigEcho{})
+		if _, err := client.CreateSession(t.Context(), &SessionConfig{
+			SessionID:           "disabled",
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+		}); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		resumed, err := client.ResumeSession(t.Context(), "disabled", &ResumeSessionConfig{
+			InfiniteSessions:    &InfiniteSessionConfig{Enabled: Bool(false)},
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+		})
+		if err != nil {
+			t.Fatalf("Failed to resume session: %v", err)
+		}
+		if got := resumed.WorkspacePath(); got != "" {
+			t.Errorf("Expected no workspace, got %q", got)
+		}
+	})
+}
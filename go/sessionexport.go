@@ -0,0 +1,193 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// SessionExportVersion is the version of the format written by
+// [Session.Export]. [Client.ImportSession] reads exports of this version and
+// earlier ones.
+const SessionExportVersion = 1
+
+// ErrUnsupportedExportVersion matches errors from [Client.ImportSession] for
+// exports written in a newer format than this SDK reads.
+var ErrUnsupportedExportVersion = errors.New("unsupported session export version")
+
+// importPreamble introduces an imported conversation in the system message
+// of a session whose CLI cannot import history.
+const importPreamble = "The conversation so far, imported from an earlier session, is below. " +
+	"Continue it as if it had taken place in this session.\n\n"
+
+// SessionExport is the document written by [Session.Export]: a session's
+// event history and metadata, in a JSON format that is versioned so later
+// SDKs can read older exports.
+type SessionExport struct {
+	// Version is the format version, [SessionExportVersion] for exports
+	// written by this SDK
+	Version int `json:"version"`
+	// SessionID is the ID of the exported session. Imported sessions get
+	// a new ID.
+	SessionID string `json:"sessionId"`
+	// ExportedAt is when the export was written
+	ExportedAt time.Time `json:"exportedAt"`
+	// Model is the model the session used, if known
+	Model string `json:"model,omitempty"`
+	// WorkingDirectory is the directory the session's tools operated in, if
+	// known
+	WorkingDirectory string `json:"workingDirectory,omitempty"`
+	// Title and Summary are the latest title and summary of the
+	// conversation, as returned by [Session.Summary]
+	Title   string `json:"title,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	// Events is the session history, as returned by [Session.GetMessages]
+	Events []SessionEvent `json:"events"`
+}
+
+// sessionImportRequest is the request for session.import
+type sessionImportRequest struct {
+	SessionID string         `json:"sessionId"`
+	Events    []SessionEvent `json:"events"`
+}
+
+// Export returns the session's full event history and metadata as a
+// versioned JSON document, a [SessionExport], to store outside the CLI and
+// later pass to [Client.ImportSession], on this machine or another.
+//
+// Example:
+//
+//	data, err := session.Export(ctx)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	db.Exec("INSERT INTO conversations (id, export) VALUES (?, ?)", session.SessionID, data)
+func (s *Session) Export(ctx context.Context) ([]byte, error) {
+	events, err := s.GetMessages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export session: %w", err)
+	}
+	if events == nil {
+		events = []SessionEvent{}
+	}
+	config := s.Config()
+	summary := s.Summary()
+	return json.Marshal(SessionExport{
+		Version:          SessionExportVersion,
+		SessionID:        s.SessionID,
+		ExportedAt:       time.Now().UTC(),
+		Model:            config.Model,
+		WorkingDirectory: config.WorkingDirectory,
+		Title:            summary.Title,
+		Summary:          summary.Summary,
+		Events:           events,
+	})
+}
+
+// ImportSession creates a new session from data written by
+// [Session.Export], seeded with the exported conversation. The config is
+// used as with [Client.CreateSession]; its Model and WorkingDirectory
+// default to those of the export.
+//
+// CLIs that import history receive the exported events, so the new session
+// continues with the full context and [Session.GetMessages] returns the
+// imported history followed by new events. Other CLIs get the conversation
+// as a transcript appended to the system message, and GetMessages returns
+// the imported events ahead of the CLI's own history; a later export of the
+// session includes them, but a resumed session does not.
+//
+// Exports in a newer format than this SDK reads fail with an error matching
+// [ErrUnsupportedExportVersion].
+//
+// Example:
+//
+//	session, err := client.ImportSession(ctx, data, &copilot.SessionConfig{
+//	    OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	session.Send(ctx, copilot.MessageOptions{Prompt: "Where were we?"})
+func (c *Client) ImportSession(ctx context.Context, data []byte, config *SessionConfig) (*Session, error) {
+	var export SessionExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("failed to parse session export: %w", err)
+	}
+	switch {
+	case export.Version <= 0:
+		return nil, errors.New("failed to import session: data is not a session export, it has no version")
+	case export.Version > SessionExportVersion:
+		return nil, fmt.Errorf("%w: export version %d is newer than version %d read by this SDK", ErrUnsupportedExportVersion, export.Version, SessionExportVersion)
+	}
+	if config == nil {
+		config = &SessionConfig{}
+	}
+
+	if err := c.ensureConnected(); err != nil {
+		return nil, err
+	}
+	seeded := *config
+	if seeded.Model == "" {
+		seeded.Model = export.Model
+	}
+	if seeded.WorkingDirectory == "" {
+		seeded.WorkingDirectory = export.WorkingDirectory
+	}
+	native := c.supportsImport()
+	if !native {
+		seeded.SystemMessage = withImportedTranscript(seeded.SystemMessage, export.Events)
+	}
+
+	session, err := c.CreateSession(ctx, &seeded)
+	if err != nil {
+		return nil, err
+	}
+	session.summary.record(export.Title, export.Summary)
+	if !native {
+		session.imported = export.Events
+		return session, nil
+	}
+	if _, err := c.client.RequestContext(ctx, "session.import", sessionImportRequest{SessionID: session.SessionID, Events: export.Events}); err != nil {
+		session.Destroy()
+		c.DeleteSession(context.Background(), session.SessionID)
+		return nil, fmt.Errorf("failed to import session history: %w", classifyError(err))
+	}
+	return session, nil
+}
+
+// supportsImport reports whether the connected CLI imports session history.
+// Like [ServerCapabilities.MessageModel], nil means it does not.
+func (c *Client) supportsImport() bool {
+	capabilities := c.capabilities.Load()
+	return capabilities != nil && capabilities.ImportHistory != nil && *capabilities.ImportHistory
+}
+
+// withImportedTranscript appends the transcript of imported events to a
+// system message.
+func withImportedTranscript(systemMessage *SystemMessageConfig, events []SessionEvent) *SystemMessageConfig {
+	transcript := renderMarkdown(events)
+	if transcript == "" {
+		return systemMessage
+	}
+	result := SystemMessageConfig{Mode: "append"}
+	if systemMessage != nil {
+		result = *systemMessage
+	}
+	if result.Content != "" {
+		result.Content += "\n\n"
+	}
+	result.Content += importPreamble + transcript
+	return &result
+}
+
+// withImported puts the history imported without CLI support ahead of the
+// events the CLI returns.
+func (s *Session) withImported(events []SessionEvent) []SessionEvent {
+	if len(s.imported) == 0 {
+		return events
+	}
+	return append(slices.Clone(s.imported), events...)
+}
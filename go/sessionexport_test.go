@@ -0,0 +1,120 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClient_ImportSession(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	history := []SessionEvent{
+		{ID: "e1", Type: UserMessage, Timestamp: at, Data: Data{Content: String("Why is the build failing?")}},
+		{ID: "e2", ParentID: String("e1"), Type: ToolExecutionStart, Timestamp: at.Add(time.Second), Data: Data{ToolCallID: String("t1"), ToolName: String("bash")}},
+		{ID: "e3", ParentID: String("e2"), Type: ToolExecutionComplete, Timestamp: at.Add(2 * time.Second), Data: Data{ToolCallID: String("t1"), Success: Bool(true)}},
+		{ID: "e4", ParentID: String("e3"), Type: AssistantMessage, Timestamp: at.Add(3 * time.Second), Data: Data{Content: String("A test imports a deleted package.")}},
+	}
+
+	for _, native := range []bool{false, true} {
+		name := "replayed in the system message"
+		if native {
+			name = "imported by the CLI"
+		}
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			stored := map[string][]SessionEvent{"s-original": history}
+			var systemMessage *SystemMessageConfig
+			var model string
+			cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				switch method {
+				case "session.create":
+					var req createSessionRequest
+					json.Unmarshal(params, &req)
+					if req.SessionID == "" {
+						req.SessionID = "s-imported"
+						systemMessage, model = req.SystemMessage, req.Model
+					}
+					return createSessionResponse{SessionID: req.SessionID, sessionConfigEcho: sessionConfigEcho{Model: "gpt-5"}}, nil
+				case "session.import":
+					var req sessionImportRequest
+					json.Unmarshal(params, &req)
+					stored[req.SessionID] = req.Events
+				case "session.getMessages":
+					var req sessionGetMessagesRequest
+					json.Unmarshal(params, &req)
+					return sessionGetMessagesResponse{Events: stored[req.SessionID]}, nil
+				}
+				return nil, nil
+			})
+			if native {
+				cli.capabilities = &ServerCapabilities{ImportHistory: Bool(true)}
+			}
+			client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+			t.Cleanup(func() { client.ForceStop() })
+
+			original, err := client.CreateSession(t.Context(), &SessionConfig{SessionID: "s-original", OnPermissionRequest: PermissionHandler.ApproveAll})
+			if err != nil {
+				t.Fatalf("Failed to create session: %v", err)
+			}
+			original.summary.record("Build failure", "")
+			data, err := original.Export(t.Context())
+			if err != nil {
+				t.Fatalf("Export failed: %v", err)
+			}
+			var export SessionExport
+			if err := json.Unmarshal(data, &export); err != nil {
+				t.Fatalf("Export is not JSON: %v", err)
+			}
+			if export.Version != SessionExportVersion || export.SessionID != "s-original" || export.Model != "gpt-5" || export.Title != "Build failure" {
+				t.Errorf("Expected version, session ID, model and title in the export, got %+v", export)
+			}
+
+			imported, err := client.ImportSession(t.Context(), data, &SessionConfig{OnPermissionRequest: PermissionHandler.ApproveAll})
+			if err != nil {
+				t.Fatalf("ImportSession failed: %v", err)
+			}
+			if imported.SessionID != "s-imported" {
+				t.Errorf("Expected a new session, got %s", imported.SessionID)
+			}
+			if got := imported.Summary().Title; got != "Build failure" {
+				t.Errorf("Expected the exported title, got %q", got)
+			}
+			events, err := imported.GetMessages(t.Context())
+			if err != nil {
+				t.Fatalf("GetMessages failed: %v", err)
+			}
+			want, _ := json.Marshal(history)
+			if got, _ := json.Marshal(events); string(got) != string(want) {
+				t.Errorf("Expected the exported history back\nwant %s\ngot  %s", want, got)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if model != "gpt-5" {
+				t.Errorf("Expected the exported model, got %q", model)
+			}
+			replayed := systemMessage != nil && strings.Contains(systemMessage.Content, "A test imports a deleted package.")
+			if replayed == native {
+				t.Errorf("Expected the transcript in the system message only without CLI support, got %+v", systemMessage)
+			}
+		})
+	}
+}
+
+func TestClient_ImportSessionVersion(t *testing.T) {
+	client := NewClient(&ClientOptions{CLIUrl: "127.0.0.1:1"})
+
+	_, err := client.ImportSession(t.Context(), []byte(`{"version": 2, "events": []}`), nil)
+	if !errors.Is(err, ErrUnsupportedExportVersion) {
+		t.Errorf("Expected ErrUnsupportedExportVersion for a newer export, got %v", err)
+	}
+	_, err = client.ImportSession(t.Context(), []byte(`{"events": []}`), nil)
+	if err == nil || !strings.Contains(err.Error(), "no version") {
+		t.Errorf("Expected an error for data without a version, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package copilot
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// UnmarshalJSON decodes session metadata, parsing its start and modification
+// times into StartedAt and ModifiedAt.
+func (m *SessionMetadata) UnmarshalJSON(data []byte) error {
+	type plain SessionMetadata
+	if err := json.Unmarshal(data, (*plain)(m)); err != nil {
+		return err
+	}
+	m.StartedAt, _ = time.Parse(time.RFC3339Nano, m.StartTime)
+	m.ModifiedAt, _ = time.Parse(time.RFC3339Nano, m.ModifiedTime)
+	return nil
+}
+
+// paginateSessions applies the Limit and ModifiedBefore of filter to
+// sessions, sorted by most recent modification first. Sessions are returned
+// as listed if neither is set.
+func paginateSessions(sessions []SessionMetadata, filter *SessionListFilter) []SessionMetadata {
+	if filter == nil || (filter.Limit <= 0 && filter.ModifiedBefore.IsZero()) {
+		return sessions
+	}
+	slices.SortStableFunc(sessions, func(a, b SessionMetadata) int {
+		return cmp.Or(b.ModifiedAt.Compare(a.ModifiedAt), cmp.Compare(a.SessionID, b.SessionID))
+	})
+	if !filter.ModifiedBefore.IsZero() {
+		i := 0
+		for i < len(sessions) && !sessions[i].ModifiedAt.Before(filter.ModifiedBefore) {
+			i++
+		}
+		sessions = sessions[i:]
+	}
+	if filter.Limit > 0 && len(sessions) > filter.Limit {
+		sessions = sessions[:filter.Limit]
+	}
+	return sessions
+}
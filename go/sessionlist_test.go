@@ -0,0 +1,98 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClient_ListSessionsPaging(t *testing.T) {
+	var filters []string
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "session.create":
+			return createSessionResponse{SessionID: "s2"}, nil
+		case "session.list":
+			filters = append(filters, string(params))
+			return json.RawMessage(`{"sessions":[
+				{"sessionId":"s1","startTime":"2026-01-01T10:00:00Z","modifiedTime":"2026-01-01T11:00:00Z"},
+				{"sessionId":"s2","startTime":"2026-01-02T10:00:00Z","modifiedTime":"2026-01-03T09:30:00.5Z","title":"CLI title"},
+				{"sessionId":"s3","startTime":"2026-01-02T08:00:00Z","modifiedTime":"2026-01-02T12:00:00Z"},
+				{"sessionId":"s4","startTime":"","modifiedTime":"not a time"}
+			]}`), nil
+		}
+		return nil, nil
+	})
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	ids := func(sessions []SessionMetadata) string {
+		var ids []string
+		for _, s := range sessions {
+			ids = append(ids, s.SessionID)
+		}
+		return fmt.Sprint(ids)
+	}
+
+	t.Run("parses times", func(t *testing.T) {
+		sessions, err := client.ListSessions(t.Context(), nil)
+		if err != nil {
+			t.Fatalf("ListSessions failed: %v", err)
+		}
+		if got := ids(sessions); got != "[s1 s2 s3 s4]" {
+			t.Errorf("Expected the sessions as listed without paging, got %s", got)
+		}
+		if want := time.Date(2026, 1, 3, 9, 30, 0, 5e8, time.UTC); !sessions[1].ModifiedAt.Equal(want) {
+			t.Errorf("Expected ModifiedAt %v, got %v", want, sessions[1].ModifiedAt)
+		}
+		if want := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC); !sessions[1].StartedAt.Equal(want) {
+			t.Errorf("Expected StartedAt %v, got %v", want, sessions[1].StartedAt)
+		}
+		if !sessions[3].StartedAt.IsZero() || !sessions[3].ModifiedAt.IsZero() {
+			t.Errorf("Expected zero times for invalid values, got %+v", sessions[3])
+		}
+		if sessions[1].Title == nil || *sessions[1].Title != "CLI title" {
+			t.Errorf("Expected the CLI's title, got %v", sessions[1].Title)
+		}
+	})
+
+	t.Run("pages by modification time", func(t *testing.T) {
+		filter := &SessionListFilter{Cwd: "/repo", Limit: 2}
+		page, err := client.ListSessions(t.Context(), filter)
+		if err != nil {
+			t.Fatalf("ListSessions failed: %v", err)
+		}
+		if got := ids(page); got != "[s2 s3]" {
+			t.Errorf("Expected the two most recent sessions, got %s", got)
+		}
+		filter.ModifiedBefore = page[len(page)-1].ModifiedAt
+		page, err = client.ListSessions(t.Context(), filter)
+		if err != nil {
+			t.Fatalf("ListSessions failed: %v", err)
+		}
+		if got := ids(page); got != "[s1 s4]" {
+			t.Errorf("Expected the next page, got %s", got)
+		}
+		if got := filters[len(filters)-1]; got != `{"filter":{"cwd":"/repo"}}` {
+			t.Errorf("Expected only the CLI's filter fields to be sent, got %s", got)
+		}
+	})
+
+	t.Run("reports the title of open sessions", func(t *testing.T) {
+		session, err := client.CreateSession(t.Context(), &SessionConfig{OnPermissionRequest: PermissionHandler.ApproveAll})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		session.dispatchEvent(SessionEvent{Type: SessionTitleChanged, Data: Data{Title: String("Parser fix")}})
+		sessions, err := client.ListSessions(t.Context(), nil)
+		if err != nil {
+			t.Fatalf("ListSessions failed: %v", err)
+		}
+		if sessions[1].Title == nil || *sessions[1].Title != "Parser fix" {
+			t.Errorf("Expected the open session's latest title, got %v", sessions[1].Title)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package copilot
+
+import "sync"
+
+// sessionShardCount is the number of shards in a sessionRegistry.
+const sessionShardCount = 32
+
+// sessionRegistry maps session IDs to the sessions a client tracks. It is
+// sharded by session ID, so routing events and requests to different
+// sessions does not contend for one lock. The zero value is empty and ready
+// to use.
+type sessionRegistry struct {
+	shards [sessionShardCount]sessionShard
+}
+
+type sessionShard struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+// shard returns the shard holding sessionID, chosen by its FNV-1a hash.
+func (r *sessionRegistry) shard(sessionID string) *sessionShard {
+	hash := uint32(2166136261)
+	for i := 0; i < len(sessionID); i++ {
+		hash ^= uint32(sessionID[i])
+		hash *= 16777619
+	}
+	return &r.shards[hash%sessionShardCount]
+}
+
+// get returns the tracked session with the given ID, or nil.
+func (r *sessionRegistry) get(sessionID string) *Session {
+	shard := r.shard(sessionID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return shard.sessions[sessionID]
+}
+
+// put tracks a session, replacing any tracked session with the same ID.
+func (r *sessionRegistry) put(session *Session) {
+	shard := r.shard(session.SessionID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if shard.sessions == nil {
+		shard.sessions = make(map[string]*Session)
+	}
+	shard.sessions[session.SessionID] = session
+}
+
+// remove stops tracking the session with the given ID and returns it, or
+// nil if it was not tracked.
+func (r *sessionRegistry) remove(sessionID string) *Session {
+	shard := r.shard(sessionID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	session := shard.sessions[sessionID]
+	delete(shard.sessions, sessionID)
+	return session
+}
+
+// removeIf stops tracking session if it is the tracked session with its ID.
+func (r *sessionRegistry) removeIf(session *Session) {
+	shard := r.shard(session.SessionID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if shard.sessions[session.SessionID] == session {
+		delete(shard.sessions, session.SessionID)
+	}
+}
+
+// all returns the tracked sessions, in no particular order.
+func (r *sessionRegistry) all() []*Session {
+	var sessions []*Session
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.mu.RLock()
+		for _, session := range shard.sessions {
+			sessions = append(sessions, session)
+		}
+		shard.mu.RUnlock()
+	}
+	return sessions
+}
+
+// clear stops tracking all sessions and returns them, in no particular
+// order.
+func (r *sessionRegistry) clear() []*Session {
+	var sessions []*Session
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.mu.Lock()
+		for _, session := range shard.sessions {
+			sessions = append(sessions, session)
+		}
+		shard.sessions = nil
+		shard.mu.Unlock()
+	}
+	return sessions
+}
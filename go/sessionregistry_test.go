@@ -0,0 +1,179 @@
+package copilot
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSessionRegistry(t *testing.T) {
+	var registry sessionRegistry
+	sessions := make([]*Session, 500)
+	for i := range sessions {
+		sessions[i] = &Session{SessionID: fmt.Sprintf("session-%d", i)}
+		registry.put(sessions[i])
+	}
+	for _, session := range sessions {
+		if got := registry.get(session.SessionID); got != session {
+			t.Fatalf("Expected %s to be tracked, got %v", session.SessionID, got)
+		}
+	}
+	if got := len(registry.all()); got != len(sessions) {
+		t.Errorf("Expected all %d sessions, got %d", len(sessions), got)
+	}
+
+	registry.removeIf(&Session{SessionID: "session-1"})
+	if registry.get("session-1") != sessions[1] {
+		t.Error("Expected removeIf to keep a session replaced by another")
+	}
+	registry.removeIf(sessions[1])
+	if registry.remove("session-2") != sessions[2] || registry.remove("session-2") != nil {
+		t.Error("Expected remove to return the session once")
+	}
+	if registry.get("session-1") != nil || registry.get("session-2") != nil {
+		t.Error("Expected removed sessions to be untracked")
+	}
+
+	if got := len(registry.clear()); got != len(sessions)-2 {
+		t.Errorf("Expected clear to return %d sessions, got %d", len(sessions)-2, got)
+	}
+	if registry.get("session-3") != nil || len(registry.all()) != 0 {
+		t.Error("Expected no sessions after clear")
+	}
+	registry.put(sessions[3])
+	if registry.get("session-3") != sessions[3] {
+		t.Error("Expected put to work after clear")
+	}
+}
+
+func TestClient_SessionEventRouting(t *testing.T) {
+	// recorder returns a session whose handler records the IDs of the
+	// events it receives.
+	recorder := func(sessionID string) (*Session, func() []string) {
+		var mu sync.Mutex
+		var ids []string
+		session := &Session{SessionID: sessionID}
+		session.On(func(event SessionEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			ids = append(ids, event.ID)
+		})
+		return session, func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return slices.Clone(ids)
+		}
+	}
+	sequence := func(n int) []string {
+		ids := make([]string, n)
+		for i := range ids {
+			ids[i] = strconv.Itoa(i)
+		}
+		return ids
+	}
+	send := func(client *Client, sessionID string, ids []string) {
+		for _, id := range ids {
+			client.handleSessionEvent(sessionEventRequest{SessionID: sessionID, Event: SessionEvent{ID: id, Type: AssistantMessageDelta}})
+		}
+	}
+
+	t.Run("routes concurrent events to their own sessions in order", func(t *testing.T) {
+		client := NewClient(&ClientOptions{CLIUrl: "127.0.0.1:1"})
+		seen := make([]func() []string, 100)
+		for i := range seen {
+			var session *Session
+			session, seen[i] = recorder(fmt.Sprintf("session-%d", i))
+			client.registerSession(session)
+		}
+		var wg sync.WaitGroup
+		for i := range seen {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				send(client, fmt.Sprintf("session-%d", i), sequence(50))
+			}()
+		}
+		wg.Wait()
+		for i, got := range seen {
+			if !slices.Equal(got(), sequence(50)) {
+				t.Fatalf("Expected session-%d to get its 50 events in order, got %v", i, got())
+			}
+		}
+	})
+
+	t.Run("holds events for unknown sessions until they are registered", func(t *testing.T) {
+		client := NewClient(&ClientOptions{CLIUrl: "127.0.0.1:1"})
+		other, otherSeen := recorder("other")
+		client.registerSession(other)
+
+		send(client, "late", []string{"0", "1"})
+		session, seen := recorder("late")
+		client.registerSession(session)
+		send(client, "late", []string{"2"})
+		if got := seen(); !slices.Equal(got, sequence(3)) {
+			t.Errorf("Expected the held events before the new one, got %v", got)
+		}
+		if got := otherSeen(); len(got) != 0 {
+			t.Errorf("Expected no events for another session, got %v", got)
+		}
+
+		client.sessions.remove("late")
+		send(client, "late", []string{"3"})
+		if got := seen(); len(got) != 3 {
+			t.Errorf("Expected no events after the session was removed, got %v", got)
+		}
+		client.earlyEvents.mu.Lock()
+		held := len(client.earlyEvents.events["late"])
+		client.earlyEvents.mu.Unlock()
+		if held != 1 {
+			t.Errorf("Expected the event for the removed session to be held, got %d held", held)
+		}
+	})
+
+	t.Run("keeps order for events racing registration", func(t *testing.T) {
+		client := NewClient(&ClientOptions{CLIUrl: "127.0.0.1:1"})
+		session, seen := recorder("racy")
+		started := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			send(client, "racy", sequence(10))
+			close(started)
+			send(client, "racy", sequence(maxEarlyEvents)[10:])
+		}()
+		<-started
+		client.registerSession(session)
+		<-done
+		if got := seen(); !slices.Equal(got, sequence(maxEarlyEvents)) {
+			t.Errorf("Expected all %d events in order, got %v", maxEarlyEvents, got)
+		}
+	})
+}
+
+// BenchmarkClient_RouteSessionEvents routes interleaved delta events for
+// 500 sessions from concurrent senders.
+func BenchmarkClient_RouteSessionEvents(b *testing.B) {
+	client := NewClient(&ClientOptions{CLIUrl: "127.0.0.1:1"})
+	ids := make([]string, 500)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("session-%d", i)
+		session := &Session{SessionID: ids[i]}
+		session.On(func(event SessionEvent) {})
+		client.registerSession(session)
+	}
+	var next sync.Mutex
+	var offset int
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		next.Lock()
+		i := offset
+		offset += 7
+		next.Unlock()
+		for pb.Next() {
+			client.handleSessionEvent(sessionEventRequest{SessionID: ids[i%len(ids)], Event: SessionEvent{Type: AssistantMessageDelta}})
+			i++
+		}
+	})
+}
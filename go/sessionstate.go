@@ -0,0 +1,464 @@
+package copilot
+
+import (
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SessionState is what a session is doing, as reported by [Session.State].
+//
+// The state is derived from the session's event stream and from the
+// permission, user input and tool handlers the SDK runs for it:
+//
+//	trigger                                   effect
+//	Send, assistant.turn_start                turn in progress
+//	tool.execution_start, tool handler runs   tool call pending, turn in progress
+//	tool.execution_complete, handler returns  tool call done
+//	permission handler runs / returns         permission pending / done
+//	user input handler runs / returns         user input pending / done
+//	session.compaction_start / _complete      compacting / done
+//	Abort, abort                              aborting, if a turn is in progress
+//	session.idle, session.error, failed Send  everything done
+//
+// With several pending at once, the state is the first that applies of
+// [SessionStateAborting], [SessionStateAwaitingUserInput],
+// [SessionStateAwaitingPermission], [SessionStateAwaitingTool],
+// [SessionStateCompacting] and [SessionStateSending], or [SessionStateIdle]
+// if nothing is pending.
+//
+// Missing and out-of-order events are tolerated: tool calls, permission and
+// user input requests imply a turn without assistant.turn_start, a tool
+// call that completed before its start event is not counted as pending, and
+// session.idle or session.error clears whatever is still pending.
+type SessionState string
+
+const (
+	// SessionStateIdle means no turn is in progress
+	SessionStateIdle SessionState = "idle"
+	// SessionStateSending means a message was sent and the model is working
+	// on it
+	SessionStateSending SessionState = "sending"
+	// SessionStateAwaitingTool means a tool call is running
+	SessionStateAwaitingTool SessionState = "awaiting_tool"
+	// SessionStateAwaitingPermission means a permission handler is deciding
+	// on a request
+	SessionStateAwaitingPermission SessionState = "awaiting_permission"
+	// SessionStateAwaitingUserInput means a user input handler is answering
+	// a question
+	SessionStateAwaitingUserInput SessionState = "awaiting_user_input"
+	// SessionStateCompacting means the CLI is compacting the history
+	SessionStateCompacting SessionState = "compacting"
+	// SessionStateAborting means the turn was aborted and has not ended yet
+	SessionStateAborting SessionState = "aborting"
+)
+
+// maxFinishedToolCalls bounds the completed tool call IDs remembered in a
+// turn to ignore their late start events.
+const maxFinishedToolCalls = 1024
+
+// sessionState is the state machine behind [Session.State]. It also backs
+// [Session.WaitForIdle], the pauses of [SendAndWaitOptions.IncludeHandlerTime],
+// diagnostic bundles, pacing and [ClientPool] placement.
+type sessionState struct {
+	mu            sync.Mutex
+	current       SessionState
+	busy          bool // a turn is in progress
+	aborting      bool
+	compacting    bool
+	tools         map[string]struct{} // tool calls pending
+	finishedTools map[string]struct{} // tool calls completed this turn
+	permissions   int
+	userInputs    int
+	generation    int // incremented when a turn ends, so handlers of an earlier turn do not count down
+	lastEventType SessionEventType
+	lastEventAt   time.Time
+	waiter        *idleWaiter   // released when the session is next idle; created lazily
+	handlersCh    chan struct{} // closed when a handler starts or returns; created lazily
+
+	listeners    []stateListener
+	nextListener int
+	changes      []stateChange // waiting to be passed to listeners
+	notifying    bool
+	// onIdle is called, without mu held, whenever a turn ends, even if the
+	// session was idle already
+	onIdle func()
+	// onPanic reports a listener that panicked
+	onPanic func(r any, stack []byte)
+}
+
+type stateListener struct {
+	id      int
+	handler func(previous, current SessionState)
+}
+
+type stateChange struct {
+	previous, current SessionState
+}
+
+// idleWaiter is released when a busy session becomes idle.
+type idleWaiter struct {
+	done chan struct{}
+	err  error // set before done is closed if the turn ended with session.error
+}
+
+// stateHold is what a running handler holds the session in.
+type stateHold int
+
+const (
+	holdTool stateHold = iota
+	holdPermission
+	holdUserInput
+)
+
+// stateLocked derives the state. Must be called with mu held.
+func (s *sessionState) stateLocked() SessionState {
+	switch {
+	case s.aborting && s.busy:
+		return SessionStateAborting
+	case s.userInputs > 0:
+		return SessionStateAwaitingUserInput
+	case s.permissions > 0:
+		return SessionStateAwaitingPermission
+	case len(s.tools) > 0:
+		return SessionStateAwaitingTool
+	case s.compacting:
+		return SessionStateCompacting
+	case s.busy:
+		return SessionStateSending
+	}
+	return SessionStateIdle
+}
+
+// get returns the current state.
+func (s *sessionState) get() SessionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stateLocked()
+}
+
+// settleLocked records a change of the derived state, and releases the idle
+// waiter with err once idle. Must be called with mu held.
+func (s *sessionState) settleLocked(err error) {
+	previous := s.current
+	if previous == "" {
+		previous = SessionStateIdle
+	}
+	current := s.stateLocked()
+	s.current = current
+	if current == SessionStateIdle {
+		s.releaseLocked(err)
+	}
+	if current != previous && len(s.listeners) > 0 {
+		s.changes = append(s.changes, stateChange{previous, current})
+	}
+}
+
+// endTurnLocked clears everything pending. Must be called with mu held.
+func (s *sessionState) endTurnLocked() {
+	s.busy = false
+	s.aborting = false
+	s.compacting = false
+	clear(s.tools)
+	clear(s.finishedTools)
+	if s.permissions > 0 || s.userInputs > 0 {
+		s.permissions, s.userInputs = 0, 0
+		s.handlersChangedLocked()
+	}
+	s.generation++
+}
+
+// markBusy records that a message is being sent. It reports whether the
+// session was already busy, for unmarkBusy.
+func (s *sessionState) markBusy() bool {
+	s.mu.Lock()
+	wasBusy := s.stateLocked() != SessionStateIdle
+	s.busy = true
+	s.settleLocked(nil)
+	s.mu.Unlock()
+	s.notify()
+	return wasBusy
+}
+
+// unmarkBusy undoes markBusy after a failed send, unless the session was
+// already busy before it.
+func (s *sessionState) unmarkBusy(wasBusy bool) {
+	if wasBusy {
+		return
+	}
+	s.mu.Lock()
+	s.endTurnLocked()
+	s.settleLocked(nil)
+	s.mu.Unlock()
+	s.turnEnded()
+	s.notify()
+}
+
+// abort records that the turn was aborted, reporting false if no turn was
+// in progress. abortFailed undoes it.
+func (s *sessionState) abort() bool {
+	s.mu.Lock()
+	aborting := s.busy
+	s.aborting = aborting
+	s.settleLocked(nil)
+	s.mu.Unlock()
+	s.notify()
+	return aborting
+}
+
+func (s *sessionState) abortFailed() {
+	s.mu.Lock()
+	s.aborting = false
+	s.settleLocked(nil)
+	s.mu.Unlock()
+	s.notify()
+}
+
+// observeEvent records an event received by the session.
+func (s *sessionState) observeEvent(event SessionEvent) {
+	s.mu.Lock()
+	s.lastEventType = event.Type
+	s.lastEventAt = time.Now()
+	var err error
+	ended := false
+	switch event.Type {
+	case AssistantTurnStart:
+		// Turns may start without a Send from this client, such as on a
+		// resumed session
+		s.busy = true
+	case ToolExecutionStart:
+		if id := event.Data.ToolCallID; id != nil {
+			if _, finished := s.finishedTools[*id]; !finished {
+				s.startToolLocked(*id)
+			}
+		}
+	case ToolExecutionComplete:
+		if id := event.Data.ToolCallID; id != nil {
+			s.finishToolLocked(*id)
+		}
+	case SessionCompactionStart:
+		s.compacting = true
+	case SessionCompactionComplete:
+		s.compacting = false
+	case Abort:
+		s.aborting = s.busy
+	case SessionIdle:
+		s.endTurnLocked()
+		ended = true
+	case SessionError:
+		s.endTurnLocked()
+		err = sessionEventError(event)
+		ended = true
+	}
+	s.settleLocked(err)
+	s.mu.Unlock()
+	if ended {
+		s.turnEnded()
+	}
+	s.notify()
+}
+
+// startToolLocked records a pending tool call. Must be called with mu held.
+func (s *sessionState) startToolLocked(id string) {
+	if s.tools == nil {
+		s.tools = make(map[string]struct{})
+	}
+	s.tools[id] = struct{}{}
+	s.busy = true
+}
+
+// finishToolLocked records a completed tool call. Must be called with mu
+// held.
+func (s *sessionState) finishToolLocked(id string) {
+	delete(s.tools, id)
+	if s.finishedTools == nil || len(s.finishedTools) >= maxFinishedToolCalls {
+		s.finishedTools = make(map[string]struct{})
+	}
+	s.finishedTools[id] = struct{}{}
+}
+
+// begin records that a handler is running and returns a function recording
+// that it returned. toolCallID identifies the call for holdTool.
+func (s *sessionState) begin(hold stateHold, toolCallID string) func() {
+	s.mu.Lock()
+	generation := s.generation
+	switch hold {
+	case holdTool:
+		s.startToolLocked(toolCallID)
+	case holdPermission:
+		s.permissions++
+		s.busy = true
+		s.handlersChangedLocked()
+	case holdUserInput:
+		s.userInputs++
+		s.busy = true
+		s.handlersChangedLocked()
+	}
+	s.settleLocked(nil)
+	s.mu.Unlock()
+	s.notify()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			// A turn that ended in the meantime cleared the handler already
+			if s.generation == generation {
+				switch hold {
+				case holdTool:
+					s.finishToolLocked(toolCallID)
+				case holdPermission:
+					s.permissions--
+					s.handlersChangedLocked()
+				case holdUserInput:
+					s.userInputs--
+					s.handlersChangedLocked()
+				}
+			}
+			s.settleLocked(nil)
+			s.mu.Unlock()
+			s.notify()
+		})
+	}
+}
+
+// handlers reports whether a permission or user input handler is running,
+// and a channel closed on the next change.
+func (s *sessionState) handlers() (bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.handlersCh == nil {
+		s.handlersCh = make(chan struct{})
+	}
+	return s.permissions > 0 || s.userInputs > 0, s.handlersCh
+}
+
+func (s *sessionState) handlersChangedLocked() {
+	if s.handlersCh != nil {
+		close(s.handlersCh)
+		s.handlersCh = nil
+	}
+}
+
+// idle returns a waiter released when the session is next idle, or nil if
+// it is idle now.
+func (s *sessionState) idle() *idleWaiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stateLocked() == SessionStateIdle {
+		return nil
+	}
+	if s.waiter == nil {
+		s.waiter = &idleWaiter{done: make(chan struct{})}
+	}
+	return s.waiter
+}
+
+// releaseLocked releases the current waiter with err.
+func (s *sessionState) releaseLocked(err error) {
+	if s.waiter != nil {
+		s.waiter.err = err
+		close(s.waiter.done)
+		s.waiter = nil
+	}
+}
+
+// turnEnded calls onIdle.
+func (s *sessionState) turnEnded() {
+	if s.onIdle != nil {
+		s.onIdle()
+	}
+}
+
+// subscribe adds a listener and returns a function removing it.
+func (s *sessionState) subscribe(handler func(previous, current SessionState)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextListener++
+	id := s.nextListener
+	s.listeners = append(s.listeners, stateListener{id: id, handler: handler})
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.listeners = slices.DeleteFunc(s.listeners, func(l stateListener) bool { return l.id == id })
+	}
+}
+
+// notify passes recorded changes to the listeners, in order and one at a
+// time. A call made while another goroutine is notifying leaves its
+// changes to that goroutine.
+func (s *sessionState) notify() {
+	s.mu.Lock()
+	if s.notifying || len(s.changes) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.notifying = true
+	for len(s.changes) > 0 {
+		change := s.changes[0]
+		s.changes = s.changes[1:]
+		listeners := slices.Clone(s.listeners)
+		s.mu.Unlock()
+		for _, listener := range listeners {
+			s.callListener(listener, change)
+		}
+		s.mu.Lock()
+	}
+	s.changes = nil
+	s.notifying = false
+	s.mu.Unlock()
+}
+
+func (s *sessionState) callListener(listener stateListener, change stateChange) {
+	defer func() {
+		if r := recover(); r != nil && s.onPanic != nil {
+			s.onPanic(r, debug.Stack())
+		}
+	}()
+	listener.handler(change.previous, change.current)
+}
+
+// State returns what the session is doing: idle, working on a message, or
+// waiting for a tool, permission decision, user answer, compaction or
+// abort. See [SessionState] for how it is derived.
+//
+// Example:
+//
+//	if session.State() == copilot.SessionStateIdle {
+//	    session.Send(ctx, copilot.MessageOptions{Prompt: "Next task"})
+//	}
+func (s *Session) State() SessionState {
+	return s.state.get()
+}
+
+// OnStateChange subscribes to changes of [Session.State]. Handlers are
+// called with each change in order, one at a time, from the goroutine that
+// caused it, so they must not block. A handler may call session methods.
+// Panics in handlers are recovered and logged. Returns a function that
+// unsubscribes the handler.
+//
+// Example:
+//
+//	unsubscribe := session.OnStateChange(func(previous, current copilot.SessionState) {
+//	    if current == copilot.SessionStateAwaitingPermission {
+//	        ui.ShowBadge("needs approval")
+//	    }
+//	})
+//	defer unsubscribe()
+func (s *Session) OnStateChange(handler func(previous, current SessionState)) func() {
+	return s.state.subscribe(handler)
+}
+
+// initState connects the session's state machine to the session.
+func (s *Session) initState() {
+	s.state.onIdle = func() {
+		if s.pacer != nil {
+			s.pacer.release(s.SessionID)
+		}
+	}
+	s.state.onPanic = func(r any, stack []byte) {
+		s.diagnostics.recordPanic("state change handler", s.SessionID, r)
+		s.logger.Error("state change handler panicked", "sessionID", s.SessionID, "panic", r, "stack", string(stack))
+	}
+}
@@ -0,0 +1,237 @@
+package copilot
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/sdklog"
+)
+
+func TestSessionState_Transitions(t *testing.T) {
+	type step struct {
+		do   func(s *sessionState, ends map[string]func())
+		want SessionState
+	}
+	event := func(eventType SessionEventType, toolCallID string) step {
+		return step{do: func(s *sessionState, _ map[string]func()) {
+			event := SessionEvent{Type: eventType}
+			if toolCallID != "" {
+				event.Data.ToolCallID = String(toolCallID)
+			}
+			s.observeEvent(event)
+		}}
+	}
+	begin := func(hold stateHold, key string) step {
+		return step{do: func(s *sessionState, ends map[string]func()) { ends[key] = s.begin(hold, key) }}
+	}
+	end := func(key string) step {
+		return step{do: func(_ *sessionState, ends map[string]func()) { ends[key]() }}
+	}
+	// send marks the session busy; end("send") then fails the send
+	send := step{do: func(s *sessionState, ends map[string]func()) {
+		wasBusy := s.markBusy()
+		ends["send"] = func() { s.unmarkBusy(wasBusy) }
+	}}
+	abort := step{do: func(s *sessionState, _ map[string]func()) { s.abort() }}
+	then := func(s step, want SessionState) step {
+		s.want = want
+		return s
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "a tool call waiting for permission",
+			steps: []step{
+				then(send, SessionStateSending),
+				then(event(AssistantTurnStart, ""), SessionStateSending),
+				then(event(ToolExecutionStart, "t1"), SessionStateAwaitingTool),
+				then(begin(holdPermission, "p1"), SessionStateAwaitingPermission),
+				then(end("p1"), SessionStateAwaitingTool),
+				then(begin(holdTool, "t1"), SessionStateAwaitingTool),
+				then(end("t1"), SessionStateSending),
+				then(event(ToolExecutionComplete, "t1"), SessionStateSending),
+				then(event(SessionIdle, ""), SessionStateIdle),
+			},
+		},
+		{
+			name: "user input asked from a tool takes precedence",
+			steps: []step{
+				then(send, SessionStateSending),
+				then(begin(holdTool, "t1"), SessionStateAwaitingTool),
+				then(begin(holdUserInput, "q1"), SessionStateAwaitingUserInput),
+				then(event(ToolExecutionStart, "t2"), SessionStateAwaitingUserInput),
+				then(end("q1"), SessionStateAwaitingTool),
+				then(end("t1"), SessionStateAwaitingTool),
+				then(event(ToolExecutionComplete, "t2"), SessionStateSending),
+			},
+		},
+		{
+			name: "a completion before its start leaves no tool pending",
+			steps: []step{
+				then(send, SessionStateSending),
+				then(event(ToolExecutionComplete, "t1"), SessionStateSending),
+				then(event(ToolExecutionStart, "t1"), SessionStateSending),
+				then(event(SessionIdle, ""), SessionStateIdle),
+			},
+		},
+		{
+			name: "a turn without turn start or idle",
+			steps: []step{
+				then(event(ToolExecutionStart, "t1"), SessionStateAwaitingTool),
+				then(event(ToolExecutionComplete, "t1"), SessionStateSending),
+				then(event(SessionError, ""), SessionStateIdle),
+				then(event(ToolExecutionComplete, "t2"), SessionStateIdle),
+			},
+		},
+		{
+			name: "idle clears tool calls missing their completion",
+			steps: []step{
+				then(event(ToolExecutionStart, "t1"), SessionStateAwaitingTool),
+				then(event(ToolExecutionStart, "t2"), SessionStateAwaitingTool),
+				then(event(SessionIdle, ""), SessionStateIdle),
+				then(event(ToolExecutionComplete, "t1"), SessionStateIdle),
+			},
+		},
+		{
+			name: "a handler outliving its turn",
+			steps: []step{
+				then(begin(holdPermission, "p1"), SessionStateAwaitingPermission),
+				then(event(SessionIdle, ""), SessionStateIdle),
+				then(send, SessionStateSending),
+				then(end("p1"), SessionStateSending),
+				then(begin(holdPermission, "p2"), SessionStateAwaitingPermission),
+				then(end("p2"), SessionStateSending),
+			},
+		},
+		{
+			name: "compaction while idle and during a turn",
+			steps: []step{
+				then(event(SessionCompactionStart, ""), SessionStateCompacting),
+				then(event(SessionCompactionComplete, ""), SessionStateIdle),
+				then(send, SessionStateSending),
+				then(event(SessionCompactionStart, ""), SessionStateCompacting),
+				then(event(ToolExecutionStart, "t1"), SessionStateAwaitingTool),
+				then(event(ToolExecutionComplete, "t1"), SessionStateCompacting),
+				then(event(SessionIdle, ""), SessionStateIdle),
+			},
+		},
+		{
+			name: "aborting a turn",
+			steps: []step{
+				then(abort, SessionStateIdle),
+				then(send, SessionStateSending),
+				then(begin(holdPermission, "p1"), SessionStateAwaitingPermission),
+				then(abort, SessionStateAborting),
+				then(end("p1"), SessionStateAborting),
+				then(event(SessionIdle, ""), SessionStateIdle),
+				then(event(AssistantTurnStart, ""), SessionStateSending),
+				then(event(Abort, ""), SessionStateAborting),
+				then(event(SessionError, ""), SessionStateIdle),
+			},
+		},
+		{
+			name: "failed sends",
+			steps: []step{
+				then(send, SessionStateSending),
+				then(end("send"), SessionStateIdle),
+				then(event(AssistantTurnStart, ""), SessionStateSending),
+				then(send, SessionStateSending),
+				then(end("send"), SessionStateSending),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s sessionState
+			var changes []stateChange
+			s.subscribe(func(previous, current SessionState) {
+				changes = append(changes, stateChange{previous, current})
+			})
+			ends := make(map[string]func())
+			states := []SessionState{SessionStateIdle}
+			for i, step := range tt.steps {
+				step.do(&s, ends)
+				if got := s.get(); got != step.want {
+					t.Fatalf("Step %d: expected %s, got %s", i, step.want, got)
+				}
+				if states[len(states)-1] != step.want {
+					states = append(states, step.want)
+				}
+			}
+			var want []stateChange
+			for i := 1; i < len(states); i++ {
+				want = append(want, stateChange{states[i-1], states[i]})
+			}
+			if !slices.Equal(changes, want) {
+				t.Errorf("Expected changes %v, got %v", want, changes)
+			}
+		})
+	}
+}
+
+func TestSession_OnStateChange(t *testing.T) {
+	session, server := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+		if method == "session.send" {
+			return sessionSendResponse{MessageID: "m1"}, nil
+		}
+		return nil, nil
+	})
+	session.pacer = newPacer(PacingOptions{MaxConcurrentBusySessions: 1})
+	session.logger = sdklog.New(io.Discard, false)
+
+	var mu sync.Mutex
+	var changes []stateChange
+	session.OnStateChange(func(SessionState, SessionState) { panic("boom") })
+	unsubscribe := session.OnStateChange(func(previous, current SessionState) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, stateChange{previous, current})
+	})
+
+	if _, err := session.Send(t.Context(), MessageOptions{Prompt: "run it"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if state := session.State(); state != SessionStateSending {
+		t.Errorf("Expected sending after Send, got %s", state)
+	}
+	server.emit(SessionEvent{Type: ToolExecutionStart, Data: Data{ToolCallID: String("t1"), ToolName: String("bash")}})
+	server.emit(SessionEvent{Type: ToolExecutionComplete, Data: Data{ToolCallID: String("t1")}})
+	server.emit(SessionEvent{Type: SessionIdle})
+	if err := session.WaitForIdle(t.Context()); err != nil {
+		t.Fatalf("WaitForIdle failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for session.pacer.state().BusySessions != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if busy := session.pacer.state().BusySessions; busy != 0 {
+		t.Errorf("Expected the pacer to release the session when idle, got %d busy", busy)
+	}
+
+	mu.Lock()
+	want := []stateChange{
+		{SessionStateIdle, SessionStateSending},
+		{SessionStateSending, SessionStateAwaitingTool},
+		{SessionStateAwaitingTool, SessionStateSending},
+		{SessionStateSending, SessionStateIdle},
+	}
+	if !slices.Equal(changes, want) {
+		t.Errorf("Expected changes %v, got %v", want, changes)
+	}
+	mu.Unlock()
+
+	unsubscribe()
+	session.Send(t.Context(), MessageOptions{Prompt: "again"})
+	mu.Lock()
+	defer mu.Unlock()
+	if len(changes) != len(want) {
+		t.Errorf("Expected no changes after unsubscribing, got %v", changes[len(want):])
+	}
+}
@@ -0,0 +1,188 @@
+package copilot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSharedContextMaxBytes is the default of [SharedContextOptions.MaxBytes].
+const defaultSharedContextMaxBytes = 8 << 10
+
+// sharedContextHeader introduces the shared entries in the system message.
+const sharedContextHeader = "Context shared by other sessions working on the same project:"
+
+// SessionSharedContextInjected is emitted by the SDK when a session is
+// created or resumed with entries from [Client.SharedContext]. Use
+// [SessionEvent.AsSharedContextInjected] to read the payload.
+const SessionSharedContextInjected SessionEventType = "session.shared_context_injected"
+
+// SharedContext holds facts, such as "the build command is make ci", that
+// sessions of a client share. Sessions created or resumed with
+// [SessionConfig.SharedContext] receive the entries present at that time in
+// their system message. Changing the store does not affect sessions already
+// created, and [Client.Restart] re-attaches sessions with the entries they
+// were created with.
+//
+// A SharedContext is safe for concurrent use.
+type SharedContext struct {
+	mu      sync.Mutex
+	entries map[string]string
+}
+
+// SharedContextEntry is an entry of a [SharedContext].
+type SharedContextEntry struct {
+	Key  string
+	Text string
+}
+
+// SharedContextOptions configures how a session includes the entries of
+// [Client.SharedContext].
+type SharedContextOptions struct {
+	// MaxBytes bounds the size of the entries added to the system message.
+	// Entries are added in key order; those that do not fit are left out
+	// and reported in the session.shared_context_injected event.
+	// Default: 8 KiB.
+	MaxBytes int
+}
+
+// SharedContextInjectedData is the payload of a
+// session.shared_context_injected event.
+type SharedContextInjectedData struct {
+	// Keys lists the entries added to the session's system message
+	Keys []string
+	// Skipped lists the entries left out because they did not fit
+	// [SharedContextOptions.MaxBytes]
+	Skipped []string
+	// Bytes is the size of the text added to the system message
+	Bytes int
+}
+
+// AsSharedContextInjected returns the payload of a
+// session.shared_context_injected event. The second return value is false
+// for any other event type.
+func (e SessionEvent) AsSharedContextInjected() (*SharedContextInjectedData, bool) {
+	if e.Type != SessionSharedContextInjected {
+		return nil, false
+	}
+	data, ok := e.Data.Output.(*SharedContextInjectedData)
+	return data, ok
+}
+
+// SharedContext returns the client's shared context store.
+//
+// Example:
+//
+//	client.SharedContext().Put("build", "The build command is make ci.")
+//	session, err := client.CreateSession(ctx, &copilot.SessionConfig{
+//	    OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+//	    SharedContext:       &copilot.SharedContextOptions{},
+//	})
+func (c *Client) SharedContext() *SharedContext {
+	return &c.sharedContext
+}
+
+// Put sets the text of the entry key, replacing any previous text.
+func (sc *SharedContext) Put(key, text string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.entries == nil {
+		sc.entries = make(map[string]string)
+	}
+	sc.entries[key] = text
+}
+
+// Remove deletes the entry key, if present.
+func (sc *SharedContext) Remove(key string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	delete(sc.entries, key)
+}
+
+// Entries returns the entries in key order.
+func (sc *SharedContext) Entries() []SharedContextEntry {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	keys := make([]string, 0, len(sc.entries))
+	for key := range sc.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	entries := make([]SharedContextEntry, len(keys))
+	for i, key := range keys {
+		entries[i] = SharedContextEntry{Key: key, Text: sc.entries[key]}
+	}
+	return entries
+}
+
+// render returns the entries as a system message section of at most
+// maxBytes bytes, and which entries it includes. It returns nil data if the
+// store is empty.
+func (sc *SharedContext) render(maxBytes int) (string, *SharedContextInjectedData) {
+	if maxBytes <= 0 {
+		maxBytes = defaultSharedContextMaxBytes
+	}
+	entries := sc.Entries()
+	if len(entries) == 0 {
+		return "", nil
+	}
+	data := &SharedContextInjectedData{}
+	var b strings.Builder
+	b.WriteString(sharedContextHeader)
+	for _, entry := range entries {
+		block := fmt.Sprintf("\n\n### %s\n%s", entry.Key, entry.Text)
+		if b.Len()+len(block) > maxBytes {
+			data.Skipped = append(data.Skipped, entry.Key)
+			continue
+		}
+		b.WriteString(block)
+		data.Keys = append(data.Keys, entry.Key)
+	}
+	if len(data.Keys) == 0 {
+		return "", data
+	}
+	data.Bytes = b.Len()
+	return b.String(), data
+}
+
+// withSharedContext returns systemMessage with the client's shared context
+// appended if options is set, leaving the caller's config unchanged, and
+// the payload of the event to emit, or nil if there is nothing to report.
+func (c *Client) withSharedContext(systemMessage *SystemMessageConfig, options *SharedContextOptions) (*SystemMessageConfig, *SharedContextInjectedData) {
+	if options == nil {
+		return systemMessage, nil
+	}
+	text, data := c.sharedContext.render(options.MaxBytes)
+	if text == "" {
+		return systemMessage, data
+	}
+	result := SystemMessageConfig{Mode: "append"}
+	if systemMessage != nil {
+		result = *systemMessage
+	}
+	if result.Content != "" {
+		result.Content += "\n\n"
+	}
+	result.Content += text
+	return &result, data
+}
+
+// reportSharedContext emits a session.shared_context_injected event
+// describing the shared entries the session was given.
+func (s *Session) reportSharedContext(data *SharedContextInjectedData) {
+	if data == nil {
+		return
+	}
+	s.dispatchEvent(SessionEvent{
+		Type:      SessionSharedContextInjected,
+		Timestamp: time.Now(),
+		Ephemeral: Bool(true),
+		Data: Data{
+			Message: String(fmt.Sprintf("added %d shared context entries (%d bytes), left out %d over the limit",
+				len(data.Keys), data.Bytes, len(data.Skipped))),
+			Output: data,
+		},
+	})
+}
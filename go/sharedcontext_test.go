@@ -0,0 +1,118 @@
+package copilot
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestClient_SharedContext(t *testing.T) {
+	var mu sync.Mutex
+	systemMessages := map[string]*SystemMessageConfig{}
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		if method != "session.create" && method != "session.resume" {
+			return nil, nil
+		}
+		var req struct {
+			SessionID     string               `json:"sessionId"`
+			SystemMessage *SystemMessageConfig `json:"systemMessage"`
+		}
+		json.Unmarshal(params, &req)
+		mu.Lock()
+		systemMessages[method+" "+req.SessionID] = req.SystemMessage
+		mu.Unlock()
+		return createSessionResponse{SessionID: req.SessionID}, nil
+	})
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	sent := func(key string) *SystemMessageConfig {
+		mu.Lock()
+		defer mu.Unlock()
+		return systemMessages[key]
+	}
+	// create creates a session and returns its shared context events
+	create := func(t *testing.T, id string, config *SessionConfig) []*SharedContextInjectedData {
+		t.Helper()
+		var injected []*SharedContextInjectedData
+		config.SessionID = id
+		config.OnPermissionRequest = PermissionHandler.ApproveAll
+		config.OnEvent = []SessionEventHandler{func(event SessionEvent) {
+			if data, ok := event.AsSharedContextInjected(); ok {
+				injected = append(injected, data)
+			}
+		}}
+		if _, err := client.CreateSession(t.Context(), config); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		return injected
+	}
+
+	store := client.SharedContext()
+	store.Put("build", "The build command is make ci.")
+	store.Put("style", "Use tabs.")
+	store.Put("build", "The build command is make ci-fast.")
+
+	t.Run("adds the entries to the system message", func(t *testing.T) {
+		injected := create(t, "s1", &SessionConfig{
+			SystemMessage: &SystemMessageConfig{Content: "Be brief."},
+			SharedContext: &SharedContextOptions{},
+		})
+		want := "Be brief.\n\n" + sharedContextHeader + "\n\n### build\nThe build command is make ci-fast.\n\n### style\nUse tabs."
+		if got := sent("session.create s1"); got == nil || got.Content != want {
+			t.Errorf("Expected system message %q, got %+v", want, got)
+		}
+		if len(injected) != 1 || !slices.Equal(injected[0].Keys, []string{"build", "style"}) || injected[0].Bytes != len(want)-len("Be brief.\n\n") {
+			t.Errorf("Expected one event listing both entries, got %+v", injected)
+		}
+	})
+
+	t.Run("leaves out entries over the size limit", func(t *testing.T) {
+		store.Put("architecture", strings.Repeat("The parser lives in internal/parse. ", 20))
+		t.Cleanup(func() { store.Remove("architecture") })
+		injected := create(t, "s2", &SessionConfig{SharedContext: &SharedContextOptions{MaxBytes: 200}})
+
+		got := sent("session.create s2")
+		if got == nil || len(got.Content) > 200 || got.Mode != "append" || strings.Contains(got.Content, "internal/parse") {
+			t.Fatalf("Expected the large entry left out within 200 bytes, got %+v", got)
+		}
+		if len(injected) != 1 || !slices.Equal(injected[0].Skipped, []string{"architecture"}) || !slices.Equal(injected[0].Keys, []string{"build", "style"}) {
+			t.Errorf("Expected the large entry reported as skipped, got %+v", injected)
+		}
+	})
+
+	t.Run("affects only later sessions", func(t *testing.T) {
+		store.Remove("style")
+		t.Cleanup(func() { store.Put("style", "Use tabs.") })
+		create(t, "s3", &SessionConfig{SharedContext: &SharedContextOptions{}})
+		if strings.Contains(sent("session.create s3").Content, "style") {
+			t.Error("Expected the removed entry to be left out")
+		}
+		if !strings.Contains(sent("session.create s1").Content, "Use tabs.") {
+			t.Error("Expected earlier sessions to keep their entries")
+		}
+	})
+
+	t.Run("is off by default", func(t *testing.T) {
+		if injected := create(t, "s4", &SessionConfig{}); sent("session.create s4") != nil || len(injected) != 0 {
+			t.Errorf("Expected no system message or event, got %+v, %v", sent("session.create s4"), injected)
+		}
+	})
+
+	t.Run("adds the entries on resume", func(t *testing.T) {
+		_, err := client.ResumeSessionWithOptions(t.Context(), "s5", &ResumeSessionConfig{
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			SharedContext:       &SharedContextOptions{},
+		})
+		if err != nil {
+			t.Fatalf("Failed to resume session: %v", err)
+		}
+		if got := sent("session.resume s5"); got == nil || !strings.Contains(got.Content, "make ci-fast") {
+			t.Errorf("Expected the entries in session.resume, got %+v", got)
+		}
+	})
+}
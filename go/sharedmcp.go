@@ -0,0 +1,250 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrLocalSharedMCPServer is returned by [Client.StartSharedMCPServer] for
+// local (stdio) MCP server configs. The CLI starts local MCP servers itself,
+// once per session, and has no protocol for attaching sessions to a server
+// managed by the client, so only remote (http or sse) servers can be shared.
+var ErrLocalSharedMCPServer = errors.New("local MCP servers cannot be shared; only remote (http or sse) servers are supported")
+
+// SharedMCPServer is a handle to an MCP server config shared by several
+// sessions of a [Client]. Sessions attach to it by listing its name in
+// [SessionConfig.SharedMCPServers], and detach when they are destroyed. The
+// server is unregistered once the handle has been closed and the last session
+// has detached.
+//
+// Sharing is bookkeeping in the SDK: one config is defined once and its
+// users are counted. The CLI has no protocol for sharing an MCP server
+// between sessions, so every attached session is still sent the config and
+// opens its own connection to the server; sharing does not reduce the number
+// of connections or server processes.
+type SharedMCPServer struct {
+	server    *sharedMCPServer
+	closeOnce sync.Once
+}
+
+// Name returns the name sessions use to reference the server.
+func (h *SharedMCPServer) Name() string {
+	return h.server.name
+}
+
+// Sessions returns the number of sessions attached to the server.
+func (h *SharedMCPServer) Sessions() int {
+	h.server.client.sharedMCPMux.Lock()
+	defer h.server.client.sharedMCPMux.Unlock()
+	return h.server.sessions
+}
+
+// Done returns a channel that is closed when the server is unregistered.
+func (h *SharedMCPServer) Done() <-chan struct{} {
+	return h.server.done
+}
+
+// Close releases the handle. Sessions created afterwards can no longer
+// attach to the server, which stays registered until the last attached
+// session detaches. Calling Close more than once has no effect.
+func (h *SharedMCPServer) Close() {
+	h.closeOnce.Do(h.server.release)
+}
+
+// sharedMCPServer is a named MCP server config shared by the sessions of a
+// client. It is reference counted: it stays registered while handles are
+// open, and is done once no handle is open and no session is attached.
+type sharedMCPServer struct {
+	client   *Client
+	name     string
+	config   MCPServerConfig
+	handles  int // guarded by client.sharedMCPMux
+	sessions int // guarded by client.sharedMCPMux
+	done     chan struct{}
+}
+
+// StartSharedMCPServer registers an MCP server that sessions can share by
+// referencing name in [SessionConfig.SharedMCPServers] or
+// [ResumeSessionConfig.SharedMCPServers]. Sessions that configure an
+// identical server in MCPServers attach to the shared one as well.
+//
+// Only remote (http or sse) servers can be shared; local configs return
+// [ErrLocalSharedMCPServer]. Starting a server under a name that is already
+// registered returns a new handle to it if the configs are identical, and an
+// error otherwise.
+//
+// No server is started and no connection is made: each attached session is
+// sent the config and connects to the server itself, as with MCPServers.
+// The registry keeps the config in one place and reports, through
+// [SharedMCPServer.Sessions] and [SharedMCPServer.Done], which sessions still
+// use it.
+//
+// Close the returned handle when no new sessions need the server; it is
+// unregistered once the last attached session detaches.
+//
+// Example:
+//
+//	docs, err := client.StartSharedMCPServer("docs", copilot.MCPServerConfig{
+//	    "type": "http",
+//	    "url":  "https://mcp.example.com/docs",
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer docs.Close()
+//
+//	session, err := client.CreateSession(ctx, &copilot.SessionConfig{
+//	    OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+//	    SharedMCPServers:    []string{"docs"},
+//	})
+func (c *Client) StartSharedMCPServer(name string, config MCPServerConfig) (*SharedMCPServer, error) {
+	if name == "" {
+		return nil, errors.New("shared MCP server name is required")
+	}
+	if !isRemoteMCPServer(config) {
+		return nil, ErrLocalSharedMCPServer
+	}
+
+	c.sharedMCPMux.Lock()
+	defer c.sharedMCPMux.Unlock()
+	if server, ok := c.sharedMCP[name]; ok {
+		if !reflect.DeepEqual(server.config, config) {
+			return nil, fmt.Errorf("shared MCP server %q is already registered with a different config", name)
+		}
+		server.handles++
+		return &SharedMCPServer{server: server}, nil
+	}
+
+	if c.sharedMCP == nil {
+		c.sharedMCP = make(map[string]*sharedMCPServer)
+	}
+	server := &sharedMCPServer{
+		client:  c,
+		name:    name,
+		config:  cloneMCPServerConfig(config),
+		handles: 1,
+		done:    make(chan struct{}),
+	}
+	c.sharedMCP[name] = server
+	return &SharedMCPServer{server: server}, nil
+}
+
+// attachSharedMCPServers resolves the shared servers named by a session
+// config and merges them into its MCP servers. Configured servers identical
+// to a shared one attach to it too. The returned servers must be released
+// when the session detaches.
+func (c *Client) attachSharedMCPServers(names []string, servers map[string]MCPServerConfig) (map[string]MCPServerConfig, []*sharedMCPServer, error) {
+	if len(names) == 0 && len(servers) == 0 {
+		return servers, nil, nil
+	}
+
+	c.sharedMCPMux.Lock()
+	defer c.sharedMCPMux.Unlock()
+
+	merged := servers
+	var attached []*sharedMCPServer
+	attach := func(server *sharedMCPServer) {
+		for _, s := range attached {
+			if s == server {
+				return
+			}
+		}
+		attached = append(attached, server)
+	}
+
+	for _, name := range names {
+		server, ok := c.sharedMCP[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("shared MCP server %q is not registered", name)
+		}
+		if existing, ok := servers[name]; ok && !reflect.DeepEqual(existing, server.config) {
+			return nil, nil, fmt.Errorf("MCP server %q is configured both in MCPServers and as a shared server", name)
+		}
+		if len(attached) == 0 {
+			merged = make(map[string]MCPServerConfig, len(servers)+len(names))
+			for k, v := range servers {
+				merged[k] = v
+			}
+		}
+		merged[name] = server.config
+		attach(server)
+	}
+	for _, config := range servers {
+		for _, server := range c.sharedMCP {
+			if reflect.DeepEqual(config, server.config) {
+				attach(server)
+			}
+		}
+	}
+
+	for _, server := range attached {
+		server.sessions++
+	}
+	return merged, attached, nil
+}
+
+// release drops a handle reference. Once the last handle is closed, new
+// sessions can no longer attach.
+func (s *sharedMCPServer) release() {
+	s.client.sharedMCPMux.Lock()
+	defer s.client.sharedMCPMux.Unlock()
+	s.handles--
+	if s.handles == 0 && s.client.sharedMCP[s.name] == s {
+		delete(s.client.sharedMCP, s.name)
+	}
+	s.shutdownIfUnusedLocked()
+}
+
+// detach drops a session reference.
+func (s *sharedMCPServer) detach() {
+	s.client.sharedMCPMux.Lock()
+	defer s.client.sharedMCPMux.Unlock()
+	s.sessions--
+	s.shutdownIfUnusedLocked()
+}
+
+// shutdownIfUnusedLocked marks the server done once no handle is open and
+// no session is attached. Must be called with client.sharedMCPMux held.
+func (s *sharedMCPServer) shutdownIfUnusedLocked() {
+	if s.handles == 0 && s.sessions == 0 {
+		close(s.done)
+	}
+}
+
+// detachSharedMCPServers releases the shared MCP servers the session is
+// attached to. It is safe to call more than once.
+func (s *Session) detachSharedMCPServers() {
+	s.sharedMCPMux.Lock()
+	servers := s.sharedMCP
+	s.sharedMCP = nil
+	s.sharedMCPMux.Unlock()
+	detachSharedMCPServers(servers)
+}
+
+// detachSharedMCPServers drops a session reference from each server.
+func detachSharedMCPServers(servers []*sharedMCPServer) {
+	for _, server := range servers {
+		server.detach()
+	}
+}
+
+// isRemoteMCPServer reports whether config describes a remote MCP server.
+func isRemoteMCPServer(config MCPServerConfig) bool {
+	switch config["type"] {
+	case "http", "sse":
+		return true
+	}
+	return false
+}
+
+// cloneMCPServerConfig returns a shallow copy of config, so later changes to
+// the caller's map don't affect the shared server.
+func cloneMCPServerConfig(config MCPServerConfig) MCPServerConfig {
+	clone := make(MCPServerConfig, len(config))
+	for k, v := range config {
+		clone[k] = v
+	}
+	return clone
+}
@@ -0,0 +1,152 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestClient_SharedMCPServers(t *testing.T) {
+	var mu sync.Mutex
+	var created []createSessionRequest
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "session.create":
+			var req createSessionRequest
+			json.Unmarshal(params, &req)
+			mu.Lock()
+			created = append(created, req)
+			mu.Unlock()
+			return createSessionResponse{SessionID: req.SessionID}, nil
+		case "session.destroy":
+			return map[string]any{}, nil
+		}
+		return nil, nil
+	})
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+
+	docsConfig := MCPServerConfig{"type": "http", "url": "https://mcp.example.com/docs", "tools": []any{"*"}}
+	createSession := func(id string, config SessionConfig) *Session {
+		t.Helper()
+		config.SessionID = id
+		config.OnPermissionRequest = PermissionHandler.ApproveAll
+		session, err := client.CreateSession(t.Context(), &config)
+		if err != nil {
+			t.Fatalf("Failed to create session %s: %v", id, err)
+		}
+		return session
+	}
+	isDone := func(server *SharedMCPServer) bool {
+		select {
+		case <-server.Done():
+			return true
+		default:
+			return false
+		}
+	}
+
+	t.Run("is unregistered when the last session detaches", func(t *testing.T) {
+		docs, err := client.StartSharedMCPServer("docs", docsConfig)
+		if err != nil {
+			t.Fatalf("Failed to register shared server: %v", err)
+		}
+		s1 := createSession("s1", SessionConfig{SharedMCPServers: []string{"docs"}})
+		s2 := createSession("s2", SessionConfig{
+			SharedMCPServers: []string{"docs"},
+			MCPServers:       map[string]MCPServerConfig{"local": {"type": "local", "command": "mcp"}},
+		})
+		if docs.Sessions() != 2 {
+			t.Errorf("Expected 2 attached sessions, got %d", docs.Sessions())
+		}
+
+		mu.Lock()
+		last := created[len(created)-1]
+		mu.Unlock()
+		if !reflect.DeepEqual(last.MCPServers["docs"], docsConfig) || last.MCPServers["local"] == nil {
+			t.Errorf("Expected the shared server to be merged into the session's servers, got %v", last.MCPServers)
+		}
+
+		docs.Close()
+		docs.Close()
+		if isDone(docs) {
+			t.Fatal("Expected the server to stay registered while sessions are attached")
+		}
+		if _, err := client.CreateSession(t.Context(), &SessionConfig{
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			SharedMCPServers:    []string{"docs"},
+		}); err == nil {
+			t.Error("Expected new sessions not to attach after Close")
+		}
+
+		if err := s1.Destroy(); err != nil {
+			t.Fatalf("Failed to destroy session: %v", err)
+		}
+		if isDone(docs) || docs.Sessions() != 1 {
+			t.Fatalf("Expected the server to stay registered with 1 session, got %d", docs.Sessions())
+		}
+		if err := s2.Destroy(); err != nil {
+			t.Fatalf("Failed to destroy session: %v", err)
+		}
+		if !isDone(docs) {
+			t.Error("Expected the server to be unregistered after the last session detached")
+		}
+	})
+
+	t.Run("is unregistered on close when no session is attached", func(t *testing.T) {
+		docs, err := client.StartSharedMCPServer("docs", docsConfig)
+		if err != nil {
+			t.Fatalf("Failed to register shared server: %v", err)
+		}
+		docs.Close()
+		if !isDone(docs) {
+			t.Error("Expected the server to be unregistered")
+		}
+	})
+
+	t.Run("identical configs count against one server", func(t *testing.T) {
+		first, err := client.StartSharedMCPServer("docs", docsConfig)
+		if err != nil {
+			t.Fatalf("Failed to register shared server: %v", err)
+		}
+		second, err := client.StartSharedMCPServer("docs", docsConfig)
+		if err != nil {
+			t.Fatalf("Expected an identical config to return a handle to the server: %v", err)
+		}
+		if _, err := client.StartSharedMCPServer("docs", MCPServerConfig{"type": "http", "url": "https://other"}); err == nil {
+			t.Error("Expected a different config under the same name to fail")
+		}
+
+		createSession("s3", SessionConfig{MCPServers: map[string]MCPServerConfig{"my-docs": docsConfig}})
+		if first.Sessions() != 1 {
+			t.Errorf("Expected the identical session config to attach, got %d sessions", first.Sessions())
+		}
+
+		first.Close()
+		second.Close()
+		if isDone(first) {
+			t.Fatal("Expected the server to stay registered while the session is attached")
+		}
+		client.ForceStop()
+		if !isDone(first) {
+			t.Error("Expected the server to be unregistered when the client force stops")
+		}
+	})
+
+	t.Run("rejects local servers and unknown names", func(t *testing.T) {
+		if _, err := client.StartSharedMCPServer("pg", MCPServerConfig{"type": "local", "command": "pg-mcp"}); !errors.Is(err, ErrLocalSharedMCPServer) {
+			t.Errorf("Expected ErrLocalSharedMCPServer, got %v", err)
+		}
+		if _, err := client.CreateSession(t.Context(), &SessionConfig{
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+			SharedMCPServers:    []string{"missing"},
+		}); err == nil {
+			t.Error("Expected an unknown shared server to fail")
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// startErrorStderrLines is the number of stderr lines kept in a CLIStartError.
+const startErrorStderrLines = 20
+
+// processExitGrace is how long a failed handshake waits for a spawned CLI
+// process to exit, to tell a crash at startup from a protocol problem.
+const processExitGrace = time.Second
+
+// lookupCLI checks that the CLI can be executed. command is the executable
+// to run; script, if not empty, is the JavaScript entry point passed to it.
+// It returns a *CLINotFoundError if either is missing.
+func lookupCLI(command, script string) error {
+	if script != "" {
+		if _, err := os.Stat(script); errors.Is(err, fs.ErrNotExist) {
+			return &CLINotFoundError{Command: script, Searched: []string{script}, err: err}
+		}
+	}
+	if _, err := exec.LookPath(command); err != nil && (errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist)) {
+		return &CLINotFoundError{Command: command, Searched: searchedPaths(command), err: err}
+	}
+	return nil
+}
+
+// searchedPaths lists where exec.LookPath looks for command.
+func searchedPaths(command string) []string {
+	if strings.ContainsRune(command, filepath.Separator) || strings.ContainsRune(command, '/') {
+		return []string{command}
+	}
+	var paths []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+		paths = append(paths, filepath.Join(dir, command))
+	}
+	return paths
+}
+
+// processStartError classifies an error from starting the CLI process.
+func (c *Client) processStartError(command string, err error) error {
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		return &CLINotFoundError{Command: command, Searched: searchedPaths(command), err: err}
+	}
+	return &CLIStartError{ExitCode: -1, Reason: "failed to start CLI server", err: err}
+}
+
+// startError builds a *CLIStartError for the spawned CLI process, including
+// its exit code if it has exited and the tail of its stderr.
+func (c *Client) startError(reason string, err error) *CLIStartError {
+	startErr := &CLIStartError{ExitCode: -1, Reason: reason, err: err}
+	if c.processDone != nil {
+		select {
+		case <-c.processDone:
+			if c.process != nil && c.process.ProcessState != nil {
+				startErr.ExitCode = c.process.ProcessState.ExitCode()
+			}
+		default:
+		}
+	}
+	if c.startStderr != nil {
+		stderr, _, _ := c.startStderr.snapshot()
+		startErr.Stderr = stderr[max(len(stderr)-startErrorStderrLines, 0):]
+	}
+	return startErr
+}
+
+// handshakeError classifies a failed ping during Start. If the spawned CLI
+// process exits shortly after, it crashed at startup and a *CLIStartError is
+// returned; otherwise the server answered badly and a *HandshakeError is.
+func (c *Client) handshakeError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	if c.processDone != nil {
+		select {
+		case <-c.processDone:
+			return c.startError("CLI server process exited during startup", err)
+		case <-time.After(processExitGrace):
+		}
+	}
+	return &HandshakeError{Reason: "ping failed", Received: err.Error(), err: err}
+}
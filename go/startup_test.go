@@ -0,0 +1,56 @@
+package copilot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLookupCLI(t *testing.T) {
+	t.Run("finds an executable in PATH", func(t *testing.T) {
+		exe, err := os.Executable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("PATH", filepath.Dir(exe))
+		if err := lookupCLI(filepath.Base(exe), ""); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("searches each PATH entry for a bare command", func(t *testing.T) {
+		dirs := []string{t.TempDir(), t.TempDir()}
+		t.Setenv("PATH", strings.Join(dirs, string(os.PathListSeparator)))
+
+		err := lookupCLI("copilot-missing", "")
+		var notFound *CLINotFoundError
+		if !errors.As(err, &notFound) || !errors.Is(err, ErrCLINotFound) {
+			t.Fatalf("Expected a CLINotFoundError, got %v", err)
+		}
+		want := []string{filepath.Join(dirs[0], "copilot-missing"), filepath.Join(dirs[1], "copilot-missing")}
+		if notFound.Command != "copilot-missing" || !reflect.DeepEqual(notFound.Searched, want) {
+			t.Errorf("Expected %v to be searched, got %+v", want, notFound)
+		}
+	})
+
+	t.Run("searches only the given path", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "copilot")
+		err := lookupCLI(path, "")
+		var notFound *CLINotFoundError
+		if !errors.As(err, &notFound) || !reflect.DeepEqual(notFound.Searched, []string{path}) {
+			t.Errorf("Expected only %s to be searched, got %v", path, err)
+		}
+	})
+
+	t.Run("reports a missing JavaScript entry point", func(t *testing.T) {
+		script := filepath.Join(t.TempDir(), "index.js")
+		err := lookupCLI("node", script)
+		var notFound *CLINotFoundError
+		if !errors.As(err, &notFound) || notFound.Command != script {
+			t.Errorf("Expected %s to be reported missing, got %v", script, err)
+		}
+	})
+}
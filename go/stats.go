@@ -0,0 +1,84 @@
+package copilot
+
+import (
+	"time"
+)
+
+// defaultRequestTimeout is the default of [ClientOptions.RequestTimeout].
+const defaultRequestTimeout = 10 * time.Minute
+
+// ClientStats counts what a client holds in memory, for monitoring
+// long-lived clients. See [Client.Stats].
+type ClientStats struct {
+	// PendingRequests is the number of requests sent to the CLI that await a
+	// response
+	PendingRequests int
+	// PendingWarnings is the number of requests sent on the current
+	// connection while at least [ClientOptions.PendingRequestsWarning]
+	// requests awaited a response
+	PendingWarnings int
+	// RequestHandlers is the number of methods the client answers for the CLI
+	RequestHandlers int
+	// Sessions is the number of sessions the client tracks
+	Sessions int
+	// EventHandlers is the number of event handlers across sessions
+	EventHandlers int
+	// ToolHandlers is the number of tool handlers across sessions
+	ToolHandlers int
+	// ToolCalls is the number of tool calls remembered across sessions to
+	// answer calls the CLI delivers again
+	ToolCalls int
+	// EarlyEvents is the number of events held for sessions the client does
+	// not track yet
+	EarlyEvents int
+	// OrderedEvents is the number of events held back by
+	// [SessionConfig.EventOrder] across sessions
+	OrderedEvents int
+	// ParseFailures is the number of messages from the CLI discarded on the
+	// current connection because they were malformed. The client skips
+	// malformed messages, and gives up on the connection after several in a
+	// row.
+	ParseFailures int
+}
+
+// Stats returns counts of the requests, handlers and queued events the client
+// holds. Requests that get no response are bounded by
+// [ClientOptions.RequestTimeout], and by [ClientOptions.MaxPendingRequests]
+// when set, so PendingRequests stays stable even if the CLI drops responses.
+//
+// Example:
+//
+//	stats := client.Stats()
+//	metrics.Gauge("copilot.pending_requests", stats.PendingRequests)
+func (c *Client) Stats() ClientStats {
+	var stats ClientStats
+	c.startStopMux.RLock()
+	if c.client != nil {
+		stats.PendingRequests = c.client.PendingRequests()
+		stats.PendingWarnings = c.client.PendingWarnings()
+		stats.RequestHandlers = c.client.RequestHandlers()
+		stats.ParseFailures = c.client.ParseFailures()
+	}
+	c.startStopMux.RUnlock()
+
+	c.earlyEvents.mu.Lock()
+	c.earlyEvents.pruneLocked(time.Now())
+	for _, held := range c.earlyEvents.events {
+		stats.EarlyEvents += len(held)
+	}
+	c.earlyEvents.mu.Unlock()
+
+	sessions := c.sessions.all()
+	stats.Sessions = len(sessions)
+	for _, session := range sessions {
+		stats.EventHandlers += session.HandlerCount()
+		session.toolHandlersM.RLock()
+		stats.ToolHandlers += len(session.toolHandlers)
+		session.toolHandlersM.RUnlock()
+		stats.ToolCalls += session.toolCalls.len()
+		if session.eventOrder != nil {
+			stats.OrderedEvents += session.eventOrder.buffered()
+		}
+	}
+	return stats
+}
@@ -0,0 +1,206 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForStats polls client.Stats until cond holds and returns the stats.
+func waitForStats(t *testing.T, client *Client, cond func(ClientStats) bool) ClientStats {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	stats := client.Stats()
+	for !cond(stats) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		stats = client.Stats()
+	}
+	return stats
+}
+
+func TestClient_Stats(t *testing.T) {
+	t.Run("counts handlers and sessions", func(t *testing.T) {
+		cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.create" {
+				var req createSessionRequest
+				json.Unmarshal(params, &req)
+				return createSessionResponse{SessionID: req.SessionID}, nil
+			}
+			return nil, nil
+		})
+		client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+		t.Cleanup(func() { client.ForceStop() })
+		if stats := client.Stats(); stats != (ClientStats{}) {
+			t.Errorf("Expected empty stats before starting, got %+v", stats)
+		}
+		if err := client.Start(t.Context()); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		noop := func(ToolInvocation) (ToolResult, error) { return ToolResult{}, nil }
+		for _, id := range []string{"s1", "s2"} {
+			_, err := client.CreateSession(t.Context(), &SessionConfig{
+				SessionID:           id,
+				OnPermissionRequest: PermissionHandler.ApproveAll,
+				Tools:               []Tool{{Name: "a", Handler: noop}, {Name: "b", Handler: noop}},
+				OnEvent:             []SessionEventHandler{func(SessionEvent) {}},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create session: %v", err)
+			}
+		}
+		cli.emitTo("not-yet-created", SessionEvent{Type: SessionIdle, Timestamp: time.Now()})
+
+		stats := waitForStats(t, client, func(stats ClientStats) bool { return stats.EarlyEvents == 1 })
+		if stats.EarlyEvents != 1 || stats.Sessions != 2 || stats.ToolHandlers != 4 || stats.EventHandlers != 2 || stats.RequestHandlers == 0 || stats.PendingRequests != 0 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("stays bounded when responses are dropped", func(t *testing.T) {
+		release := make(chan struct{})
+		t.Cleanup(func() { close(release) })
+		var received atomic.Int64
+		cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+			// Never answer every tenth request
+			if received.Add(1)%10 == 0 {
+				<-release
+			}
+			return GetStatusResponse{Version: "1.0.0"}, nil
+		})
+		var timeouts atomic.Int64
+		client := NewClient(&ClientOptions{
+			CLIUrl:         cli.addr(),
+			RequestTimeout: 20 * time.Millisecond,
+			OnRPCCall: func(call RPCCall) {
+				if errors.Is(call.Err, ErrRequestTimeout) {
+					timeouts.Add(1)
+				}
+			},
+		})
+		t.Cleanup(func() { client.ForceStop() })
+		if err := client.Start(t.Context()); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+
+		cycle := func(requests int) {
+			var wg sync.WaitGroup
+			for range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range requests / 8 {
+						if _, err := client.GetStatus(t.Context()); err != nil && !errors.Is(err, ErrRequestTimeout) {
+							t.Errorf("Unexpected error: %v", err)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+		}
+		heap := func() uint64 {
+			runtime.GC()
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			return m.HeapAlloc
+		}
+
+		cycle(800)
+		before := heap()
+		cycle(800)
+		after := heap()
+
+		if got := client.Stats().PendingRequests; got != 0 {
+			t.Errorf("Expected no pending requests, got %d", got)
+		}
+		if timeouts.Load() < 100 {
+			t.Errorf("Expected dropped responses to time out, got %d timeouts", timeouts.Load())
+		}
+		if after > before && after-before > 1<<20 {
+			t.Errorf("Expected stable memory, heap grew from %d to %d bytes", before, after)
+		}
+	})
+
+	t.Run("fails requests over the pending limit", func(t *testing.T) {
+		release := make(chan struct{})
+		t.Cleanup(func() { close(release) })
+		cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+			<-release
+			return nil, nil
+		})
+		var rejected atomic.Int64
+		client := NewClient(&ClientOptions{
+			CLIUrl:             cli.addr(),
+			MaxPendingRequests: 3,
+			OnRPCCall: func(call RPCCall) {
+				if errors.Is(call.Err, ErrTooManyPendingRequests) {
+					rejected.Add(1)
+				}
+			},
+		})
+		t.Cleanup(func() { client.ForceStop() })
+		if err := client.Start(t.Context()); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+
+		for range 3 {
+			go client.GetStatus(t.Context())
+		}
+		waitForStats(t, client, func(stats ClientStats) bool { return stats.PendingRequests == 3 })
+		if _, err := client.GetStatus(t.Context()); !errors.Is(err, ErrTooManyPendingRequests) {
+			t.Errorf("Expected ErrTooManyPendingRequests, got %v", err)
+		}
+		if rejected.Load() != 1 {
+			t.Errorf("Expected the rejected call to be reported, got %d", rejected.Load())
+		}
+	})
+
+	t.Run("reports requests over the pending warning without failing them", func(t *testing.T) {
+		release := make(chan struct{})
+		cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "status.get" {
+				<-release
+			}
+			return nil, nil
+		})
+		var flagged atomic.Int64
+		client := NewClient(&ClientOptions{
+			CLIUrl:                 cli.addr(),
+			PendingRequestsWarning: 3,
+			OnRPCCall: func(call RPCCall) {
+				if call.OverPendingWarning {
+					flagged.Add(1)
+				}
+			},
+		})
+		t.Cleanup(func() { client.ForceStop() })
+		if err := client.Start(t.Context()); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+
+		for range 3 {
+			go client.GetStatus(t.Context())
+		}
+		waitForStats(t, client, func(stats ClientStats) bool { return stats.PendingRequests == 3 })
+		if _, err := client.Ping(t.Context(), ""); err != nil {
+			t.Errorf("Expected the request over the warning to be sent, got %v", err)
+		}
+		close(release)
+		waitForStats(t, client, func(stats ClientStats) bool { return stats.PendingRequests == 0 })
+		if got := client.Stats().PendingWarnings; got != 1 {
+			t.Errorf("Expected 1 pending warning, got %d", got)
+		}
+		if flagged.Load() != 1 {
+			t.Errorf("Expected the request over the warning to be reported, got %d", flagged.Load())
+		}
+	})
+
+	t.Run("does not cap pending requests by default", func(t *testing.T) {
+		if got := NewClient(nil).options.MaxPendingRequests; got != 0 {
+			t.Errorf("Expected no pending request cap by default, got %d", got)
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sync"
+)
+
+// StreamEventKind identifies what a [StreamEvent] reports.
+type StreamEventKind string
+
+const (
+	// StreamContent is a chunk of assistant content
+	StreamContent StreamEventKind = "content"
+	// StreamToolStart reports that a tool call started
+	StreamToolStart StreamEventKind = "tool_start"
+	// StreamToolResult reports that a tool call completed
+	StreamToolResult StreamEventKind = "tool_result"
+)
+
+// StreamEvent is an event of a turn streamed by [Session.Stream].
+type StreamEvent struct {
+	// Kind is what the event reports
+	Kind StreamEventKind
+	// MessageID identifies the assistant message a StreamContent chunk
+	// belongs to
+	MessageID string
+	// Content is the text of a StreamContent chunk, or the result of a
+	// StreamToolResult, or its error message if the tool failed
+	Content string
+	// ToolCallID and ToolName identify the tool call of StreamToolStart and
+	// StreamToolResult events
+	ToolCallID string
+	ToolName   string
+	// Success reports whether the tool call of a StreamToolResult succeeded
+	Success bool
+	// Event is the session event this event was derived from
+	Event SessionEvent
+}
+
+// streamEventTypes are the session events [Session.Stream] listens to.
+var streamEventTypes = []SessionEventType{
+	AssistantMessageDelta,
+	AssistantMessage,
+	ToolExecutionStart,
+	ToolExecutionComplete,
+	SessionIdle,
+	SessionError,
+	Abort,
+}
+
+// Stream sends a message and returns an iterator over the assistant content
+// chunks, tool call starts and tool results of the turn, ending when the
+// session becomes idle.
+//
+// Content arrives in chunks if the session was created with
+// [SessionConfig.Streaming], and as whole messages otherwise. If the send
+// fails, the session reports an error, the turn is aborted or ctx is done,
+// the iterator yields the error, matching the errors of
+// [Session.SendAndCollect], and stops. Unlike SendAndCollect, Stream has no
+// default timeout; use ctx to bound the turn.
+//
+// The events are subscribed to only while the iterator runs, and
+// unsubscribed when the turn ends or the loop stops early.
+//
+// Example:
+//
+//	for event, err := range session.Stream(ctx, copilot.MessageOptions{Prompt: "Explain this repo"}) {
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    switch event.Kind {
+//	    case copilot.StreamContent:
+//	        fmt.Print(event.Content)
+//	    case copilot.StreamToolStart:
+//	        fmt.Printf("\n[running %s]\n", event.ToolName)
+//	    }
+//	}
+func (s *Session) Stream(ctx context.Context, options MessageOptions) iter.Seq2[StreamEvent, error] {
+	return func(yield func(StreamEvent, error) bool) {
+		// Events are queued rather than handed over directly, so a consumer
+		// that is slow to drain does not hold up the session's other handlers
+		var mu sync.Mutex
+		var queue []SessionEvent
+		ready := make(chan struct{}, 1)
+		unsubscribe := s.OnTypes(streamEventTypes, func(event SessionEvent) {
+			mu.Lock()
+			queue = append(queue, event)
+			mu.Unlock()
+			select {
+			case ready <- struct{}{}:
+			default:
+			}
+		})
+		defer unsubscribe()
+
+		// Only aborts acknowledged after the send started concern this turn
+		aborted := s.aborts.wait()
+		messageID, _, err := s.send(ctx, options)
+		if err != nil {
+			yield(StreamEvent{}, err)
+			return
+		}
+
+		var events []SessionEvent
+		streamed := make(map[string]bool) // messages whose content arrived in chunks
+		abortedError := func(reason string) error {
+			return &AbortedError{
+				MessageID:      messageID,
+				Reason:         reason,
+				PartialContent: partialContent(events),
+				Turn:           &TurnResult{MessageID: messageID, Events: events},
+			}
+		}
+		for {
+			select {
+			case <-ready:
+			case <-aborted:
+				yield(StreamEvent{}, abortedError(""))
+				return
+			case <-ctx.Done():
+				yield(StreamEvent{}, fmt.Errorf("waiting for session.idle: %w", ctx.Err()))
+				return
+			}
+			mu.Lock()
+			pending := queue
+			queue = nil
+			mu.Unlock()
+
+			for _, event := range pending {
+				events = append(events, event)
+				switch event.Type {
+				case SessionIdle:
+					return
+				case Abort:
+					yield(StreamEvent{}, abortedError(derefString(event.Data.Reason)))
+					return
+				case SessionError:
+					errMsg := "session error"
+					if event.Data.Message != nil {
+						errMsg = *event.Data.Message
+					}
+					yield(StreamEvent{}, fmt.Errorf("session error: %s", errMsg))
+					return
+				}
+				if streamEvent, ok := toStreamEvent(event, streamed); ok && !yield(streamEvent, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// toStreamEvent converts a session event to a stream event. Whole assistant
+// messages are only converted if none of their content was streamed, which
+// streamed records.
+func toStreamEvent(event SessionEvent, streamed map[string]bool) (StreamEvent, bool) {
+	data := event.Data
+	switch event.Type {
+	case AssistantMessageDelta:
+		if data.DeltaContent == nil || *data.DeltaContent == "" {
+			return StreamEvent{}, false
+		}
+		messageID := derefString(data.MessageID)
+		streamed[messageID] = true
+		return StreamEvent{Kind: StreamContent, MessageID: messageID, Content: *data.DeltaContent, Event: event}, true
+	case AssistantMessage:
+		messageID := derefString(data.MessageID)
+		if streamed[messageID] || data.Content == nil || *data.Content == "" {
+			return StreamEvent{}, false
+		}
+		return StreamEvent{Kind: StreamContent, MessageID: messageID, Content: *data.Content, Event: event}, true
+	case ToolExecutionStart:
+		return StreamEvent{Kind: StreamToolStart, ToolCallID: derefString(data.ToolCallID), ToolName: derefString(data.ToolName), Event: event}, true
+	case ToolExecutionComplete:
+		result := StreamEvent{Kind: StreamToolResult, ToolCallID: derefString(data.ToolCallID), ToolName: derefString(data.ToolName), Event: event}
+		result.Success = data.Success != nil && *data.Success
+		switch {
+		case data.Result != nil:
+			result.Content = data.Result.Content
+		case data.Error != nil && data.Error.ErrorClass != nil:
+			result.Content = data.Error.ErrorClass.Message
+		case data.Error != nil && data.Error.String != nil:
+			result.Content = *data.Error.String
+		}
+		return result, true
+	}
+	return StreamEvent{}, false
+}
@@ -0,0 +1,130 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSession_Stream(t *testing.T) {
+	delta := func(messageID, content string) SessionEvent {
+		return SessionEvent{Type: AssistantMessageDelta, Data: Data{MessageID: String(messageID), DeltaContent: String(content)}}
+	}
+	newStreamingSession := func(t *testing.T, events ...SessionEvent) *Session {
+		var server *fakeServer
+		session, server := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.send" {
+				go func() {
+					for _, event := range events {
+						server.emit(event)
+					}
+				}()
+				return sessionSendResponse{MessageID: "msg-1"}, nil
+			}
+			return map[string]any{}, nil
+		})
+		return session
+	}
+
+	t.Run("yields content and tool events until idle", func(t *testing.T) {
+		session := newStreamingSession(t,
+			delta("a1", "Let me "),
+			delta("a1", "check."),
+			SessionEvent{Type: AssistantMessage, Data: Data{MessageID: String("a1"), Content: String("Let me check.")}},
+			SessionEvent{Type: ToolExecutionStart, Data: Data{ToolCallID: String("t1"), ToolName: String("grep")}},
+			SessionEvent{Type: ToolExecutionComplete, Data: Data{ToolCallID: String("t1"), Success: Bool(true), Result: &Result{Content: "3 matches"}}},
+			SessionEvent{Type: AssistantMessage, Data: Data{MessageID: String("a2"), Content: String("Found it.")}},
+			SessionEvent{Type: SessionIdle},
+		)
+
+		var kinds []StreamEventKind
+		var content string
+		for event, err := range session.Stream(t.Context(), MessageOptions{Prompt: "Hi"}) {
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			kinds = append(kinds, event.Kind)
+			switch event.Kind {
+			case StreamContent:
+				content += event.Content
+			case StreamToolResult:
+				if event.ToolCallID != "t1" || !event.Success || event.Content != "3 matches" {
+					t.Errorf("Unexpected tool result %+v", event)
+				}
+			}
+		}
+
+		want := []StreamEventKind{StreamContent, StreamContent, StreamToolStart, StreamToolResult, StreamContent}
+		if len(kinds) != len(want) {
+			t.Fatalf("Expected %v, got %v", want, kinds)
+		}
+		for i := range want {
+			if kinds[i] != want[i] {
+				t.Fatalf("Expected %v, got %v", want, kinds)
+			}
+		}
+		if content != "Let me check.Found it." {
+			t.Errorf("Expected each message's content once, got %q", content)
+		}
+		if session.HandlerCount() != 0 {
+			t.Errorf("Expected the stream to unsubscribe, %d handlers left", session.HandlerCount())
+		}
+	})
+
+	t.Run("yields session errors", func(t *testing.T) {
+		session := newStreamingSession(t,
+			delta("a1", "Partial"),
+			SessionEvent{Type: SessionError, Data: Data{Message: String("model overloaded")}},
+		)
+		var chunks int
+		var streamErr error
+		for event, err := range session.Stream(t.Context(), MessageOptions{Prompt: "Hi"}) {
+			if err != nil {
+				streamErr = err
+				continue
+			}
+			if event.Kind == StreamContent {
+				chunks++
+			}
+		}
+		if chunks != 1 || streamErr == nil || streamErr.Error() != "session error: model overloaded" {
+			t.Errorf("Expected a chunk then the session error, got %d chunks and %v", chunks, streamErr)
+		}
+	})
+
+	t.Run("yields aborts with the partial content", func(t *testing.T) {
+		session := newStreamingSession(t,
+			delta("a1", "Partial"),
+			SessionEvent{Type: Abort, Data: Data{Reason: String("cancelled")}},
+		)
+		var streamErr error
+		for _, err := range session.Stream(t.Context(), MessageOptions{Prompt: "Hi"}) {
+			streamErr = err
+		}
+		var abortedErr *AbortedError
+		if !errors.As(streamErr, &abortedErr) || abortedErr.PartialContent != "Partial" || abortedErr.Reason != "cancelled" {
+			t.Errorf("Expected an AbortedError, got %v", streamErr)
+		}
+	})
+
+	t.Run("unsubscribes when the loop stops early", func(t *testing.T) {
+		session := newStreamingSession(t, delta("a1", "one"), delta("a1", "two"), SessionEvent{Type: SessionIdle})
+		for range session.Stream(t.Context(), MessageOptions{Prompt: "Hi"}) {
+			break
+		}
+		if session.HandlerCount() != 0 {
+			t.Errorf("Expected the stream to unsubscribe, %d handlers left", session.HandlerCount())
+		}
+	})
+
+	t.Run("yields send errors", func(t *testing.T) {
+		session, _ := newTestSession(t, nil)
+		var streamErr error
+		for _, err := range session.Stream(t.Context(), MessageOptions{Prompt: "Hi", Initiator: "not valid!"}) {
+			streamErr = err
+		}
+		if streamErr == nil || session.HandlerCount() != 0 {
+			t.Errorf("Expected the send error and no handlers left, got %v", streamErr)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+// SessionSummaryChanged is emitted when the CLI generates a new summary of
+// the conversation. [SessionEvent.AsTitleChanged] also reports the summary
+// carried by session.title_changed events.
+const SessionSummaryChanged SessionEventType = "session.summary_changed"
+
+// summarizePrompt asks a forked session to summarize a transcript.
+const summarizePrompt = "Summarize the following conversation in a few sentences. " +
+	"Reply with the summary only.\n\n"
+
+// SessionTitleData is the payload of a session.title_changed or
+// session.summary_changed event.
+type SessionTitleData struct {
+	// Title is the conversation's title, or empty if the event has none
+	Title string
+	// Summary is the conversation's summary, or empty if the event has none
+	Summary string
+}
+
+// AsTitleChanged returns the title and summary reported by a
+// session.title_changed or session.summary_changed event. The second return
+// value is false for any other event type.
+//
+// Example:
+//
+//	session.On(func(event copilot.SessionEvent) {
+//	    if data, ok := event.AsTitleChanged(); ok && data.Title != "" {
+//	        fmt.Printf("Conversation title: %s\n", data.Title)
+//	    }
+//	})
+func (e SessionEvent) AsTitleChanged() (*SessionTitleData, bool) {
+	if e.Type != SessionTitleChanged && e.Type != SessionSummaryChanged {
+		return nil, false
+	}
+	return &SessionTitleData{
+		Title:   derefString(e.Data.Title),
+		Summary: derefString(e.Data.Summary),
+	}, true
+}
+
+// sessionSummarizeRequest is the request for session.summarize
+type sessionSummarizeRequest struct {
+	SessionID string `json:"sessionId"`
+}
+
+// sessionSummarizeResponse is the response from session.summarize
+type sessionSummarizeResponse struct {
+	Summary string `json:"summary"`
+	Title   string `json:"title,omitempty"`
+}
+
+// summaryState tracks the latest title and summary of a session.
+type summaryState struct {
+	mu      sync.Mutex
+	title   string
+	summary string
+}
+
+// observeEvent records the title and summary of title and summary events.
+func (s *summaryState) observeEvent(event SessionEvent) {
+	if data, ok := event.AsTitleChanged(); ok {
+		s.record(data.Title, data.Summary)
+	}
+}
+
+// record stores a title and summary, keeping the previous value of either
+// one that is empty.
+func (s *summaryState) record(title, summary string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if title != "" {
+		s.title = title
+	}
+	if summary != "" {
+		s.summary = summary
+	}
+}
+
+// Summary returns the latest title and summary of the conversation, from the
+// session's events or [Session.Summarize]. Either may be empty.
+func (s *Session) Summary() SessionTitleData {
+	s.summary.mu.Lock()
+	defer s.summary.mu.Unlock()
+	return SessionTitleData{Title: s.summary.title, Summary: s.summary.summary}
+}
+
+// Summarize asks the CLI for a summary of the conversation so far.
+//
+// CLIs without a summarize RPC are handled by sending the transcript, with a
+// request to summarize it, to a temporary session that is deleted afterwards,
+// so the summarization turn does not appear in this session's history.
+//
+// The summary is also returned by later calls to [Session.Summary] and
+// included in [Client.ListSessions].
+//
+// Example:
+//
+//	summary, err := session.Summarize(ctx)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(summary)
+func (s *Session) Summarize(ctx context.Context) (string, error) {
+	if err := s.checkNotDestroyed(); err != nil {
+		return "", err
+	}
+
+	result, err := s.rpcClient().Request("session.summarize", sessionSummarizeRequest{SessionID: s.SessionID})
+	if err != nil {
+		if !isMethodNotFound(err) {
+			return "", fmt.Errorf("failed to summarize session: %w", err)
+		}
+		return s.summarizeInFork(ctx)
+	}
+
+	var response sessionSummarizeResponse
+	if err := json.Unmarshal(result, &response); err != nil {
+		return "", fmt.Errorf("failed to unmarshal summarize response: %w", err)
+	}
+	s.summary.record(response.Title, response.Summary)
+	return response.Summary, nil
+}
+
+// summarizeInFork summarizes the session's transcript in a temporary session.
+func (s *Session) summarizeInFork(ctx context.Context) (string, error) {
+	if s.fork == nil {
+		return "", errors.New("failed to summarize session: the CLI has no summarize RPC and the session cannot be forked")
+	}
+	events, err := s.GetMessages(ctx)
+	if err != nil {
+		return "", err
+	}
+	transcript := renderMarkdown(events)
+	if transcript == "" {
+		return "", nil
+	}
+
+	fork, cleanup, err := s.fork(ctx, s.Config().Model)
+	if err != nil {
+		return "", fmt.Errorf("failed to fork session for summary: %w", err)
+	}
+	defer cleanup()
+
+	reply, err := fork.SendAndWait(ctx, MessageOptions{Prompt: summarizePrompt + transcript})
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize session: %w", err)
+	}
+	if reply == nil || reply.Data.Content == nil {
+		return "", errors.New("failed to summarize session: no summary was returned")
+	}
+	summary := strings.TrimSpace(*reply.Data.Content)
+	s.summary.record("", summary)
+	return summary, nil
+}
+
+// forkSession creates a temporary session, on the same model, for work that
+// must not appear in another session's history. Its tools are denied
+// permission. The returned function destroys and deletes it.
+func (c *Client) forkSession(ctx context.Context, model string) (*Session, func(), error) {
+	fork, err := c.CreateSession(ctx, &SessionConfig{
+		Model: model,
+		OnPermissionRequest: func(PermissionRequest, PermissionInvocation) (PermissionRequestResult, error) {
+			return DeniedNoApprovalRule(), nil
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		fork.Destroy()
+		c.DeleteSession(context.Background(), fork.SessionID)
+	}
+	return fork, cleanup, nil
+}
+
+// latestSummary returns the title and summary a tracked session has seen,
+// if any.
+func (c *Client) latestSummary(sessionID string) SessionTitleData {
+	session := c.sessions.get(sessionID)
+	if session == nil {
+		return SessionTitleData{}
+	}
+	return session.Summary()
+}
+
+// isMethodNotFound reports whether err is the server's answer to a method it
+// does not implement.
+func isMethodNotFound(err error) bool {
+	var rpcErr *jsonrpc2.Error
+	return errors.As(err, &rpcErr) && rpcErr.Code == -32601
+}
@@ -0,0 +1,127 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/github/copilot-sdk/go/internal/jsonrpc2"
+)
+
+func TestSession_Summarize(t *testing.T) {
+	t.Run("uses the summarize RPC", func(t *testing.T) {
+		cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+			switch method {
+			case "session.create":
+				return createSessionResponse{SessionID: "s1"}, nil
+			case "session.summarize":
+				return sessionSummarizeResponse{Summary: "Fixed the parser bug.", Title: "Parser fix"}, nil
+			case "session.list":
+				return listSessionsResponse{Sessions: []SessionMetadata{{SessionID: "s1"}, {SessionID: "other", Summary: String("Old work")}}}, nil
+			}
+			return nil, nil
+		})
+		client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+		t.Cleanup(func() { client.ForceStop() })
+		session, err := client.CreateSession(t.Context(), &SessionConfig{SessionID: "s1", OnPermissionRequest: PermissionHandler.ApproveAll})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+
+		summary, err := session.Summarize(t.Context())
+		if err != nil || summary != "Fixed the parser bug." {
+			t.Fatalf("Expected the RPC's summary, got %q, %v", summary, err)
+		}
+		if got := session.Summary(); got.Title != "Parser fix" || got.Summary != summary {
+			t.Errorf("Expected the summary to be recorded, got %+v", got)
+		}
+
+		sessions, err := client.ListSessions(t.Context(), nil)
+		if err != nil {
+			t.Fatalf("ListSessions failed: %v", err)
+		}
+		if sessions[0].Summary == nil || *sessions[0].Summary != summary {
+			t.Errorf("Expected the latest summary in the listing, got %v", sessions[0].Summary)
+		}
+		if sessions[1].Summary == nil || *sessions[1].Summary != "Old work" {
+			t.Errorf("Expected untracked sessions to keep the CLI's summary, got %v", sessions[1].Summary)
+		}
+	})
+
+	t.Run("falls back to a forked session", func(t *testing.T) {
+		history := []SessionEvent{
+			{Type: UserMessage, Data: Data{Content: String("Why does the parser crash?")}},
+			{Type: AssistantMessage, Data: Data{Content: String("It reads past the end of the buffer.")}},
+		}
+		var mainCalls []string
+		var mu sync.Mutex
+		session, _ := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			mu.Lock()
+			mainCalls = append(mainCalls, method)
+			mu.Unlock()
+			switch method {
+			case "session.summarize":
+				return nil, &jsonrpc2.Error{Code: -32601, Message: "Method not found: session.summarize"}
+			case "session.getMessages":
+				return sessionGetMessagesResponse{Events: history}, nil
+			}
+			return nil, nil
+		})
+
+		var prompt string
+		var forkServer *fakeServer
+		var fork *Session
+		fork, forkServer = newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.send" {
+				var req sessionSendRequest
+				json.Unmarshal(params, &req)
+				prompt = req.Prompt
+				go func() {
+					forkServer.emit(SessionEvent{Type: AssistantMessage, Data: Data{Content: String(" Parser crash traced to a buffer overrun. ")}})
+					forkServer.emit(SessionEvent{Type: SessionIdle})
+				}()
+				return sessionSendResponse{MessageID: "f1"}, nil
+			}
+			return nil, nil
+		})
+		cleaned := false
+		session.fork = func(ctx context.Context, model string) (*Session, func(), error) {
+			return fork, func() { cleaned = true }, nil
+		}
+
+		summary, err := session.Summarize(t.Context())
+		if err != nil || summary != "Parser crash traced to a buffer overrun." {
+			t.Fatalf("Expected the fork's summary, got %q, %v", summary, err)
+		}
+		if !strings.Contains(prompt, "Why does the parser crash?") || !strings.Contains(prompt, "past the end of the buffer") {
+			t.Errorf("Expected the transcript in the fork's prompt, got %q", prompt)
+		}
+		if !cleaned {
+			t.Error("Expected the fork to be cleaned up")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, method := range mainCalls {
+			if method == "session.send" {
+				t.Error("Expected no turn in the original session")
+			}
+		}
+		if session.Summary().Summary != summary {
+			t.Errorf("Expected the summary to be recorded, got %+v", session.Summary())
+		}
+	})
+
+	t.Run("records titles and summaries from events", func(t *testing.T) {
+		session := newSession("s1", nil, "")
+		session.dispatchEvent(SessionEvent{Type: SessionTitleChanged, Data: Data{Title: String("Parser fix")}})
+		session.dispatchEvent(SessionEvent{Type: SessionSummaryChanged, Data: Data{Summary: String("Found the overrun.")}})
+		if got := session.Summary(); got.Title != "Parser fix" || got.Summary != "Found the overrun." {
+			t.Errorf("Unexpected summary: %+v", got)
+		}
+		if _, ok := (SessionEvent{Type: SessionIdle}).AsTitleChanged(); ok {
+			t.Error("Expected other events to have no title payload")
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrTemplateVariables is returned by [PromptTemplate.Render] and
+// [PromptTemplate.Bind] when the variables don't match the template's
+// placeholders.
+var ErrTemplateVariables = errors.New("template variables do not match")
+
+// PromptTemplate is a prompt with named placeholders, written as {name}.
+// Names start with a letter or underscore, followed by letters, digits, or
+// underscores. Literal braces are written doubled: {{ renders as { and }}
+// as }. Templates are safe for concurrent use.
+type PromptTemplate struct {
+	name      string
+	parts     []templatePart
+	variables []string
+}
+
+// templatePart is either literal text or, if variable is set, a placeholder.
+type templatePart struct {
+	text     string
+	variable string
+}
+
+// RenderedTemplate is a prompt rendered from a [PromptTemplate], along with
+// the template name and variables used. Set it as [MessageOptions.Template]
+// to keep them with the message's [MessageRef].
+type RenderedTemplate struct {
+	// Name is the name of the template
+	Name string `json:"name"`
+	// Variables are the values substituted into the template
+	Variables map[string]string `json:"variables"`
+	// Prompt is the rendered text
+	Prompt string `json:"-"`
+}
+
+// ParsePromptTemplate parses a prompt template. name identifies the template
+// in message metadata.
+//
+// Example:
+//
+//	tmpl, err := copilot.ParsePromptTemplate("review", "Review {file} for {concern}. Reply as {{\"ok\": bool}}.")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func ParsePromptTemplate(name, text string) (*PromptTemplate, error) {
+	t := &PromptTemplate{name: name}
+	seen := make(map[string]bool)
+	var literal strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '{' && strings.HasPrefix(text[i:], "{{"):
+			literal.WriteByte('{')
+			i++
+		case c == '}' && strings.HasPrefix(text[i:], "}}"):
+			literal.WriteByte('}')
+			i++
+		case c == '}':
+			return nil, fmt.Errorf("failed to parse template %q: unmatched '}' at offset %d; write '}}' for a literal brace", name, i)
+		case c == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("failed to parse template %q: unclosed '{' at offset %d; write '{{' for a literal brace", name, i)
+			}
+			variable := text[i+1 : i+end]
+			if !isTemplateVariableName(variable) {
+				return nil, fmt.Errorf("failed to parse template %q: invalid placeholder %q at offset %d", name, "{"+variable+"}", i)
+			}
+			if literal.Len() > 0 {
+				t.parts = append(t.parts, templatePart{text: literal.String()})
+				literal.Reset()
+			}
+			t.parts = append(t.parts, templatePart{variable: variable})
+			if !seen[variable] {
+				seen[variable] = true
+				t.variables = append(t.variables, variable)
+			}
+			i += end
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	if literal.Len() > 0 {
+		t.parts = append(t.parts, templatePart{text: literal.String()})
+	}
+	sort.Strings(t.variables)
+	return t, nil
+}
+
+// MustParsePromptTemplate is like [ParsePromptTemplate] but panics if the
+// template cannot be parsed. It is intended for templates defined in
+// package-level variables.
+func MustParsePromptTemplate(name, text string) *PromptTemplate {
+	t, err := ParsePromptTemplate(name, text)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Name returns the name of the template.
+func (t *PromptTemplate) Name() string {
+	return t.name
+}
+
+// Variables returns the names of the template's placeholders, sorted.
+func (t *PromptTemplate) Variables() []string {
+	return append([]string(nil), t.variables...)
+}
+
+// Render substitutes vars into the template. It returns an error wrapping
+// [ErrTemplateVariables] if a placeholder has no value or a value has no
+// placeholder. Values are inserted as is; braces in them are not
+// interpreted.
+//
+// Example:
+//
+//	prompt, err := tmpl.Render(map[string]string{"file": "main.go", "concern": "races"})
+func (t *PromptTemplate) Render(vars map[string]string) (string, error) {
+	var missing, extra []string
+	for _, variable := range t.variables {
+		if _, ok := vars[variable]; !ok {
+			missing = append(missing, variable)
+		}
+	}
+	for variable := range vars {
+		if !slices.Contains(t.variables, variable) {
+			extra = append(extra, variable)
+		}
+	}
+	if len(missing) > 0 || len(extra) > 0 {
+		sort.Strings(extra)
+		var problems []string
+		if len(missing) > 0 {
+			problems = append(problems, "missing "+strings.Join(missing, ", "))
+		}
+		if len(extra) > 0 {
+			problems = append(problems, "unknown "+strings.Join(extra, ", "))
+		}
+		return "", fmt.Errorf("failed to render template %q: %w: %s", t.name, ErrTemplateVariables, strings.Join(problems, "; "))
+	}
+
+	var b strings.Builder
+	for _, part := range t.parts {
+		if part.variable != "" {
+			b.WriteString(vars[part.variable])
+		} else {
+			b.WriteString(part.text)
+		}
+	}
+	return b.String(), nil
+}
+
+// Bind renders the template like [PromptTemplate.Render] and returns the
+// result with the template name and variables, for use as
+// [MessageOptions.Template].
+//
+// Example:
+//
+//	rendered, err := tmpl.Bind(map[string]string{"file": "main.go", "concern": "races"})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	_, err = session.Send(ctx, copilot.MessageOptions{Template: rendered})
+func (t *PromptTemplate) Bind(vars map[string]string) (*RenderedTemplate, error) {
+	prompt, err := t.Render(vars)
+	if err != nil {
+		return nil, err
+	}
+	variables := make(map[string]string, len(vars))
+	for k, v := range vars {
+		variables[k] = v
+	}
+	return &RenderedTemplate{Name: t.name, Variables: variables, Prompt: prompt}, nil
+}
+
+// isTemplateVariableName reports whether s is a valid placeholder name.
+func isTemplateVariableName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePromptTemplate(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		vars      map[string]string
+		want      string
+		variables []string
+	}{
+		{
+			name:      "substitutes placeholders",
+			text:      "Review {file} for {concern}.",
+			vars:      map[string]string{"file": "main.go", "concern": "races"},
+			want:      "Review main.go for races.",
+			variables: []string{"concern", "file"},
+		},
+		{
+			name:      "repeated placeholders",
+			text:      "{a}-{b}-{a}",
+			vars:      map[string]string{"a": "x", "b": "y"},
+			want:      "x-y-x",
+			variables: []string{"a", "b"},
+		},
+		{
+			name:      "doubled braces are literal",
+			text:      `Reply as {{"ok": {value}}} or {{name}}`,
+			vars:      map[string]string{"value": "true"},
+			want:      `Reply as {"ok": true} or {name}`,
+			variables: []string{"value"},
+		},
+		{
+			name:      "braces in values are not interpreted",
+			text:      "Say {x}",
+			vars:      map[string]string{"x": "{y} }}"},
+			want:      "Say {y} }}",
+			variables: []string{"x"},
+		},
+		{
+			name: "no placeholders",
+			text: "Hello, world",
+			vars: map[string]string{},
+			want: "Hello, world",
+		},
+		{
+			name:      "names with underscores and digits",
+			text:      "{_id}{file_2}",
+			vars:      map[string]string{"_id": "1", "file_2": "b"},
+			want:      "1b",
+			variables: []string{"_id", "file_2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParsePromptTemplate("test", tt.text)
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %v", err)
+			}
+			if got := tmpl.Variables(); !reflect.DeepEqual(got, tt.variables) {
+				t.Errorf("Expected variables %v, got %v", tt.variables, got)
+			}
+			got, err := tmpl.Render(tt.vars)
+			if err != nil {
+				t.Fatalf("Unexpected render error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("rejects malformed templates", func(t *testing.T) {
+		for _, text := range []string{"{", "a { b", "}", "a } b", "{}", "{1a}", "{a b}", "{a-b}", "{{a}"} {
+			if _, err := ParsePromptTemplate("bad", text); err == nil {
+				t.Errorf("Expected an error parsing %q", text)
+			}
+		}
+	})
+
+	t.Run("MustParsePromptTemplate panics on malformed templates", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a panic")
+			}
+		}()
+		MustParsePromptTemplate("bad", "{")
+	})
+}
+
+func TestPromptTemplate_Render(t *testing.T) {
+	tmpl := MustParsePromptTemplate("review", "Review {file} for {concern}.")
+
+	tests := []struct {
+		name string
+		vars map[string]string
+		want string
+	}{
+		{"missing variables", map[string]string{"file": "a.go"}, `failed to render template "review": template variables do not match: missing concern`},
+		{"extra variables", map[string]string{"file": "a.go", "concern": "x", "z": "1", "y": "2"}, `failed to render template "review": template variables do not match: unknown y, z`},
+		{"missing and extra", map[string]string{"flie": "a.go"}, `failed to render template "review": template variables do not match: missing concern, file; unknown flie`},
+		{"nil map", nil, `failed to render template "review": template variables do not match: missing concern, file`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tmpl.Render(tt.vars)
+			if !errors.Is(err, ErrTemplateVariables) {
+				t.Fatalf("Expected ErrTemplateVariables, got %v", err)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, err.Error())
+			}
+		})
+	}
+
+	t.Run("empty values are allowed", func(t *testing.T) {
+		got, err := tmpl.Render(map[string]string{"file": "", "concern": ""})
+		if err != nil || got != "Review  for ." {
+			t.Errorf("Expected empty substitutions, got %q, %v", got, err)
+		}
+	})
+}
+
+func TestSession_SendTemplate(t *testing.T) {
+	var sent json.RawMessage
+	session, _ := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+		if method == "session.send" {
+			sent = params
+			return sessionSendResponse{MessageID: "msg"}, nil
+		}
+		return nil, nil
+	})
+
+	vars := map[string]string{"file": "main.go"}
+	rendered, err := MustParsePromptTemplate("review", "Review {file}").Bind(vars)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	vars["file"] = "changed.go"
+
+	if _, err := session.Send(t.Context(), MessageOptions{Template: rendered}); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	var req map[string]any
+	if err := json.Unmarshal(sent, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req["prompt"] != "Review main.go" {
+		t.Errorf("Expected the rendered prompt to be sent, got %q", req["prompt"])
+	}
+	if _, ok := req["template"]; ok {
+		t.Errorf("Expected the template to stay in the SDK, got %v", req["template"])
+	}
+
+	ref, ok := session.MessageRef("msg")
+	if !ok || ref.Template == nil {
+		t.Fatalf("Expected the message reference to carry the template, got %+v", ref)
+	}
+	if ref.Template.Name != "review" || !reflect.DeepEqual(ref.Template.Variables, map[string]string{"file": "main.go"}) {
+		t.Errorf("Expected template metadata, got %+v", ref.Template)
+	}
+}
@@ -0,0 +1,9 @@
+package copilot
+
+import "github.com/github/copilot-sdk/go/internal/testhook"
+
+func init() {
+	testhook.DispatchEvent = func(session, event any) {
+		session.(*Session).dispatchEvent(event.(SessionEvent))
+	}
+}
@@ -0,0 +1,347 @@
+package copilot
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRecordedHandlerSpans bounds the handler spans a session keeps for
+// assembling turn timelines.
+const maxRecordedHandlerSpans = 256
+
+// TimelineSpanKind is what a session was doing during a [TimelineSpan].
+type TimelineSpanKind string
+
+const (
+	// TimelineWaiting is the time from sending the message to the first
+	// event of the turn after the CLI's echo of the prompt.
+	TimelineWaiting TimelineSpanKind = "waiting"
+	// TimelineThinking is time the model spent working on its next step:
+	// the turn's time not covered by tools, streaming, or waits for
+	// handlers.
+	TimelineThinking TimelineSpanKind = "thinking"
+	// TimelineToolQueued is the time from the start of an SDK tool's
+	// execution to its handler starting, such as behind another tool with
+	// [SessionConfig.SequentialTools].
+	TimelineToolQueued TimelineSpanKind = "tool_queued"
+	// TimelineToolRunning is the execution of a tool, from its handler
+	// starting, or its start event for tools the SDK does not run, to its
+	// completion event.
+	TimelineToolRunning TimelineSpanKind = "tool_running"
+	// TimelinePermission is a wait for a permission decision.
+	TimelinePermission TimelineSpanKind = "permission"
+	// TimelineUserInput is a wait for the answer to a question.
+	TimelineUserInput TimelineSpanKind = "user_input"
+	// TimelineHook is a call of a hook handler.
+	TimelineHook TimelineSpanKind = "hook"
+	// TimelineStreaming is the streaming of an assistant message, from its
+	// first delta to the complete message.
+	TimelineStreaming TimelineSpanKind = "streaming"
+)
+
+// TimelineSpan is a stretch of wall-clock time in a turn. Times are taken
+// when the SDK received events or ran handlers, not from the CLI's clock.
+type TimelineSpan struct {
+	Kind TimelineSpanKind
+	// Label names the tool, the permission request kind, the hook type, or
+	// the question asked
+	Label string
+	// ToolCallID is the tool call the span belongs to, if known
+	ToolCallID string
+	Start      time.Time
+	End        time.Time
+}
+
+// Duration returns the length of the span.
+func (s TimelineSpan) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// FormatTimeline renders spans as a text Gantt chart, one line per span in
+// start order, with bars width characters wide for the whole timeline.
+// It returns "" for no spans.
+//
+// Example:
+//
+//	start := time.Now()
+//	result, err := session.SendAndCollect(ctx, copilot.MessageOptions{Prompt: "Fix the build"}, nil)
+//	if err == nil && time.Since(start) > time.Minute {
+//	    log.Printf("slow turn:\n%s", copilot.FormatTimeline(result.Timeline, 60))
+//	}
+//
+// prints
+//
+//	waiting           |=                                                           |  1.2s
+//	thinking          |====                                                        |  4.8s
+//	permission shell  |   ==                                                       |  2.1s
+//	...
+func FormatTimeline(spans []TimelineSpan, width int) string {
+	if len(spans) == 0 {
+		return ""
+	}
+	width = max(width, 1)
+	sorted := slices.Clone(spans)
+	slices.SortStableFunc(sorted, func(a, b TimelineSpan) int { return a.Start.Compare(b.Start) })
+	start, end := sorted[0].Start, sorted[0].End
+	labels := make([]string, len(sorted))
+	durations := make([]string, len(sorted))
+	labelWidth, durationWidth := 0, 0
+	for i, span := range sorted {
+		if span.End.After(end) {
+			end = span.End
+		}
+		labels[i] = strings.TrimSpace(string(span.Kind) + " " + truncateLabel(span.Label, 30))
+		durations[i] = span.Duration().Round(time.Millisecond).String()
+		labelWidth = max(labelWidth, len(labels[i]))
+		durationWidth = max(durationWidth, len(durations[i]))
+	}
+	total := end.Sub(start)
+
+	var b strings.Builder
+	for i, span := range sorted {
+		from, to := 0, width
+		if total > 0 {
+			from = int(math.Floor(float64(span.Start.Sub(start)) / float64(total) * float64(width)))
+			to = int(math.Ceil(float64(span.End.Sub(start)) / float64(total) * float64(width)))
+		}
+		from = min(from, width-1)
+		to = min(max(to, from+1), width)
+		bar := strings.Repeat(" ", from) + strings.Repeat("=", to-from) + strings.Repeat(" ", width-to)
+		fmt.Fprintf(&b, "%-*s |%s| %*s\n", labelWidth, labels[i], bar, durationWidth, durations[i])
+	}
+	return b.String()
+}
+
+// truncateLabel shortens a label to n runes on one line.
+func truncateLabel(label string, n int) string {
+	label = strings.Join(strings.Fields(label), " ")
+	if runes := []rune(label); len(runes) > n {
+		return string(runes[:n-1]) + "…"
+	}
+	return label
+}
+
+// handlerSpan is the run of a tool, permission, user input or hook handler
+// recorded by the session.
+type handlerSpan struct {
+	TimelineSpan
+	traceID string
+}
+
+// timelineRecorder keeps the recent handler spans of a session, and
+// provides the clock timelines are measured with.
+type timelineRecorder struct {
+	mu    sync.Mutex
+	spans []handlerSpan
+	// now is the clock; nil uses time.Now
+	now func() time.Time
+}
+
+func (r *timelineRecorder) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// begin records the start of a handler run of the turn traced by traceID,
+// and returns a function recording its end.
+func (r *timelineRecorder) begin(kind TimelineSpanKind, label, toolCallID, traceID string) func() {
+	start := r.clock()
+	return func() {
+		span := handlerSpan{
+			TimelineSpan: TimelineSpan{Kind: kind, Label: label, ToolCallID: toolCallID, Start: start, End: r.clock()},
+			traceID:      traceID,
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if len(r.spans) == maxRecordedHandlerSpans {
+			r.spans = slices.Delete(r.spans, 0, 1)
+		}
+		r.spans = append(r.spans, span)
+	}
+}
+
+// recorded returns the handler spans that ended at or after since.
+func (r *timelineRecorder) recorded(since time.Time) []handlerSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var spans []handlerSpan
+	for _, span := range r.spans {
+		if !span.End.Before(since) {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// timedEvent is an event and when the SDK received it.
+type timedEvent struct {
+	event    SessionEvent
+	received time.Time
+}
+
+// buildTimeline assembles the timeline of a turn sent at sent and observed
+// until end, from its events and the handler spans recorded meanwhile.
+// Events and handler spans belong to the turn if they carry one of its trace
+// IDs, which are the ID the SDK generated for it and the CLI's interaction ID,
+// and handler spans also if they ran for one of its tool calls. Without trace
+// IDs, everything during the turn does.
+func buildTimeline(sent, end time.Time, traceIDs []string, events []timedEvent, handlers []handlerSpan) []TimelineSpan {
+	ofTurn := func(id string) bool {
+		return len(traceIDs) == 0 || id == "" || slices.Contains(traceIDs, id)
+	}
+	var spans []TimelineSpan
+	add := func(span TimelineSpan) {
+		if span.End.After(span.Start) {
+			spans = append(spans, span)
+		}
+	}
+
+	type toolRun struct {
+		name    string
+		start   time.Time
+		handler *handlerSpan
+		end     time.Time
+		done    bool
+	}
+	tools := make(map[string]*toolRun)
+	var toolOrder []string
+	streams := make(map[string]time.Time)
+	var firstEvent time.Time
+	for _, e := range events {
+		if e.received.After(end) || (e.event.Type != SessionIdle && e.event.Data.InteractionID != nil && !ofTurn(*e.event.Data.InteractionID)) {
+			continue
+		}
+		if firstEvent.IsZero() && e.event.Type != UserMessage {
+			firstEvent = e.received
+		}
+		data := e.event.Data
+		switch e.event.Type {
+		case ToolExecutionStart:
+			if data.ToolCallID == nil {
+				continue
+			}
+			run := &toolRun{start: e.received}
+			if data.ToolName != nil {
+				run.name = *data.ToolName
+			}
+			if _, seen := tools[*data.ToolCallID]; !seen {
+				toolOrder = append(toolOrder, *data.ToolCallID)
+			}
+			tools[*data.ToolCallID] = run
+		case ToolExecutionComplete:
+			if data.ToolCallID == nil {
+				continue
+			}
+			if run, ok := tools[*data.ToolCallID]; ok && !run.done {
+				run.end, run.done = e.received, true
+			}
+		case AssistantMessageDelta:
+			if data.MessageID != nil {
+				if _, ok := streams[*data.MessageID]; !ok {
+					streams[*data.MessageID] = e.received
+				}
+			}
+		case AssistantMessage:
+			if data.MessageID != nil {
+				if start, ok := streams[*data.MessageID]; ok {
+					add(TimelineSpan{Kind: TimelineStreaming, Start: start, End: e.received})
+					delete(streams, *data.MessageID)
+				}
+			}
+		}
+	}
+	for _, start := range streams {
+		add(TimelineSpan{Kind: TimelineStreaming, Start: start, End: end})
+	}
+
+	for i := range handlers {
+		h := &handlers[i]
+		run := tools[h.ToolCallID]
+		switch {
+		case h.Start.Before(sent) || h.Start.After(end):
+			continue
+		case h.Kind == TimelineToolRunning:
+			if run != nil && run.handler == nil {
+				run.handler = h
+			}
+			continue
+		case !ofTurn(h.traceID) && run == nil:
+			continue
+		}
+		add(h.TimelineSpan)
+	}
+
+	for _, id := range toolOrder {
+		run := tools[id]
+		runEnd := end
+		if run.done {
+			runEnd = run.end
+		}
+		runStart := run.start
+		if run.handler != nil {
+			add(TimelineSpan{Kind: TimelineToolQueued, Label: run.name, ToolCallID: id, Start: run.start, End: run.handler.Start})
+			runStart = run.handler.Start
+			if !run.done {
+				runEnd = run.handler.End
+			}
+		}
+		add(TimelineSpan{Kind: TimelineToolRunning, Label: run.name, ToolCallID: id, Start: runStart, End: runEnd})
+	}
+
+	if firstEvent.IsZero() {
+		add(TimelineSpan{Kind: TimelineWaiting, Start: sent, End: end})
+		return sortSpans(spans)
+	}
+	add(TimelineSpan{Kind: TimelineWaiting, Start: sent, End: firstEvent})
+	for _, gap := range uncovered(firstEvent, end, spans) {
+		add(TimelineSpan{Kind: TimelineThinking, Start: gap[0], End: gap[1]})
+	}
+	return sortSpans(spans)
+}
+
+// uncovered returns the stretches of [from, to] that no span covers.
+func uncovered(from, to time.Time, spans []TimelineSpan) [][2]time.Time {
+	sorted := slices.Clone(spans)
+	slices.SortFunc(sorted, func(a, b TimelineSpan) int { return a.Start.Compare(b.Start) })
+	var gaps [][2]time.Time
+	cursor := from
+	for _, span := range sorted {
+		if span.Start.After(cursor) {
+			gaps = append(gaps, [2]time.Time{cursor, minTime(span.Start, to)})
+		}
+		if span.End.After(cursor) {
+			cursor = span.End
+		}
+		if !cursor.Before(to) {
+			return gaps
+		}
+	}
+	if to.After(cursor) {
+		gaps = append(gaps, [2]time.Time{cursor, to})
+	}
+	return gaps
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// sortSpans orders spans by start, then by end.
+func sortSpans(spans []TimelineSpan) []TimelineSpan {
+	slices.SortStableFunc(spans, func(a, b TimelineSpan) int {
+		if c := a.Start.Compare(b.Start); c != 0 {
+			return c
+		}
+		return a.End.Compare(b.End)
+	})
+	return spans
+}
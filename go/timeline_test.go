@@ -0,0 +1,243 @@
+package copilot
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	base := time.Date(2026, 1, 2, 15, 4, 0, 0, time.UTC)
+	at := func(seconds float64) time.Time { return base.Add(time.Duration(seconds * float64(time.Second))) }
+	event := func(seconds float64, eventType SessionEventType, data Data) timedEvent {
+		return timedEvent{event: SessionEvent{Type: eventType, Data: data}, received: at(seconds)}
+	}
+	handler := func(kind TimelineSpanKind, label, toolCallID, traceID string, from, to float64) handlerSpan {
+		return handlerSpan{TimelineSpan: TimelineSpan{Kind: kind, Label: label, ToolCallID: toolCallID, Start: at(from), End: at(to)}, traceID: traceID}
+	}
+	span := func(kind TimelineSpanKind, label, toolCallID string, from, to float64) TimelineSpan {
+		return TimelineSpan{Kind: kind, Label: label, ToolCallID: toolCallID, Start: at(from), End: at(to)}
+	}
+	tool := func(id, name string) Data { return Data{ToolCallID: String(id), ToolName: String(name)} }
+	message := func(id string) Data { return Data{MessageID: String(id)} }
+
+	tests := []struct {
+		name     string
+		end      float64
+		traceIDs []string
+		events   []timedEvent
+		handlers []handlerSpan
+		want     []TimelineSpan
+	}{
+		{
+			name:     "a tool call waiting for permission, then a streamed answer",
+			end:      25,
+			traceIDs: []string{"sdk-trace", "turn-1"},
+			events: []timedEvent{
+				event(0.5, UserMessage, Data{}),
+				event(1, AssistantTurnStart, Data{}),
+				event(3, ToolExecutionStart, tool("t1", "bash")),
+				event(20, ToolExecutionComplete, Data{ToolCallID: String("t1")}),
+				event(22, AssistantMessageDelta, message("m1")),
+				event(24, AssistantMessageDelta, message("m1")),
+				event(25, AssistantMessage, message("m1")),
+				event(25, SessionIdle, Data{}),
+			},
+			handlers: []handlerSpan{
+				handler(TimelinePermission, "shell", "t1", "turn-1", 3, 5),
+				handler(TimelineToolRunning, "bash", "t1", "turn-1", 5, 19.5),
+			},
+			want: []TimelineSpan{
+				span(TimelineWaiting, "", "", 0, 1),
+				span(TimelineThinking, "", "", 1, 3),
+				span(TimelinePermission, "shell", "t1", 3, 5),
+				span(TimelineToolQueued, "bash", "t1", 3, 5),
+				span(TimelineToolRunning, "bash", "t1", 5, 20),
+				span(TimelineThinking, "", "", 20, 22),
+				span(TimelineStreaming, "", "", 22, 25),
+			},
+		},
+		{
+			name: "tools the SDK does not run span their events",
+			end:  10,
+			events: []timedEvent{
+				event(2, ToolExecutionStart, tool("t1", "view")),
+				event(2.5, ToolExecutionStart, tool("t2", "grep")),
+				event(4, ToolExecutionComplete, Data{ToolCallID: String("t2")}),
+				event(6, ToolExecutionComplete, Data{ToolCallID: String("t1")}),
+				event(10, SessionIdle, Data{}),
+			},
+			want: []TimelineSpan{
+				span(TimelineWaiting, "", "", 0, 2),
+				span(TimelineToolRunning, "view", "t1", 2, 6),
+				span(TimelineToolRunning, "grep", "t2", 2.5, 4),
+				span(TimelineThinking, "", "", 6, 10),
+			},
+		},
+		{
+			name:     "events and handlers of other turns are left out",
+			end:      8,
+			traceIDs: []string{"sdk-trace", "turn-1"},
+			events: []timedEvent{
+				event(1, AssistantTurnStart, Data{InteractionID: String("turn-1")}),
+				event(2, ToolExecutionStart, Data{ToolCallID: String("other"), ToolName: String("bash"), InteractionID: String("turn-0")}),
+				event(8, SessionIdle, Data{InteractionID: String("turn-0")}),
+			},
+			handlers: []handlerSpan{
+				handler(TimelineHook, "preToolUse", "", "turn-0", 2, 3),
+				handler(TimelineHook, "userPromptSubmitted", "", "sdk-trace", 0.2, 0.4),
+				handler(TimelineUserInput, "Which file?", "", "turn-1", 4, 6),
+				handler(TimelineHook, "sessionStart", "", "", -2, -1),
+			},
+			want: []TimelineSpan{
+				span(TimelineWaiting, "", "", 0, 1),
+				span(TimelineHook, "userPromptSubmitted", "", 0.2, 0.4),
+				span(TimelineThinking, "", "", 1, 4),
+				span(TimelineUserInput, "Which file?", "", 4, 6),
+				span(TimelineThinking, "", "", 6, 8),
+			},
+		},
+		{
+			name: "an unfinished turn ends at the snapshot",
+			end:  12,
+			events: []timedEvent{
+				event(1, ToolExecutionStart, tool("t1", "bash")),
+				event(3, AssistantMessageDelta, message("m1")),
+				event(13, AssistantMessage, message("m1")),
+			},
+			want: []TimelineSpan{
+				span(TimelineWaiting, "", "", 0, 1),
+				span(TimelineToolRunning, "bash", "t1", 1, 12),
+				span(TimelineStreaming, "", "", 3, 12),
+			},
+		},
+		{
+			name: "a turn without events is spent waiting",
+			end:  30,
+			want: []TimelineSpan{span(TimelineWaiting, "", "", 0, 30)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildTimeline(base, at(tt.end), tt.traceIDs, tt.events, tt.handlers)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unexpected timeline:\n got: %s\nwant: %s", describeSpans(got, base), describeSpans(tt.want, base))
+			}
+		})
+	}
+}
+
+func describeSpans(spans []TimelineSpan, base time.Time) string {
+	var parts []string
+	for _, span := range spans {
+		parts = append(parts, string(span.Kind)+"("+span.Label+","+span.ToolCallID+") "+span.Start.Sub(base).String()+"-"+span.End.Sub(base).String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+func TestTimelineRecorder(t *testing.T) {
+	clock := time.Date(2026, 1, 2, 15, 4, 0, 0, time.UTC)
+	recorder := timelineRecorder{now: func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}}
+	for i := 0; i < maxRecordedHandlerSpans+1; i++ {
+		recorder.begin(TimelineHook, "preToolUse", "", "trace")()
+	}
+
+	spans := recorder.recorded(time.Time{})
+	if len(spans) != maxRecordedHandlerSpans {
+		t.Fatalf("Expected %d spans, got %d", maxRecordedHandlerSpans, len(spans))
+	}
+	if got := spans[0].Duration(); got != time.Second {
+		t.Errorf("Expected spans of a second, got %v", got)
+	}
+	if got := recorder.recorded(spans[len(spans)-1].End); len(got) != 1 {
+		t.Errorf("Expected the spans ending at or after since, got %d", len(got))
+	}
+}
+
+func TestFormatTimeline(t *testing.T) {
+	base := time.Date(2026, 1, 2, 15, 4, 0, 0, time.UTC)
+	spans := []TimelineSpan{
+		{Kind: TimelineToolRunning, Label: "bash", Start: base.Add(2 * time.Second), End: base.Add(8 * time.Second)},
+		{Kind: TimelineWaiting, Start: base, End: base.Add(time.Second)},
+		{Kind: TimelineThinking, Start: base.Add(time.Second), End: base.Add(2 * time.Second)},
+		{Kind: TimelineStreaming, Start: base.Add(8 * time.Second), End: base.Add(10 * time.Second)},
+	}
+	want := "" +
+		"waiting           |=         | 1s\n" +
+		"thinking          | =        | 1s\n" +
+		"tool_running bash |  ======  | 6s\n" +
+		"streaming         |        ==| 2s\n"
+	if got := FormatTimeline(spans, 10); got != want {
+		t.Errorf("Unexpected chart:\n%s\nwant:\n%s", got, want)
+	}
+	if got := FormatTimeline(nil, 10); got != "" {
+		t.Errorf("Expected no chart for no spans, got %q", got)
+	}
+}
+
+func TestSession_SendAndCollectTimeline(t *testing.T) {
+	var session *Session
+	var server *fakeServer
+	var client *Client
+	session, server = newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+		if method == "session.send" {
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				server.emit(SessionEvent{Type: AssistantTurnStart})
+				server.emit(SessionEvent{Type: ToolExecutionStart, Data: Data{ToolCallID: String("t1"), ToolName: String("slow")}})
+				client.handlePermissionRequest(permissionRequestRequest{SessionID: session.SessionID, Request: PermissionRequest{Kind: "shell", ToolCallID: "t1"}})
+				client.handleToolCallRequest(toolCallRequest{SessionID: session.SessionID, ToolCallID: "t1", ToolName: "slow"})
+				server.emit(SessionEvent{Type: ToolExecutionComplete, Data: Data{ToolCallID: String("t1"), Success: Bool(true)}})
+				server.emit(SessionEvent{Type: AssistantMessageDelta, Data: Data{MessageID: String("m1"), DeltaContent: String("do")}})
+				time.Sleep(20 * time.Millisecond)
+				server.emit(SessionEvent{Type: AssistantMessage, Data: Data{MessageID: String("m1"), Content: String("done")}})
+				server.emit(SessionEvent{Type: SessionIdle})
+			}()
+			return sessionSendResponse{MessageID: "msg"}, nil
+		}
+		return nil, nil
+	})
+	session.registerPermissionHandler(func(PermissionRequest, PermissionInvocation) (PermissionRequestResult, error) {
+		time.Sleep(30 * time.Millisecond)
+		return Approved(), nil
+	})
+	session.registerTools([]Tool{{Name: "slow", Handler: func(ToolInvocation) (ToolResult, error) {
+		time.Sleep(50 * time.Millisecond)
+		return ToolResult{TextResultForLLM: "ok"}, nil
+	}}}, 0)
+	client = NewClient(nil)
+	client.sessions.put(session)
+
+	result, err := session.SendAndCollect(t.Context(), MessageOptions{Prompt: "run it"}, &SendAndWaitOptions{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	byKind := make(map[TimelineSpanKind]TimelineSpan)
+	for i, span := range result.Timeline {
+		if i > 0 && span.Start.Before(result.Timeline[i-1].Start) {
+			t.Errorf("Expected spans in start order, got %v", result.Timeline)
+		}
+		byKind[span.Kind] = span
+	}
+	if span := byKind[TimelineWaiting]; span.Duration() < 20*time.Millisecond {
+		t.Errorf("Expected at least 20ms waiting for the first event, got %+v", span)
+	}
+	if span := byKind[TimelinePermission]; span.Label != "shell" || span.ToolCallID != "t1" || span.Duration() < 30*time.Millisecond {
+		t.Errorf("Unexpected permission span %+v", span)
+	}
+	if span := byKind[TimelineToolRunning]; span.Label != "slow" || span.ToolCallID != "t1" || span.Duration() < 50*time.Millisecond {
+		t.Errorf("Unexpected tool span %+v", span)
+	}
+	if span := byKind[TimelineStreaming]; span.Duration() < 20*time.Millisecond {
+		t.Errorf("Expected at least 20ms streaming, got %+v", span)
+	}
+	if chart := FormatTimeline(result.Timeline, 40); !strings.Contains(chart, "tool_running slow") {
+		t.Errorf("Expected the tool in the chart, got:\n%s", chart)
+	}
+}
@@ -0,0 +1,48 @@
+package copilot
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/github/copilot-sdk/go/internal/timestamp"
+)
+
+// parseTimestamp converts a timestamp sent by the CLI into a time.Time.
+//
+// RFC 3339 strings and numeric Unix times are accepted. Numbers are read as
+// milliseconds when at or above 1e11 and as seconds otherwise. Missing, null,
+// and zero values yield the zero time.
+func parseTimestamp(raw json.RawMessage) (time.Time, error) {
+	return timestamp.Parse(raw)
+}
+
+// UnmarshalJSON decodes a session event, accepting numeric Unix timestamps in
+// seconds or milliseconds as well as RFC 3339 strings. A timestamp that
+// cannot be parsed leaves Timestamp zero instead of failing the event; see
+// [SessionEvent.RawTimestamp]. The JSON is kept for the typed payload
+// accessors, such as [SessionEvent.AsAssistantUsage].
+func (e *SessionEvent) UnmarshalJSON(data []byte) error {
+	type plain SessionEvent
+	aux := struct {
+		*plain
+		Timestamp json.RawMessage `json:"timestamp"`
+	}{plain: (*plain)(e)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	e.Timestamp, _ = parseTimestamp(aux.Timestamp)
+	e.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// RawTimestamp returns the event's timestamp exactly as sent by the CLI, or
+// nil for events created in code.
+func (e SessionEvent) RawTimestamp() json.RawMessage {
+	var aux struct {
+		Timestamp json.RawMessage `json:"timestamp"`
+	}
+	if err := json.Unmarshal(e.raw, &aux); err != nil {
+		return nil
+	}
+	return aux.Timestamp
+}
@@ -0,0 +1,179 @@
+package copilot
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	expected := time.Date(2026, 3, 1, 12, 30, 45, 500_000_000, time.UTC)
+
+	tests := []struct {
+		name string
+		raw  string
+		want time.Time
+	}{
+		{"RFC 3339 string", `"2026-03-01T12:30:45.5Z"`, expected},
+		{"unix seconds", `1772368245.5`, expected},
+		{"unix milliseconds", `1772368245500`, expected},
+		{"quoted unix milliseconds", `"1772368245500"`, expected},
+		{"zero", `0`, time.Time{}},
+		{"null", `null`, time.Time{}},
+		{"missing", ``, time.Time{}},
+		{"empty string", `""`, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTimestamp(json.RawMessage(tt.raw))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("rejects invalid values", func(t *testing.T) {
+		for _, raw := range []string{`"yesterday"`, `true`, `{}`} {
+			if _, err := parseTimestamp(json.RawMessage(raw)); err == nil {
+				t.Errorf("Expected error for %s", raw)
+			}
+		}
+	})
+}
+
+func TestSessionEvent_Timestamp(t *testing.T) {
+	for _, raw := range []string{`"2026-03-01T12:30:45Z"`, `1772368245`, `1772368245000`} {
+		event, err := UnmarshalSessionEvent([]byte(`{"id": "e", "type": "session.idle", "parentId": null, "data": {}, "timestamp": ` + raw + `}`))
+		if err != nil {
+			t.Fatalf("Failed to unmarshal event with timestamp %s: %v", raw, err)
+		}
+		if want := time.Date(2026, 3, 1, 12, 30, 45, 0, time.UTC); !event.Timestamp.Equal(want) {
+			t.Errorf("Expected %v for %s, got %v", want, raw, event.Timestamp)
+		}
+		if event.Type != SessionIdle || event.ID != "e" {
+			t.Errorf("Expected other fields to decode, got %+v", event)
+		}
+		if string(event.RawTimestamp()) != raw {
+			t.Errorf("Expected raw timestamp %s, got %s", raw, event.RawTimestamp())
+		}
+	}
+
+	t.Run("tolerates invalid timestamps", func(t *testing.T) {
+		event, err := UnmarshalSessionEvent([]byte(`{"id": "e", "type": "session.idle", "parentId": null, "data": {}, "timestamp": "yesterday"}`))
+		if err != nil {
+			t.Fatalf("Expected the event to decode, got %v", err)
+		}
+		if !event.Timestamp.IsZero() || event.Type != SessionIdle {
+			t.Errorf("Expected a zero timestamp and the other fields, got %+v", event)
+		}
+		if string(event.RawTimestamp()) != `"yesterday"` {
+			t.Errorf("Expected the raw timestamp to be kept, got %s", event.RawTimestamp())
+		}
+	})
+
+	t.Run("has no raw timestamp for events created in code", func(t *testing.T) {
+		if raw := (SessionEvent{Timestamp: time.Now()}).RawTimestamp(); raw != nil {
+			t.Errorf("Expected nil, got %s", raw)
+		}
+	})
+}
+
+func TestHookInput_Timestamp(t *testing.T) {
+	t.Run("parses milliseconds and keeps the raw value", func(t *testing.T) {
+		var input PreToolUseHookInput
+		if err := json.Unmarshal([]byte(`{"timestamp": 1772368245000, "cwd": "/repo", "toolName": "bash"}`), &input); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := time.Date(2026, 3, 1, 12, 30, 45, 0, time.UTC); !input.Time.Equal(want) {
+			t.Errorf("Expected %v, got %v", want, input.Time)
+		}
+		if input.Timestamp != 1772368245000 {
+			t.Errorf("Expected the numeric timestamp, got %d", input.Timestamp)
+		}
+		if string(input.RawTimestamp) != "1772368245000" {
+			t.Errorf("Expected raw timestamp to be kept, got %s", input.RawTimestamp)
+		}
+		if input.ToolName != "bash" || input.Cwd != "/repo" {
+			t.Errorf("Expected other fields to decode, got %+v", input)
+		}
+	})
+
+	t.Run("parses seconds", func(t *testing.T) {
+		var input SessionEndHookInput
+		if err := json.Unmarshal([]byte(`{"timestamp": 1772368245, "reason": "complete"}`), &input); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if input.Time.Unix() != 1772368245 || input.Timestamp != 1772368245 || input.Reason != "complete" {
+			t.Errorf("Unexpected input: %+v", input)
+		}
+	})
+
+	t.Run("converts strings to milliseconds", func(t *testing.T) {
+		var input UserPromptSubmittedHookInput
+		if err := json.Unmarshal([]byte(`{"timestamp": "2026-03-01T12:30:45Z", "prompt": "hi"}`), &input); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if input.Timestamp != 1772368245000 || input.Time.Unix() != 1772368245 {
+			t.Errorf("Unexpected input: %+v", input)
+		}
+	})
+
+	t.Run("tolerates invalid timestamps", func(t *testing.T) {
+		var input PostToolUseHookInput
+		if err := json.Unmarshal([]byte(`{"timestamp": "yesterday", "toolName": "bash"}`), &input); err != nil {
+			t.Fatalf("Expected the input to decode, got %v", err)
+		}
+		if !input.Time.IsZero() || input.Timestamp != 0 || input.ToolName != "bash" {
+			t.Errorf("Unexpected input: %+v", input)
+		}
+		if string(input.RawTimestamp) != `"yesterday"` {
+			t.Errorf("Expected the raw timestamp to be kept, got %s", input.RawTimestamp)
+		}
+	})
+
+	t.Run("marshals Time for inputs built in code", func(t *testing.T) {
+		when := time.Date(2026, 3, 1, 12, 30, 45, 0, time.UTC)
+		data, err := json.Marshal(PreToolUseHookInput{Time: when, Cwd: "/repo", ToolName: "bash"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(string(data), `"timestamp":1772368245000`) {
+			t.Errorf("Expected Time in Unix milliseconds, got %s", data)
+		}
+		var decoded PreToolUseHookInput
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !decoded.Time.Equal(when) || decoded.Timestamp != when.UnixMilli() || decoded.ToolName != "bash" {
+			t.Errorf("Expected the input to round-trip, got %+v", decoded)
+		}
+	})
+
+	t.Run("marshals the raw timestamp as sent", func(t *testing.T) {
+		var input SessionStartHookInput
+		if err := json.Unmarshal([]byte(`{"timestamp": "2026-03-01T12:30:45Z", "source": "new"}`), &input); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		data, err := json.Marshal(&input)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(string(data), `"timestamp":"2026-03-01T12:30:45Z"`) {
+			t.Errorf("Expected the raw timestamp, got %s", data)
+		}
+	})
+
+	t.Run("leaves missing timestamps zero", func(t *testing.T) {
+		var input ErrorOccurredHookInput
+		if err := json.Unmarshal([]byte(`{"error": "boom"}`), &input); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !input.Time.IsZero() || input.Timestamp != 0 {
+			t.Errorf("Expected zero timestamps, got %v and %d", input.Time, input.Timestamp)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrToolCallWithdrawn is the cause, as returned by [context.Cause], of a
+// tool handler's context cancelled because the CLI completed the call without
+// waiting for the handler, for example when another client aborted the turn.
+var ErrToolCallWithdrawn = errors.New("tool call withdrawn")
+
+// runningToolCalls cancels the contexts of a session's tool handlers that
+// are still running when the CLI reports their call complete.
+type runningToolCalls struct {
+	mu     sync.Mutex
+	cancel map[string]context.CancelCauseFunc
+}
+
+// begin returns the context of a tool call's handler, derived from parent,
+// and a function to call when the handler returns.
+func (r *runningToolCalls) begin(parent context.Context, toolCallID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	if toolCallID == "" {
+		return ctx, func() { cancel(nil) }
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel == nil {
+		r.cancel = make(map[string]context.CancelCauseFunc)
+	}
+	r.cancel[toolCallID] = cancel
+	return ctx, func() {
+		r.mu.Lock()
+		delete(r.cancel, toolCallID)
+		r.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// observeEvent withdraws the running call a ToolExecutionComplete event
+// reports complete.
+func (r *runningToolCalls) observeEvent(event *SessionEvent) {
+	if event.Type != ToolExecutionComplete || event.Data.ToolCallID == nil {
+		return
+	}
+	r.mu.Lock()
+	cancel := r.cancel[*event.Data.ToolCallID]
+	delete(r.cancel, *event.Data.ToolCallID)
+	r.mu.Unlock()
+	if cancel != nil {
+		cancel(ErrToolCallWithdrawn)
+	}
+}
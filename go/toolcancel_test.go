@@ -0,0 +1,107 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClient_ToolCancellation(t *testing.T) {
+	type QueryParams struct {
+		SQL string `json:"sql"`
+	}
+	// newQuerySession registers a tool that blocks until its context is done
+	// and reports the context's cause.
+	newQuerySession := func(t *testing.T) (*Client, *Session, <-chan struct{}, <-chan error) {
+		started := make(chan struct{}, 1)
+		causes := make(chan error, 1)
+		tool := DefineToolWithContext("query_db", "Run a query",
+			func(ctx context.Context, params QueryParams, inv ToolInvocation) (string, error) {
+				if params.SQL != "SELECT 1" || inv.Context != ctx {
+					t.Errorf("Unexpected params %+v or context", params)
+				}
+				started <- struct{}{}
+				select {
+				case <-ctx.Done():
+					causes <- context.Cause(ctx)
+				case <-time.After(5 * time.Second):
+					causes <- nil
+				}
+				return "", ctx.Err()
+			})
+		client := NewClient(nil)
+		session := newSession("s1", nil, "")
+		session.registerTools([]Tool{tool}, 0)
+		client.sessions.put(session)
+		return client, session, started, causes
+	}
+	call := func(client *Client, toolCallID string) <-chan ToolResult {
+		results := make(chan ToolResult, 1)
+		go func() {
+			response, _ := client.handleToolCallRequest(toolCallRequest{
+				SessionID:  "s1",
+				ToolCallID: toolCallID,
+				ToolName:   "query_db",
+				Arguments:  map[string]any{"sql": "SELECT 1"},
+			})
+			results <- response.Result
+		}()
+		return results
+	}
+	wait := func(t *testing.T, started <-chan struct{}) {
+		t.Helper()
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the handler to start")
+		}
+	}
+
+	t.Run("cancels the handler when the CLI withdraws the call", func(t *testing.T) {
+		client, session, started, causes := newQuerySession(t)
+		results := call(client, "call-1")
+		wait(t, started)
+		session.dispatchEvent(SessionEvent{Type: ToolExecutionComplete, Data: Data{ToolCallID: String("call-2")}})
+		session.dispatchEvent(SessionEvent{Type: ToolExecutionComplete, Data: Data{ToolCallID: String("call-1")}})
+		if cause := <-causes; !errors.Is(cause, ErrToolCallWithdrawn) {
+			t.Errorf("Expected ErrToolCallWithdrawn, got %v", cause)
+		}
+		if result := <-results; result.ResultType != "failure" {
+			t.Errorf("Expected a failed result, got %+v", result)
+		}
+		if len(session.runningTools.cancel) != 0 {
+			t.Errorf("Expected no running calls, got %d", len(session.runningTools.cancel))
+		}
+	})
+
+	t.Run("cancels the handler when the CLI reports an abort", func(t *testing.T) {
+		client, session, started, causes := newQuerySession(t)
+		results := call(client, "call-1")
+		wait(t, started)
+		session.dispatchEvent(SessionEvent{Type: Abort})
+		if cause := <-causes; !errors.Is(cause, context.Canceled) {
+			t.Errorf("Expected the context to be canceled, got %v", cause)
+		}
+		<-results
+
+		// The next turn's calls get a live context
+		results = call(client, "call-2")
+		wait(t, started)
+		session.markDestroyed("destroyed by caller")
+		if cause := <-causes; !errors.Is(cause, context.Canceled) {
+			t.Errorf("Expected the context to be canceled when the session is destroyed, got %v", cause)
+		}
+		<-results
+	})
+
+	t.Run("DefineTool handlers still work", func(t *testing.T) {
+		tool := DefineTool("echo", "Echo", func(params QueryParams, inv ToolInvocation) (string, error) {
+			return params.SQL, nil
+		})
+		result, err := tool.Handler(ToolInvocation{Arguments: map[string]any{"sql": "SELECT 2"}})
+		if err != nil || result.TextResultForLLM != "SELECT 2" {
+			t.Errorf("Expected the handler's result, got %+v, %v", result, err)
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package copilot
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxToolResultBinaryBytes bounds the binary content of one tool result,
+// before base64 encoding, so the result stays well within the JSON-RPC
+// message limit.
+const maxToolResultBinaryBytes = 16 << 20
+
+// ErrToolResultTooLarge matches errors for tool results whose binary
+// content exceeds the SDK's limit. Such results are not sent to the model;
+// the call fails instead.
+var ErrToolResultTooLarge = errors.New("tool result too large")
+
+// ToolResultTooLargeError reports a tool result whose binary content
+// exceeds the limit. It matches [ErrToolResultTooLarge].
+type ToolResultTooLargeError struct {
+	// Block is the index in [ToolResult.Content] of the block that crossed
+	// the limit
+	Block    int
+	MimeType string
+	// Size is the binary content of the result up to and including Block,
+	// in bytes
+	Size  int
+	Limit int
+}
+
+func (e *ToolResultTooLargeError) Error() string {
+	return fmt.Sprintf("%v: binary content reaches %d bytes at block %d (%s), over the %d byte limit",
+		ErrToolResultTooLarge, e.Size, e.Block, e.MimeType, e.Limit)
+}
+
+func (e *ToolResultTooLargeError) Is(target error) bool { return target == ErrToolResultTooLarge }
+
+// ToolResultBlockType is the kind of a [ToolResultBlock].
+type ToolResultBlockType string
+
+const (
+	// ToolResultText is a block of text.
+	ToolResultText ToolResultBlockType = "text"
+	// ToolResultJSON is a value sent to the model as JSON.
+	ToolResultJSON ToolResultBlockType = "json"
+	// ToolResultBinary is binary data with a MIME type, such as an image.
+	ToolResultBinary ToolResultBlockType = "binary"
+)
+
+// ToolResultBlock is a piece of a tool's result. Create blocks with
+// [TextBlock], [JSONBlock] and [BinaryBlock].
+type ToolResultBlock struct {
+	Type ToolResultBlockType
+	// Text is the text of a ToolResultText block
+	Text string
+	// Value is the value of a ToolResultJSON block
+	Value any
+	// Data is the content of a ToolResultBinary block. The SDK base64
+	// encodes it.
+	Data []byte
+	// MimeType is the MIME type of a ToolResultBinary block, such as
+	// "image/png"
+	MimeType string
+	// Description describes a ToolResultBinary block to the model
+	Description string
+}
+
+// TextBlock returns a block of text for [ToolResult.Content].
+func TextBlock(text string) ToolResultBlock {
+	return ToolResultBlock{Type: ToolResultText, Text: text}
+}
+
+// JSONBlock returns a block for [ToolResult.Content] holding value, which
+// is sent to the model as JSON.
+func JSONBlock(value any) ToolResultBlock {
+	return ToolResultBlock{Type: ToolResultJSON, Value: value}
+}
+
+// BinaryBlock returns a block of binary data for [ToolResult.Content], such
+// as a rendered chart.
+//
+// Example:
+//
+//	png, err := renderChart(params)
+//	if err != nil {
+//	    return copilot.ToolResult{}, err
+//	}
+//	return copilot.ToolResult{Content: []copilot.ToolResultBlock{
+//	    copilot.TextBlock("Revenue by quarter"),
+//	    copilot.BinaryBlock("image/png", png),
+//	}}, nil
+func BinaryBlock(mimeType string, data []byte) ToolResultBlock {
+	return ToolResultBlock{Type: ToolResultBinary, MimeType: mimeType, Data: data}
+}
+
+// expandContent converts the result's Content into the text and binary
+// results the CLI accepts. Text and JSON blocks are appended, in order and
+// separated by blank lines, to TextResultForLLM; binary blocks are appended
+// to BinaryResultsForLLM. A result with Content and no ResultType succeeds.
+func (r ToolResult) expandContent() (ToolResult, error) {
+	if len(r.Content) == 0 {
+		return r, nil
+	}
+	var text []string
+	if r.TextResultForLLM != "" {
+		text = append(text, r.TextResultForLLM)
+	}
+	binary := append([]ToolBinaryResult(nil), r.BinaryResultsForLLM...)
+	size := 0
+	for i, block := range r.Content {
+		switch block.Type {
+		case ToolResultText:
+			text = append(text, block.Text)
+		case ToolResultJSON:
+			data, err := json.Marshal(block.Value)
+			if err != nil {
+				return ToolResult{}, fmt.Errorf("failed to serialize tool result block %d: %w", i, err)
+			}
+			text = append(text, string(data))
+		case ToolResultBinary:
+			if block.MimeType == "" {
+				return ToolResult{}, fmt.Errorf("tool result block %d has no MIME type", i)
+			}
+			size += len(block.Data)
+			if size > maxToolResultBinaryBytes {
+				return ToolResult{}, &ToolResultTooLargeError{Block: i, MimeType: block.MimeType, Size: size, Limit: maxToolResultBinaryBytes}
+			}
+			binary = append(binary, ToolBinaryResult{
+				Data:        base64.StdEncoding.EncodeToString(block.Data),
+				MimeType:    block.MimeType,
+				Type:        binaryResultType(block.MimeType),
+				Description: block.Description,
+			})
+		default:
+			return ToolResult{}, fmt.Errorf("tool result block %d has unknown type %q", i, block.Type)
+		}
+	}
+	r.TextResultForLLM = strings.Join(text, "\n\n")
+	r.BinaryResultsForLLM = binary
+	r.Content = nil
+	if r.ResultType == "" {
+		r.ResultType = "success"
+	}
+	return r, nil
+}
+
+// binaryResultType returns the type of a binary result with mimeType.
+func binaryResultType(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return "image"
+	case strings.HasPrefix(mimeType, "audio/"):
+		return "audio"
+	}
+	return "resource"
+}
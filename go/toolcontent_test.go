@@ -0,0 +1,108 @@
+package copilot
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToolResult_Content(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nchart")
+	call := func(t *testing.T, tool Tool) ToolResult {
+		t.Helper()
+		client := NewClient(nil)
+		session := newSession("s1", nil, "")
+		session.registerTools([]Tool{tool}, 0)
+		client.sessions.put(session)
+		response, rpcErr := client.handleToolCallRequest(toolCallRequest{
+			SessionID: "s1", ToolCallID: "call-1", ToolName: tool.Name, Arguments: map[string]any{},
+		})
+		if rpcErr != nil {
+			t.Fatalf("Tool call failed: %v", rpcErr)
+		}
+		return response.Result
+	}
+
+	t.Run("forwards blocks as text and binary results", func(t *testing.T) {
+		type Params struct{}
+		tool := DefineTool("render_chart", "Render a chart", func(Params, ToolInvocation) (*ToolResult, error) {
+			block := BinaryBlock("image/png", png)
+			block.Description = "Revenue chart"
+			return &ToolResult{Content: []ToolResultBlock{
+				TextBlock("Revenue by quarter"),
+				JSONBlock(map[string]int{"q1": 10}),
+				block,
+				BinaryBlock("audio/wav", []byte("RIFF")),
+				BinaryBlock("application/pdf", []byte("%PDF")),
+			}}, nil
+		})
+		result := call(t, tool)
+
+		if result.ResultType != "success" || result.TextResultForLLM != "Revenue by quarter\n\n{\"q1\":10}" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+		want := []ToolBinaryResult{
+			{Data: base64.StdEncoding.EncodeToString(png), MimeType: "image/png", Type: "image", Description: "Revenue chart"},
+			{Data: base64.StdEncoding.EncodeToString([]byte("RIFF")), MimeType: "audio/wav", Type: "audio"},
+			{Data: base64.StdEncoding.EncodeToString([]byte("%PDF")), MimeType: "application/pdf", Type: "resource"},
+		}
+		if !reflect.DeepEqual(result.BinaryResultsForLLM, want) {
+			t.Errorf("Expected binary results %+v, got %+v", want, result.BinaryResultsForLLM)
+		}
+		if result.Content != nil {
+			t.Errorf("Expected the blocks to be consumed, got %+v", result.Content)
+		}
+	})
+
+	t.Run("keeps results without content as they are", func(t *testing.T) {
+		for _, result := range []ToolResult{
+			{TextResultForLLM: "done", ResultType: "success"},
+			{TextResultForLLM: "done", ResultType: "success", Content: []ToolResultBlock{}},
+		} {
+			got, err := result.expandContent()
+			if err != nil || got.TextResultForLLM != "done" || got.BinaryResultsForLLM != nil {
+				t.Errorf("Expected %+v unchanged, got %+v, %v", result, got, err)
+			}
+		}
+		got, err := ToolResult{TextResultForLLM: "Summary", Content: []ToolResultBlock{TextBlock("Details")}}.expandContent()
+		if err != nil || got.TextResultForLLM != "Summary\n\nDetails" {
+			t.Errorf("Expected the blocks after the text, got %+v, %v", got, err)
+		}
+	})
+
+	t.Run("fails oversized binary content", func(t *testing.T) {
+		tool := Tool{Name: "dump", Handler: func(ToolInvocation) (ToolResult, error) {
+			return ToolResult{Content: []ToolResultBlock{
+				BinaryBlock("image/png", make([]byte, maxToolResultBinaryBytes/2)),
+				BinaryBlock("image/png", make([]byte, maxToolResultBinaryBytes/2+1)),
+			}}, nil
+		}}
+		result := call(t, tool)
+		if result.ResultType != "failure" || !strings.Contains(result.Error, "block 1 (image/png)") {
+			t.Errorf("Expected the call to fail naming the block, got %+v", result)
+		}
+		if len(result.BinaryResultsForLLM) != 0 {
+			t.Errorf("Expected no binary results, got %d", len(result.BinaryResultsForLLM))
+		}
+
+		_, err := ToolResult{Content: []ToolResultBlock{BinaryBlock("image/png", make([]byte, maxToolResultBinaryBytes+1))}}.expandContent()
+		var tooLarge *ToolResultTooLargeError
+		if !errors.Is(err, ErrToolResultTooLarge) || !errors.As(err, &tooLarge) || tooLarge.Size != maxToolResultBinaryBytes+1 {
+			t.Errorf("Expected a ToolResultTooLargeError, got %v", err)
+		}
+	})
+
+	t.Run("rejects invalid blocks", func(t *testing.T) {
+		for _, block := range []ToolResultBlock{
+			BinaryBlock("", png),
+			JSONBlock(func() {}),
+			{Type: "video"},
+		} {
+			if _, err := (ToolResult{Content: []ToolResultBlock{block}}).expandContent(); err == nil {
+				t.Errorf("Expected an error for %+v", block.Type)
+			}
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package copilot
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// toolCallCacheSize bounds the number of tool calls remembered per session
+	// for duplicate detection.
+	toolCallCacheSize = 256
+	// toolCallCacheTTL is how long a completed tool call's result is kept to
+	// answer duplicates.
+	toolCallCacheTTL = 10 * time.Minute
+)
+
+// toolCallCache remembers recent tool calls of a session by toolCallId, so
+// that a tool.call request the CLI delivers more than once runs the handler
+// only once.
+type toolCallCache struct {
+	mu      sync.Mutex
+	entries map[string]*toolCall
+	order   []*toolCall // oldest first
+	now     func() time.Time
+}
+
+// toolCall is a tool call that is running or has completed.
+type toolCall struct {
+	id        string
+	done      chan struct{}
+	result    ToolResult
+	completed time.Time // zero while running
+}
+
+func newToolCallCache() *toolCallCache {
+	return &toolCallCache{
+		entries: make(map[string]*toolCall),
+		now:     time.Now,
+	}
+}
+
+// begin returns the call for toolCallID. first is true if the call was not
+// seen before, in which case the caller must run the handler and then call
+// finish; otherwise the caller should wait for the first execution's result.
+func (c *toolCallCache) begin(toolCallID string) (call *toolCall, first bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.evictLocked(now)
+	if call, ok := c.entries[toolCallID]; ok && !call.expired(now) {
+		return call, false
+	}
+	call = &toolCall{id: toolCallID, done: make(chan struct{})}
+	c.entries[toolCallID] = call
+	c.order = append(c.order, call)
+	return call, true
+}
+
+// evictLocked drops expired calls from the front of the queue, then the
+// oldest calls while the cache is full.
+func (c *toolCallCache) evictLocked(now time.Time) {
+	for len(c.order) > 0 {
+		oldest := c.order[0]
+		if !oldest.expired(now) && len(c.order) < toolCallCacheSize {
+			return
+		}
+		if c.entries[oldest.id] == oldest {
+			delete(c.entries, oldest.id)
+		}
+		c.order = c.order[1:]
+	}
+}
+
+// finish records the result of the first execution and releases duplicates
+// waiting for it.
+func (c *toolCallCache) finish(call *toolCall, result ToolResult) {
+	c.mu.Lock()
+	call.result = result
+	call.completed = c.now()
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// expired reports whether the call completed more than toolCallCacheTTL ago.
+func (c *toolCall) expired(now time.Time) bool {
+	return !c.completed.IsZero() && now.Sub(c.completed) >= toolCallCacheTTL
+}
+
+// wait returns the result of the first execution, waiting for it to complete.
+func (c *toolCall) wait() ToolResult {
+	<-c.done
+	return c.result
+}
+
+// len returns the number of remembered tool calls.
+func (c *toolCallCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
@@ -0,0 +1,160 @@
+package copilot
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_DuplicateToolCalls(t *testing.T) {
+	newToolSession := func(tools ...Tool) (*Client, *Session) {
+		client := NewClient(nil)
+		session := newSession("s1", nil, "")
+		session.registerTools(tools, 0)
+		client.sessions.put(session)
+		return client, session
+	}
+	call := func(client *Client, toolName, toolCallID string) ToolResult {
+		response, _ := client.handleToolCallRequest(toolCallRequest{
+			SessionID:  "s1",
+			ToolCallID: toolCallID,
+			ToolName:   toolName,
+		})
+		return response.Result
+	}
+	countingTool := func(name string, idempotent bool, runs *atomic.Int32, release <-chan struct{}) Tool {
+		return Tool{
+			Name:       name,
+			Idempotent: idempotent,
+			Handler: func(ToolInvocation) (ToolResult, error) {
+				n := runs.Add(1)
+				if release != nil {
+					<-release
+				}
+				return ToolResult{TextResultForLLM: fmt.Sprintf("run %d", n), ResultType: "success"}, nil
+			},
+		}
+	}
+
+	t.Run("duplicate after completion returns the cached result", func(t *testing.T) {
+		var runs atomic.Int32
+		client, _ := newToolSession(countingTool("charge_card", false, &runs, nil))
+
+		first := call(client, "charge_card", "call-1")
+		second := call(client, "charge_card", "call-1")
+		if runs.Load() != 1 {
+			t.Errorf("Expected the handler to run once, ran %d times", runs.Load())
+		}
+		if second.TextResultForLLM != first.TextResultForLLM {
+			t.Errorf("Expected the cached result %q, got %q", first.TextResultForLLM, second.TextResultForLLM)
+		}
+		if result := call(client, "charge_card", "call-2"); result.TextResultForLLM != "run 2" {
+			t.Errorf("Expected a new call ID to run the handler, got %q", result.TextResultForLLM)
+		}
+	})
+
+	t.Run("duplicate during execution attaches to the running call", func(t *testing.T) {
+		var runs atomic.Int32
+		release := make(chan struct{})
+		client, _ := newToolSession(countingTool("charge_card", false, &runs, release))
+
+		results := make(chan ToolResult, 2)
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results <- call(client, "charge_card", "call-1")
+			}()
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for runs.Load() == 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		select {
+		case <-results:
+			t.Fatal("Expected the duplicate to wait for the running call")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		close(release)
+		wg.Wait()
+		close(results)
+		for result := range results {
+			if result.TextResultForLLM != "run 1" {
+				t.Errorf("Expected both requests to get the first result, got %q", result.TextResultForLLM)
+			}
+		}
+		if runs.Load() != 1 {
+			t.Errorf("Expected the handler to run once, ran %d times", runs.Load())
+		}
+	})
+
+	t.Run("duplicate before a result is cached is not lost", func(t *testing.T) {
+		var runs atomic.Int32
+		client, session := newToolSession(countingTool("charge_card", false, &runs, nil))
+
+		// The duplicate registers first, as if it overtook the original
+		first, isFirst := session.toolCalls.begin("call-1")
+		if !isFirst {
+			t.Fatal("Expected the first begin to own the call")
+		}
+		done := make(chan ToolResult, 1)
+		go func() { done <- call(client, "charge_card", "call-1") }()
+		session.toolCalls.finish(first, ToolResult{TextResultForLLM: "original", ResultType: "success"})
+
+		if result := <-done; result.TextResultForLLM != "original" {
+			t.Errorf("Expected the duplicate to get the original result, got %q", result.TextResultForLLM)
+		}
+		if runs.Load() != 0 {
+			t.Errorf("Expected the handler not to run for the duplicate, ran %d times", runs.Load())
+		}
+	})
+
+	t.Run("idempotent tools run again", func(t *testing.T) {
+		var runs atomic.Int32
+		client, _ := newToolSession(countingTool("read_file", true, &runs, nil))
+
+		call(client, "read_file", "call-1")
+		if result := call(client, "read_file", "call-1"); result.TextResultForLLM != "run 2" {
+			t.Errorf("Expected the handler to run again, got %q", result.TextResultForLLM)
+		}
+	})
+}
+
+func TestToolCallCache(t *testing.T) {
+	t.Run("expires completed calls after the TTL", func(t *testing.T) {
+		cache := newToolCallCache()
+		now := time.Now()
+		cache.now = func() time.Time { return now }
+
+		call, _ := cache.begin("call-1")
+		cache.finish(call, ToolResult{})
+		if _, first := cache.begin("call-1"); first {
+			t.Error("Expected a duplicate within the TTL")
+		}
+		now = now.Add(toolCallCacheTTL)
+		if _, first := cache.begin("call-1"); !first {
+			t.Error("Expected the call to be forgotten after the TTL")
+		}
+	})
+
+	t.Run("keeps at most toolCallCacheSize calls", func(t *testing.T) {
+		cache := newToolCallCache()
+		for i := 0; i < toolCallCacheSize+10; i++ {
+			call, _ := cache.begin(fmt.Sprintf("call-%d", i))
+			cache.finish(call, ToolResult{})
+		}
+		if len(cache.entries) > toolCallCacheSize || len(cache.order) > toolCallCacheSize {
+			t.Errorf("Expected at most %d entries, got %d", toolCallCacheSize, len(cache.entries))
+		}
+		if _, first := cache.begin("call-0"); !first {
+			t.Error("Expected the oldest call to be evicted")
+		}
+		if _, first := cache.begin(fmt.Sprintf("call-%d", toolCallCacheSize+9)); first {
+			t.Error("Expected the newest call to be kept")
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ToolLogsTelemetryKey is the key under which the SDK attaches a tool call's
+// log entries, as a []ToolLogEntry, to the Data.ToolTelemetry of its
+// ToolExecutionComplete event. Use [SessionEvent.ToolLogs] to read them.
+const ToolLogsTelemetryKey = "sdk.toolLogs"
+
+// Limits on the logs kept per tool call. Longer messages are truncated and
+// further entries are dropped, with markers saying so.
+const (
+	maxToolLogEntries      = 100
+	maxToolLogMessageBytes = 2048
+	maxToolLogBytes        = 32 * 1024
+)
+
+// ToolLogEntry is a log line written by a tool handler with
+// [ToolInvocation.Log].
+type ToolLogEntry struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// toolLog collects the log entries of one tool call.
+type toolLog struct {
+	mu      sync.Mutex
+	entries []ToolLogEntry
+	bytes   int
+	dropped int
+}
+
+func (l *toolLog) add(level, msg string) {
+	if n := len(msg); n > maxToolLogMessageBytes {
+		cut := maxToolLogMessageBytes
+		for cut > 0 && !utf8.RuneStart(msg[cut]) {
+			cut--
+		}
+		msg = fmt.Sprintf("%s… [truncated %d bytes]", msg[:cut], n-cut)
+	}
+	entry := ToolLogEntry{Time: time.Now(), Level: level, Message: msg}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) >= maxToolLogEntries || l.bytes+len(msg) > maxToolLogBytes {
+		l.dropped++
+		return
+	}
+	l.entries = append(l.entries, entry)
+	l.bytes += len(msg)
+}
+
+// snapshot returns the entries, followed by a marker if any were dropped.
+func (l *toolLog) snapshot() []ToolLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) == 0 && l.dropped == 0 {
+		return nil
+	}
+	entries := append([]ToolLogEntry(nil), l.entries...)
+	if l.dropped > 0 {
+		entries = append(entries, ToolLogEntry{
+			Time:    time.Now(),
+			Level:   "warn",
+			Message: fmt.Sprintf("[%d more log entries dropped]", l.dropped),
+		})
+	}
+	return entries
+}
+
+// Log records a log line for this tool call. Entries are attached to the
+// call's ToolExecutionComplete event (see [SessionEvent.ToolLogs]), included
+// in [TurnResult.ToolLogs] and [Turn] tool calls, and rendered by the
+// Markdown methods. They are not sent to the CLI. level is free-form; "debug",
+// "info", "warn" and "error" are conventional.
+//
+// Log is safe to call from multiple goroutines. A call keeps at most 100
+// entries of up to 2 KiB each, 32 KiB in total; longer messages are truncated
+// and further entries dropped, with markers saying so. Log does nothing on an
+// invocation not created by the SDK.
+//
+// Example:
+//
+//	func(inv copilot.ToolInvocation) (copilot.ToolResult, error) {
+//	    inv.Log("info", "fetching issues")
+//	    ...
+//	}
+func (i ToolInvocation) Log(level, msg string) {
+	if i.logs != nil {
+		i.logs.add(level, msg)
+	}
+}
+
+// ToolLogs returns the log entries a tool handler wrote with
+// [ToolInvocation.Log], for a ToolExecutionComplete event. It returns nil for
+// other events and for calls that didn't log.
+func (e SessionEvent) ToolLogs() []ToolLogEntry {
+	if e.Type != ToolExecutionComplete {
+		return nil
+	}
+	switch logs := e.Data.ToolTelemetry[ToolLogsTelemetryKey].(type) {
+	case nil:
+		return nil
+	case []ToolLogEntry:
+		return logs
+	default:
+		// Decoded from JSON, e.g. in a saved transcript
+		raw, err := json.Marshal(logs)
+		if err != nil {
+			return nil
+		}
+		var entries []ToolLogEntry
+		if json.Unmarshal(raw, &entries) != nil {
+			return nil
+		}
+		return entries
+	}
+}
+
+// toolLogTracker holds the logs of a session's tool calls until their
+// ToolExecutionComplete events are dispatched.
+type toolLogTracker struct {
+	mu    sync.Mutex
+	calls map[string]*toolLog
+}
+
+// begin returns the log for a tool call.
+func (t *toolLogTracker) begin(toolCallID string) *toolLog {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.calls == nil {
+		t.calls = make(map[string]*toolLog)
+	}
+	l, ok := t.calls[toolCallID]
+	if !ok {
+		l = &toolLog{}
+		t.calls[toolCallID] = l
+	}
+	return l
+}
+
+// observeEvent attaches collected logs to ToolExecutionComplete events.
+// Logs of calls that never completed are dropped when the session goes idle.
+func (t *toolLogTracker) observeEvent(event *SessionEvent) {
+	switch event.Type {
+	case SessionIdle:
+		t.mu.Lock()
+		clear(t.calls)
+		t.mu.Unlock()
+	case ToolExecutionComplete:
+		if event.Data.ToolCallID == nil {
+			return
+		}
+		t.mu.Lock()
+		l, ok := t.calls[*event.Data.ToolCallID]
+		delete(t.calls, *event.Data.ToolCallID)
+		t.mu.Unlock()
+		if !ok {
+			return
+		}
+		entries := l.snapshot()
+		if entries == nil {
+			return
+		}
+		telemetry := make(map[string]any, len(event.Data.ToolTelemetry)+1)
+		for k, v := range event.Data.ToolTelemetry {
+			telemetry[k] = v
+		}
+		telemetry[ToolLogsTelemetryKey] = entries
+		event.Data.ToolTelemetry = telemetry
+	}
+}
+
+// collectToolLogs returns the tool logs attached to events, by tool call ID.
+func collectToolLogs(events []SessionEvent) map[string][]ToolLogEntry {
+	var logs map[string][]ToolLogEntry
+	for _, event := range events {
+		entries := event.ToolLogs()
+		if entries == nil || event.Data.ToolCallID == nil {
+			continue
+		}
+		if logs == nil {
+			logs = make(map[string][]ToolLogEntry)
+		}
+		logs[*event.Data.ToolCallID] = entries
+	}
+	return logs
+}
@@ -0,0 +1,207 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestToolInvocation_Log(t *testing.T) {
+	newLoggingSession := func(t *testing.T, handler ToolHandler) (*Client, *Session) {
+		client := NewClient(nil)
+		session := newSession("s1", nil, "")
+		session.registerTools([]Tool{{Name: "triage", Handler: handler}}, 0)
+		client.sessions.put(session)
+		return client, session
+	}
+	call := func(client *Client, toolCallID string) {
+		client.handleToolCallRequest(toolCallRequest{SessionID: "s1", ToolCallID: toolCallID, ToolName: "triage"})
+	}
+	complete := func(toolCallID string) SessionEvent {
+		return SessionEvent{Type: ToolExecutionComplete, Data: Data{
+			ToolCallID:    String(toolCallID),
+			Success:       Bool(true),
+			ToolTelemetry: map[string]any{"cli": 1},
+		}}
+	}
+
+	t.Run("attaches logs to the completion event", func(t *testing.T) {
+		client, session := newLoggingSession(t, func(inv ToolInvocation) (ToolResult, error) {
+			inv.Log("info", "fetching issues")
+			inv.Log("warn", "rate limited, retrying")
+			return ToolResult{TextResultForLLM: "ok"}, nil
+		})
+		var completed SessionEvent
+		session.On(func(event SessionEvent) { completed = event })
+
+		call(client, "c1")
+		session.dispatchEvent(complete("c1"))
+
+		logs := completed.ToolLogs()
+		if len(logs) != 2 || logs[0].Level != "info" || logs[0].Message != "fetching issues" || logs[1].Level != "warn" {
+			t.Fatalf("Unexpected logs: %+v", logs)
+		}
+		if logs[0].Time.IsZero() {
+			t.Error("Expected entries to be timestamped")
+		}
+		if completed.Data.ToolTelemetry["cli"] != 1 {
+			t.Errorf("Expected the CLI's telemetry to be kept, got %v", completed.Data.ToolTelemetry)
+		}
+
+		// Logs are handed out once
+		session.dispatchEvent(complete("c1"))
+		if completed.ToolLogs() != nil {
+			t.Error("Expected a repeated completion event to carry no logs")
+		}
+	})
+
+	t.Run("calls without logs are left alone", func(t *testing.T) {
+		client, session := newLoggingSession(t, func(ToolInvocation) (ToolResult, error) {
+			return ToolResult{}, nil
+		})
+		var completed SessionEvent
+		session.On(func(event SessionEvent) { completed = event })
+		call(client, "c1")
+		session.dispatchEvent(complete("c1"))
+		if _, ok := completed.Data.ToolTelemetry[ToolLogsTelemetryKey]; ok {
+			t.Error("Expected no logs in the telemetry")
+		}
+	})
+
+	t.Run("idle drops logs of calls that never completed", func(t *testing.T) {
+		client, session := newLoggingSession(t, func(inv ToolInvocation) (ToolResult, error) {
+			inv.Log("info", "x")
+			return ToolResult{}, nil
+		})
+		call(client, "c1")
+		session.dispatchEvent(SessionEvent{Type: SessionIdle})
+		if n := len(session.toolLogs.calls); n != 0 {
+			t.Errorf("Expected pending logs to be dropped, got %d", n)
+		}
+	})
+
+	t.Run("is safe for concurrent use", func(t *testing.T) {
+		client, session := newLoggingSession(t, func(inv ToolInvocation) (ToolResult, error) {
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 5; j++ {
+						inv.Log("debug", fmt.Sprintf("worker %d step %d", i, j))
+					}
+				}()
+			}
+			wg.Wait()
+			return ToolResult{}, nil
+		})
+		var completed SessionEvent
+		session.On(func(event SessionEvent) { completed = event })
+		call(client, "c1")
+		session.dispatchEvent(complete("c1"))
+		if n := len(completed.ToolLogs()); n != 50 {
+			t.Errorf("Expected 50 entries, got %d", n)
+		}
+	})
+
+	t.Run("invocations not created by the SDK ignore logs", func(t *testing.T) {
+		ToolInvocation{}.Log("info", "nothing happens")
+	})
+}
+
+func TestToolLog_Bounds(t *testing.T) {
+	t.Run("truncates long messages", func(t *testing.T) {
+		var l toolLog
+		l.add("info", strings.Repeat("é", maxToolLogMessageBytes))
+		entries := l.snapshot()
+		msg := entries[0].Message
+		if !strings.Contains(msg, "… [truncated ") || !utf8.ValidString(msg) {
+			t.Errorf("Expected a valid truncated message, got %q", msg[len(msg)-40:])
+		}
+		if len(msg) > maxToolLogMessageBytes+40 {
+			t.Errorf("Expected the message to be cut near %d bytes, got %d", maxToolLogMessageBytes, len(msg))
+		}
+	})
+
+	t.Run("drops entries past the limit", func(t *testing.T) {
+		var l toolLog
+		for i := 0; i < maxToolLogEntries+7; i++ {
+			l.add("info", "line")
+		}
+		entries := l.snapshot()
+		if len(entries) != maxToolLogEntries+1 {
+			t.Fatalf("Expected %d entries and a marker, got %d", maxToolLogEntries, len(entries))
+		}
+		if last := entries[len(entries)-1]; last.Message != "[7 more log entries dropped]" {
+			t.Errorf("Unexpected marker: %+v", last)
+		}
+	})
+
+	t.Run("bounds the total size", func(t *testing.T) {
+		var l toolLog
+		for i := 0; i < maxToolLogEntries; i++ {
+			l.add("info", strings.Repeat("x", maxToolLogMessageBytes))
+		}
+		total := 0
+		for _, entry := range l.snapshot() {
+			total += len(entry.Message)
+		}
+		if total > maxToolLogBytes+100 {
+			t.Errorf("Expected at most about %d bytes, got %d", maxToolLogBytes, total)
+		}
+	})
+}
+
+func TestSessionEvent_ToolLogs(t *testing.T) {
+	t.Run("decodes logs from saved events", func(t *testing.T) {
+		raw := `{"type":"tool.execution_complete","data":{"toolCallId":"c1","toolTelemetry":{"sdk.toolLogs":[{"time":"2026-01-02T03:04:05Z","level":"info","message":"hi"}]}}}`
+		var event SessionEvent
+		if err := json.Unmarshal([]byte(raw), &event); err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+		logs := event.ToolLogs()
+		if len(logs) != 1 || logs[0].Message != "hi" || !logs[0].Time.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+			t.Errorf("Unexpected logs: %+v", logs)
+		}
+	})
+
+	t.Run("other events have no logs", func(t *testing.T) {
+		event := SessionEvent{Type: ToolExecutionStart, Data: Data{ToolTelemetry: map[string]any{ToolLogsTelemetryKey: []ToolLogEntry{{}}}}}
+		if event.ToolLogs() != nil {
+			t.Error("Expected nil")
+		}
+	})
+}
+
+func TestTurnResult_Markdown(t *testing.T) {
+	logTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	result := &TurnResult{Events: []SessionEvent{
+		{Type: UserMessage, Data: Data{Content: String("Triage the issues")}},
+		{Type: ToolExecutionStart, Data: Data{ToolCallID: String("c1"), ToolName: String("triage")}},
+		{Type: ToolExecutionComplete, Data: Data{ToolCallID: String("c1"), Success: Bool(true), ToolTelemetry: map[string]any{
+			ToolLogsTelemetryKey: []ToolLogEntry{{Time: logTime, Level: "info", Message: "saw ```fence```"}},
+		}}},
+		{Type: ToolExecutionStart, Data: Data{ToolCallID: String("c2"), ToolName: String("label")}},
+		{Type: ToolExecutionComplete, Data: Data{ToolCallID: String("c2"), Success: Bool(false)}},
+		{Type: AssistantMessage, Data: Data{Content: String("Done.")}},
+	}}
+
+	want := "### User\n\nTriage the issues\n\n" +
+		"- Tool `triage` succeeded\n\n" +
+		"<details>\n<summary>Logs (1)</summary>\n\n````\n03:04:05.000 info  saw ```fence```\n````\n\n</details>\n\n" +
+		"- Tool `label` failed\n\n" +
+		"### Assistant\n\nDone.\n"
+	if got := result.Markdown(); got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+	if logs := collectToolLogs(result.Events); len(logs) != 1 || len(logs["c1"]) != 1 {
+		t.Errorf("Expected the logs of c1, got %+v", logs)
+	}
+	if got := (&TurnResult{}).Markdown(); got != "" {
+		t.Errorf("Expected an empty turn to render as nothing, got %q", got)
+	}
+}
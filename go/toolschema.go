@@ -0,0 +1,61 @@
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// preparedTool is a tool definition serialized by [PrepareTools].
+type preparedTool struct {
+	name        string
+	description string
+	data        json.RawMessage
+}
+
+// PrepareTools serializes the definitions of tools, including their parameter
+// schemas, once, and returns copies that reuse the serialized form in every
+// session they are passed to. Use it when many sessions are created with the
+// same large set of tools, since otherwise each session.create serializes
+// every schema again. A schema that cannot be serialized is reported here
+// rather than when a session is created.
+//
+// The schemas are captured when PrepareTools is called: later changes to a
+// returned tool's Parameters are not sent, while a changed Name or
+// Description makes that tool be serialized again.
+//
+// Example:
+//
+//	tools, err := copilot.PrepareTools(catalogTools)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, user := range users {
+//	    session, err := client.CreateSession(ctx, &copilot.SessionConfig{
+//	        Tools:               tools,
+//	        OnPermissionRequest: copilot.PermissionHandler.ApproveAll,
+//	    })
+//	    // ...
+//	}
+func PrepareTools(tools []Tool) ([]Tool, error) {
+	prepared := make([]Tool, len(tools))
+	for i, tool := range tools {
+		tool.prepared = nil
+		data, err := json.Marshal(tool)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize tool %q: %w", tool.Name, err)
+		}
+		tool.prepared = &preparedTool{name: tool.Name, description: tool.Description, data: data}
+		prepared[i] = tool
+	}
+	return prepared, nil
+}
+
+// MarshalJSON encodes the tool's definition, reusing the form serialized by
+// [PrepareTools] if there is one.
+func (t Tool) MarshalJSON() ([]byte, error) {
+	if p := t.prepared; p != nil && p.name == t.Name && p.description == t.Description {
+		return p.data, nil
+	}
+	type definition Tool
+	return json.Marshal(definition(t))
+}
@@ -0,0 +1,145 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// catalogTools returns n tools with schemas the size of those generated from
+// an API catalog: 20 documented properties each.
+func catalogTools(n int) []Tool {
+	tools := make([]Tool, n)
+	for i := range tools {
+		properties := make(map[string]any)
+		for j := range 20 {
+			properties[fmt.Sprintf("field_%d", j)] = map[string]any{
+				"type":        "string",
+				"description": fmt.Sprintf("Field %d of the request, as documented in the catalog entry for operation %d.", j, i),
+				"enum":        []string{"alpha", "beta", "gamma", "delta"},
+			}
+		}
+		tools[i] = Tool{
+			Name:        fmt.Sprintf("catalog_operation_%d", i),
+			Description: fmt.Sprintf("Calls catalog operation %d.", i),
+			Parameters:  map[string]any{"type": "object", "properties": properties, "required": []string{"field_0"}},
+			Handler:     func(ToolInvocation) (ToolResult, error) { return ToolResult{}, nil },
+		}
+	}
+	return tools
+}
+
+func TestPrepareTools(t *testing.T) {
+	tools := catalogTools(3)
+
+	t.Run("serializes like unprepared tools", func(t *testing.T) {
+		prepared, err := PrepareTools(tools)
+		if err != nil {
+			t.Fatalf("Failed to prepare tools: %v", err)
+		}
+		want, _ := json.Marshal(tools)
+		got, _ := json.Marshal(prepared)
+		if string(got) != string(want) {
+			t.Errorf("Expected prepared tools to serialize the same:\n%s\ngot:\n%s", want, got)
+		}
+		if prepared[0].Handler == nil {
+			t.Error("Expected the handler to be kept")
+		}
+	})
+
+	t.Run("reserializes a renamed tool", func(t *testing.T) {
+		prepared, _ := PrepareTools(tools)
+		prepared[0].Name = "renamed"
+		data, _ := json.Marshal(prepared[0])
+		var decoded Tool
+		json.Unmarshal(data, &decoded)
+		if decoded.Name != "renamed" {
+			t.Errorf("Expected the new name, got %q", decoded.Name)
+		}
+	})
+
+	t.Run("reports schemas that cannot be serialized", func(t *testing.T) {
+		bad := []Tool{{Name: "bad", Parameters: map[string]any{"default": func() {}}}}
+		if _, err := PrepareTools(bad); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
+
+func TestClient_CreateSessionWithManyTools(t *testing.T) {
+	var created createSessionRequest
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		if method == "session.create" {
+			json.Unmarshal(params, &created)
+			return createSessionResponse{SessionID: "s1"}, nil
+		}
+		return nil, nil
+	})
+	tools, err := PrepareTools(catalogTools(100))
+	if err != nil {
+		t.Fatalf("Failed to prepare tools: %v", err)
+	}
+	config := &SessionConfig{Tools: tools, OnPermissionRequest: PermissionHandler.ApproveAll}
+
+	t.Run("sends every tool", func(t *testing.T) {
+		client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+		t.Cleanup(func() { client.ForceStop() })
+		if _, err := client.CreateSession(t.Context(), config); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if len(created.Tools) != 100 || created.Tools[99].Name != "catalog_operation_99" {
+			t.Errorf("Expected 100 tools, got %d", len(created.Tools))
+		}
+	})
+
+	t.Run("fails before sending a message over the limit", func(t *testing.T) {
+		client := NewClient(&ClientOptions{CLIUrl: cli.addr(), MaxMessageBytes: 64 << 10})
+		t.Cleanup(func() { client.ForceStop() })
+		if err := client.Start(t.Context()); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		if _, err := client.CreateSession(t.Context(), config); !errors.Is(err, ErrMessageTooLarge) {
+			t.Errorf("Expected ErrMessageTooLarge, got %v", err)
+		}
+	})
+}
+
+// BenchmarkClient_CreateSession100Tools creates sessions with 100 catalog
+// tools, serializing the schemas for each session or once with PrepareTools.
+func BenchmarkClient_CreateSession100Tools(b *testing.B) {
+	cli := newFakeCLI(b, func(method string, params json.RawMessage) (any, error) {
+		if method == "session.create" {
+			return createSessionResponse{SessionID: "s1"}, nil
+		}
+		return nil, nil
+	})
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	b.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(b.Context()); err != nil {
+		b.Fatalf("Failed to start: %v", err)
+	}
+
+	plain := catalogTools(100)
+	prepared, err := PrepareTools(plain)
+	if err != nil {
+		b.Fatalf("Failed to prepare tools: %v", err)
+	}
+	data, _ := json.Marshal(plain)
+	b.Logf("100 tools serialize to %d KiB", len(data)>>10)
+
+	for _, bench := range []struct {
+		name  string
+		tools []Tool
+	}{{"plain", plain}, {"prepared", prepared}} {
+		b.Run(bench.name, func(b *testing.B) {
+			config := &SessionConfig{Tools: bench.tools, OnPermissionRequest: PermissionHandler.ApproveAll}
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := client.CreateSession(b.Context(), config); err != nil {
+					b.Fatalf("Failed to create session: %v", err)
+				}
+			}
+		})
+	}
+}
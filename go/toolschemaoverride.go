@@ -0,0 +1,231 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrToolSchemaMismatch matches errors from [Client.CreateSession],
+// [Client.ResumeSessionWithOptions] and [Session.RegisterTools] for a tool
+// made with [DefineToolWithSchema] whose schema does not fit its handler's
+// parameter type.
+var ErrToolSchemaMismatch = errors.New("tool schema does not match its parameter type")
+
+// ToolSchemaError is returned when the schema given to
+// [DefineToolWithSchema] is invalid or allows arguments the handler cannot
+// decode. It matches [ErrToolSchemaMismatch].
+type ToolSchemaError struct {
+	// Tool is the tool's name
+	Tool string
+	// Path locates the offending part of the schema, e.g.
+	// "parameters.properties.unit", or is empty for the schema as a whole
+	Path string
+	// Reason describes the mismatch
+	Reason string
+}
+
+func (e *ToolSchemaError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v: tool %q", ErrToolSchemaMismatch, e.Tool)
+	if e.Path != "" {
+		fmt.Fprintf(&b, " at %s", e.Path)
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Reason)
+	return b.String()
+}
+
+func (e *ToolSchemaError) Is(target error) bool { return target == ErrToolSchemaMismatch }
+
+// DefineToolWithSchema is like [DefineTool], but sends schema as the tool's
+// parameter schema instead of one reflected from T. Use it for constraints
+// the reflected schema cannot express, such as enum, minimum, pattern or
+// oneOf, which make the model call the tool more reliably. The jsonschema
+// struct tag only sets descriptions.
+//
+// The schema must fit T: every property must name a field of T, and its
+// type must decode into that field. Sessions given a tool whose schema
+// does not fit fail to be created with a *[ToolSchemaError], rather than
+// the handler failing on arguments it cannot decode.
+//
+// Example:
+//
+//	type ForecastParams struct {
+//	    City string `json:"city"`
+//	    Unit string `json:"unit"`
+//	    Days int    `json:"days"`
+//	}
+//
+//	tool := copilot.DefineToolWithSchema("get_forecast", "Get the weather forecast for a city",
+//	    json.RawMessage(`{
+//	        "type": "object",
+//	        "properties": {
+//	            "city": {"type": "string", "minLength": 1},
+//	            "unit": {"type": "string", "enum": ["celsius", "fahrenheit"]},
+//	            "days": {"type": "integer", "minimum": 1, "maximum": 14}
+//	        },
+//	        "required": ["city"]
+//	    }`),
+//	    func(params ForecastParams, inv copilot.ToolInvocation) (any, error) {
+//	        return forecast(params.City, params.Unit, params.Days)
+//	    })
+func DefineToolWithSchema[T any, U any](name, description string, schema json.RawMessage, handler func(T, ToolInvocation) (U, error)) Tool {
+	tool := Tool{
+		Name:        name,
+		Description: description,
+		Handler: createTypedHandler(func(_ context.Context, params T, inv ToolInvocation) (U, error) {
+			return handler(params, inv)
+		}),
+	}
+	if err := json.Unmarshal(schema, &tool.Parameters); err != nil || tool.Parameters == nil {
+		reason := "schema must be a JSON object"
+		if err != nil {
+			reason = fmt.Sprintf("schema is not valid JSON: %v", err)
+		}
+		tool.checkSchema = func(map[string]any) error {
+			return &ToolSchemaError{Tool: name, Reason: reason}
+		}
+		return tool
+	}
+	var zero T
+	paramsType := reflect.TypeOf(zero)
+	tool.checkSchema = func(parameters map[string]any) error {
+		return checkSchemaFits(name, parameters, paramsType)
+	}
+	return tool
+}
+
+// checkToolSchemas checks the schemas of tools made with
+// [DefineToolWithSchema] against their parameter types.
+func checkToolSchemas(tools []Tool) error {
+	for _, tool := range tools {
+		if tool.checkSchema == nil {
+			continue
+		}
+		if err := tool.checkSchema(tool.Parameters); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSchemaFits fails if schema allows arguments that do not decode into
+// paramsType, by comparing it with the schema reflected from paramsType.
+func checkSchemaFits(name string, schema map[string]any, paramsType reflect.Type) error {
+	reflected := generateSchemaForType(paramsType)
+	if path, reason := compareSchema("parameters", schema, reflected); reason != "" {
+		return &ToolSchemaError{Tool: name, Path: path, Reason: fmt.Sprintf("%s (params type %v)", reason, paramsType)}
+	}
+	return nil
+}
+
+// compareSchema returns the path of the first part of given that allows
+// values the reflected schema does not, and why, or an empty reason if
+// there is none. Parts of either schema it cannot follow, such as $ref, are
+// accepted.
+func compareSchema(path string, given, reflected map[string]any) (string, string) {
+	if _, ok := given["$ref"]; ok {
+		return "", ""
+	}
+	if _, ok := reflected["$ref"]; ok {
+		return "", ""
+	}
+	for _, keyword := range []string{"oneOf", "anyOf", "allOf"} {
+		alternatives, _ := given[keyword].([]any)
+		for i, alternative := range alternatives {
+			if alternative, ok := alternative.(map[string]any); ok {
+				if path, reason := compareSchema(fmt.Sprintf("%s.%s[%d]", path, keyword, i), alternative, reflected); reason != "" {
+					return path, reason
+				}
+			}
+		}
+	}
+
+	givenTypes, reflectedTypes := schemaTypes(given), schemaTypes(reflected)
+	if len(reflectedTypes) > 0 {
+		for _, t := range givenTypes {
+			if !typeFits(t, reflectedTypes) {
+				return path + ".type", fmt.Sprintf("schema allows %s, but the field is %s", t, strings.Join(reflectedTypes, " or "))
+			}
+		}
+	}
+
+	reflectedProperties, isStruct := reflected["properties"].(map[string]any)
+	if givenProperties, ok := given["properties"].(map[string]any); ok && isStruct {
+		names := make([]string, 0, len(givenProperties))
+		for property := range givenProperties {
+			names = append(names, property)
+		}
+		sort.Strings(names)
+		for _, property := range names {
+			field, ok := lookupProperty(reflectedProperties, property)
+			if !ok {
+				return path + ".properties." + property, "the params type has no field for this property, so its value would be dropped"
+			}
+			givenProperty, _ := givenProperties[property].(map[string]any)
+			fieldSchema, _ := field.(map[string]any)
+			if path, reason := compareSchema(path+".properties."+property, givenProperty, fieldSchema); reason != "" {
+				return path, reason
+			}
+		}
+	}
+	if required, ok := given["required"].([]any); ok && isStruct {
+		for _, property := range required {
+			if name, ok := property.(string); ok {
+				if _, ok := lookupProperty(reflectedProperties, name); !ok {
+					return path + ".required", fmt.Sprintf("required property %q has no field in the params type", name)
+				}
+			}
+		}
+	}
+
+	givenItems, ok := given["items"].(map[string]any)
+	reflectedItems, isSlice := reflected["items"].(map[string]any)
+	if ok && isSlice {
+		return compareSchema(path+".items", givenItems, reflectedItems)
+	}
+	return "", ""
+}
+
+// schemaTypes returns the JSON types a schema allows, other than null.
+func schemaTypes(schema map[string]any) []string {
+	var types []string
+	switch t := schema["type"].(type) {
+	case string:
+		types = []string{t}
+	case []any:
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	// null decodes into any field, leaving it unchanged
+	return slices.DeleteFunc(types, func(t string) bool { return t == "null" })
+}
+
+// typeFits reports whether values of JSON type t decode into a field that
+// accepts the reflected types. Integers decode into float fields.
+func typeFits(t string, reflected []string) bool {
+	return slices.Contains(reflected, t) || (t == "integer" && slices.Contains(reflected, "number"))
+}
+
+// lookupProperty finds a property by name, ignoring case as encoding/json
+// does when decoding into a struct.
+func lookupProperty(properties map[string]any, name string) (any, bool) {
+	if value, ok := properties[name]; ok {
+		return value, true
+	}
+	for property, value := range properties {
+		if strings.EqualFold(property, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,167 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type forecastParams struct {
+	City  string    `json:"city"`
+	Unit  *string   `json:"unit,omitempty"`
+	Days  int       `json:"days"`
+	Temp  float64   `json:"temp"`
+	Tags  []string  `json:"tags"`
+	Where *location `json:"where,omitempty"`
+	Extra any       `json:"extra"`
+}
+
+type location struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+func TestDefineToolWithSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		wantPath string // empty if the schema fits
+	}{
+		{
+			name: "constraints the reflected schema cannot express",
+			schema: `{"type": "object", "properties": {
+				"city": {"type": "string", "pattern": "^[A-Z]"},
+				"unit": {"type": ["string", "null"], "enum": ["celsius", "fahrenheit"]},
+				"days": {"type": "integer", "minimum": 1, "maximum": 14},
+				"tags": {"type": "array", "items": {"type": "string", "enum": ["rain", "wind"]}},
+				"where": {"oneOf": [{"type": "object", "properties": {"lat": {"type": "number"}}}, {"type": "null"}]},
+				"extra": {"type": "object"}
+			}, "required": ["city"]}`,
+		},
+		{
+			name:   "integers into a float field and property names in another case",
+			schema: `{"type": "object", "properties": {"Temp": {"type": "integer"}}}`,
+		},
+		{
+			name:     "wrong property type",
+			schema:   `{"type": "object", "properties": {"days": {"type": "string"}}}`,
+			wantPath: "parameters.properties.days.type",
+		},
+		{
+			name:     "numbers into an integer field",
+			schema:   `{"type": "object", "properties": {"days": {"type": "number"}}}`,
+			wantPath: "parameters.properties.days.type",
+		},
+		{
+			name:     "property without a field",
+			schema:   `{"type": "object", "properties": {"country": {"type": "string"}}}`,
+			wantPath: "parameters.properties.country",
+		},
+		{
+			name:     "required property without a field",
+			schema:   `{"type": "object", "properties": {}, "required": ["country"]}`,
+			wantPath: "parameters.required",
+		},
+		{
+			name:     "wrong array items",
+			schema:   `{"type": "object", "properties": {"tags": {"type": "array", "items": {"type": "integer"}}}}`,
+			wantPath: "parameters.properties.tags.items.type",
+		},
+		{
+			name:     "wrong alternative of a nested object",
+			schema:   `{"type": "object", "properties": {"where": {"anyOf": [{"type": "object", "properties": {"lat": {"type": "string"}}}]}}}`,
+			wantPath: "parameters.properties.where.anyOf[0].properties.lat.type",
+		},
+		{
+			name:     "not an object schema",
+			schema:   `{"type": "array"}`,
+			wantPath: "parameters.type",
+		},
+		{
+			name:   "not JSON",
+			schema: `{"type": `,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := DefineToolWithSchema("get_forecast", "Get the forecast", json.RawMessage(tt.schema),
+				func(params forecastParams, inv ToolInvocation) (any, error) { return params.City, nil })
+			err := checkToolSchemas([]Tool{tool})
+			if tt.name == "not JSON" {
+				if !errors.Is(err, ErrToolSchemaMismatch) || !strings.Contains(err.Error(), "not valid JSON") {
+					t.Errorf("Expected an invalid JSON error, got %v", err)
+				}
+				return
+			}
+			if tt.wantPath == "" {
+				if err != nil {
+					t.Errorf("Expected the schema to fit, got %v", err)
+				}
+				return
+			}
+			var schemaErr *ToolSchemaError
+			if !errors.As(err, &schemaErr) || !errors.Is(err, ErrToolSchemaMismatch) {
+				t.Fatalf("Expected a *ToolSchemaError, got %v", err)
+			}
+			if schemaErr.Tool != "get_forecast" || schemaErr.Path != tt.wantPath {
+				t.Errorf("Expected the error at %s, got %q at %s: %v", tt.wantPath, schemaErr.Tool, schemaErr.Path, err)
+			}
+		})
+	}
+
+	t.Run("sends the schema and decodes arguments", func(t *testing.T) {
+		tool := DefineToolWithSchema("get_forecast", "Get the forecast",
+			json.RawMessage(`{"type": "object", "properties": {"city": {"type": "string", "enum": ["Oslo"]}}}`),
+			func(params forecastParams, inv ToolInvocation) (string, error) { return params.City, nil })
+		data, err := json.Marshal(tool)
+		if err != nil || !strings.Contains(string(data), `"enum":["Oslo"]`) {
+			t.Errorf("Expected the given schema in the definition, got %s (%v)", data, err)
+		}
+		result, err := tool.Handler(ToolInvocation{Arguments: map[string]any{"city": "Oslo"}})
+		if err != nil || result.TextResultForLLM != "Oslo" {
+			t.Errorf("Expected the handler to get the decoded city, got %+v (%v)", result, err)
+		}
+	})
+}
+
+func TestClient_CreateSessionToolSchema(t *testing.T) {
+	var mu sync.Mutex
+	var created []string
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		if method != "session.create" {
+			return nil, nil
+		}
+		mu.Lock()
+		created = append(created, string(params))
+		mu.Unlock()
+		return createSessionResponse{SessionID: "s1"}, nil
+	})
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	t.Cleanup(func() { client.ForceStop() })
+
+	handler := func(params forecastParams, inv ToolInvocation) (any, error) { return nil, nil }
+	_, err := client.CreateSession(t.Context(), &SessionConfig{
+		OnPermissionRequest: PermissionHandler.ApproveAll,
+		Tools: []Tool{DefineToolWithSchema("get_forecast", "Get the forecast",
+			json.RawMessage(`{"type": "object", "properties": {"days": {"type": "string"}}}`), handler)},
+	})
+	if !errors.Is(err, ErrToolSchemaMismatch) || !strings.Contains(err.Error(), "forecastParams") {
+		t.Errorf("Expected CreateSession to fail naming the params type, got %v", err)
+	}
+
+	_, err = client.CreateSession(t.Context(), &SessionConfig{
+		OnPermissionRequest: PermissionHandler.ApproveAll,
+		Tools: []Tool{DefineToolWithSchema("get_forecast", "Get the forecast",
+			json.RawMessage(`{"type": "object", "properties": {"days": {"type": "integer", "maximum": 14}}}`), handler)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(created) != 1 || !strings.Contains(created[0], `"maximum":14`) {
+		t.Errorf("Expected only the fitting schema to reach session.create, got %v", created)
+	}
+}
@@ -0,0 +1,134 @@
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ToolTimeoutErrorCode is the error code of the ToolExecutionComplete event
+// the SDK emits when a tool call exceeds its [Tool.Timeout].
+const ToolTimeoutErrorCode = "tool_timeout"
+
+// maxAbandonedToolCalls bounds the number of timed-out tool handlers that may
+// still be running per client. Once reached, calls to tools with a timeout
+// fail immediately instead of starting another handler.
+const maxAbandonedToolCalls = 32
+
+// toolAbandonGrace is how long an abandoned handler may keep running before
+// the SDK logs that it ignored cancellation.
+const toolAbandonGrace = 10 * time.Second
+
+// toolCancelGrace is how long a handler whose context is cancelled before
+// its deadline, by an abort or the session's destruction, may take to return
+// before it is abandoned.
+const toolCancelGrace = time.Second
+
+// executeToolCallWithTimeout runs a tool handler with a deadline. When the
+// deadline passes, the invocation's context is cancelled, the model gets a
+// timeout result, and the handler is abandoned: its eventual result is
+// discarded. A handler cancelled earlier gets toolCancelGrace to return, so
+// an aborted call does not wait out the timeout, holding the session's
+// sequential tool lock, for a handler that ignores cancellation.
+func (c *Client) executeToolCallWithTimeout(session *Session, invocation ToolInvocation, handler ToolHandler, timeout time.Duration) ToolResult {
+	if n := c.abandonedTools.Load(); n >= maxAbandonedToolCalls {
+		c.logger.Error("not running tool: too many timed-out tool handlers are still running",
+			"sessionID", invocation.SessionID, "tool", invocation.ToolName, "toolCallID", invocation.ToolCallID, "running", n)
+		return buildFailedToolResult(fmt.Sprintf("tool '%s' not run: %d timed-out tool handlers are still running", invocation.ToolName, n))
+	}
+
+	parent := invocation.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	invocation.Context = ctx
+
+	done := make(chan ToolResult, 1)
+	go func() {
+		done <- c.executeToolCall(invocation, handler)
+	}()
+
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+	}
+	cancelled := ctx.Err() != context.DeadlineExceeded
+	if cancelled {
+		// Cancelled by an abort or the session's destruction rather than the
+		// timeout: the handler has a moment, up to its deadline, to return
+		grace := toolCancelGrace
+		if deadline, _ := ctx.Deadline(); time.Until(deadline) < grace {
+			grace = time.Until(deadline)
+		}
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case result := <-done:
+			return result
+		case <-timer.C:
+		}
+	}
+
+	c.abandonedTools.Add(1)
+	go func() {
+		defer c.abandonedTools.Add(-1)
+		select {
+		case <-done:
+		case <-time.After(toolAbandonGrace):
+			c.logger.Warn("tool ignored cancellation; abandoning it",
+				"sessionID", invocation.SessionID, "tool", invocation.ToolName, "toolCallID", invocation.ToolCallID,
+				"timeout", timeout, "cancelled", cancelled)
+			<-done
+		}
+	}()
+
+	if cancelled {
+		return buildCancelledToolResult(invocation.ToolName, context.Cause(ctx))
+	}
+	session.emitToolTimeout(invocation.ToolCallID, invocation.ToolName, timeout)
+	return buildTimedOutToolResult(invocation.ToolName, timeout)
+}
+
+// emitToolTimeout delivers a failed ToolExecutionComplete event, with error
+// code ToolTimeoutErrorCode, to the session's event handlers.
+func (s *Session) emitToolTimeout(toolCallID, toolName string, timeout time.Duration) {
+	s.dispatchEvent(SessionEvent{
+		Type:      ToolExecutionComplete,
+		Timestamp: time.Now(),
+		Ephemeral: Bool(true),
+		Data: Data{
+			ToolCallID: String(toolCallID),
+			ToolName:   String(toolName),
+			Success:    Bool(false),
+			Error: &ErrorUnion{ErrorClass: &ErrorClass{
+				Code:    String(ToolTimeoutErrorCode),
+				Message: fmt.Sprintf("tool timed out after %s", timeout),
+			}},
+		},
+	})
+}
+
+// buildCancelledToolResult creates a failure ToolResult for a tool call
+// abandoned after its context was cancelled before the deadline.
+func buildCancelledToolResult(toolName string, cause error) ToolResult {
+	return ToolResult{
+		TextResultForLLM: fmt.Sprintf("Tool '%s' was cancelled.", toolName),
+		ResultType:       "failure",
+		Error:            fmt.Sprintf("tool cancelled: %v", cause),
+		ToolTelemetry:    map[string]any{},
+	}
+}
+
+// buildTimedOutToolResult creates a failure ToolResult for a tool call that
+// exceeded its timeout.
+func buildTimedOutToolResult(toolName string, timeout time.Duration) ToolResult {
+	return ToolResult{
+		TextResultForLLM: fmt.Sprintf("Tool '%s' timed out after %s and was cancelled.", toolName, timeout),
+		ResultType:       "failure",
+		Error:            fmt.Sprintf("tool timed out after %s", timeout),
+		ToolTelemetry:    map[string]any{},
+	}
+}
@@ -0,0 +1,179 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_ToolTimeout(t *testing.T) {
+	newToolSession := func(t *testing.T, defaultTimeout time.Duration, tools ...Tool) (*Client, *Session) {
+		client := NewClient(nil)
+		session := newSession("s1", nil, "")
+		session.registerTools(tools, defaultTimeout)
+		client.sessions.put(session)
+		return client, session
+	}
+	var callIDs atomic.Int32
+	call := func(client *Client, toolName string) ToolResult {
+		response, _ := client.handleToolCallRequest(toolCallRequest{
+			SessionID:  "s1",
+			ToolCallID: fmt.Sprintf("call-%d", callIDs.Add(1)),
+			ToolName:   toolName,
+			Arguments:  map[string]any{},
+		})
+		return response.Result
+	}
+
+	t.Run("cancels a sleeping handler and reports the timeout", func(t *testing.T) {
+		cancelled := make(chan error, 1)
+		client, session := newToolSession(t, 0, Tool{
+			Name:    "slow_query",
+			Timeout: 20 * time.Millisecond,
+			Handler: func(invocation ToolInvocation) (ToolResult, error) {
+				select {
+				case <-invocation.Context.Done():
+					cancelled <- invocation.Context.Err()
+				case <-time.After(5 * time.Second):
+					cancelled <- nil
+				}
+				return ToolResult{TextResultForLLM: "done"}, nil
+			},
+		})
+		var events []SessionEvent
+		session.On(func(event SessionEvent) { events = append(events, event) })
+
+		start := time.Now()
+		result := call(client, "slow_query")
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected the call to return at the deadline, took %s", elapsed)
+		}
+		if result.ResultType != "failure" || result.Error != "tool timed out after 20ms" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+		if result.TextResultForLLM != "Tool 'slow_query' timed out after 20ms and was cancelled." {
+			t.Errorf("Unexpected text for the model: %q", result.TextResultForLLM)
+		}
+		if err := <-cancelled; !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected the handler's context to hit its deadline, got %v", err)
+		}
+
+		if len(events) != 1 || events[0].Type != ToolExecutionComplete {
+			t.Fatalf("Expected a ToolExecutionComplete event, got %+v", events)
+		}
+		data := events[0].Data
+		if data.Success == nil || *data.Success || *data.ToolCallID == "" ||
+			data.Error == nil || data.Error.ErrorClass == nil || *data.Error.ErrorClass.Code != ToolTimeoutErrorCode {
+			t.Errorf("Expected a failed event flagged as timeout, got %+v", data)
+		}
+	})
+
+	t.Run("session default applies to tools without a timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		t.Cleanup(func() { close(release) })
+		client, _ := newToolSession(t, 10*time.Millisecond,
+			Tool{Name: "stuck", Handler: func(ToolInvocation) (ToolResult, error) {
+				<-release
+				return ToolResult{}, nil
+			}},
+			Tool{Name: "fast", Handler: func(ToolInvocation) (ToolResult, error) {
+				return ToolResult{TextResultForLLM: "ok", ResultType: "success"}, nil
+			}},
+			Tool{Name: "unbounded", Timeout: -1, Handler: func(invocation ToolInvocation) (ToolResult, error) {
+				time.Sleep(30 * time.Millisecond)
+				return ToolResult{TextResultForLLM: "ok", ResultType: "success"}, nil
+			}},
+		)
+
+		if result := call(client, "stuck"); result.Error != "tool timed out after 10ms" {
+			t.Errorf("Expected timeout, got %+v", result)
+		}
+		if result := call(client, "fast"); result.ResultType != "success" {
+			t.Errorf("Expected success, got %+v", result)
+		}
+		if result := call(client, "unbounded"); result.ResultType != "success" {
+			t.Errorf("Expected a negative timeout to disable the default, got %+v", result)
+		}
+	})
+
+	t.Run("abandons a handler that ignores an abort", func(t *testing.T) {
+		release := make(chan struct{})
+		t.Cleanup(func() { close(release) })
+		started := make(chan struct{})
+		client, session := newToolSession(t, 0, Tool{
+			Name:    "long_build",
+			Timeout: time.Minute,
+			Handler: func(ToolInvocation) (ToolResult, error) {
+				close(started)
+				<-release
+				return ToolResult{}, nil
+			},
+		})
+
+		results := make(chan ToolResult, 1)
+		go func() { results <- call(client, "long_build") }()
+		<-started
+		start := time.Now()
+		session.trace.abort()
+
+		select {
+		case result := <-results:
+			if elapsed := time.Since(start); elapsed < toolCancelGrace {
+				t.Errorf("Expected the handler to get %s to return, took %s", toolCancelGrace, elapsed)
+			}
+			if result.ResultType != "failure" || result.Error != "tool cancelled: context canceled" {
+				t.Errorf("Expected a cancelled result, got %+v", result)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the aborted call to return without waiting out its timeout")
+		}
+		if n := client.abandonedTools.Load(); n != 1 {
+			t.Errorf("Expected the handler to be abandoned, got %d", n)
+		}
+	})
+
+	t.Run("refuses new calls once too many handlers are abandoned", func(t *testing.T) {
+		release := make(chan struct{})
+		var calls atomic.Int32
+		client, _ := newToolSession(t, 0, Tool{
+			Name:    "ignores_cancel",
+			Timeout: 10 * time.Millisecond,
+			Handler: func(ToolInvocation) (ToolResult, error) {
+				calls.Add(1)
+				<-release
+				return ToolResult{}, nil
+			},
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < maxAbandonedToolCalls; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				call(client, "ignores_cancel")
+			}()
+		}
+		wg.Wait()
+
+		result := call(client, "ignores_cancel")
+		if result.ResultType != "failure" || result.Error != "tool 'ignores_cancel' not run: 32 timed-out tool handlers are still running" {
+			t.Errorf("Expected the call to be refused, got %+v", result)
+		}
+		if n := calls.Load(); n > maxAbandonedToolCalls {
+			t.Errorf("Expected at most %d handler calls, got %d", maxAbandonedToolCalls, n)
+		}
+
+		close(release)
+		deadline := time.Now().Add(2 * time.Second)
+		for client.abandonedTools.Load() != 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if n := client.abandonedTools.Load(); n != 0 {
+			t.Errorf("Expected abandoned handlers to be released, got %d", n)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package copilot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+// turnTrace tracks the turn that handler invocations belong to, so tool
+// calls, permission and user input requests, and hooks can be correlated
+// with the message that caused them and with the turn's events.
+//
+// The trace ID of a turn is the interaction ID the CLI reports on the turn's
+// user.message event. Until that event arrives, or if the CLI reports none,
+// it is an ID generated by the SDK, which is then set as the interaction ID
+// of the turn's events that lack one.
+type turnTrace struct {
+	mu sync.Mutex
+	// ctx is cancelled by Abort, after which a new one is created, and when
+	// the session is destroyed
+	ctx       context.Context
+	cancel    context.CancelFunc
+	lifetime  context.Context
+	close     context.CancelFunc
+	messageID string
+	traceID   string
+	adopted   bool // traceID is the CLI's interaction ID
+	active    bool // a turn is in progress
+}
+
+// initLocked creates the contexts on first use.
+func (t *turnTrace) initLocked() {
+	if t.lifetime == nil {
+		t.lifetime, t.close = context.WithCancel(context.Background())
+	}
+	if t.ctx == nil {
+		t.ctx, t.cancel = context.WithCancel(t.lifetime)
+	}
+}
+
+// begin starts a turn before its message is sent.
+func (t *turnTrace) begin() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messageID = ""
+	t.traceID = newTraceID()
+	t.adopted = false
+	t.active = true
+}
+
+// recordSend records the message ID returned for the turn's message.
+func (t *turnTrace) recordSend(messageID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messageID = messageID
+}
+
+// end ends the turn when its message could not be sent.
+func (t *turnTrace) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active = false
+}
+
+// observeEvent adopts the CLI's interaction ID for the turn, and sets the
+// trace ID on events of the turn that have no interaction ID. An abort event
+// cancels the context of running invocations, so turns aborted by the CLI or
+// another client stop them too.
+func (t *turnTrace) observeEvent(event *SessionEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if event.Type == Abort {
+		t.abortLocked()
+	}
+	if !t.active {
+		return
+	}
+	if event.Type == UserMessage && !t.adopted && event.Data.InteractionID != nil && *event.Data.InteractionID != "" {
+		t.traceID = *event.Data.InteractionID
+		t.adopted = true
+	}
+	if event.Data.InteractionID == nil {
+		event.Data.InteractionID = String(t.traceID)
+	}
+	if event.Type == SessionIdle {
+		t.active = false
+	}
+}
+
+// current returns the context, message ID and trace ID for an invocation.
+// The IDs are those of the most recently sent message.
+func (t *turnTrace) current() (context.Context, string, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.initLocked()
+	return t.ctx, t.messageID, t.traceID
+}
+
+// abort cancels the context of running invocations.
+func (t *turnTrace) abort() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.abortLocked()
+}
+
+func (t *turnTrace) abortLocked() {
+	t.initLocked()
+	t.cancel()
+	t.ctx, t.cancel = context.WithCancel(t.lifetime)
+}
+
+// destroy cancels the context of running and future invocations.
+func (t *turnTrace) destroy() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.initLocked()
+	t.close()
+}
+
+// newTraceID returns a random ID for a turn.
+func newTraceID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "sdk-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,138 @@
+package copilot
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSession_InvocationTrace(t *testing.T) {
+	newTracedSession := func(t *testing.T) (*Client, *Session, *fakeServer) {
+		session, server := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.send" {
+				return sessionSendResponse{MessageID: "m1"}, nil
+			}
+			return nil, nil
+		})
+		client := NewClient(nil)
+		client.sessions.put(session)
+		return client, session, server
+	}
+	// waitFor emits an event and waits until the session's handlers see it.
+	waitFor := func(t *testing.T, session *Session, server *fakeServer, event SessionEvent) SessionEvent {
+		t.Helper()
+		seen := make(chan SessionEvent, 1)
+		unsubscribe := session.OnTypes([]SessionEventType{event.Type}, func(e SessionEvent) { seen <- e })
+		defer unsubscribe()
+		server.emit(event)
+		select {
+		case e := <-seen:
+			return e
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for %s", event.Type)
+			return SessionEvent{}
+		}
+	}
+
+	t.Run("correlates a tool call with its hook and events", func(t *testing.T) {
+		client, session, server := newTracedSession(t)
+		var mu sync.Mutex
+		var toolInv ToolInvocation
+		var hookInv HookInvocation
+		session.registerTools([]Tool{{Name: "lookup", Handler: func(inv ToolInvocation) (ToolResult, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			toolInv = inv
+			return ToolResult{TextResultForLLM: "ok"}, nil
+		}}}, 0)
+		session.registerHooks(&SessionHooks{OnPreToolUse: func(input PreToolUseHookInput, inv HookInvocation) (*PreToolUseHookOutput, error) {
+			hookInv = inv
+			return nil, nil
+		}})
+
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "look it up"}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		waitFor(t, session, server, SessionEvent{Type: UserMessage, Data: Data{Content: String("look it up"), InteractionID: String("int-1")}})
+		if _, err := session.handleHooksInvoke("preToolUse", json.RawMessage(`{"toolName":"lookup"}`)); err != nil {
+			t.Fatalf("Hook failed: %v", err)
+		}
+		client.handleToolCallRequest(toolCallRequest{SessionID: session.SessionID, ToolCallID: "c1", ToolName: "lookup"})
+		started := waitFor(t, session, server, SessionEvent{Type: ToolExecutionStart, Data: Data{ToolCallID: String("c1")}})
+
+		mu.Lock()
+		defer mu.Unlock()
+		if toolInv.TraceID != "int-1" || toolInv.MessageID != "m1" {
+			t.Errorf("Expected tool invocation traced to int-1 and m1, got %q and %q", toolInv.TraceID, toolInv.MessageID)
+		}
+		if hookInv.TraceID != "int-1" || hookInv.MessageID != "m1" {
+			t.Errorf("Expected hook invocation traced to int-1 and m1, got %q and %q", hookInv.TraceID, hookInv.MessageID)
+		}
+		if started.Data.InteractionID == nil || *started.Data.InteractionID != "int-1" {
+			t.Errorf("Expected the event's interaction ID to be int-1, got %v", started.Data.InteractionID)
+		}
+	})
+
+	t.Run("generates a trace ID when the CLI reports none", func(t *testing.T) {
+		_, session, server := newTracedSession(t)
+		if _, err := session.Send(t.Context(), MessageOptions{Prompt: "hi"}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		_, _, traceID := session.trace.current()
+		if !strings.HasPrefix(traceID, "sdk-") {
+			t.Fatalf("Expected a generated trace ID, got %q", traceID)
+		}
+		event := waitFor(t, session, server, SessionEvent{Type: AssistantMessage, Data: Data{Content: String("hello")}})
+		if event.Data.InteractionID == nil || *event.Data.InteractionID != traceID {
+			t.Errorf("Expected the event's interaction ID to be %q, got %v", traceID, event.Data.InteractionID)
+		}
+
+		waitFor(t, session, server, SessionEvent{Type: SessionIdle})
+		event = waitFor(t, session, server, SessionEvent{Type: AssistantMessage, Data: Data{Content: String("later")}})
+		if event.Data.InteractionID != nil {
+			t.Errorf("Expected events after the turn to be left alone, got %q", *event.Data.InteractionID)
+		}
+	})
+
+	t.Run("cancels invocation contexts on abort", func(t *testing.T) {
+		_, session, _ := newTracedSession(t)
+		session.registerPermissionHandler(func(request PermissionRequest, inv PermissionInvocation) (PermissionRequestResult, error) {
+			go session.Abort(t.Context())
+			select {
+			case <-inv.Context.Done():
+				return DeniedByUser("aborted"), nil
+			case <-time.After(2 * time.Second):
+				return Approved(), nil
+			}
+		})
+		result, err := session.handlePermissionRequest(PermissionRequest{Kind: "shell"})
+		if err != nil || result.Kind != PermissionDeniedByUser {
+			t.Errorf("Expected the context to be cancelled by Abort, got %+v, %v", result, err)
+		}
+
+		ctx, _, _ := session.trace.current()
+		if ctx.Err() != nil {
+			t.Error("Expected later invocations to get a live context")
+		}
+	})
+
+	t.Run("cancels invocation contexts on destroy", func(t *testing.T) {
+		_, session, _ := newTracedSession(t)
+		var inv UserInputInvocation
+		session.registerUserInputHandler(func(request UserInputRequest, i UserInputInvocation) (UserInputResponse, error) {
+			inv = i
+			return UserInputResponse{Answer: "yes"}, nil
+		})
+		if _, err := session.handleUserInputRequest(UserInputRequest{Question: "continue?"}); err != nil {
+			t.Fatalf("User input failed: %v", err)
+		}
+		session.markDestroyed("destroyed")
+		select {
+		case <-inv.Context.Done():
+		case <-time.After(2 * time.Second):
+			t.Error("Expected the context to be cancelled on destroy")
+		}
+	})
+}
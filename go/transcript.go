@@ -0,0 +1,102 @@
+package copilot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markdown renders the turn as Markdown: the prompt, the assistant messages,
+// and a line per tool call, with the diff of files the call edited and any
+// [ToolInvocation.Log] entries in a collapsed <details> block.
+func (t *Turn) Markdown() string {
+	return renderMarkdown(t.Events)
+}
+
+// Markdown renders the turn's events like [Turn.Markdown].
+//
+// Example:
+//
+//	result, err := session.SendAndCollect(ctx, copilot.MessageOptions{Prompt: "Triage the new issues"}, nil)
+//	if err == nil {
+//	    os.WriteFile("turn.md", []byte(result.Markdown()), 0o644)
+//	}
+func (r *TurnResult) Markdown() string {
+	return renderMarkdown(r.Events)
+}
+
+// renderMarkdown renders user and assistant messages and tool calls.
+func renderMarkdown(events []SessionEvent) string {
+	var b strings.Builder
+	toolNames := make(map[string]string)
+	toolArgs := make(map[string]any)
+	completed := make(map[string]bool)
+	for _, event := range events {
+		switch event.Type {
+		case UserMessage:
+			if event.Data.Content != nil {
+				fmt.Fprintf(&b, "### User\n\n%s\n\n", strings.TrimSpace(*event.Data.Content))
+			}
+		case AssistantMessage:
+			if event.Data.Content != nil && strings.TrimSpace(*event.Data.Content) != "" {
+				fmt.Fprintf(&b, "### Assistant\n\n%s\n\n", strings.TrimSpace(*event.Data.Content))
+			}
+		case ToolExecutionStart:
+			if event.Data.ToolCallID != nil && event.Data.ToolName != nil {
+				toolNames[*event.Data.ToolCallID] = *event.Data.ToolName
+				toolArgs[*event.Data.ToolCallID] = event.Data.Arguments
+			}
+		case ToolExecutionComplete:
+			if event.Data.ToolCallID == nil || completed[*event.Data.ToolCallID] {
+				continue
+			}
+			id := *event.Data.ToolCallID
+			completed[id] = true
+			name := toolNames[id]
+			if name == "" && event.Data.ToolName != nil {
+				name = *event.Data.ToolName
+			}
+			status := "succeeded"
+			if event.Data.Success != nil && !*event.Data.Success {
+				status = "failed"
+			}
+			fmt.Fprintf(&b, "- Tool `%s` %s\n\n", name, status)
+			if diff := toolFileDiff(toolArgs[id]); status == "succeeded" && diff != nil && !diff.Empty() {
+				unified := diff.Unified()
+				fence := fenceFor(unified)
+				fmt.Fprintf(&b, "%sdiff\n%s%s\n\n", fence, unified, fence)
+			}
+			if logs := event.ToolLogs(); len(logs) > 0 {
+				writeToolLogs(&b, logs)
+			}
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// writeToolLogs writes log entries as a collapsed code block.
+func writeToolLogs(b *strings.Builder, logs []ToolLogEntry) {
+	lines := make([]string, len(logs))
+	for i, entry := range logs {
+		lines[i] = fmt.Sprintf("%s %-5s %s", entry.Time.Format("15:04:05.000"), entry.Level, entry.Message)
+	}
+	text := strings.Join(lines, "\n")
+	fence := fenceFor(text)
+	fmt.Fprintf(b, "<details>\n<summary>Logs (%d)</summary>\n\n%s\n%s\n%s\n\n</details>\n\n", len(logs), fence, text, fence)
+}
+
+// fenceFor returns a fence of backticks longer than any run in text.
+func fenceFor(text string) string {
+	longestRun, run := 0, 0
+	for _, c := range text {
+		if c == '`' {
+			run++
+			longestRun = max(longestRun, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longestRun+1))
+}
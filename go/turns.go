@@ -0,0 +1,419 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ErrTurnNotFound is returned by [Session.FindTurn] when no turn in the
+// session history matches the given message ID.
+var ErrTurnNotFound = errors.New("turn not found")
+
+// MessageRef links the message ID returned by [Session.Send] to the identifiers
+// the CLI uses for the same turn in events and history.
+type MessageRef struct {
+	// MessageID is the ID returned by Send
+	MessageID string
+	// UserEventID is the ID of the user.message event for the prompt
+	UserEventID string
+	// InteractionID is the interaction ID shared by the events of the turn
+	InteractionID string
+	// Template is the template the prompt was rendered from, if it was sent
+	// with [MessageOptions.Template]. The SDK keeps it; the CLI does not
+	// receive it.
+	Template *RenderedTemplate
+}
+
+// Turn is one prompt/response exchange assembled from session history.
+type Turn struct {
+	// MessageID is the ID the turn was looked up by
+	MessageID string
+	// InteractionID is the interaction ID of the turn, if reported by the CLI
+	InteractionID string
+	// UserMessage is the user.message event that started the turn
+	UserMessage SessionEvent
+	// AssistantMessages are the assistant.message events of the turn, in order
+	AssistantMessages []SessionEvent
+	// ToolCalls are the tool executions performed during the turn, in start order
+	ToolCalls []ToolCall
+	// Events are all events of the turn, starting with UserMessage
+	Events []SessionEvent
+}
+
+// TurnResult is a turn sent and awaited with [Session.SendAndCollect].
+type TurnResult struct {
+	// MessageID is the ID returned by Send for the prompt
+	MessageID string
+	// InteractionID is the interaction ID of the turn, or empty if the
+	// user.message event for the prompt was not received
+	InteractionID string
+	// FinalMessage is the last assistant.message event of the turn, or nil if there was none
+	FinalMessage *SessionEvent
+	// AssistantMessages are the assistant.message events of the turn, in order
+	AssistantMessages []SessionEvent
+	// ToolCalls are the tool executions performed during the turn, in start order
+	ToolCalls []ToolCall
+	// Reasoning are the assistant.reasoning events of the turn, in order
+	Reasoning []SessionEvent
+	// Idle is the session.idle event that ended the turn, or nil if the turn
+	// did not complete
+	Idle *SessionEvent
+	// Usage totals the assistant.usage events of the turn
+	Usage UsageSummary
+	// Events are the events received between sending the prompt and
+	// session.idle. Events of other turns, such as those of a concurrent
+	// Send on the same session, are left out once the turn's interaction ID
+	// is known.
+	Events []SessionEvent
+	// ModelFallback describes the switch to a fallback model if the session's
+	// model rejected the message, or is nil. See [SessionConfig.ModelFallbacks].
+	ModelFallback *ModelFallback
+	// ToolLogs are the entries SDK tool handlers wrote with
+	// [ToolInvocation.Log] during the turn, by tool call ID
+	ToolLogs map[string][]ToolLogEntry
+	// Determinism records the settings of a message sent with
+	// [MessageOptions.Deterministic], or is nil
+	Determinism *EffectiveDeterminism
+	// Timeline breaks the turn's wall-clock time down into waiting for the
+	// first event, thinking, tool executions, waits for permission, user
+	// input and hook handlers, and streaming, in start order. Render it with
+	// [FormatTimeline].
+	Timeline []TimelineSpan
+}
+
+// ToolCall is a tool execution assembled from its start and completion events.
+type ToolCall struct {
+	ToolCallID string
+	ToolName   string
+	Arguments  any
+	// Success is nil while the tool is still running
+	Success *bool
+	Result  *Result
+	// Logs are the entries the SDK tool handler wrote with
+	// [ToolInvocation.Log], if the call ran in this process
+	Logs []ToolLogEntry
+}
+
+// FinalResponse returns the last assistant message of the turn, or nil if there is none.
+func (t *Turn) FinalResponse() *SessionEvent {
+	if len(t.AssistantMessages) == 0 {
+		return nil
+	}
+	return &t.AssistantMessages[len(t.AssistantMessages)-1]
+}
+
+// maxMessageRefs bounds the message references a session keeps, and the
+// sends it holds open waiting for their user.message events.
+const maxMessageRefs = 256
+
+// messageRefTracker pairs message IDs returned by session.send with the
+// user.message events the CLI emits for them. The CLI echoes no ID of the
+// send on the event, except when it reuses the message ID as the event ID,
+// so other events are paired in order with this session's open sends: those
+// started but not yet paired, since the event may arrive before or after
+// session.send returns. A send stays open until the session next goes idle,
+// so user messages sent by other clients or replayed on resume while no send
+// is open are ignored, and cannot shift later pairings.
+type messageRefTracker struct {
+	mu    sync.Mutex
+	refs  map[string]*MessageRef
+	order []string       // keys of refs, oldest first
+	open  []*pendingSend // oldest first
+}
+
+// pendingSend is a send whose user.message event has not been paired yet.
+type pendingSend struct {
+	messageID string            // empty until session.send returns
+	event     *SessionEvent     // the event paired before session.send returned
+	idle      bool              // the session went idle before session.send returned
+	template  *RenderedTemplate // MessageOptions.Template
+}
+
+// begin opens a send, before session.send is called.
+func (m *messageRefTracker) begin() *pendingSend {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	send := &pendingSend{}
+	m.open = append(m.open, send)
+	if len(m.open) > maxMessageRefs {
+		m.open = m.open[1:]
+	}
+	return send
+}
+
+// abandon closes a send whose session.send call failed.
+func (m *messageRefTracker) abandon(send *pendingSend) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.close(send)
+}
+
+// recordSend registers the message ID session.send returned for a send.
+func (m *messageRefTracker) recordSend(send *pendingSend, messageID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if messageID == "" {
+		m.close(send)
+		return
+	}
+	if m.refs == nil {
+		m.refs = make(map[string]*MessageRef)
+	}
+	if _, ok := m.refs[messageID]; !ok {
+		m.refs[messageID] = &MessageRef{MessageID: messageID, Template: send.template}
+		m.order = append(m.order, messageID)
+		if len(m.order) > maxMessageRefs {
+			delete(m.refs, m.order[0])
+			m.order = m.order[1:]
+		}
+	}
+	// An event that reuses the message ID may have been paired with an
+	// earlier concurrent send
+	for _, other := range m.open {
+		if other != send && other.event != nil && other.event.ID == messageID {
+			other.event, send.event = send.event, other.event
+			break
+		}
+	}
+	send.messageID = messageID
+	if send.event != nil {
+		m.refs[messageID].link(*send.event)
+	}
+	if send.event != nil || send.idle {
+		m.close(send)
+	}
+}
+
+// recordEvent observes a session event, pairs user.message events with open
+// sends, and closes the sends of a turn that completed without one.
+func (m *messageRefTracker) recordEvent(event SessionEvent) {
+	switch event.Type {
+	case UserMessage:
+	case SessionIdle:
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.open = slices.DeleteFunc(m.open, func(send *pendingSend) bool {
+			send.idle = true
+			return send.messageID != ""
+		})
+		return
+	default:
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The CLI may reuse the send's message ID as the event ID
+	if ref, ok := m.refs[event.ID]; ok {
+		ref.link(event)
+		for _, send := range m.open {
+			if send.messageID == event.ID {
+				m.close(send)
+				break
+			}
+		}
+		return
+	}
+	for _, send := range m.open {
+		if send.event != nil {
+			continue
+		}
+		if ref := m.refs[send.messageID]; ref != nil {
+			ref.link(event)
+			m.close(send)
+		} else {
+			send.event = &event
+		}
+		return
+	}
+}
+
+// close removes a send from the open sends. m.mu must be held.
+func (m *messageRefTracker) close(send *pendingSend) {
+	if i := slices.Index(m.open, send); i >= 0 {
+		m.open = slices.Delete(m.open, i, i+1)
+	}
+}
+
+// lookup returns the reference for a message ID.
+func (m *messageRefTracker) lookup(messageID string) (MessageRef, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ref, ok := m.refs[messageID]
+	if !ok {
+		return MessageRef{}, false
+	}
+	return *ref, true
+}
+
+func (r *MessageRef) link(event SessionEvent) {
+	r.UserEventID = event.ID
+	if event.Data.InteractionID != nil {
+		r.InteractionID = *event.Data.InteractionID
+	}
+}
+
+// MessageRef returns the identifiers the CLI uses for a message sent from this
+// session. The second return value is false if the message ID was not returned
+// by [Session.Send] on this session object, or is not among the session's 256
+// most recent messages.
+//
+// The UserEventID and InteractionID fields are filled in once the corresponding
+// user.message event has been received.
+func (s *Session) MessageRef(messageID string) (MessageRef, bool) {
+	return s.messageRefs.lookup(messageID)
+}
+
+// FindTurn assembles the user prompt, assistant output, and tool calls for one
+// turn from the session history.
+//
+// The messageID may be the ID returned by [Session.Send], the ID of the
+// user.message event, or the message ID of any assistant message in the turn.
+// Returns an error wrapping [ErrTurnNotFound] if no turn matches.
+//
+// Example:
+//
+//	messageID, _ := session.Send(ctx, copilot.MessageOptions{Prompt: "Hi"})
+//	// ... wait for session.idle ...
+//	turn, err := session.FindTurn(ctx, messageID)
+//	if err == nil && turn.FinalResponse() != nil {
+//	    fmt.Println(*turn.FinalResponse().Data.Content)
+//	}
+func (s *Session) FindTurn(ctx context.Context, messageID string) (*Turn, error) {
+	events, err := s.GetMessages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ref, _ := s.messageRefs.lookup(messageID)
+	turn := findTurn(events, messageID, ref)
+	if turn == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTurnNotFound, messageID)
+	}
+	return turn, nil
+}
+
+// findTurn locates the turn matching messageID in events and assembles it.
+func findTurn(events []SessionEvent, messageID string, ref MessageRef) *Turn {
+	start := -1
+	lastUser := -1
+	for i, event := range events {
+		if event.Type == UserMessage {
+			lastUser = i
+			if event.ID == messageID ||
+				(ref.UserEventID != "" && event.ID == ref.UserEventID) ||
+				(ref.InteractionID != "" && event.Data.InteractionID != nil && *event.Data.InteractionID == ref.InteractionID) {
+				start = i
+				break
+			}
+			continue
+		}
+		if lastUser >= 0 && event.Data.MessageID != nil && *event.Data.MessageID == messageID {
+			start = lastUser
+			break
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+
+	end := len(events)
+	for i := start + 1; i < len(events); i++ {
+		if events[i].Type == UserMessage {
+			end = i
+			break
+		}
+	}
+
+	turn := buildTurn(events[start:end])
+	turn.MessageID = messageID
+	return turn
+}
+
+// buildTurn assembles a Turn from events starting with a user.message event.
+func buildTurn(events []SessionEvent) *Turn {
+	turn := &Turn{
+		UserMessage: events[0],
+		Events:      append([]SessionEvent(nil), events...),
+	}
+	if events[0].Data.InteractionID != nil {
+		turn.InteractionID = *events[0].Data.InteractionID
+	}
+
+	for _, event := range events[1:] {
+		if event.Type == AssistantMessage {
+			turn.AssistantMessages = append(turn.AssistantMessages, event)
+		}
+	}
+	turn.ToolCalls = toolCalls(events[1:])
+	return turn
+}
+
+// toolCalls assembles the tool executions reported by events.
+func toolCalls(events []SessionEvent) []ToolCall {
+	var calls []ToolCall
+	toolIndex := make(map[string]int)
+	for _, event := range events {
+		switch event.Type {
+		case ToolExecutionStart:
+			if event.Data.ToolCallID == nil {
+				continue
+			}
+			call := ToolCall{ToolCallID: *event.Data.ToolCallID, Arguments: event.Data.Arguments}
+			if event.Data.ToolName != nil {
+				call.ToolName = *event.Data.ToolName
+			}
+			toolIndex[call.ToolCallID] = len(calls)
+			calls = append(calls, call)
+		case ToolExecutionComplete:
+			if event.Data.ToolCallID == nil {
+				continue
+			}
+			i, ok := toolIndex[*event.Data.ToolCallID]
+			if !ok {
+				i = len(calls)
+				toolIndex[*event.Data.ToolCallID] = i
+				calls = append(calls, ToolCall{ToolCallID: *event.Data.ToolCallID})
+			}
+			calls[i].Success = event.Data.Success
+			calls[i].Result = event.Data.Result
+			calls[i].Logs = event.ToolLogs()
+		}
+	}
+	return calls
+}
+
+// buildTurnResult assembles the result of the turn sent with messageID from
+// the events received while waiting for it. Once the turn's interaction ID
+// is known from ref, events carrying another interaction ID are dropped:
+// they belong to an earlier turn still finishing or to a concurrent send.
+// The session.idle event is kept either way, as it ends the wait.
+func buildTurnResult(messageID string, ref MessageRef, events []SessionEvent) *TurnResult {
+	result := &TurnResult{MessageID: messageID, InteractionID: ref.InteractionID}
+	for _, event := range events {
+		if ref.InteractionID != "" && event.Type != SessionIdle &&
+			event.Data.InteractionID != nil && *event.Data.InteractionID != "" && *event.Data.InteractionID != ref.InteractionID {
+			continue
+		}
+		result.Events = append(result.Events, event)
+		switch event.Type {
+		case AssistantMessage:
+			result.AssistantMessages = append(result.AssistantMessages, event)
+		case AssistantReasoning:
+			result.Reasoning = append(result.Reasoning, event)
+		case AssistantUsage:
+			result.Usage.observe(event)
+		case SessionIdle:
+			idle := event
+			result.Idle = &idle
+		}
+	}
+	if n := len(result.AssistantMessages); n > 0 {
+		result.FinalMessage = &result.AssistantMessages[n-1]
+	}
+	result.ToolCalls = toolCalls(result.Events)
+	result.ToolLogs = collectToolLogs(result.Events)
+	return result
+}
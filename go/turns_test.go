@@ -0,0 +1,293 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func userMessageEvent(id, interactionID, prompt string) SessionEvent {
+	return SessionEvent{ID: id, Type: UserMessage, Data: Data{Content: &prompt, InteractionID: &interactionID}}
+}
+
+func turnHistory() []SessionEvent {
+	toolCallID := "call-1"
+	toolName := "grep"
+	success := true
+	msgID := "assistant-msg-1"
+	first := "Let me search."
+	final := "Found it."
+	other := "Bye."
+	return []SessionEvent{
+		userMessageEvent("evt-user-1", "interaction-1", "Find the bug"),
+		{ID: "evt-2", Type: AssistantMessage, Data: Data{Content: &first, MessageID: &msgID}},
+		{ID: "evt-3", Type: ToolExecutionStart, Data: Data{ToolCallID: &toolCallID, ToolName: &toolName, Arguments: map[string]any{"pattern": "bug"}}},
+		{ID: "evt-4", Type: ToolExecutionComplete, Data: Data{ToolCallID: &toolCallID, Success: &success, Result: &Result{Content: "main.go:1"}}},
+		{ID: "evt-5", Type: AssistantMessage, Data: Data{Content: &final}},
+		{ID: "evt-6", Type: SessionIdle},
+		userMessageEvent("evt-user-2", "interaction-2", "Thanks"),
+		{ID: "evt-8", Type: AssistantMessage, Data: Data{Content: &other}},
+	}
+}
+
+func TestFindTurn(t *testing.T) {
+	t.Run("assembles a turn by user event ID", func(t *testing.T) {
+		turn := findTurn(turnHistory(), "evt-user-1", MessageRef{})
+		if turn == nil {
+			t.Fatal("Expected turn to be found")
+		}
+		if turn.InteractionID != "interaction-1" {
+			t.Errorf("Expected interaction-1, got %q", turn.InteractionID)
+		}
+		if len(turn.Events) != 6 {
+			t.Errorf("Expected 6 events in turn, got %d", len(turn.Events))
+		}
+		if len(turn.AssistantMessages) != 2 {
+			t.Errorf("Expected 2 assistant messages, got %d", len(turn.AssistantMessages))
+		}
+		if final := turn.FinalResponse(); final == nil || *final.Data.Content != "Found it." {
+			t.Errorf("Unexpected final response: %+v", final)
+		}
+		if len(turn.ToolCalls) != 1 {
+			t.Fatalf("Expected 1 tool call, got %d", len(turn.ToolCalls))
+		}
+		call := turn.ToolCalls[0]
+		if call.ToolName != "grep" || call.Success == nil || !*call.Success || call.Result.Content != "main.go:1" {
+			t.Errorf("Unexpected tool call: %+v", call)
+		}
+	})
+
+	t.Run("resolves send message IDs through the message ref", func(t *testing.T) {
+		turn := findTurn(turnHistory(), "send-id", MessageRef{MessageID: "send-id", InteractionID: "interaction-2"})
+		if turn == nil || turn.UserMessage.ID != "evt-user-2" {
+			t.Fatalf("Expected second turn, got %+v", turn)
+		}
+		if turn.MessageID != "send-id" {
+			t.Errorf("Expected MessageID send-id, got %q", turn.MessageID)
+		}
+	})
+
+	t.Run("finds the turn containing an assistant message ID", func(t *testing.T) {
+		turn := findTurn(turnHistory(), "assistant-msg-1", MessageRef{})
+		if turn == nil || turn.UserMessage.ID != "evt-user-1" {
+			t.Fatalf("Expected first turn, got %+v", turn)
+		}
+	})
+
+	t.Run("returns nil for unknown IDs", func(t *testing.T) {
+		if turn := findTurn(turnHistory(), "missing", MessageRef{}); turn != nil {
+			t.Errorf("Expected no turn, got %+v", turn)
+		}
+	})
+}
+
+func TestBuildTurnResult(t *testing.T) {
+	interaction := func(id string) *string { return &id }
+	content := func(s string) *string { return &s }
+	inputTokens, outputTokens := 100.0, 20.0
+	events := []SessionEvent{
+		{ID: "prev", Type: AssistantMessage, Data: Data{Content: content("previous turn"), InteractionID: interaction("interaction-0")}},
+		userMessageEvent("evt-user-1", "interaction-1", "Find the bug"),
+		{ID: "r1", Type: AssistantReasoning, Data: Data{Content: content("thinking"), InteractionID: interaction("interaction-1")}},
+		{ID: "m1", Type: AssistantMessage, Data: Data{Content: content("Let me search."), InteractionID: interaction("interaction-1")}},
+		{ID: "u1", Type: AssistantUsage, Data: Data{InputTokens: &inputTokens, OutputTokens: &outputTokens, InteractionID: interaction("interaction-1")}},
+		userMessageEvent("evt-user-2", "interaction-2", "Concurrent prompt"),
+		{ID: "other", Type: AssistantMessage, Data: Data{Content: content("other turn"), InteractionID: interaction("interaction-2")}},
+		{ID: "m2", Type: AssistantMessage, Data: Data{Content: content("Found it."), InteractionID: interaction("interaction-1")}},
+		{ID: "u2", Type: AssistantUsage, Data: Data{InputTokens: &inputTokens, InteractionID: interaction("interaction-1")}},
+		{ID: "idle", Type: SessionIdle, Data: Data{InteractionID: interaction("interaction-2")}},
+	}
+
+	t.Run("keeps only the events of the turn", func(t *testing.T) {
+		result := buildTurnResult("send-1", MessageRef{MessageID: "send-1", InteractionID: "interaction-1"}, events)
+		var ids []string
+		for _, event := range result.Events {
+			ids = append(ids, event.ID)
+		}
+		if got, want := fmt.Sprint(ids), "[evt-user-1 r1 m1 u1 m2 u2 idle]"; got != want {
+			t.Errorf("Expected events %s, got %s", want, got)
+		}
+		if len(result.AssistantMessages) != 2 || result.FinalMessage == nil || result.FinalMessage.ID != "m2" {
+			t.Errorf("Expected both assistant messages of the turn ending with m2, got %+v", result.AssistantMessages)
+		}
+		if len(result.Reasoning) != 1 || result.Reasoning[0].ID != "r1" {
+			t.Errorf("Expected the reasoning event, got %+v", result.Reasoning)
+		}
+		if result.Idle == nil || result.Idle.ID != "idle" {
+			t.Errorf("Expected the idle event, got %+v", result.Idle)
+		}
+		if want := (TokenUsage{Requests: 2, InputTokens: 200, OutputTokens: 20}); result.Usage.TokenUsage != want {
+			t.Errorf("Expected usage %+v, got %+v", want, result.Usage)
+		}
+		if result.InteractionID != "interaction-1" {
+			t.Errorf("Expected interaction-1, got %q", result.InteractionID)
+		}
+	})
+
+	t.Run("keeps every event until the interaction ID is known", func(t *testing.T) {
+		result := buildTurnResult("send-1", MessageRef{MessageID: "send-1"}, events)
+		if len(result.Events) != len(events) || result.FinalMessage.ID != "m2" {
+			t.Errorf("Expected all %d events, got %d", len(events), len(result.Events))
+		}
+	})
+
+	t.Run("assembles tool calls", func(t *testing.T) {
+		result := buildTurnResult("send-1", MessageRef{MessageID: "send-1", InteractionID: "interaction-1"}, turnHistory()[:6])
+		if len(result.ToolCalls) != 1 || result.ToolCalls[0].ToolName != "grep" || result.ToolCalls[0].Success == nil {
+			t.Errorf("Expected the completed grep call, got %+v", result.ToolCalls)
+		}
+	})
+}
+
+func TestMessageRefTracker(t *testing.T) {
+	// send opens a send and records the message ID session.send returned
+	send := func(tracker *messageRefTracker, messageID string) {
+		tracker.recordSend(tracker.begin(), messageID)
+	}
+
+	t.Run("links sends to user messages observed afterwards", func(t *testing.T) {
+		var tracker messageRefTracker
+		send(&tracker, "send-1")
+		send(&tracker, "send-2")
+		tracker.recordEvent(userMessageEvent("evt-1", "i-1", "a"))
+		tracker.recordEvent(userMessageEvent("evt-2", "i-2", "b"))
+
+		ref, ok := tracker.lookup("send-2")
+		if !ok || ref.UserEventID != "evt-2" || ref.InteractionID != "i-2" {
+			t.Errorf("Unexpected ref: %+v", ref)
+		}
+	})
+
+	t.Run("links user messages observed before the send returns", func(t *testing.T) {
+		var tracker messageRefTracker
+		pending := tracker.begin()
+		tracker.recordEvent(userMessageEvent("evt-1", "i-1", "a"))
+		tracker.recordSend(pending, "send-1")
+
+		ref, ok := tracker.lookup("send-1")
+		if !ok || ref.UserEventID != "evt-1" || ref.InteractionID != "i-1" {
+			t.Errorf("Unexpected ref: %+v", ref)
+		}
+	})
+
+	t.Run("matches events that reuse the send message ID", func(t *testing.T) {
+		var tracker messageRefTracker
+		send(&tracker, "send-1")
+		send(&tracker, "send-2")
+		tracker.recordEvent(userMessageEvent("send-2", "i-2", "b"))
+		tracker.recordEvent(userMessageEvent("evt-1", "i-1", "a"))
+
+		if ref, _ := tracker.lookup("send-1"); ref.UserEventID != "evt-1" {
+			t.Errorf("Expected send-1 to link to evt-1, got %+v", ref)
+		}
+		if ref, _ := tracker.lookup("send-2"); ref.UserEventID != "send-2" {
+			t.Errorf("Expected send-2 to link to itself, got %+v", ref)
+		}
+
+		// Before the sends return, too
+		first, second := tracker.begin(), tracker.begin()
+		tracker.recordEvent(userMessageEvent("send-4", "i-4", "d"))
+		tracker.recordEvent(userMessageEvent("evt-3", "i-3", "c"))
+		tracker.recordSend(second, "send-4")
+		tracker.recordSend(first, "send-3")
+		if ref, _ := tracker.lookup("send-3"); ref.UserEventID != "evt-3" {
+			t.Errorf("Expected send-3 to link to evt-3, got %+v", ref)
+		}
+		if ref, _ := tracker.lookup("send-4"); ref.UserEventID != "send-4" {
+			t.Errorf("Expected send-4 to link to itself, got %+v", ref)
+		}
+	})
+
+	t.Run("ignores user messages sent elsewhere", func(t *testing.T) {
+		var tracker messageRefTracker
+		// Replayed on resume, or sent by another client, before any send
+		tracker.recordEvent(userMessageEvent("replayed", "i-0", "old"))
+		send(&tracker, "send-1")
+		tracker.recordEvent(userMessageEvent("evt-1", "i-1", "a"))
+		tracker.recordEvent(SessionEvent{Type: SessionIdle})
+		// Sent by another client between turns
+		tracker.recordEvent(userMessageEvent("foreign", "i-x", "x"))
+		send(&tracker, "send-2")
+		tracker.recordEvent(userMessageEvent("evt-2", "i-2", "b"))
+
+		if ref, _ := tracker.lookup("send-1"); ref.UserEventID != "evt-1" {
+			t.Errorf("Expected send-1 to link to evt-1, got %+v", ref)
+		}
+		if ref, _ := tracker.lookup("send-2"); ref.UserEventID != "evt-2" {
+			t.Errorf("Expected send-2 to link to evt-2, got %+v", ref)
+		}
+	})
+
+	t.Run("closes sends when the turn completes or the send fails", func(t *testing.T) {
+		var tracker messageRefTracker
+		send(&tracker, "unpaired")
+		tracker.recordEvent(SessionEvent{Type: SessionIdle})
+		tracker.abandon(tracker.begin())
+		pending := tracker.begin()
+		tracker.recordEvent(SessionEvent{Type: SessionIdle})
+		tracker.recordSend(pending, "late")
+		if len(tracker.open) != 0 {
+			t.Fatalf("Expected no open sends, got %d", len(tracker.open))
+		}
+		send(&tracker, "send-1")
+		tracker.recordEvent(userMessageEvent("evt-1", "i-1", "a"))
+		if ref, _ := tracker.lookup("send-1"); ref.UserEventID != "evt-1" {
+			t.Errorf("Expected send-1 to link to evt-1, got %+v", ref)
+		}
+	})
+
+	t.Run("keeps the most recent references", func(t *testing.T) {
+		var tracker messageRefTracker
+		for i := range maxMessageRefs + 10 {
+			send(&tracker, fmt.Sprintf("send-%d", i))
+			tracker.recordEvent(userMessageEvent(fmt.Sprintf("evt-%d", i), "", ""))
+		}
+		if len(tracker.refs) != maxMessageRefs || len(tracker.order) != maxMessageRefs {
+			t.Errorf("Expected %d references, got %d", maxMessageRefs, len(tracker.refs))
+		}
+		if _, ok := tracker.lookup("send-0"); ok {
+			t.Error("Expected the oldest reference to be dropped")
+		}
+		if ref, _ := tracker.lookup(fmt.Sprintf("send-%d", maxMessageRefs+9)); ref.UserEventID != fmt.Sprintf("evt-%d", maxMessageRefs+9) {
+			t.Errorf("Unexpected newest reference %+v", ref)
+		}
+	})
+}
+
+func TestSession_FindTurn(t *testing.T) {
+	history := turnHistory()
+	var server *fakeServer
+	session, server := newTestSession(t, func(method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "session.send":
+			server.emit(history[6])
+			return sessionSendResponse{MessageID: "send-id"}, nil
+		case "session.getMessages":
+			return sessionGetMessagesResponse{Events: history}, nil
+		}
+		return nil, nil
+	})
+
+	messageID, err := session.Send(t.Context(), MessageOptions{Prompt: "Thanks"})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	ref, ok := session.MessageRef(messageID)
+	if !ok || ref.UserEventID != "evt-user-2" || ref.InteractionID != "interaction-2" {
+		t.Errorf("Unexpected message ref: %+v", ref)
+	}
+
+	turn, err := session.FindTurn(t.Context(), messageID)
+	if err != nil {
+		t.Fatalf("FindTurn failed: %v", err)
+	}
+	if turn.UserMessage.ID != "evt-user-2" {
+		t.Errorf("Expected turn for evt-user-2, got %q", turn.UserMessage.ID)
+	}
+
+	if _, err := session.FindTurn(t.Context(), "missing"); !errors.Is(err, ErrTurnNotFound) {
+		t.Errorf("Expected ErrTurnNotFound, got %v", err)
+	}
+}
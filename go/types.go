@@ -1,6 +1,14 @@
 package copilot
 
-import "encoding/json"
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/github/copilot-sdk/go/protocol"
+)
 
 // ConnectionState represents the client connection state
 type ConnectionState string
@@ -37,7 +45,21 @@ type ClientOptions struct {
 	AutoStart *bool
 	// AutoRestart automatically restarts the CLI server if it crashes (default: true).
 	// Use Bool(false) to disable.
+	//
+	// When the connection to a CLI process spawned by the client is lost,
+	// the client starts the CLI again with the same options and re-attaches
+	// its sessions, as [Client.Restart] does, backing off as configured by
+	// RestartBackoff. Requests that were waiting for a response, and those
+	// made before the restart completes, fail with [ErrConnectionLost]. It
+	// has no effect with CLIUrl, whose server the client does not manage.
 	AutoRestart *bool
+	// RestartBackoff configures the waits between AutoRestart attempts and
+	// when to give up. Nil uses the defaults of [RestartBackoff].
+	RestartBackoff *RestartBackoff
+	// OnConnectionStateChange is called with each change of the client's
+	// [ConnectionState], for example to observe automatic restarts. Changes
+	// are delivered in order on a goroutine of their own.
+	OnConnectionStateChange func(ConnectionStateChange)
 	// Env is the environment variables for the CLI process (default: inherits from current process).
 	// Each entry is of the form "key=value".
 	// If Env is nil, the new process uses the current process's environment.
@@ -54,6 +76,133 @@ type ClientOptions struct {
 	// Default: true (but defaults to false when GitHubToken is provided).
 	// Use Bool(false) to explicitly disable.
 	UseLoggedInUser *bool
+	// Pacing delays Session.Send calls across all sessions to stay under rate limits.
+	// Default: nil (no pacing).
+	Pacing *PacingOptions
+	// IntegrationID identifies the integration using the SDK to the CLI, which
+	// reports it with API requests for attribution. Same format as
+	// [MessageOptions.Initiator].
+	IntegrationID string
+	// CleanupTimeout bounds the session.destroy calls made by Client.Stop,
+	// which run concurrently under this one deadline, so that unresponsive
+	// sessions cannot stall shutdown.
+	// Default: 10 seconds.
+	CleanupTimeout time.Duration
+	// RequestIDGenerator generates the IDs of JSON-RPC requests sent to the
+	// CLI, for example to make them deterministic in tests. IDs must be
+	// unique among the requests in flight.
+	// Default: nil (random UUIDs).
+	RequestIDGenerator func() string
+	// OnRPCCall is called after each JSON-RPC call to or from the CLI
+	// completes, with its request ID, for correlating SDK and CLI logs. It is
+	// called synchronously and must not block.
+	OnRPCCall func(RPCCall)
+	// OnRPCMessage is called with every JSON-RPC message sent to or received
+	// from the CLI, including the CLI's own requests such as tool calls, for
+	// debugging the wire protocol. It is called synchronously, in the order
+	// messages are written or read, and must not block.
+	OnRPCMessage func(RPCMessage)
+	// TraceWriter receives every JSON-RPC message sent to or received from
+	// the CLI, one per line, as the time, "-->" for sent or "<--" for
+	// received messages, the kind, method and ID, and the JSON. Writes are
+	// serialized and their errors ignored.
+	TraceWriter io.Writer
+	// TraceRedactPaths lists JSON paths whose values OnRPCMessage and
+	// TraceWriter receive as "[REDACTED]", as dot-separated keys from the
+	// top of the message, where "*" matches any key or array element, such
+	// as "params.tools.*.description". The API key and bearer token of
+	// custom providers are always redacted.
+	TraceRedactPaths []string
+	// OnDeprecatedUse is called when a legacy API, such as
+	// [DeprecatedMCPServerMap], is used, with the API's name and the
+	// caller's file:line, to find code to migrate. It is called
+	// synchronously. Calls are counted in [DiagnosticBundle.DeprecatedUses]
+	// whether or not it is set.
+	OnDeprecatedUse func(api, callSite string)
+	// ServerLoad configures the estimate of the CLI's load reported by
+	// [Client.ServerLoad]. Default: nil (default thresholds, no callback).
+	ServerLoad *ServerLoadOptions
+	// MaxMessageBytes limits the size of each JSON-RPC message exchanged with
+	// the CLI. Requests over the limit fail with [ErrMessageTooLarge] before
+	// being sent; responses and notifications over it are dropped.
+	// Default: 64 MiB.
+	MaxMessageBytes int
+	// MaxInlineAttachmentBytes limits the size of each of
+	// [MessageOptions.InlineAttachments]. Sends with a larger one fail with an
+	// *[AttachmentTooLargeError] before anything is sent. Default: 10 MiB;
+	// negative disables the limit.
+	MaxInlineAttachmentBytes int
+	// RequestTimeout bounds how long a request to the CLI waits for its
+	// response when the caller's context has no deadline, so requests whose
+	// response the CLI drops do not wait, and hold memory, forever. They fail
+	// with [ErrRequestTimeout]. Default: 10 minutes; negative disables it.
+	// [WithRequestTimeout] overrides it for a single call.
+	RequestTimeout time.Duration
+	// RetryPolicy re-sends requests to idempotent methods, such as ping and
+	// session.getMessages, that fail with [ErrRequestTimeout]. Each attempt
+	// is reported to OnRPCCall. Default: nil (no retries).
+	RetryPolicy *RetryPolicy
+	// MaxPendingRequests, when positive, caps the requests awaiting a
+	// response from the CLI. Requests over the cap fail with
+	// [ErrTooManyPendingRequests] without being sent, and are reported to
+	// OnRPCCall like other failed calls. Default: 0 (no cap).
+	MaxPendingRequests int
+	// PendingRequestsWarning, when positive, flags requests sent while at
+	// least this many requests await a response from the CLI. They are sent
+	// as usual, reported to OnRPCCall with [RPCCall.OverPendingWarning] set
+	// and counted in [ClientStats.PendingWarnings], so a growing backlog can
+	// be alerted on without failing calls. Default: 0 (no warning).
+	PendingRequestsWarning int
+	// ServerRequestOrder selects whether the permission, user input and hook
+	// requests of a session are answered one at a time in the order the CLI
+	// sent them ([ServerRequestsOrdered], the default), or concurrently
+	// ([ServerRequestsConcurrent]) for throughput when handlers are slow.
+	ServerRequestOrder ServerRequestOrder
+	// EventAliases renames session event types the CLI emits to the types
+	// the SDK knows, in addition to the renames built into the SDK for the
+	// negotiated protocol version, and takes precedence over them. Use it to
+	// keep handlers working when a CLI emits an event under an older name.
+	// Event types the SDK does not know are counted in
+	// [DiagnosticBundle.UnknownEventTypes].
+	EventAliases map[string]SessionEventType
+	// CompressionThreshold is the size in bytes from which JSON-RPC messages
+	// to the CLI are compressed on TCP connections, if the CLI accepts
+	// compressed messages. Smaller messages are sent as they are, and stdio
+	// connections are never compressed. Default: 16 KiB; negative disables
+	// compression.
+	CompressionThreshold int
+	// Logger receives the SDK's own diagnostic messages, such as recovered
+	// handler panics with their stack traces, slow handler reports and
+	// discarded JSON-RPC messages, as structured records with attributes
+	// such as sessionID, method and requestID, and a source attribute of
+	// "copilot-sdk". It takes precedence over LogOutput. Pass
+	// slog.New(slog.DiscardHandler) to silence the SDK.
+	Logger *slog.Logger
+	// LogOutput receives the SDK's diagnostic messages when Logger is nil,
+	// one per line, as the message followed by its attributes as key=value
+	// pairs. The messages are never colored, and debug messages are dropped.
+	// Default: os.Stderr.
+	LogOutput io.Writer
+	// MachineReadableLogs writes each diagnostic message to LogOutput as a
+	// single-line JSON object with "time", "level", "source" and "msg"
+	// fields followed by the attributes, instead of as text.
+	MachineReadableLogs bool
+}
+
+// RPCCall describes a completed JSON-RPC call, as passed to
+// [ClientOptions.OnRPCCall].
+type RPCCall struct {
+	Method string
+	// RequestID is the JSON-RPC request ID, or empty for notifications
+	RequestID string
+	// Incoming is true for requests and notifications sent by the CLI
+	Incoming bool
+	Duration time.Duration
+	// Err is the error the call failed with, or nil
+	Err error
+	// OverPendingWarning is true for requests to the CLI sent while at least
+	// [ClientOptions.PendingRequestsWarning] requests awaited a response
+	OverPendingWarning bool
 }
 
 // Bool returns a pointer to the given bool value.
@@ -99,52 +248,32 @@ type SystemMessageConfig struct {
 	Content string `json:"content,omitempty"`
 }
 
-// PermissionRequest represents a permission request from the server
-type PermissionRequest struct {
-	Kind       string         `json:"kind"`
-	ToolCallID string         `json:"toolCallId,omitempty"`
-	Extra      map[string]any `json:"-"` // Additional fields vary by kind
-}
-
-// UnmarshalJSON implements custom JSON unmarshaling for PermissionRequest
-// to capture additional fields (varying by kind) into the Extra map.
-func (p *PermissionRequest) UnmarshalJSON(data []byte) error {
-	// Unmarshal known fields via an alias to avoid infinite recursion
-	type Alias PermissionRequest
-	var alias Alias
-	if err := json.Unmarshal(data, &alias); err != nil {
-		return err
-	}
-	*p = PermissionRequest(alias)
-
-	// Unmarshal all fields into a generic map
-	var raw map[string]any
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return err
-	}
-
-	// Remove known fields, keep the rest as Extra
-	delete(raw, "kind")
-	delete(raw, "toolCallId")
-	if len(raw) > 0 {
-		p.Extra = raw
-	}
-	return nil
-}
+// PermissionRequest represents a permission request from the server. See
+// [protocol.PermissionRequest].
+type PermissionRequest = protocol.PermissionRequest
 
-// PermissionRequestResult represents the result of a permission request
-type PermissionRequestResult struct {
-	Kind  string `json:"kind"`
-	Rules []any  `json:"rules,omitempty"`
-}
+// PermissionRequestResult represents the result of a permission request.
+// Build one with [Approved], [DeniedByRules], [DeniedByUser] or
+// [DeniedNoApprovalRule]. See [protocol.PermissionRequestResult].
+type PermissionRequestResult = protocol.PermissionRequestResult
 
 // PermissionHandlerFunc executes a permission request
-// The handler should return a PermissionRequestResult. Returning an error denies the permission.
+// The handler should return a PermissionRequestResult. Returning an error
+// denies the permission, as does returning a Kind the CLI does not accept.
 type PermissionHandlerFunc func(request PermissionRequest, invocation PermissionInvocation) (PermissionRequestResult, error)
 
 // PermissionInvocation provides context about a permission request
 type PermissionInvocation struct {
 	SessionID string
+	// Context is cancelled when the session's current turn is aborted or
+	// the session is destroyed
+	Context context.Context
+	// MessageID is the ID of the message whose turn triggered the request, if
+	// it was sent by this client
+	MessageID string
+	// TraceID correlates the request with the turn's session events: it is
+	// the interaction ID set on those events
+	TraceID string
 }
 
 // UserInputRequest represents a request for user input from the agent
@@ -167,116 +296,71 @@ type UserInputHandler func(request UserInputRequest, invocation UserInputInvocat
 // UserInputInvocation provides context about a user input request
 type UserInputInvocation struct {
 	SessionID string
+	// Context is cancelled when the session's current turn is aborted or
+	// the session is destroyed. For questions from [ToolInvocation.AskUser],
+	// it is the tool's Context.
+	Context context.Context
+	// MessageID is the ID of the message whose turn triggered the request, if
+	// it was sent by this client
+	MessageID string
+	// TraceID correlates the request with the turn's session events: it is
+	// the interaction ID set on those events
+	TraceID string
+	// ToolCallID is the ID of the tool call asking, for questions from
+	// [ToolInvocation.AskUser]; it is empty for the agent's questions
+	ToolCallID string
 }
 
-// PreToolUseHookInput is the input for a pre-tool-use hook
-type PreToolUseHookInput struct {
-	Timestamp int64  `json:"timestamp"`
-	Cwd       string `json:"cwd"`
-	ToolName  string `json:"toolName"`
-	ToolArgs  any    `json:"toolArgs"`
-}
+// PreToolUseHookInput is the input for a pre-tool-use hook. See [protocol.PreToolUseHookInput].
+type PreToolUseHookInput = protocol.PreToolUseHookInput
 
-// PreToolUseHookOutput is the output for a pre-tool-use hook
-type PreToolUseHookOutput struct {
-	PermissionDecision       string `json:"permissionDecision,omitempty"` // "allow", "deny", "ask"
-	PermissionDecisionReason string `json:"permissionDecisionReason,omitempty"`
-	ModifiedArgs             any    `json:"modifiedArgs,omitempty"`
-	AdditionalContext        string `json:"additionalContext,omitempty"`
-	SuppressOutput           bool   `json:"suppressOutput,omitempty"`
-}
+// PreToolUseHookOutput is the output for a pre-tool-use hook. See [protocol.PreToolUseHookOutput].
+type PreToolUseHookOutput = protocol.PreToolUseHookOutput
 
 // PreToolUseHandler handles pre-tool-use hook invocations
 type PreToolUseHandler func(input PreToolUseHookInput, invocation HookInvocation) (*PreToolUseHookOutput, error)
 
-// PostToolUseHookInput is the input for a post-tool-use hook
-type PostToolUseHookInput struct {
-	Timestamp  int64  `json:"timestamp"`
-	Cwd        string `json:"cwd"`
-	ToolName   string `json:"toolName"`
-	ToolArgs   any    `json:"toolArgs"`
-	ToolResult any    `json:"toolResult"`
-}
+// PostToolUseHookInput is the input for a post-tool-use hook. See [protocol.PostToolUseHookInput].
+type PostToolUseHookInput = protocol.PostToolUseHookInput
 
-// PostToolUseHookOutput is the output for a post-tool-use hook
-type PostToolUseHookOutput struct {
-	ModifiedResult    any    `json:"modifiedResult,omitempty"`
-	AdditionalContext string `json:"additionalContext,omitempty"`
-	SuppressOutput    bool   `json:"suppressOutput,omitempty"`
-}
+// PostToolUseHookOutput is the output for a post-tool-use hook. See [protocol.PostToolUseHookOutput].
+type PostToolUseHookOutput = protocol.PostToolUseHookOutput
 
 // PostToolUseHandler handles post-tool-use hook invocations
 type PostToolUseHandler func(input PostToolUseHookInput, invocation HookInvocation) (*PostToolUseHookOutput, error)
 
-// UserPromptSubmittedHookInput is the input for a user-prompt-submitted hook
-type UserPromptSubmittedHookInput struct {
-	Timestamp int64  `json:"timestamp"`
-	Cwd       string `json:"cwd"`
-	Prompt    string `json:"prompt"`
-}
+// UserPromptSubmittedHookInput is the input for a user-prompt-submitted hook. See [protocol.UserPromptSubmittedHookInput].
+type UserPromptSubmittedHookInput = protocol.UserPromptSubmittedHookInput
 
-// UserPromptSubmittedHookOutput is the output for a user-prompt-submitted hook
-type UserPromptSubmittedHookOutput struct {
-	ModifiedPrompt    string `json:"modifiedPrompt,omitempty"`
-	AdditionalContext string `json:"additionalContext,omitempty"`
-	SuppressOutput    bool   `json:"suppressOutput,omitempty"`
-}
+// UserPromptSubmittedHookOutput is the output for a user-prompt-submitted hook. See [protocol.UserPromptSubmittedHookOutput].
+type UserPromptSubmittedHookOutput = protocol.UserPromptSubmittedHookOutput
 
 // UserPromptSubmittedHandler handles user-prompt-submitted hook invocations
 type UserPromptSubmittedHandler func(input UserPromptSubmittedHookInput, invocation HookInvocation) (*UserPromptSubmittedHookOutput, error)
 
-// SessionStartHookInput is the input for a session-start hook
-type SessionStartHookInput struct {
-	Timestamp     int64  `json:"timestamp"`
-	Cwd           string `json:"cwd"`
-	Source        string `json:"source"` // "startup", "resume", "new"
-	InitialPrompt string `json:"initialPrompt,omitempty"`
-}
+// SessionStartHookInput is the input for a session-start hook. See [protocol.SessionStartHookInput].
+type SessionStartHookInput = protocol.SessionStartHookInput
 
-// SessionStartHookOutput is the output for a session-start hook
-type SessionStartHookOutput struct {
-	AdditionalContext string         `json:"additionalContext,omitempty"`
-	ModifiedConfig    map[string]any `json:"modifiedConfig,omitempty"`
-}
+// SessionStartHookOutput is the output for a session-start hook. See [protocol.SessionStartHookOutput].
+type SessionStartHookOutput = protocol.SessionStartHookOutput
 
 // SessionStartHandler handles session-start hook invocations
 type SessionStartHandler func(input SessionStartHookInput, invocation HookInvocation) (*SessionStartHookOutput, error)
 
-// SessionEndHookInput is the input for a session-end hook
-type SessionEndHookInput struct {
-	Timestamp    int64  `json:"timestamp"`
-	Cwd          string `json:"cwd"`
-	Reason       string `json:"reason"` // "complete", "error", "abort", "timeout", "user_exit"
-	FinalMessage string `json:"finalMessage,omitempty"`
-	Error        string `json:"error,omitempty"`
-}
+// SessionEndHookInput is the input for a session-end hook. See [protocol.SessionEndHookInput].
+type SessionEndHookInput = protocol.SessionEndHookInput
 
-// SessionEndHookOutput is the output for a session-end hook
-type SessionEndHookOutput struct {
-	SuppressOutput bool     `json:"suppressOutput,omitempty"`
-	CleanupActions []string `json:"cleanupActions,omitempty"`
-	SessionSummary string   `json:"sessionSummary,omitempty"`
-}
+// SessionEndHookOutput is the output for a session-end hook. See [protocol.SessionEndHookOutput].
+type SessionEndHookOutput = protocol.SessionEndHookOutput
 
 // SessionEndHandler handles session-end hook invocations
 type SessionEndHandler func(input SessionEndHookInput, invocation HookInvocation) (*SessionEndHookOutput, error)
 
-// ErrorOccurredHookInput is the input for an error-occurred hook
-type ErrorOccurredHookInput struct {
-	Timestamp    int64  `json:"timestamp"`
-	Cwd          string `json:"cwd"`
-	Error        string `json:"error"`
-	ErrorContext string `json:"errorContext"` // "model_call", "tool_execution", "system", "user_input"
-	Recoverable  bool   `json:"recoverable"`
-}
+// ErrorOccurredHookInput is the input for an error-occurred hook. See [protocol.ErrorOccurredHookInput].
+type ErrorOccurredHookInput = protocol.ErrorOccurredHookInput
 
-// ErrorOccurredHookOutput is the output for an error-occurred hook
-type ErrorOccurredHookOutput struct {
-	SuppressOutput   bool   `json:"suppressOutput,omitempty"`
-	ErrorHandling    string `json:"errorHandling,omitempty"` // "retry", "skip", "abort"
-	RetryCount       int    `json:"retryCount,omitempty"`
-	UserNotification string `json:"userNotification,omitempty"`
-}
+// ErrorOccurredHookOutput is the output for an error-occurred hook. See [protocol.ErrorOccurredHookOutput].
+type ErrorOccurredHookOutput = protocol.ErrorOccurredHookOutput
 
 // ErrorOccurredHandler handles error-occurred hook invocations
 type ErrorOccurredHandler func(input ErrorOccurredHookInput, invocation HookInvocation) (*ErrorOccurredHookOutput, error)
@@ -284,6 +368,15 @@ type ErrorOccurredHandler func(input ErrorOccurredHookInput, invocation HookInvo
 // HookInvocation provides context about a hook invocation
 type HookInvocation struct {
 	SessionID string
+	// Context is cancelled when the session's current turn is aborted or
+	// the session is destroyed
+	Context context.Context
+	// MessageID is the ID of the message whose turn triggered the hook, if
+	// it was sent by this client
+	MessageID string
+	// TraceID correlates the hook with the turn's session events: it is
+	// the interaction ID set on those events
+	TraceID string
 }
 
 // SessionHooks configures hook handlers for a session
@@ -317,7 +410,9 @@ type MCPRemoteServerConfig struct {
 }
 
 // MCPServerConfig can be either MCPLocalServerConfig or MCPRemoteServerConfig
-// Use a map[string]any for flexibility, or create separate configs
+// in map form. Sessions configured with it are reported to
+// [ClientOptions.OnDeprecatedUse]; set [SessionConfig.TypedMCPServers] to
+// the typed configs instead.
 type MCPServerConfig map[string]any
 
 // CustomAgentConfig configures a custom agent
@@ -350,6 +445,18 @@ type InfiniteSessionConfig struct {
 	// BufferExhaustionThreshold is the context utilization (0.0-1.0) at which
 	// the session blocks until compaction completes. Default: 0.95
 	BufferExhaustionThreshold *float64 `json:"bufferExhaustionThreshold,omitempty"`
+	// MaxWorkspaceBytes caps the size of the session workspace. When a new
+	// checkpoint pushes the workspace past it, the SDK removes the oldest
+	// checkpoints, never the latest one or plan.md, and emits a
+	// [SessionWorkspacePruned] event. Zero means no limit. Applied by the SDK;
+	// not sent to the CLI.
+	MaxWorkspaceBytes int64 `json:"-"`
+	// WorkspaceRoot is a directory under which the CLI creates the session
+	// workspace, instead of its default location, for example a writable
+	// scratch mount in a read-only container. It is made absolute and, unless
+	// the client connects to an external server, checked for writability when
+	// the session is created or resumed; see [WorkspaceNotWritableError].
+	WorkspaceRoot string `json:"workspaceRoot,omitempty"`
 }
 
 // SessionConfig configures a new session
@@ -361,15 +468,29 @@ type SessionConfig struct {
 	ClientName string
 	// Model to use for this session
 	Model string
+	// ModelFallbacks are models to switch to, in order, when a message is
+	// rejected because the current model is rate limited or unavailable.
+	// Each message falls back at most once, and the session stays on the
+	// fallback model afterwards. See [SessionModelFallback].
+	ModelFallbacks []string
 	// ReasoningEffort level for models that support it.
 	// Valid values: "low", "medium", "high", "xhigh"
 	// Only applies to models where capabilities.supports.reasoningEffort is true.
 	ReasoningEffort string
+	// ResponseLanguage is a BCP 47 language tag, such as "de" or "pt-BR",
+	// naming the language the assistant should answer in. It is added to the
+	// system message once, rather than to every prompt, and kept when the
+	// session is resumed by this client. [MessageOptions.ResponseLanguage]
+	// overrides it for one message.
+	ResponseLanguage string
 	// ConfigDir overrides the default configuration directory location.
 	// When specified, the session will use this directory for storing config and state.
 	ConfigDir string
 	// Tools exposes caller-implemented tools to the CLI
 	Tools []Tool
+	// ToolTimeout is the default [Tool.Timeout] for tools that don't set one.
+	// Zero means tool calls are not timed out.
+	ToolTimeout time.Duration
 	// SystemMessage configures system message customization
 	SystemMessage *SystemMessageConfig
 	// AvailableTools is a list of tool names to allow. When specified, only these tools will be available.
@@ -382,8 +503,35 @@ type SessionConfig struct {
 	// If nil, all permission requests are denied by default.
 	// Provide a handler to approve operations (file writes, shell commands, URL fetches, etc.).
 	OnPermissionRequest PermissionHandlerFunc
+	// PermissionPolicy answers permission requests with rules matching
+	// their kind, tool, shell command or path, before AutoApprove and
+	// OnPermissionRequest. See [PermissionPolicy].
+	PermissionPolicy *PermissionPolicy
+	// AutoApprove answers permission requests without calling
+	// OnPermissionRequest, which may then be nil. See [AutoApprovePolicy].
+	AutoApprove *AutoApprovePolicy
+	// PermissionBatching presents permission requests of the same kind that
+	// arrive close together to one handler call. Requests PermissionPolicy
+	// and AutoApprove do not decide are batched. See [PermissionBatching].
+	PermissionBatching *PermissionBatching
+	// PendingActions delivers permission requests, and preToolUse hook
+	// invocations answered with "ask", on [Session.PendingActions] for a UI
+	// to approve or deny. Requests PermissionPolicy and AutoApprove do not
+	// decide are delivered. See [PendingActionOptions].
+	PendingActions *PendingActionOptions
 	// OnUserInputRequest is a handler for user input requests from the agent (enables ask_user tool)
 	OnUserInputRequest UserInputHandler
+	// UserInputFallback answers the agent's questions when OnUserInputRequest
+	// is nil. Setting it enables the ask_user tool. Default: answer with
+	// [DefaultUserInputFallbackText]. See [UserInputFallback].
+	UserInputFallback *UserInputFallback
+	// PromptPreflight configures the check of each prompt's estimated size
+	// against the model's limits made before sending it.
+	// Default: nil (reject prompts over the limit with a *PromptTooLargeError).
+	PromptPreflight *PromptPreflight
+	// Budget caps the premium requests and estimated cost of the session.
+	// Default: nil (no limit). See [BudgetConfig].
+	Budget *BudgetConfig
 	// Hooks configures hook handlers for session lifecycle events
 	Hooks *SessionHooks
 	// WorkingDirectory is the working directory for the session.
@@ -395,8 +543,16 @@ type SessionConfig struct {
 	Streaming bool
 	// Provider configures a custom model provider (BYOK)
 	Provider *ProviderConfig
-	// MCPServers configures MCP servers for the session
+	// MCPServers configures MCP servers for the session in map form. It is
+	// a legacy field, reported to [ClientOptions.OnDeprecatedUse]; use
+	// TypedMCPServers.
 	MCPServers map[string]MCPServerConfig
+	// TypedMCPServers configures MCP servers for the session, by name. A
+	// name may not also be used in MCPServers.
+	TypedMCPServers map[string]TypedMCPServerConfig
+	// SharedMCPServers names MCP servers started with
+	// [Client.StartSharedMCPServer] that the session attaches to
+	SharedMCPServers []string
 	// CustomAgents configures custom agents for the session
 	CustomAgents []CustomAgentConfig
 	// SkillDirectories is a list of directories to load skills from
@@ -406,6 +562,39 @@ type SessionConfig struct {
 	// InfiniteSessions configures infinite sessions for persistent workspaces and automatic compaction.
 	// When enabled (default), sessions automatically manage context limits and persist state.
 	InfiniteSessions *InfiniteSessionConfig
+	// EventOrder enables detection, and optionally correction, of events
+	// delivered out of chronological order. Nil disables the guard.
+	EventOrder *EventOrderOptions
+	// SlowHandlers configures how event handlers that take long to return are
+	// reported. Nil uses the defaults of [SlowHandlerOptions].
+	SlowHandlers *SlowHandlerOptions
+	// HookFallback selects what happens to Hooks when the CLI does not
+	// support hooks (default: HookFallbackError). The outcome is reported in
+	// [ResolvedSessionConfig.Hooks].
+	HookFallback HookFallback
+	// ToolResultSanitizer inspects the results of SDK tools, and of built-in
+	// tools through the postToolUse hook, before they reach the model, to
+	// flag or neutralize prompt injections. Built-in tool results are only
+	// inspected if the CLI supports hooks. See [ToolResultSanitizer].
+	ToolResultSanitizer ToolResultSanitizer
+	// Features turns CLI experiment flags on or off for this session, such as
+	// "parallel_tool_calls", instead of through the CLI process's environment.
+	// The call fails with a *[FeatureUnsupportedError] if the CLI
+	// does not support one of them; see [Client.SupportedFeatures].
+	Features map[string]bool
+	// SharedContext adds the entries of [Client.SharedContext] present when
+	// the session is created to its system message. Nil leaves them out.
+	SharedContext *SharedContextOptions
+	// PartialFailurePolicy selects what happens when one of MCPServers,
+	// SharedMCPServers, SkillDirectories or CustomAgents cannot be set up
+	// (default: PartialFailureFailFast). With PartialFailureWarnAndContinue,
+	// failing entries are left out and reported in [Session.Warnings].
+	PartialFailurePolicy PartialFailurePolicy
+	// OnEvent lists event handlers subscribed before the session starts
+	// receiving events, so they see every event, including those the CLI
+	// emits while the session is being set up. Each is equivalent to a
+	// [Session.On] call.
+	OnEvent []SessionEventHandler
 }
 
 // Tool describes a caller-implemented tool that can be invoked by Copilot
@@ -414,14 +603,58 @@ type Tool struct {
 	Description string         `json:"description,omitempty"`
 	Parameters  map[string]any `json:"parameters,omitempty"`
 	Handler     ToolHandler    `json:"-"`
+	// Timeout bounds each call of the handler. When it expires the SDK cancels
+	// [ToolInvocation.Context] and answers the model with a failed result
+	// saying the tool timed out. Zero uses the session's ToolTimeout; a
+	// negative value disables the timeout for this tool.
+	Timeout time.Duration `json:"-"`
+	// Idempotent marks the handler as safe to run more than once for the same
+	// call. By default, when the CLI delivers a tool call again (for example
+	// after a retry), the SDK answers the duplicate with the first execution's
+	// result, waiting for it if it is still running. Idempotent tools run the
+	// handler again instead.
+	Idempotent bool `json:"-"`
+
+	prepared *preparedTool
+	// checkSchema checks Parameters against the handler's parameter type,
+	// for tools made with DefineToolWithSchema
+	checkSchema func(parameters map[string]any) error
 }
 
 // ToolInvocation describes a tool call initiated by Copilot
 type ToolInvocation struct {
-	SessionID  string
+	SessionID string
+	// ToolCallID identifies the call, as on its ToolExecutionStart and
+	// ToolExecutionComplete events
 	ToolCallID string
 	ToolName   string
 	Arguments  any
+	// RawArguments is the arguments exactly as the CLI sent them, for
+	// handlers that decode them themselves. It is nil if the CLI sent none.
+	RawArguments json.RawMessage
+	// Timestamp is when the CLI made the call, if it reports it, or else
+	// when the SDK received it
+	Timestamp time.Time
+	// WorkingDirectory is the directory the CLI runs the call in, if it
+	// reports it, or else the session's [SessionConfig.WorkingDirectory]
+	WorkingDirectory string
+	// Context is never nil in invocations created by the SDK. It is
+	// cancelled when the tool's timeout expires, the session's current turn
+	// is aborted, whether by [Session.Abort] or by the CLI, the session is
+	// destroyed, or the CLI withdraws the call by reporting it complete, in
+	// which case [context.Cause] returns [ErrToolCallWithdrawn]. Handlers
+	// doing slow work should pass it on or check it, since a handler that
+	// keeps running after its timeout is abandoned rather than stopped.
+	Context context.Context
+	// MessageID is the ID of the message whose turn triggered the call, if
+	// it was sent by this client
+	MessageID string
+	// TraceID correlates the call with the turn's session events: it is the
+	// interaction ID set on those events
+	TraceID string
+
+	logs    *toolLog
+	session *Session
 }
 
 // ToolHandler executes a tool invocation.
@@ -436,6 +669,12 @@ type ToolResult struct {
 	Error               string             `json:"error,omitempty"`
 	SessionLog          string             `json:"sessionLog,omitempty"`
 	ToolTelemetry       map[string]any     `json:"toolTelemetry,omitempty"`
+	// Content holds text, JSON and binary blocks, such as images, for the
+	// model. The SDK appends text and JSON blocks to TextResultForLLM and
+	// binary blocks to BinaryResultsForLLM before sending the result. A
+	// result whose binary content exceeds 16 MiB fails with an error
+	// matching [ErrToolResultTooLarge].
+	Content []ToolResultBlock `json:"-"`
 }
 
 // ResumeSessionConfig configures options when resuming a session
@@ -445,8 +684,16 @@ type ResumeSessionConfig struct {
 	ClientName string
 	// Model to use for this session. Can change the model when resuming.
 	Model string
+	// ModelFallbacks are models to switch to, in order, when a message is
+	// rejected because the current model is rate limited or unavailable.
+	// Each message falls back at most once, and the session stays on the
+	// fallback model afterwards. See [SessionModelFallback].
+	ModelFallbacks []string
 	// Tools exposes caller-implemented tools to the CLI
 	Tools []Tool
+	// ToolTimeout is the default [Tool.Timeout] for tools that don't set one.
+	// Zero means tool calls are not timed out.
+	ToolTimeout time.Duration
 	// SystemMessage configures system message customization
 	SystemMessage *SystemMessageConfig
 	// AvailableTools is a list of tool names to allow. When specified, only these tools will be available.
@@ -460,12 +707,43 @@ type ResumeSessionConfig struct {
 	// ReasoningEffort level for models that support it.
 	// Valid values: "low", "medium", "high", "xhigh"
 	ReasoningEffort string
+	// ResponseLanguage is a BCP 47 language tag naming the language the
+	// assistant should answer in. See [SessionConfig.ResponseLanguage]. If
+	// empty, a session this client already tracks keeps its language.
+	ResponseLanguage string
 	// OnPermissionRequest is a handler for permission requests from the server.
 	// If nil, all permission requests are denied by default.
 	// Provide a handler to approve operations (file writes, shell commands, URL fetches, etc.).
 	OnPermissionRequest PermissionHandlerFunc
+	// PermissionPolicy answers permission requests with rules matching
+	// their kind, tool, shell command or path, before AutoApprove and
+	// OnPermissionRequest. See [PermissionPolicy].
+	PermissionPolicy *PermissionPolicy
+	// AutoApprove answers permission requests without calling
+	// OnPermissionRequest, which may then be nil. See [AutoApprovePolicy].
+	AutoApprove *AutoApprovePolicy
+	// PermissionBatching presents permission requests of the same kind that
+	// arrive close together to one handler call. Requests PermissionPolicy
+	// and AutoApprove do not decide are batched. See [PermissionBatching].
+	PermissionBatching *PermissionBatching
+	// PendingActions delivers permission requests, and preToolUse hook
+	// invocations answered with "ask", on [Session.PendingActions] for a UI
+	// to approve or deny. Requests PermissionPolicy and AutoApprove do not
+	// decide are delivered. See [PendingActionOptions].
+	PendingActions *PendingActionOptions
 	// OnUserInputRequest is a handler for user input requests from the agent (enables ask_user tool)
 	OnUserInputRequest UserInputHandler
+	// UserInputFallback answers the agent's questions when OnUserInputRequest
+	// is nil. Setting it enables the ask_user tool. Default: answer with
+	// [DefaultUserInputFallbackText]. See [UserInputFallback].
+	UserInputFallback *UserInputFallback
+	// PromptPreflight configures the check of each prompt's estimated size
+	// against the model's limits made before sending it.
+	// Default: nil (reject prompts over the limit with a *PromptTooLargeError).
+	PromptPreflight *PromptPreflight
+	// Budget caps the premium requests and estimated cost of the session.
+	// Default: nil (no limit). See [BudgetConfig].
+	Budget *BudgetConfig
 	// Hooks configures hook handlers for session lifecycle events
 	Hooks *SessionHooks
 	// WorkingDirectory is the working directory for the session.
@@ -477,8 +755,16 @@ type ResumeSessionConfig struct {
 	// When true, assistant.message_delta and assistant.reasoning_delta events
 	// with deltaContent are sent as the response is generated.
 	Streaming bool
-	// MCPServers configures MCP servers for the session
+	// MCPServers configures MCP servers for the session in map form. It is
+	// a legacy field, reported to [ClientOptions.OnDeprecatedUse]; use
+	// TypedMCPServers.
 	MCPServers map[string]MCPServerConfig
+	// TypedMCPServers configures MCP servers for the session, by name. A
+	// name may not also be used in MCPServers.
+	TypedMCPServers map[string]TypedMCPServerConfig
+	// SharedMCPServers names MCP servers started with
+	// [Client.StartSharedMCPServer] that the session attaches to
+	SharedMCPServers []string
 	// CustomAgents configures custom agents for the session
 	CustomAgents []CustomAgentConfig
 	// SkillDirectories is a list of directories to load skills from
@@ -487,6 +773,39 @@ type ResumeSessionConfig struct {
 	DisabledSkills []string
 	// InfiniteSessions configures infinite sessions for persistent workspaces and automatic compaction.
 	InfiniteSessions *InfiniteSessionConfig
+	// EventOrder enables detection, and optionally correction, of events
+	// delivered out of chronological order. Nil disables the guard.
+	EventOrder *EventOrderOptions
+	// SlowHandlers configures how event handlers that take long to return are
+	// reported. Nil uses the defaults of [SlowHandlerOptions].
+	SlowHandlers *SlowHandlerOptions
+	// HookFallback selects what happens to Hooks when the CLI does not
+	// support hooks (default: HookFallbackError). The outcome is reported in
+	// [ResolvedSessionConfig.Hooks].
+	HookFallback HookFallback
+	// ToolResultSanitizer inspects the results of SDK tools, and of built-in
+	// tools through the postToolUse hook, before they reach the model, to
+	// flag or neutralize prompt injections. Built-in tool results are only
+	// inspected if the CLI supports hooks. See [ToolResultSanitizer].
+	ToolResultSanitizer ToolResultSanitizer
+	// Features turns CLI experiment flags on or off for this session, such as
+	// "parallel_tool_calls", instead of through the CLI process's environment.
+	// The call fails with a *[FeatureUnsupportedError] if the CLI
+	// does not support one of them; see [Client.SupportedFeatures].
+	Features map[string]bool
+	// SharedContext adds the entries of [Client.SharedContext] present when
+	// the session is resumed to its system message. Nil leaves them out.
+	SharedContext *SharedContextOptions
+	// PartialFailurePolicy selects what happens when one of MCPServers,
+	// SharedMCPServers, SkillDirectories or CustomAgents cannot be set up
+	// (default: PartialFailureFailFast). With PartialFailureWarnAndContinue,
+	// failing entries are left out and reported in [Session.Warnings].
+	PartialFailurePolicy PartialFailurePolicy
+	// OnEvent lists event handlers subscribed before the session starts
+	// receiving events, so they see every event, including those the CLI
+	// emits while the session is being set up. Each is equivalent to a
+	// [Session.On] call.
+	OnEvent []SessionEventHandler
 	// DisableResume, when true, skips emitting the session.resume event.
 	// Useful for reconnecting to a session without triggering resume-related side effects.
 	DisableResume bool
@@ -528,10 +847,88 @@ type ToolBinaryResult struct {
 type MessageOptions struct {
 	// Prompt is the message to send
 	Prompt string
-	// Attachments are file or directory attachments
+	// Attachments are file, directory, selection, GitHub reference or, if
+	// the CLI accepts them, [URL] attachments
 	Attachments []Attachment
+	// InlineAttachments are attachments whose content is sent in the
+	// message. They need a CLI that accepts [Inline] attachments.
+	InlineAttachments []InlineAttachment
+	// Images are image attachments, validated against the current model's
+	// vision capabilities before sending
+	Images []ImageAttachment
 	// Mode is the message delivery mode (default: "enqueue")
 	Mode string
+	// ResponseSchema is an optional JSON schema the final assistant message should
+	// conform to. Passed to the CLI, which constrains the output where supported.
+	ResponseSchema json.RawMessage
+	// Initiator tags the message with the product or feature that sent it, for
+	// attribution in the CLI's telemetry. Must be 1-64 characters of letters,
+	// digits, '.', '_', '-' or '/', starting with a letter or digit.
+	Initiator string
+	// Template is the rendered template the prompt came from. Its name and
+	// variables are kept by the SDK, in [Session.MessageRef]; only the prompt
+	// is sent to the CLI. If Prompt is empty, the rendered template text is
+	// sent.
+	Template *RenderedTemplate
+	// ReasoningEffort overrides the session's reasoning effort for this
+	// message: ReasoningEffortLow, ReasoningEffortMedium, ReasoningEffortHigh
+	// or ReasoningEffortXHigh. Send fails with an *[UnsupportedReasoningError]
+	// if the session's model does not support the level.
+	ReasoningEffort string
+	// ThinkingBudgetTokens caps the tokens the model may spend reasoning
+	// about this message. Zero leaves the model's default. Send fails with an
+	// *[UnsupportedReasoningError] if the model has no reasoning controls.
+	ThinkingBudgetTokens int
+	// ResponseLanguage is a BCP 47 language tag naming the language to answer
+	// this message in, overriding [SessionConfig.ResponseLanguage]. The
+	// request is prepended to the prompt when it differs from the session's
+	// language.
+	ResponseLanguage string
+	// Deterministic asks for a reproducible turn, for regression evals. The
+	// settings are sent to the CLI, which applies those the model supports,
+	// and the SDK runs the session's tool handlers one at a time for the
+	// turn and serializes the request canonically. [Session.SendAndCollect]
+	// records the settings in [TurnResult.Determinism].
+	Deterministic *DeterminismConfig
+	// Model is the ID of the model to answer this message with, overriding
+	// the session's model for this message only. The session's model
+	// fallback chain does not apply to it. Send fails with
+	// [ErrUnsupportedByCLI] if the CLI does not accept a model per message,
+	// and with an *[UnknownModelError] listing the available models if the
+	// CLI rejects a model [Client.ListModels] does not return.
+	Model string
+}
+
+// SendAndWaitOptions configures how [Session.SendAndWaitWithOptions] waits for a turn to complete
+type SendAndWaitOptions struct {
+	// Timeout is how long to wait for the session to become idle.
+	// Default: 60 seconds, or none if the context has a deadline. Setting it
+	// is reported as [DeprecatedWaitTimeout]; set a deadline on ctx instead.
+	Timeout time.Duration
+	// IncludeHandlerTime counts time spent in the session's permission and user
+	// input handlers against Timeout. By default the timeout is paused while
+	// those handlers run.
+	IncludeHandlerTime bool
+	// OnProgress is called with a summary of the turn derived from the event
+	// stream: phase, running tool, characters streamed and elapsed time. It
+	// is called on the goroutine calling SendAndWait, at most once per
+	// ProgressInterval and only when something changed, plus a final call
+	// with phase ProgressDone when the session becomes idle.
+	OnProgress func(ProgressUpdate)
+	// ProgressInterval is the minimum time between OnProgress calls.
+	// Default: 250 milliseconds.
+	ProgressInterval time.Duration
+}
+
+// GetMessagesOptions configures [Session.GetMessagesWithOptions].
+type GetMessagesOptions struct {
+	// ConsistentWith is a message ID returned by [Session.Send]. When set, the
+	// call waits until the history includes that message's user.message
+	// event, so a read following a send sees the send.
+	ConsistentWith string
+	// ConsistencyTimeout bounds the wait for ConsistentWith. If it elapses, a
+	// *[ConsistencyTimeoutError] is returned. Default: 5 seconds.
+	ConsistencyTimeout time.Duration
 }
 
 // SessionEventHandler is a callback for session events
@@ -607,16 +1004,29 @@ type SessionListFilter struct {
 	Repository string `json:"repository,omitempty"`
 	// Branch filters by branch
 	Branch string `json:"branch,omitempty"`
+	// Limit caps the number of sessions returned. When Limit or
+	// ModifiedBefore is set, sessions are sorted by most recent modification
+	// first. Zero means no limit. The CLI lists every session; the SDK pages
+	// through them.
+	Limit int `json:"-"`
+	// ModifiedBefore keeps only sessions last modified before it. Set it to
+	// the ModifiedAt of the last session of a page to get the next page.
+	ModifiedBefore time.Time `json:"-"`
 }
 
 // SessionMetadata contains metadata about a session
 type SessionMetadata struct {
-	SessionID    string          `json:"sessionId"`
-	StartTime    string          `json:"startTime"`
-	ModifiedTime string          `json:"modifiedTime"`
-	Summary      *string         `json:"summary,omitempty"`
-	IsRemote     bool            `json:"isRemote"`
-	Context      *SessionContext `json:"context,omitempty"`
+	SessionID    string `json:"sessionId"`
+	StartTime    string `json:"startTime"`
+	ModifiedTime string `json:"modifiedTime"`
+	// StartedAt and ModifiedAt are StartTime and ModifiedTime parsed, or
+	// the zero time if they are missing or not RFC 3339 times
+	StartedAt  time.Time       `json:"-"`
+	ModifiedAt time.Time       `json:"-"`
+	Title      *string         `json:"title,omitempty"`
+	Summary    *string         `json:"summary,omitempty"`
+	IsRemote   bool            `json:"isRemote"`
+	Context    *SessionContext `json:"context,omitempty"`
 }
 
 // SessionLifecycleEventType represents the type of session lifecycle event
@@ -681,12 +1091,15 @@ type createSessionRequest struct {
 	SkillDirectories  []string                   `json:"skillDirectories,omitempty"`
 	DisabledSkills    []string                   `json:"disabledSkills,omitempty"`
 	InfiniteSessions  *InfiniteSessionConfig     `json:"infiniteSessions,omitempty"`
+	IntegrationID     string                     `json:"integrationId,omitempty"`
+	Features          map[string]bool            `json:"features,omitempty"`
 }
 
 // createSessionResponse is the response from session.create
 type createSessionResponse struct {
 	SessionID     string `json:"sessionId"`
 	WorkspacePath string `json:"workspacePath"`
+	sessionConfigEcho
 }
 
 // resumeSessionRequest is the request for session.resume
@@ -713,12 +1126,18 @@ type resumeSessionRequest struct {
 	SkillDirectories  []string                   `json:"skillDirectories,omitempty"`
 	DisabledSkills    []string                   `json:"disabledSkills,omitempty"`
 	InfiniteSessions  *InfiniteSessionConfig     `json:"infiniteSessions,omitempty"`
+	IntegrationID     string                     `json:"integrationId,omitempty"`
+	Features          map[string]bool            `json:"features,omitempty"`
+	// ResponseLanguage is already part of SystemMessage; it is kept so the
+	// language survives resuming
+	ResponseLanguage string `json:"-"`
 }
 
 // resumeSessionResponse is the response from session.resume
 type resumeSessionResponse struct {
 	SessionID     string `json:"sessionId"`
 	WorkspacePath string `json:"workspacePath"`
+	sessionConfigEcho
 }
 
 type hooksInvokeRequest struct {
@@ -770,6 +1189,9 @@ type setForegroundSessionResponse struct {
 
 type pingRequest struct {
 	Message string `json:"message,omitempty"`
+	// Compression lists the Content-Encoding values the SDK decodes, offered
+	// on TCP connections only
+	Compression []string `json:"compression,omitempty"`
 }
 
 // PingResponse is the response from a ping request
@@ -777,6 +1199,8 @@ type PingResponse struct {
 	Message         string `json:"message"`
 	Timestamp       int64  `json:"timestamp"`
 	ProtocolVersion *int   `json:"protocolVersion,omitempty"`
+	// Capabilities lists optional features the server supports, if it reports them
+	Capabilities *ServerCapabilities `json:"capabilities,omitempty"`
 }
 
 // getStatusRequest is the request for status.get
@@ -829,10 +1253,17 @@ type sessionAbortRequest struct {
 }
 
 type sessionSendRequest struct {
-	SessionID   string       `json:"sessionId"`
-	Prompt      string       `json:"prompt"`
-	Attachments []Attachment `json:"attachments,omitempty"`
-	Mode        string       `json:"mode,omitempty"`
+	SessionID      string           `json:"sessionId"`
+	Prompt         string           `json:"prompt"`
+	Attachments    []sendAttachment `json:"attachments,omitempty"`
+	Mode           string           `json:"mode,omitempty"`
+	ResponseSchema json.RawMessage  `json:"responseSchema,omitempty"`
+	Initiator      string           `json:"initiator,omitempty"`
+	Model          string           `json:"model,omitempty"`
+	// ReasoningEffort and ThinkingBudgetTokens apply to this message only
+	ReasoningEffort      string             `json:"reasoningEffort,omitempty"`
+	ThinkingBudgetTokens int                `json:"thinkingBudgetTokens,omitempty"`
+	Determinism          *DeterminismConfig `json:"determinism,omitempty"`
 }
 
 // sessionSendResponse is the response from session.send
@@ -853,6 +1284,29 @@ type toolCallRequest struct {
 	ToolCallID string `json:"toolCallId"`
 	ToolName   string `json:"toolName"`
 	Arguments  any    `json:"arguments"`
+	// RawArguments is the arguments exactly as sent by the CLI
+	RawArguments json.RawMessage `json:"-"`
+	// RawTimestamp is the timestamp exactly as sent by the CLI, if any
+	RawTimestamp json.RawMessage `json:"timestamp,omitempty"`
+	Cwd          string          `json:"cwd,omitempty"`
+}
+
+// UnmarshalJSON decodes a tool call request, keeping its arguments as sent.
+func (r *toolCallRequest) UnmarshalJSON(data []byte) error {
+	type plain toolCallRequest
+	aux := struct {
+		*plain
+		Arguments json.RawMessage `json:"arguments"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	r.Arguments, r.RawArguments = nil, nil
+	if len(aux.Arguments) == 0 || string(aux.Arguments) == "null" {
+		return nil
+	}
+	r.RawArguments = aux.Arguments
+	return json.Unmarshal(aux.Arguments, &r.Arguments)
 }
 
 // toolCallResponse represents the response to a tool call request
@@ -0,0 +1,97 @@
+package copilot
+
+import (
+	"maps"
+	"sync"
+)
+
+// TokenUsage totals model requests as reported by assistant.usage events.
+// Counts the CLI did not report are zero.
+type TokenUsage struct {
+	// Requests is the number of assistant.usage events
+	Requests         int
+	InputTokens      float64
+	OutputTokens     float64
+	CacheReadTokens  float64
+	CacheWriteTokens float64
+	// Cost is the premium request cost the CLI reported
+	Cost float64
+}
+
+// add counts one model request.
+func (u *TokenUsage) add(usage *AssistantUsageData) {
+	u.Requests++
+	u.InputTokens += usage.InputTokens
+	u.OutputTokens += usage.OutputTokens
+	u.CacheReadTokens += usage.CacheReadTokens
+	u.CacheWriteTokens += usage.CacheWriteTokens
+	u.Cost += usage.Cost
+}
+
+// UsageSummary totals the model requests of a turn or session, overall and
+// by model.
+type UsageSummary struct {
+	TokenUsage
+	// ByModel breaks the totals down by model ID. Requests whose usage event
+	// names no model are only counted in the totals.
+	ByModel map[string]TokenUsage
+}
+
+// observe counts the request reported by an assistant.usage event, and
+// ignores other events.
+func (s *UsageSummary) observe(event SessionEvent) {
+	usage, ok := event.AsAssistantUsage()
+	if !ok {
+		return
+	}
+	s.TokenUsage.add(usage)
+	if usage.Model == "" {
+		return
+	}
+	if s.ByModel == nil {
+		s.ByModel = make(map[string]TokenUsage)
+	}
+	model := s.ByModel[usage.Model]
+	model.add(usage)
+	s.ByModel[usage.Model] = model
+}
+
+// usageTracker accumulates the usage of a session from its events.
+type usageTracker struct {
+	mu      sync.Mutex
+	summary UsageSummary
+}
+
+func (u *usageTracker) observeEvent(event SessionEvent) {
+	if event.Type != AssistantUsage {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.summary.observe(event)
+}
+
+// Usage returns the token usage of the session: the totals of the
+// assistant.usage events it has received, overall and by model. The CLI has
+// no call reporting usage, so usage is accumulated as events arrive, and only
+// covers those received by this Session, not the turns of a resumed session
+// before it was resumed.
+//
+// For the usage of one turn, see [TurnResult.Usage]. To stop a session
+// automatically when it spends too much, see [SessionConfig.Budget].
+//
+// Example:
+//
+//	if _, err := session.SendAndWait(ctx, copilot.MessageOptions{Prompt: "Continue"}); err != nil {
+//	    log.Fatal(err)
+//	}
+//	if usage := session.Usage(); usage.InputTokens+usage.OutputTokens > 1_000_000 {
+//	    session.Abort(ctx)
+//	}
+func (s *Session) Usage() UsageSummary {
+	s.usage.mu.Lock()
+	defer s.usage.mu.Unlock()
+	summary := s.usage.summary
+	summary.ByModel = maps.Clone(summary.ByModel)
+	return summary
+}
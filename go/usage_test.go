@@ -0,0 +1,44 @@
+package copilot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSession_Usage(t *testing.T) {
+	usage := func(model string, input, output, cost float64) SessionEvent {
+		event := SessionEvent{Type: AssistantUsage, Data: Data{InputTokens: &input, OutputTokens: &output, Cost: &cost}}
+		if model != "" {
+			event.Data.Model = String(model)
+		}
+		return event
+	}
+
+	session := newSession("s1", nil, "")
+	if got := session.Usage(); got.Requests != 0 || got.ByModel != nil {
+		t.Fatalf("Expected no usage before any event, got %+v", got)
+	}
+	session.dispatchEvent(usage("gpt-5", 100, 20, 1))
+	session.dispatchEvent(usage("gpt-5", 300, 40, 1))
+	session.dispatchEvent(usage("claude-sonnet-4.5", 50, 10, 0.5))
+	session.dispatchEvent(usage("", 5, 1, 0))
+	session.dispatchEvent(SessionEvent{Type: SessionIdle})
+
+	got := session.Usage()
+	if want := (TokenUsage{Requests: 4, InputTokens: 455, OutputTokens: 71, Cost: 2.5}); got.TokenUsage != want {
+		t.Errorf("Expected totals %+v, got %+v", want, got.TokenUsage)
+	}
+	want := map[string]TokenUsage{
+		"gpt-5":             {Requests: 2, InputTokens: 400, OutputTokens: 60, Cost: 2},
+		"claude-sonnet-4.5": {Requests: 1, InputTokens: 50, OutputTokens: 10, Cost: 0.5},
+	}
+	if !reflect.DeepEqual(got.ByModel, want) {
+		t.Errorf("Expected by-model usage %+v, got %+v", want, got.ByModel)
+	}
+
+	// The returned breakdown is a copy
+	got.ByModel["gpt-5"] = TokenUsage{}
+	if session.Usage().ByModel["gpt-5"].Requests != 2 {
+		t.Error("Expected Usage to return a copy of the breakdown")
+	}
+}
@@ -0,0 +1,97 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultUserInputFallbackText is the answer given to the agent's questions
+// when no [UserInputHandler] is registered and no other fallback is
+// configured.
+const DefaultUserInputFallbackText = "No user is available to answer. Proceed with your best judgment and state any assumptions you made."
+
+// UserInputUnavailableWarning is the Data.WarningType of the session.warning
+// event the SDK emits when it answers a question with the
+// [UserInputFallback] because no [UserInputHandler] is registered.
+const UserInputUnavailableWarning = "user_input_unavailable"
+
+// ErrNoUserInputHandler is returned to the CLI when the agent asks a question
+// without a [UserInputHandler] registered and the fallback mode is
+// [UserInputFallbackFail].
+var ErrNoUserInputHandler = errors.New("no user input handler registered")
+
+// UserInputFallbackMode selects how questions are answered when no
+// [UserInputHandler] is registered.
+type UserInputFallbackMode string
+
+const (
+	// UserInputFallbackText answers with [UserInputFallback.Text]. This is
+	// the default.
+	UserInputFallbackText UserInputFallbackMode = "text"
+	// UserInputFallbackDefaultChoice answers with the first of the offered
+	// choices, or with [UserInputFallback.Text] if there are none.
+	UserInputFallbackDefaultChoice UserInputFallbackMode = "default-choice"
+	// UserInputFallbackFail fails the request, so the agent's ask_user tool
+	// call reports an error.
+	UserInputFallbackFail UserInputFallbackMode = "fail"
+)
+
+// UserInputFallback configures how the agent's questions are answered when
+// no [UserInputHandler] is registered. Each fallback answer is accompanied by
+// a session.warning event with Data.WarningType set to
+// [UserInputUnavailableWarning].
+type UserInputFallback struct {
+	// Mode selects the fallback behavior. Default: [UserInputFallbackText]
+	Mode UserInputFallbackMode `json:"mode,omitempty"`
+	// Text is the freeform answer for [UserInputFallbackText] and for
+	// [UserInputFallbackDefaultChoice] when no choices are offered.
+	// Default: [DefaultUserInputFallbackText]
+	Text string `json:"text,omitempty"`
+}
+
+// answer applies the fallback to request.
+func (f UserInputFallback) answer(request UserInputRequest) (UserInputResponse, error) {
+	text := f.Text
+	if text == "" {
+		text = DefaultUserInputFallbackText
+	}
+	switch f.Mode {
+	case "", UserInputFallbackText:
+		return UserInputResponse{Answer: text, WasFreeform: true}, nil
+	case UserInputFallbackDefaultChoice:
+		if len(request.Choices) > 0 {
+			return UserInputResponse{Answer: request.Choices[0]}, nil
+		}
+		return UserInputResponse{Answer: text, WasFreeform: true}, nil
+	case UserInputFallbackFail:
+		return UserInputResponse{}, ErrNoUserInputHandler
+	default:
+		return UserInputResponse{}, fmt.Errorf("unknown user input fallback mode %q", f.Mode)
+	}
+}
+
+// answerWithoutHandler answers a question with the session's fallback and
+// emits a warning so the missing handler doesn't go unnoticed.
+func (s *Session) answerWithoutHandler(request UserInputRequest) (UserInputResponse, error) {
+	var fallback UserInputFallback
+	if s.userInputFallback != nil {
+		fallback = *s.userInputFallback
+	}
+	response, err := fallback.answer(request)
+
+	message := fmt.Sprintf("no user input handler is registered; answered %q with %q", request.Question, response.Answer)
+	if err != nil {
+		message = fmt.Sprintf("no user input handler is registered; failed the question %q", request.Question)
+	}
+	s.dispatchEvent(SessionEvent{
+		Type:      SessionWarning,
+		Timestamp: time.Now(),
+		Ephemeral: Bool(true),
+		Data: Data{
+			WarningType: String(UserInputUnavailableWarning),
+			Message:     String(message),
+		},
+	})
+	return response, err
+}
@@ -0,0 +1,122 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSession_UserInputFallback(t *testing.T) {
+	colors := UserInputRequest{Question: "Pick a color", Choices: []string{"Red", "Blue"}}
+	freeform := UserInputRequest{Question: "What is your name?"}
+
+	tests := []struct {
+		name     string
+		fallback *UserInputFallback
+		request  UserInputRequest
+		want     UserInputResponse
+		wantErr  error
+	}{
+		{
+			name:    "default answers with the default text",
+			request: colors,
+			want:    UserInputResponse{Answer: DefaultUserInputFallbackText, WasFreeform: true},
+		},
+		{
+			name:     "text mode uses the configured text",
+			fallback: &UserInputFallback{Text: "Use the defaults."},
+			request:  colors,
+			want:     UserInputResponse{Answer: "Use the defaults.", WasFreeform: true},
+		},
+		{
+			name:     "default choice picks the first choice",
+			fallback: &UserInputFallback{Mode: UserInputFallbackDefaultChoice},
+			request:  colors,
+			want:     UserInputResponse{Answer: "Red"},
+		},
+		{
+			name:     "default choice without choices falls back to text",
+			fallback: &UserInputFallback{Mode: UserInputFallbackDefaultChoice, Text: "unknown"},
+			request:  freeform,
+			want:     UserInputResponse{Answer: "unknown", WasFreeform: true},
+		},
+		{
+			name:     "fail mode returns an error",
+			fallback: &UserInputFallback{Mode: UserInputFallbackFail},
+			request:  colors,
+			wantErr:  ErrNoUserInputHandler,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newSession("s1", nil, "")
+			session.userInputFallback = tt.fallback
+			var warnings []SessionEvent
+			session.On(func(event SessionEvent) {
+				if event.Type == SessionWarning {
+					warnings = append(warnings, event)
+				}
+			})
+
+			got, err := session.handleUserInputRequest(tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+			if len(warnings) != 1 || *warnings[0].Data.WarningType != UserInputUnavailableWarning {
+				t.Fatalf("Expected one user input warning, got %+v", warnings)
+			}
+			if msg := *warnings[0].Data.Message; !strings.Contains(msg, tt.request.Question) {
+				t.Errorf("Expected the warning to name the question, got %q", msg)
+			}
+		})
+	}
+
+	t.Run("registered handlers take precedence", func(t *testing.T) {
+		session := newSession("s1", nil, "")
+		session.userInputFallback = &UserInputFallback{Mode: UserInputFallbackFail}
+		session.registerUserInputHandler(func(UserInputRequest, UserInputInvocation) (UserInputResponse, error) {
+			return UserInputResponse{Answer: "Blue"}, nil
+		})
+		var warned bool
+		session.On(func(event SessionEvent) { warned = warned || event.Type == SessionWarning })
+
+		got, err := session.handleUserInputRequest(colors)
+		if err != nil || got.Answer != "Blue" {
+			t.Errorf("Expected the handler's answer, got %+v, %v", got, err)
+		}
+		if warned {
+			t.Error("Expected no warning when a handler is registered")
+		}
+	})
+
+	t.Run("setting a fallback enables ask_user", func(t *testing.T) {
+		var requested []*bool
+		cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+			if method == "session.create" {
+				var req createSessionRequest
+				json.Unmarshal(params, &req)
+				requested = append(requested, req.RequestUserInput)
+				return createSessionResponse{SessionID: req.SessionID}, nil
+			}
+			return nil, nil
+		})
+		client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+		t.Cleanup(func() { client.ForceStop() })
+
+		for _, fallback := range []*UserInputFallback{nil, {Mode: UserInputFallbackDefaultChoice}} {
+			if _, err := client.CreateSession(t.Context(), &SessionConfig{
+				OnPermissionRequest: PermissionHandler.ApproveAll,
+				UserInputFallback:   fallback,
+			}); err != nil {
+				t.Fatalf("Failed to create session: %v", err)
+			}
+		}
+		if requested[0] != nil || requested[1] == nil || !*requested[1] {
+			t.Errorf("Expected only the session with a fallback to request user input, got %v", requested)
+		}
+	})
+}
@@ -0,0 +1,299 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultSendAndWaitTimeout is how long SendAndWait waits when neither a
+// timeout nor a context deadline is given.
+const defaultSendAndWaitTimeout = 60 * time.Second
+
+// waitTimer is a timeout that can be paused while session handlers are running.
+type waitTimer struct {
+	enabled            bool
+	remaining          time.Duration
+	activity           *sessionState
+	includeHandlerTime bool
+
+	timer     *time.Timer
+	startedAt time.Time
+	changedCh <-chan struct{}
+}
+
+// newWaitTimer starts a timer for timeout. A zero timeout never expires.
+func newWaitTimer(timeout time.Duration, activity *sessionState, includeHandlerTime bool) *waitTimer {
+	w := &waitTimer{
+		enabled:            timeout > 0,
+		remaining:          timeout,
+		activity:           activity,
+		includeHandlerTime: includeHandlerTime,
+	}
+	w.update()
+	return w
+}
+
+// update re-evaluates handler activity, pausing or resuming the timer.
+func (w *waitTimer) update() {
+	if !w.enabled {
+		return
+	}
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+		w.remaining -= time.Since(w.startedAt)
+	}
+
+	paused := false
+	if !w.includeHandlerTime {
+		paused, w.changedCh = w.activity.handlers()
+	}
+	if !paused {
+		w.startedAt = time.Now()
+		w.timer = time.NewTimer(max(w.remaining, 0))
+	}
+}
+
+// expired returns a channel that fires when the timeout elapses, or nil while paused.
+func (w *waitTimer) expired() <-chan time.Time {
+	if w.timer == nil {
+		return nil
+	}
+	return w.timer.C
+}
+
+// changed returns a channel closed when handler activity changes.
+func (w *waitTimer) changed() <-chan struct{} {
+	return w.changedCh
+}
+
+func (w *waitTimer) stop() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+// sendAndCollect sends a message and waits until the session is idle,
+// collecting the events emitted in the meantime.
+func (s *Session) sendAndCollect(ctx context.Context, options MessageOptions, waitOptions *SendAndWaitOptions) (*TurnResult, error) {
+	var opts SendAndWaitOptions
+	if waitOptions != nil {
+		opts = *waitOptions
+	}
+	timeout := opts.Timeout
+	if _, ok := ctx.Deadline(); !ok && timeout <= 0 {
+		timeout = defaultSendAndWaitTimeout
+	}
+
+	idleCh := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+	abortCh := make(chan string, 1)
+	var events []SessionEvent
+	var received []time.Time
+	var mu sync.Mutex
+	var progress *progressTracker
+	if opts.OnProgress != nil {
+		progress = newProgressTracker(time.Now)
+	}
+
+	unsubscribe := s.On(func(event SessionEvent) {
+		if progress != nil {
+			progress.observe(event)
+		}
+		at := s.timeline.clock()
+		mu.Lock()
+		events = append(events, event)
+		received = append(received, at)
+		mu.Unlock()
+
+		switch event.Type {
+		case SessionIdle:
+			select {
+			case idleCh <- struct{}{}:
+			default:
+			}
+		case Abort:
+			reason := ""
+			if event.Data.Reason != nil {
+				reason = *event.Data.Reason
+			}
+			select {
+			case abortCh <- reason:
+			default:
+			}
+		case SessionError:
+			select {
+			case errCh <- sessionEventError(event):
+			default:
+			}
+		}
+	})
+	defer unsubscribe()
+
+	// Only aborts acknowledged after the send started concern this turn
+	aborted := s.aborts.wait()
+	sentAt := s.timeline.clock()
+	messageID, sent, err := s.send(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	// The trace ID the SDK generated, until the CLI's interaction ID arrives
+	_, _, traceID := s.trace.current()
+
+	snapshot := func() *TurnResult {
+		end := s.timeline.clock()
+		mu.Lock()
+		turnEvents := append([]SessionEvent(nil), events...)
+		timed := make([]timedEvent, len(events))
+		for i, event := range events {
+			timed[i] = timedEvent{event: event, received: received[i]}
+		}
+		mu.Unlock()
+		ref, _ := s.messageRefs.lookup(messageID)
+		result := buildTurnResult(messageID, ref, turnEvents)
+		result.ModelFallback = sent.fallback
+		result.Determinism = sent.determinism
+		traceIDs := []string{traceID}
+		if ref.InteractionID != "" {
+			traceIDs = append(traceIDs, ref.InteractionID)
+		}
+		result.Timeline = buildTimeline(sentAt, end, traceIDs, timed, s.timeline.recorded(sentAt))
+		return result
+	}
+	abortedError := func(reason string) error {
+		result := snapshot()
+		return &AbortedError{
+			MessageID:      messageID,
+			Reason:         reason,
+			PartialContent: partialContent(result.Events),
+			Turn:           result,
+		}
+	}
+	// checkAborted reports an abort that arrived along with another outcome,
+	// such as the session.idle or session.error the CLI emits after aborting
+	checkAborted := func() error {
+		select {
+		case reason := <-abortCh:
+			return abortedError(reason)
+		case <-aborted:
+			return abortedError("")
+		default:
+			return nil
+		}
+	}
+
+	timer := newWaitTimer(timeout, &s.state, opts.IncludeHandlerTime)
+	defer timer.stop()
+
+	var progressTick <-chan time.Time
+	if progress != nil {
+		interval := opts.ProgressInterval
+		if interval <= 0 {
+			interval = defaultProgressInterval
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		progressTick = ticker.C
+		reportProgress(progress, opts.OnProgress)
+	}
+
+	for {
+		select {
+		case <-progressTick:
+			reportProgress(progress, opts.OnProgress)
+		case <-idleCh:
+			if err := checkAborted(); err != nil {
+				return nil, err
+			}
+			if progress != nil {
+				update, _ := progress.take()
+				opts.OnProgress(update)
+			}
+			return snapshot(), nil
+		case reason := <-abortCh:
+			return nil, abortedError(reason)
+		case <-aborted:
+			return nil, abortedError("")
+		case err := <-errCh:
+			if abortErr := checkAborted(); abortErr != nil {
+				return nil, abortErr
+			}
+			return nil, err
+		case <-ctx.Done(): // TODO: remove once session.Send honors the context
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%w waiting for session.idle: %w", ErrTimeout, ctx.Err())
+			}
+			return nil, fmt.Errorf("waiting for session.idle: %w", ctx.Err())
+		case <-timer.expired():
+			return nil, fmt.Errorf("%w waiting for session.idle: %w", ErrTimeout, context.DeadlineExceeded)
+		case <-timer.changed():
+			timer.update()
+		}
+	}
+}
+
+// reportProgress calls onProgress if the tracked progress changed since the
+// last report.
+func reportProgress(progress *progressTracker, onProgress func(ProgressUpdate)) {
+	if update, changed := progress.take(); changed {
+		onProgress(update)
+	}
+}
+
+// WaitForIdle blocks until the session is idle: until the CLI has finished
+// the turns started by messages sent so far, including work triggered by
+// tools. It returns at once if the session is idle already. It waits until
+// [Session.State] is [SessionStateIdle].
+//
+// If a session.error event ends the turn, WaitForIdle returns it as an error,
+// as [Session.SendAndWait] does. It gives up when ctx is done, or when the
+// session is destroyed with an error matching [ErrSessionDestroyed]. Unlike
+// SendAndWait, it applies no timeout of its own.
+//
+// Example:
+//
+//	for _, prompt := range prompts {
+//	    if _, err := session.Send(ctx, copilot.MessageOptions{Prompt: prompt}); err != nil {
+//	        return err
+//	    }
+//	}
+//	if err := session.WaitForIdle(ctx); err != nil {
+//	    return err
+//	}
+func (s *Session) WaitForIdle(ctx context.Context) error {
+	if err := s.checkNotDestroyed(); err != nil {
+		return err
+	}
+	waiter := s.state.idle()
+	if waiter == nil {
+		return nil
+	}
+	select {
+	case <-waiter.done:
+		return waiter.err
+	case <-s.Done():
+		return s.checkNotDestroyed()
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w waiting for session.idle: %w", ErrTimeout, ctx.Err())
+		}
+		return fmt.Errorf("waiting for session.idle: %w", ctx.Err())
+	}
+}
+
+// sessionEventError returns the error reported by a session.error event.
+// Authentication errors match [ErrNotAuthenticated].
+func sessionEventError(event SessionEvent) error {
+	errMsg := "session error"
+	if event.Data.Message != nil {
+		errMsg = *event.Data.Message
+	}
+	err := fmt.Errorf("session error: %s", errMsg)
+	if event.Data.ErrorType != nil && *event.Data.ErrorType == "authentication" {
+		err = &classifiedError{sentinel: ErrNotAuthenticated, err: err}
+	}
+	return err
+}
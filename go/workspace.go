@@ -0,0 +1,386 @@
+package copilot
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoWorkspace is returned by workspace operations on a session without a
+// workspace, i.e. one created with infinite sessions disabled.
+var ErrNoWorkspace = errors.New("session has no workspace")
+
+// SessionWorkspacePruned is emitted by the SDK after it removes old
+// checkpoints because the workspace exceeded
+// [InfiniteSessionConfig.MaxWorkspaceBytes]. Use
+// [SessionEvent.AsWorkspacePruned] to read the payload.
+const SessionWorkspacePruned SessionEventType = "session.workspace_pruned"
+
+// checkpointsDir is the workspace subdirectory holding checkpoints.
+const checkpointsDir = "checkpoints"
+
+// WorkspaceUsage reports the disk usage of a session workspace.
+type WorkspaceUsage struct {
+	// TotalBytes is the size of all files in the workspace
+	TotalBytes int64
+	// Subdirectories maps each top-level subdirectory (e.g. "checkpoints",
+	// "files") to the size of the files under it
+	Subdirectories map[string]int64
+	// Checkpoints is the number of checkpoints in the workspace
+	Checkpoints int
+}
+
+// WorkspacePrunedData is the payload of a session.workspace_pruned event.
+type WorkspacePrunedData struct {
+	// RemovedCheckpoints lists the numbers of the removed checkpoints, oldest first
+	RemovedCheckpoints []int
+	// BytesFreed is the total size of the removed checkpoints
+	BytesFreed int64
+	// Usage is the workspace usage after pruning
+	Usage WorkspaceUsage
+}
+
+// AsWorkspacePruned returns the payload of a session.workspace_pruned event.
+// The second return value is false for any other event type.
+func (e SessionEvent) AsWorkspacePruned() (*WorkspacePrunedData, bool) {
+	if e.Type != SessionWorkspacePruned {
+		return nil, false
+	}
+	data, ok := e.Data.Output.(*WorkspacePrunedData)
+	return data, ok
+}
+
+// workspaceCheckpoint is a checkpoint file or directory in the workspace.
+type workspaceCheckpoint struct {
+	number int
+	path   string
+	bytes  int64
+}
+
+// WorkspaceUsage reports the disk usage of the session's workspace. It returns
+// [ErrNoWorkspace] if the session has no workspace.
+//
+// Example:
+//
+//	usage, err := session.WorkspaceUsage()
+//	if err == nil {
+//	    fmt.Printf("%d bytes, %d checkpoints\n", usage.TotalBytes, usage.Checkpoints)
+//	}
+func (s *Session) WorkspaceUsage() (WorkspaceUsage, error) {
+	root := s.WorkspacePath()
+	if root == "" {
+		return WorkspaceUsage{}, ErrNoWorkspace
+	}
+	return measureWorkspace(root)
+}
+
+// PruneCheckpoints removes all but the keepLast most recent checkpoints from
+// the session's workspace. The latest checkpoint is always kept, so keepLast
+// values below 1 are treated as 1. Files outside the checkpoints directory,
+// including plan.md, are never touched. It returns [ErrNoWorkspace] if the
+// session has no workspace.
+//
+// Removed checkpoints can no longer be used to rewind the session.
+//
+// Example:
+//
+//	if err := session.PruneCheckpoints(5); err != nil {
+//	    log.Printf("Failed to prune checkpoints: %v", err)
+//	}
+func (s *Session) PruneCheckpoints(keepLast int) error {
+	root := s.WorkspacePath()
+	if root == "" {
+		return ErrNoWorkspace
+	}
+	checkpoints, err := listCheckpoints(root)
+	if err != nil {
+		return err
+	}
+	keepLast = max(keepLast, 1)
+	if len(checkpoints) <= keepLast {
+		return nil
+	}
+	_, _, err = removeCheckpoints(checkpoints[:len(checkpoints)-keepLast])
+	return err
+}
+
+// measureWorkspace walks a workspace directory and sums file sizes.
+func measureWorkspace(root string) (WorkspaceUsage, error) {
+	usage := WorkspaceUsage{Subdirectories: make(map[string]int64)}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		top, _, nested := strings.Cut(filepath.ToSlash(rel), "/")
+		if d.IsDir() {
+			if rel != "." && !nested {
+				usage.Subdirectories[top] = 0 // listed even when empty
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		usage.TotalBytes += info.Size()
+		if nested {
+			usage.Subdirectories[top] += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return WorkspaceUsage{}, fmt.Errorf("failed to measure workspace: %w", err)
+	}
+
+	checkpoints, err := listCheckpoints(root)
+	if err != nil {
+		return WorkspaceUsage{}, err
+	}
+	usage.Checkpoints = len(checkpoints)
+	return usage, nil
+}
+
+// listCheckpoints returns the checkpoints in a workspace, oldest first.
+// Checkpoints are the entries of the checkpoints directory whose names start
+// with their sequence number, e.g. "003.md"; other entries are ignored.
+func listCheckpoints(root string) ([]workspaceCheckpoint, error) {
+	dir := filepath.Join(root, checkpointsDir)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+
+	var checkpoints []workspaceCheckpoint
+	for _, entry := range entries {
+		digits := strings.IndexFunc(entry.Name(), func(r rune) bool { return r < '0' || r > '9' })
+		if digits == -1 {
+			digits = len(entry.Name())
+		}
+		number, err := strconv.Atoi(entry.Name()[:digits])
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		size, err := treeSize(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+		}
+		checkpoints = append(checkpoints, workspaceCheckpoint{number: number, path: path, bytes: size})
+	}
+	sort.SliceStable(checkpoints, func(i, j int) bool { return checkpoints[i].number < checkpoints[j].number })
+	return checkpoints, nil
+}
+
+// treeSize returns the size of a file, or of all files under a directory.
+func treeSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+// removeCheckpoints deletes checkpoints in order, stopping at the first
+// failure. It returns the numbers of the removed checkpoints and the bytes
+// freed.
+func removeCheckpoints(checkpoints []workspaceCheckpoint) ([]int, int64, error) {
+	var removed []int
+	var freed int64
+	for _, checkpoint := range checkpoints {
+		if err := os.RemoveAll(checkpoint.path); err != nil {
+			return removed, freed, fmt.Errorf("failed to remove checkpoint %d: %w", checkpoint.number, err)
+		}
+		removed = append(removed, checkpoint.number)
+		freed += checkpoint.bytes
+	}
+	return removed, freed, nil
+}
+
+// workspaceLimiter prunes a session's checkpoints when its workspace grows
+// past a size limit.
+type workspaceLimiter struct {
+	maxBytes int64
+	running  atomic.Bool
+}
+
+// observeEvent starts a background size check after each new checkpoint.
+func (w *workspaceLimiter) observeEvent(s *Session, event SessionEvent) {
+	if _, ok := event.AsCheckpointCreated(); !ok {
+		return
+	}
+	if !w.running.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer w.running.Store(false)
+		w.enforce(s)
+	}()
+}
+
+// enforce removes the oldest checkpoints, never the latest, until the
+// workspace fits in maxBytes, and emits a session.workspace_pruned event if
+// any were removed.
+func (w *workspaceLimiter) enforce(s *Session) {
+	root := s.WorkspacePath()
+	if root == "" {
+		return
+	}
+	usage, err := measureWorkspace(root)
+	if err != nil || usage.TotalBytes <= w.maxBytes {
+		return
+	}
+	checkpoints, err := listCheckpoints(root)
+	if err != nil {
+		return
+	}
+
+	var remove []workspaceCheckpoint
+	excess := usage.TotalBytes - w.maxBytes
+	for _, checkpoint := range checkpoints[:max(len(checkpoints)-1, 0)] {
+		if excess <= 0 {
+			break
+		}
+		remove = append(remove, checkpoint)
+		excess -= checkpoint.bytes
+	}
+	if len(remove) == 0 {
+		return
+	}
+
+	removed, freed, _ := removeCheckpoints(remove)
+	if len(removed) == 0 {
+		return
+	}
+	data := &WorkspacePrunedData{RemovedCheckpoints: removed, BytesFreed: freed}
+	data.Usage, _ = measureWorkspace(root)
+
+	s.dispatchEvent(SessionEvent{
+		Type:      SessionWorkspacePruned,
+		Timestamp: time.Now(),
+		Ephemeral: Bool(true),
+		Data: Data{
+			Message: String(fmt.Sprintf("removed %d old checkpoints (%d bytes) to keep the workspace under %d bytes",
+				len(data.RemovedCheckpoints), data.BytesFreed, w.maxBytes)),
+			Output: data,
+		},
+	})
+}
+
+// WorkspaceRootIgnoredWarning is the warning type of the session.warning
+// event emitted when [InfiniteSessionConfig.WorkspaceRoot] is set but the CLI
+// placed the session's workspace elsewhere, as CLIs without support for the
+// option do.
+const WorkspaceRootIgnoredWarning = "workspace_root_ignored"
+
+// ErrWorkspaceNotWritable matches errors from [Client.CreateSession] and
+// [Client.ResumeSessionWithOptions] when [InfiniteSessionConfig.WorkspaceRoot]
+// cannot be written to.
+var ErrWorkspaceNotWritable = errors.New("workspace root is not writable")
+
+// WorkspaceNotWritableError is returned when creating or resuming a session
+// whose [InfiniteSessionConfig.WorkspaceRoot] cannot be written to. It matches
+// [ErrWorkspaceNotWritable].
+type WorkspaceNotWritableError struct {
+	// Path is the absolute path of the workspace root
+	Path string
+	// Err is the error creating the directory or the probe file
+	Err error
+}
+
+func (e *WorkspaceNotWritableError) Error() string {
+	return fmt.Sprintf("workspace root %s is not writable: %v", e.Path, e.Err)
+}
+
+func (e *WorkspaceNotWritableError) Unwrap() error { return e.Err }
+
+func (e *WorkspaceNotWritableError) Is(target error) bool { return target == ErrWorkspaceNotWritable }
+
+// prepareWorkspaceRoot makes config's WorkspaceRoot absolute, so the CLI
+// resolves it as the caller does, and checks that it is writable by creating
+// and removing a probe file in it. The check is skipped for external servers,
+// which may not share the SDK's filesystem. The caller's config is not
+// modified.
+func (c *Client) prepareWorkspaceRoot(config *InfiniteSessionConfig) (*InfiniteSessionConfig, error) {
+	if config == nil || config.WorkspaceRoot == "" || !infiniteSessionsEnabled(config) {
+		return config, nil
+	}
+	root, err := filepath.Abs(config.WorkspaceRoot)
+	if err != nil {
+		return nil, &WorkspaceNotWritableError{Path: config.WorkspaceRoot, Err: err}
+	}
+	if !c.isExternalServer {
+		if err := probeWritable(root); err != nil {
+			return nil, &WorkspaceNotWritableError{Path: root, Err: err}
+		}
+	}
+	prepared := *config
+	prepared.WorkspaceRoot = root
+	return &prepared, nil
+}
+
+// probeWritable creates dir if needed, then creates and removes a file in it.
+func probeWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	probe, err := os.CreateTemp(dir, ".copilot-sdk-probe-*")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// workspaceUnderRoot returns the workspace path of a session created with
+// config: the path the CLI reported, or the session's directory under
+// WorkspaceRoot if the CLI reported none.
+func workspaceUnderRoot(config *InfiniteSessionConfig, sessionID, reported string) string {
+	if reported != "" || config == nil || config.WorkspaceRoot == "" || !infiniteSessionsEnabled(config) {
+		return reported
+	}
+	return filepath.Join(config.WorkspaceRoot, sessionID)
+}
+
+// warnWorkspaceOutsideRoot emits a session.warning event if the session's
+// workspace is not under root.
+func (s *Session) warnWorkspaceOutsideRoot(config *InfiniteSessionConfig) {
+	workspacePath := s.WorkspacePath()
+	if config == nil || config.WorkspaceRoot == "" || workspacePath == "" {
+		return
+	}
+	rel, err := filepath.Rel(config.WorkspaceRoot, workspacePath)
+	if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return
+	}
+	s.dispatchEvent(SessionEvent{
+		Type:      SessionWarning,
+		Timestamp: time.Now(),
+		Ephemeral: Bool(true),
+		Data: Data{
+			WarningType: String(WorkspaceRootIgnoredWarning),
+			Message:     String(fmt.Sprintf("the CLI placed the workspace at %s rather than under %s", workspacePath, config.WorkspaceRoot)),
+		},
+	})
+}
@@ -0,0 +1,303 @@
+package copilot
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newWorkspaceFixture creates a workspace with plan.md, files/, and the given
+// checkpoint files of 100 bytes each.
+func newWorkspaceFixture(t *testing.T, checkpoints ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	write := func(rel string, size int) {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("plan.md", 10)
+	write("files/notes.txt", 20)
+	write("files/nested/data.json", 30)
+	write("checkpoints/index.md", 5)
+	for _, name := range checkpoints {
+		write(filepath.Join("checkpoints", name), 100)
+	}
+	return root
+}
+
+func remainingCheckpoints(t *testing.T, root string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Join(root, "checkpoints"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestSession_WorkspaceUsage(t *testing.T) {
+	t.Run("reports bytes per subdirectory and checkpoint count", func(t *testing.T) {
+		root := newWorkspaceFixture(t, "001.md", "002.md", "010.md")
+		os.Mkdir(filepath.Join(root, "empty"), 0o755)
+		session := newSession("s1", nil, root)
+
+		usage, err := session.WorkspaceUsage()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := WorkspaceUsage{
+			TotalBytes:     10 + 20 + 30 + 5 + 300,
+			Subdirectories: map[string]int64{"files": 50, "checkpoints": 305, "empty": 0},
+			Checkpoints:    3,
+		}
+		if !reflect.DeepEqual(usage, want) {
+			t.Errorf("Expected %+v, got %+v", want, usage)
+		}
+	})
+
+	t.Run("returns ErrNoWorkspace without a workspace", func(t *testing.T) {
+		session := newSession("s1", nil, "")
+		if _, err := session.WorkspaceUsage(); !errors.Is(err, ErrNoWorkspace) {
+			t.Errorf("Expected ErrNoWorkspace, got %v", err)
+		}
+		if err := session.PruneCheckpoints(1); !errors.Is(err, ErrNoWorkspace) {
+			t.Errorf("Expected ErrNoWorkspace, got %v", err)
+		}
+	})
+}
+
+func TestSession_PruneCheckpoints(t *testing.T) {
+	t.Run("keeps the most recent checkpoints by number", func(t *testing.T) {
+		root := newWorkspaceFixture(t, "002.md", "010.md", "001.md", "009.md")
+		session := newSession("s1", nil, root)
+
+		if err := session.PruneCheckpoints(2); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := []string{"009.md", "010.md", "index.md"}
+		if got := remainingCheckpoints(t, root); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+		if _, err := os.Stat(filepath.Join(root, "plan.md")); err != nil {
+			t.Errorf("Expected plan.md to be kept: %v", err)
+		}
+	})
+
+	t.Run("never removes the latest checkpoint", func(t *testing.T) {
+		root := newWorkspaceFixture(t, "001.md", "002.md")
+		session := newSession("s1", nil, root)
+
+		if err := session.PruneCheckpoints(0); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := []string{"002.md", "index.md"}
+		if got := remainingCheckpoints(t, root); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("removes checkpoint directories", func(t *testing.T) {
+		root := newWorkspaceFixture(t, "001/summary.md", "002/summary.md")
+		session := newSession("s1", nil, root)
+
+		if err := session.PruneCheckpoints(1); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		want := []string{"002", "index.md"}
+		if got := remainingCheckpoints(t, root); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("succeeds without a checkpoints directory", func(t *testing.T) {
+		session := newSession("s1", nil, t.TempDir())
+		if err := session.PruneCheckpoints(1); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func TestSession_MaxWorkspaceBytes(t *testing.T) {
+	checkpointEvent := func(number float64) SessionEvent {
+		return SessionEvent{Type: SessionCheckpointCreated, Data: Data{CheckpointNumber: Float64(number)}}
+	}
+	newLimitedSession := func(root string, maxBytes int64) (*Session, chan SessionEvent) {
+		session := newSession("s1", nil, root)
+		session.workspaceLimit = &workspaceLimiter{maxBytes: maxBytes}
+		pruned := make(chan SessionEvent, 1)
+		session.On(func(event SessionEvent) {
+			if event.Type == SessionWorkspacePruned {
+				pruned <- event
+			}
+		})
+		return session, pruned
+	}
+
+	t.Run("prunes old checkpoints after a checkpoint exceeds the limit", func(t *testing.T) {
+		// 65 bytes outside checkpoints plus 4 checkpoints of 100 bytes
+		root := newWorkspaceFixture(t, "001.md", "002.md", "003.md", "004.md")
+		session, pruned := newLimitedSession(root, 300)
+
+		session.dispatchEvent(checkpointEvent(4))
+		var event SessionEvent
+		select {
+		case event = <-pruned:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected a workspace pruned event")
+		}
+
+		data, ok := event.AsWorkspacePruned()
+		if !ok {
+			t.Fatalf("Expected a workspace pruned payload, got %+v", event)
+		}
+		if !reflect.DeepEqual(data.RemovedCheckpoints, []int{1, 2}) || data.BytesFreed != 200 {
+			t.Errorf("Unexpected payload: %+v", data)
+		}
+		if data.Usage.TotalBytes != 265 || data.Usage.Checkpoints != 2 {
+			t.Errorf("Unexpected usage after pruning: %+v", data.Usage)
+		}
+		want := []string{"003.md", "004.md", "index.md"}
+		if got := remainingCheckpoints(t, root); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("keeps the latest checkpoint even when still over the limit", func(t *testing.T) {
+		root := newWorkspaceFixture(t, "001.md", "002.md")
+		session, pruned := newLimitedSession(root, 10)
+
+		session.dispatchEvent(checkpointEvent(2))
+		select {
+		case event := <-pruned:
+			if data, _ := event.AsWorkspacePruned(); !reflect.DeepEqual(data.RemovedCheckpoints, []int{1}) {
+				t.Errorf("Expected only checkpoint 1 to be removed, got %+v", data)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected a workspace pruned event")
+		}
+		want := []string{"002.md", "index.md"}
+		if got := remainingCheckpoints(t, root); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+		if _, err := os.Stat(filepath.Join(root, "plan.md")); err != nil {
+			t.Errorf("Expected plan.md to be kept: %v", err)
+		}
+	})
+
+	t.Run("does nothing under the limit", func(t *testing.T) {
+		root := newWorkspaceFixture(t, "001.md", "002.md")
+		session, pruned := newLimitedSession(root, 1<<20)
+
+		session.dispatchEvent(checkpointEvent(2))
+		select {
+		case event := <-pruned:
+			t.Errorf("Unexpected pruned event: %+v", event)
+		case <-time.After(50 * time.Millisecond):
+		}
+		if got := remainingCheckpoints(t, root); len(got) != 3 {
+			t.Errorf("Expected no checkpoints removed, got %v", got)
+		}
+	})
+}
+
+func TestClient_WorkspaceRoot(t *testing.T) {
+	var created createSessionRequest
+	cli := newFakeCLI(t, func(method string, params json.RawMessage) (any, error) {
+		if method == "session.create" {
+			json.Unmarshal(params, &created)
+			return createSessionResponse{SessionID: "s1"}, nil
+		}
+		return nil, nil
+	})
+	client := NewClient(&ClientOptions{CLIUrl: cli.addr()})
+	t.Cleanup(func() { client.ForceStop() })
+	if err := client.Start(t.Context()); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	// The root is probed only for a CLI the client spawned, which shares its
+	// filesystem.
+	client.isExternalServer = false
+
+	t.Run("sends the root and places the workspace under it", func(t *testing.T) {
+		root := filepath.Join(t.TempDir(), "scratch")
+		session, err := client.CreateSession(t.Context(), &SessionConfig{
+			InfiniteSessions:    &InfiniteSessionConfig{WorkspaceRoot: root},
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if created.InfiniteSessions == nil || created.InfiniteSessions.WorkspaceRoot != root {
+			t.Errorf("Expected workspaceRoot %s to be sent, got %+v", root, created.InfiniteSessions)
+		}
+		if got, want := session.WorkspacePath(), filepath.Join(root, "s1"); got != want {
+			t.Errorf("Expected workspace path %s, got %s", want, got)
+		}
+		entries, _ := os.ReadDir(root)
+		if len(entries) != 0 {
+			t.Errorf("Expected the probe file to be removed, got %v", entries)
+		}
+	})
+
+	t.Run("fails on a read-only root", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("root can write to read-only directories")
+		}
+		root := t.TempDir()
+		if err := os.Chmod(root, 0o500); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Chmod(root, 0o700) })
+		_, err := client.CreateSession(t.Context(), &SessionConfig{
+			InfiniteSessions:    &InfiniteSessionConfig{WorkspaceRoot: root},
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+		})
+		var notWritable *WorkspaceNotWritableError
+		if !errors.As(err, &notWritable) || notWritable.Path != root {
+			t.Errorf("Expected WorkspaceNotWritableError for %s, got %v", root, err)
+		}
+	})
+
+	t.Run("fails when the root cannot be created", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		root := filepath.Join(file, "workspaces")
+		_, err := client.CreateSession(t.Context(), &SessionConfig{
+			InfiniteSessions:    &InfiniteSessionConfig{WorkspaceRoot: root},
+			OnPermissionRequest: PermissionHandler.ApproveAll,
+		})
+		if !errors.Is(err, ErrWorkspaceNotWritable) || !strings.Contains(err.Error(), root) {
+			t.Errorf("Expected an ErrWorkspaceNotWritable error naming %s, got %v", root, err)
+		}
+	})
+
+	t.Run("warns when the CLI places the workspace elsewhere", func(t *testing.T) {
+		elsewhere := newWorkspaceFixture(t)
+		session := newSession("s1", nil, elsewhere)
+		var warned string
+		session.On(func(event SessionEvent) {
+			if event.Type == SessionWarning && *event.Data.WarningType == WorkspaceRootIgnoredWarning {
+				warned = *event.Data.Message
+			}
+		})
+		session.warnWorkspaceOutsideRoot(&InfiniteSessionConfig{WorkspaceRoot: t.TempDir()})
+		if !strings.Contains(warned, elsewhere) {
+			t.Errorf("Expected a warning naming %s, got %q", elsewhere, warned)
+		}
+	})
+}
@@ -0,0 +1,376 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// workspaceWatchDebounce is how long a path must be quiet before its
+	// change is reported, so a file written in several steps is reported once.
+	workspaceWatchDebounce = 100 * time.Millisecond
+	// workspacePollInterval is how often the workspace is scanned when file
+	// system notifications are unavailable.
+	workspacePollInterval = 500 * time.Millisecond
+)
+
+// workspace files and subdirectories reported by [Session.WatchWorkspace].
+const (
+	planFile = "plan.md"
+	filesDir = "files"
+)
+
+// newFSWatcher creates the file system watcher; tests replace it to force
+// polling.
+var newFSWatcher = fsnotify.NewWatcher
+
+// WorkspaceChangeKind classifies a [WorkspaceChange].
+type WorkspaceChangeKind string
+
+const (
+	// WorkspacePlanUpdated reports that plan.md was created or written.
+	WorkspacePlanUpdated WorkspaceChangeKind = "plan_updated"
+	// WorkspaceCheckpointAdded reports a new checkpoint.
+	WorkspaceCheckpointAdded WorkspaceChangeKind = "checkpoint_added"
+	// WorkspaceFileAdded reports a new file under files/.
+	WorkspaceFileAdded WorkspaceChangeKind = "file_added"
+	// WorkspaceFileModified reports a write to an existing file under files/.
+	WorkspaceFileModified WorkspaceChangeKind = "file_modified"
+)
+
+// WorkspaceChange is a change to a session's workspace reported by
+// [Session.WatchWorkspace].
+type WorkspaceChange struct {
+	Kind WorkspaceChangeKind
+	// Path is the absolute path of the changed file, or of the checkpoint
+	Path string
+	// Checkpoint is the number of the added checkpoint, for
+	// WorkspaceCheckpointAdded
+	Checkpoint int
+}
+
+// WatchWorkspace reports changes to the session's workspace as they happen:
+// plan.md updates, new checkpoints, and files added or modified under files/.
+// Changes are debounced per path, so a file written in several steps is
+// reported once, about 100ms after the last write. Other files, and removals,
+// are not reported.
+//
+// Changes come from file system notifications, or, where those are
+// unavailable, from scanning the workspace every 500ms. The channel is closed
+// when ctx is done or the session is destroyed; a slow reader delays later
+// changes but loses none. It returns [ErrNoWorkspace] if the session has no
+// workspace.
+//
+// Example:
+//
+//	changes, err := session.WatchWorkspace(ctx)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for change := range changes {
+//	    if change.Kind == copilot.WorkspacePlanUpdated {
+//	        plan, _ := os.ReadFile(change.Path)
+//	        fmt.Println(string(plan))
+//	    }
+//	}
+func (s *Session) WatchWorkspace(ctx context.Context) (<-chan WorkspaceChange, error) {
+	if err := s.checkNotDestroyed(); err != nil {
+		return nil, err
+	}
+	root := s.WorkspacePath()
+	if root == "" {
+		return nil, ErrNoWorkspace
+	}
+	w := &workspaceWatcher{
+		root:      root,
+		pending:   make(map[string]*pendingWorkspaceChange),
+		changes:   make(chan WorkspaceChange, 16),
+		ctx:       ctx,
+		destroyed: s.Done(),
+	}
+
+	notifier, err := newFSWatcher()
+	if err != nil {
+		s.logger.Warn("file system notifications unavailable, polling the workspace", "sessionID", s.SessionID, "error", err)
+		snapshot, err := snapshotWorkspace(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch workspace: %w", err)
+		}
+		go w.poll(snapshot)
+		return w.changes, nil
+	}
+	if err := notifier.Add(root); err != nil {
+		notifier.Close()
+		return nil, fmt.Errorf("failed to watch workspace: %w", err)
+	}
+	for _, dir := range []string{checkpointsDir, filesDir} {
+		if err := w.watchTree(notifier, filepath.Join(root, dir), false); err != nil {
+			notifier.Close()
+			return nil, fmt.Errorf("failed to watch workspace: %w", err)
+		}
+	}
+	go w.run(notifier, s)
+	return w.changes, nil
+}
+
+// workspaceWatcher debounces and reports the changes of one
+// [Session.WatchWorkspace] call.
+type workspaceWatcher struct {
+	root      string
+	pending   map[string]*pendingWorkspaceChange
+	changes   chan WorkspaceChange
+	ctx       context.Context
+	destroyed <-chan struct{}
+}
+
+// pendingWorkspaceChange is a change waiting for its path to be quiet.
+type pendingWorkspaceChange struct {
+	change WorkspaceChange
+	due    time.Time
+}
+
+// run reports the changes seen by notifier until the watch ends.
+func (w *workspaceWatcher) run(notifier *fsnotify.Watcher, s *Session) {
+	defer close(w.changes)
+	defer notifier.Close()
+	timer := time.NewTimer(0)
+	timer.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-w.destroyed:
+			return
+		case event, ok := <-notifier.Events:
+			if !ok {
+				return
+			}
+			w.handle(notifier, event)
+		case err, ok := <-notifier.Errors:
+			if !ok {
+				return
+			}
+			s.logger.Warn("workspace watcher failed", "sessionID", s.SessionID, "error", err)
+		case <-timer.C:
+			if !w.flush() {
+				return
+			}
+		}
+		w.schedule(timer)
+	}
+}
+
+// poll reports the changes between scans of the workspace until the watch
+// ends.
+func (w *workspaceWatcher) poll(snapshot map[string]workspaceFileState) {
+	defer close(w.changes)
+	ticker := time.NewTicker(workspacePollInterval)
+	defer ticker.Stop()
+	timer := time.NewTimer(0)
+	timer.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-w.destroyed:
+			return
+		case <-ticker.C:
+			next, err := snapshotWorkspace(w.root)
+			if err != nil {
+				continue
+			}
+			for path, state := range next {
+				previous, existed := snapshot[path]
+				if !existed || (!state.dir && (state.size != previous.size || !state.modTime.Equal(previous.modTime))) {
+					w.observe(path, !existed, state.dir)
+				}
+			}
+			snapshot = next
+		case <-timer.C:
+			if !w.flush() {
+				return
+			}
+		}
+		w.schedule(timer)
+	}
+}
+
+// handle records a file system notification.
+func (w *workspaceWatcher) handle(notifier *fsnotify.Watcher, event fsnotify.Event) {
+	switch {
+	case event.Has(fsnotify.Create):
+		info, err := os.Lstat(event.Name)
+		if err != nil {
+			return
+		}
+		w.observe(event.Name, true, info.IsDir())
+		if info.IsDir() {
+			// Entries created before the watch was added have no
+			// notifications of their own
+			w.watchTree(notifier, event.Name, true)
+		}
+	case event.Has(fsnotify.Write):
+		w.observe(event.Name, false, false)
+	}
+}
+
+// watchTree watches dir, and the directories under it if they hold files,
+// if dir is a workspace directory whose changes are reported. With
+// observeExisting, the entries already in it are recorded as created.
+func (w *workspaceWatcher) watchTree(notifier *fsnotify.Watcher, dir string, observeExisting bool) error {
+	rel, err := filepath.Rel(w.root, dir)
+	if err != nil {
+		return nil
+	}
+	top, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	if top != checkpointsDir && top != filesDir {
+		return nil
+	}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && observeExisting {
+			w.observe(path, true, d.IsDir())
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if top == checkpointsDir && path != filepath.Join(w.root, checkpointsDir) {
+			// Only the checkpoints themselves are reported
+			return fs.SkipDir
+		}
+		return notifier.Add(path)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		// Created later, and watched then
+		return nil
+	}
+	return err
+}
+
+// observe records a created or written path, if its change is reported.
+func (w *workspaceWatcher) observe(path string, created, dir bool) {
+	change, ok := w.classify(path, created, dir)
+	if !ok {
+		return
+	}
+	due := time.Now().Add(workspaceWatchDebounce)
+	if pending := w.pending[path]; pending != nil {
+		// A file created and then written is still new
+		if pending.change.Kind == WorkspaceFileAdded {
+			change.Kind = WorkspaceFileAdded
+		}
+		if pending.change.Kind == WorkspaceCheckpointAdded {
+			change = pending.change
+		}
+	}
+	w.pending[path] = &pendingWorkspaceChange{change: change, due: due}
+}
+
+// classify returns the change reported for a created or written path.
+func (w *workspaceWatcher) classify(path string, created, dir bool) (WorkspaceChange, bool) {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return WorkspaceChange{}, false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == planFile && !dir:
+		return WorkspaceChange{Kind: WorkspacePlanUpdated, Path: path}, true
+	case len(parts) == 2 && parts[0] == checkpointsDir && created:
+		name := parts[1]
+		digits := strings.IndexFunc(name, func(r rune) bool { return r < '0' || r > '9' })
+		if digits == -1 {
+			digits = len(name)
+		}
+		number, err := strconv.Atoi(name[:digits])
+		if err != nil {
+			return WorkspaceChange{}, false
+		}
+		return WorkspaceChange{Kind: WorkspaceCheckpointAdded, Path: path, Checkpoint: number}, true
+	case len(parts) >= 2 && parts[0] == filesDir && !dir:
+		if created {
+			return WorkspaceChange{Kind: WorkspaceFileAdded, Path: path}, true
+		}
+		return WorkspaceChange{Kind: WorkspaceFileModified, Path: path}, true
+	}
+	return WorkspaceChange{}, false
+}
+
+// schedule sets timer to fire when the next pending change is due.
+func (w *workspaceWatcher) schedule(timer *time.Timer) {
+	var next time.Time
+	for _, pending := range w.pending {
+		if next.IsZero() || pending.due.Before(next) {
+			next = pending.due
+		}
+	}
+	if next.IsZero() {
+		timer.Stop()
+		return
+	}
+	timer.Reset(time.Until(next))
+}
+
+// flush reports the pending changes that are due, oldest first. It returns
+// false if the watch ended while reporting.
+func (w *workspaceWatcher) flush() bool {
+	now := time.Now()
+	var due []*pendingWorkspaceChange
+	for path, pending := range w.pending {
+		if !pending.due.After(now) {
+			due = append(due, pending)
+			delete(w.pending, path)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].due.Before(due[j].due) })
+	for _, pending := range due {
+		select {
+		case w.changes <- pending.change:
+		case <-w.ctx.Done():
+			return false
+		case <-w.destroyed:
+			return false
+		}
+	}
+	return true
+}
+
+// workspaceFileState is what a workspace scan records about a path.
+type workspaceFileState struct {
+	dir     bool
+	size    int64
+	modTime time.Time
+}
+
+// snapshotWorkspace scans a workspace for polling.
+func snapshotWorkspace(root string) (map[string]workspaceFileState, error) {
+	snapshot := make(map[string]workspaceFileState)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path != root {
+				// Removed during the scan
+				return nil
+			}
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		snapshot[path] = workspaceFileState{dir: d.IsDir(), size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return snapshot, err
+}
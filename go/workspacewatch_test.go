@@ -0,0 +1,120 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/github/copilot-sdk/go/internal/sdklog"
+)
+
+func TestSession_WatchWorkspace(t *testing.T) {
+	modes := map[string]func(*testing.T){
+		"notifications": func(*testing.T) {},
+		"polling": func(t *testing.T) {
+			newFSWatcher = func() (*fsnotify.Watcher, error) { return nil, errors.New("unavailable") }
+			t.Cleanup(func() { newFSWatcher = fsnotify.NewWatcher })
+		},
+	}
+	for mode, setup := range modes {
+		t.Run(mode, func(t *testing.T) {
+			setup(t)
+			root := newWorkspaceFixture(t, "001.md")
+			session := newSession("s1", nil, root)
+			session.logger = sdklog.New(io.Discard, false)
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			changes, err := session.WatchWorkspace(ctx)
+			if err != nil {
+				t.Fatalf("Failed to watch: %v", err)
+			}
+
+			write := func(rel, content string) {
+				path := filepath.Join(root, rel)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			write("plan.md", "# Plan\n")
+			write("plan.md", "# Plan\n\n1. Fix the bug\n")
+			write("checkpoints/002.md", "checkpoint")
+			write("checkpoints/index.md", "1. 001\n2. 002\n")
+			write("files/notes.txt", "more notes than before")
+			write("files/new/deeper/report.md", "report")
+
+			want := []string{
+				"checkpoint_added checkpoints/002.md 2",
+				"file_added files/new/deeper/report.md 0",
+				"file_modified files/notes.txt 0",
+				"plan_updated plan.md 0",
+			}
+			var got []string
+			timeout := time.After(5 * time.Second)
+			for len(got) < len(want) {
+				select {
+				case change := <-changes:
+					rel, _ := filepath.Rel(root, change.Path)
+					got = append(got, fmt.Sprintf("%s %s %d", change.Kind, filepath.ToSlash(rel), change.Checkpoint))
+				case <-timeout:
+					t.Fatalf("Timed out waiting for changes, got %q", got)
+				}
+			}
+			select {
+			case change := <-changes:
+				got = append(got, fmt.Sprintf("%s %s", change.Kind, change.Path))
+			case <-time.After(2 * workspacePollInterval):
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, want) {
+				t.Errorf("Expected changes %q, got %q", want, got)
+			}
+
+			cancel()
+			select {
+			case _, ok := <-changes:
+				if ok {
+					t.Error("Expected no more changes after cancellation")
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for the channel to close")
+			}
+		})
+	}
+
+	t.Run("closes the channel when the session is destroyed", func(t *testing.T) {
+		session := newSession("s1", nil, newWorkspaceFixture(t))
+		changes, err := session.WatchWorkspace(t.Context())
+		if err != nil {
+			t.Fatalf("Failed to watch: %v", err)
+		}
+		session.markDestroyed("destroyed by caller")
+		select {
+		case _, ok := <-changes:
+			if ok {
+				t.Error("Expected no changes")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the channel to close")
+		}
+		if _, err := session.WatchWorkspace(t.Context()); !errors.Is(err, ErrSessionDestroyed) {
+			t.Errorf("Expected ErrSessionDestroyed, got %v", err)
+		}
+	})
+
+	t.Run("returns ErrNoWorkspace without a workspace", func(t *testing.T) {
+		session := newSession("s1", nil, "")
+		if _, err := session.WatchWorkspace(t.Context()); !errors.Is(err, ErrNoWorkspace) {
+			t.Errorf("Expected ErrNoWorkspace, got %v", err)
+		}
+	})
+}
@@ -84,7 +84,7 @@ async function generateSessionEvents(schemaPath?: string): Promise<void> {
     const result = await quicktype({
         inputData,
         lang: "go",
-        rendererOptions: { package: "copilot" },
+        rendererOptions: { package: "protocol" },
     });
 
     const banner = `// AUTO-GENERATED FILE - DO NOT EDIT
@@ -92,12 +92,82 @@ async function generateSessionEvents(schemaPath?: string): Promise<void> {
 
 `;
 
-    const outPath = await writeGeneratedFile("go/generated_session_events.go", banner + result.lines.join("\n"));
-    console.log(`  ✓ ${outPath}`);
+    const protocolPath = await writeGeneratedFile("go/protocol/generated_session_events.go", banner + result.lines.join("\n"));
+    console.log(`  ✓ ${protocolPath}`);
+    await formatGoFile(protocolPath);
 
+    const lines = reexportSessionEvents(result.lines);
+    const outPath = await writeGeneratedFile("go/generated_session_events.go", banner + lines.join("\n"));
+    console.log(`  ✓ ${outPath}`);
     await formatGoFile(outPath);
 }
 
+/**
+ * Builds package copilot's side of the session events: SessionEvent itself,
+ * which has the SDK's accessors and an unexported field holding the event's
+ * JSON as received, and an alias for every other type and constant that
+ * package protocol defines.
+ */
+function reexportSessionEvents(lines: string[]): string[] {
+    const start = lines.findIndex((l) => l.startsWith("type SessionEvent struct"));
+    const end = lines.findIndex((l, i) => i > start && l.startsWith("}"));
+    if (start < 0 || end < 0) {
+        throw new Error("SessionEvent struct not found in quicktype output");
+    }
+    const types: string[] = [];
+    const constants: string[] = [];
+    let inConst = false;
+    for (const line of lines) {
+        const typeMatch = /^type (\w+) /.exec(line);
+        if (typeMatch && typeMatch[1] !== "SessionEvent") {
+            types.push(typeMatch[1]);
+        }
+        if (line.startsWith("const (")) {
+            inConst = true;
+        } else if (inConst && line.startsWith(")")) {
+            inConst = false;
+        } else if (inConst) {
+            const constMatch = /^\s*(\w+)\s+\w+\s*=/.exec(line);
+            if (constMatch) {
+                constants.push(constMatch[1]);
+            }
+        }
+    }
+    return [
+        "package copilot",
+        "",
+        "import (",
+        '    "encoding/json"',
+        '    "time"',
+        "",
+        '    "github.com/github/copilot-sdk/go/protocol"',
+        ")",
+        "",
+        "func UnmarshalSessionEvent(data []byte) (SessionEvent, error) {",
+        "    var r SessionEvent",
+        "    err := json.Unmarshal(data, &r)",
+        "    return r, err",
+        "}",
+        "",
+        "func (r *SessionEvent) Marshal() ([]byte, error) {",
+        "    return json.Marshal(r)",
+        "}",
+        "",
+        "// SessionEvent is [protocol.SessionEvent] with the SDK's accessors.",
+        ...lines.slice(start, end),
+        "    // raw is the event's JSON as received, or nil for events created in code",
+        "    raw json.RawMessage",
+        "}",
+        "",
+        ...types.map((t) => `type ${t} = protocol.${t}`),
+        "",
+        "const (",
+        ...constants.map((c) => `    ${c} = protocol.${c}`),
+        ")",
+        "",
+    ];
+}
+
 // ── RPC Types ───────────────────────────────────────────────────────────────
 
 async function generateRpc(schemaPath?: string): Promise<void> {
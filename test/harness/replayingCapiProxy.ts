@@ -5,6 +5,7 @@
 import type { retrieveAvailableModels } from "@github/copilot/sdk";
 import { existsSync } from "fs";
 import { mkdir, readFile, writeFile } from "fs/promises";
+import type { IncomingHttpHeaders } from "http";
 import type {
   ChatCompletion,
   ChatCompletionChunk,
@@ -173,9 +174,10 @@ export class ReplayingCapiProxy extends CapturingHttpProxy {
             (e) => e.request.url === chatCompletionEndpoint,
           );
           const parsedExchanges = await Promise.all(
-            chatCompletionExchanges.map((e) =>
-              parseHttpExchange(e.request.body, e.response?.body),
-            ),
+            chatCompletionExchanges.map(async (e) => ({
+              ...(await parseHttpExchange(e.request.body, e.response?.body)),
+              requestHeaders: e.request.headers,
+            })),
           );
           options.onResponseStart(200, {});
           options.onData(Buffer.from(JSON.stringify(parsedExchanges)));
@@ -991,6 +993,8 @@ export type ToolResultNormalizer = {
 export type ParsedHttpExchange = {
   request: ChatCompletionCreateParamsBase;
   response: ChatCompletion | undefined;
+  // Only populated for exchanges returned by the /exchanges endpoint
+  requestHeaders?: IncomingHttpHeaders;
 };
 
 // We want to be able to reuse the proxy across multiple tests, so it needs to be reconfigurable